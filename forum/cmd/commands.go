@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"forum/internal/database"
+	"forum/internal/digest"
+	"forum/internal/exchange"
+	"forum/internal/mail"
+	"forum/internal/server"
+	"forum/internal/store"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// backupOptions regroupe les réglages des sauvegardes, communs au serveur et à la commande "backup"
+type backupOptions struct {
+	Dir  string
+	Keep int
+}
+
+// digestOptions regroupe les réglages des résumés par email, communs au serveur et à la commande "digest"
+type digestOptions struct {
+	BaseURL    string // Adresse publique du forum, pour les liens des emails
+	SecretFile string // Fichier de la clé qui signe les liens de désabonnement
+	MailDir    string // Dossier où déposer les emails quand aucun serveur SMTP n'est configuré
+	MailFrom   string // Expéditeur des emails
+	SMTPAddr   string // Adresse du serveur SMTP, vide pour déposer les emails dans MailDir
+}
+
+// mailer renvoie le Mailer configuré: SMTP si une adresse est donnée, dépôt de fichiers sinon
+// Les identifiants SMTP sont lus dans SMTP_USERNAME et SMTP_PASSWORD, comme les clés OAuth
+func (o digestOptions) mailer() mail.Mailer {
+	if o.SMTPAddr != "" {
+		return mail.NewSMTPMailer(o.SMTPAddr, o.MailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	}
+	return mail.NewFileMailer(o.MailDir, o.MailFrom)
+}
+
+// usage affiche l'aide des sous-commandes
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: forum [options] [command]
+
+Sans commande, le serveur du forum est démarré.
+
+Commandes:
+  migrate up           Applique toutes les migrations en attente
+  migrate down [n]     Annule les n dernières migrations (1 par défaut)
+  migrate status       Affiche l'état de chaque migration
+  backup [dossier]     Sauvegarde la base dans un fichier horodaté (dossier -backup-dir par défaut)
+  restore <fichier>    Remplace la base par une sauvegarde, après vérification (serveur arrêté)
+  export [-format json|ndjson] [-o fichier]
+                       Exporte les données dans un format portable (sortie standard par défaut)
+  import [-format ndjson|json|phpbb|discourse] [-source nom] [-prefix phpbb_] <fichier>
+                       Importe les données d'un autre forum; relancer le même import ne crée pas de doublons
+  digest               Envoie tout de suite les résumés par email dus
+
+Options:`)
+	flag.PrintDefaults()
+}
+
+// runCommand exécute une sous-commande et renvoie le code de sortie du programme
+func runCommand(dbConfig database.Config, backups backupOptions, digests digestOptions, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(dbConfig, args[1:])
+	case "backup":
+		return runBackup(dbConfig, backups, args[1:])
+	case "restore":
+		return runRestore(dbConfig, args[1:])
+	case "export":
+		return runExport(dbConfig, args[1:])
+	case "import":
+		return runImport(dbConfig, args[1:])
+	case "digest":
+		return runDigest(dbConfig, digests)
+	case "help":
+		usage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		usage()
+		return 2
+	}
+}
+
+// runMigrate gère les sous-commandes "migrate up", "migrate down" et "migrate status"
+func runMigrate(dbConfig database.Config, args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+
+	if err := database.OpenDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	switch args[0] {
+	case "up":
+		count, err := database.MigrateUp()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying migrations: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%d migration(s) applied\n", count)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Invalid number of steps: %s\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		count, err := database.MigrateDown(steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reverting migrations: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%d migration(s) reverted\n", count)
+
+	case "status":
+		migrations, err := database.MigrationStatus()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading migrations: %v\n", err)
+			return 1
+		}
+		for _, migration := range migrations {
+			status := "pending"
+			if migration.Applied {
+				status = "applied " + migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", migration.Version, migration.Name, status)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate command: %s\n\n", args[0])
+		usage()
+		return 2
+	}
+
+	return 0
+}
+
+// runBackup sauvegarde la base dans le dossier donné, puis supprime les sauvegardes en trop
+func runBackup(dbConfig database.Config, backups backupOptions, args []string) int {
+	dir := backups.Dir
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	if err := database.OpenDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	path, err := database.RunBackup(context.Background(), dir, backups.Keep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error backing up database: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Backup written to %s\n", path)
+	return 0
+}
+
+// runRestore remplace la base par une sauvegarde
+// La base actuelle est d'abord copiée à côté d'elle, pour pouvoir revenir en arrière
+func runRestore(dbConfig database.Config, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: forum restore <file>")
+		return 2
+	}
+
+	if err := database.OpenDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	// On vérifie la sauvegarde avant de toucher à quoi que ce soit
+	ctx := context.Background()
+	if err := database.VerifyBackup(ctx, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking backup: %v\n", err)
+		return 1
+	}
+
+	safetyCopy := dbConfig.Path + ".pre-restore-" + time.Now().Format("20060102-150405")
+	if err := database.Backup(ctx, safetyCopy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving current database: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Current database saved to %s\n", safetyCopy)
+
+	if err := database.Restore(ctx, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring database: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Database restored from %s\n", args[0])
+	return 0
+}
+
+// runExport écrit toutes les données du forum au format d'échange
+func runExport(dbConfig database.Config, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "ndjson", "Format de l'export (json ou ndjson)")
+	output := flags.String("o", "", "Fichier de sortie (sortie standard par défaut)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "json" && *format != "ndjson" {
+		fmt.Fprintf(os.Stderr, "Unknown export format: %s\n", *format)
+		return 2
+	}
+
+	if err := database.OpenDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating export file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	counts, err := exchange.Export(context.Background(), w, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting data: %v\n", err)
+		return 1
+	}
+	// Le résumé va sur la sortie d'erreur pour ne pas se mêler à l'export
+	for _, section := range []string{"categories", "users", "posts", "comments", "post_reactions", "comment_reactions", "images", "reports"} {
+		fmt.Fprintf(os.Stderr, "%-18s %d\n", section, counts[section])
+	}
+	return 0
+}
+
+// runImport charge un export d'un autre forum (ou de celui-ci) dans la base
+func runImport(dbConfig database.Config, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "ndjson", "Format du fichier (ndjson, json, phpbb ou discourse)")
+	source := flags.String("source", "", "Nom de la source, qui rend l'import idempotent (le format par défaut)")
+	prefix := flags.String("prefix", "phpbb_", "Préfixe des tables phpBB")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: forum import [-format ndjson|json|phpbb|discourse] [-source name] [-prefix phpbb_] <file>")
+		return 2
+	}
+	if *source == "" {
+		*source = *format
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening import file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	var data *exchange.Dataset
+	switch *format {
+	case "ndjson":
+		data, err = exchange.ReadNDJSON(file)
+	case "json":
+		data, err = exchange.ReadJSON(file)
+	case "phpbb":
+		data, err = exchange.ReadPhpBB(file, *prefix)
+	case "discourse":
+		data, err = exchange.ReadDiscourse(file)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown import format: %s\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading import file: %v\n", err)
+		return 1
+	}
+
+	// Le schéma doit être à jour, et les index de recherche présents pour indexer les posts importés
+	if err := database.InitDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	stats, err := exchange.Import(context.Background(), *source, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing data: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%-18s %8s %8s %8s\n", "", "imported", "existing", "skipped")
+	for _, section := range exchange.ImportSections {
+		fmt.Printf("%-18s %8d %8d %8d\n", section, stats.Imported[section], stats.Existing[section], stats.Skipped[section])
+	}
+	return 0
+}
+
+// runDigest envoie les résumés par email dus, sans attendre le passage suivant du serveur
+func runDigest(dbConfig database.Config, digests digestOptions) int {
+	secret, err := server.LoadSecret(digests.SecretFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading secret: %v\n", err)
+		return 1
+	}
+
+	if err := database.InitDB(dbConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB()
+
+	sender := digest.NewSender(store.NewSQLiteStores().Digests, digests.mailer(), digests.BaseURL, secret)
+	sent, err := sender.Run(context.Background(), time.Now())
+	fmt.Printf("Sent %d email digests\n", sent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error sending email digests: %v\n", err)
+		return 1
+	}
+	return 0
+}
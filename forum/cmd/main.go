@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"forum/internal/database"
+	"forum/internal/digest"
+	"forum/internal/events"
 	"forum/internal/handlers"
 	"forum/internal/middleware"
-	"forum/internal/models"
+	"forum/internal/publish"
 	"forum/internal/server"
+	"forum/internal/store"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -50,22 +55,72 @@ func init() {
 func main() {
 	// Définir et lire les options de ligne de commande
 	var (
-		dbPath      = flag.String("db", "./forum.db", "Chemin vers la base de données SQLite")
-		httpPort    = flag.Int("http", 8085, "Port HTTP")
-		httpsPort   = flag.Int("https", 8443, "Port HTTPS")
-		certDir     = flag.String("certs", "./certs", "Dossier des certificats SSL")
-		domain      = flag.String("domain", "localhost", "Nom de domaine pour HTTPS")
-		dev         = flag.Bool("dev", true, "Mode développement (certificat auto-signé)")
-		uploadDir   = flag.String("uploads", "./static/uploads", "Dossier pour les uploads")
+		dbPath         = flag.String("db", "./forum.db", "Chemin vers la base de données SQLite")
+		httpPort       = flag.Int("http", 8085, "Port HTTP")
+		httpsPort      = flag.Int("https", 8443, "Port HTTPS")
+		certDir        = flag.String("certs", "./certs", "Dossier des certificats SSL")
+		domain         = flag.String("domain", "localhost", "Nom de domaine pour HTTPS")
+		dev            = flag.Bool("dev", true, "Mode développement (certificat auto-signé)")
+		uploadDir      = flag.String("uploads", "./static/uploads", "Dossier pour les uploads")
+		requestTimeout = flag.Duration("request-timeout", 30*time.Second, "Délai maximal de traitement d'une requête (0 pour désactiver)")
+		backupInterval = flag.Duration("backup-interval", 0, "Intervalle entre deux sauvegardes automatiques (0 pour désactiver)")
+		digestInterval = flag.Duration("digest-interval", 0, "Intervalle entre deux recherches des résumés par email à envoyer (0 pour désactiver)")
+		messageRate    = flag.Int("message-rate", 10, "Nombre maximal de messages privés envoyés par un utilisateur par minute (0 pour ne pas limiter)")
+		purgeInterval  = flag.Duration("message-purge-interval", time.Hour, "Intervalle entre deux suppressions des messages privés expirés (0 pour désactiver)")
+		badgeInterval  = flag.Duration("badge-interval", time.Hour, "Intervalle entre deux attributions des badges gagnés (0 pour désactiver)")
+		draftInterval  = flag.Duration("publish-interval", time.Minute, "Intervalle entre deux publications des brouillons programmés (0 pour désactiver)")
+		reputationTTL  = flag.Duration("reputation-cache", time.Minute, "Durée de conservation des réputations calculées (0 pour les recalculer à chaque lecture)")
 	)
+	
+	// Réglages des sauvegardes, utilisés par le serveur et par la commande "backup"
+	backups := backupOptions{}
+	flag.StringVar(&backups.Dir, "backup-dir", "./backups", "Dossier des sauvegardes")
+	flag.IntVar(&backups.Keep, "backup-keep", 7, "Nombre de sauvegardes conservées (0 pour toutes les garder)")
+	
+	// Réglages des résumés par email, utilisés par le serveur et par la commande "digest"
+	digests := digestOptions{}
+	flag.StringVar(&digests.BaseURL, "base-url", "", "Adresse publique du forum pour les liens des emails (déduite de -domain par défaut)")
+	flag.StringVar(&digests.SecretFile, "secret-file", "./secret.key", "Fichier de la clé qui signe les liens envoyés par email (créé s'il n'existe pas)")
+	flag.StringVar(&digests.MailDir, "mail-dir", "./mail", "Dossier où déposer les emails quand -smtp est vide")
+	flag.StringVar(&digests.MailFrom, "mail-from", "Forum <forum@localhost>", "Expéditeur des emails")
+	flag.StringVar(&digests.SMTPAddr, "smtp", "", "Adresse du serveur SMTP (hôte:port), identifiants dans SMTP_USERNAME et SMTP_PASSWORD")
+	
+	// Réglages de la connexion SQLite
+	dbConfig := database.DefaultConfig("")
+	flag.StringVar(&dbConfig.JournalMode, "db-journal", dbConfig.JournalMode, "Mode du journal SQLite (WAL, DELETE...)")
+	flag.StringVar(&dbConfig.Synchronous, "db-synchronous", dbConfig.Synchronous, "Niveau de synchronisation SQLite (OFF, NORMAL, FULL, EXTRA)")
+	flag.DurationVar(&dbConfig.BusyTimeout, "db-busy-timeout", dbConfig.BusyTimeout, "Attente maximale quand la base est verrouillée")
+	flag.IntVar(&dbConfig.MaxReadConns, "db-read-conns", dbConfig.MaxReadConns, "Nombre de connexions du pool de lecture")
+	flag.BoolVar(&dbConfig.ForeignKeys, "db-foreign-keys", dbConfig.ForeignKeys, "Active les clés étrangères SQLite")
+	
+	flag.Usage = usage
 	flag.Parse()
+	
+	dbConfig.Path = *dbPath
+	if err := dbConfig.Validate(); err != nil {
+		log.Fatalf("Invalid database configuration: %v", err)
+	}
+
+	// Les liens des emails pointent vers l'adresse HTTPS du serveur
+	if digests.BaseURL == "" {
+		digests.BaseURL = "https://" + *domain
+		if *dev {
+			digests.BaseURL += fmt.Sprintf(":%d", *httpsPort)
+		}
+	}
+	digests.BaseURL = strings.TrimSuffix(digests.BaseURL, "/")
+
+	// Les sous-commandes (par exemple "migrate up") s'exécutent sans démarrer le serveur
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(dbConfig, backups, digests, flag.Args()))
+	}
 
 	// Créer les dossiers nécessaires s'ils n'existent pas
 	os.MkdirAll(*certDir, 0755)
 	os.MkdirAll(*uploadDir, 0755)
 	
 	// On initialise la base de données au démarrage du programme
-	err := database.InitDB(*dbPath)
+	err := database.InitDB(dbConfig)
 	if err != nil {
 		// Si on n'arrive pas à initialiser la base de données, on arrête tout
 		log.Fatalf("Error initializing database: %v", err)
@@ -73,13 +128,52 @@ func main() {
 	// On s'assure que la base de données sera fermée proprement à la fin du programme
 	defer database.CloseDB()
 	
-	// Initialiser les tables supplémentaires pour les fonctionnalités étendues
-	if err := models.InitImageTables(); err != nil {
-		log.Fatalf("Error initializing image tables: %v", err)
+	// Sauvegardes automatiques pendant que le serveur tourne
+	if *backupInterval > 0 {
+		log.Printf("Backing up database every %s to %s", *backupInterval, backups.Dir)
+		go database.ScheduleBackups(context.Background(), backups.Dir, *backupInterval, backups.Keep)
+	}
+	
+	// La clé du serveur signe les liens de désabonnement des emails
+	secret, err := server.LoadSecret(digests.SecretFile)
+	if err != nil {
+		log.Fatalf("Error loading secret: %v", err)
+	}
+	
+	// Les handlers accèdent aux données à travers les stockages SQLite
+	// Les modifications sont annoncées en temps réel aux pages ouvertes par le hub des événements
+	hub := events.NewHub(events.DefaultHistorySize, events.DefaultBufferSize)
+	// Les réputations calculées sont gardées en cache pour ne pas refaire les comptes à chaque post
+	stores := store.NewSQLiteStores()
+	if *reputationTTL > 0 {
+		stores = store.WithReputationCache(stores, *reputationTTL)
+	}
+	stores = store.WithEvents(stores, hub)
+	h := handlers.New(stores, secret, hub)
+	if *messageRate > 0 {
+		h.MessageLimiter = middleware.NewRateLimiter(*messageRate, time.Minute)
+	}
+	
+	// Résumés par email pendant que le serveur tourne
+	if *digestInterval > 0 {
+		log.Printf("Checking email digests every %s", *digestInterval)
+		sender := digest.NewSender(stores.Digests, digests.mailer(), digests.BaseURL, secret)
+		go sender.Schedule(context.Background(), *digestInterval)
 	}
 	
-	if err := models.ModerationQueueInit(); err != nil {
-		log.Fatalf("Error initializing moderation tables: %v", err)
+	// Suppression des messages privés plus anciens que la durée de conservation choisie par les administrateurs
+	if *purgeInterval > 0 {
+		go store.ScheduleMessagePurge(context.Background(), stores.Messages, *purgeInterval)
+	}
+
+	// Attribution des badges gagnés par l'activité des utilisateurs
+	if *badgeInterval > 0 {
+		go store.ScheduleBadgeAwards(context.Background(), stores.Reputation, *badgeInterval)
+	}
+
+	// Publication des brouillons programmés, avec les règles du forum au moment de la publication
+	if *draftInterval > 0 {
+		go publish.NewPublisher(stores).Schedule(context.Background(), *draftInterval)
 	}
 	
 	// On crée un nouveau routeur pour gérer les différentes adresses du site
@@ -90,50 +184,116 @@ func main() {
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 	
 	// On configure les routes pour l'authentification
-	mux.HandleFunc("/register", handlers.RegisterHandler)
-	mux.HandleFunc("/login", handlers.LoginHandler)
-	mux.HandleFunc("/logout", handlers.LogoutHandler)
+	mux.HandleFunc("/register", h.RegisterHandler)
+	mux.HandleFunc("/login", h.LoginHandler)
+	mux.HandleFunc("/logout", h.LogoutHandler)
 	
 	// Routes pour l'authentification OAuth
-	mux.HandleFunc("/auth/google", handlers.GoogleLoginHandler)
-	mux.HandleFunc("/auth/google/callback", handlers.GoogleCallbackHandler)
-	mux.HandleFunc("/auth/github", handlers.GitHubLoginHandler)
-	mux.HandleFunc("/auth/github/callback", handlers.GitHubCallbackHandler)
+	mux.HandleFunc("/auth/google", h.GoogleLoginHandler)
+	mux.HandleFunc("/auth/google/callback", h.GoogleCallbackHandler)
+	mux.HandleFunc("/auth/github", h.GitHubLoginHandler)
+	mux.HandleFunc("/auth/github/callback", h.GitHubCallbackHandler)
 	
 	// Routes pour les pages principales
-	mux.HandleFunc("/", handlers.HomeHandler)
+	mux.HandleFunc("/", h.HomeHandler)
 	
 	// Routes pour les posts
-	mux.HandleFunc("/post/create", handlers.CreatePostHandler)
-	mux.HandleFunc("/post/", handlers.ViewPostHandler)
-	mux.HandleFunc("/post/edit/", handlers.EditPostHandler)
-	mux.HandleFunc("/post/delete/", handlers.DeletePostHandler)
-	mux.HandleFunc("/post/react", handlers.ReactToPostHandler)
+	mux.HandleFunc("/post/create", h.CreatePostHandler)
+	mux.HandleFunc("/post/submitted", h.PostSubmittedHandler)
+	mux.HandleFunc("/post/", h.ViewPostHandler)
+	mux.HandleFunc("/post/edit/", h.EditPostHandler)
+	mux.HandleFunc("/post/delete/", h.DeletePostHandler)
+	mux.HandleFunc("/post/react", h.ReactToPostHandler)
+	mux.HandleFunc("/post/accept", h.AcceptAnswerHandler)
+	mux.HandleFunc("/reactions", h.ReactorsHandler)
+	mux.HandleFunc("/api/markdown/preview", h.PreviewMarkdownHandler)
+
+	// Routes pour les tags
+	mux.HandleFunc("/tag/", h.TagHandler)
+	mux.HandleFunc("/api/tags", h.APITagsHandler)
 
-	mux.HandleFunc("/profile", handlers.ProfileHandler)
+	mux.HandleFunc("/profile", h.ProfileHandler)
+	mux.HandleFunc("/api/users/suggest", h.SuggestUsersHandler)
+
+	// Routes pour les notifications
+	mux.HandleFunc("/notifications", h.NotificationsHandler)
+	mux.HandleFunc("/notifications/read", h.ReadNotificationHandler)
+	mux.HandleFunc("/notifications/digest", h.DigestSettingsHandler)
+	mux.HandleFunc("/digest/unsubscribe", h.UnsubscribeDigestHandler)
+
+	// Routes pour les messages privés
+	mux.HandleFunc("/messages", h.MessagesHandler)
+	mux.HandleFunc("/messages/", h.ConversationHandler)
+	mux.HandleFunc("/messages/new", h.NewConversationHandler)
+	mux.HandleFunc("/messages/send", h.SendMessageHandler)
+	mux.HandleFunc("/messages/leave", h.LeaveConversationHandler)
+	mux.HandleFunc("/messages/report", h.ReportMessageHandler)
+	mux.HandleFunc("/messages/block", h.BlockUserHandler)
+
+	// Flux des événements temps réel
+	mux.HandleFunc("/events", h.EventsHandler)
+
+	// Routes pour les abonnements
+	mux.HandleFunc("/following", h.FollowingHandler)
+	mux.HandleFunc("/subscription/", h.SubscriptionHandler)
+
+	// Routes pour les signets
+	mux.HandleFunc("/bookmark/", h.BookmarkHandler)
+	mux.HandleFunc("/bookmark/folder/", h.BookmarkFolderHandler)
+	mux.HandleFunc("/bookmarks/export", h.ExportBookmarksHandler)
+
+	// Routes pour les brouillons
+	mux.HandleFunc("/draft/save", h.SaveDraftHandler)
+	mux.HandleFunc("/draft/delete", h.DeleteDraftHandler)
+	mux.HandleFunc("/draft/unschedule", h.UnscheduleDraftHandler)
+
+	// Route pour les votes des sondages
+	mux.HandleFunc("/poll/vote", h.VotePollHandler)
+	
+	// Routes pour la recherche
+	mux.HandleFunc("/search", h.SearchHandler)
+	mux.HandleFunc("/api/search", h.APISearchHandler)
 	
 	// Routes pour les commentaires
-	mux.HandleFunc("/comment/create", handlers.CreateCommentHandler)
-	mux.HandleFunc("/comment/edit", handlers.EditCommentHandler)
-	mux.HandleFunc("/comment/delete/", handlers.DeleteCommentHandler)
-	mux.HandleFunc("/comment/react", handlers.ReactToCommentHandler)
+	mux.HandleFunc("/comment/create", h.CreateCommentHandler)
+	mux.HandleFunc("/comment/edit", h.EditCommentHandler)
+	mux.HandleFunc("/comment/delete/", h.DeleteCommentHandler)
+	mux.HandleFunc("/comment/react", h.ReactToCommentHandler)
 	
 	// Routes pour le téléchargement et la gestion des images
-	mux.HandleFunc("/upload/image", handlers.UploadImageHandler)
-	mux.HandleFunc("/image/", handlers.GetImageHandler)
+	mux.HandleFunc("/upload/image", h.UploadImageHandler)
+	mux.HandleFunc("/image/", h.GetImageHandler)
 	
 	// Routes pour la modération
 	moderatorMux := http.NewServeMux()
-	moderatorMux.HandleFunc("/mod/pending", handlers.ListPendingPostsHandler)
-	moderatorMux.HandleFunc("/mod/approve/", handlers.ApprovePostHandler)
-	moderatorMux.HandleFunc("/mod/reject/", handlers.RejectPostHandler)
+	moderatorMux.HandleFunc("/mod/pending", h.ListPendingPostsHandler)
+	moderatorMux.HandleFunc("/mod/approve/", h.ApprovePostHandler)
+	moderatorMux.HandleFunc("/mod/reject/", h.RejectPostHandler)
+	moderatorMux.HandleFunc("/mod/tags", h.ListTagsHandler)
+	moderatorMux.HandleFunc("/mod/tag/synonym/add", h.AddTagSynonymHandler)
+	moderatorMux.HandleFunc("/mod/tag/synonym/remove", h.RemoveTagSynonymHandler)
+	moderatorMux.HandleFunc("/mod/post/pin", h.PinPostHandler)
+	moderatorMux.HandleFunc("/mod/post/lock", h.LockPostHandler)
+	moderatorMux.HandleFunc("/mod/announcements", h.ListAnnouncementsHandler)
+	moderatorMux.HandleFunc("/mod/announcement/create", h.CreateAnnouncementHandler)
+	moderatorMux.HandleFunc("/mod/announcement/delete", h.DeleteAnnouncementHandler)
 	
 	// Routes pour l'administration
 	adminMux := http.NewServeMux()
-	adminMux.HandleFunc("/admin/reports", handlers.ListReportsHandler)
-	adminMux.HandleFunc("/admin/report/handle/", handlers.HandleReportHandler)
-	adminMux.HandleFunc("/admin/users", handlers.ListUsersHandler)
-	adminMux.HandleFunc("/admin/user/role/", handlers.UpdateUserRoleHandler)
+	adminMux.HandleFunc("/admin/reports", h.ListReportsHandler)
+	adminMux.HandleFunc("/admin/report/handle/", h.HandleReportHandler)
+	adminMux.HandleFunc("/admin/users", h.ListUsersHandler)
+	adminMux.HandleFunc("/admin/messages", h.MessageSettingsHandler)
+	adminMux.HandleFunc("/admin/user/role/", h.UpdateUserRoleHandler)
+	adminMux.HandleFunc("/admin/categories", h.ListCategoriesHandler)
+	adminMux.HandleFunc("/admin/reactions", h.ListReactionsHandler)
+	adminMux.HandleFunc("/admin/reaction/", h.AdminReactionHandler)
+	adminMux.HandleFunc("/admin/category/create", h.CreateCategoryHandler)
+	adminMux.HandleFunc("/admin/category/edit/", h.UpdateCategoryHandler)
+	adminMux.HandleFunc("/admin/category/move/", h.MoveCategoryHandler)
+	adminMux.HandleFunc("/admin/category/merge/", h.MergeCategoryHandler)
+	adminMux.HandleFunc("/admin/api/categories", h.APICategoriesHandler)
+	adminMux.HandleFunc("/admin/api/categories/", h.APICategoriesHandler)
 	
 	// Application des middleware de sécurité pour chaque niveau d'accès
 	moderatorHandler := middleware.RequireRoleMiddleware(moderatorMux, "moderator")
@@ -149,7 +309,9 @@ func main() {
 	// Appliquer les middleware dans l'ordre
 	handler := middleware.LoggingMiddleware(mux)
 	handler = rateLimiter.Middleware(handler)
-	handler = middleware.AuthMiddleware(handler)
+	handler = middleware.AuthMiddleware(handler, stores.Sessions, stores.Users)
+	// Le flux /events reste ouvert tant que la page est affichée: il n'a pas de délai maximal
+	handler = middleware.TimeoutMiddleware(handler, *requestTimeout, "/events")
 	
 	// Configuration HTTPS
 	httpsConfig := server.HTTPSConfig{
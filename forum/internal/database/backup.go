@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Préfixe et extension des fichiers de sauvegarde: forum-AAAAMMJJ-HHMMSS.db
+const (
+	backupPrefix     = "forum-"
+	backupExtension  = ".db"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupFileName renvoie le chemin d'une nouvelle sauvegarde horodatée dans le dossier dir
+func BackupFileName(dir string, now time.Time) string {
+	return filepath.Join(dir, backupPrefix+now.Format(backupTimeLayout)+backupExtension)
+}
+
+// Backup copie la base ouverte dans destPath avec l'API de sauvegarde en ligne de SQLite
+// La copie est cohérente même si le serveur continue d'écrire pendant la sauvegarde
+func Backup(ctx context.Context, destPath string) error {
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("backup file already exists: %s", destPath)
+	}
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+
+	err = copyDatabase(ctx, dest, ReadDB)
+	if err == nil {
+		// La copie hérite du mode WAL de la base: la sauvegarde repasse en journal classique
+		// pour tenir dans un seul fichier, sans fichiers -wal et -shm à côté
+		_, err = dest.ExecContext(ctx, "PRAGMA journal_mode = DELETE")
+	}
+	if err != nil {
+		dest.Close()
+		removeBackupFiles(destPath)
+		return err
+	}
+	return dest.Close()
+}
+
+// backupSidecars sont les suffixes des fichiers que SQLite crée à côté d'une base en mode WAL
+var backupSidecars = []string{"-wal", "-shm"}
+
+// removeBackupFiles supprime une sauvegarde et les fichiers -wal et -shm qui peuvent l'accompagner
+func removeBackupFiles(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	for _, suffix := range backupSidecars {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyBackup vérifie que srcPath est une sauvegarde intacte d'une base du forum
+func VerifyBackup(ctx context.Context, srcPath string) error {
+	backup, err := loadBackup(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	return backup.Close()
+}
+
+// Restore remplace le contenu de la base ouverte par celui de la sauvegarde srcPath
+// La sauvegarde est vérifiée avant d'écraser quoi que ce soit, puis la base restaurée est vérifiée à son tour
+// Le serveur ne doit pas tourner pendant une restauration
+func Restore(ctx context.Context, srcPath string) error {
+	backup, err := loadBackup(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	// Les pages de la sauvegarde remplacent celles de la base, en passant par la connexion d'écriture
+	err = copyDatabase(ctx, DB, backup)
+	if err != nil {
+		return err
+	}
+
+	return checkIntegrity(ctx, DB)
+}
+
+// loadBackup charge la sauvegarde srcPath en mémoire et la vérifie
+// Le fichier est ouvert en lecture seule pour ne jamais le modifier, mais la vérification des index
+// de recherche FTS5 a besoin d'écrire: elle se fait donc sur la copie en mémoire
+func loadBackup(ctx context.Context, srcPath string) (*sql.DB, error) {
+	if _, err := os.Stat(srcPath); err != nil {
+		return nil, err
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	// Une base en mémoire n'existe que dans sa connexion: le pool n'en garde qu'une, pour toujours
+	backup, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	backup.SetMaxOpenConns(1)
+	backup.SetConnMaxLifetime(0)
+	backup.SetConnMaxIdleTime(0)
+
+	if err := copyDatabase(ctx, backup, src); err != nil {
+		backup.Close()
+		return nil, fmt.Errorf("reading backup %s: %v", srcPath, err)
+	}
+	if err := checkIntegrity(ctx, backup); err != nil {
+		backup.Close()
+		return nil, fmt.Errorf("backup %s is corrupted: %v", srcPath, err)
+	}
+
+	var hasMigrations int
+	err = backup.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&hasMigrations)
+	if err != nil {
+		backup.Close()
+		return nil, err
+	}
+	if hasMigrations == 0 {
+		backup.Close()
+		return nil, fmt.Errorf("%s is not a forum database backup", srcPath)
+	}
+	return backup, nil
+}
+
+// copyDatabase copie toutes les pages de la base principale de src vers celle de dest
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			destSQLite, ok := destDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a SQLite connection")
+			}
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a SQLite connection")
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+
+			// Une seule étape copie toutes les pages d'un coup
+			_, err = backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// checkIntegrity exécute PRAGMA integrity_check et renvoie une erreur si la base est endommagée
+func checkIntegrity(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// PruneBackups supprime les sauvegardes les plus anciennes du dossier dir pour n'en garder que keep
+// Seuls les fichiers nommés par BackupFileName sont concernés, avec leurs fichiers -wal et -shm
+func PruneBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupExtension) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupExtension)
+		if _, err := time.Parse(backupTimeLayout, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+	if len(backups) <= keep {
+		return nil, nil
+	}
+
+	// L'horodatage dans le nom permet de trier par ordre alphabétique
+	sort.Strings(backups)
+	var removed []string
+	for _, name := range backups[:len(backups)-keep] {
+		path := filepath.Join(dir, name)
+		if err := removeBackupFiles(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// RunBackup crée une sauvegarde horodatée dans dir puis applique la rétention
+func RunBackup(ctx context.Context, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := BackupFileName(dir, time.Now())
+	if err := Backup(ctx, path); err != nil {
+		return "", err
+	}
+
+	removed, err := PruneBackups(dir, keep)
+	for _, old := range removed {
+		log.Printf("Removed old backup %s", old)
+	}
+	return path, err
+}
+
+// ScheduleBackups lance une sauvegarde toutes les "interval" jusqu'à l'annulation du contexte
+func ScheduleBackups(ctx context.Context, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := RunBackup(ctx, dir, keep)
+			if err != nil {
+				log.Printf("Error running scheduled backup: %v", err)
+				continue
+			}
+			log.Printf("Scheduled backup written to %s", path)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Une sauvegarde restaurée rend les données écrites avant elle et efface celles écrites après
+func TestBackupRestore(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.db")
+
+	exec(t, "INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')")
+	if err := Backup(ctx, path); err != nil {
+		t.Fatalf("backing up: %v", err)
+	}
+	if err := Backup(ctx, path); err == nil {
+		t.Error("backing up over an existing file succeeded")
+	}
+
+	// La sauvegarde tient dans un seul fichier, sans fichiers du mode WAL
+	for _, suffix := range backupSidecars {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("backup left %s%s behind: %v", path, suffix, err)
+		}
+	}
+	if err := VerifyBackup(ctx, path); err != nil {
+		t.Fatalf("verifying backup: %v", err)
+	}
+
+	exec(t, "INSERT INTO users (id, email, username, password) VALUES (2, 'b@example.com', 'bob', 'x')")
+	if err := Restore(ctx, path); err != nil {
+		t.Fatalf("restoring: %v", err)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM users WHERE id IN (1, 2)"); n != 1 {
+		t.Errorf("users after restore: got %d, want 1", n)
+	}
+}
+
+// Un fichier qui n'est pas une base du forum est refusé avant d'écraser quoi que ce soit
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	exec(t, "INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')")
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte("pas une base SQLite"), 0644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	for _, path := range []string{garbage, filepath.Join(t.TempDir(), "missing.db")} {
+		if err := Restore(ctx, path); err == nil {
+			t.Errorf("restoring %s succeeded", path)
+		}
+	}
+	if n := count(t, "SELECT COUNT(*) FROM users"); n != 1 {
+		t.Errorf("users after failed restores: got %d, want 1", n)
+	}
+}
+
+// La rétention supprime les sauvegardes les plus anciennes avec leurs fichiers -wal et -shm
+// et ne touche pas aux autres fichiers du dossier
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	var backups []string
+	for i := 0; i < 4; i++ {
+		backups = append(backups, BackupFileName(dir, start.Add(time.Duration(i)*time.Hour)))
+	}
+	others := []string{filepath.Join(dir, "forum-manuel.db"), filepath.Join(dir, "notes.txt")}
+	files := append(append([]string{backups[0] + "-wal", backups[0] + "-shm"}, backups...), others...)
+	for _, path := range files {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("writing %s: %v", path, err)
+		}
+	}
+
+	removed, err := PruneBackups(dir, 2)
+	if err != nil {
+		t.Fatalf("pruning backups: %v", err)
+	}
+	if len(removed) != 2 || removed[0] != backups[0] || removed[1] != backups[1] {
+		t.Errorf("removed backups: got %v, want the two oldest", removed)
+	}
+	for _, path := range files {
+		_, err := os.Stat(path)
+		gone := os.IsNotExist(err)
+		want := path == backups[0] || path == backups[1] || path == backups[0]+"-wal" || path == backups[0]+"-shm"
+		if gone != want {
+			t.Errorf("%s: removed %v, want %v", path, gone, want)
+		}
+	}
+}
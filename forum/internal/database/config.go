@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Config regroupe les réglages de connexion à SQLite
+// Ils sont passés dans la chaîne de connexion (DSN) pour s'appliquer à chaque connexion du pool
+type Config struct {
+	Path         string        // Chemin du fichier de base de données
+	JournalMode  string        // Mode du journal: WAL permet de lire pendant une écriture
+	ForeignKeys  bool          // Active les clés étrangères (et donc les ON DELETE CASCADE)
+	BusyTimeout  time.Duration // Temps d'attente quand la base est verrouillée par un autre processus
+	Synchronous  string        // Niveau de synchronisation disque: OFF, NORMAL, FULL ou EXTRA
+	MaxReadConns int           // Nombre maximum de connexions du pool de lecture
+	Driver       string        // Pilote database/sql enregistré pour SQLite, "sqlite3" si vide
+}
+
+// DefaultConfig renvoie les réglages recommandés pour le forum
+func DefaultConfig(path string) Config {
+	return Config{
+		Path:         path,
+		JournalMode:  "WAL",
+		ForeignKeys:  true,
+		BusyTimeout:  5 * time.Second,
+		Synchronous:  "NORMAL",
+		MaxReadConns: runtime.NumCPU(),
+	}
+}
+
+// Validate vérifie que les réglages sont utilisables
+func (c Config) Validate() error {
+	switch strings.ToUpper(c.JournalMode) {
+	case "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF":
+	default:
+		return fmt.Errorf("invalid journal mode: %s", c.JournalMode)
+	}
+	switch strings.ToUpper(c.Synchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return fmt.Errorf("invalid synchronous level: %s", c.Synchronous)
+	}
+	if c.BusyTimeout < 0 {
+		return fmt.Errorf("invalid busy timeout: %v", c.BusyTimeout)
+	}
+	if c.MaxReadConns < 1 {
+		return fmt.Errorf("invalid number of read connections: %d", c.MaxReadConns)
+	}
+	return nil
+}
+
+// commonParams renvoie les paramètres partagés par les deux pools
+func (c Config) commonParams() url.Values {
+	params := url.Values{}
+	if c.ForeignKeys {
+		params.Set("_foreign_keys", "on")
+	} else {
+		params.Set("_foreign_keys", "off")
+	}
+	params.Set("_busy_timeout", fmt.Sprint(c.BusyTimeout.Milliseconds()))
+	params.Set("_synchronous", strings.ToUpper(c.Synchronous))
+	return params
+}
+
+// writerDSN construit la chaîne de connexion du pool d'écriture
+// Les transactions prennent le verrou d'écriture dès le BEGIN pour éviter les "database is locked"
+// lors de la promotion d'une transaction de lecture en écriture
+func (c Config) writerDSN() string {
+	params := c.commonParams()
+	params.Set("_journal_mode", strings.ToUpper(c.JournalMode))
+	params.Set("_txlock", "immediate")
+	return c.Path + "?" + params.Encode()
+}
+
+// readerDSN construit la chaîne de connexion du pool de lecture
+// Le mode du journal est enregistré dans le fichier par le pool d'écriture, inutile de le redemander ici
+func (c Config) readerDSN() string {
+	params := c.commonParams()
+	params.Set("_query_only", "on")
+	return c.Path + "?" + params.Encode()
+}
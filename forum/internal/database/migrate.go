@@ -0,0 +1,289 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Les fichiers de migration sont embarqués dans le binaire
+// Chaque migration est une paire NNNN_nom.up.sql / NNNN_nom.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration représente une version du schéma de la base de données
+type Migration struct {
+	Version   int
+	Name      string
+	Up        string
+	Down      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// legacyMarkers permet d'adopter les bases créées avant le système de migrations:
+// si la fonction renvoie vrai, la migration est déjà reflétée dans le schéma et sera marquée appliquée
+var legacyMarkers = map[int]func() (bool, error){
+	4: func() (bool, error) { return hasColumn("posts", "like_count") },
+}
+
+// loadMigrations lit et trie les migrations embarquées
+func loadMigrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, label, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %v", name, err)
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []*Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// ensureMigrationsTable crée la table schema_migrations si besoin
+// et marque comme appliquées les migrations déjà présentes dans une base plus ancienne
+func ensureMigrationsTable() error {
+	var exists int
+	err := DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	_, err = DB.Exec(`
+	CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return err
+	}
+
+	// Une base sans table users est neuve: toutes les migrations seront exécutées
+	var legacy int
+	err = DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&legacy)
+	if err != nil || legacy == 0 {
+		return err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		marker, ok := legacyMarkers[migration.Version]
+		if !ok {
+			continue
+		}
+		applied, err := marker()
+		if err != nil {
+			return err
+		}
+		if applied {
+			_, err = DB.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name)
+			if err != nil {
+				return err
+			}
+			log.Printf("Migration %04d_%s already present in existing database", migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus renvoie toutes les migrations connues avec leur état
+func MigrationStatus() ([]*Migration, error) {
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range migrations {
+		if appliedAt, ok := applied[migration.Version]; ok {
+			migration.Applied = true
+			migration.AppliedAt = appliedAt
+		}
+	}
+
+	return migrations, nil
+}
+
+// MigrateUp exécute toutes les migrations qui n'ont pas encore été appliquées
+// Chaque migration est exécutée dans sa propre transaction. Une migration qui a besoin de FTS5
+// reste en attente si SQLite a été compilé sans ce module
+func MigrateUp() (int, error) {
+	migrations, err := MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if migration.Applied {
+			continue
+		}
+		if err := runMigration(migration, migration.Up, true); err != nil {
+			// Les index de recherche ont besoin de FTS5: leur migration attend un binaire qui l'a
+			if isMissingFTS5(err) {
+				log.Printf("Skipped migration %04d_%s: SQLite was built without FTS5", migration.Version, migration.Name)
+				continue
+			}
+			return count, err
+		}
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown annule les "steps" dernières migrations appliquées
+func MigrateDown(steps int) (int, error) {
+	migrations, err := MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := migrations[i]
+		if !migration.Applied {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s cannot be reverted", migration.Version, migration.Name)
+		}
+		if err := runMigration(migration, migration.Down, false); err != nil {
+			return count, err
+		}
+		log.Printf("Reverted migration %04d_%s", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// runMigration exécute un script et met à jour schema_migrations dans la même transaction
+func runMigration(migration *Migration, script string, up bool) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("migration %04d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// hasColumn vérifie si une colonne existe dans une table
+func hasColumn(table, column string) (bool, error) {
+	rows, err := DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
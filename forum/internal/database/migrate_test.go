@@ -0,0 +1,189 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB ouvre une base vide dans un dossier temporaire et lui applique toutes les migrations
+func openTestDB(t *testing.T) {
+	t.Helper()
+	if err := OpenDB(DefaultConfig(filepath.Join(t.TempDir(), "forum.db"))); err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		CloseDB()
+	})
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+}
+
+// migrateDownTo annule les migrations appliquées jusqu'à ce que version ne le soit plus
+func migrateDownTo(t *testing.T, version int) {
+	t.Helper()
+	migrations, err := MigrationStatus()
+	if err != nil {
+		t.Fatalf("reading migration status: %v", err)
+	}
+	steps := 0
+	for _, migration := range migrations {
+		if migration.Applied && migration.Version >= version {
+			steps++
+		}
+	}
+	if _, err := MigrateDown(steps); err != nil {
+		t.Fatalf("reverting migrations: %v", err)
+	}
+}
+
+// exec exécute des requêtes sur le pool d'écriture et arrête le test en cas d'erreur
+func exec(t *testing.T, queries ...string) {
+	t.Helper()
+	for _, query := range queries {
+		if _, err := DB.Exec(query); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+}
+
+// count renvoie le résultat d'une requête SELECT COUNT(*)
+func count(t *testing.T, query string) int {
+	t.Helper()
+	var n int
+	if err := DB.QueryRow(query).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// Les lignes laissées par les suppressions faites sans clés étrangères sont supprimées par la migration
+// 0021, et seulement elles
+func TestOrphanCleanupMigration(t *testing.T) {
+	openTestDB(t)
+	migrateDownTo(t, 21)
+
+	exec(t,
+		"INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')",
+		"INSERT INTO posts (id, title, content, user_id) VALUES (1, 'Titre', 'Contenu', 1)",
+		"INSERT INTO comments (id, content, user_id, post_id) VALUES (1, 'Gardé', 1, 1)",
+		"INSERT INTO post_categories (post_id, category_id) VALUES (1, 1)",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type) VALUES (1, 1, 'like')",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type) VALUES (1, 1, 'like')",
+	)
+
+	// Le pool d'écriture n'a qu'une connexion: le PRAGMA s'applique aux insertions qui suivent
+	exec(t,
+		"PRAGMA foreign_keys = OFF",
+		"INSERT INTO comments (id, content, user_id, post_id) VALUES (2, 'Orphelin', 1, 99)",
+		"INSERT INTO post_categories (post_id, category_id) VALUES (99, 1)",
+		"INSERT INTO post_tags (post_id, tag_id) VALUES (99, 1)",
+		"INSERT INTO images (filename, user_id, post_id) VALUES ('orphan.png', 1, 99)",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type) VALUES (1, 99, 'like')",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type) VALUES (1, 2, 'like')",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type) VALUES (1, 98, 'like')",
+		"PRAGMA foreign_keys = ON",
+	)
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+
+	if n := count(t, "SELECT COUNT(*) FROM pragma_foreign_key_check"); n != 0 {
+		t.Errorf("foreign key check: %d violations left", n)
+	}
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM comments":          1,
+		"SELECT COUNT(*) FROM post_categories":   1,
+		"SELECT COUNT(*) FROM post_tags":         0,
+		"SELECT COUNT(*) FROM images":            0,
+		"SELECT COUNT(*) FROM post_reactions":    1,
+		"SELECT COUNT(*) FROM comment_reactions": 1,
+	} {
+		if n := count(t, query); n != want {
+			t.Errorf("%s: got %d, want %d", query, n, want)
+		}
+	}
+}
+
+// La migration 0015 ne copie pas les réactions orphelines, que ses nouvelles tables refuseraient
+func TestReactionsMigrationSkipsOrphans(t *testing.T) {
+	openTestDB(t)
+	migrateDownTo(t, 15)
+
+	exec(t,
+		"INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')",
+		"INSERT INTO posts (id, title, content, user_id) VALUES (1, 'Titre', 'Contenu', 1)",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type) VALUES (1, 1, 'like')",
+		"PRAGMA foreign_keys = OFF",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type) VALUES (1, 99, 'like')",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type) VALUES (1, 99, 'like')",
+		"PRAGMA foreign_keys = ON",
+	)
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM post_reactions"); n != 1 {
+		t.Errorf("post reactions: got %d, want 1", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM comment_reactions"); n != 0 {
+		t.Errorf("comment reactions: got %d, want 0", n)
+	}
+}
+
+// La migration 0022 garde la plus récente des réactions opposées d'un utilisateur et recalcule les compteurs
+func TestExclusiveScoreReactionsMigration(t *testing.T) {
+	openTestDB(t)
+	migrateDownTo(t, 22)
+
+	exec(t,
+		"INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')",
+		"INSERT INTO users (id, email, username, password) VALUES (2, 'b@example.com', 'bob', 'x')",
+		"INSERT INTO posts (id, title, content, user_id) VALUES (1, 'Titre', 'Contenu', 1)",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type, created_at) VALUES (1, 1, 'like', '2024-01-01 10:00:00')",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type, created_at) VALUES (1, 1, 'dislike', '2024-01-02 10:00:00')",
+		"INSERT INTO post_reactions (user_id, post_id, reaction_type, created_at) VALUES (2, 1, 'like', '2024-01-01 10:00:00')",
+		"INSERT INTO comments (id, content, user_id, post_id) VALUES (1, 'Commentaire', 1, 1)",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type, created_at) VALUES (2, 1, 'dislike', '2024-01-01 10:00:00')",
+		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type, created_at) VALUES (2, 1, 'like', '2024-01-03 10:00:00')",
+	)
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM post_reactions WHERE user_id = 1 AND reaction_type = 'dislike'": 1,
+		"SELECT COUNT(*) FROM post_reactions WHERE user_id = 1 AND reaction_type = 'like'":    0,
+		"SELECT COUNT(*) FROM post_reactions WHERE user_id = 2":                               1,
+		"SELECT like_count FROM posts WHERE id = 1":                                           1,
+		"SELECT dislike_count FROM posts WHERE id = 1":                                        1,
+		"SELECT COUNT(*) FROM comment_reactions WHERE reaction_type = 'like'":                 1,
+		"SELECT like_count + dislike_count FROM comments WHERE id = 1":                        1,
+	} {
+		if n := count(t, query); n != want {
+			t.Errorf("%s: got %d, want %d", query, n, want)
+		}
+	}
+}
+
+// La migration 0023 garde le niveau "Membre" aux comptes inscrits avant elle, pas aux suivants
+func TestGrandfatheredTrustMigration(t *testing.T) {
+	openTestDB(t)
+	migrateDownTo(t, 23)
+
+	exec(t, "INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')")
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	exec(t, "INSERT INTO users (id, email, username, password) VALUES (2, 'b@example.com', 'bob', 'x')")
+
+	for query, want := range map[string]int{
+		"SELECT min_trust_level FROM users WHERE id = 1": 1,
+		"SELECT min_trust_level FROM users WHERE id = 2": 0,
+	} {
+		if n := count(t, query); n != want {
+			t.Errorf("%s: got %d, want %d", query, n, want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+-- Images uploadées par les utilisateurs, éventuellement associées à un post
+
+CREATE TABLE IF NOT EXISTS images (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	filename TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	post_id INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL
+);
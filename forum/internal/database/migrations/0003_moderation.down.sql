@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS pending_post_categories;
+DROP TABLE IF EXISTS pending_posts;
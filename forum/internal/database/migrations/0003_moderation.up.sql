@@ -0,0 +1,37 @@
+-- Posts en attente de modération et signalements
+
+CREATE TABLE IF NOT EXISTS pending_posts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	status TEXT DEFAULT 'pending',
+	moderator_id INTEGER,
+	reason TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (moderator_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS pending_post_categories (
+	post_id INTEGER NOT NULL,
+	category_id INTEGER NOT NULL,
+	PRIMARY KEY (post_id, category_id),
+	FOREIGN KEY (post_id) REFERENCES pending_posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS reports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	type TEXT NOT NULL,
+	content_id INTEGER NOT NULL,
+	reporter_id INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	status TEXT DEFAULT 'pending',
+	admin_id INTEGER,
+	admin_response TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (reporter_id) REFERENCES users(id),
+	FOREIGN KEY (admin_id) REFERENCES users(id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE comments DROP COLUMN dislike_count;
+ALTER TABLE comments DROP COLUMN like_count;
+ALTER TABLE posts DROP COLUMN comment_count;
+ALTER TABLE posts DROP COLUMN dislike_count;
+ALTER TABLE posts DROP COLUMN like_count;
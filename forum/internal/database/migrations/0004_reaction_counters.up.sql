@@ -0,0 +1,16 @@
+-- Compteurs dénormalisés des réactions et des commentaires, remplis à partir des données existantes
+
+ALTER TABLE posts ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN dislike_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN comment_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN dislike_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts SET
+	like_count = (SELECT COUNT(*) FROM post_reactions WHERE post_id = posts.id AND reaction_type = 'like'),
+	dislike_count = (SELECT COUNT(*) FROM post_reactions WHERE post_id = posts.id AND reaction_type = 'dislike'),
+	comment_count = (SELECT COUNT(*) FROM comments WHERE post_id = posts.id);
+
+UPDATE comments SET
+	like_count = (SELECT COUNT(*) FROM comment_reactions WHERE comment_id = comments.id AND reaction_type = 'like'),
+	dislike_count = (SELECT COUNT(*) FROM comment_reactions WHERE comment_id = comments.id AND reaction_type = 'dislike');
@@ -0,0 +1,11 @@
+-- Correspondance entre les identifiants d'une source importée et ceux du forum
+-- Elle rend les imports idempotents: un élément déjà importé n'est jamais recréé
+
+CREATE TABLE IF NOT EXISTS import_map (
+	source TEXT NOT NULL,      -- Nom de la source, par exemple 'phpbb'
+	kind TEXT NOT NULL,        -- 'category', 'user', 'post' ou 'comment'
+	source_id INTEGER NOT NULL,
+	local_id INTEGER NOT NULL,
+	imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (source, kind, source_id)
+);
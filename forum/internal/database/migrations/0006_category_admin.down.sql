@@ -0,0 +1,4 @@
+ALTER TABLE categories DROP COLUMN post_permission;
+ALTER TABLE categories DROP COLUMN archived;
+ALTER TABLE categories DROP COLUMN color;
+ALTER TABLE categories DROP COLUMN position;
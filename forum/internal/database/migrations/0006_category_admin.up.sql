@@ -0,0 +1,9 @@
+-- Réglages des catégories modifiables par les administrateurs: ordre, couleur, archivage et droits de publication
+
+ALTER TABLE categories ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN color TEXT NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN post_permission TEXT NOT NULL DEFAULT 'open'; -- 'open', 'moderators' ou 'readonly'
+
+-- Les catégories existantes gardent l'ordre de leur création
+UPDATE categories SET position = id;
@@ -0,0 +1,7 @@
+-- Arbre des catégories: une catégorie peut être rangée dans une catégorie parente
+-- Pas de clé étrangère: SQLite ne sait pas supprimer une colonne qui en porte une, et la cohérence
+-- (parent existant, pas de cycle) est vérifiée par l'application
+
+ALTER TABLE categories ADD COLUMN parent_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
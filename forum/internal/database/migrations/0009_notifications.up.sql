@@ -0,0 +1,20 @@
+-- Notifications des utilisateurs, par exemple quand quelqu'un les mentionne avec @nom
+-- comment_id est NULL pour une mention dans le texte d'un post
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	actor_id INTEGER,
+	type TEXT NOT NULL,
+	post_id INTEGER NOT NULL,
+	comment_id INTEGER,
+	is_read BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, is_read, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_post ON notifications(post_id);
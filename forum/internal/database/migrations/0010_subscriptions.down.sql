@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS subscriptions_user_delete;
+DROP TRIGGER IF EXISTS subscriptions_category_delete;
+DROP TRIGGER IF EXISTS subscriptions_post_delete;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,35 @@
+-- Abonnements des utilisateurs à un post, à une catégorie (avec ses sous-catégories) ou à un autre utilisateur
+-- target_id désigne une ligne de posts, categories ou users selon target_type: les triggers
+-- remplacent les clés étrangères pour supprimer les abonnements d'une cible supprimée
+-- Un abonnement muet ne produit pas de notification et ses posts ne sont pas dans le fil
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	target_type TEXT NOT NULL CHECK (target_type IN ('post', 'category', 'user')),
+	target_id INTEGER NOT NULL,
+	muted BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, target_type, target_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_target ON subscriptions(target_type, target_id);
+
+CREATE TRIGGER IF NOT EXISTS subscriptions_post_delete AFTER DELETE ON posts BEGIN
+	DELETE FROM subscriptions WHERE target_type = 'post' AND target_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS subscriptions_category_delete AFTER DELETE ON categories BEGIN
+	DELETE FROM subscriptions WHERE target_type = 'category' AND target_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS subscriptions_user_delete AFTER DELETE ON users BEGIN
+	DELETE FROM subscriptions WHERE target_type = 'user' AND target_id = old.id;
+END;
+
+-- Les auteurs et les commentateurs existants suivent leurs posts
+INSERT OR IGNORE INTO subscriptions (user_id, target_type, target_id)
+SELECT user_id, 'post', id FROM posts;
+INSERT OR IGNORE INTO subscriptions (user_id, target_type, target_id)
+SELECT DISTINCT user_id, 'post', post_id FROM comments;
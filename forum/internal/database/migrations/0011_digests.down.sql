@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_digest;
+ALTER TABLE users DROP COLUMN last_digest_at;
+ALTER TABLE users DROP COLUMN digest_frequency;
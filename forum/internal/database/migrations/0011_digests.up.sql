@@ -0,0 +1,7 @@
+-- Résumés d'activité envoyés par email: fréquence choisie par chaque utilisateur et date du dernier envoi
+-- last_digest_at est la fin de la période couverte par le dernier résumé, le suivant repart de là
+
+ALTER TABLE users ADD COLUMN digest_frequency TEXT NOT NULL DEFAULT 'none'; -- 'none', 'daily' ou 'weekly'
+ALTER TABLE users ADD COLUMN last_digest_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_digest ON users(digest_frequency, last_digest_at);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS settings;
+DROP INDEX IF EXISTS idx_user_blocks_blocked;
+DROP TABLE IF EXISTS user_blocks;
+DROP INDEX IF EXISTS idx_messages_created;
+DROP INDEX IF EXISTS idx_messages_conversation;
+DROP TABLE IF EXISTS messages;
+DROP INDEX IF EXISTS idx_conversation_members_user;
+DROP TABLE IF EXISTS conversation_members;
+DROP TABLE IF EXISTS conversations;
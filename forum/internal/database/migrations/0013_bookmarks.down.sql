@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS bookmarks_comment_delete;
+DROP TRIGGER IF EXISTS bookmarks_post_delete;
+DROP INDEX IF EXISTS idx_bookmarks_folder;
+DROP INDEX IF EXISTS idx_bookmarks_target;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS bookmark_folders;
@@ -0,0 +1,36 @@
+-- Signets privés des utilisateurs sur des posts et des commentaires, rangés dans des dossiers facultatifs
+-- target_id désigne une ligne de posts ou de comments selon target_type: les triggers
+-- remplacent les clés étrangères pour supprimer les signets d'un contenu supprimé
+
+CREATE TABLE IF NOT EXISTS bookmark_folders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS bookmarks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+	target_id INTEGER NOT NULL,
+	folder_id INTEGER,
+	note TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, target_type, target_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (folder_id) REFERENCES bookmark_folders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_target ON bookmarks(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_folder ON bookmarks(folder_id);
+
+CREATE TRIGGER IF NOT EXISTS bookmarks_post_delete AFTER DELETE ON posts BEGIN
+	DELETE FROM bookmarks WHERE target_type = 'post' AND target_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS bookmarks_comment_delete AFTER DELETE ON comments BEGIN
+	DELETE FROM bookmarks WHERE target_type = 'comment' AND target_id = old.id;
+END;
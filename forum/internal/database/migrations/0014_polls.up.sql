@@ -0,0 +1,40 @@
+-- Sondages attachés aux posts, avec leurs options et leurs votes
+-- Un sondage appartient soit à un post publié, soit à un post en attente de modération:
+-- l'approbation le rattache au post créé
+
+CREATE TABLE IF NOT EXISTS polls (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER UNIQUE,
+	pending_post_id INTEGER UNIQUE,
+	question TEXT NOT NULL,
+	multiple_choice BOOLEAN NOT NULL DEFAULT 0,
+	anonymous BOOLEAN NOT NULL DEFAULT 1,
+	allow_vote_change BOOLEAN NOT NULL DEFAULT 1,
+	closes_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	CHECK ((post_id IS NULL) != (pending_post_id IS NULL)),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (pending_post_id) REFERENCES pending_posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	poll_id INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	label TEXT NOT NULL,
+	FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+	poll_id INTEGER NOT NULL,
+	option_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (option_id, user_id),
+	FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+	FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_options_poll ON poll_options(poll_id, position);
+CREATE INDEX IF NOT EXISTS idx_poll_votes_user ON poll_votes(poll_id, user_id);
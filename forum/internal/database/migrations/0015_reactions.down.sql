@@ -0,0 +1,46 @@
+DROP INDEX IF EXISTS idx_comment_reactions_comment;
+DROP INDEX IF EXISTS idx_post_reactions_post;
+
+-- Une seule réaction par utilisateur et par contenu: seuls les likes et dislikes sont gardés,
+-- le like l'emporte quand l'utilisateur a posé les deux
+CREATE TABLE post_reactions_old (
+	user_id INTEGER NOT NULL,
+	post_id INTEGER NOT NULL,
+	reaction_type TEXT NOT NULL,  -- 'like' ou 'dislike'
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, post_id),
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+INSERT OR IGNORE INTO post_reactions_old (user_id, post_id, reaction_type, created_at)
+	SELECT user_id, post_id, reaction_type, created_at FROM post_reactions
+	WHERE reaction_type IN ('like', 'dislike')
+	ORDER BY reaction_type DESC;
+DROP TABLE post_reactions;
+ALTER TABLE post_reactions_old RENAME TO post_reactions;
+
+CREATE TABLE comment_reactions_old (
+	user_id INTEGER NOT NULL,
+	comment_id INTEGER NOT NULL,
+	reaction_type TEXT NOT NULL,  -- 'like' ou 'dislike'
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, comment_id),
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+INSERT OR IGNORE INTO comment_reactions_old (user_id, comment_id, reaction_type, created_at)
+	SELECT user_id, comment_id, reaction_type, created_at FROM comment_reactions
+	WHERE reaction_type IN ('like', 'dislike')
+	ORDER BY reaction_type DESC;
+DROP TABLE comment_reactions;
+ALTER TABLE comment_reactions_old RENAME TO comment_reactions;
+
+UPDATE posts SET
+	like_count = (SELECT COUNT(*) FROM post_reactions WHERE post_id = posts.id AND reaction_type = 'like'),
+	dislike_count = (SELECT COUNT(*) FROM post_reactions WHERE post_id = posts.id AND reaction_type = 'dislike');
+
+UPDATE comments SET
+	like_count = (SELECT COUNT(*) FROM comment_reactions WHERE comment_id = comments.id AND reaction_type = 'like'),
+	dislike_count = (SELECT COUNT(*) FROM comment_reactions WHERE comment_id = comments.id AND reaction_type = 'dislike');
+
+DROP TABLE IF EXISTS reactions;
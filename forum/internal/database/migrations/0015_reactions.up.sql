@@ -0,0 +1,57 @@
+-- Jeu de réactions configurable par les administrateurs, à la place du like/dislike codé en dur
+-- Un utilisateur peut poser plusieurs réactions différentes sur un même contenu: la clé primaire
+-- des tables de réactions inclut donc le type de réaction. Les likes et dislikes existants sont gardés
+-- tels quels, leur type désigne les réactions 'like' et 'dislike' créées ici.
+-- Les compteurs like_count et dislike_count comptent désormais les réactions qui ajoutent
+-- ou retirent un point au score (colonne score de reactions), ce qui ne change rien aux données existantes.
+-- Les réactions laissées par des posts, commentaires ou utilisateurs supprimés ne sont pas copiées:
+-- les nouvelles tables vérifient leurs clés étrangères
+
+CREATE TABLE IF NOT EXISTS reactions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,                -- Identifiant stocké dans reaction_type
+	emoji TEXT NOT NULL,
+	label TEXT NOT NULL,
+	score INTEGER NOT NULL DEFAULT 0 CHECK (score IN (-1, 0, 1)), -- 0 si la réaction ne compte pas dans le score
+	position INTEGER NOT NULL DEFAULT 0,
+	archived BOOLEAN NOT NULL DEFAULT 0,      -- Une réaction archivée ne peut plus être ajoutée
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO reactions (name, emoji, label, score, position) VALUES
+	('like', '👍', 'J''aime', 1, 1),
+	('dislike', '👎', 'Je n''aime pas', -1, 2);
+
+CREATE TABLE post_reactions_new (
+	user_id INTEGER NOT NULL,
+	post_id INTEGER NOT NULL,
+	reaction_type TEXT NOT NULL,  -- reactions.name
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, post_id, reaction_type),
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+INSERT INTO post_reactions_new (user_id, post_id, reaction_type, created_at)
+	SELECT user_id, post_id, reaction_type, created_at FROM post_reactions
+	WHERE post_id IN (SELECT id FROM posts) AND user_id IN (SELECT id FROM users);
+DROP TABLE post_reactions;
+ALTER TABLE post_reactions_new RENAME TO post_reactions;
+
+CREATE TABLE comment_reactions_new (
+	user_id INTEGER NOT NULL,
+	comment_id INTEGER NOT NULL,
+	reaction_type TEXT NOT NULL,  -- reactions.name
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, comment_id, reaction_type),
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+INSERT INTO comment_reactions_new (user_id, comment_id, reaction_type, created_at)
+	SELECT user_id, comment_id, reaction_type, created_at FROM comment_reactions
+	WHERE comment_id IN (SELECT id FROM comments) AND user_id IN (SELECT id FROM users);
+DROP TABLE comment_reactions;
+ALTER TABLE comment_reactions_new RENAME TO comment_reactions;
+
+-- Les listes "qui a réagi" et les compteurs par réaction sont lus par contenu
+CREATE INDEX IF NOT EXISTS idx_post_reactions_post ON post_reactions(post_id, reaction_type);
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_comment ON comment_reactions(comment_id, reaction_type);
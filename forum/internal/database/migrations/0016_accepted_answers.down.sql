@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS posts_accepted_comment_delete;
+DROP INDEX IF EXISTS idx_posts_accepted;
+ALTER TABLE posts DROP COLUMN accepted_at;
+ALTER TABLE posts DROP COLUMN accepted_comment_id;
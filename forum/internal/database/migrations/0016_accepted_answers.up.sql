@@ -0,0 +1,12 @@
+-- Réponse acceptée d'un post: un commentaire choisi par l'auteur du post ou un modérateur
+-- Un post qui a une réponse acceptée est résolu. accepted_at date l'acceptation
+
+ALTER TABLE posts ADD COLUMN accepted_comment_id INTEGER;
+ALTER TABLE posts ADD COLUMN accepted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_posts_accepted ON posts(accepted_comment_id);
+
+-- La suppression de la réponse acceptée rend le post de nouveau non résolu
+CREATE TRIGGER IF NOT EXISTS posts_accepted_comment_delete AFTER DELETE ON comments BEGIN
+	UPDATE posts SET accepted_comment_id = NULL, accepted_at = NULL WHERE accepted_comment_id = old.id;
+END;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_badges;
+DROP INDEX IF EXISTS idx_pending_posts_user;
+ALTER TABLE pending_posts DROP COLUMN tags;
+DROP INDEX IF EXISTS idx_reports_content_user;
+ALTER TABLE reports DROP COLUMN content_user_id;
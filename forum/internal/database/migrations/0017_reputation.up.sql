@@ -0,0 +1,27 @@
+-- Réputation et badges des utilisateurs
+-- La réputation est calculée à partir des réactions reçues, des réponses acceptées et des décisions
+-- de modération: les signalements retiennent l'auteur du contenu signalé, qui disparaît avec lui
+-- quand le signalement est approuvé. Les badges gagnés sont conservés avec leur date d'attribution
+
+ALTER TABLE reports ADD COLUMN content_user_id INTEGER;
+
+UPDATE reports SET content_user_id = CASE type
+	WHEN 'post' THEN (SELECT user_id FROM posts WHERE id = reports.content_id)
+	WHEN 'comment' THEN (SELECT user_id FROM comments WHERE id = reports.content_id)
+	WHEN 'message' THEN (SELECT user_id FROM messages WHERE id = reports.content_id)
+END;
+
+CREATE INDEX IF NOT EXISTS idx_reports_content_user ON reports(content_user_id);
+
+-- Les posts des nouveaux membres passent par la modération avec leurs tags, séparés par des virgules
+ALTER TABLE pending_posts ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_pending_posts_user ON pending_posts(user_id);
+
+CREATE TABLE IF NOT EXISTS user_badges (
+	user_id INTEGER NOT NULL,
+	badge TEXT NOT NULL,
+	awarded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, badge),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS announcements;
+DROP INDEX IF EXISTS idx_posts_pinned;
+ALTER TABLE posts DROP COLUMN locked_at;
+ALTER TABLE posts DROP COLUMN pinned_until;
+ALTER TABLE posts DROP COLUMN pinned_category_id;
+ALTER TABLE posts DROP COLUMN pinned_at;
@@ -0,0 +1,22 @@
+-- Posts épinglés, discussions verrouillées et annonces du site, gérés par les modérateurs
+-- Un post épinglé passe en tête de la liste de tous les posts (pinned_category_id NULL) ou de
+-- la liste d'une catégorie, jusqu'à pinned_until s'il est renseigné. Un post verrouillé n'accepte
+-- plus de nouveaux commentaires ni de réactions. Les annonces sont affichées en haut de toutes
+-- les pages jusqu'à expires_at s'il est renseigné
+
+ALTER TABLE posts ADD COLUMN pinned_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN pinned_category_id INTEGER;
+ALTER TABLE posts ADD COLUMN pinned_until TIMESTAMP;
+ALTER TABLE posts ADD COLUMN locked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_posts_pinned ON posts(pinned_at);
+
+CREATE TABLE IF NOT EXISTS announcements (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message TEXT NOT NULL,                    -- Markdown
+	level TEXT NOT NULL DEFAULT 'info' CHECK (level IN ('info', 'warning')),
+	user_id INTEGER,                          -- Modérateur qui a publié l'annonce
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP,                     -- NULL pour une annonce sans fin
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_drafts_publish_at;
+DROP INDEX IF EXISTS idx_drafts_user_post;
+DROP INDEX IF EXISTS idx_drafts_user;
+DROP TABLE IF EXISTS drafts;
@@ -0,0 +1,25 @@
+-- Brouillons des posts, enregistrés automatiquement pendant la saisie
+-- Un brouillon prépare un nouveau post (post_id NULL) ou la modification d'un post publié: un
+-- utilisateur a au plus un brouillon par post modifié. Un brouillon de nouveau post peut être
+-- programmé: il est publié à publish_at, après vérification des droits de publication à ce moment.
+-- publish_error garde la raison d'une publication programmée refusée, le brouillon est alors conservé
+
+CREATE TABLE IF NOT EXISTS drafts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	post_id INTEGER,
+	title TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL DEFAULT '',
+	categories TEXT NOT NULL DEFAULT '',       -- IDs des catégories, séparés par des virgules
+	tags TEXT NOT NULL DEFAULT '',             -- Tags normalisés, séparés par des virgules
+	publish_at TIMESTAMP,
+	publish_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_user ON drafts(user_id, updated_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_drafts_user_post ON drafts(user_id, post_id) WHERE post_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_drafts_publish_at ON drafts(publish_at) WHERE publish_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Droits de lecture des catégories: une catégorie peut être réservée aux membres connectés ou à l'équipe
+-- Comme les droits de publication, ils s'appliquent aussi à toutes les sous-catégories
+
+ALTER TABLE categories ADD COLUMN read_permission TEXT NOT NULL DEFAULT 'everyone'; -- 'everyone', 'members' ou 'staff'
@@ -0,0 +1,2 @@
+-- Les lignes orphelines supprimées ne peuvent pas être restaurées, et la base reste valide sans elles
+SELECT 1;
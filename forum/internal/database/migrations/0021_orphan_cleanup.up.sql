@@ -0,0 +1,11 @@
+-- Nettoyage des lignes orphelines laissées par les suppressions faites quand les clés étrangères
+-- n'étaient pas encore activées: les ON DELETE CASCADE ne s'appliquaient pas, et les commentaires,
+-- catégories, tags, images et réactions des posts supprimés sont restés dans la base
+
+DELETE FROM comments WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_categories WHERE post_id NOT IN (SELECT id FROM posts)
+	OR category_id NOT IN (SELECT id FROM categories);
+DELETE FROM post_tags WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM images WHERE post_id IS NOT NULL AND post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_reactions WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM comment_reactions WHERE comment_id NOT IN (SELECT id FROM comments);
@@ -0,0 +1,2 @@
+-- Les réactions supprimées ne peuvent pas être restaurées: annuler cette migration ne change rien
+SELECT 1;
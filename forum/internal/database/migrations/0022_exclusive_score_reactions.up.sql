@@ -0,0 +1,37 @@
+-- Une réaction qui compte dans le score remplace désormais les réactions de score opposé du même
+-- utilisateur sur le même contenu. Quand un utilisateur a déjà posé des réactions opposées, comme un like
+-- et un dislike, seule la plus récente est gardée, puis les compteurs like_count et dislike_count sont recalculés
+
+DELETE FROM post_reactions WHERE rowid IN (
+	SELECT x.rowid FROM post_reactions x
+	JOIN reactions r ON r.name = x.reaction_type
+	WHERE r.score != 0 AND EXISTS (
+		SELECT 1 FROM post_reactions y
+		JOIN reactions s ON s.name = y.reaction_type
+		WHERE y.user_id = x.user_id AND y.post_id = x.post_id AND s.score = -r.score
+		AND (y.created_at > x.created_at OR (y.created_at = x.created_at AND y.rowid > x.rowid))
+	)
+);
+
+DELETE FROM comment_reactions WHERE rowid IN (
+	SELECT x.rowid FROM comment_reactions x
+	JOIN reactions r ON r.name = x.reaction_type
+	WHERE r.score != 0 AND EXISTS (
+		SELECT 1 FROM comment_reactions y
+		JOIN reactions s ON s.name = y.reaction_type
+		WHERE y.user_id = x.user_id AND y.comment_id = x.comment_id AND s.score = -r.score
+		AND (y.created_at > x.created_at OR (y.created_at = x.created_at AND y.rowid > x.rowid))
+	)
+);
+
+UPDATE posts SET
+	like_count = (SELECT COUNT(*) FROM post_reactions x JOIN reactions r ON r.name = x.reaction_type
+		WHERE x.post_id = posts.id AND r.score > 0),
+	dislike_count = (SELECT COUNT(*) FROM post_reactions x JOIN reactions r ON r.name = x.reaction_type
+		WHERE x.post_id = posts.id AND r.score < 0);
+
+UPDATE comments SET
+	like_count = (SELECT COUNT(*) FROM comment_reactions x JOIN reactions r ON r.name = x.reaction_type
+		WHERE x.comment_id = comments.id AND r.score > 0),
+	dislike_count = (SELECT COUNT(*) FROM comment_reactions x JOIN reactions r ON r.name = x.reaction_type
+		WHERE x.comment_id = comments.id AND r.score < 0);
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN min_trust_level;
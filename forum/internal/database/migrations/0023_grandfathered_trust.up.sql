@@ -0,0 +1,7 @@
+-- Niveau de confiance minimal des comptes
+-- Les membres inscrits avant la réputation n'ont pas de points: ils gardent le niveau "Membre"
+-- pour ne pas voir leurs posts passer par la file de modération. Les nouveaux comptes partent de 0
+
+ALTER TABLE users ADD COLUMN min_trust_level INTEGER NOT NULL DEFAULT 0;
+
+UPDATE users SET min_trust_level = 1;
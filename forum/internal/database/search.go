@@ -0,0 +1,31 @@
+package database
+
+import "strings"
+
+// SearchEnabled indique si les index de recherche plein texte (FTS5) sont disponibles
+// Le module FTS5 n'est compilé dans go-sqlite3 qu'avec le tag de build "sqlite_fts5"
+var SearchEnabled bool
+
+// searchIndexReady indique si les index FTS5 créés par la migration 0024 existent
+// et si SQLite sait les lire
+func searchIndexReady() (bool, error) {
+	var existing int
+	err := DB.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('posts_fts', 'comments_fts')",
+	).Scan(&existing)
+	if err != nil || existing < 2 {
+		return false, err
+	}
+
+	// Une base indexée peut être ouverte par un binaire compilé sans FTS5
+	_, err = DB.Exec("SELECT rowid FROM posts_fts LIMIT 0")
+	if isMissingFTS5(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// isMissingFTS5 vérifie si l'erreur vient d'un SQLite compilé sans FTS5
+func isMissingFTS5(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such module: fts5")
+}
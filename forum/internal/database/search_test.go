@@ -0,0 +1,39 @@
+//go:build sqlite_fts5
+
+package database
+
+import "testing"
+
+// La migration 0024 indexe les posts et les commentaires déjà présents, et se défait sans erreur
+func TestSearchIndexMigration(t *testing.T) {
+	openTestDB(t)
+	if ready, err := searchIndexReady(); err != nil || !ready {
+		t.Fatalf("search index after the migrations: ready %v, error %v", ready, err)
+	}
+
+	migrateDownTo(t, 24)
+	if ready, err := searchIndexReady(); err != nil || ready {
+		t.Fatalf("search index after reverting 0024: ready %v, error %v", ready, err)
+	}
+	exec(t,
+		"INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')",
+		"INSERT INTO posts (id, title, content, user_id) VALUES (1, 'Migration', 'Contenu indexé', 1)",
+		"INSERT INTO comments (id, content, user_id, post_id) VALUES (1, 'Réponse déjà là', 1, 1)",
+	)
+
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM posts_fts WHERE posts_fts MATCH 'indexe'"); n != 1 {
+		t.Errorf("existing posts indexed: %d, want 1", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM comments_fts WHERE comments_fts MATCH 'deja'"); n != 1 {
+		t.Errorf("existing comments indexed: %d, want 1", n)
+	}
+
+	// Les triggers suivent les écritures faites après la migration
+	exec(t, "UPDATE posts SET content = 'Autre contenu' WHERE id = 1")
+	if n := count(t, "SELECT COUNT(*) FROM posts_fts WHERE posts_fts MATCH 'indexe'"); n != 0 {
+		t.Errorf("updated post still indexed with its old content: %d", n)
+	}
+}
@@ -0,0 +1,170 @@
+// Package digest envoie par email les résumés d'activité du forum: nouveaux posts des catégories
+// suivies, réponses aux posts de l'utilisateur et mentions depuis le résumé précédent
+package digest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"forum/internal/mail"
+	"forum/internal/models"
+	"forum/internal/store"
+	htmltemplate "html/template"
+	"log"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+)
+
+// TemplateDir est le dossier des templates des emails, digest.html et digest.txt
+const TemplateDir = "templates/email"
+
+// Sender prépare et envoie les résumés dus
+type Sender struct {
+	Digests store.DigestStore
+	Mailer  mail.Mailer
+	BaseURL string // Adresse publique du forum, sans / final, pour les liens des emails
+	Secret  []byte // Clé qui signe les liens de désabonnement
+}
+
+// NewSender crée un Sender
+func NewSender(digests store.DigestStore, mailer mail.Mailer, baseURL string, secret []byte) *Sender {
+	return &Sender{Digests: digests, Mailer: mailer, BaseURL: baseURL, Secret: secret}
+}
+
+// emailData est passé aux deux templates d'un résumé
+type emailData struct {
+	Username       string
+	Frequency      string
+	BaseURL        string
+	Digest         *models.Digest
+	UnsubscribeURL string
+	SettingsURL    string
+}
+
+// templates regroupe les versions HTML et texte d'un email
+type templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+func loadTemplates() (*templates, error) {
+	html, err := htmltemplate.ParseFiles(filepath.Join(TemplateDir, "digest.html"))
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(TemplateDir, "digest.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &templates{html: html, text: text}, nil
+}
+
+// subject renvoie le sujet d'un résumé selon sa fréquence
+func subject(frequency string) string {
+	if frequency == models.DigestWeekly {
+		return "Votre résumé hebdomadaire du forum"
+	}
+	return "Votre résumé quotidien du forum"
+}
+
+// Run envoie les résumés dus à l'instant now et renvoie le nombre d'emails envoyés
+// Un résumé vide n'est pas envoyé mais sa période est close. Un envoi raté n'empêche pas les autres:
+// la période de cet utilisateur reste ouverte et son résumé sera retenté au passage suivant
+func (s *Sender) Run(ctx context.Context, now time.Time) (int, error) {
+	tmpl, err := loadTemplates()
+	if err != nil {
+		return 0, err
+	}
+
+	recipients, err := s.Digests.GetDueDigests(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var failures []error
+	for _, recipient := range recipients {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		delivered, err := s.send(ctx, tmpl, recipient, now)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("digest for user %d: %w", recipient.UserID, err))
+			continue
+		}
+		if delivered {
+			sent++
+		}
+	}
+	return sent, errors.Join(failures...)
+}
+
+// send envoie le résumé d'un utilisateur s'il n'est pas vide, puis clôt sa période
+func (s *Sender) send(ctx context.Context, tmpl *templates, recipient *models.DigestRecipient, now time.Time) (bool, error) {
+	digest, err := s.Digests.GetDigest(ctx, recipient.UserID, recipient.Since, now)
+	if err != nil {
+		return false, err
+	}
+
+	delivered := false
+	if !digest.IsEmpty() {
+		unsubscribeURL := UnsubscribeURL(s.BaseURL, s.Secret, recipient.UserID)
+		data := emailData{
+			Username:       recipient.Username,
+			Frequency:      recipient.Frequency,
+			BaseURL:        s.BaseURL,
+			Digest:         digest,
+			UnsubscribeURL: unsubscribeURL,
+			SettingsURL:    s.BaseURL + "/notifications",
+		}
+
+		var html, text bytes.Buffer
+		if err := tmpl.html.Execute(&html, data); err != nil {
+			return false, err
+		}
+		if err := tmpl.text.Execute(&text, data); err != nil {
+			return false, err
+		}
+
+		err = s.Mailer.Send(ctx, &mail.Message{
+			To:      recipient.Email,
+			Subject: subject(recipient.Frequency),
+			Text:    text.String(),
+			HTML:    html.String(),
+			// Désabonnement en un clic depuis le client mail (RFC 8058)
+			Headers: map[string]string{
+				"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		delivered = true
+	}
+
+	return delivered, s.Digests.MarkDigestSent(ctx, recipient.UserID, now)
+}
+
+// Schedule envoie les résumés dus toutes les "interval" jusqu'à l'annulation du contexte
+func (s *Sender) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sent, err := s.Run(ctx, now)
+			if err != nil {
+				log.Printf("Error sending email digests: %v", err)
+			}
+			if sent > 0 {
+				log.Printf("Sent %d email digests", sent)
+			}
+		}
+	}
+}
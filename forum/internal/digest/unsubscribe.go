@@ -0,0 +1,36 @@
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+)
+
+// unsubscribePurpose distingue les signatures de désabonnement d'autres signatures faites avec la même clé
+const unsubscribePurpose = "digest-unsubscribe:"
+
+// UnsubscribeSignature signe l'ID d'un utilisateur avec la clé du serveur
+// La signature ne change pas d'un résumé à l'autre: tous les liens envoyés restent valides
+func UnsubscribeSignature(secret []byte, userID int) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsubscribePurpose + strconv.Itoa(userID)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyUnsubscribe vérifie la signature d'un lien de désabonnement, en temps constant
+func VerifyUnsubscribe(secret []byte, userID int, signature string) bool {
+	if len(secret) == 0 || userID <= 0 {
+		return false
+	}
+	return hmac.Equal([]byte(UnsubscribeSignature(secret, userID)), []byte(signature))
+}
+
+// UnsubscribeURL renvoie le lien qui désactive les résumés d'un utilisateur en un clic, sans connexion
+func UnsubscribeURL(baseURL string, secret []byte, userID int) string {
+	query := url.Values{}
+	query.Set("user", strconv.Itoa(userID))
+	query.Set("sig", UnsubscribeSignature(secret, userID))
+	return baseURL + "/digest/unsubscribe?" + query.Encode()
+}
@@ -0,0 +1,183 @@
+// Package events diffuse en temps réel les événements du forum (nouveaux commentaires, réactions,
+// notifications, file de modération) aux pages ouvertes, à travers un hub de publication en mémoire
+//
+// Chaque événement est publié sur un sujet: un post, un utilisateur ou la file de modération.
+// Les derniers événements sont gardés pour qu'un client qui se reconnecte avec Last-Event-ID
+// reçoive ceux qu'il a manqués. Un client trop lent est déconnecté plutôt que de bloquer les autres.
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tailles par défaut de l'historique du hub et de la file de chaque abonné
+const (
+	DefaultHistorySize = 256
+	DefaultBufferSize  = 32
+)
+
+// Types d'événements
+const (
+	TypeComment      = "comment"      // Nouveau commentaire sur un post
+	TypeReaction     = "reaction"     // Compteurs de réactions d'un post ou d'un commentaire
+	TypeNotification = "notification" // Nombre de notifications non lues d'un utilisateur
+	TypePending      = "pending"      // Nombre de posts en attente de modération
+	TypeMessage      = "message"      // Nouveau message privé ou conversation lue
+	TypeResync       = "resync"       // Des événements ont été perdus: le client doit recharger ses données
+)
+
+// ModerationTopic est le sujet de la file des posts en attente, réservé aux modérateurs
+const ModerationTopic = "moderation"
+
+// PostTopic renvoie le sujet des événements d'un post: commentaires et réactions
+func PostTopic(postID int) string {
+	return "post:" + strconv.Itoa(postID)
+}
+
+// UserTopic renvoie le sujet des événements d'un utilisateur: ses notifications et ses messages privés
+func UserTopic(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// Event est un événement publié sur un sujet
+type Event struct {
+	ID    string // "<époque>-<numéro>", voir Hub.Subscribe
+	Topic string
+	Type  string
+	Data  []byte // Contenu JSON
+	seq   uint64
+}
+
+// Subscription reçoit les événements des sujets choisis par un client
+type Subscription struct {
+	topics map[string]bool
+	events chan Event
+	closed bool
+}
+
+// Events renvoie le canal des événements. Il est fermé quand l'abonnement prend fin,
+// en particulier quand le client n'a pas lu assez vite
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Hub distribue les événements publiés aux abonnements en cours
+// Un Hub nil est valide: il ignore les publications
+type Hub struct {
+	mu          sync.Mutex
+	epoch       string // Change à chaque démarrage: les numéros d'un autre démarrage sont inconnus
+	seq         uint64
+	history     []Event
+	historySize int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub crée un hub qui garde les historySize derniers événements
+// et laisse au plus bufferSize événements en attente pour chaque abonné
+func NewHub(historySize, bufferSize int) *Hub {
+	return &Hub{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish envoie un événement aux abonnés d'un sujet. data est encodé en JSON
+// L'envoi ne bloque jamais: un abonné dont la file est pleine est déconnecté,
+// il se reconnectera avec Last-Event-ID et rattrapera les événements depuis l'historique
+func (h *Hub) Publish(topic, eventType string, data interface{}) error {
+	if h == nil {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event := Event{
+		ID:    h.epoch + "-" + strconv.FormatUint(h.seq, 10),
+		Topic: topic,
+		Type:  eventType,
+		Data:  payload,
+		seq:   h.seq,
+	}
+	h.history = append(h.history, event)
+	if len(h.history) > h.historySize {
+		h.history = append([]Event(nil), h.history[len(h.history)-h.historySize:]...)
+	}
+
+	for subscription := range h.subscribers {
+		if !subscription.topics[topic] {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			h.remove(subscription)
+		}
+	}
+	return nil
+}
+
+// Subscribe abonne un client à des sujets
+// Si lastEventID est donné, les événements publiés depuis sur ces sujets sont renvoyés pour être
+// envoyés en premier. complete vaut false si certains de ces événements ne sont plus disponibles:
+// le client doit alors recharger ses données
+func (h *Hub) Subscribe(topics []string, lastEventID string) (subscription *Subscription, backlog []Event, complete bool) {
+	subscription = &Subscription{
+		topics: map[string]bool{},
+		events: make(chan Event, h.bufferSize),
+	}
+	for _, topic := range topics {
+		subscription.topics[topic] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscribers[subscription] = struct{}{}
+	if lastEventID == "" {
+		return subscription, nil, true
+	}
+
+	epoch, seqStr, _ := strings.Cut(lastEventID, "-")
+	lastSeq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || epoch != h.epoch || lastSeq > h.seq {
+		return subscription, nil, false
+	}
+	// L'historique contient les numéros qui suivent directement le dernier événement reçu
+	complete = len(h.history) == 0 || h.history[0].seq <= lastSeq+1
+	for _, event := range h.history {
+		if event.seq > lastSeq && subscription.topics[event.Topic] {
+			backlog = append(backlog, event)
+		}
+	}
+	return subscription, backlog, complete
+}
+
+// Unsubscribe met fin à un abonnement et ferme son canal
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(subscription)
+}
+
+// remove retire un abonné du hub. Le verrou doit être pris
+func (h *Hub) remove(subscription *Subscription) {
+	if subscription.closed {
+		return
+	}
+	delete(h.subscribers, subscription)
+	close(subscription.events)
+	subscription.closed = true
+}
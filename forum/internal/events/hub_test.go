@@ -0,0 +1,180 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// publish publie un événement de test et renvoie son identifiant
+func publish(t *testing.T, h *Hub, topic string, n int) string {
+	t.Helper()
+	if err := h.Publish(topic, TypeComment, map[string]int{"n": n}); err != nil {
+		t.Fatalf("publishing: %v", err)
+	}
+	return h.history[len(h.history)-1].ID
+}
+
+// numbers renvoie le numéro publié dans chaque événement
+func numbers(t *testing.T, events []Event) []int {
+	t.Helper()
+	var result []int
+	for _, event := range events {
+		var data map[string]int
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			t.Fatalf("decoding %s: %v", event.Data, err)
+		}
+		result = append(result, data["n"])
+	}
+	return result
+}
+
+func TestPublishToSubscribers(t *testing.T) {
+	h := NewHub(DefaultHistorySize, DefaultBufferSize)
+	post, _, complete := h.Subscribe([]string{PostTopic(1)}, "")
+	user, _, _ := h.Subscribe([]string{UserTopic(1)}, "")
+	if !complete {
+		t.Error("a new subscription without Last-Event-ID is incomplete")
+	}
+
+	publish(t, h, PostTopic(1), 1)
+	publish(t, h, PostTopic(2), 2)
+
+	if event := <-post.Events(); event.Topic != PostTopic(1) || event.Type != TypeComment || string(event.Data) != `{"n":1}` {
+		t.Errorf("received event: %+v", event)
+	}
+	select {
+	case event := <-post.Events():
+		t.Errorf("event of another post received: %+v", event)
+	case event := <-user.Events():
+		t.Errorf("event of a post received on a user topic: %+v", event)
+	default:
+	}
+
+	h.Unsubscribe(post)
+	h.Unsubscribe(post)
+	if _, open := <-post.Events(); open {
+		t.Error("channel still open after Unsubscribe")
+	}
+	if len(h.subscribers) != 1 {
+		t.Errorf("%d subscribers left, want 1", len(h.subscribers))
+	}
+}
+
+func TestNilHubIgnoresPublications(t *testing.T) {
+	var h *Hub
+	if err := h.Publish(PostTopic(1), TypeComment, nil); err != nil {
+		t.Errorf("publishing on a nil hub: %v", err)
+	}
+}
+
+// Un client qui se reconnecte avec Last-Event-ID reçoit les événements manqués de ses sujets
+func TestLastEventIDReplay(t *testing.T) {
+	h := NewHub(DefaultHistorySize, DefaultBufferSize)
+	publish(t, h, PostTopic(1), 1)
+	last := publish(t, h, PostTopic(1), 2)
+	publish(t, h, PostTopic(1), 3)
+	publish(t, h, PostTopic(2), 4)
+	publish(t, h, PostTopic(1), 5)
+
+	_, backlog, complete := h.Subscribe([]string{PostTopic(1)}, last)
+	if !complete {
+		t.Error("replay from the history is incomplete")
+	}
+	if got := numbers(t, backlog); !reflect.DeepEqual(got, []int{3, 5}) {
+		t.Errorf("replayed events: %v, want [3 5]", got)
+	}
+
+	// Un client à jour n'a rien à rattraper
+	_, backlog, complete = h.Subscribe([]string{PostTopic(1)}, h.history[len(h.history)-1].ID)
+	if !complete || len(backlog) != 0 {
+		t.Errorf("up-to-date client: %d events, complete %v", len(backlog), complete)
+	}
+}
+
+// Quand l'historique a débordé depuis le dernier événement reçu, le client doit se resynchroniser
+func TestResyncAfterHistoryOverflow(t *testing.T) {
+	h := NewHub(3, DefaultBufferSize)
+	last := publish(t, h, PostTopic(1), 1)
+	for n := 2; n <= 6; n++ {
+		publish(t, h, PostTopic(1), n)
+	}
+	if len(h.history) != 3 {
+		t.Fatalf("history holds %d events, want 3", len(h.history))
+	}
+
+	_, backlog, complete := h.Subscribe([]string{PostTopic(1)}, last)
+	if complete {
+		t.Error("replay reported complete although events 2 and 3 were dropped")
+	}
+	if got := numbers(t, backlog); !reflect.DeepEqual(got, []int{4, 5, 6}) {
+		t.Errorf("replayed events: %v, want [4 5 6]", got)
+	}
+
+	// Le plus ancien événement gardé suit directement le dernier reçu: rien n'est perdu
+	_, backlog, complete = h.Subscribe([]string{PostTopic(1)}, h.epoch+"-3")
+	if !complete || !reflect.DeepEqual(numbers(t, backlog), []int{4, 5, 6}) {
+		t.Errorf("replay from the oldest kept event: %v, complete %v", numbers(t, backlog), complete)
+	}
+}
+
+// Un identifiant d'un autre démarrage du serveur, ou illisible, demande une resynchronisation
+func TestLastEventIDFromAnotherEpoch(t *testing.T) {
+	h := NewHub(DefaultHistorySize, DefaultBufferSize)
+	publish(t, h, PostTopic(1), 1)
+	publish(t, h, PostTopic(1), 2)
+
+	for _, lastEventID := range []string{
+		"ancien-1",
+		h.epoch + "-99",
+		h.epoch + "-abc",
+		h.epoch,
+	} {
+		subscription, backlog, complete := h.Subscribe([]string{PostTopic(1)}, lastEventID)
+		if complete || len(backlog) != 0 {
+			t.Errorf("Last-Event-ID %q: %d events, complete %v, want a resync", lastEventID, len(backlog), complete)
+		}
+		// L'abonnement reste actif pour les événements suivants
+		publish(t, h, PostTopic(1), 3)
+		if event := <-subscription.Events(); !reflect.DeepEqual(numbers(t, []Event{event}), []int{3}) {
+			t.Errorf("Last-Event-ID %q: next event %s", lastEventID, event.Data)
+		}
+		h.Unsubscribe(subscription)
+	}
+}
+
+// Un abonné qui ne lit pas sa file est déconnecté sans bloquer les autres
+func TestSlowSubscriberIsDisconnected(t *testing.T) {
+	h := NewHub(DefaultHistorySize, 2)
+	slow, _, _ := h.Subscribe([]string{PostTopic(1)}, "")
+	fast, _, _ := h.Subscribe([]string{PostTopic(1)}, "")
+
+	var lastRead string
+	for n := 1; n <= 3; n++ {
+		publish(t, h, PostTopic(1), n)
+		event := <-fast.Events()
+		lastRead = event.ID
+	}
+
+	var received []Event
+	for event := range slow.Events() {
+		received = append(received, event)
+	}
+	if got := numbers(t, received); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("slow subscriber received %v before being disconnected, want [1 2]", got)
+	}
+	if _, ok := h.subscribers[slow]; ok {
+		t.Error("slow subscriber still registered")
+	}
+	if _, ok := h.subscribers[fast]; !ok {
+		t.Error("fast subscriber was disconnected")
+	}
+	// Se désabonner après la déconnexion ne ferme pas le canal une seconde fois
+	h.Unsubscribe(slow)
+
+	// En se reconnectant, l'abonné lent rattrape l'événement perdu depuis l'historique
+	_, backlog, complete := h.Subscribe([]string{PostTopic(1)}, received[len(received)-1].ID)
+	if !complete || !reflect.DeepEqual(numbers(t, backlog), []int{3}) || backlog[0].ID != lastRead {
+		t.Errorf("replay after the disconnection: %v, complete %v", numbers(t, backlog), complete)
+	}
+}
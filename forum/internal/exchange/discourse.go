@@ -0,0 +1,175 @@
+package exchange
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Discourse n'a pas d'export complet en un seul fichier: l'adaptateur lit un objet JSON qui regroupe
+// les réponses de son API, sans les modifier:
+//
+//	{
+//	  "users":      [...], // GET /admin/users/list/active.json?show_emails=true
+//	  "categories": [...], // GET /categories.json?include_subcategories=true, champ category_list.categories
+//	  "topics":     [...], // GET /t/{id}.json?include_raw=true pour chaque sujet, avec tous ses messages
+//	  "likes":      [...]  // Facultatif: {"post_id":1,"user_id":2,"created_at":"..."} pour chaque "j'aime"
+//	}
+//
+// Les sujets deviennent des posts (avec le texte de leur premier message) et les autres messages des commentaires
+
+// Type des messages normaux de Discourse (les autres sont des actions de modération ou des messages privés au staff)
+const discourseRegularPost = 1
+
+var discourseTags = regexp.MustCompile(`<[^>]+>`)
+
+type discourseExport struct {
+	Users []struct {
+		ID        int       `json:"id"`
+		Username  string    `json:"username"`
+		Email     string    `json:"email"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"users"`
+	Categories []discourseCategory `json:"categories"`
+	Topics     []struct {
+		ID         int       `json:"id"`
+		Title      string    `json:"title"`
+		CategoryID int       `json:"category_id"`
+		CreatedAt  time.Time `json:"created_at"`
+		PostStream struct {
+			Posts []discoursePost `json:"posts"`
+		} `json:"post_stream"`
+	} `json:"topics"`
+	Likes []struct {
+		PostID    int       `json:"post_id"`
+		UserID    int       `json:"user_id"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"likes"`
+}
+
+type discourseCategory struct {
+	ID               int                 `json:"id"`
+	Name             string              `json:"name"`
+	DescriptionText  string              `json:"description_text"`
+	ParentCategoryID int                 `json:"parent_category_id"`
+	SubcategoryList  []discourseCategory `json:"subcategory_list"`
+}
+
+type discoursePost struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	PostNumber int       `json:"post_number"`
+	PostType   int       `json:"post_type"`
+	Raw        string    `json:"raw"`
+	Cooked     string    `json:"cooked"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// text renvoie le texte source du message, ou à défaut son HTML sans les balises
+func (p discoursePost) text() string {
+	if p.Raw != "" {
+		return p.Raw
+	}
+	return strings.TrimSpace(html.UnescapeString(discourseTags.ReplaceAllString(p.Cooked, "")))
+}
+
+// ReadDiscourse lit l'export JSON d'un forum Discourse décrit plus haut
+func ReadDiscourse(r io.Reader) (*Dataset, error) {
+	var export discourseExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+
+	data := &Dataset{}
+	// Les sous-catégories sont renvoyées dans le champ subcategory_list de leur catégorie parente
+	var addCategories func(categories []discourseCategory, parentID int)
+	addCategories = func(categories []discourseCategory, parentID int) {
+		for _, category := range categories {
+			parent := parentID
+			if category.ParentCategoryID != 0 {
+				parent = category.ParentCategoryID
+			}
+			data.Categories = append(data.Categories, Category{
+				Type: "category", ID: category.ID, Name: category.Name, Description: category.DescriptionText, ParentID: parent,
+			})
+			addCategories(category.SubcategoryList, category.ID)
+		}
+	}
+	addCategories(export.Categories, 0)
+
+	for _, user := range export.Users {
+		// Les comptes internes de Discourse (system, discobot) ont un ID négatif
+		if user.ID <= 0 {
+			continue
+		}
+		data.Users = append(data.Users, User{
+			Type: "user", ID: user.ID, Email: user.Email, Username: user.Username, Role: "user", CreatedAt: user.CreatedAt,
+		})
+	}
+
+	firstPosts := map[int]int{} // ID du premier message -> ID du sujet
+	for _, topic := range export.Topics {
+		var first *discoursePost
+		var replies []discoursePost
+		for _, post := range topic.PostStream.Posts {
+			if post.PostType != 0 && post.PostType != discourseRegularPost {
+				continue
+			}
+			if post.PostNumber == 1 {
+				message := post
+				first = &message
+			} else {
+				replies = append(replies, post)
+			}
+		}
+		if first == nil {
+			continue
+		}
+
+		firstPosts[first.ID] = topic.ID
+		var categoryIDs []int
+		if topic.CategoryID != 0 {
+			categoryIDs = []int{topic.CategoryID}
+		}
+		data.Posts = append(data.Posts, Post{
+			Type:        "post",
+			ID:          topic.ID,
+			UserID:      first.UserID,
+			Title:       topic.Title,
+			Content:     first.text(),
+			CategoryIDs: categoryIDs,
+			CreatedAt:   first.CreatedAt,
+			UpdatedAt:   first.UpdatedAt,
+		})
+
+		for _, reply := range replies {
+			data.Comments = append(data.Comments, Comment{
+				Type:      "comment",
+				ID:        reply.ID,
+				PostID:    topic.ID,
+				UserID:    reply.UserID,
+				Content:   reply.text(),
+				CreatedAt: reply.CreatedAt,
+				UpdatedAt: reply.UpdatedAt,
+			})
+		}
+	}
+
+	for _, like := range export.Likes {
+		if topicID, ok := firstPosts[like.PostID]; ok {
+			data.PostReactions = append(data.PostReactions, PostReaction{
+				Type: "post_reaction", UserID: like.UserID, PostID: topicID, Reaction: "like", CreatedAt: like.CreatedAt,
+			})
+		} else {
+			data.CommentReactions = append(data.CommentReactions, CommentReaction{
+				Type: "comment_reaction", UserID: like.UserID, CommentID: like.PostID, Reaction: "like", CreatedAt: like.CreatedAt,
+			})
+		}
+	}
+
+	return data, nil
+}
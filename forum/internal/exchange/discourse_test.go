@@ -0,0 +1,103 @@
+package exchange
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const discourseExportJSON = `{
+  "users": [
+    {"id": -1, "username": "system", "email": "no_email", "created_at": "2024-01-01T00:00:00Z"},
+    {"id": 1, "username": "alice", "email": "alice@example.com", "created_at": "2024-01-02T10:00:00Z"},
+    {"id": 2, "username": "bob", "email": "bob@example.com", "created_at": "2024-01-03T10:00:00Z"}
+  ],
+  "categories": [
+    {"id": 5, "name": "Support", "description_text": "Entraide", "subcategory_list": [
+      {"id": 6, "name": "Installation", "subcategory_list": [{"id": 7, "name": "Windows", "parent_category_id": 6}]}
+    ]},
+    {"id": 8, "name": "Annonces"}
+  ],
+  "topics": [
+    {"id": 30, "title": "Installation impossible", "category_id": 6, "created_at": "2024-02-01T09:00:00Z", "post_stream": {"posts": [
+      {"id": 301, "user_id": 1, "post_number": 1, "post_type": 1, "raw": "Erreur **fatale**", "cooked": "<p>ignoré</p>",
+       "created_at": "2024-02-01T09:00:00Z", "updated_at": "2024-02-01T09:30:00Z"},
+      {"id": 302, "user_id": -1, "post_number": 2, "post_type": 3, "raw": "Sujet fermé", "created_at": "2024-02-01T10:00:00Z"},
+      {"id": 303, "user_id": 2, "post_number": 3, "post_type": 1, "cooked": "<p>Essayez &amp; <b>relancez</b></p>",
+       "created_at": "2024-02-01T11:00:00Z", "updated_at": "2024-02-01T11:00:00Z"}
+    ]}},
+    {"id": 31, "title": "Sans premier message", "category_id": 8, "post_stream": {"posts": [
+      {"id": 311, "user_id": 1, "post_number": 2, "raw": "Réponse orpheline"}
+    ]}}
+  ],
+  "likes": [
+    {"post_id": 301, "user_id": 2, "created_at": "2024-02-02T08:00:00Z"},
+    {"post_id": 303, "user_id": 1, "created_at": "2024-02-02T09:00:00Z"}
+  ]
+}`
+
+func TestReadDiscourse(t *testing.T) {
+	data, err := ReadDiscourse(strings.NewReader(discourseExportJSON))
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	date := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	// Les sous-catégories sont aplaties et rattachées à leur parente
+	wantCategories := []Category{
+		{Type: "category", ID: 5, Name: "Support", Description: "Entraide"},
+		{Type: "category", ID: 6, Name: "Installation", ParentID: 5},
+		{Type: "category", ID: 7, Name: "Windows", ParentID: 6},
+		{Type: "category", ID: 8, Name: "Annonces"},
+	}
+	if !reflect.DeepEqual(data.Categories, wantCategories) {
+		t.Errorf("categories: %+v", data.Categories)
+	}
+
+	// Les comptes internes sont ignorés
+	var usernames []string
+	for _, user := range data.Users {
+		usernames = append(usernames, user.Username)
+	}
+	if !reflect.DeepEqual(usernames, []string{"alice", "bob"}) || data.Users[0].CreatedAt != date("2024-01-02T10:00:00Z") {
+		t.Errorf("users: %+v", data.Users)
+	}
+
+	// Un sujet sans premier message est ignoré, le texte source est préféré au HTML
+	wantPosts := []Post{{
+		Type: "post", ID: 30, UserID: 1, Title: "Installation impossible", Content: "Erreur **fatale**", CategoryIDs: []int{6},
+		CreatedAt: date("2024-02-01T09:00:00Z"), UpdatedAt: date("2024-02-01T09:30:00Z"),
+	}}
+	if !reflect.DeepEqual(data.Posts, wantPosts) {
+		t.Errorf("posts: %+v", data.Posts)
+	}
+
+	// Les actions de modération ne deviennent pas des commentaires; sans texte source, le HTML perd ses balises
+	wantComments := []Comment{{
+		Type: "comment", ID: 303, PostID: 30, UserID: 2, Content: "Essayez & relancez",
+		CreatedAt: date("2024-02-01T11:00:00Z"), UpdatedAt: date("2024-02-01T11:00:00Z"),
+	}}
+	if !reflect.DeepEqual(data.Comments, wantComments) {
+		t.Errorf("comments: %+v", data.Comments)
+	}
+
+	// Un "j'aime" sur le premier message porte sur le post, les autres sur les commentaires
+	wantPostReactions := []PostReaction{{Type: "post_reaction", UserID: 2, PostID: 30, Reaction: "like", CreatedAt: date("2024-02-02T08:00:00Z")}}
+	wantCommentReactions := []CommentReaction{{Type: "comment_reaction", UserID: 1, CommentID: 303, Reaction: "like", CreatedAt: date("2024-02-02T09:00:00Z")}}
+	if !reflect.DeepEqual(data.PostReactions, wantPostReactions) || !reflect.DeepEqual(data.CommentReactions, wantCommentReactions) {
+		t.Errorf("reactions: %+v and %+v", data.PostReactions, data.CommentReactions)
+	}
+}
+
+func TestReadDiscourseInvalidJSON(t *testing.T) {
+	if _, err := ReadDiscourse(strings.NewReader(`{"users": [`)); err == nil {
+		t.Error("reading a truncated export: got no error")
+	}
+}
@@ -0,0 +1,86 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"forum/internal/database"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB crée une base vide dans un dossier temporaire, avec toutes les migrations appliquées
+// La base est fermée à la fin du test
+func openTestDB(t *testing.T) {
+	t.Helper()
+	if err := database.InitDB(database.DefaultConfig(filepath.Join(t.TempDir(), "forum.db"))); err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		database.CloseDB()
+	})
+}
+
+// exec exécute des requêtes de préparation
+func exec(t *testing.T, queries ...string) {
+	t.Helper()
+	for _, query := range queries {
+		if _, err := database.DB.Exec(query); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+}
+
+// Les droits de publication et de lecture des catégories survivent à un export suivi d'un import
+func TestCategoryPermissionsRoundTrip(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	exec(t,
+		`INSERT INTO categories (name, position, post_permission, read_permission) VALUES ('Équipe', 10, 'moderators', 'staff')`,
+		`INSERT INTO categories (name, position, read_permission) VALUES ('Membres', 11, 'members')`,
+	)
+
+	var exported bytes.Buffer
+	if _, err := Export(ctx, &exported, "ndjson"); err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+	data, err := ReadNDJSON(bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	written := map[string]Category{}
+	for _, category := range data.Categories {
+		written[category.Name] = category
+	}
+	if c := written["Équipe"]; c.PostPermission != "moderators" || c.ReadPermission != "staff" {
+		t.Errorf("exported staff category: %+v", c)
+	}
+	if c := written["Membres"]; c.ReadPermission != "members" {
+		t.Errorf("exported members category: %+v", c)
+	}
+	if c := written["Général"]; c.ReadPermission != "" {
+		t.Errorf("default read permission is written: %+v", c)
+	}
+
+	// Import dans une base neuve: les noms sont libres, les catégories sont recréées
+	database.CloseDB()
+	openTestDB(t)
+	for i := range data.Categories {
+		data.Categories[i].Name += " importée"
+	}
+	data.Categories = append(data.Categories, Category{Type: "category", ID: 99, Name: "Inconnue", ReadPermission: "everybody"})
+	if _, err := Import(ctx, "test", data); err != nil {
+		t.Fatalf("importing: %v", err)
+	}
+	for name, want := range map[string]string{
+		"Équipe importée":  "staff",
+		"Membres importée": "members",
+		"Général importée": "everyone",
+		"Inconnue":         "everyone",
+	} {
+		var got string
+		err := database.ReadDB.QueryRowContext(ctx, "SELECT read_permission FROM categories WHERE name = ?", name).Scan(&got)
+		if err != nil || got != want {
+			t.Errorf("read permission of %s: got %q, error %v, want %q", name, got, err, want)
+		}
+	}
+}
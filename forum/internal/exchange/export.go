@@ -0,0 +1,259 @@
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"forum/internal/database"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordWriter écrit les enregistrements d'un export, section par section
+type recordWriter interface {
+	begin(section string) error
+	write(record interface{}) error
+	finish() error
+}
+
+// ndjsonWriter écrit un enregistrement par ligne
+type ndjsonWriter struct {
+	out *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) begin(section string) error { return nil }
+
+func (w *ndjsonWriter) write(record interface{}) error { return w.enc.Encode(record) }
+
+func (w *ndjsonWriter) finish() error { return w.out.Flush() }
+
+// jsonWriter écrit un seul objet JSON dont chaque section, sauf l'en-tête, est un tableau
+type jsonWriter struct {
+	out     *bufio.Writer
+	inArray bool // Vrai quand la section courante est un tableau
+	first   bool // Vrai tant qu'aucun élément n'a été écrit dans la section courante
+}
+
+func (w *jsonWriter) begin(section string) error {
+	if section == "meta" {
+		_, err := w.out.WriteString(`{"meta":`)
+		return err
+	}
+	closing := ""
+	if w.inArray {
+		closing = "]"
+	}
+	_, err := fmt.Fprintf(w.out, "%s,\n%q:[", closing, section)
+	w.inArray = true
+	w.first = true
+	return err
+}
+
+func (w *jsonWriter) write(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if !w.inArray {
+		_, err = w.out.Write(data)
+		return err
+	}
+	separator := ""
+	if !w.first {
+		separator = ","
+	}
+	w.first = false
+	_, err = fmt.Fprintf(w.out, "%s\n%s", separator, data)
+	return err
+}
+
+func (w *jsonWriter) finish() error {
+	closing := "}\n"
+	if w.inArray {
+		closing = "]}\n"
+	}
+	if _, err := w.out.WriteString(closing); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// Export écrit toute la base dans w, au format "ndjson" ou "json"
+// Il renvoie le nombre d'enregistrements écrits pour chaque section
+func Export(ctx context.Context, w io.Writer, format string) (map[string]int, error) {
+	out := bufio.NewWriter(w)
+	var writer recordWriter
+	switch format {
+	case "ndjson":
+		writer = &ndjsonWriter{out: out, enc: json.NewEncoder(out)}
+	case "json":
+		writer = &jsonWriter{out: out}
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+
+	counts := map[string]int{}
+	if err := writer.begin("meta"); err != nil {
+		return nil, err
+	}
+	err := writer.write(Meta{Type: "meta", Format: FormatName, Version: FormatVersion, ExportedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+
+	// Chaque section est lue par une seule requête et écrite au fil de la lecture
+	sections := []struct {
+		name  string
+		query string
+		scan  func(*sql.Rows) (interface{}, error)
+	}{
+		{"categories", `SELECT id, name, description, color, archived, post_permission, read_permission, COALESCE(parent_id, 0) FROM categories ORDER BY position, id`, scanCategory},
+		{"users", `SELECT id, email, username, role, created_at FROM users ORDER BY id`, scanUser},
+		{"posts", `
+			SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at,
+				(SELECT group_concat(category_id) FROM post_categories WHERE post_id = p.id),
+				(SELECT group_concat(t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id)
+			FROM posts p ORDER BY p.id`, scanPost},
+		{"comments", `SELECT id, post_id, user_id, content, created_at, updated_at FROM comments ORDER BY id`, scanComment},
+		{"post_reactions", `SELECT user_id, post_id, reaction_type, created_at FROM post_reactions ORDER BY post_id, user_id`, scanPostReaction},
+		{"comment_reactions", `SELECT user_id, comment_id, reaction_type, created_at FROM comment_reactions ORDER BY comment_id, user_id`, scanCommentReaction},
+		{"images", `SELECT id, user_id, post_id, filename, created_at FROM images ORDER BY id`, scanImage},
+		{"reports", `
+			SELECT id, type, content_id, reporter_id, reason, status, admin_id, admin_response, created_at, updated_at
+			FROM reports ORDER BY id`, scanReport},
+	}
+
+	for _, section := range sections {
+		if err := writer.begin(section.name); err != nil {
+			return counts, err
+		}
+		count, err := exportSection(ctx, writer, section.query, section.scan)
+		counts[section.name] = count
+		if err != nil {
+			return counts, fmt.Errorf("exporting %s: %v", section.name, err)
+		}
+	}
+
+	return counts, writer.finish()
+}
+
+// exportSection exécute une requête et écrit chacune de ses lignes
+func exportSection(ctx context.Context, writer recordWriter, query string, scan func(*sql.Rows) (interface{}, error)) (int, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return count, err
+		}
+		if err := writer.write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func scanCategory(rows *sql.Rows) (interface{}, error) {
+	category := Category{Type: "category"}
+	var description sql.NullString
+	var permission, readPermission string
+	err := rows.Scan(&category.ID, &category.Name, &description, &category.Color, &category.Archived, &permission, &readPermission, &category.ParentID)
+	category.Description = description.String
+	// Les droits par défaut ne sont pas écrits
+	if permission != "open" {
+		category.PostPermission = permission
+	}
+	if readPermission != "everyone" {
+		category.ReadPermission = readPermission
+	}
+	return category, err
+}
+
+func scanUser(rows *sql.Rows) (interface{}, error) {
+	user := User{Type: "user"}
+	var role sql.NullString
+	err := rows.Scan(&user.ID, &user.Email, &user.Username, &role, &user.CreatedAt)
+	user.Role = role.String
+	return user, err
+}
+
+func scanPost(rows *sql.Rows) (interface{}, error) {
+	post := Post{Type: "post", CategoryIDs: []int{}}
+	var categoryIDs, tags sql.NullString
+	err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &categoryIDs, &tags)
+	if err != nil {
+		return nil, err
+	}
+	if categoryIDs.Valid {
+		for _, idStr := range strings.Split(categoryIDs.String, ",") {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return nil, err
+			}
+			post.CategoryIDs = append(post.CategoryIDs, id)
+		}
+	}
+	// Un nom de tag normalisé ne contient jamais de virgule
+	if tags.Valid {
+		post.Tags = strings.Split(tags.String, ",")
+	}
+	return post, nil
+}
+
+func scanComment(rows *sql.Rows) (interface{}, error) {
+	comment := Comment{Type: "comment"}
+	err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt)
+	return comment, err
+}
+
+func scanPostReaction(rows *sql.Rows) (interface{}, error) {
+	reaction := PostReaction{Type: "post_reaction"}
+	err := rows.Scan(&reaction.UserID, &reaction.PostID, &reaction.Reaction, &reaction.CreatedAt)
+	return reaction, err
+}
+
+func scanCommentReaction(rows *sql.Rows) (interface{}, error) {
+	reaction := CommentReaction{Type: "comment_reaction"}
+	err := rows.Scan(&reaction.UserID, &reaction.CommentID, &reaction.Reaction, &reaction.CreatedAt)
+	return reaction, err
+}
+
+func scanImage(rows *sql.Rows) (interface{}, error) {
+	image := Image{Type: "image"}
+	var postID sql.NullInt64
+	err := rows.Scan(&image.ID, &image.UserID, &postID, &image.Filename, &image.CreatedAt)
+	if postID.Valid {
+		id := int(postID.Int64)
+		image.PostID = &id
+	}
+	return image, err
+}
+
+func scanReport(rows *sql.Rows) (interface{}, error) {
+	report := Report{Type: "report"}
+	var status sql.NullString
+	var adminID sql.NullInt64
+	var adminResponse sql.NullString
+	err := rows.Scan(&report.ID, &report.ContentType, &report.ContentID, &report.ReporterID, &report.Reason,
+		&status, &adminID, &adminResponse, &report.CreatedAt, &report.UpdatedAt)
+	report.Status = status.String
+	if adminID.Valid {
+		id := int(adminID.Int64)
+		report.AdminID = &id
+	}
+	if adminResponse.Valid {
+		report.AdminResponse = &adminResponse.String
+	}
+	return report, err
+}
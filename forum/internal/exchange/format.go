@@ -0,0 +1,147 @@
+// Package exchange lit et écrit le format d'échange du forum
+//
+// Le format NDJSON contient un enregistrement JSON par ligne. Le champ "type" indique la nature de
+// l'enregistrement, et la première ligne est toujours l'en-tête:
+//
+//	{"type":"meta","format":"forum","version":1,"exported_at":"2026-10-18T21:00:00Z"}
+//	{"type":"category","id":1,"name":"Général"}
+//	{"type":"user","id":2,"email":"alice@example.com","username":"alice","role":"user","created_at":"..."}
+//	{"type":"post","id":1,"user_id":2,"title":"Bonjour","content":"...","category_ids":[1],"created_at":"...","updated_at":"..."}
+//	{"type":"comment","id":1,"post_id":1,"user_id":2,"content":"...","created_at":"...","updated_at":"..."}
+//	{"type":"post_reaction","user_id":2,"post_id":1,"reaction":"like","created_at":"..."}
+//	{"type":"comment_reaction","user_id":2,"comment_id":1,"reaction":"dislike","created_at":"..."}
+//	{"type":"image","id":1,"user_id":2,"post_id":1,"filename":"2_photo.png","created_at":"..."}
+//	{"type":"report","id":1,"content_type":"post","content_id":1,"reporter_id":2,"reason":"...","status":"pending","created_at":"...","updated_at":"..."}
+//
+// Les enregistrements apparaissent dans cet ordre, pour qu'un élément ne référence jamais un élément
+// qui n'a pas encore été lu. Les dates sont au format RFC 3339. Les mots de passe ne sont jamais exportés.
+//
+// Le format JSON contient les mêmes enregistrements, regroupés dans un seul objet:
+//
+//	{"meta":{...},"categories":[...],"users":[...],"posts":[...],"comments":[...],
+//	 "post_reactions":[...],"comment_reactions":[...],"images":[...],"reports":[...]}
+//
+// À l'import, les images et les signalements sont ignorés, ainsi que les réactions dont l'identifiant
+// ne correspond à aucune réaction configurée sur le forum. Les identifiants des enregistrements sont ceux
+// de la source: ils ne servent qu'à relier les éléments entre eux et sont remplacés par ceux du forum.
+// Les adaptateurs phpBB et Discourse convertissent leurs données dans ce même format avant l'import.
+package exchange
+
+import (
+	"time"
+)
+
+// Identifiant et version du format d'échange
+const (
+	FormatName    = "forum"
+	FormatVersion = 1
+)
+
+// Meta est l'en-tête d'un export
+type Meta struct {
+	Type       string    `json:"type"`
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Category est une catégorie de posts
+type Category struct {
+	Type        string `json:"type"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Champs de présentation et de droits, absents des exports des autres forums
+	Color          string `json:"color,omitempty"`
+	Archived       bool   `json:"archived,omitempty"`
+	PostPermission string `json:"post_permission,omitempty"`
+	ReadPermission string `json:"read_permission,omitempty"`
+	ParentID       int    `json:"parent_id,omitempty"` // ID de la catégorie parente dans le même fichier
+}
+
+// User est un compte utilisateur, sans son mot de passe
+type User struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Post est un post publié, avec les IDs de ses catégories et les noms de ses tags
+type Post struct {
+	Type        string    `json:"type"`
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	CategoryIDs []int     `json:"category_ids"`
+	Tags        []string  `json:"tags,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Comment est un commentaire sur un post
+type Comment struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	UserID    int       `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PostReaction est la réaction d'un utilisateur à un post ("like" ou "dislike")
+type PostReaction struct {
+	Type      string    `json:"type"`
+	UserID    int       `json:"user_id"`
+	PostID    int       `json:"post_id"`
+	Reaction  string    `json:"reaction"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentReaction est la réaction d'un utilisateur à un commentaire ("like" ou "dislike")
+type CommentReaction struct {
+	Type      string    `json:"type"`
+	UserID    int       `json:"user_id"`
+	CommentID int       `json:"comment_id"`
+	Reaction  string    `json:"reaction"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Image décrit une image envoyée (le fichier lui-même n'est pas exporté)
+type Image struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	PostID    *int      `json:"post_id,omitempty"`
+	Filename  string    `json:"filename"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Report est un signalement ou une demande de rôle de modérateur
+type Report struct {
+	Type          string    `json:"type"`
+	ID            int       `json:"id"`
+	ContentType   string    `json:"content_type"`
+	ContentID     int       `json:"content_id"`
+	ReporterID    int       `json:"reporter_id"`
+	Reason        string    `json:"reason"`
+	Status        string    `json:"status"`
+	AdminID       *int      `json:"admin_id,omitempty"`
+	AdminResponse *string   `json:"admin_response,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Dataset regroupe les enregistrements à importer, déjà dans l'ordre du format d'échange
+type Dataset struct {
+	Categories       []Category
+	Users            []User
+	Posts            []Post
+	Comments         []Comment
+	PostReactions    []PostReaction
+	CommentReactions []CommentReaction
+}
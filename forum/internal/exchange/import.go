@@ -0,0 +1,484 @@
+package exchange
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"forum/internal/database"
+	"forum/internal/models"
+	"strings"
+	"time"
+)
+
+// ImportStats compte, pour chaque section, les éléments importés, ceux qui l'étaient déjà,
+// et ceux qui ont été ignorés parce qu'ils référencent un élément absent
+type ImportStats struct {
+	Imported map[string]int
+	Existing map[string]int
+	Skipped  map[string]int
+}
+
+// Sections importées, dans l'ordre où elles sont écrites
+var ImportSections = []string{"categories", "users", "posts", "comments", "post_reactions", "comment_reactions"}
+
+// Table de la base correspondant à chaque type d'élément de import_map
+var mappedTables = map[string]string{
+	"category": "categories",
+	"user":     "users",
+	"post":     "posts",
+	"comment":  "comments",
+}
+
+// importer écrit un Dataset dans une transaction
+type importer struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	source string
+	stats  *ImportStats
+}
+
+// Import écrit les données dans la base, en une seule transaction
+// source identifie la provenance des données (par exemple "phpbb"): importer deux fois
+// la même source ne recrée pas les éléments déjà importés, qui ne sont pas non plus modifiés
+// Les utilisateurs sont rapprochés des comptes existants par email et les catégories par nom
+func Import(ctx context.Context, source string, data *Dataset) (*ImportStats, error) {
+	if source == "" {
+		return nil, fmt.Errorf("import source name is required")
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	imp := &importer{
+		ctx:    ctx,
+		tx:     tx,
+		source: source,
+		stats: &ImportStats{
+			Imported: map[string]int{},
+			Existing: map[string]int{},
+			Skipped:  map[string]int{},
+		},
+	}
+
+	steps := []func(*Dataset) error{
+		imp.importCategories,
+		imp.importUsers,
+		imp.importPosts,
+		imp.importComments,
+		imp.importPostReactions,
+		imp.importCommentReactions,
+		imp.updateCounters,
+	}
+	for _, step := range steps {
+		if err := step(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return imp.stats, nil
+}
+
+// lookup cherche l'ID local d'un élément déjà importé
+// exists est faux si l'élément a été supprimé du forum depuis son import
+func (imp *importer) lookup(kind string, sourceID int) (localID int, found, exists bool, err error) {
+	query := fmt.Sprintf(`
+		SELECT m.local_id, EXISTS(SELECT 1 FROM %s WHERE id = m.local_id)
+		FROM import_map m WHERE m.source = ? AND m.kind = ? AND m.source_id = ?`, mappedTables[kind])
+	err = imp.tx.QueryRowContext(imp.ctx, query, imp.source, kind, sourceID).Scan(&localID, &exists)
+	if err == sql.ErrNoRows {
+		return 0, false, false, nil
+	}
+	if err != nil {
+		return 0, false, false, err
+	}
+	return localID, true, exists, nil
+}
+
+// resolve renvoie l'ID local d'un élément référencé, ou 0 s'il n'a pas été importé ou n'existe plus
+func (imp *importer) resolve(kind string, sourceID int) (int, error) {
+	localID, _, exists, err := imp.lookup(kind, sourceID)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return localID, nil
+}
+
+// remember enregistre la correspondance entre un ID de la source et un ID local
+func (imp *importer) remember(kind string, sourceID, localID int) error {
+	_, err := imp.tx.ExecContext(imp.ctx,
+		"INSERT INTO import_map (source, kind, source_id, local_id) VALUES (?, ?, ?, ?)",
+		imp.source, kind, sourceID, localID)
+	return err
+}
+
+// alreadyImported vérifie si un élément a déjà été importé et met à jour les compteurs dans ce cas
+func (imp *importer) alreadyImported(kind, section string, sourceID int) (bool, error) {
+	_, found, _, err := imp.lookup(kind, sourceID)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		imp.stats.Existing[section]++
+	}
+	return found, nil
+}
+
+// insert exécute un INSERT et renvoie l'ID de la ligne créée
+func (imp *importer) insert(query string, args ...interface{}) (int, error) {
+	result, err := imp.tx.ExecContext(imp.ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (imp *importer) importCategories(data *Dataset) error {
+	// Les parents sont rattachés une fois toutes les catégories créées, quel que soit leur ordre dans le fichier
+	var created []Category
+	for _, category := range data.Categories {
+		done, err := imp.alreadyImported("category", "categories", category.ID)
+		if err != nil {
+			return fmt.Errorf("importing category %d: %v", category.ID, err)
+		}
+		if done {
+			continue
+		}
+
+		// Une catégorie du même nom est réutilisée plutôt que dupliquée
+		var localID int
+		err = imp.tx.QueryRowContext(imp.ctx, "SELECT id FROM categories WHERE name = ? COLLATE NOCASE", category.Name).Scan(&localID)
+		if err == sql.ErrNoRows {
+			var description interface{}
+			if category.Description != "" {
+				description = category.Description
+			}
+			permission := category.PostPermission
+			if permission != "moderators" && permission != "readonly" {
+				permission = "open"
+			}
+			readPermission := category.ReadPermission
+			if readPermission != "members" && readPermission != "staff" {
+				readPermission = "everyone"
+			}
+			// Les catégories importées sont placées après les catégories existantes, dans l'ordre du fichier
+			localID, err = imp.insert(`
+				INSERT INTO categories (name, description, position, color, archived, post_permission, read_permission)
+				VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM categories), ?, ?, ?, ?)`,
+				category.Name, description, category.Color, category.Archived, permission, readPermission)
+			if category.ParentID != 0 {
+				created = append(created, category)
+			}
+		}
+		if err == nil {
+			err = imp.remember("category", category.ID, localID)
+		}
+		if err != nil {
+			return fmt.Errorf("importing category %d: %v", category.ID, err)
+		}
+		imp.stats.Imported["categories"]++
+	}
+
+	// Une catégorie existante garde sa place: seules les catégories créées par l'import sont rattachées
+	for _, category := range created {
+		localID, err := imp.resolve("category", category.ID)
+		if err != nil {
+			return fmt.Errorf("importing category %d: %v", category.ID, err)
+		}
+		parentID, err := imp.resolve("category", category.ParentID)
+		if err != nil {
+			return fmt.Errorf("importing category %d: %v", category.ID, err)
+		}
+		if parentID == 0 || parentID == localID {
+			continue
+		}
+		_, err = imp.tx.ExecContext(imp.ctx, "UPDATE categories SET parent_id = ? WHERE id = ?", parentID, localID)
+		if err != nil {
+			return fmt.Errorf("importing category %d: %v", category.ID, err)
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importUsers(data *Dataset) error {
+	for _, user := range data.Users {
+		done, err := imp.alreadyImported("user", "users", user.ID)
+		if err != nil {
+			return fmt.Errorf("importing user %d: %v", user.ID, err)
+		}
+		if done {
+			continue
+		}
+
+		// Sans email, on en fabrique un qui ne peut recevoir aucun message mais reste unique
+		email := strings.TrimSpace(user.Email)
+		if email == "" {
+			email = fmt.Sprintf("%s-%d@import.invalid", imp.source, user.ID)
+		}
+
+		// Un compte qui existe déjà avec le même email est réutilisé
+		var localID int
+		err = imp.tx.QueryRowContext(imp.ctx, "SELECT id FROM users WHERE email = ? COLLATE NOCASE", email).Scan(&localID)
+		if err == sql.ErrNoRows {
+			localID, err = imp.createUser(user, email)
+		}
+		if err == nil {
+			err = imp.remember("user", user.ID, localID)
+		}
+		if err != nil {
+			return fmt.Errorf("importing user %d: %v", user.ID, err)
+		}
+		imp.stats.Imported["users"]++
+	}
+	return nil
+}
+
+// createUser crée le compte d'un utilisateur importé
+// Comme pour les comptes OAuth, il n'a pas de mot de passe: il ne peut pas se connecter avec un mot de passe
+func (imp *importer) createUser(user User, email string) (int, error) {
+	username, err := imp.availableUsername(user.Username)
+	if err != nil {
+		return 0, err
+	}
+
+	role := user.Role
+	if role != "admin" && role != "moderator" {
+		role = "user"
+	}
+
+	return imp.insert(
+		"INSERT INTO users (email, username, password, role, created_at) VALUES (?, ?, ?, ?, ?)",
+		email, username, "", role, timestamp(user.CreatedAt),
+	)
+}
+
+// availableUsername renvoie le nom demandé, suivi d'un numéro s'il est déjà pris
+func (imp *importer) availableUsername(username string) (string, error) {
+	base := strings.TrimSpace(username)
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var taken bool
+		err := imp.tx.QueryRowContext(imp.ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", candidate).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+func (imp *importer) importPosts(data *Dataset) error {
+	for _, post := range data.Posts {
+		done, err := imp.alreadyImported("post", "posts", post.ID)
+		if err != nil {
+			return fmt.Errorf("importing post %d: %v", post.ID, err)
+		}
+		if done {
+			continue
+		}
+
+		userID, err := imp.resolve("user", post.UserID)
+		if err != nil {
+			return fmt.Errorf("importing post %d: %v", post.ID, err)
+		}
+		if userID == 0 {
+			imp.stats.Skipped["posts"]++
+			continue
+		}
+
+		localID, err := imp.insert(
+			"INSERT INTO posts (title, content, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			post.Title, post.Content, userID, timestamp(post.CreatedAt), timestamp(updatedAt(post.CreatedAt, post.UpdatedAt)),
+		)
+		if err != nil {
+			return fmt.Errorf("importing post %d: %v", post.ID, err)
+		}
+
+		for _, categoryID := range post.CategoryIDs {
+			localCategoryID, err := imp.resolve("category", categoryID)
+			if err == nil && localCategoryID != 0 {
+				_, err = imp.tx.ExecContext(imp.ctx,
+					"INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?, ?)", localID, localCategoryID)
+			}
+			if err != nil {
+				return fmt.Errorf("importing post %d: %v", post.ID, err)
+			}
+		}
+
+		// Les tags sont renormalisés: un fichier produit par un autre outil peut contenir des noms libres
+		var tags []string
+		for _, name := range post.Tags {
+			if tag := models.NormalizeTag(name); tag != "" && len(tags) < models.MaxTagsPerPost {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > 0 {
+			if err := models.SetPostTagsTx(imp.ctx, imp.tx, int(localID), tags); err != nil {
+				return fmt.Errorf("importing post %d: %v", post.ID, err)
+			}
+		}
+
+		if err := imp.remember("post", post.ID, localID); err != nil {
+			return fmt.Errorf("importing post %d: %v", post.ID, err)
+		}
+		imp.stats.Imported["posts"]++
+	}
+	return nil
+}
+
+func (imp *importer) importComments(data *Dataset) error {
+	for _, comment := range data.Comments {
+		done, err := imp.alreadyImported("comment", "comments", comment.ID)
+		if err != nil {
+			return fmt.Errorf("importing comment %d: %v", comment.ID, err)
+		}
+		if done {
+			continue
+		}
+
+		postID, err := imp.resolve("post", comment.PostID)
+		var userID int
+		if err == nil {
+			userID, err = imp.resolve("user", comment.UserID)
+		}
+		if err != nil {
+			return fmt.Errorf("importing comment %d: %v", comment.ID, err)
+		}
+		if postID == 0 || userID == 0 {
+			imp.stats.Skipped["comments"]++
+			continue
+		}
+
+		localID, err := imp.insert(
+			"INSERT INTO comments (content, user_id, post_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			comment.Content, userID, postID, timestamp(comment.CreatedAt), timestamp(updatedAt(comment.CreatedAt, comment.UpdatedAt)),
+		)
+		if err == nil {
+			err = imp.remember("comment", comment.ID, localID)
+		}
+		if err != nil {
+			return fmt.Errorf("importing comment %d: %v", comment.ID, err)
+		}
+		imp.stats.Imported["comments"]++
+	}
+	return nil
+}
+
+// Les réactions n'ont pas d'ID propre: la clé (utilisateur, élément) suffit à les rendre idempotentes
+
+func (imp *importer) importPostReactions(data *Dataset) error {
+	for _, reaction := range data.PostReactions {
+		err := imp.importReaction("post_reactions", "post", "post_id", reaction.UserID, reaction.PostID, reaction.Reaction, reaction.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("importing reaction of user %d on post %d: %v", reaction.UserID, reaction.PostID, err)
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importCommentReactions(data *Dataset) error {
+	for _, reaction := range data.CommentReactions {
+		err := imp.importReaction("comment_reactions", "comment", "comment_id", reaction.UserID, reaction.CommentID, reaction.Reaction, reaction.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("importing reaction of user %d on comment %d: %v", reaction.UserID, reaction.CommentID, err)
+		}
+	}
+	return nil
+}
+
+// importReaction ajoute une réaction à un post ou un commentaire importé
+// Les réactions qui ne sont pas configurées sur ce forum sont ignorées
+func (imp *importer) importReaction(table, kind, column string, sourceUserID, sourceTargetID int, reactionType string, createdAt time.Time) error {
+	var known int
+	err := imp.tx.QueryRowContext(imp.ctx, "SELECT COUNT(*) FROM reactions WHERE name = ?", reactionType).Scan(&known)
+	if err != nil {
+		return err
+	}
+	if known == 0 {
+		imp.stats.Skipped[table]++
+		return nil
+	}
+
+	userID, err := imp.resolve("user", sourceUserID)
+	if err != nil {
+		return err
+	}
+	targetID, err := imp.resolve(kind, sourceTargetID)
+	if err != nil {
+		return err
+	}
+	if userID == 0 || targetID == 0 {
+		imp.stats.Skipped[table]++
+		return nil
+	}
+
+	query := fmt.Sprintf("INSERT OR IGNORE INTO %s (user_id, %s, reaction_type, created_at) VALUES (?, ?, ?, ?)", table, column)
+	result, err := imp.tx.ExecContext(imp.ctx, query, userID, targetID, reactionType, timestamp(createdAt))
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		imp.stats.Imported[table]++
+	} else {
+		imp.stats.Existing[table]++
+	}
+	return nil
+}
+
+// updateCounters recalcule les compteurs dénormalisés des posts et commentaires de la source
+// like_count et dislike_count comptent les réactions qui ajoutent et qui retirent un point au score
+func (imp *importer) updateCounters(data *Dataset) error {
+	_, err := imp.tx.ExecContext(imp.ctx, `
+		UPDATE posts SET
+			like_count = (SELECT COUNT(*) FROM post_reactions pr JOIN reactions r ON r.name = pr.reaction_type
+				WHERE pr.post_id = posts.id AND r.score > 0),
+			dislike_count = (SELECT COUNT(*) FROM post_reactions pr JOIN reactions r ON r.name = pr.reaction_type
+				WHERE pr.post_id = posts.id AND r.score < 0),
+			comment_count = (SELECT COUNT(*) FROM comments WHERE post_id = posts.id)
+		WHERE id IN (SELECT local_id FROM import_map WHERE source = ? AND kind = 'post')`, imp.source)
+	if err != nil {
+		return err
+	}
+
+	_, err = imp.tx.ExecContext(imp.ctx, `
+		UPDATE comments SET
+			like_count = (SELECT COUNT(*) FROM comment_reactions cr JOIN reactions r ON r.name = cr.reaction_type
+				WHERE cr.comment_id = comments.id AND r.score > 0),
+			dislike_count = (SELECT COUNT(*) FROM comment_reactions cr JOIN reactions r ON r.name = cr.reaction_type
+				WHERE cr.comment_id = comments.id AND r.score < 0)
+		WHERE id IN (SELECT local_id FROM import_map WHERE source = ? AND kind = 'comment')`, imp.source)
+	return err
+}
+
+// timestamp convertit une date au format de CURRENT_TIMESTAMP, utilisé par le reste de la base
+// Une date absente devient la date actuelle
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// updatedAt renvoie la date de modification, ou la date de création si elle est absente
+func updatedAt(createdAt, updatedAt time.Time) time.Time {
+	if updatedAt.IsZero() {
+		return createdAt
+	}
+	return updatedAt
+}
@@ -0,0 +1,122 @@
+package exchange
+
+import (
+	"context"
+	"forum/internal/database"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// count renvoie le résultat d'une requête SELECT COUNT(*)
+func count(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.ReadDB.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// Importer deux fois la même source ne duplique rien, même après la suppression d'un élément importé
+func TestImportIsIdempotent(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	// marc a déjà un compte; le nom de Zoé est pris par un autre membre
+	exec(t,
+		`INSERT INTO users (email, username, password, role) VALUES ('MARC@example.com', 'marc_local', 'x', 'user')`,
+		`INSERT INTO users (email, username, password, role) VALUES ('autre@example.com', 'Zoé & co', 'x', 'user')`,
+	)
+
+	read := func() *Dataset {
+		t.Helper()
+		data, err := ReadPhpBB(strings.NewReader(phpbbDump), "forum_")
+		if err != nil {
+			t.Fatalf("reading dump: %v", err)
+		}
+		// Une réaction inconnue du forum et une réaction d'un utilisateur absent sont ignorées
+		data.PostReactions = append(data.PostReactions,
+			PostReaction{Type: "post_reaction", UserID: 2, PostID: 10, Reaction: "bravo"},
+			PostReaction{Type: "post_reaction", UserID: 99, PostID: 10, Reaction: "like"},
+		)
+		return data
+	}
+	tables := []string{"categories", "users", "posts", "comments", "post_reactions", "comment_reactions"}
+	rows := func() map[string]int {
+		t.Helper()
+		counts := map[string]int{}
+		for _, table := range tables {
+			counts[table] = count(t, "SELECT COUNT(*) FROM "+table)
+		}
+		return counts
+	}
+	before := rows()
+
+	stats, err := Import(ctx, "phpbb", read())
+	if err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	wantImported := map[string]int{"categories": 2, "users": 2, "posts": 1, "comments": 1, "post_reactions": 1, "comment_reactions": 1}
+	if !reflect.DeepEqual(stats.Imported, wantImported) || len(stats.Existing) != 0 {
+		t.Errorf("first import: imported %v, existing %v", stats.Imported, stats.Existing)
+	}
+	if want := map[string]int{"post_reactions": 2}; !reflect.DeepEqual(stats.Skipped, want) {
+		t.Errorf("first import: skipped %v, want %v", stats.Skipped, want)
+	}
+	after := rows()
+	for table, imported := range map[string]int{"categories": 2, "users": 1, "posts": 1, "comments": 1, "post_reactions": 1, "comment_reactions": 1} {
+		if after[table] != before[table]+imported {
+			t.Errorf("%s: %d rows after the import, want %d", table, after[table], before[table]+imported)
+		}
+	}
+
+	// Le compte existant est réutilisé, le nouveau membre reçoit un nom libre
+	if n := count(t, "SELECT COUNT(*) FROM users WHERE username = 'Zoé & co_2' AND email = 'zoe@example.com' AND password = ''"); n != 1 {
+		t.Error("imported user with a taken username was not renamed")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM comments c JOIN users u ON u.id = c.user_id WHERE u.username = 'marc_local'"); n != 1 {
+		t.Error("comment of marc is not attached to the existing account")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM categories c JOIN categories p ON p.id = c.parent_id WHERE c.name = 'Aide & astuces' AND p.name = 'Communauté'"); n != 1 {
+		t.Error("imported forum is not attached to its parent category")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM posts WHERE title = 'Première question' AND like_count = 1 AND comment_count = 1"); n != 1 {
+		t.Error("counters of the imported post are not up to date")
+	}
+
+	stats, err = Import(ctx, "phpbb", read())
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	wantExisting := map[string]int{"categories": 2, "users": 2, "posts": 1, "comments": 1, "post_reactions": 1, "comment_reactions": 1}
+	if len(stats.Imported) != 0 || !reflect.DeepEqual(stats.Existing, wantExisting) {
+		t.Errorf("second import: imported %v, existing %v", stats.Imported, stats.Existing)
+	}
+	if got := rows(); !reflect.DeepEqual(got, after) {
+		t.Errorf("rows after the second import: %v, want %v", got, after)
+	}
+
+	// Un post supprimé du forum n'est pas recréé, ni ses commentaires
+	exec(t, "DELETE FROM posts WHERE title = 'Première question'")
+	if _, err := Import(ctx, "phpbb", read()); err != nil {
+		t.Fatalf("third import: %v", err)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM posts WHERE title = 'Première question'"); n != 0 {
+		t.Error("a deleted post was imported again")
+	}
+
+	// Une autre source est importée à part, en réutilisant les comptes et les catégories du même nom
+	if _, err := Import(ctx, "phpbb-copie", read()); err != nil {
+		t.Fatalf("importing another source: %v", err)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM posts WHERE title = 'Première question'"); n != 1 {
+		t.Errorf("posts of another source: %d, want 1", n)
+	}
+	if got := rows(); got["users"] != after["users"] || got["categories"] != after["categories"] {
+		t.Errorf("another source duplicated users or categories: %v, want %v", got, after)
+	}
+
+	if _, err := Import(ctx, "", read()); err == nil {
+		t.Error("importing without a source name: got no error")
+	}
+}
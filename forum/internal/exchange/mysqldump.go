@@ -0,0 +1,386 @@
+package exchange
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dumpTable contient les lignes d'une table lues dans un dump MySQL
+// Chaque ligne associe le nom d'une colonne à sa valeur; NULL devient une chaîne vide
+type dumpTable struct {
+	columns []string
+	rows    []map[string]string
+}
+
+// readMySQLDump lit un dump produit par mysqldump et renvoie le contenu des tables demandées
+// Seules les instructions CREATE TABLE (pour l'ordre des colonnes) et INSERT sont interprétées
+func readMySQLDump(r io.Reader, wanted map[string]bool) (map[string]*dumpTable, error) {
+	tables := map[string]*dumpTable{}
+	reader := bufio.NewReaderSize(r, 1<<20)
+
+	for {
+		statement, err := readStatement(reader)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if statement != "" {
+			if parseErr := parseStatement(statement, wanted, tables); parseErr != nil {
+				return nil, parseErr
+			}
+		}
+		if err == io.EOF {
+			return tables, nil
+		}
+	}
+}
+
+// readStatement lit une instruction SQL jusqu'au point-virgule qui la termine
+// Les commentaires sont retirés et les points-virgules dans les chaînes sont ignorés
+func readStatement(reader *bufio.Reader) (string, error) {
+	var statement strings.Builder
+	var quote rune // Guillemet de la chaîne ou de l'identifiant en cours, 0 en dehors
+	atLineStart := true
+
+	for {
+		c, _, err := reader.ReadRune()
+		if err != nil {
+			return strings.TrimSpace(statement.String()), err
+		}
+
+		if quote != 0 {
+			statement.WriteRune(c)
+			if c == '\\' && quote != '`' {
+				// Le caractère échappé est copié tel quel
+				next, _, err := reader.ReadRune()
+				if err != nil {
+					return "", errors.New("unterminated string in dump")
+				}
+				statement.WriteRune(next)
+			} else if c == quote {
+				quote = 0
+			}
+			atLineStart = false
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			return strings.TrimSpace(statement.String()), nil
+		case c == '#' || (c == '-' && atLineStart && peekIs(reader, '-')):
+			// Commentaire jusqu'à la fin de la ligne
+			if _, err := reader.ReadString('\n'); err != nil {
+				return strings.TrimSpace(statement.String()), err
+			}
+			atLineStart = true
+			continue
+		case c == '/' && peekIs(reader, '*'):
+			// Commentaire /* ... */, y compris les commentaires conditionnels /*!40101 ... */
+			if err := skipBlockComment(reader); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		statement.WriteRune(c)
+		atLineStart = c == '\n' || (atLineStart && (c == ' ' || c == '\t'))
+	}
+}
+
+// peekIs vérifie si le prochain caractère est c, sans le consommer
+func peekIs(reader *bufio.Reader, c byte) bool {
+	next, err := reader.Peek(1)
+	return err == nil && next[0] == c
+}
+
+// skipBlockComment consomme un commentaire /* ... */ dont le "/" a déjà été lu
+func skipBlockComment(reader *bufio.Reader) error {
+	reader.ReadByte() // Le "*" d'ouverture
+	previous := byte(0)
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return errors.New("unterminated comment in dump")
+		}
+		if previous == '*' && c == '/' {
+			return nil
+		}
+		previous = c
+	}
+}
+
+// parseStatement interprète une instruction CREATE TABLE ou INSERT portant sur une table demandée
+func parseStatement(statement string, wanted map[string]bool, tables map[string]*dumpTable) error {
+	upper := strings.ToUpper(statement[:min(len(statement), 32)])
+	switch {
+	case strings.HasPrefix(upper, "CREATE TABLE"):
+		name, rest := readIdentifier(skipKeywords(statement, "CREATE", "TABLE", "IF", "NOT", "EXISTS"))
+		if !wanted[name] {
+			return nil
+		}
+		columns, err := parseColumnDefinitions(rest)
+		if err != nil {
+			return fmt.Errorf("table %s: %v", name, err)
+		}
+		tables[name] = &dumpTable{columns: columns}
+
+	case strings.HasPrefix(upper, "INSERT") || strings.HasPrefix(upper, "REPLACE"):
+		name, rest := readIdentifier(skipKeywords(statement, "INSERT", "REPLACE", "IGNORE", "INTO"))
+		if !wanted[name] {
+			return nil
+		}
+		table := tables[name]
+		if table == nil {
+			table = &dumpTable{}
+			tables[name] = table
+		}
+		if err := parseInsert(rest, table); err != nil {
+			return fmt.Errorf("table %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// skipKeywords saute les mots-clés donnés au début de l'instruction, dans n'importe quel ordre
+func skipKeywords(s string, keywords ...string) string {
+	for {
+		s = strings.TrimSpace(s)
+		skipped := false
+		for _, keyword := range keywords {
+			if len(s) > len(keyword) && strings.EqualFold(s[:len(keyword)], keyword) && isSpace(s[len(keyword)]) {
+				s = s[len(keyword):]
+				skipped = true
+			}
+		}
+		if !skipped {
+			return s
+		}
+	}
+}
+
+// readIdentifier lit un nom de table ou de colonne, entre accents graves ou non
+func readIdentifier(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "`") {
+		end := strings.Index(s[1:], "`")
+		if end < 0 {
+			return s[1:], ""
+		}
+		return s[1 : end+1], s[end+2:]
+	}
+	end := strings.IndexFunc(s, func(c rune) bool { return c == ' ' || c == '(' || c == '\n' || c == '\t' })
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// parseColumnDefinitions extrait les noms des colonnes du corps d'un CREATE TABLE
+func parseColumnDefinitions(body string) ([]string, error) {
+	start := strings.Index(body, "(")
+	end := strings.LastIndex(body, ")")
+	if start < 0 || end < start {
+		return nil, errors.New("invalid CREATE TABLE statement")
+	}
+
+	var columns []string
+	for _, definition := range splitTopLevel(body[start+1 : end]) {
+		definition = strings.TrimSpace(definition)
+		// Les lignes PRIMARY KEY, KEY, UNIQUE... ne commencent pas par un nom de colonne entre accents graves
+		if strings.HasPrefix(definition, "`") {
+			name, _ := readIdentifier(definition)
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
+}
+
+// splitTopLevel découpe une liste sur les virgules qui ne sont ni entre parenthèses ni dans une chaîne
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseInsert lit la liste de colonnes facultative et les tuples de VALUES
+func parseInsert(s string, table *dumpTable) error {
+	s = strings.TrimSpace(s)
+	columns := table.columns
+	if strings.HasPrefix(s, "(") {
+		end := strings.Index(s, ")")
+		if end < 0 {
+			return errors.New("invalid column list")
+		}
+		columns = nil
+		for _, column := range strings.Split(s[1:end], ",") {
+			name, _ := readIdentifier(column)
+			columns = append(columns, name)
+		}
+		s = strings.TrimSpace(s[end+1:])
+	}
+	if len(columns) == 0 {
+		return errors.New("INSERT without column names before its CREATE TABLE")
+	}
+	if len(s) < 6 || !strings.EqualFold(s[:6], "VALUES") {
+		return errors.New("only INSERT ... VALUES is supported")
+	}
+
+	p := &valueParser{s: s[6:]}
+	for {
+		p.skipSpaces()
+		if p.done() {
+			return nil
+		}
+		values, err := p.tuple()
+		if err != nil {
+			return err
+		}
+		if len(values) != len(columns) {
+			return fmt.Errorf("expected %d values, got %d", len(columns), len(values))
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		table.rows = append(table.rows, row)
+
+		p.skipSpaces()
+		if !p.done() && p.s[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+// valueParser lit les tuples "(1,'texte',NULL)" d'un INSERT
+type valueParser struct {
+	s   string
+	pos int
+}
+
+func (p *valueParser) done() bool { return p.pos >= len(p.s) }
+
+func (p *valueParser) skipSpaces() {
+	for !p.done() && isSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// tuple lit un tuple entre parenthèses
+func (p *valueParser) tuple() ([]string, error) {
+	if p.s[p.pos] != '(' {
+		return nil, fmt.Errorf("expected '(' at offset %d", p.pos)
+	}
+	p.pos++
+
+	var values []string
+	for {
+		p.skipSpaces()
+		if p.done() {
+			return nil, errors.New("unterminated tuple")
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipSpaces()
+		if p.done() {
+			return nil, errors.New("unterminated tuple")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return values, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+		}
+	}
+}
+
+// value lit une chaîne entre apostrophes, un nombre ou NULL
+func (p *valueParser) value() (string, error) {
+	if p.s[p.pos] != '\'' {
+		start := p.pos
+		for !p.done() && p.s[p.pos] != ',' && p.s[p.pos] != ')' && !isSpace(p.s[p.pos]) {
+			p.pos++
+		}
+		value := p.s[start:p.pos]
+		if strings.EqualFold(value, "NULL") {
+			return "", nil
+		}
+		return value, nil
+	}
+
+	p.pos++
+	var value strings.Builder
+	for {
+		if p.done() {
+			return "", errors.New("unterminated string")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && !p.done():
+			value.WriteString(unescapeMySQL(p.s[p.pos]))
+			p.pos++
+		case c == '\'' && !p.done() && p.s[p.pos] == '\'':
+			// Apostrophe doublée
+			value.WriteByte('\'')
+			p.pos++
+		case c == '\'':
+			return value.String(), nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+}
+
+// unescapeMySQL traduit les séquences d'échappement de mysqldump
+func unescapeMySQL(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '0':
+		return "\x00"
+	case 'Z':
+		return "\x1a"
+	default:
+		return string(c)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t'
+}
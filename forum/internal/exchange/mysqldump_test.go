@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMySQLDump(t *testing.T) {
+	dump := "-- MySQL dump 10.13\n" +
+		"/*!40101 SET NAMES utf8mb4 */;\n" +
+		"# Commentaire à la MySQL; avec un point-virgule\n" +
+		"CREATE TABLE IF NOT EXISTS `t_items` (\n" +
+		"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` varchar(255) NOT NULL DEFAULT '',\n" +
+		"  `note` text COMMENT 'une, virgule',\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `name` (`name`,`id`)\n" +
+		") ENGINE=InnoDB;\n" +
+		"CREATE TABLE `t_other` (`id` int);\n" +
+		"INSERT INTO `t_other` VALUES (1);\n" +
+		"INSERT INTO `t_items` VALUES (1,'Simple',NULL),(2,'L''apostrophe; doublée','a\\'b\\\\c\\nd'),\n" +
+		"(3, 'Tuple (avec) parenthèses' , '-- pas un commentaire');\n" +
+		"INSERT IGNORE INTO t_items (`note`, `id`, `name`) VALUES ('/* gardé */',4,'Colonnes dans le désordre');\n"
+
+	tables, err := readMySQLDump(strings.NewReader(dump), map[string]bool{"t_items": true})
+	if err != nil {
+		t.Fatalf("reading dump: %v", err)
+	}
+	if len(tables) != 1 || tables["t_other"] != nil {
+		t.Errorf("tables read: %v, want only t_items", tables)
+	}
+	items := tables["t_items"]
+	if items == nil {
+		t.Fatal("table t_items not read")
+	}
+	if want := []string{"id", "name", "note"}; !reflect.DeepEqual(items.columns, want) {
+		t.Errorf("columns: %v, want %v", items.columns, want)
+	}
+	want := []map[string]string{
+		{"id": "1", "name": "Simple", "note": ""},
+		{"id": "2", "name": "L'apostrophe; doublée", "note": "a'b\\c\nd"},
+		{"id": "3", "name": "Tuple (avec) parenthèses", "note": "-- pas un commentaire"},
+		{"id": "4", "name": "Colonnes dans le désordre", "note": "/* gardé */"},
+	}
+	if !reflect.DeepEqual(items.rows, want) {
+		t.Errorf("rows:\n%v\nwant\n%v", items.rows, want)
+	}
+}
+
+func TestReadMySQLDumpErrors(t *testing.T) {
+	wanted := map[string]bool{"t": true}
+	for _, tt := range []struct {
+		dump string
+		err  string
+	}{
+		{"INSERT INTO `t` VALUES (1);", "without column names"},
+		{"CREATE TABLE `t` (`a` int);\nINSERT INTO `t` VALUES (1,2);", "expected 1 values, got 2"},
+		{"CREATE TABLE `t` (`a` int);\nINSERT INTO `t` VALUES (1", "unterminated tuple"},
+		{"CREATE TABLE `t` (`a` int);\nINSERT INTO `t` VALUES ('abc", "unterminated string"},
+		{"CREATE TABLE `t` (`a` int);\nINSERT INTO `t` SELECT 1;", "only INSERT ... VALUES"},
+		{"/* sans fin", "unterminated comment"},
+	} {
+		_, err := readMySQLDump(strings.NewReader(tt.dump), wanted)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("reading %q: got error %v, want %q", tt.dump, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package exchange
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Valeurs de phpBB utilisées par l'adaptateur
+const (
+	phpbbForumTypeCat   = "0" // FORUM_CAT: une catégorie qui ne contient que des forums
+	phpbbForumTypePost  = "1" // FORUM_POST: un forum qui contient des sujets
+	phpbbUserTypeIgnore = "2" // USER_IGNORE: l'invité anonyme et les robots
+	phpbbVisible        = "1" // ITEM_APPROVED
+)
+
+var (
+	// Smileys de phpBB 3.0: <!-- s:) --><img ... /><!-- s:) -->
+	phpbbSmiley = regexp.MustCompile(`<!-- s(.*?) --><img[^>]*><!-- s.*? -->`)
+	// Balises HTML des messages de phpBB 3.0 et balises XML de phpBB 3.2 et suivants
+	phpbbMarkup = regexp.MustCompile(`<!--.*?-->|<[^>]+>`)
+)
+
+// ReadPhpBB lit un dump MySQL d'une base phpBB 3
+// Les catégories et les forums deviennent des catégories, rangées comme dans phpBB, les sujets des posts (avec le texte de leur premier message)
+// et les autres messages des commentaires. Les remerciements de l'extension "Thanks for posts",
+// si sa table est présente, deviennent des "like"
+// prefix est le préfixe des tables, "phpbb_" par défaut
+func ReadPhpBB(r io.Reader, prefix string) (*Dataset, error) {
+	names := []string{"forums", "users", "topics", "posts", "thanks"}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[prefix+name] = true
+	}
+
+	tables, err := readMySQLDump(r, wanted)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names[:4] {
+		if tables[prefix+name] == nil {
+			return nil, fmt.Errorf("table %s%s not found in dump", prefix, name)
+		}
+	}
+
+	data := &Dataset{}
+
+	for _, row := range tables[prefix+"forums"].rows {
+		// Les liens (FORUM_LINK) ne contiennent rien
+		if row["forum_type"] != phpbbForumTypePost && row["forum_type"] != phpbbForumTypeCat {
+			continue
+		}
+		data.Categories = append(data.Categories, Category{
+			Type:        "category",
+			ID:          atoi(row["forum_id"]),
+			Name:        phpbbText(row["forum_name"], ""),
+			Description: phpbbText(row["forum_desc"], row["forum_desc_uid"]),
+			ParentID:    atoi(row["parent_id"]),
+		})
+	}
+
+	for _, row := range tables[prefix+"users"].rows {
+		if row["user_type"] == phpbbUserTypeIgnore {
+			continue
+		}
+		data.Users = append(data.Users, User{
+			Type:      "user",
+			ID:        atoi(row["user_id"]),
+			Email:     row["user_email"],
+			Username:  html.UnescapeString(row["username"]),
+			Role:      "user",
+			CreatedAt: unixTime(row["user_regdate"]),
+		})
+	}
+
+	// Les messages sont regroupés par sujet et triés par date
+	postsByTopic := map[string][]map[string]string{}
+	for _, row := range tables[prefix+"posts"].rows {
+		if !phpbbIsVisible(row, "post") {
+			continue
+		}
+		postsByTopic[row["topic_id"]] = append(postsByTopic[row["topic_id"]], row)
+	}
+
+	firstPosts := map[string]int{} // ID du premier message -> ID du sujet
+	for _, topic := range tables[prefix+"topics"].rows {
+		// Un sujet déplacé laisse une copie "fantôme" qui pointe vers le vrai sujet
+		if atoi(topic["topic_moved_id"]) != 0 || !phpbbIsVisible(topic, "topic") {
+			continue
+		}
+		messages := postsByTopic[topic["topic_id"]]
+		if len(messages) == 0 {
+			continue
+		}
+		sort.SliceStable(messages, func(i, j int) bool {
+			return atoi(messages[i]["post_time"]) < atoi(messages[j]["post_time"])
+		})
+
+		// Le premier message est désigné par topic_first_post_id, ou à défaut le plus ancien
+		first := messages[0]
+		for _, message := range messages {
+			if message["post_id"] == topic["topic_first_post_id"] {
+				first = message
+			}
+		}
+
+		topicID := atoi(topic["topic_id"])
+		firstPosts[first["post_id"]] = topicID
+		data.Posts = append(data.Posts, Post{
+			Type:        "post",
+			ID:          topicID,
+			UserID:      atoi(first["poster_id"]),
+			Title:       phpbbText(topic["topic_title"], ""),
+			Content:     phpbbText(first["post_text"], first["bbcode_uid"]),
+			CategoryIDs: []int{atoi(topic["forum_id"])},
+			CreatedAt:   unixTime(topic["topic_time"]),
+			UpdatedAt:   phpbbEditTime(first),
+		})
+
+		for _, message := range messages {
+			if message["post_id"] == first["post_id"] {
+				continue
+			}
+			data.Comments = append(data.Comments, Comment{
+				Type:      "comment",
+				ID:        atoi(message["post_id"]),
+				PostID:    topicID,
+				UserID:    atoi(message["poster_id"]),
+				Content:   phpbbText(message["post_text"], message["bbcode_uid"]),
+				CreatedAt: unixTime(message["post_time"]),
+				UpdatedAt: phpbbEditTime(message),
+			})
+		}
+	}
+
+	if thanks := tables[prefix+"thanks"]; thanks != nil {
+		for _, row := range thanks.rows {
+			createdAt := unixTime(row["thanks_time"])
+			if topicID, ok := firstPosts[row["post_id"]]; ok {
+				data.PostReactions = append(data.PostReactions, PostReaction{
+					Type: "post_reaction", UserID: atoi(row["user_id"]), PostID: topicID, Reaction: "like", CreatedAt: createdAt,
+				})
+			} else {
+				data.CommentReactions = append(data.CommentReactions, CommentReaction{
+					Type: "comment_reaction", UserID: atoi(row["user_id"]), CommentID: atoi(row["post_id"]), Reaction: "like", CreatedAt: createdAt,
+				})
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// phpbbIsVisible vérifie qu'un sujet ou un message est approuvé
+// phpBB 3.0 utilise la colonne *_approved, phpBB 3.1 et suivants la colonne *_visibility
+func phpbbIsVisible(row map[string]string, kind string) bool {
+	if visibility, ok := row[kind+"_visibility"]; ok {
+		return visibility == phpbbVisible
+	}
+	if approved, ok := row[kind+"_approved"]; ok {
+		return approved == "1"
+	}
+	return true
+}
+
+// phpbbEditTime renvoie la date de la dernière modification d'un message, ou sa date de publication
+func phpbbEditTime(row map[string]string) time.Time {
+	if atoi(row["post_edit_time"]) > 0 {
+		return unixTime(row["post_edit_time"])
+	}
+	return unixTime(row["post_time"])
+}
+
+// phpbbText convertit un texte stocké par phpBB en texte brut avec son BBCode d'origine
+// phpBB échappe le HTML, ajoute l'identifiant uid dans les balises BBCode ([b:uid]) et,
+// depuis la version 3.2, enveloppe le tout dans du XML
+func phpbbText(text, uid string) string {
+	text = phpbbSmiley.ReplaceAllString(text, "$1")
+	text = phpbbMarkup.ReplaceAllString(text, "")
+	if uid != "" {
+		text = strings.ReplaceAll(text, ":"+uid+"]", "]")
+		text = strings.NewReplacer("[/list:u]", "[/list]", "[/list:o]", "[/list]", "[/*:m]", "").Replace(text)
+	}
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// unixTime convertit un horodatage Unix de phpBB
+func unixTime(value string) time.Time {
+	seconds := atoi(value)
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0).UTC()
+}
+
+// atoi convertit une valeur du dump en entier, 0 si elle est vide ou invalide
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
@@ -0,0 +1,97 @@
+package exchange
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// phpbbDump est un extrait de dump d'une base phpBB 3.2 avec le préfixe "forum_"
+const phpbbDump = "CREATE TABLE `forum_forums` (`forum_id` int, `parent_id` int, `forum_type` tinyint, `forum_name` varchar(255), `forum_desc` text, `forum_desc_uid` varchar(8));\n" +
+	"INSERT INTO `forum_forums` VALUES (1,0,0,'Communauté','',''),(2,1,1,'Aide &amp; astuces','<t>Questions [b:ab12]fréquentes[/b:ab12]</t>','ab12'),(3,1,2,'Site officiel','','');\n" +
+	"CREATE TABLE `forum_users` (`user_id` int, `user_type` tinyint, `username` varchar(255), `user_email` varchar(100), `user_regdate` int);\n" +
+	"INSERT INTO `forum_users` VALUES (1,2,'Anonymous','',0),(2,0,'Zoé &amp; co','zoe@example.com',1700000000),(3,0,'marc','marc@example.com',1700000100);\n" +
+	"CREATE TABLE `forum_topics` (`topic_id` int, `forum_id` int, `topic_title` varchar(255), `topic_time` int, `topic_first_post_id` int, `topic_moved_id` int, `topic_visibility` tinyint);\n" +
+	"INSERT INTO `forum_topics` VALUES (10,2,'Première question',1700001000,100,0,1),(11,2,'Question déplacée',1700001000,100,10,1),(12,2,'Sujet refusé',1700002000,120,0,2);\n" +
+	"CREATE TABLE `forum_posts` (`post_id` int, `topic_id` int, `poster_id` int, `post_time` int, `post_edit_time` int, `post_text` mediumtext, `bbcode_uid` varchar(8), `post_visibility` tinyint);\n" +
+	"INSERT INTO `forum_posts` VALUES (101,10,3,1700001500,0,'<r>Réponse <!-- s:) --><img src=\"smile.gif\"><!-- s:) --> [list:cd34][*:cd34]un[/*:m][/list:u:cd34]</r>','cd34',1)," +
+	"(100,10,2,1700001000,1700001200,'<t>Comment fait-on ?</t>','',1),(102,10,3,1700001600,0,'En attente','',0),(120,12,2,1700002000,0,'Refusé','',1);\n" +
+	"CREATE TABLE `forum_thanks` (`post_id` int, `user_id` int, `thanks_time` int);\n" +
+	"INSERT INTO `forum_thanks` VALUES (100,3,1700001700),(101,2,1700001800);\n"
+
+func TestReadPhpBB(t *testing.T) {
+	data, err := ReadPhpBB(strings.NewReader(phpbbDump), "forum_")
+	if err != nil {
+		t.Fatalf("reading dump: %v", err)
+	}
+
+	// Les liens sont ignorés, les forums gardent leur catégorie parente
+	wantCategories := []Category{
+		{Type: "category", ID: 1, Name: "Communauté"},
+		{Type: "category", ID: 2, Name: "Aide & astuces", Description: "Questions [b]fréquentes[/b]", ParentID: 1},
+	}
+	if !reflect.DeepEqual(data.Categories, wantCategories) {
+		t.Errorf("categories: %+v", data.Categories)
+	}
+
+	// L'invité anonyme et les robots ne sont pas importés
+	wantUsers := []User{
+		{Type: "user", ID: 2, Email: "zoe@example.com", Username: "Zoé & co", Role: "user", CreatedAt: time.Unix(1700000000, 0).UTC()},
+		{Type: "user", ID: 3, Email: "marc@example.com", Username: "marc", Role: "user", CreatedAt: time.Unix(1700000100, 0).UTC()},
+	}
+	if !reflect.DeepEqual(data.Users, wantUsers) {
+		t.Errorf("users: %+v", data.Users)
+	}
+
+	// Le sujet déplacé et le sujet refusé sont ignorés; le premier message est celui du sujet, même listé après
+	wantPosts := []Post{{
+		Type: "post", ID: 10, UserID: 2, Title: "Première question", Content: "Comment fait-on ?", CategoryIDs: []int{2},
+		CreatedAt: time.Unix(1700001000, 0).UTC(), UpdatedAt: time.Unix(1700001200, 0).UTC(),
+	}}
+	if !reflect.DeepEqual(data.Posts, wantPosts) {
+		t.Errorf("posts: %+v", data.Posts)
+	}
+
+	// Le message en attente n'est pas importé, le BBCode perd l'identifiant uid et les smileys leur image
+	wantComments := []Comment{{
+		Type: "comment", ID: 101, PostID: 10, UserID: 3, Content: "Réponse :) [list][*]un[/list]",
+		CreatedAt: time.Unix(1700001500, 0).UTC(), UpdatedAt: time.Unix(1700001500, 0).UTC(),
+	}}
+	if !reflect.DeepEqual(data.Comments, wantComments) {
+		t.Errorf("comments: %+v", data.Comments)
+	}
+
+	// Un remerciement sur le premier message porte sur le post, les autres sur les commentaires
+	wantPostReactions := []PostReaction{{Type: "post_reaction", UserID: 3, PostID: 10, Reaction: "like", CreatedAt: time.Unix(1700001700, 0).UTC()}}
+	wantCommentReactions := []CommentReaction{{Type: "comment_reaction", UserID: 2, CommentID: 101, Reaction: "like", CreatedAt: time.Unix(1700001800, 0).UTC()}}
+	if !reflect.DeepEqual(data.PostReactions, wantPostReactions) || !reflect.DeepEqual(data.CommentReactions, wantCommentReactions) {
+		t.Errorf("reactions: %+v and %+v", data.PostReactions, data.CommentReactions)
+	}
+}
+
+func TestReadPhpBBMissingTable(t *testing.T) {
+	_, err := ReadPhpBB(strings.NewReader(phpbbDump), "phpbb_")
+	if err == nil || !strings.Contains(err.Error(), "phpbb_forums not found") {
+		t.Errorf("reading with the wrong prefix: got error %v", err)
+	}
+}
+
+// phpBB 3.0 marque les sujets et les messages approuvés dans les colonnes *_approved
+func TestPhpBBIsVisible(t *testing.T) {
+	for _, tt := range []struct {
+		row  map[string]string
+		want bool
+	}{
+		{map[string]string{"post_visibility": "1"}, true},
+		{map[string]string{"post_visibility": "0"}, false},
+		{map[string]string{"post_visibility": "2", "post_approved": "1"}, false},
+		{map[string]string{"post_approved": "1"}, true},
+		{map[string]string{"post_approved": "0"}, false},
+		{map[string]string{}, true},
+	} {
+		if got := phpbbIsVisible(tt.row, "post"); got != tt.want {
+			t.Errorf("phpbbIsVisible(%v) = %v, want %v", tt.row, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package exchange
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Taille maximale d'une ligne NDJSON: un post très long tient sur une seule ligne
+const maxLineSize = 64 << 20
+
+// ReadNDJSON lit un export au format NDJSON
+func ReadNDJSON(r io.Reader) (*Dataset, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	data := &Dataset{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		if lineNumber == 1 && header.Type != "meta" {
+			return nil, errors.New("line 1: missing meta record")
+		}
+
+		var err error
+		switch header.Type {
+		case "meta":
+			var meta Meta
+			if err = json.Unmarshal(line, &meta); err == nil {
+				err = checkMeta(meta)
+			}
+		case "category":
+			err = appendRecord(line, &data.Categories)
+		case "user":
+			err = appendRecord(line, &data.Users)
+		case "post":
+			err = appendRecord(line, &data.Posts)
+		case "comment":
+			err = appendRecord(line, &data.Comments)
+		case "post_reaction":
+			err = appendRecord(line, &data.PostReactions)
+		case "comment_reaction":
+			err = appendRecord(line, &data.CommentReactions)
+		case "image", "report":
+			// Les images et les signalements ne sont pas importés
+		default:
+			err = fmt.Errorf("unknown record type %q", header.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNumber == 0 {
+		return nil, errors.New("empty export")
+	}
+	return data, nil
+}
+
+// ReadJSON lit un export au format JSON
+func ReadJSON(r io.Reader) (*Dataset, error) {
+	var export struct {
+		Meta             Meta              `json:"meta"`
+		Categories       []Category        `json:"categories"`
+		Users            []User            `json:"users"`
+		Posts            []Post            `json:"posts"`
+		Comments         []Comment         `json:"comments"`
+		PostReactions    []PostReaction    `json:"post_reactions"`
+		CommentReactions []CommentReaction `json:"comment_reactions"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	if err := checkMeta(export.Meta); err != nil {
+		return nil, err
+	}
+
+	return &Dataset{
+		Categories:       export.Categories,
+		Users:            export.Users,
+		Posts:            export.Posts,
+		Comments:         export.Comments,
+		PostReactions:    export.PostReactions,
+		CommentReactions: export.CommentReactions,
+	}, nil
+}
+
+// checkMeta vérifie que l'en-tête correspond à une version connue du format
+func checkMeta(meta Meta) error {
+	if meta.Format != FormatName {
+		return fmt.Errorf("unknown format %q", meta.Format)
+	}
+	if meta.Version < 1 || meta.Version > FormatVersion {
+		return fmt.Errorf("unsupported format version %d", meta.Version)
+	}
+	return nil
+}
+
+// appendRecord décode une ligne et l'ajoute à la liste
+func appendRecord[T any](line []byte, records *[]T) error {
+	var record T
+	if err := json.Unmarshal(line, &record); err != nil {
+		return err
+	}
+	*records = append(*records, record)
+	return nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// announcementErrors traduit les erreurs des annonces qui viennent de la saisie du modérateur
+var announcementErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrAnnouncementRequired, "Le message de l'annonce est obligatoire", http.StatusBadRequest},
+		{models.ErrAnnouncementTooLong, "Le message de l'annonce est trop long (500 caractères au plus)", http.StatusBadRequest},
+		{models.ErrInvalidAnnouncementLevel, "Type d'annonce invalide", http.StatusBadRequest},
+		{errInvalidExpiry, "Date de fin invalide", http.StatusBadRequest},
+		{models.ErrAnnouncementExpiryInPast, "La fin de l'annonce doit être dans le futur", http.StatusBadRequest},
+		{models.ErrAnnouncementNotFound, "Annonce introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de l'enregistrement de l'annonce",
+}
+
+// ListAnnouncementsHandler affiche les annonces, expirées comprises, et le formulaire d'une nouvelle annonce
+func (h *Handler) ListAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	currentUser := requireModerator(w, r)
+	if currentUser == nil {
+		return
+	}
+
+	announcements, err := h.Moderation.GetAnnouncements(r.Context(), time.Now())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des annonces", http.StatusInternalServerError)
+		log.Printf("Error fetching announcements: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Announcements": announcements,
+		"CurrentUser":   currentUser,
+		"PageTitle":     "Annonces",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/mod_announcements.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// CreateAnnouncementHandler publie une annonce: POST /mod/announcement/create avec message, level
+// et expires_at (facultatif)
+func (h *Handler) CreateAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	currentUser := requireModerator(w, r)
+	if currentUser == nil {
+		return
+	}
+
+	expiresAt, err := expiryFromForm(r, "expires_at")
+	if err != nil {
+		respondError(w, err, announcementErrors, "creating announcement")
+		return
+	}
+	announcement, err := models.NewAnnouncement(r.FormValue("message"), r.FormValue("level"), currentUser.ID, expiresAt, time.Now())
+	if err != nil {
+		respondError(w, err, announcementErrors, "creating announcement")
+		return
+	}
+	if _, err := h.Moderation.CreateAnnouncement(r.Context(), announcement); err != nil {
+		respondError(w, err, announcementErrors, "creating announcement")
+		return
+	}
+
+	http.Redirect(w, r, "/mod/announcements", http.StatusSeeOther)
+}
+
+// DeleteAnnouncementHandler retire une annonce: POST /mod/announcement/delete avec announcement_id
+func (h *Handler) DeleteAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireModerator(w, r) == nil {
+		return
+	}
+
+	announcementID, err := strconv.Atoi(r.FormValue("announcement_id"))
+	if err != nil || announcementID <= 0 {
+		http.Error(w, "Annonce invalide", http.StatusBadRequest)
+		return
+	}
+	if err := h.Moderation.DeleteAnnouncement(r.Context(), announcementID); err != nil {
+		respondError(w, err, announcementErrors, "deleting announcement")
+		return
+	}
+
+	http.Redirect(w, r, "/mod/announcements", http.StatusSeeOther)
+}
+
+// activeAnnouncements renvoie les annonces en cours, que base.html affiche en haut de toutes les pages
+// sous la clé ActiveAnnouncements. Elles sont lues à chaque page pour que leur expiration s'applique
+// sans délai. Une erreur est journalisée et n'empêche pas d'afficher la page
+func (h *Handler) activeAnnouncements(r *http.Request) []*models.Announcement {
+	announcements, err := h.Moderation.GetActiveAnnouncements(r.Context(), time.Now())
+	if err != nil {
+		log.Printf("Error fetching active announcements: %v", err)
+		return nil
+	}
+	return announcements
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// L'auteur du post et les modérateurs choisissent la réponse acceptée, les autres membres non
+func TestAcceptAnswer(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	author := createUser(t, h, "author", "user")
+	other := createUser(t, h, "other", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, author, "Question ouverte", 1)
+	createPost(t, h, author, "Question sans réponse", 1)
+	answerID, err := h.Comments.CreateComment(ctx, "Première réponse", other.ID, postID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	betterID, err := h.Comments.CreateComment(ctx, "Meilleure réponse", moderator.ID, postID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	accept := func(commentID int) *http.Request {
+		return postForm("/post/accept", url.Values{"post_id": {itoa(postID)}, "comment_id": {itoa(commentID)}})
+	}
+	accepted := func() int {
+		t.Helper()
+		post, err := h.Posts.GetPostByID(ctx, postID, 0)
+		if err != nil {
+			t.Fatalf("fetching post: %v", err)
+		}
+		return post.AcceptedCommentID
+	}
+
+	w := serve(h.AcceptAnswerHandler, accept(answerID), author)
+	expectRedirect(t, w, "/post/"+itoa(postID)+"#comment-"+itoa(answerID))
+	if got := accepted(); got != answerID {
+		t.Errorf("accepted answer after the author's choice: got %d, want %d", got, answerID)
+	}
+
+	w = serve(h.AcceptAnswerHandler, accept(betterID), other)
+	expectStatus(t, w, http.StatusForbidden)
+	w = serve(h.AcceptAnswerHandler, accept(betterID), moderator)
+	expectRedirect(t, w, "/post/"+itoa(postID)+"#comment-"+itoa(betterID))
+	if got := accepted(); got != betterID {
+		t.Errorf("accepted answer after the moderator's choice: got %d, want %d", got, betterID)
+	}
+
+	// Un commentaire d'un autre post ou un post inconnu sont refusés
+	w = serve(h.AcceptAnswerHandler, accept(999), author)
+	expectStatus(t, w, http.StatusBadRequest)
+	w = serve(h.AcceptAnswerHandler, postForm("/post/accept", url.Values{"post_id": {"999"}, "comment_id": {"0"}}), moderator)
+	expectStatus(t, w, http.StatusNotFound)
+
+	// Le filtre des posts résolus ne garde que le post qui a une réponse acceptée
+	for filter, want := range map[string]string{"solved": "Question ouverte", "unsolved": "Question sans réponse"} {
+		w = serve(h.HomeHandler, get("/?solved="+filter), nil)
+		expectStatus(t, w, http.StatusOK)
+		body := w.Body.String()
+		if !strings.Contains(body, want) || strings.Count(body, "Question ") != strings.Count(body, want) {
+			t.Errorf("home page filtered on %s does not list only %q", filter, want)
+		}
+	}
+
+	// comment_id 0 retire la réponse acceptée
+	w = serve(h.AcceptAnswerHandler, accept(0), author)
+	expectRedirect(t, w, "/post/"+itoa(postID))
+	if got := accepted(); got != 0 {
+		t.Errorf("accepted answer after removal: got %d, want 0", got)
+	}
+}
@@ -1,6 +1,5 @@
 package handlers
 import (
-	"forum/internal/models"
 	"html/template"
 	"log"
 	"net/http"
@@ -9,7 +8,7 @@ import (
 
 // RegisterHandler s'occupe de l'inscription des nouveaux utilisateurs
 // C'est comme un guichetier qui traite les formulaires d'inscription
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Si quelqu'un demande simplement à voir le formulaire d'inscription (requête GET)
 	// C'est comme quand quelqu'un vous demande un formulaire vierge
 	if r.Method == http.MethodGet {
@@ -61,7 +60,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		
 		// On enregistre le nouvel utilisateur dans notre base de données
 		// C'est comme ajouter une nouvelle fiche dans notre registre des membres
-		err = models.RegisterUser(email, username, password)
+		err = h.Users.RegisterUser(r.Context(), email, username, password)
 		if err != nil {
 			// Si l'inscription échoue (par exemple si l'email est déjà pris), on signale l'erreur
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -81,7 +80,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 // LoginHandler s'occupe de la connexion des utilisateurs
 // C'est comme un agent de sécurité qui vérifie les identifiants à l'entrée
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Si quelqu'un demande simplement à voir le formulaire de connexion (requête GET)
 	// C'est comme quand quelqu'un demande un badge d'accès vierge
 	if r.Method == http.MethodGet {
@@ -116,7 +115,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		
 		// On vérifie si les identifiants sont corrects
 		// C'est comme vérifier si les papiers d'identité sont authentiques
-		user, err := models.Authenticate(email, password)
+		user, err := h.Users.Authenticate(r.Context(), email, password)
 		if err != nil {
 			// Si les identifiants sont incorrects, on refuse l'accès
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
@@ -125,7 +124,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		
 		// Si les identifiants sont corrects, on crée une session pour l'utilisateur
 		// C'est comme donner un bracelet temporaire à un visiteur qui a montré ses papiers
-		session, err := models.CreateSession(user.ID)
+		session, err := h.Sessions.CreateSession(r.Context(), user.ID)
 		if err != nil {
 			// Si on n'arrive pas à créer la session, on signale une erreur
 			http.Error(w, "Failed to create session", http.StatusInternalServerError)
@@ -158,14 +157,14 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 // LogoutHandler s'occupe de la déconnexion des utilisateurs
 // C'est comme un agent qui récupère le bracelet d'accès quand un visiteur part
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	// On récupère le cookie de session dans le navigateur de l'utilisateur
 	// C'est comme vérifier si le visiteur a bien un bracelet
 	cookie, err := r.Cookie("session")
 	if err == nil {
 		// Si le cookie existe, on supprime la session correspondante de la base de données
 		// C'est comme désactiver le bracelet dans notre système
-		models.DeleteSession(cookie.Value)
+		h.Sessions.DeleteSession(r.Context(), cookie.Value)
 		
 		// On supprime aussi le cookie du navigateur de l'utilisateur
 		// C'est comme retirer physiquement le bracelet au visiteur
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	h := newTestHandler(t)
+	expectStatus(t, serve(h.RegisterHandler, get("/register"), nil), http.StatusOK)
+
+	valid := url.Values{
+		"email": {"alice@example.com"}, "username": {"alice"},
+		"password": {"password123"}, "confirm_password": {"password123"},
+	}
+	expectRedirect(t, serve(h.RegisterHandler, postForm("/register", valid), nil), "/login")
+	if _, err := h.Users.GetUserByEmail(context.Background(), "alice@example.com"); err != nil {
+		t.Fatalf("registered user: %v", err)
+	}
+
+	for _, c := range []struct {
+		name  string
+		field string
+		value string
+	}{
+		{"missing username", "username", ""},
+		{"missing password", "password", ""},
+		{"different confirmation", "confirm_password", "password456"},
+		{"email already used", "username", "alice2"},
+	} {
+		form := url.Values{}
+		for key, value := range valid {
+			form[key] = value
+		}
+		form.Set(c.field, c.value)
+		if w := serve(h.RegisterHandler, postForm("/register", form), nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", c.name, w.Code)
+		}
+	}
+	expectStatus(t, serve(h.RegisterHandler, httptest.NewRequest(http.MethodDelete, "/register", nil), nil), http.StatusMethodNotAllowed)
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	h := newTestHandler(t)
+	createUser(t, h, "alice", "user")
+	expectStatus(t, serve(h.LoginHandler, get("/login"), nil), http.StatusOK)
+
+	wrong := url.Values{"email": {"alice@example.com"}, "password": {"mauvais"}}
+	expectStatus(t, serve(h.LoginHandler, postForm("/login", wrong), nil), http.StatusUnauthorized)
+
+	w := serve(h.LoginHandler, postForm("/login", url.Values{"email": {"alice@example.com"}, "password": {"password123"}}), nil)
+	expectRedirect(t, w, "/")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || !cookies[0].HttpOnly || cookies[0].Value == "" {
+		t.Fatalf("session cookie: %+v", cookies)
+	}
+	session, err := h.Sessions.GetSessionByUUID(context.Background(), cookies[0].Value)
+	if err != nil {
+		t.Fatalf("session after login: %v", err)
+	}
+
+	r := get("/logout")
+	r.AddCookie(cookies[0])
+	w = serve(h.LogoutHandler, r, nil)
+	expectRedirect(t, w, "/")
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("cookie after logout: %+v", cleared)
+	}
+	if _, err := h.Sessions.GetSessionByUUID(context.Background(), session.UUID); err == nil {
+		t.Error("session still exists after logout")
+	}
+}
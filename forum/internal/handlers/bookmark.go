@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookmarkErrors traduit les erreurs des signets qui viennent de la saisie de l'utilisateur
+var bookmarkErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrInvalidBookmarkTarget, "Contenu invalide", http.StatusBadRequest},
+		{models.ErrBookmarkTargetNotFound, "Contenu introuvable", http.StatusNotFound},
+		{models.ErrBookmarkNotFound, "Signet introuvable", http.StatusNotFound},
+		{models.ErrBookmarkNoteTooLong, "La note est trop longue", http.StatusBadRequest},
+		{models.ErrInvalidFolderName, "Nom de dossier invalide", http.StatusBadRequest},
+		{models.ErrFolderExists, "Ce dossier existe déjà", http.StatusBadRequest},
+		{models.ErrFolderNotFound, "Dossier introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de la mise à jour des signets",
+}
+
+// savedTab est l'adresse de l'onglet des signets du profil
+const savedTab = "/profile?tab=saved"
+
+// BookmarkHandler traite les formulaires des signets: /bookmark/save enregistre un post ou un commentaire,
+// ou change le dossier et la note d'un signet existant, et /bookmark/remove le retire
+func (h *Handler) BookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	targetType := r.FormValue("target_type")
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil || targetID <= 0 || !models.ValidBookmarkTarget(targetType) {
+		http.Error(w, "Contenu invalide", http.StatusBadRequest)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/bookmark/") {
+	case "save":
+		folderID := 0
+		if folderStr := r.FormValue("folder_id"); folderStr != "" {
+			folderID, err = strconv.Atoi(folderStr)
+			if err != nil || folderID < 0 {
+				http.Error(w, "Dossier invalide", http.StatusBadRequest)
+				return
+			}
+		}
+		err = h.Bookmarks.SaveBookmark(r.Context(), currentUser.ID, targetType, targetID, folderID, r.FormValue("note"))
+	case "remove":
+		err = h.Bookmarks.RemoveBookmark(r.Context(), currentUser.ID, targetType, targetID)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		respondError(w, err, bookmarkErrors, "updating bookmark")
+		return
+	}
+
+	http.Redirect(w, r, localRedirect(r.FormValue("redirect"), savedTab), http.StatusSeeOther)
+}
+
+// BookmarkFolderHandler traite les formulaires des dossiers de signets: /bookmark/folder/create
+// et /bookmark/folder/delete. Supprimer un dossier garde ses signets, hors de tout dossier
+func (h *Handler) BookmarkFolderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	redirect := savedTab
+	switch strings.TrimPrefix(r.URL.Path, "/bookmark/folder/") {
+	case "create":
+		folderID, err := h.Bookmarks.CreateBookmarkFolder(r.Context(), currentUser.ID, r.FormValue("name"))
+		if err != nil {
+			respondError(w, err, bookmarkErrors, "creating bookmark folder")
+			return
+		}
+		redirect += "&folder=" + strconv.Itoa(folderID)
+	case "delete":
+		folderID, err := strconv.Atoi(r.FormValue("folder_id"))
+		if err != nil || folderID <= 0 {
+			http.Error(w, "Dossier invalide", http.StatusBadRequest)
+			return
+		}
+		if err := h.Bookmarks.DeleteBookmarkFolder(r.Context(), currentUser.ID, folderID); err != nil {
+			respondError(w, err, bookmarkErrors, "deleting bookmark folder")
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// exportedBookmark est un signet dans l'export JSON
+type exportedBookmark struct {
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Author    string    `json:"author"`
+	Excerpt   string    `json:"excerpt"`
+	Folder    string    `json:"folder,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportBookmarksHandler télécharge tous les signets de l'utilisateur: /bookmarks/export?format=json|html
+// Le format html est le format de signets Netscape, que les navigateurs savent importer
+func (h *Handler) ExportBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "html" {
+		http.Error(w, "Format d'export invalide", http.StatusBadRequest)
+		return
+	}
+
+	bookmarks, err := h.Bookmarks.GetBookmarks(r.Context(), currentUser.ID, 0)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des signets", http.StatusInternalServerError)
+		log.Printf("Error fetching bookmarks: %v", err)
+		return
+	}
+
+	// Les liens exportés sont absolus pour rester utilisables hors du forum
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	origin := scheme + "://" + r.Host
+
+	w.Header().Set("Content-Disposition", `attachment; filename="signets.`+format+`"`)
+	if format == "json" {
+		exported := make([]exportedBookmark, 0, len(bookmarks))
+		for _, b := range bookmarks {
+			exported = append(exported, exportedBookmark{
+				Type:      b.TargetType,
+				Title:     b.PostTitle,
+				URL:       origin + b.Path(),
+				Author:    b.AuthorName,
+				Excerpt:   b.Excerpt,
+				Folder:    b.FolderName,
+				Note:      b.Note,
+				CreatedAt: b.CreatedAt,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(exported); err != nil {
+			log.Printf("Error encoding bookmarks export: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writeNetscapeBookmarks(w, bookmarks, origin)
+}
+
+// writeNetscapeBookmarks écrit des signets au format Netscape: les signets hors dossier d'abord,
+// puis un dossier <H3> par dossier de l'utilisateur, dans l'ordre de leur premier signet
+func writeNetscapeBookmarks(w http.ResponseWriter, bookmarks []*models.Bookmark, origin string) {
+	var folders []string
+	byFolder := map[string][]*models.Bookmark{}
+	for _, b := range bookmarks {
+		if _, ok := byFolder[b.FolderName]; !ok && b.FolderName != "" {
+			folders = append(folders, b.FolderName)
+		}
+		byFolder[b.FolderName] = append(byFolder[b.FolderName], b)
+	}
+
+	writeItems := func(items []*models.Bookmark, indent string) {
+		for _, b := range items {
+			title := b.PostTitle
+			if b.TargetType == models.BookmarkComment {
+				title = "Commentaire de " + b.AuthorName + " sur " + b.PostTitle
+			}
+			fmt.Fprintf(w, "%s<DT><A HREF=\"%s\" ADD_DATE=\"%d\">%s</A>\n",
+				indent, html.EscapeString(origin+b.Path()), b.CreatedAt.Unix(), html.EscapeString(title))
+			if b.Note != "" {
+				fmt.Fprintf(w, "%s<DD>%s\n", indent, html.EscapeString(b.Note))
+			}
+		}
+	}
+
+	fmt.Fprint(w, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	fmt.Fprint(w, "<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	fmt.Fprint(w, "<TITLE>Signets</TITLE>\n<H1>Signets</H1>\n<DL><p>\n")
+	writeItems(byFolder[""], "    ")
+	for _, folder := range folders {
+		fmt.Fprintf(w, "    <DT><H3>%s</H3>\n    <DL><p>\n", html.EscapeString(folder))
+		writeItems(byFolder[folder], "        ")
+		fmt.Fprint(w, "    </DL><p>\n")
+	}
+	fmt.Fprint(w, "</DL><p>\n")
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBookmarks(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	member := createUser(t, h, "member", "user")
+	postID := createPost(t, h, author, "Un post public", 1)
+
+	form := url.Values{"target_type": {"post"}, "target_id": {itoa(postID)}, "note": {"À relire"}}
+	expectRedirect(t, serve(h.BookmarkHandler, postForm("/bookmark/save", form), nil), "/login")
+	expectRedirect(t, serve(h.BookmarkHandler, postForm("/bookmark/save", form), member), savedTab)
+
+	w := serve(h.BookmarkFolderHandler, postForm("/bookmark/folder/create", url.Values{"name": {"Lectures"}}), member)
+	expectStatus(t, w, http.StatusSeeOther)
+	folders, err := h.Bookmarks.GetBookmarkFolders(context.Background(), member.ID)
+	if err != nil || len(folders) != 1 {
+		t.Fatalf("bookmark folders: %+v, error %v", folders, err)
+	}
+	w = serve(h.BookmarkFolderHandler, postForm("/bookmark/folder/create", url.Values{"name": {"Lectures"}}), member)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// Enregistrer de nouveau déplace le signet dans le dossier
+	form.Set("folder_id", itoa(folders[0].ID))
+	expectRedirect(t, serve(h.BookmarkHandler, postForm("/bookmark/save", form), member), savedTab)
+
+	w = serve(h.ExportBookmarksHandler, get("/bookmarks/export"), member)
+	expectStatus(t, w, http.StatusOK)
+	var exported []exportedBookmark
+	if err := json.NewDecoder(w.Body).Decode(&exported); err != nil {
+		t.Fatalf("decoding export: %v", err)
+	}
+	if len(exported) != 1 || exported[0].Title != "Un post public" || exported[0].Folder != "Lectures" || exported[0].Note != "À relire" {
+		t.Errorf("exported bookmarks: %+v", exported)
+	}
+
+	w = serve(h.ExportBookmarksHandler, get("/bookmarks/export?format=html"), member)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "<H3>Lectures</H3>") || !strings.Contains(body, "Un post public") {
+		t.Errorf("html export: %.300s", body)
+	}
+	expectStatus(t, serve(h.ExportBookmarksHandler, get("/bookmarks/export?format=csv"), member), http.StatusBadRequest)
+
+	remove := url.Values{"target_type": {"post"}, "target_id": {itoa(postID)}}
+	expectRedirect(t, serve(h.BookmarkHandler, postForm("/bookmark/remove", remove), member), savedTab)
+	expectStatus(t, serve(h.BookmarkHandler, postForm("/bookmark/remove", remove), member), http.StatusNotFound)
+
+	missing := url.Values{"target_type": {"post"}, "target_id": {"999"}}
+	expectStatus(t, serve(h.BookmarkHandler, postForm("/bookmark/save", missing), member), http.StatusNotFound)
+	invalid := url.Values{"target_type": {"user"}, "target_id": {itoa(postID)}}
+	expectStatus(t, serve(h.BookmarkHandler, postForm("/bookmark/save", invalid), member), http.StatusBadRequest)
+}
+
+// Un post qu'on ne peut pas lire ne peut pas être enregistré, et ses signets ne sont plus exportés
+func TestBookmarkReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	moderator := createUser(t, h, "moderator", "moderator")
+	member := createUser(t, h, "member", "user")
+	hiddenID := createStaffPost(t, h, moderator, "Post du staff")
+	commentID, err := h.Comments.CreateComment(ctx, "Réponse du staff", moderator.ID, hiddenID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+
+	for _, target := range []url.Values{
+		{"target_type": {"post"}, "target_id": {itoa(hiddenID)}},
+		{"target_type": {"comment"}, "target_id": {itoa(commentID)}},
+	} {
+		w := serve(h.BookmarkHandler, postForm("/bookmark/save", target), member)
+		expectStatus(t, w, http.StatusNotFound)
+	}
+
+	// Le modérateur enregistre le post, puis perd son rôle: le signet n'est plus exporté
+	target := url.Values{"target_type": {"post"}, "target_id": {itoa(hiddenID)}}
+	expectRedirect(t, serve(h.BookmarkHandler, postForm("/bookmark/save", target), moderator), savedTab)
+	bookmarks, err := h.Bookmarks.GetBookmarks(ctx, moderator.ID, 0)
+	if err != nil || len(bookmarks) != 1 {
+		t.Fatalf("bookmarks of the moderator: %+v, error %v", bookmarks, err)
+	}
+	if err := h.Moderation.UpdateUserRole(ctx, moderator.ID, "user"); err != nil {
+		t.Fatalf("demoting moderator: %v", err)
+	}
+	moderator.Role = "user"
+	w := serve(h.ExportBookmarksHandler, get("/bookmarks/export"), moderator)
+	expectStatus(t, w, http.StatusOK)
+	if strings.Contains(w.Body.String(), "Post du staff") {
+		t.Errorf("export leaks a hidden post: %s", w.Body.String())
+	}
+}
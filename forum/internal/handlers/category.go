@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// categoryErrors traduit les erreurs de l'administration des catégories
+var categoryErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrCategoryNotFound, "Catégorie introuvable", http.StatusNotFound},
+		{models.ErrCategoryNameRequired, "Le nom de la catégorie est obligatoire", http.StatusBadRequest},
+		{models.ErrCategoryNameTooLong, "Le nom de la catégorie est trop long", http.StatusBadRequest},
+		{models.ErrInvalidCategoryColor, "La couleur doit être au format #rrggbb", http.StatusBadRequest},
+		{models.ErrInvalidPostPermission, "Droit de publication invalide", http.StatusBadRequest},
+		{models.ErrInvalidReadPermission, "Droit de lecture invalide", http.StatusBadRequest},
+		{models.ErrCategoryNameTaken, "Une catégorie porte déjà ce nom", http.StatusBadRequest},
+		{models.ErrMergeCategoryIntoItself, "Une catégorie ne peut pas être fusionnée avec elle-même", http.StatusBadRequest},
+		{models.ErrParentCategoryNotFound, "Catégorie parente introuvable", http.StatusBadRequest},
+		{models.ErrCategoryCycle, "Une catégorie ne peut pas être placée sous elle-même ou sous l'une de ses sous-catégories", http.StatusBadRequest},
+	},
+	failure: "Erreur lors de l'enregistrement de la catégorie",
+}
+
+// categoryPermissionErrors traduit les refus de publication dans les catégories choisies
+var categoryPermissionErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrCategoryUnavailable, "Catégorie introuvable", http.StatusForbidden},
+		{models.ErrCategoryArchived, "Une des catégories choisies est archivée", http.StatusForbidden},
+		{models.ErrCategoryReadOnly, "Une des catégories choisies est en lecture seule", http.StatusForbidden},
+		{models.ErrCategoryModeratorsOnly, "Seuls les modérateurs peuvent publier dans une des catégories choisies", http.StatusForbidden},
+	},
+	failure: "Erreur lors de la vérification des catégories",
+}
+
+// categoryJSON est la représentation d'une catégorie dans l'API d'administration
+type categoryJSON struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Position       int    `json:"position"`
+	Color          string `json:"color"`
+	Archived       bool   `json:"archived"`
+	PostPermission string `json:"post_permission"`
+	ReadPermission string `json:"read_permission"`
+	ParentID       int    `json:"parent_id"` // 0 pour une catégorie racine
+}
+
+// categoryPatchJSON contient les champs envoyés à l'API pour créer ou modifier une catégorie
+// Les champs absents gardent leur valeur actuelle
+type categoryPatchJSON struct {
+	Name           *string `json:"name"`
+	Description    *string `json:"description"`
+	Color          *string `json:"color"`
+	Archived       *bool   `json:"archived"`
+	PostPermission *string `json:"post_permission"`
+	ReadPermission *string `json:"read_permission"`
+	ParentID       *int    `json:"parent_id"`
+}
+
+// apply copie les champs présents dans la catégorie
+func (p categoryPatchJSON) apply(category *models.Category) {
+	if p.Name != nil {
+		category.Name = *p.Name
+	}
+	if p.Description != nil {
+		category.Description = sql.NullString{String: *p.Description, Valid: *p.Description != ""}
+	}
+	if p.Color != nil {
+		category.Color = *p.Color
+	}
+	if p.Archived != nil {
+		category.Archived = *p.Archived
+	}
+	if p.PostPermission != nil {
+		category.PostPermission = *p.PostPermission
+	}
+	if p.ReadPermission != nil {
+		category.ReadPermission = *p.ReadPermission
+	}
+	if p.ParentID != nil {
+		category.ParentID = *p.ParentID
+	}
+}
+
+func newCategoryJSON(category models.Category) categoryJSON {
+	return categoryJSON{
+		ID:             category.ID,
+		Name:           category.Name,
+		Description:    category.Description.String,
+		Position:       category.Position,
+		Color:          category.Color,
+		Archived:       category.Archived,
+		PostPermission: category.PostPermission,
+		ReadPermission: category.ReadPermission,
+		ParentID:       category.ParentID,
+	}
+}
+
+// categoryFromForm lit les champs du formulaire d'une catégorie
+func categoryFromForm(r *http.Request) models.Category {
+	description := strings.TrimSpace(r.FormValue("description"))
+	parentID, _ := strconv.Atoi(r.FormValue("parent_id"))
+	return models.Category{
+		Name:           r.FormValue("name"),
+		Description:    sql.NullString{String: description, Valid: description != ""},
+		Color:          r.FormValue("color"),
+		Archived:       r.FormValue("archived") == "on",
+		PostPermission: r.FormValue("post_permission"),
+		ReadPermission: r.FormValue("read_permission"),
+		ParentID:       parentID,
+	}
+}
+
+// categoryIDFromPath extrait l'ID de la catégorie de la fin d'une URL comme /admin/category/edit/3
+func categoryIDFromPath(path string) (int, bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 5 {
+		return 0, false
+	}
+	id, err := strconv.Atoi(parts[4])
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// requireAdmin vérifie que l'utilisateur est administrateur et répond 403 sinon
+func requireAdmin(w http.ResponseWriter, r *http.Request) *models.User {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || currentUser.Role != "admin" {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return nil
+	}
+	return currentUser
+}
+
+// requireModerator vérifie que l'utilisateur est modérateur ou administrateur et répond 403 sinon
+func requireModerator(w http.ResponseWriter, r *http.Request) *models.User {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return nil
+	}
+	return currentUser
+}
+
+// ListCategoriesHandler affiche la page d'administration des catégories
+func (h *Handler) ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := requireAdmin(w, r)
+	if currentUser == nil {
+		return
+	}
+
+	categories, err := h.Categories.GetCategories(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des catégories", http.StatusInternalServerError)
+		log.Printf("Error fetching categories: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Categories":  categories,
+		"CurrentUser": currentUser,
+		"PageTitle":   "Gestion des catégories",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/admin_categories.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// CreateCategoryHandler crée une catégorie depuis le formulaire d'administration
+func (h *Handler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireAdmin(w, r) == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête incorrecte", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.Categories.CreateCategory(r.Context(), categoryFromForm(r))
+	if err != nil {
+		respondError(w, err, categoryErrors, "creating category")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// UpdateCategoryHandler modifie une catégorie depuis le formulaire d'administration
+func (h *Handler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireAdmin(w, r) == nil {
+		return
+	}
+
+	categoryID, ok := categoryIDFromPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête incorrecte", http.StatusBadRequest)
+		return
+	}
+
+	category := categoryFromForm(r)
+	category.ID = categoryID
+	err := h.Categories.UpdateCategory(r.Context(), category)
+	if err != nil {
+		respondError(w, err, categoryErrors, "updating category")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// MoveCategoryHandler monte ou descend une catégorie d'un cran parmi les catégories de même parent
+func (h *Handler) MoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireAdmin(w, r) == nil {
+		return
+	}
+
+	categoryID, ok := categoryIDFromPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	direction := r.FormValue("direction")
+	if direction != "up" && direction != "down" {
+		http.Error(w, "Direction invalide", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := h.Categories.GetCategories(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des catégories", http.StatusInternalServerError)
+		log.Printf("Error fetching categories: %v", err)
+		return
+	}
+
+	// On échange la catégorie avec sa voisine de même parent, puis on enregistre l'ordre de ces catégories
+	parentID := -1
+	for _, category := range categories {
+		if category.ID == categoryID {
+			parentID = category.ParentID
+		}
+	}
+	if parentID < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	var order []int
+	index := -1
+	for _, category := range categories {
+		if category.ParentID == parentID {
+			if category.ID == categoryID {
+				index = len(order)
+			}
+			order = append(order, category.ID)
+		}
+	}
+	neighbour := index - 1
+	if direction == "down" {
+		neighbour = index + 1
+	}
+	if neighbour >= 0 && neighbour < len(order) {
+		order[index], order[neighbour] = order[neighbour], order[index]
+		if err := h.Categories.ReorderCategories(r.Context(), order); err != nil {
+			respondError(w, err, categoryErrors, "reordering categories")
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// MergeCategoryHandler fusionne une catégorie dans une autre
+func (h *Handler) MergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireAdmin(w, r) == nil {
+		return
+	}
+
+	categoryID, ok := categoryIDFromPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil || targetID <= 0 {
+		http.Error(w, "Catégorie cible invalide", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Categories.MergeCategories(r.Context(), categoryID, targetID)
+	if err != nil {
+		respondError(w, err, categoryErrors, "merging categories")
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// writeJSON envoie une réponse JSON
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// writeJSONError envoie une erreur au format {"error": "..."}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// APICategoriesHandler est l'API JSON d'administration des catégories:
+//
+//	GET    /admin/api/categories              liste toutes les catégories, archivées comprises
+//	POST   /admin/api/categories              crée une catégorie
+//	GET    /admin/api/categories/{id}         renvoie une catégorie
+//	PATCH  /admin/api/categories/{id}         modifie les champs envoyés
+//	POST   /admin/api/categories/{id}/merge   fusionne la catégorie dans {"target_id": n}
+//	POST   /admin/api/categories/reorder      range les catégories dans l'ordre {"ids": [...]}
+func (h *Handler) APICategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || currentUser.Role != "admin" {
+		writeJSONError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/api/categories"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "":
+		switch r.Method {
+		case http.MethodGet:
+			h.apiListCategories(w, r)
+		case http.MethodPost:
+			h.apiCreateCategory(w, r)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case path == "reorder":
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.apiReorderCategories(w, r)
+
+	default:
+		categoryID, err := strconv.Atoi(parts[0])
+		if err != nil || categoryID <= 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "merge") {
+			writeJSONError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodPost:
+			h.apiMergeCategory(w, r, categoryID)
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			h.apiGetCategory(w, r, categoryID)
+		case len(parts) == 1 && (r.Method == http.MethodPatch || r.Method == http.MethodPut):
+			h.apiUpdateCategory(w, r, categoryID)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func (h *Handler) apiListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Categories.GetCategories(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Error fetching categories")
+		log.Printf("Error fetching categories: %v", err)
+		return
+	}
+
+	response := []categoryJSON{}
+	for _, category := range categories {
+		response = append(response, newCategoryJSON(category))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) apiGetCategory(w http.ResponseWriter, r *http.Request, categoryID int) {
+	category, err := h.Categories.GetCategoryByID(r.Context(), categoryID)
+	if err != nil {
+		respondJSONError(w, err, categoryErrors, "fetching category")
+		return
+	}
+	writeJSON(w, http.StatusOK, newCategoryJSON(*category))
+}
+
+func (h *Handler) apiCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var patch categoryPatchJSON
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	var category models.Category
+	patch.apply(&category)
+	categoryID, err := h.Categories.CreateCategory(r.Context(), category)
+	if err != nil {
+		respondJSONError(w, err, categoryErrors, "creating category")
+		return
+	}
+
+	created, err := h.Categories.GetCategoryByID(r.Context(), categoryID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Error fetching category")
+		log.Printf("Error fetching category: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newCategoryJSON(*created))
+}
+
+func (h *Handler) apiUpdateCategory(w http.ResponseWriter, r *http.Request, categoryID int) {
+	category, err := h.Categories.GetCategoryByID(r.Context(), categoryID)
+	if err != nil {
+		respondJSONError(w, err, categoryErrors, "fetching category")
+		return
+	}
+
+	var patch categoryPatchJSON
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	patch.apply(category)
+
+	if err := h.Categories.UpdateCategory(r.Context(), *category); err != nil {
+		respondJSONError(w, err, categoryErrors, "updating category")
+		return
+	}
+	h.apiGetCategory(w, r, categoryID)
+}
+
+func (h *Handler) apiReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs []int `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if err := h.Categories.ReorderCategories(r.Context(), body.IDs); err != nil {
+		respondJSONError(w, err, categoryErrors, "reordering categories")
+		return
+	}
+	h.apiListCategories(w, r)
+}
+
+func (h *Handler) apiMergeCategory(w http.ResponseWriter, r *http.Request, categoryID int) {
+	var body struct {
+		TargetID int `json:"target_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if err := h.Categories.MergeCategories(r.Context(), categoryID, body.TargetID); err != nil {
+		respondJSONError(w, err, categoryErrors, "merging categories")
+		return
+	}
+	h.apiGetCategory(w, r, body.TargetID)
+}
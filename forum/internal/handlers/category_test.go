@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"forum/internal/models"
+	"forum/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// jsonRequest construit une requête de l'API JSON avec le corps donné
+func jsonRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Le formulaire d'administration traduit les erreurs de saisie et répond 404 pour une catégorie inconnue
+func TestCategoryAdminForm(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+
+	w := serve(h.CreateCategoryHandler, postForm("/admin/category/create", url.Values{"name": {"Nouvelle"}}), member)
+	expectStatus(t, w, http.StatusForbidden)
+
+	w = serve(h.CreateCategoryHandler, postForm("/admin/category/create", url.Values{"name": {"Nouvelle"}, "color": {"#12345"}}), admin)
+	expectStatus(t, w, http.StatusBadRequest)
+	if !strings.Contains(w.Body.String(), "#rrggbb") {
+		t.Errorf("invalid color message: %q", w.Body.String())
+	}
+
+	w = serve(h.CreateCategoryHandler, postForm("/admin/category/create", url.Values{"name": {"Nouvelle"}, "color": {"#123456"}}), admin)
+	expectRedirect(t, w, "/admin/categories")
+	w = serve(h.CreateCategoryHandler, postForm("/admin/category/create", url.Values{"name": {"Nouvelle"}}), admin)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = serve(h.UpdateCategoryHandler, postForm("/admin/category/edit/999", url.Values{"name": {"Renommée"}}), admin)
+	expectStatus(t, w, http.StatusNotFound)
+	w = serve(h.MergeCategoryHandler, postForm("/admin/category/merge/1", url.Values{"target_id": {"1"}}), admin)
+	expectStatus(t, w, http.StatusBadRequest)
+	w = serve(h.MergeCategoryHandler, postForm("/admin/category/merge/1", url.Values{"target_id": {"2"}}), admin)
+	expectRedirect(t, w, "/admin/categories")
+}
+
+// L'API JSON répond avec les mêmes messages, au format {"error": "..."}
+func TestCategoryAdminAPI(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+
+	w := serve(h.APICategoriesHandler, jsonRequest(http.MethodPost, "/admin/api/categories", `{"name": "API", "read_permission": "staff"}`), admin)
+	expectStatus(t, w, http.StatusCreated)
+	var created categoryJSON
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil || created.Name != "API" || created.ReadPermission != "staff" {
+		t.Fatalf("created category: %+v, error %v", created, err)
+	}
+
+	for _, c := range []struct {
+		method, target, body string
+		status               int
+		message              string
+	}{
+		{http.MethodGet, "/admin/api/categories/999", "", http.StatusNotFound, "Catégorie introuvable"},
+		{http.MethodPatch, "/admin/api/categories/" + itoa(created.ID), `{"name": ""}`, http.StatusBadRequest, "Le nom de la catégorie est obligatoire"},
+		{http.MethodPatch, "/admin/api/categories/" + itoa(created.ID), `{"parent_id": ` + itoa(created.ID) + `}`, http.StatusBadRequest, "Une catégorie ne peut pas être placée sous elle-même ou sous l'une de ses sous-catégories"},
+		{http.MethodPost, "/admin/api/categories/reorder", `{"ids": [999]}`, http.StatusNotFound, "Catégorie introuvable"},
+		{http.MethodPost, "/admin/api/categories/" + itoa(created.ID) + "/merge", `{"target_id": 999}`, http.StatusNotFound, "Catégorie introuvable"},
+	} {
+		w := serve(h.APICategoriesHandler, jsonRequest(c.method, c.target, c.body), admin)
+		var body map[string]string
+		json.NewDecoder(w.Body).Decode(&body)
+		if w.Code != c.status || body["error"] != c.message {
+			t.Errorf("%s %s: got %d %q, want %d %q", c.method, c.target, w.Code, body["error"], c.status, c.message)
+		}
+	}
+}
+
+// failingCategories simule une base de données indisponible pendant la vérification des catégories
+type failingCategories struct {
+	store.CategoryStore
+}
+
+func (failingCategories) CheckCategoryPermission(ctx context.Context, categoryIDs []int, role string) error {
+	return errors.New("database is locked")
+}
+
+// Un refus de publication répond 403 avec la raison, une panne de la base répond 500 sans la détailler
+func TestCreatePostCategoryPermission(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+	archivedID, err := h.Categories.CreateCategory(context.Background(), models.Category{Name: "Archives", Archived: true})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	r := multipartForm(t, "/post/create", map[string][]string{"title": {"Titre"}, "content": {"Contenu"}, "categories": {itoa(archivedID)}})
+	w := serve(h.CreatePostHandler, r, member)
+	expectStatus(t, w, http.StatusForbidden)
+	if !strings.Contains(w.Body.String(), "archivée") {
+		t.Errorf("archived category message: %q", w.Body.String())
+	}
+
+	h.Categories = failingCategories{h.Categories}
+	r = multipartForm(t, "/post/create", map[string][]string{"title": {"Titre"}, "content": {"Contenu"}, "categories": {"1"}})
+	w = serve(h.CreatePostHandler, r, member)
+	expectStatus(t, w, http.StatusInternalServerError)
+	if strings.Contains(w.Body.String(), "database is locked") {
+		t.Errorf("database error leaked to the client: %q", w.Body.String())
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"forum/internal/middleware"
 	"forum/internal/models"
 	"log"
@@ -9,8 +11,66 @@ import (
 	"strings"
 )
 
+// categoryPaths renvoie la branche de chaque catégorie du post, de la racine jusqu'à la catégorie
+func (h *Handler) categoryPaths(ctx context.Context, post *models.Post) ([][]models.Category, error) {
+	var paths [][]models.Category
+	for _, category := range post.Categories {
+		path, err := h.Categories.GetCategoryPath(ctx, category.ID)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// commentsAllowed vérifie qu'aucune catégorie du post, ni aucune de leurs catégories parentes, n'est en lecture seule
+func commentsAllowed(paths [][]models.Category) bool {
+	for _, path := range paths {
+		for _, category := range path {
+			if !category.AllowsComments() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readAllowed vérifie qu'un utilisateur de ce rôle peut lire toutes les catégories du post et leurs catégories parentes
+func readAllowed(paths [][]models.Category, role string) bool {
+	for _, path := range paths {
+		for _, category := range path {
+			if !category.AllowsReadFrom(role) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readablePost récupère un post que l'utilisateur (nil pour un visiteur) peut lire
+// Un post rangé dans une catégorie qu'il ne peut pas lire est introuvable, comme un post qui n'existe pas
+func (h *Handler) readablePost(ctx context.Context, postID int, user *models.User) (*models.Post, error) {
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	post, err := h.Posts.GetPostByID(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := h.categoryPaths(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	if !readAllowed(paths, userRole(user)) {
+		return nil, models.ErrPostNotFound
+	}
+	return post, nil
+}
+
 // CreateCommentHandler gère la création d'un nouveau commentaire
-func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -47,8 +107,48 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Les posts d'une catégorie en lecture seule ne peuvent plus être commentés
+	post, err := h.Posts.GetPostByID(r.Context(), postID, currentUser.ID)
+	if err != nil {
+		if err.Error() == "post not found" {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Error fetching post", http.StatusInternalServerError)
+			log.Printf("Error fetching post: %v", err)
+		}
+		return
+	}
+	paths, err := h.categoryPaths(r.Context(), post)
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		log.Printf("Error fetching category paths: %v", err)
+		return
+	}
+	if !readAllowed(paths, currentUser.Role) {
+		http.NotFound(w, r)
+		return
+	}
+	if !commentsAllowed(paths) {
+		http.Error(w, "Comments are closed on this post", http.StatusForbidden)
+		return
+	}
+	if post.Locked {
+		http.Error(w, "Cette discussion est verrouillée: les nouveaux commentaires sont fermés", http.StatusForbidden)
+		return
+	}
+
+	// Les liens vers d'autres sites demandent un niveau de confiance minimal
+	if !h.requireLinkTrust(w, r, currentUser, content) {
+		return
+	}
+
 	// Créer le commentaire
-	_, err = models.CreateComment(content, currentUser.ID, postID)
+	_, err = h.Comments.CreateComment(r.Context(), content, currentUser.ID, postID)
+	if errors.Is(err, models.ErrPostLocked) {
+		// Le post a été verrouillé depuis la vérification ci-dessus
+		http.Error(w, "Cette discussion est verrouillée: les nouveaux commentaires sont fermés", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error creating comment: %v", err)
@@ -60,7 +160,7 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // EditCommentHandler gère la modification d'un commentaire existant
-func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EditCommentHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -104,8 +204,13 @@ func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Les liens vers d'autres sites demandent un niveau de confiance minimal
+	if !h.requireLinkTrust(w, r, currentUser, content) {
+		return
+	}
+
 	// Mettre à jour le commentaire
-	err = models.UpdateComment(commentID, currentUser.ID, content)
+	err = h.Comments.UpdateComment(r.Context(), commentID, currentUser.ID, content)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error updating comment: %v", err)
@@ -117,7 +222,7 @@ func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteCommentHandler gère la suppression d'un commentaire
-func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 	// Extraire l'ID du commentaire de l'URL
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 4 || parts[2] != "delete" {
@@ -148,7 +253,7 @@ func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Supprimer le commentaire
 	isAdmin := currentUser.Role == "admin"
-	err = models.DeleteComment(commentID, currentUser.ID, isAdmin)
+	err = h.Comments.DeleteComment(r.Context(), commentID, currentUser.ID, isAdmin)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error deleting comment: %v", err)
@@ -159,8 +264,8 @@ func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/post/"+postIDStr, http.StatusSeeOther)
 }
 
-// ReactToCommentHandler gère les réactions (like/dislike) à un commentaire
-func ReactToCommentHandler(w http.ResponseWriter, r *http.Request) {
+// ReactToCommentHandler ajoute ou retire une réaction de l'utilisateur sur un commentaire
+func (h *Handler) ReactToCommentHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -184,7 +289,7 @@ func ReactToCommentHandler(w http.ResponseWriter, r *http.Request) {
 	// Récupérer les données du formulaire
 	commentIDStr := r.FormValue("comment_id")
 	postIDStr := r.FormValue("post_id")
-	reactionType := r.FormValue("reaction_type") // "like", "dislike" ou "" (pour supprimer)
+	reactionType := r.FormValue("reaction_type") // Identifiant de la réaction, ou "" pour retirer toutes les siennes
 
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil || commentID <= 0 {
@@ -199,10 +304,9 @@ func ReactToCommentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Enregistrer la réaction
-	err = models.ReactToComment(commentID, currentUser.ID, reactionType)
+	err = h.Comments.ReactToComment(r.Context(), commentID, currentUser.ID, reactionType)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("Error reacting to comment: %v", err)
+		respondError(w, err, reactionErrors, "reacting to comment")
 		return
 	}
 
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCreateComment(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+	postID := createPost(t, h, admin, "Un post", 1)
+
+	form := url.Values{"post_id": {itoa(postID)}, "content": {"Un commentaire"}}
+	w := serve(h.CreateCommentHandler, postForm("/comment/create", form), nil)
+	expectRedirect(t, w, "/login")
+
+	w = serve(h.CreateCommentHandler, postForm("/comment/create", url.Values{"post_id": {itoa(postID)}}), member)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = serve(h.CreateCommentHandler, postForm("/comment/create", form), member)
+	expectRedirect(t, w, "/post/"+itoa(postID))
+
+	comments, err := h.Comments.GetCommentsByPostID(context.Background(), postID, member.ID)
+	if err != nil || len(comments) != 1 || comments[0].Content != "Un commentaire" {
+		t.Fatalf("comments after creation: %v, error %v", comments, err)
+	}
+}
+
+func TestCreateCommentOnHiddenOrLockedPost(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+
+	staffID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Équipe", ReadPermission: models.CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	hiddenID := createPost(t, h, admin, "Post du staff", staffID)
+	w := serve(h.CreateCommentHandler, postForm("/comment/create", url.Values{
+		"post_id": {itoa(hiddenID)}, "content": {"Bonjour"},
+	}), member)
+	expectStatus(t, w, http.StatusNotFound)
+
+	lockedID := createPost(t, h, admin, "Post verrouillé", 1)
+	if err := h.Moderation.SetPostLocked(ctx, lockedID, true); err != nil {
+		t.Fatalf("locking post: %v", err)
+	}
+	w = serve(h.CreateCommentHandler, postForm("/comment/create", url.Values{
+		"post_id": {itoa(lockedID)}, "content": {"Bonjour"},
+	}), member)
+	expectStatus(t, w, http.StatusForbidden)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/digest"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// DigestSettingsHandler enregistre la fréquence des résumés par email de l'utilisateur connecté
+func (h *Handler) DigestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	frequency := r.FormValue("frequency")
+	if !models.ValidDigestFrequency(frequency) {
+		http.Error(w, "Fréquence invalide", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Digests.SetDigestFrequency(r.Context(), currentUser.ID, frequency)
+	if err != nil {
+		http.Error(w, "Erreur lors de la mise à jour des résumés", http.StatusInternalServerError)
+		log.Printf("Error updating digest frequency: %v", err)
+		return
+	}
+
+	http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+}
+
+// UnsubscribeDigestHandler désactive les résumés par email depuis le lien signé d'un email
+// Le lien fonctionne sans connexion. Un GET, que les clients mail et les antivirus peuvent faire
+// d'eux-mêmes en ouvrant le lien, ne fait qu'afficher une confirmation: seul un POST désabonne.
+// Les clients mail qui gèrent le désabonnement en un clic (RFC 8058) envoient ce POST directement
+func (h *Handler) UnsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("user"))
+	if err != nil || !digest.VerifyUnsubscribe(h.Secret, userID, r.URL.Query().Get("sig")) {
+		http.Error(w, "Lien de désabonnement invalide", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		err = h.Digests.SetDigestFrequency(r.Context(), userID, models.DigestNone)
+		if err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				http.NotFound(w, r)
+			} else {
+				http.Error(w, "Erreur lors du désabonnement", http.StatusInternalServerError)
+				log.Printf("Error unsubscribing from digests: %v", err)
+			}
+			return
+		}
+
+		// Le client mail n'attend qu'un code de réponse
+		if r.FormValue("List-Unsubscribe") == "One-Click" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser":  middleware.GetUserFromContext(r),
+		"PageTitle":    "Désabonnement",
+		"Unsubscribed": r.Method == http.MethodPost,
+		"ActionURL":    r.URL.RequestURI(),
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/digest_unsubscribe.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/digest"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// digestFrequency renvoie la fréquence des résumés enregistrée pour l'utilisateur
+func digestFrequency(t *testing.T, h *Handler, userID int) string {
+	t.Helper()
+	user, err := h.Users.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("fetching user: %v", err)
+	}
+	return user.DigestFrequency
+}
+
+// Ouvrir le lien de désabonnement n'affiche qu'une confirmation: seul le POST désabonne
+func TestUnsubscribeDigest(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+	if err := h.Digests.SetDigestFrequency(context.Background(), member.ID, models.DigestDaily); err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+	link := digest.UnsubscribeURL("", h.Secret, member.ID)
+
+	w := serve(h.UnsubscribeDigestHandler, get(link), nil)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), `method="post"`) {
+		t.Error("GET does not render the confirmation form")
+	}
+	if frequency := digestFrequency(t, h, member.ID); frequency != models.DigestDaily {
+		t.Fatalf("GET unsubscribed the user: frequency %q", frequency)
+	}
+
+	w = serve(h.UnsubscribeDigestHandler, postForm(link, nil), nil)
+	expectStatus(t, w, http.StatusOK)
+	if frequency := digestFrequency(t, h, member.ID); frequency != models.DigestNone {
+		t.Errorf("POST did not unsubscribe the user: frequency %q", frequency)
+	}
+}
+
+// Le client mail envoie le POST en un clic sans passer par la page (RFC 8058)
+func TestUnsubscribeDigestOneClick(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+	if err := h.Digests.SetDigestFrequency(context.Background(), member.ID, models.DigestWeekly); err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+	link := digest.UnsubscribeURL("", h.Secret, member.ID)
+
+	w := serve(h.UnsubscribeDigestHandler, postForm(link, url.Values{"List-Unsubscribe": {"One-Click"}}), nil)
+	expectStatus(t, w, http.StatusOK)
+	if w.Body.Len() != 0 {
+		t.Errorf("one-click response has a body: %.100s", w.Body.String())
+	}
+	if frequency := digestFrequency(t, h, member.ID); frequency != models.DigestNone {
+		t.Errorf("one-click POST did not unsubscribe the user: frequency %q", frequency)
+	}
+
+	w = serve(h.UnsubscribeDigestHandler, postForm(link+"0", nil), nil)
+	expectStatus(t, w, http.StatusBadRequest)
+}
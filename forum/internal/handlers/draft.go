@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// draftErrors traduit les erreurs des brouillons qui viennent de la saisie de l'utilisateur
+var draftErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrDraftNotFound, "Brouillon introuvable", http.StatusNotFound},
+		{models.ErrDraftEmpty, "Le brouillon est vide", http.StatusBadRequest},
+		{models.ErrTooManyDrafts, "Vous avez atteint le nombre maximal de brouillons", http.StatusBadRequest},
+		{models.ErrPostNotFound, "Post introuvable", http.StatusNotFound},
+		{errInvalidExpiry, "Date de publication invalide", http.StatusBadRequest},
+		{errPublishTimeRequired, "Choisissez une date de publication", http.StatusBadRequest},
+		{models.ErrPublishTimeInPast, "La date de publication doit être dans le futur", http.StatusBadRequest},
+		{models.ErrOnlyNewPostsScheduled, "Seuls les nouveaux posts peuvent être programmés", http.StatusBadRequest},
+		{errScheduledAttachments, "Un post programmé ne peut pas avoir de sondage ni d'image", http.StatusBadRequest},
+	},
+	failure: "Erreur lors de l'enregistrement du brouillon",
+}
+
+// Erreurs de la programmation d'un post qui viennent du formulaire
+var (
+	errPublishTimeRequired  = errors.New("publish time is required")
+	errScheduledAttachments = errors.New("scheduled posts cannot have attachments")
+)
+
+// draftFromForm lit le brouillon envoyé par le formulaire de création ou de modification d'un post:
+// draft_id (0 pour un nouveau brouillon), post_id (0 pour un nouveau post), title, content, categories et tags
+func draftFromForm(r *http.Request, userID int) (*models.Draft, error) {
+	draft := &models.Draft{
+		UserID:  userID,
+		Title:   r.FormValue("title"),
+		Content: r.FormValue("content"),
+	}
+	draft.ID, _ = strconv.Atoi(r.FormValue("draft_id"))
+	draft.PostID, _ = strconv.Atoi(r.FormValue("post_id"))
+	for _, idStr := range r.Form["categories"] {
+		if id, err := strconv.Atoi(idStr); err == nil && id > 0 {
+			draft.CategoryIDs = append(draft.CategoryIDs, id)
+		}
+	}
+	tags, err := models.ParseTags(r.FormValue("tags"))
+	if err != nil {
+		return nil, err
+	}
+	draft.Tags = tags
+	return draft, nil
+}
+
+// draftSavedJSON est la réponse de l'enregistrement automatique d'un brouillon
+type draftSavedJSON struct {
+	DraftID int    `json:"draft_id"`
+	SavedAt string `json:"saved_at"`
+}
+
+// SaveDraftHandler enregistre le brouillon du formulaire d'un post pendant la saisie
+// POST /draft/save avec les champs lus par draftFromForm, répond avec l'ID du brouillon en JSON
+func (h *Handler) SaveDraftHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Error(w, "Connexion requise", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulaire invalide", http.StatusBadRequest)
+		return
+	}
+	draft, err := draftFromForm(r, currentUser.ID)
+	if err != nil {
+		respondError(w, err, tagErrors, "reading draft")
+		return
+	}
+	draftID, err := h.Drafts.SaveDraft(r.Context(), draft)
+	if err != nil {
+		respondError(w, err, draftErrors, "saving draft")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := draftSavedJSON{DraftID: draftID, SavedAt: time.Now().Format("15:04")}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding saved draft: %v", err)
+	}
+}
+
+// DeleteDraftHandler supprime un brouillon de l'utilisateur: POST /draft/delete avec draft_id
+// et redirect (facultatif, les brouillons du profil par défaut)
+func (h *Handler) DeleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		http.Error(w, "Brouillon invalide", http.StatusBadRequest)
+		return
+	}
+	if err := h.Drafts.DeleteDraft(r.Context(), draftID, currentUser.ID); err != nil {
+		respondError(w, err, draftErrors, "deleting draft")
+		return
+	}
+
+	http.Redirect(w, r, localRedirect(r.FormValue("redirect"), "/profile?tab=drafts"), http.StatusSeeOther)
+}
+
+// UnscheduleDraftHandler annule la publication programmée d'un brouillon, qui reste un simple brouillon
+// POST /draft/unschedule avec draft_id
+func (h *Handler) UnscheduleDraftHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		http.Error(w, "Brouillon invalide", http.StatusBadRequest)
+		return
+	}
+	if err := h.Drafts.ScheduleDraft(r.Context(), draftID, currentUser.ID, time.Time{}, time.Now()); err != nil {
+		respondError(w, err, draftErrors, "unscheduling draft")
+		return
+	}
+
+	http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+}
+
+// schedulePost garde le formulaire de création d'un post en brouillon et programme sa publication
+// à publish_at. Les règles du forum sont vérifiées maintenant pour prévenir l'auteur au plus tôt,
+// puis de nouveau à la publication. Le formulaire a déjà été lu par CreatePostHandler
+func (h *Handler) schedulePost(w http.ResponseWriter, r *http.Request, user *models.User) {
+	draft, err := draftFromForm(r, user.ID)
+	if err != nil {
+		respondError(w, err, tagErrors, "reading draft")
+		return
+	}
+	// Seul le brouillon d'un nouveau post peut être programmé, et il ne doit pas écraser celui d'une modification
+	if draft.ID > 0 {
+		existing, err := h.Drafts.GetDraft(r.Context(), draft.ID, user.ID)
+		if err == nil && existing.PostID != 0 {
+			err = models.ErrOnlyNewPostsScheduled
+		}
+		if err != nil {
+			respondError(w, err, draftErrors, "scheduling post")
+			return
+		}
+	}
+	draft.PostID = 0
+	if draft.Title == "" || draft.Content == "" {
+		http.Error(w, "Title and content are required", http.StatusBadRequest)
+		return
+	}
+
+	// Le brouillon ne garde que le texte du post: le sondage et l'image ne peuvent pas attendre la publication
+	poll, err := pollFromForm(r)
+	if err != nil {
+		respondError(w, err, pollErrors, "reading poll")
+		return
+	}
+	if _, _, imageErr := r.FormFile("image"); poll != nil || imageErr == nil {
+		respondError(w, errScheduledAttachments, draftErrors, "scheduling post")
+		return
+	}
+
+	publishAt, err := expiryFromForm(r, "publish_at")
+	if err == nil && publishAt.IsZero() {
+		err = errPublishTimeRequired
+	}
+	if err == nil && !publishAt.After(time.Now()) {
+		err = models.ErrPublishTimeInPast
+	}
+	if err != nil {
+		respondError(w, err, draftErrors, "scheduling post")
+		return
+	}
+
+	err = h.Categories.CheckCategoryPermission(r.Context(), draft.CategoryIDs, user.Role)
+	if err != nil {
+		respondError(w, err, categoryPermissionErrors, "checking category permission")
+		return
+	}
+	if !h.requireLinkTrust(w, r, user, draft.Title, draft.Content) {
+		return
+	}
+
+	draftID, err := h.Drafts.SaveDraft(r.Context(), draft)
+	if err == nil {
+		err = h.Drafts.ScheduleDraft(r.Context(), draftID, user.ID, publishAt, time.Now())
+	}
+	if err != nil {
+		respondError(w, err, draftErrors, "scheduling post")
+		return
+	}
+
+	http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+}
+
+// discardDraft supprime le brouillon du formulaire d'un post qui vient d'être publié
+func (h *Handler) discardDraft(r *http.Request, userID int) {
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		return
+	}
+	if err := h.Drafts.DeleteDraft(r.Context(), draftID, userID); err != nil && !errors.Is(err, models.ErrDraftNotFound) {
+		log.Printf("Error deleting published draft: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndDeleteDraft(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	other := createUser(t, h, "other", "user")
+
+	form := url.Values{"title": {"Brouillon"}, "content": {"En cours"}, "categories": {"1"}, "tags": {"Go, go"}}
+	w := serve(h.SaveDraftHandler, postForm("/draft/save", form), nil)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = serve(h.SaveDraftHandler, postForm("/draft/save", form), author)
+	expectStatus(t, w, http.StatusOK)
+	var saved draftSavedJSON
+	if err := json.NewDecoder(w.Body).Decode(&saved); err != nil || saved.DraftID == 0 {
+		t.Fatalf("saved draft response: %+v, error %v", saved, err)
+	}
+	draft, err := h.Drafts.GetDraft(context.Background(), saved.DraftID, author.ID)
+	if err != nil || draft.Title != "Brouillon" || len(draft.Tags) != 1 || len(draft.CategoryIDs) != 1 {
+		t.Fatalf("saved draft: %+v, error %v", draft, err)
+	}
+
+	// Les erreurs de saisie des tags sont traduites, sans le texte de l'erreur interne
+	form.Set("tags", "a, b, c, d, e, f")
+	w = serve(h.SaveDraftHandler, postForm("/draft/save", form), author)
+	expectStatus(t, w, http.StatusBadRequest)
+	if body := w.Body.String(); !strings.Contains(body, "plus de 5 tags") || strings.Contains(body, "too many") {
+		t.Errorf("too many tags: got %q", body)
+	}
+
+	w = serve(h.SaveDraftHandler, postForm("/draft/save", url.Values{}), author)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// Le brouillon d'un autre est introuvable
+	remove := url.Values{"draft_id": {itoa(saved.DraftID)}}
+	w = serve(h.DeleteDraftHandler, postForm("/draft/delete", remove), other)
+	expectStatus(t, w, http.StatusNotFound)
+	w = serve(h.DeleteDraftHandler, postForm("/draft/delete", remove), author)
+	expectRedirect(t, w, "/profile?tab=drafts")
+	if _, err := h.Drafts.GetDraft(context.Background(), saved.DraftID, author.ID); err == nil {
+		t.Error("deleted draft still exists")
+	}
+}
+
+// scheduleForm construit le formulaire de création d'un post programmé à publishAt
+func scheduleForm(t *testing.T, publishAt string, fields map[string][]string) *http.Request {
+	t.Helper()
+	values := map[string][]string{
+		"title": {"Programmé"}, "content": {"Contenu"}, "categories": {"1"},
+		"action": {"schedule"}, "publish_at": {publishAt},
+	}
+	for name, value := range fields {
+		values[name] = value
+	}
+	return multipartForm(t, "/post/create", values)
+}
+
+func TestSchedulePost(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	tomorrow := time.Now().Add(24 * time.Hour).Format(expiryLayout)
+
+	w := serve(h.CreatePostHandler, scheduleForm(t, tomorrow, nil), author)
+	expectRedirect(t, w, "/profile?tab=drafts")
+	drafts, err := h.Drafts.GetUserDrafts(context.Background(), author.ID)
+	if err != nil || len(drafts) != 1 || !drafts[0].PublishAt.Valid {
+		t.Fatalf("scheduled drafts: %+v, error %v", drafts, err)
+	}
+
+	archivedID, err := h.Categories.CreateCategory(context.Background(), models.Category{Name: "Archives", Archived: true})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	for _, tt := range []struct {
+		name      string
+		publishAt string
+		fields    map[string][]string
+		status    int
+		text      string
+	}{
+		{"no date", "", nil, http.StatusBadRequest, "Choisissez une date"},
+		{"invalid date", "demain", nil, http.StatusBadRequest, "Date de publication invalide"},
+		{"past date", "2020-01-01T10:00", nil, http.StatusBadRequest, "dans le futur"},
+		{"poll", tomorrow, map[string][]string{"poll_question": {"Oui ?"}, "poll_options": {"Oui\nNon"}}, http.StatusBadRequest, "sondage"},
+		{"too many tags", tomorrow, map[string][]string{"tags": {"a, b, c, d, e, f"}}, http.StatusBadRequest, "plus de 5 tags"},
+		{"archived category", tomorrow, map[string][]string{"categories": {itoa(archivedID)}}, http.StatusForbidden, "archivée"},
+		{"unknown draft", tomorrow, map[string][]string{"draft_id": {"999"}}, http.StatusNotFound, "Brouillon introuvable"},
+	} {
+		w := serve(h.CreatePostHandler, scheduleForm(t, tt.publishAt, tt.fields), author)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.text) {
+			t.Errorf("%s: got %d %q, want %d with %q", tt.name, w.Code, w.Body.String(), tt.status, tt.text)
+		}
+	}
+
+	// Une panne de la base pendant la vérification des catégories n'est pas montrée à l'auteur
+	h.Categories = failingCategories{h.Categories}
+	w = serve(h.CreatePostHandler, scheduleForm(t, tomorrow, nil), author)
+	expectStatus(t, w, http.StatusInternalServerError)
+	if strings.Contains(w.Body.String(), "database is locked") {
+		t.Errorf("internal error leaked to the client: %q", w.Body.String())
+	}
+}
+
+func TestUnscheduleDraft(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	tomorrow := time.Now().Add(24 * time.Hour).Format(expiryLayout)
+
+	w := serve(h.CreatePostHandler, scheduleForm(t, tomorrow, nil), author)
+	expectRedirect(t, w, "/profile?tab=drafts")
+	drafts, err := h.Drafts.GetUserDrafts(context.Background(), author.ID)
+	if err != nil || len(drafts) != 1 {
+		t.Fatalf("scheduled drafts: %+v, error %v", drafts, err)
+	}
+
+	form := url.Values{"draft_id": {itoa(drafts[0].ID)}}
+	w = serve(h.UnscheduleDraftHandler, postForm("/draft/unschedule", form), author)
+	expectRedirect(t, w, "/profile?tab=drafts")
+	draft, err := h.Drafts.GetDraft(context.Background(), drafts[0].ID, author.ID)
+	if err != nil || draft.PublishAt.Valid {
+		t.Errorf("unscheduled draft: %+v, error %v", draft, err)
+	}
+
+	w = serve(h.UnscheduleDraftHandler, postForm("/draft/unschedule", url.Values{"draft_id": {"999"}}), author)
+	expectStatus(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/events"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Réglages du flux d'événements
+const (
+	eventsHeartbeat    = 25 * time.Second // Commentaire envoyé quand rien ne se passe, pour garder la connexion ouverte
+	eventsWriteTimeout = 10 * time.Second // Délai d'écriture d'un événement avant d'abandonner un client bloqué
+	eventsRetry        = 5000             // Délai de reconnexion conseillé au navigateur, en millisecondes
+	eventsMaxPosts     = 10               // Nombre maximal de posts suivis par une connexion
+)
+
+// eventsErrors traduit les erreurs de la lecture des posts suivis par une connexion
+var eventsErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrPostNotFound, "Post not found", http.StatusNotFound},
+	},
+	failure: "Error fetching post",
+}
+
+// writeEvent écrit un événement au format Server-Sent Events
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
+
+// EventsHandler diffuse les événements temps réel en Server-Sent Events: /events?post=ID&moderation=1
+// Un visiteur peut suivre des posts. Un utilisateur connecté reçoit en plus ses notifications,
+// et un modérateur la file des posts en attente s'il la demande
+// Après une coupure, le navigateur renvoie Last-Event-ID et reçoit les événements manqués
+func (h *Handler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Events == nil {
+		http.Error(w, "Real-time events are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var topics []string
+	currentUser := middleware.GetUserFromContext(r)
+	postIDs := r.URL.Query()["post"]
+	if len(postIDs) > eventsMaxPosts {
+		http.Error(w, "Too many posts", http.StatusBadRequest)
+		return
+	}
+	for _, postIDStr := range postIDs {
+		postID, err := strconv.Atoi(postIDStr)
+		if err != nil || postID <= 0 {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		// Les compteurs d'un post que l'utilisateur ne peut pas lire ne lui sont pas diffusés
+		if _, err := h.readablePost(r.Context(), postID, currentUser); err != nil {
+			respondError(w, err, eventsErrors, "fetching followed post")
+			return
+		}
+		topics = append(topics, events.PostTopic(postID))
+	}
+
+	if currentUser != nil {
+		topics = append(topics, events.UserTopic(currentUser.ID))
+	}
+	if r.URL.Query().Get("moderation") != "" {
+		if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		topics = append(topics, events.ModerationTopic)
+	}
+	if len(topics) == 0 {
+		http.Error(w, "No events to follow", http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	subscription, backlog, complete := h.Events.Subscribe(topics, lastEventID)
+	defer h.Events.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Chaque écriture a son propre délai: un client qui ne lit plus ne garde pas la connexion indéfiniment
+	controller := http.NewResponseController(w)
+	send := func(write func() error) bool {
+		err := controller.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if err := write(); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	ok := send(func() error {
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventsRetry); err != nil {
+			return err
+		}
+		if !complete {
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", events.TypeResync); err != nil {
+				return err
+			}
+		}
+		for _, event := range backlog {
+			if err := writeEvent(w, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-subscription.Events():
+			if !open {
+				// Le client ne lisait pas assez vite: il se reconnectera et rattrapera son retard
+				return
+			}
+			if !send(func() error { return writeEvent(w, event) }) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(func() error { _, err := fmt.Fprint(w, ": ping\n\n"); return err }) {
+				return
+			}
+		}
+	}
+}
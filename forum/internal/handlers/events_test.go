@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/events"
+	"forum/internal/models"
+	"forum/internal/store"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newEventsHandler crée des handlers avec un hub d'événements
+func newEventsHandler(t *testing.T) *Handler {
+	t.Helper()
+	return New(store.NewMemoryStores(), []byte("test-secret"), events.NewHub(16, 4))
+}
+
+// serveEvents ouvre le flux d'événements et le referme aussitôt envoyés l'en-tête et l'historique
+func serveEvents(h *Handler, target, lastEventID string, user *models.User) (int, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := get(target).WithContext(ctx)
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	w := serve(h.EventsHandler, r, user)
+	return w.Code, w.Body.String()
+}
+
+func TestEvents(t *testing.T) {
+	h := newEventsHandler(t)
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, author, "Un post suivi", 1)
+
+	if code, _ := serveEvents(newTestHandler(t), "/events?post="+itoa(postID), "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("events without a hub: got %d, want 503", code)
+	}
+
+	for _, tt := range []struct {
+		target string
+		user   *models.User
+		status int
+	}{
+		{"/events", nil, http.StatusBadRequest},
+		{"/events?post=abc", nil, http.StatusBadRequest},
+		{"/events?post=999", nil, http.StatusNotFound},
+		{"/events?moderation=1", author, http.StatusForbidden},
+		{"/events?moderation=1", moderator, http.StatusOK},
+		{"/events", author, http.StatusOK},
+		{"/events?post=" + itoa(postID), nil, http.StatusOK},
+	} {
+		if code, body := serveEvents(h, tt.target, "", tt.user); code != tt.status {
+			t.Errorf("%s: got %d, want %d (body: %.200s)", tt.target, code, tt.status, body)
+		}
+	}
+
+	// Un client qui revient d'un autre démarrage du serveur doit recharger ses données
+	if err := h.Events.Publish(events.PostTopic(postID), events.TypeComment, map[string]int{"post_id": postID}); err != nil {
+		t.Fatalf("publishing: %v", err)
+	}
+	_, fresh := serveEvents(h, "/events?post="+itoa(postID), "", nil)
+	if !strings.Contains(fresh, "retry: ") || strings.Contains(fresh, "event: ") {
+		t.Errorf("new client: %q", fresh)
+	}
+	_, stale := serveEvents(h, "/events?post="+itoa(postID), "ancien-1", nil)
+	if !strings.Contains(stale, "event: "+events.TypeResync) {
+		t.Errorf("client from another epoch: %q", stale)
+	}
+}
+
+// Les compteurs d'un post qu'on ne peut pas lire ne sont pas diffusés
+func TestEventsReadPermission(t *testing.T) {
+	h := newEventsHandler(t)
+	moderator := createUser(t, h, "moderator", "moderator")
+	member := createUser(t, h, "member", "user")
+	hiddenID := createStaffPost(t, h, moderator, "Post du staff")
+
+	for _, user := range []*models.User{nil, member} {
+		if code, _ := serveEvents(h, "/events?post="+itoa(hiddenID), "", user); code != http.StatusNotFound {
+			t.Errorf("hidden post events for %v: got %d, want 404", user, code)
+		}
+	}
+	if code, _ := serveEvents(h, "/events?post="+itoa(hiddenID), "", moderator); code != http.StatusOK {
+		t.Errorf("hidden post events for the moderator: got %d, want 200", code)
+	}
+}
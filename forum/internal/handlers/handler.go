@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/events"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/store"
+	"log"
+	"net/http"
+)
+
+// Handler regroupe les handlers HTTP du forum et les stockages dont ils ont besoin
+type Handler struct {
+	store.Stores
+	Secret []byte      // Clé du serveur qui signe les liens envoyés par email
+	Events *events.Hub // Hub des événements temps réel diffusés par /events, nil pour les désactiver
+	// MessageLimiter limite le nombre de messages privés envoyés par chaque utilisateur, nil pour ne pas les limiter
+	MessageLimiter *middleware.RateLimiter
+}
+
+// New crée les handlers avec leurs dépendances de stockage, la clé du serveur et le hub des événements
+func New(stores store.Stores, secret []byte, hub *events.Hub) *Handler {
+	return &Handler{Stores: stores, Secret: secret, Events: hub}
+}
+
+// userRole renvoie le rôle de l'utilisateur connecté, vide pour un visiteur
+func userRole(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Role
+}
+
+// errorResponse est la réponse à une erreur qui vient de la saisie ou des droits de l'utilisateur
+type errorResponse struct {
+	err    error
+	text   string
+	status int
+}
+
+// errorResponses traduit les erreurs d'une fonctionnalité: known liste les erreurs attendues,
+// failure est le message des autres erreurs
+type errorResponses struct {
+	known   []errorResponse
+	failure string
+}
+
+// respondError répond à l'erreur d'une action. Une erreur attendue reçoit son message et son code,
+// les autres une erreur 500 avec le message générique de la fonctionnalité et sont écrites dans le journal
+func respondError(w http.ResponseWriter, err error, responses errorResponses, operation string) {
+	text, status := responses.response(err, operation)
+	http.Error(w, text, status)
+}
+
+// respondJSONError répond comme respondError, au format {"error": "..."} des API JSON
+func respondJSONError(w http.ResponseWriter, err error, responses errorResponses, operation string) {
+	text, status := responses.response(err, operation)
+	writeJSONError(w, status, text)
+}
+
+// response renvoie le message et le code de la réponse à une erreur, et journalise les erreurs inattendues
+func (responses errorResponses) response(err error, operation string) (string, int) {
+	for _, known := range responses.known {
+		if errors.Is(err, known.err) {
+			return known.text, known.status
+		}
+	}
+	log.Printf("Error %s: %v", operation, err)
+	return responses.failure, http.StatusInternalServerError
+}
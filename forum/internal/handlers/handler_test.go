@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Les tests des handlers tournent sur le stockage en mémoire: chaque test part d'un forum vide
+// avec les catégories par défaut, et les requêtes sont envoyées directement aux handlers
+
+func TestMain(m *testing.M) {
+	// Les templates sont lus depuis la racine du module, comme au lancement du serveur
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestHandler crée des handlers branchés sur un stockage en mémoire vide
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	return New(store.NewMemoryStores(), []byte("test-secret"), nil)
+}
+
+// createUser inscrit un utilisateur avec le rôle donné ("user", "moderator" ou "admin")
+func createUser(t *testing.T, h *Handler, username, role string) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	email := username + "@example.com"
+	if err := h.Users.RegisterUser(ctx, email, username, "password123"); err != nil {
+		t.Fatalf("registering %s: %v", username, err)
+	}
+	user, err := h.Users.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("fetching %s: %v", username, err)
+	}
+	if role != "user" {
+		if err := h.Moderation.UpdateUserRole(ctx, user.ID, role); err != nil {
+			t.Fatalf("setting role of %s: %v", username, err)
+		}
+		user.Role = role
+	}
+	return user
+}
+
+// createPost crée un post de l'utilisateur dans les catégories données et renvoie son ID
+func createPost(t *testing.T, h *Handler, user *models.User, title string, categoryIDs ...int) int {
+	t.Helper()
+	postID, err := h.Posts.CreatePost(context.Background(), title, "Contenu de "+title, user.ID, categoryIDs)
+	if err != nil {
+		t.Fatalf("creating post %q: %v", title, err)
+	}
+	return postID
+}
+
+// createStaffPost crée un post de l'utilisateur dans une sous-catégorie d'une catégorie réservée
+// à l'équipe, qui en hérite le droit de lecture, et renvoie son ID
+func createStaffPost(t *testing.T, h *Handler, user *models.User, title string) int {
+	t.Helper()
+	ctx := context.Background()
+	staffID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Équipe", ReadPermission: models.CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	subID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Sous-forum", ParentID: staffID})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	return createPost(t, h, user, title, subID)
+}
+
+// serve envoie la requête au handler, au nom de user (nil pour un visiteur), et renvoie la réponse
+func serve(handler http.HandlerFunc, r *http.Request, user *models.User) *httptest.ResponseRecorder {
+	if user != nil {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, user))
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// get construit une requête GET
+func get(target string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, target, nil)
+}
+
+// postForm construit une requête POST d'un formulaire
+func postForm(target string, values url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// expectStatus vérifie le code de la réponse
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("got status %d, want %d (body: %.200s)", w.Code, want, w.Body.String())
+	}
+}
+
+// expectRedirect vérifie que la réponse redirige vers target
+func expectRedirect(t *testing.T, w *httptest.ResponseRecorder, target string) {
+	t.Helper()
+	expectStatus(t, w, http.StatusSeeOther)
+	if location := w.Header().Get("Location"); location != target {
+		t.Fatalf("redirected to %q, want %q", location, target)
+	}
+}
+
+// itoa écrit un ID dans une adresse
+func itoa(id int) string {
+	return strconv.Itoa(id)
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"fmt"
 	"forum/internal/middleware"
-	"forum/internal/models"
 	"io"
 	"log"
 	"net/http"
@@ -18,7 +17,7 @@ const (
 	UploadDir    = "./static/uploads"
 )
 
-func UploadImageHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UploadImageHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -30,6 +29,10 @@ func UploadImageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.requireImageTrust(w, r, currentUser) {
+		return
+	}
+
 	if err := os.MkdirAll(UploadDir, 0755); err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		log.Printf("Error creating upload directory: %v", err)
@@ -84,7 +87,7 @@ func UploadImageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imageID, err := models.SaveImage(filename, currentUser.ID)
+	imageID, err := h.Images.SaveImage(r.Context(), filename, currentUser.ID)
 	if err != nil {
 		os.Remove(filepath)
 		http.Error(w, "Error saving image information", http.StatusInternalServerError)
@@ -117,7 +120,7 @@ func sanitizeFilename(filename string) string {
 	return filename
 }
 
-func GetImageHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetImageHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 3 {
 		http.NotFound(w, r)
@@ -130,7 +133,7 @@ func GetImageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	image, err := models.GetImageByID(strconv.Itoa(imageID))
+	image, err := h.Images.GetImageByID(r.Context(), strconv.Itoa(imageID))
 	if err != nil {
 		http.NotFound(w, r)
 		return
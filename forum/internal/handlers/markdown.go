@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"forum/internal/markdown"
+	"forum/internal/middleware"
+	"log"
+	"net/http"
+)
+
+// MaxPreviewSize est la taille maximale d'un texte envoyé à l'aperçu
+const MaxPreviewSize = 256 * 1024
+
+// PreviewMarkdownHandler renvoie le HTML d'un texte Markdown, pour l'aperçu des formulaires
+// Le rendu n'est pas mis en cache: chaque frappe produirait une révision différente
+func (h *Handler) PreviewMarkdownHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if middleware.GetUserFromContext(r) == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxPreviewSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	content := r.FormValue("content")
+	mentions, err := h.mentionLinks(r.Context(), content)
+	if err != nil {
+		http.Error(w, "Error resolving mentions", http.StatusInternalServerError)
+		log.Printf("Error resolving mentions: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(markdown.ToHTML(content, mentions)))
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ConversationPageSize est le nombre de derniers messages affichés dans une conversation
+const ConversationPageSize = 100
+
+// messageErrors traduit les erreurs des messages privés qui viennent de la saisie de l'utilisateur
+var messageErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrMessageEmpty, "Le message est vide", http.StatusBadRequest},
+		{models.ErrMessageTooLong, "Le message est trop long", http.StatusBadRequest},
+		{models.ErrUserNotFound, "Destinataire introuvable", http.StatusBadRequest},
+		{models.ErrUserBlocked, "Vous ne pouvez pas écrire à cet utilisateur", http.StatusForbidden},
+		{models.ErrConversationTooSmall, "Indiquez au moins un destinataire", http.StatusBadRequest},
+		{models.ErrConversationTooLarge, "Trop de destinataires pour une conversation", http.StatusBadRequest},
+		{models.ErrConversationAbandoned, "Tous les autres membres ont quitté cette conversation", http.StatusBadRequest},
+		{models.ErrConversationNotFound, "Conversation introuvable", http.StatusNotFound},
+		{models.ErrMessageNotFound, "Message introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de l'envoi du message",
+}
+
+// allowMessage applique la limite d'envoi de messages privés d'un utilisateur
+// Renvoie false après avoir répondu 429 si la limite est dépassée
+func (h *Handler) allowMessage(w http.ResponseWriter, userID int) bool {
+	if h.MessageLimiter == nil || h.MessageLimiter.Allow("user:"+strconv.Itoa(userID)) {
+		return true
+	}
+	w.Header().Set("Retry-After", "60")
+	http.Error(w, "Vous envoyez trop de messages. Réessayez dans une minute.", http.StatusTooManyRequests)
+	return false
+}
+
+// MessagesHandler affiche la boîte de réception, le formulaire d'une nouvelle conversation et les utilisateurs bloqués
+// /messages?to=nom pré-remplit le destinataire, par exemple depuis un profil
+func (h *Handler) MessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	conversations, err := h.Messages.GetConversations(r.Context(), currentUser.ID)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des messages", http.StatusInternalServerError)
+		log.Printf("Error fetching conversations: %v", err)
+		return
+	}
+
+	blocked, err := h.Messages.GetBlockedUsers(r.Context(), currentUser.ID)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des utilisateurs bloqués", http.StatusInternalServerError)
+		log.Printf("Error fetching blocked users: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser":   currentUser,
+		"Conversations": conversations,
+		"BlockedUsers":  blocked,
+		"To":            r.URL.Query().Get("to"),
+		"MaxMembers":    models.MaxConversationMembers - 1,
+		"PageTitle":     "Messages",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/messages.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// NewConversationHandler démarre une conversation avec un ou plusieurs destinataires
+// Les noms des destinataires sont séparés par des virgules ou des espaces, avec ou sans @
+func (h *Handler) NewConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var names []string
+	for _, name := range strings.FieldsFunc(r.FormValue("to"), func(c rune) bool { return c == ',' || c == ' ' }) {
+		names = append(names, strings.TrimPrefix(name, "@"))
+	}
+	if len(names) == 0 {
+		http.Error(w, "Indiquez au moins un destinataire", http.StatusBadRequest)
+		return
+	}
+
+	userIDs, err := h.Notifications.ResolveMentions(r.Context(), names)
+	if err != nil {
+		http.Error(w, "Erreur lors de la recherche des destinataires", http.StatusInternalServerError)
+		log.Printf("Error resolving message recipients: %v", err)
+		return
+	}
+	var memberIDs []int
+	for _, name := range names {
+		userID, ok := userIDs[name]
+		if !ok {
+			http.Error(w, "Utilisateur introuvable: "+name, http.StatusBadRequest)
+			return
+		}
+		memberIDs = append(memberIDs, userID)
+	}
+
+	if !h.allowMessage(w, currentUser.ID) {
+		return
+	}
+
+	conversationID, err := h.Messages.CreateConversation(r.Context(), currentUser.ID, memberIDs, r.FormValue("content"))
+	if err != nil {
+		respondError(w, err, messageErrors, "creating conversation")
+		return
+	}
+
+	http.Redirect(w, r, "/messages/"+strconv.Itoa(conversationID), http.StatusSeeOther)
+}
+
+// ConversationHandler affiche une conversation et la marque comme lue: /messages/{id}
+func (h *Handler) ConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	conversationID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/messages/"))
+	if err != nil || conversationID <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	conversation, err := h.Messages.GetConversation(r.Context(), conversationID, currentUser.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrConversationNotFound) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, "Erreur lors de la récupération de la conversation", http.StatusInternalServerError)
+			log.Printf("Error fetching conversation: %v", err)
+		}
+		return
+	}
+
+	messages, err := h.Messages.GetMessages(r.Context(), conversationID, currentUser.ID, ConversationPageSize)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des messages", http.StatusInternalServerError)
+		log.Printf("Error fetching messages: %v", err)
+		return
+	}
+
+	if conversation.UnreadCount > 0 {
+		if err := h.Messages.MarkConversationRead(r.Context(), conversationID, currentUser.ID); err != nil {
+			log.Printf("Error marking conversation as read: %v", err)
+		} else {
+			// Le menu de cette page ne compte plus la conversation
+			currentUser.UnreadConversations = max(currentUser.UnreadConversations-1, 0)
+		}
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser":  currentUser,
+		"Conversation": conversation,
+		"Messages":     messages,
+		"PageTitle":    "Conversation avec " + conversation.Title,
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/conversation.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// conversationIDFromForm lit l'ID de conversation d'un formulaire
+func conversationIDFromForm(r *http.Request) (int, bool) {
+	conversationID, err := strconv.Atoi(r.FormValue("conversation_id"))
+	return conversationID, err == nil && conversationID > 0
+}
+
+// SendMessageHandler ajoute un message à une conversation
+func (h *Handler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	conversationID, ok := conversationIDFromForm(r)
+	if !ok {
+		http.Error(w, "Conversation invalide", http.StatusBadRequest)
+		return
+	}
+
+	if !h.allowMessage(w, currentUser.ID) {
+		return
+	}
+
+	messageID, err := h.Messages.SendMessage(r.Context(), conversationID, currentUser.ID, r.FormValue("content"))
+	if err != nil {
+		respondError(w, err, messageErrors, "sending message")
+		return
+	}
+
+	http.Redirect(w, r, "/messages/"+strconv.Itoa(conversationID)+"#message-"+strconv.Itoa(messageID), http.StatusSeeOther)
+}
+
+// LeaveConversationHandler retire l'utilisateur connecté d'une conversation
+func (h *Handler) LeaveConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	conversationID, ok := conversationIDFromForm(r)
+	if !ok {
+		http.Error(w, "Conversation invalide", http.StatusBadRequest)
+		return
+	}
+
+	err := h.Messages.LeaveConversation(r.Context(), conversationID, currentUser.ID)
+	if err != nil {
+		respondError(w, err, messageErrors, "leaving conversation")
+		return
+	}
+
+	http.Redirect(w, r, "/messages", http.StatusSeeOther)
+}
+
+// ReportMessageHandler signale un message privé aux administrateurs
+// Seul un membre de la conversation peut signaler l'un de ses messages, écrit par quelqu'un d'autre
+func (h *Handler) ReportMessageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	messageID, err := strconv.Atoi(r.FormValue("message_id"))
+	if err != nil || messageID <= 0 {
+		http.Error(w, "Message invalide", http.StatusBadRequest)
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		http.Error(w, "La raison du signalement est requise", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.Messages.GetMessage(r.Context(), messageID, currentUser.ID)
+	if err != nil {
+		respondError(w, err, messageErrors, "fetching reported message")
+		return
+	}
+	if message.UserID == currentUser.ID {
+		http.Error(w, "Vous ne pouvez pas signaler votre propre message", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Moderation.ReportContent(r.Context(), "message", messageID, currentUser.ID, reason)
+	if err != nil {
+		if errors.Is(err, models.ErrAlreadyReported) {
+			http.Error(w, "Vous avez déjà signalé ce message", http.StatusConflict)
+		} else {
+			http.Error(w, "Erreur lors du signalement", http.StatusInternalServerError)
+			log.Printf("Error reporting message: %v", err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/messages/"+strconv.Itoa(message.ConversationID), http.StatusSeeOther)
+}
+
+// BlockUserHandler bloque ou débloque un utilisateur: plus aucun message ne peut être échangé avec lui
+// Le formulaire indique user_id, action ("block" ou "unblock") et la page où revenir
+func (h *Handler) BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Utilisateur invalide", http.StatusBadRequest)
+		return
+	}
+
+	switch r.FormValue("action") {
+	case "block":
+		err = h.Messages.BlockUser(r.Context(), currentUser.ID, userID)
+	case "unblock":
+		err = h.Messages.UnblockUser(r.Context(), currentUser.ID, userID)
+	default:
+		http.Error(w, "Action invalide", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrCannotBlockYourself):
+			http.Error(w, "Vous ne pouvez pas vous bloquer vous-même", http.StatusBadRequest)
+		case errors.Is(err, models.ErrUserNotFound), errors.Is(err, models.ErrBlockNotFound):
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "Erreur lors de la mise à jour des blocages", http.StatusInternalServerError)
+			log.Printf("Error updating user block: %v", err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, localRedirect(r.FormValue("redirect"), "/messages"), http.StatusSeeOther)
+}
+
+// MessageSettingsHandler affiche et modifie la durée de conservation des messages privés (administrateurs)
+func (h *Handler) MessageSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || currentUser.Role != "admin" {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		days, err := strconv.Atoi(r.FormValue("retention_days"))
+		if err != nil || days < 0 || days > models.MaxMessageRetentionDays {
+			http.Error(w, "Durée de conservation invalide", http.StatusBadRequest)
+			return
+		}
+		if err := h.Messages.SetMessageRetention(r.Context(), days); err != nil {
+			http.Error(w, "Erreur lors de l'enregistrement de la durée de conservation", http.StatusInternalServerError)
+			log.Printf("Error updating message retention: %v", err)
+			return
+		}
+		http.Redirect(w, r, "/admin/messages", http.StatusSeeOther)
+		return
+	default:
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, err := h.Messages.GetMessageRetention(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération de la durée de conservation", http.StatusInternalServerError)
+		log.Printf("Error fetching message retention: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser":      currentUser,
+		"RetentionDays":    days,
+		"MaxRetentionDays": models.MaxMessageRetentionDays,
+		"PageTitle":        "Messages privés",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/admin_messages.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Les erreurs des stockages sont traduites en messages et en codes de réponse, quel que soit leur texte
+func TestMessageErrors(t *testing.T) {
+	h := newTestHandler(t)
+	alice := createUser(t, h, "alice", "user")
+	bob := createUser(t, h, "bob", "user")
+
+	w := serve(h.NewConversationHandler, postForm("/messages/new", url.Values{"to": {"bob"}, "content": {""}}), alice)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	if err := h.Messages.BlockUser(context.Background(), bob.ID, alice.ID); err != nil {
+		t.Fatalf("blocking: %v", err)
+	}
+	w = serve(h.NewConversationHandler, postForm("/messages/new", url.Values{"to": {"bob"}, "content": {"Bonjour"}}), alice)
+	expectStatus(t, w, http.StatusForbidden)
+
+	w = serve(h.ConversationHandler, get("/messages/99"), alice)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = serve(h.BlockUserHandler, postForm("/messages/block", url.Values{"user_id": {itoa(alice.ID)}, "action": {"block"}}), alice)
+	expectStatus(t, w, http.StatusBadRequest)
+	w = serve(h.BlockUserHandler, postForm("/messages/block", url.Values{"user_id": {itoa(bob.ID)}, "action": {"unblock"}}), alice)
+	expectStatus(t, w, http.StatusNotFound)
+}
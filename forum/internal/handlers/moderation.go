@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"forum/internal/middleware"
-	"forum/internal/models"
 	"forum/internal/utils"
 	"log"
 	"net/http"
@@ -11,7 +10,7 @@ import (
 )
 
 // ListPendingPostsHandler affiche les posts en attente de modération
-func ListPendingPostsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListPendingPostsHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que l'utilisateur a le rôle de modérateur
 	currentUser := middleware.GetUserFromContext(r)
 	if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
@@ -20,7 +19,7 @@ func ListPendingPostsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Récupérer tous les posts en attente
-	pendingPosts, err := models.GetPendingPosts()
+	pendingPosts, err := h.Moderation.GetPendingPosts(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des posts en attente", http.StatusInternalServerError)
 		log.Printf("Error fetching pending posts: %v", err)
@@ -42,6 +41,8 @@ func ListPendingPostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
@@ -50,7 +51,7 @@ func ListPendingPostsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ApprovePostHandler approuve un post en attente
-func ApprovePostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ApprovePostHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
@@ -78,7 +79,7 @@ func ApprovePostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Approuver le post
-	newPostID, err := models.ApprovePendingPost(postID, currentUser.ID)
+	newPostID, err := h.Moderation.ApprovePendingPost(r.Context(), postID, currentUser.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error approving post: %v", err)
@@ -90,7 +91,7 @@ func ApprovePostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // RejectPostHandler rejette un post en attente
-func RejectPostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RejectPostHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
@@ -131,7 +132,7 @@ func RejectPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Rejeter le post
-	err = models.RejectPendingPost(postID, currentUser.ID, reason)
+	err = h.Moderation.RejectPendingPost(r.Context(), postID, currentUser.ID, reason)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error rejecting post: %v", err)
@@ -143,7 +144,7 @@ func RejectPostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListReportsHandler affiche les signalements en attente
-func ListReportsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListReportsHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que l'utilisateur a le rôle d'administrateur
 	currentUser := middleware.GetUserFromContext(r)
 	if currentUser == nil || currentUser.Role != "admin" {
@@ -152,7 +153,7 @@ func ListReportsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Récupérer tous les signalements en attente
-	reports, err := models.GetReports()
+	reports, err := h.Moderation.GetReports(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des signalements", http.StatusInternalServerError)
 		log.Printf("Error fetching reports: %v", err)
@@ -174,6 +175,8 @@ func ListReportsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
@@ -182,7 +185,7 @@ func ListReportsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleReportHandler traite un signalement (approbation ou rejet)
-func HandleReportHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleReportHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
@@ -196,14 +199,14 @@ func HandleReportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extraire l'ID du signalement de l'URL
+	// Extraire l'ID du signalement de l'URL: /admin/report/handle/{id}
 	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
+	if len(parts) != 5 {
 		http.NotFound(w, r)
 		return
 	}
 
-	reportID, err := strconv.Atoi(parts[3])
+	reportID, err := strconv.Atoi(parts[4])
 	if err != nil || reportID <= 0 {
 		http.NotFound(w, r)
 		return
@@ -230,7 +233,7 @@ func HandleReportHandler(w http.ResponseWriter, r *http.Request) {
 		status = "approved"
 	}
 
-	err = models.HandleReport(reportID, currentUser.ID, status, response)
+	err = h.Moderation.HandleReport(r.Context(), reportID, currentUser.ID, status, response)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error handling report: %v", err)
@@ -242,7 +245,7 @@ func HandleReportHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListUsersHandler affiche la liste des utilisateurs pour l'administration
-func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que l'utilisateur a le rôle d'administrateur
 	currentUser := middleware.GetUserFromContext(r)
 	if currentUser == nil || currentUser.Role != "admin" {
@@ -251,7 +254,7 @@ func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Récupérer tous les utilisateurs
-	users, err := models.GetAllUsers()
+	users, err := h.Users.GetAllUsers(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des utilisateurs", http.StatusInternalServerError)
 		log.Printf("Error fetching users: %v", err)
@@ -273,6 +276,8 @@ func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
@@ -281,7 +286,7 @@ func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateUserRoleHandler met à jour le rôle d'un utilisateur
-func UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que la méthode est POST
 	if r.Method != http.MethodPost {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
@@ -295,14 +300,14 @@ func UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extraire l'ID de l'utilisateur de l'URL
+	// Extraire l'ID de l'utilisateur de l'URL: /admin/user/role/{id}
 	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
+	if len(parts) != 5 {
 		http.NotFound(w, r)
 		return
 	}
 
-	userID, err := strconv.Atoi(parts[3])
+	userID, err := strconv.Atoi(parts[4])
 	if err != nil || userID <= 0 {
 		http.NotFound(w, r)
 		return
@@ -328,7 +333,7 @@ func UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Mettre à jour le rôle de l'utilisateur
-	err = models.UpdateUserRole(userID, role)
+	err = h.Moderation.UpdateUserRole(r.Context(), userID, role)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error updating user role: %v", err)
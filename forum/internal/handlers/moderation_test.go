@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// submitPendingPost soumet un post à la file de modération et renvoie son ID
+func submitPendingPost(t *testing.T, h *Handler, author *models.User, title string) int {
+	t.Helper()
+	pendingID, err := h.Moderation.SubmitPendingPost(context.Background(), models.NewPost{
+		Title: title, Content: "Contenu de " + title, UserID: author.ID, CategoryIDs: []int{1},
+	})
+	if err != nil {
+		t.Fatalf("submitting %q: %v", title, err)
+	}
+	return pendingID
+}
+
+func TestPendingPosts(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	approvedID := submitPendingPost(t, h, author, "À approuver")
+	rejectedID := submitPendingPost(t, h, author, "À rejeter")
+
+	expectStatus(t, serve(h.ListPendingPostsHandler, get("/mod/pending"), author), http.StatusForbidden)
+	w := serve(h.ListPendingPostsHandler, get("/mod/pending"), moderator)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "À approuver") || !strings.Contains(body, "À rejeter") {
+		t.Errorf("pending posts missing from the page: %.300s", body)
+	}
+
+	for _, c := range []struct {
+		handler http.HandlerFunc
+		target  string
+		form    url.Values
+		user    *models.User
+		status  int
+	}{
+		{h.ApprovePostHandler, "/mod/approve/" + itoa(approvedID), nil, author, http.StatusForbidden},
+		{h.ApprovePostHandler, "/mod/approve/abc", nil, moderator, http.StatusNotFound},
+		{h.ApprovePostHandler, "/mod/approve/1/2", nil, moderator, http.StatusNotFound},
+		{h.RejectPostHandler, "/mod/reject/" + itoa(rejectedID), url.Values{"reason": {"Hors sujet"}}, author, http.StatusForbidden},
+		{h.RejectPostHandler, "/mod/reject/" + itoa(rejectedID), url.Values{}, moderator, http.StatusBadRequest},
+	} {
+		w := serve(c.handler, postForm(c.target, c.form), c.user)
+		if w.Code != c.status {
+			t.Errorf("%s as %s: got status %d, want %d", c.target, c.user.Username, w.Code, c.status)
+		}
+	}
+	expectStatus(t, serve(h.ApprovePostHandler, get("/mod/approve/"+itoa(approvedID)), moderator), http.StatusMethodNotAllowed)
+
+	w = serve(h.ApprovePostHandler, postForm("/mod/approve/"+itoa(approvedID), nil), moderator)
+	expectStatus(t, w, http.StatusSeeOther)
+	postID, err := strconv.Atoi(strings.TrimPrefix(w.Header().Get("Location"), "/post/"))
+	if err != nil {
+		t.Fatalf("redirect after approval: %v", err)
+	}
+	if post, err := h.Posts.GetPostByID(context.Background(), postID, moderator.ID); err != nil || post.Title != "À approuver" || post.UserID != author.ID {
+		t.Errorf("approved post: %+v, error %v", post, err)
+	}
+
+	w = serve(h.RejectPostHandler, postForm("/mod/reject/"+itoa(rejectedID), url.Values{"reason": {"Hors sujet"}}), moderator)
+	expectRedirect(t, w, "/mod/pending")
+	pending, err := h.Moderation.GetPendingPosts(context.Background())
+	if err != nil || len(pending) != 0 {
+		t.Errorf("pending posts after moderation: %+v, error %v", pending, err)
+	}
+}
+
+func TestReports(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	admin := createUser(t, h, "admin", "admin")
+	postID := createPost(t, h, author, "Signalé", 1)
+	reportID, err := h.Moderation.ReportContent(context.Background(), "post", postID, moderator.ID, "Contenu douteux")
+	if err != nil {
+		t.Fatalf("reporting post: %v", err)
+	}
+
+	// Les signalements sont réservés aux administrateurs
+	expectStatus(t, serve(h.ListReportsHandler, get("/admin/reports"), moderator), http.StatusForbidden)
+	w := serve(h.ListReportsHandler, get("/admin/reports"), admin)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "Contenu douteux") {
+		t.Errorf("report missing from the page: %.300s", w.Body.String())
+	}
+
+	target := "/admin/report/handle/" + itoa(reportID)
+	expectStatus(t, serve(h.HandleReportHandler, postForm(target, url.Values{"action": {"approve"}}), moderator), http.StatusForbidden)
+	expectStatus(t, serve(h.HandleReportHandler, postForm(target, url.Values{"action": {"ignore"}}), admin), http.StatusBadRequest)
+	expectStatus(t, serve(h.HandleReportHandler, postForm("/admin/report/handle/x", url.Values{"action": {"approve"}}), admin), http.StatusNotFound)
+
+	w = serve(h.HandleReportHandler, postForm(target, url.Values{"action": {"approve"}, "response": {"Merci"}}), admin)
+	expectRedirect(t, w, "/admin/reports")
+	reports, err := h.Moderation.GetReports(context.Background())
+	if err != nil {
+		t.Fatalf("fetching reports: %v", err)
+	}
+	for _, report := range reports {
+		if report.ID == reportID && report.Status != "approved" {
+			t.Errorf("handled report: %+v", report)
+		}
+	}
+}
+
+func TestUserRoles(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+	admin := createUser(t, h, "admin", "admin")
+
+	expectStatus(t, serve(h.ListUsersHandler, get("/admin/users"), member), http.StatusForbidden)
+	w := serve(h.ListUsersHandler, get("/admin/users"), admin)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "member@example.com") {
+		t.Errorf("user missing from the page: %.300s", w.Body.String())
+	}
+
+	target := "/admin/user/role/" + itoa(member.ID)
+	for _, c := range []struct {
+		target string
+		role   string
+		user   *models.User
+		status int
+	}{
+		{target, "moderator", member, http.StatusForbidden},
+		{target, "owner", admin, http.StatusBadRequest},
+		{"/admin/user/role/" + itoa(admin.ID), "user", admin, http.StatusBadRequest},
+		{"/admin/user/role/0", "moderator", admin, http.StatusNotFound},
+	} {
+		w := serve(h.UpdateUserRoleHandler, postForm(c.target, url.Values{"role": {c.role}}), c.user)
+		if w.Code != c.status {
+			t.Errorf("%s to %s as %s: got status %d, want %d", c.target, c.role, c.user.Username, w.Code, c.status)
+		}
+	}
+
+	expectRedirect(t, serve(h.UpdateUserRoleHandler, postForm(target, url.Values{"role": {"moderator"}}), admin), "/admin/users")
+	if user, err := h.Users.GetUserByID(context.Background(), member.ID); err != nil || user.Role != "moderator" {
+		t.Errorf("promoted user: %+v, error %v", user, err)
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/markdown"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// NotificationsPageSize est le nombre de notifications affichées sur la page des notifications
+const NotificationsPageSize = 50
+
+// userSuggestionJSON est la représentation d'un utilisateur dans l'API d'autocomplétion des mentions
+type userSuggestionJSON struct {
+	Username string `json:"username"`
+}
+
+// mentionLinks associe les noms mentionnés dans des contenus à l'adresse du profil de l'utilisateur
+// Le résultat est passé à la fonction markdown des templates
+func (h *Handler) mentionLinks(ctx context.Context, contents ...string) (map[string]string, error) {
+	var names []string
+	for _, content := range contents {
+		names = append(names, markdown.Mentions(content)...)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	userIDs, err := h.Notifications.ResolveMentions(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	links := map[string]string{}
+	for name, userID := range userIDs {
+		links[name] = "/profile?user=" + strconv.Itoa(userID)
+	}
+	return links, nil
+}
+
+// SuggestUsersHandler propose des noms d'utilisateurs pour l'autocomplétion des mentions: /api/users/suggest?q=prefixe
+func (h *Handler) SuggestUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if middleware.GetUserFromContext(r) == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 8
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 && n <= 20 {
+			limit = n
+		}
+	}
+
+	names, err := h.Notifications.SuggestUsers(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, "Error searching users", http.StatusInternalServerError)
+		log.Printf("Error suggesting users: %v", err)
+		return
+	}
+
+	response := []userSuggestionJSON{}
+	for _, name := range names {
+		response = append(response, userSuggestionJSON{Username: name})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding user suggestions: %v", err)
+	}
+}
+
+// NotificationsHandler affiche les dernières notifications de l'utilisateur connecté
+func (h *Handler) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notifications, err := h.Notifications.GetNotifications(r.Context(), currentUser.ID, NotificationsPageSize)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des notifications", http.StatusInternalServerError)
+		log.Printf("Error fetching notifications: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Notifications": notifications,
+		"CurrentUser":   currentUser,
+		"PageTitle":     "Notifications",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/notifications.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// notificationErrors traduit les erreurs de la lecture d'une notification
+var notificationErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrNotificationNotFound, "Notification introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de la mise à jour de la notification",
+}
+
+// ReadNotificationHandler marque une notification comme lue et redirige vers son contenu,
+// ou marque toutes les notifications comme lues quand le champ all est présent
+func (h *Handler) ReadNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if r.FormValue("all") != "" {
+		err := h.Notifications.MarkAllNotificationsRead(r.Context(), currentUser.ID)
+		if err != nil {
+			http.Error(w, "Erreur lors de la mise à jour des notifications", http.StatusInternalServerError)
+			log.Printf("Error marking notifications as read: %v", err)
+			return
+		}
+		http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+		return
+	}
+
+	notificationID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil || notificationID <= 0 {
+		http.Error(w, "Notification invalide", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Notifications.MarkNotificationRead(r.Context(), notificationID, currentUser.ID)
+	if err != nil {
+		respondError(w, err, notificationErrors, "marking notification as read")
+		return
+	}
+
+	// L'adresse est reconstruite à partir des IDs pour ne rediriger que vers le forum
+	target := "/notifications"
+	if postID, err := strconv.Atoi(r.FormValue("post_id")); err == nil && postID > 0 {
+		target = "/post/" + strconv.Itoa(postID)
+		if commentID, err := strconv.Atoi(r.FormValue("comment_id")); err == nil && commentID > 0 {
+			target += "#comment-" + strconv.Itoa(commentID)
+		}
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
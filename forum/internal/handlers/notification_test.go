@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Une notification se lit par son destinataire seulement, les autres reçoivent une 404
+func TestReadNotification(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	alice := createUser(t, h, "alice", "user")
+	bob := createUser(t, h, "bob", "moderator")
+	postID := createPost(t, h, bob, "Question", 1)
+	commentID, err := h.Comments.CreateComment(ctx, "Qu'en penses-tu @alice ?", bob.ID, postID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	notifications, err := h.Notifications.GetNotifications(ctx, alice.ID, 10)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("notifications of the mentioned user: %d, error %v", len(notifications), err)
+	}
+	form := url.Values{"id": {itoa(notifications[0].ID)}, "post_id": {itoa(postID)}, "comment_id": {itoa(commentID)}}
+
+	w := serve(h.ReadNotificationHandler, postForm("/notifications/read", form), bob)
+	expectStatus(t, w, http.StatusNotFound)
+	w = serve(h.ReadNotificationHandler, postForm("/notifications/read", url.Values{"id": {"999"}}), alice)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = serve(h.ReadNotificationHandler, postForm("/notifications/read", form), alice)
+	expectRedirect(t, w, "/post/"+itoa(postID)+"#comment-"+itoa(commentID))
+	notifications, _ = h.Notifications.GetNotifications(ctx, alice.ID, 10)
+	if !notifications[0].IsRead {
+		t.Error("notification is still unread")
+	}
+}
+
+// Un membre qui suit la catégorie parente ou l'auteur n'est pas prévenu d'un post réservé à l'équipe
+func TestNotificationsRespectReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	author := createUser(t, h, "author", "moderator")
+	member := createUser(t, h, "member", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+
+	parentID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Annonces internes"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	staffID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Équipe", ParentID: parentID, ReadPermission: models.CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	for _, user := range []*models.User{member, moderator} {
+		follow := url.Values{"target_type": {"category"}, "target_id": {itoa(parentID)}}
+		expectStatus(t, serve(h.SubscriptionHandler, postForm("/subscription/follow", follow), user), http.StatusSeeOther)
+		follow = url.Values{"target_type": {"user"}, "target_id": {itoa(author.ID)}}
+		expectStatus(t, serve(h.SubscriptionHandler, postForm("/subscription/follow", follow), user), http.StatusSeeOther)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	postID, err := h.Posts.CreatePost(ctx, "Sujet interne", "Pour @member et @moderator", author.ID, []int{staffID})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	if _, err := h.Comments.CreateComment(ctx, "Encore @member", author.ID, postID); err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+
+	for _, target := range []url.Values{
+		{"target_type": {"post"}, "target_id": {itoa(postID)}},
+		{"target_type": {"category"}, "target_id": {itoa(staffID)}},
+	} {
+		w := serve(h.SubscriptionHandler, postForm("/subscription/follow", target), member)
+		expectStatus(t, w, http.StatusNotFound)
+	}
+
+	for _, tt := range []struct {
+		user          *models.User
+		notifications int
+		digestPosts   int
+	}{
+		{member, 0, 0},
+		{moderator, 1, 1},
+	} {
+		notifications, err := h.Notifications.GetNotifications(ctx, tt.user.ID, 10)
+		if err != nil || len(notifications) != tt.notifications {
+			t.Errorf("notifications of %s: got %d, error %v, want %d", tt.user.Username, len(notifications), err, tt.notifications)
+		}
+		digest, err := h.Digests.GetDigest(ctx, tt.user.ID, since, time.Now().Add(time.Hour))
+		if err != nil || len(digest.Posts) != tt.digestPosts || len(digest.Mentions) != tt.notifications {
+			t.Errorf("digest of %s: %+v, error %v", tt.user.Username, digest, err)
+		}
+	}
+}
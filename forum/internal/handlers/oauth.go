@@ -1,12 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
-	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"forum/internal/database"
 	"forum/internal/models"
 	"log"
 	"net/http"
@@ -71,7 +71,7 @@ func generateState() string {
 }
 
 // GoogleLoginHandler initie l'authentification avec Google
-func GoogleLoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GoogleLoginHandler(w http.ResponseWriter, r *http.Request) {
 	state := generateState()
 	
 	// Stocker l'état pour la vérification ultérieure
@@ -94,7 +94,7 @@ func GoogleLoginHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // GoogleCallbackHandler traite la réponse de Google après authentification
-func GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	// Récupérer le code et l'état depuis les paramètres
 	code := r.URL.Query().Get("code")
 	state := r.URL.Query().Get("state")
@@ -159,7 +159,7 @@ func GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Traiter l'authentification de l'utilisateur
-	user, err := processOAuthUser(profileData.Email, profileData.Name, "google_"+profileData.Sub)
+	user, err := h.processOAuthUser(r.Context(), profileData.Email, profileData.Name, "google_"+profileData.Sub)
 	if err != nil {
 		http.Error(w, "Authentication failed", http.StatusInternalServerError)
 		log.Printf("OAuth authentication error: %v", err)
@@ -167,7 +167,7 @@ func GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Créer une session pour l'utilisateur
-	session, err := models.CreateSession(user.ID)
+	session, err := h.Sessions.CreateSession(r.Context(), user.ID)
 	if err != nil {
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		log.Printf("Error creating session: %v", err)
@@ -190,7 +190,7 @@ func GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // GitHubLoginHandler initie l'authentification avec GitHub
-func GitHubLoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GitHubLoginHandler(w http.ResponseWriter, r *http.Request) {
 	state := generateState()
 	
 	// Stocker l'état pour la vérification ultérieure
@@ -213,7 +213,7 @@ func GitHubLoginHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // GitHubCallbackHandler traite la réponse de GitHub après authentification
-func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	// Récupérer le code et l'état depuis les paramètres
 	code := r.URL.Query().Get("code")
 	state := r.URL.Query().Get("state")
@@ -319,7 +319,7 @@ func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Traiter l'authentification de l'utilisateur
-	user, err := processOAuthUser(profileData.Email, profileData.Name, fmt.Sprintf("github_%d", profileData.ID))
+	user, err := h.processOAuthUser(r.Context(), profileData.Email, profileData.Name, fmt.Sprintf("github_%d", profileData.ID))
 	if err != nil {
 		http.Error(w, "Authentication failed", http.StatusInternalServerError)
 		log.Printf("OAuth authentication error: %v", err)
@@ -327,7 +327,7 @@ func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Créer une session pour l'utilisateur
-	session, err := models.CreateSession(user.ID)
+	session, err := h.Sessions.CreateSession(r.Context(), user.ID)
 	if err != nil {
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		log.Printf("Error creating session: %v", err)
@@ -350,76 +350,53 @@ func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Traiter l'authentification OAuth
-func processOAuthUser(email, name, oauthID string) (*models.User, error) {
+func (h *Handler) processOAuthUser(ctx context.Context, email, name, oauthID string) (*models.User, error) {
 	// Vérifier si l'utilisateur existe déjà avec cet ID OAuth
-	var userID int
-	
-	err := database.DB.QueryRow(
-		"SELECT id FROM users WHERE oauth_id = ?", 
-		oauthID,
-	).Scan(&userID)
-	
+	user, err := h.Users.GetUserByOAuthID(ctx, oauthID)
 	if err == nil {
-		// L'utilisateur existe déjà, on le récupère
-		return models.GetUserByID(userID)
-	} else if err != sql.ErrNoRows {
+		// L'utilisateur existe déjà
+		return user, nil
+	} else if !errors.Is(err, models.ErrUserNotFound) {
 		// Erreur inattendue
 		return nil, err
 	}
 	
 	// Vérifier si l'utilisateur existe avec cet email
-	err = database.DB.QueryRow(
-		"SELECT id FROM users WHERE email = ?",
-		email,
-	).Scan(&userID)
-	
+	user, err = h.Users.GetUserByEmail(ctx, email)
 	if err == nil {
 		// L'utilisateur existe avec cet email, on met à jour son OAuth ID
-		_, err = database.DB.Exec(
-			"UPDATE users SET oauth_id = ? WHERE id = ?",
-			oauthID, userID,
-		)
+		err = h.Users.LinkOAuthID(ctx, user.ID, oauthID)
 		if err != nil {
 			return nil, err
 		}
-		return models.GetUserByID(userID)
-	} else if err != sql.ErrNoRows {
+		return user, nil
+	} else if !errors.Is(err, models.ErrUserNotFound) {
 		// Erreur inattendue
 		return nil, err
 	}
 	
 	// L'utilisateur n'existe pas, on le crée
-	username := generateUsername(name)
+	username := h.generateUsername(ctx, name)
 	
 	// Insérer le nouvel utilisateur dans la base de données
-	result, err := database.DB.Exec(
-		"INSERT INTO users (email, username, password, oauth_id, role) VALUES (?, ?, ?, ?, ?)",
-		email, username, "", oauthID, "user",
-	)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Récupérer l'ID du nouvel utilisateur
-	id, err := result.LastInsertId()
+	id, err := h.Users.CreateOAuthUser(ctx, email, username, oauthID)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Récupérer l'utilisateur créé
-	return models.GetUserByID(int(id))
+	return h.Users.GetUserByID(ctx, id)
 }
 
 // Générer un nom d'utilisateur unique à partir du nom complet
-func generateUsername(name string) string {
+func (h *Handler) generateUsername(ctx context.Context, name string) string {
 	// Nettoyer le nom (enlever les espaces, caractères spéciaux, etc.)
 	name = strings.ToLower(name)
 	name = strings.ReplaceAll(name, " ", "")
 	
 	// Vérifier si ce nom d'utilisateur existe déjà
-	var count int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", name).Scan(&count)
-	if err != nil || count == 0 {
+	exists, err := h.Users.UsernameExists(ctx, name)
+	if err != nil || !exists {
 		// Si le nom n'existe pas ou s'il y a une erreur, on retourne le nom tel quel
 		return name
 	}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// expiryLayout est le format des champs datetime-local de fin d'épinglage et de fin d'annonce
+const expiryLayout = "2006-01-02T15:04"
+
+// errInvalidExpiry est renvoyée quand le champ de fin d'épinglage ou de fin d'annonce n'est pas une date
+var errInvalidExpiry = errors.New("invalid expiry date")
+
+// pinErrors traduit les erreurs de l'épinglage et du verrouillage qui viennent de la saisie du modérateur
+var pinErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrPostNotFound, "Post introuvable", http.StatusNotFound},
+		{models.ErrPostNotInCategory, "Le post ne fait pas partie de cette catégorie", http.StatusBadRequest},
+		{errInvalidExpiry, "Date de fin invalide", http.StatusBadRequest},
+		{models.ErrPinExpiryInPast, "La fin de l'épinglage doit être dans le futur", http.StatusBadRequest},
+	},
+	failure: "Erreur lors de l'enregistrement",
+}
+
+// expiryFromForm lit un champ datetime-local facultatif, zéro s'il est vide
+func expiryFromForm(r *http.Request, field string) (time.Time, error) {
+	value := r.FormValue(field)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	expiry, err := time.ParseInLocation(expiryLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, errInvalidExpiry
+	}
+	return expiry, nil
+}
+
+// PinPostHandler épingle un post en tête de la liste de tous les posts ou d'une catégorie, ou retire
+// son épinglage: POST /mod/post/pin avec post_id, action (pin ou unpin), category_id (0 pour
+// la liste de tous les posts) et pinned_until (facultatif)
+func (h *Handler) PinPostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireModerator(w, r) == nil {
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		http.Error(w, "Post invalide", http.StatusBadRequest)
+		return
+	}
+
+	switch r.FormValue("action") {
+	case "pin":
+		categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+		if err != nil || categoryID < 0 {
+			http.Error(w, "Catégorie invalide", http.StatusBadRequest)
+			return
+		}
+		until, err := expiryFromForm(r, "pinned_until")
+		if err == nil {
+			err = h.Moderation.PinPost(r.Context(), postID, categoryID, until, time.Now())
+		}
+		if err != nil {
+			respondError(w, err, pinErrors, "pinning post")
+			return
+		}
+	case "unpin":
+		if err := h.Moderation.UnpinPost(r.Context(), postID); err != nil {
+			respondError(w, err, pinErrors, "unpinning post")
+			return
+		}
+	default:
+		http.Error(w, "Action invalide", http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusSeeOther)
+}
+
+// LockPostHandler verrouille ou déverrouille un post: POST /mod/post/lock avec post_id et action (lock ou unlock)
+func (h *Handler) LockPostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireModerator(w, r) == nil {
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		http.Error(w, "Post invalide", http.StatusBadRequest)
+		return
+	}
+	action := r.FormValue("action")
+	if action != "lock" && action != "unlock" {
+		http.Error(w, "Action invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Moderation.SetPostLocked(r.Context(), postID, action == "lock"); err != nil {
+		respondError(w, err, pinErrors, "locking post")
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusSeeOther)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"forum/internal/store"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Les erreurs de la saisie du modérateur sont traduites, les autres cas publient ou épinglent
+func TestPinAndAnnouncementErrors(t *testing.T) {
+	h := newTestHandler(t)
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, moderator, "À épingler", 1)
+	past := time.Now().Add(-time.Hour).Format(expiryLayout)
+
+	for _, c := range []struct {
+		handler http.HandlerFunc
+		target  string
+		form    url.Values
+		status  int
+	}{
+		{h.PinPostHandler, "/mod/post/pin", url.Values{"post_id": {"999"}, "action": {"pin"}, "category_id": {"0"}}, http.StatusNotFound},
+		{h.PinPostHandler, "/mod/post/pin", url.Values{"post_id": {itoa(postID)}, "action": {"pin"}, "category_id": {"2"}}, http.StatusBadRequest},
+		{h.PinPostHandler, "/mod/post/pin", url.Values{"post_id": {itoa(postID)}, "action": {"pin"}, "category_id": {"0"}, "pinned_until": {"demain"}}, http.StatusBadRequest},
+		{h.PinPostHandler, "/mod/post/pin", url.Values{"post_id": {itoa(postID)}, "action": {"pin"}, "category_id": {"0"}, "pinned_until": {past}}, http.StatusBadRequest},
+		{h.LockPostHandler, "/mod/post/lock", url.Values{"post_id": {"999"}, "action": {"lock"}}, http.StatusNotFound},
+		{h.CreateAnnouncementHandler, "/mod/announcement/create", url.Values{"message": {" "}, "level": {"info"}}, http.StatusBadRequest},
+		{h.CreateAnnouncementHandler, "/mod/announcement/create", url.Values{"message": {"Maintenance"}, "level": {"urgent"}}, http.StatusBadRequest},
+		{h.CreateAnnouncementHandler, "/mod/announcement/create", url.Values{"message": {"Maintenance"}, "level": {"info"}, "expires_at": {past}}, http.StatusBadRequest},
+	} {
+		w := serve(c.handler, postForm(c.target, c.form), moderator)
+		if w.Code != c.status {
+			t.Errorf("%s %v: got status %d, want %d (body: %.200s)", c.target, c.form, w.Code, c.status, w.Body.String())
+		}
+	}
+
+	w := serve(h.PinPostHandler, postForm("/mod/post/pin", url.Values{"post_id": {itoa(postID)}, "action": {"pin"}, "category_id": {"1"}}), moderator)
+	expectRedirect(t, w, "/post/"+itoa(postID))
+}
+
+// contextAnnouncements lit les annonces comme la base: une requête annulée n'obtient que son erreur
+type contextAnnouncements struct {
+	store.ModerationStore
+}
+
+func (c contextAnnouncements) GetActiveAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.ModerationStore.GetActiveAnnouncements(ctx, now)
+}
+
+// Les annonces en cours sont affichées en haut des pages, lues avec le contexte de la requête
+func TestActiveAnnouncementsOnPages(t *testing.T) {
+	h := newTestHandler(t)
+	h.Moderation = contextAnnouncements{h.Moderation}
+	moderator := createUser(t, h, "moderator", "moderator")
+
+	form := url.Values{"message": {"Maintenance ce soir"}, "level": {"warning"}}
+	expectRedirect(t, serve(h.CreateAnnouncementHandler, postForm("/mod/announcement/create", form), moderator), "/mod/announcements")
+
+	w := serve(h.SearchHandler, get("/search"), nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Maintenance ce soir") || !strings.Contains(body, "announcement-warning") {
+		t.Errorf("announcement missing from the page: %.300s", body)
+	}
+
+	// Les annonces d'une requête annulée ne sont pas lues, la page est tout de même rendue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = serve(h.SearchHandler, get("/search").WithContext(ctx), nil)
+	expectStatus(t, w, http.StatusOK)
+	if strings.Contains(w.Body.String(), "Maintenance ce soir") {
+		t.Error("announcements were read despite the cancelled request")
+	}
+}
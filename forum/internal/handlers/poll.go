@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pollCloseLayout est le format du champ datetime-local de la date de clôture
+const pollCloseLayout = "2006-01-02T15:04"
+
+// errInvalidPollClose est renvoyée quand la date de clôture du formulaire ne peut pas être lue
+var errInvalidPollClose = errors.New("invalid poll close date")
+
+// pollErrors traduit les erreurs des sondages qui viennent de la saisie de l'utilisateur
+var pollErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrPollQuestionRequired, "La question du sondage est obligatoire", http.StatusBadRequest},
+		{models.ErrPollQuestionTooLong, "La question du sondage est trop longue", http.StatusBadRequest},
+		{models.ErrPollOptionTooLong, "Une option du sondage est trop longue", http.StatusBadRequest},
+		{models.ErrDuplicatePollOption, "Deux options du sondage sont identiques", http.StatusBadRequest},
+		{models.ErrPollTooFewOptions, "Un sondage a besoin d'au moins deux options", http.StatusBadRequest},
+		{models.ErrPollTooManyOptions, "Un sondage a au plus 10 options", http.StatusBadRequest},
+		{errInvalidPollClose, "Date de clôture invalide", http.StatusBadRequest},
+		{models.ErrPollClosesInPast, "La date de clôture doit être dans le futur", http.StatusBadRequest},
+		{models.ErrPollHasVotes, "Le sondage a déjà reçu des votes: il ne peut plus être modifié", http.StatusConflict},
+		{models.ErrPollNotFound, "Sondage introuvable", http.StatusNotFound},
+		{models.ErrPollClosed, "Le sondage est clos", http.StatusForbidden},
+		{models.ErrVoteCannotChange, "Ce sondage ne permet pas de changer son vote", http.StatusForbidden},
+		{models.ErrNoOptionSelected, "Choisissez au moins une option", http.StatusBadRequest},
+		{models.ErrSingleChoicePoll, "Ce sondage n'accepte qu'un seul choix", http.StatusBadRequest},
+		{models.ErrInvalidPollOption, "Option invalide", http.StatusBadRequest},
+	},
+	failure: "Erreur lors de l'enregistrement du sondage",
+}
+
+// pollFromForm lit le sondage des formulaires de création et de modification d'un post
+// Renvoie nil sans erreur quand la question et les options sont vides: le post n'a pas de sondage
+func pollFromForm(r *http.Request) (*models.Poll, error) {
+	question := r.FormValue("poll_question")
+	options := strings.Split(r.FormValue("poll_options"), "\n")
+	if strings.TrimSpace(question) == "" && strings.TrimSpace(r.FormValue("poll_options")) == "" {
+		return nil, nil
+	}
+
+	var closesAt time.Time
+	if value := r.FormValue("poll_closes_at"); value != "" {
+		var err error
+		closesAt, err = time.ParseInLocation(pollCloseLayout, value, time.Local)
+		if err != nil {
+			return nil, errInvalidPollClose
+		}
+	}
+
+	return models.NewPoll(question, options,
+		r.FormValue("poll_multiple") == "on",
+		r.FormValue("poll_public") != "on",
+		r.FormValue("poll_vote_change") == "on",
+		closesAt, time.Now(),
+	)
+}
+
+// pollOptionsText renvoie les options d'un sondage une par ligne, pour pré-remplir le formulaire de modification
+func pollOptionsText(poll *models.Poll) string {
+	labels := make([]string, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		labels = append(labels, option.Label)
+	}
+	return strings.Join(labels, "\n")
+}
+
+// VotePollHandler enregistre le vote de l'utilisateur connecté sur un sondage
+func (h *Handler) VotePollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulaire invalide", http.StatusBadRequest)
+		return
+	}
+	pollID, err := strconv.Atoi(r.FormValue("poll_id"))
+	if err != nil || pollID <= 0 {
+		http.Error(w, "Sondage invalide", http.StatusBadRequest)
+		return
+	}
+	var optionIDs []int
+	for _, value := range r.Form["option"] {
+		optionID, err := strconv.Atoi(value)
+		if err != nil || optionID <= 0 {
+			http.Error(w, "Option invalide", http.StatusBadRequest)
+			return
+		}
+		optionIDs = append(optionIDs, optionID)
+	}
+
+	if err := h.Polls.Vote(r.Context(), pollID, currentUser.ID, optionIDs); err != nil {
+		respondError(w, err, pollErrors, "voting on poll")
+		return
+	}
+
+	http.Redirect(w, r, localRedirect(r.FormValue("redirect"), "/"), http.StatusSeeOther)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// createPoll ajoute un sondage à choix unique au post et le renvoie
+func createPoll(t *testing.T, h *Handler, postID int) *models.Poll {
+	t.Helper()
+	ctx := context.Background()
+	poll, err := models.NewPoll("Oui ou non ?", []string{"Oui", "Non"}, false, false, false, time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("building poll: %v", err)
+	}
+	if err := h.Polls.UpdatePoll(ctx, postID, poll); err != nil {
+		t.Fatalf("creating poll: %v", err)
+	}
+	poll, err = h.Polls.GetPoll(ctx, postID, 0)
+	if err != nil {
+		t.Fatalf("fetching poll: %v", err)
+	}
+	return poll
+}
+
+// voteForm construit le formulaire de vote pour les options données
+func voteForm(poll *models.Poll, options ...*models.PollOption) *http.Request {
+	values := url.Values{"poll_id": {itoa(poll.ID)}, "redirect": {"/post/" + itoa(poll.PostID)}}
+	for _, option := range options {
+		values.Add("option", itoa(option.ID))
+	}
+	return postForm("/poll/vote", values)
+}
+
+func TestVotePoll(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	member := createUser(t, h, "member", "user")
+	postID := createPost(t, h, author, "Un sondage", 1)
+	poll := createPoll(t, h, postID)
+
+	expectRedirect(t, serve(h.VotePollHandler, voteForm(poll, poll.Options[0]), nil), "/login")
+	expectRedirect(t, serve(h.VotePollHandler, voteForm(poll, poll.Options[0]), member), "/post/"+itoa(postID))
+
+	// Le sondage n'autorise ni un second vote, ni plusieurs options
+	expectStatus(t, serve(h.VotePollHandler, voteForm(poll, poll.Options[1]), member), http.StatusForbidden)
+	expectStatus(t, serve(h.VotePollHandler, voteForm(poll, poll.Options...), author), http.StatusBadRequest)
+	expectStatus(t, serve(h.VotePollHandler, voteForm(poll), author), http.StatusBadRequest)
+
+	voted, err := h.Polls.GetPoll(context.Background(), postID, member.ID)
+	if err != nil {
+		t.Fatalf("fetching poll: %v", err)
+	}
+	if voted.Options[0].Votes != 1 || voted.Options[1].Votes != 0 {
+		t.Errorf("votes: %d and %d, want 1 and 0", voted.Options[0].Votes, voted.Options[1].Votes)
+	}
+
+	unknown := postForm("/poll/vote", url.Values{"poll_id": {"999"}, "option": {"1"}})
+	expectStatus(t, serve(h.VotePollHandler, unknown, member), http.StatusNotFound)
+}
+
+// Un membre ne peut pas voter au sondage d'un post qu'il ne peut pas lire
+func TestVotePollReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	moderator := createUser(t, h, "moderator", "moderator")
+	member := createUser(t, h, "member", "user")
+	poll := createPoll(t, h, createStaffPost(t, h, moderator, "Sondage du staff"))
+
+	expectStatus(t, serve(h.VotePollHandler, voteForm(poll, poll.Options[0]), member), http.StatusNotFound)
+	expectStatus(t, serve(h.VotePollHandler, voteForm(poll, poll.Options[0]), moderator), http.StatusSeeOther)
+}
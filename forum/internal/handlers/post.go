@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"forum/internal/middleware"
 	"forum/internal/models"
@@ -8,18 +10,83 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
+// postableCategories renvoie les catégories proposées dans le formulaire d'un post:
+// celles où l'utilisateur peut publier, plus celles où le post se trouve déjà
+func postableCategories(categories []models.Category, role string, current []models.Category) []models.Category {
+	var result []models.Category
+	for _, category := range categories {
+		if category.AllowsPostsFrom(role) || hasCategoryID(current, category.ID) {
+			result = append(result, category)
+		}
+	}
+	// Une catégorie archivée n'est plus proposée, sauf au post qui s'y trouve déjà
+	for _, category := range current {
+		if category.Archived {
+			result = append(result, category)
+		}
+	}
+	return result
+}
+
+// hasCategoryID vérifie si une catégorie fait partie de la liste
+func hasCategoryID(categories []models.Category, categoryID int) bool {
+	for _, category := range categories {
+		if category.ID == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
 
+	h.listPosts(w, r, "")
+}
+
+// postListURL renvoie l'adresse de la liste des posts avec les filtres donnés
+func postListURL(categoryID, userID int, solved, sortBy string, tags []string) string {
+	query := url.Values{}
+	if categoryID > 0 {
+		query.Set("category", strconv.Itoa(categoryID))
+	}
+	if userID > 0 {
+		query.Set("user", strconv.Itoa(userID))
+	}
+	if solved != "" {
+		query.Set("solved", solved)
+	}
+	if sortBy != "" && sortBy != "date_desc" {
+		query.Set("sort", sortBy)
+	}
+	for _, tag := range tags {
+		query.Add("tag", tag)
+	}
+	if len(query) == 0 {
+		return "/"
+	}
+	return "/?" + query.Encode()
+}
+
+// activeTag est un tag utilisé comme filtre, avec l'adresse de la liste sans ce filtre
+type activeTag struct {
+	Name      string
+	RemoveURL string
+}
+
+// listPosts affiche la liste des posts filtrée par les paramètres de l'URL
+// pathTag est le tag de l'adresse /tag/{name}, vide sur la page d'accueil
+func (h *Handler) listPosts(w http.ResponseWriter, r *http.Request, pathTag string) {
 	currentUser := middleware.GetUserFromContext(r)
 	var currentUserID int
 	if currentUser != nil {
@@ -54,31 +121,110 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		sortBy = "date_desc"
 	}
 
-	posts, total, err := models.GetPosts(page, perPage, categoryID, userID, sortBy, currentUserID)
+	// Filtre sur la réponse acceptée; une valeur inconnue affiche tous les posts
+	solved := r.URL.Query().Get("solved")
+	if solved != models.PostsSolved && solved != models.PostsUnsolved {
+		solved = ""
+	}
+
+	// Les filtres par tag se cumulent entre eux et avec la catégorie
+	var tags []string
+	seenTags := map[string]bool{}
+	for _, name := range append([]string{pathTag}, r.URL.Query()["tag"]...) {
+		if tag := models.NormalizeTag(name); tag != "" && !seenTags[tag] {
+			seenTags[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	var activeTags []activeTag
+	for i, tag := range tags {
+		others := append(append([]string{}, tags[:i]...), tags[i+1:]...)
+		activeTags = append(activeTags, activeTag{Name: tag, RemoveURL: postListURL(categoryID, userID, solved, sortBy, others)})
+	}
+
+	posts, total, err := h.Posts.GetPosts(r.Context(), page, perPage, categoryID, tags, userID, solved, sortBy, currentUserID)
 	if err != nil {
 		http.Error(w, "Error fetching posts", http.StatusInternalServerError)
 		log.Printf("Error fetching posts: %v", err)
 		return
 	}
 
-	categories, err := models.GetAllCategories()
+	categories, err := h.Posts.GetAllCategories(r.Context(), userRole(currentUser))
 	if err != nil {
 		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
 		log.Printf("Error fetching categories: %v", err)
 		return
 	}
 
+	tagCloud, err := h.Tags.GetTagCloud(r.Context(), 30)
+	if err != nil {
+		http.Error(w, "Error fetching tags", http.StatusInternalServerError)
+		log.Printf("Error fetching tag cloud: %v", err)
+		return
+	}
+
+	// Un tag du nuage s'ajoute aux filtres en cours
+	tagCloudURLs := map[string]string{}
+	for _, tag := range tagCloud {
+		tagCloudURLs[tag.Name] = postListURL(categoryID, userID, solved, sortBy, append(append([]string{}, tags...), tag.Name))
+	}
+
+	tree, err := h.Categories.GetCategoryTree(r.Context(), userRole(currentUser))
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		log.Printf("Error fetching category tree: %v", err)
+		return
+	}
+
+	// Dans une catégorie, on affiche sa branche et ses sous-catégories à la place de l'arbre complet
+	var breadcrumb []models.Category
+	if categoryID > 0 {
+		if node := models.FindCategoryNode(tree, categoryID); node != nil {
+			tree = node.Children
+			breadcrumb = models.CategoryPath(categories, categoryID)
+		}
+	}
+
+	// Boutons de suivi de la catégorie et de l'auteur filtrés
+	var followCategory, followUser *followState
+	if categoryID > 0 && len(breadcrumb) > 0 {
+		followCategory, err = h.followState(r.Context(), currentUser, models.SubscriptionCategory, categoryID, r.URL.RequestURI())
+		if err != nil {
+			http.Error(w, "Error fetching subscriptions", http.StatusInternalServerError)
+			log.Printf("Error fetching category subscription: %v", err)
+			return
+		}
+	}
+	if userID > 0 {
+		followUser, err = h.followState(r.Context(), currentUser, models.SubscriptionUser, userID, r.URL.RequestURI())
+		if err != nil {
+			http.Error(w, "Error fetching subscriptions", http.StatusInternalServerError)
+			log.Printf("Error fetching user subscription: %v", err)
+			return
+		}
+	}
+
 	totalPages := (total + perPage - 1) / perPage
 
 	data := map[string]interface{}{
-		"Posts":       posts,
-		"Categories":  categories,
-		"CurrentUser": currentUser,
-		"CurrentPage": page,
-		"TotalPages":  totalPages,
-		"CategoryID":  categoryID,
-		"UserID":      userID,
-		"SortBy":      sortBy,
+		"Posts":          posts,
+		"Categories":     categories,
+		"CategoryTree":   tree,
+		"Breadcrumb":     breadcrumb,
+		"TagCloud":       tagCloud,
+		"TagCloudURLs":   tagCloudURLs,
+		"Tag":            pathTag,
+		"Tags":           tags,
+		"ActiveTags":     activeTags,
+		"CurrentUser":    currentUser,
+		"CurrentPage":    page,
+		"TotalPages":     totalPages,
+		"CategoryID":     categoryID,
+		"UserID":         userID,
+		"Solved":         solved,
+		"SortBy":         sortBy,
+		"FollowCategory": followCategory,
+		"FollowUser":     followUser,
 	}
 
 	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/home.html")
@@ -88,6 +234,8 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -95,7 +243,7 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	currentUser := middleware.GetUserFromContext(r)
 	if currentUser == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -103,7 +251,7 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == http.MethodGet {
-		categories, err := models.GetAllCategories()
+		categories, err := h.Posts.GetAllCategories(r.Context(), currentUser.Role)
 		if err != nil {
 			http.Error(w, "Error fetching categories", http.StatusInternalServerError)
 			log.Printf("Error fetching categories: %v", err)
@@ -111,10 +259,26 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		data := map[string]interface{}{
-			"Categories":  categories,
+			"Categories":  postableCategories(categories, currentUser.Role, nil),
 			"CurrentUser": currentUser,
 		}
 
+		// Reprise d'un brouillon depuis le profil
+		if draftID, err := strconv.Atoi(r.URL.Query().Get("draft")); err == nil && draftID > 0 {
+			draft, err := h.Drafts.GetDraft(r.Context(), draftID, currentUser.ID)
+			if err != nil {
+				respondError(w, err, draftErrors, "fetching draft")
+				return
+			}
+			// Le brouillon de modification d'un post se reprend depuis la page de modification
+			if draft.PostID != 0 {
+				http.Redirect(w, r, "/post/edit/"+strconv.Itoa(draft.PostID), http.StatusSeeOther)
+				return
+			}
+			data["Draft"] = draft
+			data["TagList"] = strings.Join(draft.Tags, ", ")
+		}
+
 		tmpl, err := utils.ParseTemplate("templates/base.html", "templates/create_post.html")
 		if err != nil {
 			http.Error(w, "Error loading templates", http.StatusInternalServerError)
@@ -122,6 +286,8 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 		err = tmpl.ExecuteTemplate(w, "base", data)
 		if err != nil {
 			http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -155,66 +321,90 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		postID, err := models.CreatePost(title, content, currentUser.ID, categoryIDsInt)
+		tags, err := models.ParseTags(r.FormValue("tags"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Printf("Error creating post: %v", err)
+			respondError(w, err, tagErrors, "reading tags")
 			return
 		}
 
-		file, header, err := r.FormFile("image")
-		if err == nil {
-			defer file.Close()
-
-			buff := make([]byte, 512)
-			_, err = file.Read(buff)
-			if err != nil {
-				http.Error(w, "Error reading file", http.StatusInternalServerError)
-				log.Printf("Error reading file: %v", err)
-				return
-			}
+		// Un post programmé reste un brouillon jusqu'à sa publication par le planificateur
+		if r.FormValue("action") == "schedule" {
+			h.schedulePost(w, r, currentUser)
+			return
+		}
 
-			file.Seek(0, io.SeekStart)
+		// Le sondage est vérifié avant de créer le post, pour ne pas publier un post sans son sondage
+		poll, err := pollFromForm(r)
+		if err != nil {
+			respondError(w, err, pollErrors, "reading poll")
+			return
+		}
 
-			filetype := http.DetectContentType(buff)
-			if !isAllowedImageType(filetype) {
-				http.Error(w, "The provided file format is not allowed. Please upload a JPEG, PNG or GIF image", http.StatusBadRequest)
-				return
-			}
+		// Les catégories archivées, en lecture seule ou réservées aux modérateurs sont refusées
+		err = h.Categories.CheckCategoryPermission(r.Context(), categoryIDsInt, currentUser.Role)
+		if err != nil {
+			respondError(w, err, categoryPermissionErrors, "checking category permission")
+			return
+		}
 
-			filename := fmt.Sprintf("%d_%s", currentUser.ID, header.Filename)
-			filename = sanitizeFilename(filename)
-			filepath := filepath.Join(UploadDir, filename)
+		// Les liens et les images demandent un niveau de confiance minimal
+		if !h.requireLinkTrust(w, r, currentUser, title, content) {
+			return
+		}
+		if _, _, err := r.FormFile("image"); err == nil && !h.requireImageTrust(w, r, currentUser) {
+			return
+		}
 
-			dst, err := os.Create(filepath)
+		// Les posts des nouveaux membres passent par la file de modération avec leurs tags et leur sondage
+		reputation, err := h.Reputation.GetReputation(r.Context(), currentUser.ID)
+		if err != nil {
+			http.Error(w, "Error computing reputation", http.StatusInternalServerError)
+			log.Printf("Error computing reputation: %v", err)
+			return
+		}
+		if !reputation.Allows(models.MinTrustSkipModeration) {
+			_, err := h.Moderation.SubmitPendingPost(r.Context(), models.NewPost{
+				Title:       title,
+				Content:     content,
+				UserID:      currentUser.ID,
+				CategoryIDs: categoryIDsInt,
+				Tags:        tags,
+				Poll:        poll,
+			})
 			if err != nil {
-				http.Error(w, "Error saving the file", http.StatusInternalServerError)
-				log.Printf("Error creating file: %v", err)
-				return
-			}
-			defer dst.Close()
-
-			if _, err = io.Copy(dst, file); err != nil {
-				http.Error(w, "Error saving the file", http.StatusInternalServerError)
-				log.Printf("Error copying file: %v", err)
+				http.Error(w, "Error submitting post", http.StatusInternalServerError)
+				log.Printf("Error submitting pending post: %v", err)
 				return
 			}
+			h.discardDraft(r, currentUser.ID)
+			http.Redirect(w, r, "/post/submitted", http.StatusSeeOther)
+			return
+		}
 
-			imageID, err := models.SaveImage(filename, currentUser.ID)
-			if err != nil {
-				http.Error(w, "Error saving image information", http.StatusInternalServerError)
-				log.Printf("Error saving image info: %v", err)
-				return
-			}
+		// L'image est enregistrée d'abord: le post, ses tags, son sondage et son image sont ensuite
+		// publiés ensemble, et l'image est effacée si la publication échoue
+		imageID, filename, ok := h.saveUploadedImage(w, r, currentUser.ID)
+		if !ok {
+			return
+		}
 
-			err = models.AssociateImageWithPost(imageID, postID)
-			if err != nil {
-				http.Error(w, "Error associating image with post", http.StatusInternalServerError)
-				log.Printf("Error associating image: %v", err)
-				return
-			}
+		postID, err := h.Posts.CreatePostWithDetails(r.Context(), models.NewPost{
+			Title:       title,
+			Content:     content,
+			UserID:      currentUser.ID,
+			CategoryIDs: categoryIDsInt,
+			Tags:        tags,
+			Poll:        poll,
+			ImageID:     imageID,
+		})
+		if err != nil {
+			h.discardUploadedImage(r.Context(), imageID, currentUser.ID, filename)
+			http.Error(w, "Error creating post", http.StatusInternalServerError)
+			log.Printf("Error creating post: %v", err)
+			return
 		}
 
+		h.discardDraft(r, currentUser.ID)
 		http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusSeeOther)
 		return
 	}
@@ -222,7 +412,101 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 }
 
-func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
+// saveUploadedImage enregistre l'image du formulaire et renvoie son ID et son nom de fichier,
+// ou 0 si le formulaire n'en contient pas. Renvoie false après avoir répondu si l'image est refusée
+func (h *Handler) saveUploadedImage(w http.ResponseWriter, r *http.Request, userID int) (int, string, bool) {
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		return 0, "", true
+	}
+	defer file.Close()
+
+	buff := make([]byte, 512)
+	_, err = file.Read(buff)
+	if err != nil {
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		log.Printf("Error reading file: %v", err)
+		return 0, "", false
+	}
+
+	file.Seek(0, io.SeekStart)
+
+	filetype := http.DetectContentType(buff)
+	if !isAllowedImageType(filetype) {
+		http.Error(w, "The provided file format is not allowed. Please upload a JPEG, PNG or GIF image", http.StatusBadRequest)
+		return 0, "", false
+	}
+
+	filename := fmt.Sprintf("%d_%s", userID, header.Filename)
+	filename = sanitizeFilename(filename)
+
+	dst, err := os.Create(filepath.Join(UploadDir, filename))
+	if err != nil {
+		http.Error(w, "Error saving the file", http.StatusInternalServerError)
+		log.Printf("Error creating file: %v", err)
+		return 0, "", false
+	}
+	defer dst.Close()
+
+	if _, err = io.Copy(dst, file); err != nil {
+		http.Error(w, "Error saving the file", http.StatusInternalServerError)
+		log.Printf("Error copying file: %v", err)
+		return 0, "", false
+	}
+
+	imageID, err := h.Images.SaveImage(r.Context(), filename, userID)
+	if err != nil {
+		http.Error(w, "Error saving image information", http.StatusInternalServerError)
+		log.Printf("Error saving image info: %v", err)
+		return 0, "", false
+	}
+	return imageID, filename, true
+}
+
+// discardUploadedImage efface l'image d'un post qui n'a pas pu être publié
+func (h *Handler) discardUploadedImage(ctx context.Context, imageID, userID int, filename string) {
+	if imageID == 0 {
+		return
+	}
+	if err := h.Images.DeleteImage(ctx, imageID, userID, false); err != nil {
+		log.Printf("Error deleting image of failed post: %v", err)
+	}
+	if err := os.Remove(filepath.Join(UploadDir, filename)); err != nil {
+		log.Printf("Error removing image file of failed post: %v", err)
+	}
+}
+
+// PostSubmittedHandler confirme l'envoi d'un post à la file de modération
+func (h *Handler) PostSubmittedHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser": currentUser,
+		"PageTitle":   "Post envoyé à la modération",
+		"Level":       models.MinTrustSkipModeration.Label(),
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/post_submitted.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+func (h *Handler) ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 3 {
 		http.NotFound(w, r)
@@ -241,7 +525,7 @@ func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 		currentUserID = currentUser.ID
 	}
 
-	post, err := models.GetPostByID(postID, currentUserID)
+	post, err := h.Posts.GetPostByID(r.Context(), postID, currentUserID)
 	if err != nil {
 		if err.Error() == "post not found" {
 			http.NotFound(w, r)
@@ -252,24 +536,96 @@ func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comments, err := models.GetCommentsByPostID(postID, currentUserID)
+	// Un post rangé dans une catégorie que l'utilisateur ne peut pas lire n'existe pas pour lui
+	paths, err := h.categoryPaths(r.Context(), post)
+	if err != nil {
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		log.Printf("Error fetching category paths: %v", err)
+		return
+	}
+	if !readAllowed(paths, userRole(currentUser)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	comments, err := h.Comments.GetCommentsByPostID(r.Context(), postID, currentUserID)
 	if err != nil {
 		http.Error(w, "Error fetching comments", http.StatusInternalServerError)
 		log.Printf("Error fetching comments: %v", err)
 		return
 	}
 
-	postImage, err := models.GetPostImage(postID)
+	postImage, err := h.Images.GetPostImage(r.Context(), postID)
 	if err != nil && err.Error() != "image not found" {
 		log.Printf("Error fetching post image: %v", err)
 	}
 
+	// Le fil d'Ariane suit la première catégorie du post
+	var breadcrumb []models.Category
+	if len(paths) > 0 {
+		breadcrumb = paths[0]
+	}
+
+	// Les mentions du post et des commentaires deviennent des liens vers les profils
+	contents := []string{post.Content}
+	for _, comment := range comments {
+		contents = append(contents, comment.Content)
+	}
+	mentions, err := h.mentionLinks(r.Context(), contents...)
+	if err != nil {
+		http.Error(w, "Error resolving mentions", http.StatusInternalServerError)
+		log.Printf("Error resolving mentions: %v", err)
+		return
+	}
+
+	follow, err := h.followState(r.Context(), currentUser, models.SubscriptionPost, postID, r.URL.RequestURI())
+	if err != nil {
+		http.Error(w, "Error fetching subscription", http.StatusInternalServerError)
+		log.Printf("Error fetching post subscription: %v", err)
+		return
+	}
+
+	poll, err := h.Polls.GetPoll(r.Context(), postID, currentUserID)
+	if err != nil && !errors.Is(err, models.ErrPollNotFound) {
+		http.Error(w, "Error fetching poll", http.StatusInternalServerError)
+		log.Printf("Error fetching poll: %v", err)
+		return
+	}
+
+	// La réponse acceptée est aussi affichée juste sous la question
+	var acceptedAnswer *models.Comment
+	for _, comment := range comments {
+		if comment.ID == post.AcceptedCommentID {
+			acceptedAnswer = comment
+			break
+		}
+	}
+
+	// Un post peut être épinglé dans ses catégories et dans leurs catégories parentes
+	var pinCategories []models.Category
+	for _, path := range paths {
+		for _, category := range path {
+			if !hasCategoryID(pinCategories, category.ID) {
+				pinCategories = append(pinCategories, category)
+			}
+		}
+	}
+
 	data := map[string]interface{}{
-		"Post":        post,
-		"PostImage":   postImage,
-		"Comments":    comments,
-		"CurrentUser": currentUser,
-		"CanEdit":     currentUser != nil && (currentUser.ID == post.UserID || currentUser.Role == "admin"),
+		"Post":           post,
+		"PostImage":      postImage,
+		"Comments":       comments,
+		"AcceptedAnswer": acceptedAnswer,
+		"CurrentUser":    currentUser,
+		"CanEdit":        currentUser != nil && (currentUser.ID == post.UserID || currentUser.Role == "admin"),
+		"CanAccept":      currentUser != nil && (currentUser.ID == post.UserID || currentUser.Role == "moderator" || currentUser.Role == "admin"),
+		"CanComment":     commentsAllowed(paths),
+		"CanModerate":    currentUser != nil && (currentUser.Role == "moderator" || currentUser.Role == "admin"),
+		"PinCategories":  pinCategories,
+		"Breadcrumb":     breadcrumb,
+		"Mentions":       mentions,
+		"Follow":         follow,
+		"Poll":           poll,
 	}
 
 	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/view_post.html")
@@ -279,6 +635,8 @@ func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -286,7 +644,7 @@ func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func EditPostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EditPostHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 4 || parts[2] != "edit" {
 		http.NotFound(w, r)
@@ -305,7 +663,7 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := models.GetPostByID(postID, currentUser.ID)
+	post, err := h.Posts.GetPostByID(r.Context(), postID, currentUser.ID)
 	if err != nil {
 		if err.Error() == "post not found" {
 			http.NotFound(w, r)
@@ -321,13 +679,21 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postImage, err := models.GetPostImage(postID)
+	postImage, err := h.Images.GetPostImage(r.Context(), postID)
 	if err != nil && err.Error() != "image not found" {
 		log.Printf("Error fetching post image: %v", err)
 	}
 
+	// Le sondage ne peut être modifié qu'avant son premier vote
+	poll, err := h.Polls.GetPoll(r.Context(), postID, currentUser.ID)
+	if err != nil && !errors.Is(err, models.ErrPollNotFound) {
+		http.Error(w, "Error fetching poll", http.StatusInternalServerError)
+		log.Printf("Error fetching poll: %v", err)
+		return
+	}
+
 	if r.Method == http.MethodGet {
-		categories, err := models.GetAllCategories()
+		categories, err := h.Posts.GetAllCategories(r.Context(), currentUser.Role)
 		if err != nil {
 			http.Error(w, "Error fetching categories", http.StatusInternalServerError)
 			log.Printf("Error fetching categories: %v", err)
@@ -342,10 +708,34 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 		data := map[string]interface{}{
 			"Post":               post,
 			"PostImage":          postImage,
-			"Categories":         categories,
+			"Categories":         postableCategories(categories, currentUser.Role, post.Categories),
 			"SelectedCategories": selectedCategoryIDs,
+			"Title":              post.Title,
+			"Content":            post.Content,
+			"TagList":            strings.Join(models.TagNames(post.Tags), ", "),
+			"Poll":               poll,
 			"CurrentUser":        currentUser,
 		}
+		if poll != nil {
+			data["PollOptions"] = pollOptionsText(poll)
+		}
+
+		// Une modification interrompue est reprise depuis son brouillon, sauf si le post a changé depuis
+		draft, err := h.Drafts.GetPostDraft(r.Context(), postID, currentUser.ID)
+		switch {
+		case err == nil && draft.UpdatedAt.After(post.UpdatedAt):
+			data["Draft"] = draft
+			data["Title"] = draft.Title
+			data["Content"] = draft.Content
+			data["SelectedCategories"] = draft.CategoryIDs
+			data["TagList"] = strings.Join(draft.Tags, ", ")
+		case err == nil:
+			if err := h.Drafts.DeleteDraft(r.Context(), draft.ID, currentUser.ID); err != nil {
+				log.Printf("Error deleting outdated draft: %v", err)
+			}
+		case !errors.Is(err, models.ErrDraftNotFound):
+			log.Printf("Error fetching post draft: %v", err)
+		}
 
 		tmpl, err := utils.ParseTemplate("templates/base.html", "templates/edit_post.html")
 		if err != nil {
@@ -354,6 +744,8 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 		err = tmpl.ExecuteTemplate(w, "base", data)
 		if err != nil {
 			http.Error(w, "Error rendering page", http.StatusInternalServerError)
@@ -388,15 +780,66 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = models.UpdatePost(postID, currentUser.ID, title, content, categoryIDsInt)
+		tags, err := models.ParseTags(r.FormValue("tags"))
+		if err != nil {
+			respondError(w, err, tagErrors, "reading tags")
+			return
+		}
+
+		pollLocked := poll != nil && poll.Locked
+		var newPoll *models.Poll
+		if !pollLocked {
+			newPoll, err = pollFromForm(r)
+			if err != nil {
+				respondError(w, err, pollErrors, "reading poll")
+				return
+			}
+		}
+
+		// Seules les catégories ajoutées sont vérifiées: le post garde le droit de rester dans les siennes
+		var addedCategoryIDs []int
+		for _, id := range categoryIDsInt {
+			if !hasCategoryID(post.Categories, id) {
+				addedCategoryIDs = append(addedCategoryIDs, id)
+			}
+		}
+		err = h.Categories.CheckCategoryPermission(r.Context(), addedCategoryIDs, currentUser.Role)
+		if err != nil {
+			respondError(w, err, categoryPermissionErrors, "checking category permission")
+			return
+		}
+
+		// Les liens et les images demandent un niveau de confiance minimal
+		if !h.requireLinkTrust(w, r, currentUser, title, content) {
+			return
+		}
+		if _, _, err := r.FormFile("image"); err == nil && !removeImage && !h.requireImageTrust(w, r, currentUser) {
+			return
+		}
+
+		err = h.Posts.UpdatePost(r.Context(), postID, currentUser.ID, title, content, categoryIDsInt)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			log.Printf("Error updating post: %v", err)
 			return
 		}
 
+		err = h.Tags.SetPostTags(r.Context(), postID, tags)
+		if err != nil {
+			http.Error(w, "Error saving tags", http.StatusInternalServerError)
+			log.Printf("Error saving post tags: %v", err)
+			return
+		}
+
+		if !pollLocked && (poll != nil || newPoll != nil) {
+			if err = h.Polls.UpdatePoll(r.Context(), postID, newPoll); err != nil {
+				respondError(w, err, pollErrors, "updating poll")
+				return
+			}
+		}
+
 		if removeImage && postImage != nil {
-			err = models.DeletePostImage(postID)
+			err = h.Images.DeletePostImage(r.Context(), postID)
 			if err != nil {
 				log.Printf("Error removing post image: %v", err)
 			}
@@ -440,20 +883,20 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 				}
 
 				if postImage != nil {
-					err = models.DeletePostImage(postID)
+					err = h.Images.DeletePostImage(r.Context(), postID)
 					if err != nil {
 						log.Printf("Error removing old post image: %v", err)
 					}
 				}
 
-				imageID, err := models.SaveImage(filename, currentUser.ID)
+				imageID, err := h.Images.SaveImage(r.Context(), filename, currentUser.ID)
 				if err != nil {
 					http.Error(w, "Error saving image information", http.StatusInternalServerError)
 					log.Printf("Error saving image info: %v", err)
 					return
 				}
 
-				err = models.AssociateImageWithPost(imageID, postID)
+				err = h.Images.AssociateImageWithPost(r.Context(), imageID, postID)
 				if err != nil {
 					http.Error(w, "Error associating image with post", http.StatusInternalServerError)
 					log.Printf("Error associating image: %v", err)
@@ -462,6 +905,9 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if err := h.Drafts.DeletePostDraft(r.Context(), postID, currentUser.ID); err != nil {
+			log.Printf("Error deleting post draft: %v", err)
+		}
 		http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusSeeOther)
 		return
 	}
@@ -469,7 +915,7 @@ func EditPostHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 }
 
-func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 4 || parts[2] != "delete" {
 		http.NotFound(w, r)
@@ -489,7 +935,7 @@ func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	isAdmin := currentUser.Role == "admin"
-	err = models.DeletePost(postID, currentUser.ID, isAdmin)
+	err = h.Posts.DeletePost(r.Context(), postID, currentUser.ID, isAdmin)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Printf("Error deleting post: %v", err)
@@ -499,7 +945,7 @@ func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-func ReactToPostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ReactToPostHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -526,12 +972,73 @@ func ReactToPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = models.ReactToPost(postID, currentUser.ID, reactionType)
+	err = h.Posts.ReactToPost(r.Context(), postID, currentUser.ID, reactionType)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("Error reacting to post: %v", err)
+		respondError(w, err, reactionErrors, "reacting to post")
 		return
 	}
 
 	http.Redirect(w, r, "/post/"+postIDStr, http.StatusSeeOther)
-}
\ No newline at end of file
+}
+
+// acceptAnswerErrors traduit les erreurs du choix de la réponse acceptée
+var acceptAnswerErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrPostNotFound, "Post introuvable", http.StatusNotFound},
+		{models.ErrCommentNotFound, "Ce commentaire n'appartient pas à ce post", http.StatusBadRequest},
+	},
+	failure: "Erreur lors de l'enregistrement de la réponse acceptée",
+}
+
+// AcceptAnswerHandler marque un commentaire comme la réponse acceptée d'un post, ou retire
+// la réponse acceptée si comment_id vaut 0. Seuls l'auteur du post et les modérateurs le peuvent
+func (h *Handler) AcceptAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Error(w, "Vous devez être connecté pour accepter une réponse", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête incorrecte", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		http.Error(w, "Post invalide", http.StatusBadRequest)
+		return
+	}
+	commentID, err := strconv.Atoi(r.FormValue("comment_id"))
+	if err != nil || commentID < 0 {
+		http.Error(w, "Commentaire invalide", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.Posts.GetPostByID(r.Context(), postID, currentUser.ID)
+	if err != nil {
+		respondError(w, err, acceptAnswerErrors, "fetching post")
+		return
+	}
+
+	if currentUser.ID != post.UserID && currentUser.Role != "moderator" && currentUser.Role != "admin" {
+		http.Error(w, "Seul l'auteur du post ou un modérateur peut choisir la réponse acceptée", http.StatusForbidden)
+		return
+	}
+
+	if err := h.Posts.AcceptAnswer(r.Context(), postID, commentID); err != nil {
+		respondError(w, err, acceptAnswerErrors, "accepting answer")
+		return
+	}
+
+	target := "/post/" + strconv.Itoa(postID)
+	if commentID > 0 {
+		target += "#comment-" + strconv.Itoa(commentID)
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"forum/internal/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// multipartForm construit la requête POST du formulaire de création d'un post
+func multipartForm(t *testing.T, target string, fields map[string][]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, values := range fields {
+		for _, value := range values {
+			if err := writer.WriteField(name, value); err != nil {
+				t.Fatalf("writing field %s: %v", name, err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("closing form: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+func TestHomeListsPosts(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+	createPost(t, h, admin, "Premier post", 1)
+	createPost(t, h, admin, "Second post", 2)
+
+	w := serve(h.HomeHandler, get("/"), nil)
+	expectStatus(t, w, http.StatusOK)
+	for _, title := range []string{"Premier post", "Second post"} {
+		if !strings.Contains(w.Body.String(), title) {
+			t.Errorf("home page does not list %q", title)
+		}
+	}
+
+	w = serve(h.HomeHandler, get("/unknown"), nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestViewPost(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+	postID := createPost(t, h, admin, "Un post à lire", 1)
+
+	w := serve(h.ViewPostHandler, get("/post/"+itoa(postID)), nil)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "Un post à lire") {
+		t.Error("post page does not show the title")
+	}
+
+	w = serve(h.ViewPostHandler, get("/post/999"), nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+// Un post d'une catégorie réservée à l'équipe, ou de l'une de ses sous-catégories, est caché aux autres
+func TestCategoryReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+
+	staffID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Équipe", ReadPermission: models.CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	subID, err := h.Categories.CreateCategory(ctx, models.Category{Name: "Sous-forum", ParentID: staffID})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	hiddenID := createPost(t, h, admin, "Post du staff", subID)
+	createPost(t, h, admin, "Post public", 1)
+
+	for _, user := range []*models.User{nil, member} {
+		w := serve(h.HomeHandler, get("/"), user)
+		expectStatus(t, w, http.StatusOK)
+		if strings.Contains(w.Body.String(), "Post du staff") || !strings.Contains(w.Body.String(), "Post public") {
+			t.Errorf("home page for %v: staff post must be hidden and public post listed", user)
+		}
+		w = serve(h.ViewPostHandler, get("/post/"+itoa(hiddenID)), user)
+		expectStatus(t, w, http.StatusNotFound)
+	}
+
+	w := serve(h.ViewPostHandler, get("/post/"+itoa(hiddenID)), moderator)
+	expectStatus(t, w, http.StatusOK)
+	w = serve(h.HomeHandler, get("/"), moderator)
+	if !strings.Contains(w.Body.String(), "Post du staff") {
+		t.Error("moderator does not see the staff post")
+	}
+
+	// Publier dans une catégorie qu'on ne peut pas lire est refusé comme une catégorie inconnue
+	r := multipartForm(t, "/post/create", map[string][]string{
+		"title": {"Intrus"}, "content": {"Contenu"}, "categories": {itoa(subID)},
+	})
+	w = serve(h.CreatePostHandler, r, member)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestCreatePost(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+
+	r := multipartForm(t, "/post/create", map[string][]string{
+		"title": {"Nouveau post"}, "content": {"Du contenu"}, "categories": {"1", "2"}, "tags": {"go, sqlite"},
+		"poll_question": {"Oui ou non ?"}, "poll_options": {"Oui\nNon"},
+	})
+	w := serve(h.CreatePostHandler, r, admin)
+	expectRedirect(t, w, "/post/1")
+
+	post, err := h.Posts.GetPostByID(context.Background(), 1, admin.ID)
+	if err != nil {
+		t.Fatalf("fetching created post: %v", err)
+	}
+	if post.Title != "Nouveau post" || len(post.Categories) != 2 || len(post.Tags) != 2 {
+		t.Errorf("created post: title %q, %d categories, %d tags", post.Title, len(post.Categories), len(post.Tags))
+	}
+	if poll, err := h.Polls.GetPoll(context.Background(), 1, admin.ID); err != nil || len(poll.Options) != 2 {
+		t.Errorf("poll of the created post: %v, error %v", poll, err)
+	}
+}
+
+func TestCreatePostValidation(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+
+	w := serve(h.CreatePostHandler, multipartForm(t, "/post/create", nil), nil)
+	expectRedirect(t, w, "/login")
+
+	r := multipartForm(t, "/post/create", map[string][]string{"title": {"Sans contenu"}})
+	w = serve(h.CreatePostHandler, r, member)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	moderatorsOnly, err := h.Categories.CreateCategory(context.Background(),
+		models.Category{Name: "Annonces", PostPermission: models.CategoryModeratorsOnly})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	r = multipartForm(t, "/post/create", map[string][]string{
+		"title": {"Titre"}, "content": {"Contenu"}, "categories": {itoa(moderatorsOnly)},
+	})
+	w = serve(h.CreatePostHandler, r, member)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+// Les posts d'un nouveau membre passent par la file de modération
+func TestCreatePostNewMemberIsModerated(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+
+	r := multipartForm(t, "/post/create", map[string][]string{
+		"title": {"Premier message"}, "content": {"Bonjour"}, "categories": {"1"},
+		"poll_question": {"Oui ou non ?"}, "poll_options": {"Oui\nNon"},
+	})
+	w := serve(h.CreatePostHandler, r, member)
+	expectRedirect(t, w, "/post/submitted")
+
+	pending, err := h.Moderation.GetPendingPosts(context.Background())
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending posts: %d, error %v", len(pending), err)
+	}
+	if pending[0].Poll == nil {
+		t.Error("pending post lost its poll")
+	}
+	if _, total, _ := h.Posts.GetPosts(context.Background(), 1, 10, 0, nil, 0, "", "", 0); total != 0 {
+		t.Errorf("moderated post was published: %d posts", total)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "moderator")
+	other := createUser(t, h, "other", "user")
+	postID := createPost(t, h, author, "À supprimer", 1)
+
+	w := serve(h.DeletePostHandler, postForm("/post/delete/"+itoa(postID), nil), other)
+	expectStatus(t, w, http.StatusInternalServerError)
+
+	w = serve(h.DeletePostHandler, postForm("/post/delete/"+itoa(postID), nil), author)
+	expectRedirect(t, w, "/")
+	if _, err := h.Posts.GetPostByID(context.Background(), postID, 0); err == nil {
+		t.Error("post still exists after deletion")
+	}
+}
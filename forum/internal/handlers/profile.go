@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"forum/internal/middleware"
 	"forum/internal/models"
 	"forum/internal/utils"
@@ -9,8 +10,27 @@ import (
 	"strconv"
 )
 
+// readablePosts retire les posts rangés dans une catégorie qu'un utilisateur de ce rôle ne peut pas lire
+func (h *Handler) readablePosts(ctx context.Context, posts []*models.Post, role string) ([]*models.Post, error) {
+	categories, err := h.Categories.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var readable []*models.Post
+	for _, post := range posts {
+		categoryIDs := make([]int, len(post.Categories))
+		for i, category := range post.Categories {
+			categoryIDs[i] = category.ID
+		}
+		if models.CanReadPost(categories, categoryIDs, role) {
+			readable = append(readable, post)
+		}
+	}
+	return readable, nil
+}
+
 // ProfileHandler gère l'affichage de la page de profil/activité de l'utilisateur
-func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	// Vérifier que l'utilisateur est connecté
 	currentUser := middleware.GetUserFromContext(r)
 	if currentUser == nil {
@@ -29,7 +49,7 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		if err == nil && requestedID > 0 && requestedID != currentUser.ID {
 			userID = requestedID
 			// Récupérer les informations de l'utilisateur demandé
-			profileUser, err = models.GetUserByID(userID)
+			profileUser, err = h.Users.GetUserByID(r.Context(), userID)
 			if err != nil {
 				http.Error(w, "Utilisateur non trouvé", http.StatusNotFound)
 				return
@@ -37,6 +57,47 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Bouton de suivi du profil affiché, absent sur son propre profil
+	follow, err := h.followState(r.Context(), currentUser, models.SubscriptionUser, profileUser.ID, r.URL.RequestURI())
+	if err != nil {
+		log.Printf("Error fetching user subscription: %v", err)
+		http.Error(w, "Erreur lors de la récupération des abonnements", http.StatusInternalServerError)
+		return
+	}
+
+	// Le profil d'un utilisateur bloqué propose de le débloquer plutôt que de lui écrire
+	isBlocked := false
+	if profileUser.ID != currentUser.ID {
+		blocked, err := h.Messages.GetBlockedUsers(r.Context(), currentUser.ID)
+		if err != nil {
+			log.Printf("Error fetching blocked users: %v", err)
+			http.Error(w, "Erreur lors de la récupération des utilisateurs bloqués", http.StatusInternalServerError)
+			return
+		}
+		for _, b := range blocked {
+			if b.UserID == profileUser.ID {
+				isBlocked = true
+			}
+		}
+	}
+
+	// Les badges sont attribués à l'affichage du profil, sans attendre le passage du planificateur
+	if _, err := h.Reputation.AwardBadges(r.Context(), profileUser.ID); err != nil {
+		log.Printf("Error awarding badges: %v", err)
+	}
+	reputation, err := h.Reputation.GetReputation(r.Context(), profileUser.ID)
+	if err != nil {
+		log.Printf("Error computing reputation: %v", err)
+		http.Error(w, "Erreur lors du calcul de la réputation", http.StatusInternalServerError)
+		return
+	}
+	badges, err := h.Reputation.GetUserBadges(r.Context(), profileUser.ID)
+	if err != nil {
+		log.Printf("Error fetching user badges: %v", err)
+		http.Error(w, "Erreur lors de la récupération des badges", http.StatusInternalServerError)
+		return
+	}
+
 	// Récupérer l'onglet actif
 	tab := r.URL.Query().Get("tab")
 	if tab == "" {
@@ -49,13 +110,17 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		"ProfileUser":  profileUser,
 		"IsOwnProfile": currentUser.ID == profileUser.ID,
 		"ActiveTab":    tab,
+		"Follow":       follow,
+		"IsBlocked":    isBlocked,
+		"Reputation":   reputation,
+		"Badges":       badges,
 		"PageTitle":    "Profil de " + profileUser.Username,
 	}
 
 	// Récupérer les données spécifiques à l'onglet
 	switch tab {
 	case "activity":
-		activities, err := models.GetUserActivity(userID, 50) // Limité aux 50 dernières activités
+		activities, err := h.Users.GetUserActivity(r.Context(), userID, 50) // Limité aux 50 dernières activités
 		if err != nil {
 			log.Printf("Error fetching user activities: %v", err)
 			http.Error(w, "Erreur lors de la récupération des activités", http.StatusInternalServerError)
@@ -64,7 +129,10 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		data["Activities"] = activities
 
 	case "posts":
-		posts, err := models.GetUserPosts(userID)
+		posts, err := h.Posts.GetUserPosts(r.Context(), userID)
+		if err == nil {
+			posts, err = h.readablePosts(r.Context(), posts, currentUser.Role)
+		}
 		if err != nil {
 			log.Printf("Error fetching user posts: %v", err)
 			http.Error(w, "Erreur lors de la récupération des posts", http.StatusInternalServerError)
@@ -73,7 +141,7 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		data["Posts"] = posts
 
 	case "comments":
-		comments, err := models.GetUserComments(userID)
+		comments, err := h.Comments.GetUserComments(r.Context(), userID)
 		if err != nil {
 			log.Printf("Error fetching user comments: %v", err)
 			http.Error(w, "Erreur lors de la récupération des commentaires", http.StatusInternalServerError)
@@ -82,7 +150,10 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		data["Comments"] = comments
 
 	case "likes":
-		likedPosts, err := models.GetUserLikedPosts(userID)
+		likedPosts, err := h.Posts.GetUserLikedPosts(r.Context(), userID)
+		if err == nil {
+			likedPosts, err = h.readablePosts(r.Context(), likedPosts, currentUser.Role)
+		}
 		if err != nil {
 			log.Printf("Error fetching liked posts: %v", err)
 			http.Error(w, "Erreur lors de la récupération des posts aimés", http.StatusInternalServerError)
@@ -91,13 +162,54 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		data["LikedPosts"] = likedPosts
 
 	case "dislikes":
-		dislikedPosts, err := models.GetUserDislikedPosts(userID)
+		dislikedPosts, err := h.Posts.GetUserDislikedPosts(r.Context(), userID)
+		if err == nil {
+			dislikedPosts, err = h.readablePosts(r.Context(), dislikedPosts, currentUser.Role)
+		}
 		if err != nil {
 			log.Printf("Error fetching disliked posts: %v", err)
 			http.Error(w, "Erreur lors de la récupération des posts non aimés", http.StatusInternalServerError)
 			return
 		}
 		data["DislikedPosts"] = dislikedPosts
+
+	case "saved":
+		// Les signets sont privés: l'onglet n'existe que sur son propre profil
+		if profileUser.ID != currentUser.ID {
+			http.Error(w, "Les signets sont privés", http.StatusForbidden)
+			return
+		}
+		folderID, _ := strconv.Atoi(r.URL.Query().Get("folder"))
+		folders, err := h.Bookmarks.GetBookmarkFolders(r.Context(), currentUser.ID)
+		if err != nil {
+			log.Printf("Error fetching bookmark folders: %v", err)
+			http.Error(w, "Erreur lors de la récupération des signets", http.StatusInternalServerError)
+			return
+		}
+		bookmarks, err := h.Bookmarks.GetBookmarks(r.Context(), currentUser.ID, folderID)
+		if err != nil {
+			log.Printf("Error fetching bookmarks: %v", err)
+			http.Error(w, "Erreur lors de la récupération des signets", http.StatusInternalServerError)
+			return
+		}
+		data["BookmarkFolders"] = folders
+		data["Bookmarks"] = bookmarks
+		data["ActiveFolder"] = folderID
+		data["Redirect"] = r.URL.RequestURI()
+
+	case "drafts":
+		// Les brouillons sont privés: l'onglet n'existe que sur son propre profil
+		if profileUser.ID != currentUser.ID {
+			http.Error(w, "Les brouillons sont privés", http.StatusForbidden)
+			return
+		}
+		drafts, err := h.Drafts.GetUserDrafts(r.Context(), currentUser.ID)
+		if err != nil {
+			log.Printf("Error fetching drafts: %v", err)
+			http.Error(w, "Erreur lors de la récupération des brouillons", http.StatusInternalServerError)
+			return
+		}
+		data["Drafts"] = drafts
 	}
 
 	// Charger et exécuter le template
@@ -108,6 +220,8 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
 	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
 		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestProfileTabs(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+	other := createUser(t, h, "other", "user")
+	createPost(t, h, member, "Post du membre", 1)
+
+	expectRedirect(t, serve(h.ProfileHandler, get("/profile"), nil), "/login")
+	expectStatus(t, serve(h.ProfileHandler, get("/profile?user=999"), member), http.StatusNotFound)
+
+	for _, tab := range []string{"activity", "posts", "comments", "likes", "dislikes", "saved", "drafts"} {
+		w := serve(h.ProfileHandler, get("/profile?tab="+tab), member)
+		if w.Code != http.StatusOK {
+			t.Errorf("tab %s of the own profile: got status %d (body: %.200s)", tab, w.Code, w.Body.String())
+		}
+	}
+	w := serve(h.ProfileHandler, get("/profile?tab=posts"), member)
+	if !strings.Contains(w.Body.String(), "Post du membre") {
+		t.Errorf("posts tab misses the post: %.300s", w.Body.String())
+	}
+
+	// Les signets et les brouillons des autres sont privés
+	target := "/profile?user=" + itoa(member.ID)
+	for _, tab := range []string{"saved", "drafts"} {
+		expectStatus(t, serve(h.ProfileHandler, get(target+"&tab="+tab), other), http.StatusForbidden)
+	}
+	w = serve(h.ProfileHandler, get(target+"&tab=posts"), other)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Profil de member") || !strings.Contains(body, "Post du membre") {
+		t.Errorf("profile of another member: %.300s", body)
+	}
+}
+
+// L'onglet des posts d'un profil ne montre que les posts que le visiteur peut lire
+func TestProfilePostsReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	moderator := createUser(t, h, "moderator", "moderator")
+	member := createUser(t, h, "member", "user")
+	createStaffPost(t, h, moderator, "Post du staff")
+
+	target := "/profile?tab=posts&user=" + itoa(moderator.ID)
+	if w := serve(h.ProfileHandler, get(target), member); strings.Contains(w.Body.String(), "Post du staff") {
+		t.Error("a member sees a staff post on the profile")
+	}
+	if w := serve(h.ProfileHandler, get("/profile?tab=posts"), moderator); !strings.Contains(w.Body.String(), "Post du staff") {
+		t.Error("the moderator does not see their own staff post")
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// reactionErrors traduit les erreurs des réactions qui viennent de la saisie de l'utilisateur
+var reactionErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrInvalidReactionType, "Réaction inconnue", http.StatusBadRequest},
+		{models.ErrReactionArchived, "Cette réaction n'est plus proposée", http.StatusBadRequest},
+		{models.ErrInvalidReactionTarget, "Contenu invalide", http.StatusBadRequest},
+		{models.ErrReactionTargetNotFound, "Contenu introuvable", http.StatusNotFound},
+		{models.ErrPostLocked, "Cette discussion est verrouillée: les réactions sont figées", http.StatusForbidden},
+		{models.ErrInvalidReactionName, "L'identifiant ne peut contenir que des minuscules, des chiffres, - et _ (20 caractères au plus)", http.StatusBadRequest},
+		{models.ErrInvalidReactionEmoji, "Emoji invalide", http.StatusBadRequest},
+		{models.ErrReactionLabelRequired, "Le libellé est obligatoire", http.StatusBadRequest},
+		{models.ErrReactionLabelTooLong, "Le libellé est trop long", http.StatusBadRequest},
+		{models.ErrInvalidReactionScore, "Score invalide", http.StatusBadRequest},
+		{models.ErrReactionExists, "Une réaction avec cet identifiant existe déjà", http.StatusBadRequest},
+		{models.ErrReactionNotFound, "Réaction introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de l'enregistrement de la réaction",
+}
+
+// reactorsErrors traduit les erreurs de la lecture du post dont on affiche les réactions
+var reactorsErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrPostNotFound, "Contenu introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de la récupération des réactions",
+}
+
+// reactionFromForm lit les champs du formulaire d'administration d'une réaction
+func reactionFromForm(r *http.Request) models.Reaction {
+	score, _ := strconv.Atoi(r.FormValue("score"))
+	return models.Reaction{
+		Name:     r.FormValue("name"),
+		Emoji:    r.FormValue("emoji"),
+		Label:    r.FormValue("label"),
+		Score:    score,
+		Archived: r.FormValue("archived") == "on",
+	}
+}
+
+// ReactorsHandler affiche qui a posé chaque réaction sur un post ou un commentaire:
+// /reactions?type=post|comment&id=N
+func (h *Handler) ReactorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	targetType := r.URL.Query().Get("type")
+	targetID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || targetID <= 0 || !models.ValidReactionTarget(targetType) {
+		http.Error(w, "Contenu invalide", http.StatusBadRequest)
+		return
+	}
+
+	// Le lien de retour mène au post, ou au commentaire dans la page de son post
+	postID := targetID
+	back := "/post/" + strconv.Itoa(targetID)
+	if targetType == models.ReactionOnComment {
+		comment, err := h.Comments.GetCommentByID(r.Context(), targetID, 0)
+		if err != nil {
+			http.Error(w, "Commentaire introuvable", http.StatusNotFound)
+			return
+		}
+		postID = comment.PostID
+		back = "/post/" + strconv.Itoa(comment.PostID) + "#comment-" + strconv.Itoa(targetID)
+	}
+
+	// Les réactions d'un post que l'utilisateur ne peut pas lire sont introuvables
+	currentUser := middleware.GetUserFromContext(r)
+	if _, err := h.readablePost(r.Context(), postID, currentUser); err != nil {
+		respondError(w, err, reactorsErrors, "fetching reacted post")
+		return
+	}
+
+	reactors, err := h.Reactions.GetReactors(r.Context(), targetType, targetID)
+	if err != nil {
+		respondError(w, err, reactionErrors, "fetching reactors")
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentUser": currentUser,
+		"PageTitle":   "Réactions",
+		"Reactors":    reactors,
+		"TargetType":  targetType,
+		"Back":        back,
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/reactions.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// ListReactionsHandler affiche la page d'administration des réactions
+func (h *Handler) ListReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := requireAdmin(w, r)
+	if currentUser == nil {
+		return
+	}
+
+	reactions, err := h.Reactions.GetReactions(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des réactions", http.StatusInternalServerError)
+		log.Printf("Error fetching reactions: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Reactions":   reactions,
+		"CurrentUser": currentUser,
+		"PageTitle":   "Gestion des réactions",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/admin_reactions.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// AdminReactionHandler traite les formulaires d'administration des réactions:
+// /admin/reaction/create et /admin/reaction/edit/ID
+func (h *Handler) AdminReactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if requireAdmin(w, r) == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Requête incorrecte", http.StatusBadRequest)
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, "/admin/reaction/")
+	switch {
+	case action == "create":
+		if _, err := h.Reactions.CreateReaction(r.Context(), reactionFromForm(r)); err != nil {
+			respondError(w, err, reactionErrors, "creating reaction")
+			return
+		}
+	case strings.HasPrefix(action, "edit/"):
+		reactionID, err := strconv.Atoi(strings.TrimPrefix(action, "edit/"))
+		if err != nil || reactionID <= 0 {
+			http.NotFound(w, r)
+			return
+		}
+		reaction := reactionFromForm(r)
+		reaction.ID = reactionID
+		if err := h.Reactions.UpdateReaction(r.Context(), reaction); err != nil {
+			respondError(w, err, reactionErrors, "updating reaction")
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/reactions", http.StatusSeeOther)
+}
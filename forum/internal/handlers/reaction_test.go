@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestReactToPost(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+	postID := createPost(t, h, admin, "Un post", 1)
+	react := func(reactionType string) *http.Request {
+		return postForm("/post/react", url.Values{"post_id": {itoa(postID)}, "reaction_type": {reactionType}})
+	}
+
+	// Le dislike remplace le like du même membre
+	expectRedirect(t, serve(h.ReactToPostHandler, react("like"), member), "/post/"+itoa(postID))
+	expectRedirect(t, serve(h.ReactToPostHandler, react("dislike"), member), "/post/"+itoa(postID))
+	post, err := h.Posts.GetPostByID(ctx, postID, member.ID)
+	if err != nil {
+		t.Fatalf("fetching post: %v", err)
+	}
+	if post.Likes != 0 || post.Dislikes != 1 {
+		t.Errorf("counters: %d likes, %d dislikes, want 0 and 1", post.Likes, post.Dislikes)
+	}
+
+	expectStatus(t, serve(h.ReactToPostHandler, react("unknown"), member), http.StatusBadRequest)
+
+	if err := h.Moderation.SetPostLocked(ctx, postID, true); err != nil {
+		t.Fatalf("locking post: %v", err)
+	}
+	expectStatus(t, serve(h.ReactToPostHandler, react("like"), member), http.StatusForbidden)
+}
+
+func TestReactorsReadPermission(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	moderator := createUser(t, h, "moderator", "moderator")
+	member := createUser(t, h, "member", "user")
+	publicID := createPost(t, h, member, "Un post public", 1)
+	hiddenID := createStaffPost(t, h, moderator, "Post du staff")
+	commentID, err := h.Comments.CreateComment(ctx, "Réponse du staff", moderator.ID, hiddenID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	if err := h.Posts.ReactToPost(ctx, hiddenID, moderator.ID, "like"); err != nil {
+		t.Fatalf("reacting: %v", err)
+	}
+
+	expectStatus(t, serve(h.ReactorsHandler, get("/reactions?type=post&id="+itoa(publicID)), nil), http.StatusOK)
+
+	hidden := []string{"/reactions?type=post&id=" + itoa(hiddenID), "/reactions?type=comment&id=" + itoa(commentID)}
+	for _, target := range hidden {
+		for _, user := range []*models.User{nil, member} {
+			w := serve(h.ReactorsHandler, get(target), user)
+			expectStatus(t, w, http.StatusNotFound)
+		}
+		expectStatus(t, serve(h.ReactorsHandler, get(target), moderator), http.StatusOK)
+	}
+	expectStatus(t, serve(h.ReactorsHandler, get("/reactions?type=post&id=999"), moderator), http.StatusNotFound)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"forum/internal/markdown"
+	"forum/internal/models"
+	"log"
+	"net/http"
+)
+
+// requireTrust vérifie que l'utilisateur a le niveau de confiance d'une capacité
+// Sinon elle répond avec le message donné et renvoie false
+func (h *Handler) requireTrust(w http.ResponseWriter, r *http.Request, user *models.User, minLevel models.TrustLevel, message string) bool {
+	reputation, err := h.Reputation.GetReputation(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Erreur lors du calcul de la réputation", http.StatusInternalServerError)
+		log.Printf("Error computing reputation: %v", err)
+		return false
+	}
+	if !reputation.Allows(minLevel) {
+		http.Error(w, message, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireLinkTrust refuse les contenus qui contiennent des liens vers d'autres sites quand
+// l'utilisateur n'a pas encore le niveau de confiance nécessaire
+func (h *Handler) requireLinkTrust(w http.ResponseWriter, r *http.Request, user *models.User, contents ...string) bool {
+	for _, content := range contents {
+		if markdown.HasExternalLinks(content) {
+			return h.requireTrust(w, r, user, models.MinTrustLinks,
+				"Votre niveau de confiance ne permet pas encore de publier des liens vers d'autres sites")
+		}
+	}
+	return true
+}
+
+// requireImageTrust refuse l'envoi d'images tant que l'utilisateur n'a pas le niveau de confiance nécessaire
+func (h *Handler) requireImageTrust(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	return h.requireTrust(w, r, user, models.MinTrustImages,
+		"Votre niveau de confiance ne permet pas encore d'envoyer des images")
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// searchResultJSON est la représentation d'un résultat de recherche dans l'API JSON
+type searchResultJSON struct {
+	Type      string    `json:"type"`
+	PostID    int       `json:"post_id"`
+	CommentID int       `json:"comment_id,omitempty"`
+	Title     string    `json:"title"`
+	TitleHTML string    `json:"title_highlight"`
+	Snippet   string    `json:"snippet"`
+	Highlight string    `json:"highlight"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes"`
+	Dislikes  int       `json:"dislikes"`
+	URL       string    `json:"url"`
+}
+
+// searchResponseJSON est la réponse de l'API de recherche
+type searchResponseJSON struct {
+	Query      string             `json:"query"`
+	Page       int                `json:"page"`
+	PerPage    int                `json:"per_page"`
+	Total      int                `json:"total"`
+	TotalPages int                `json:"total_pages"`
+	Results    []searchResultJSON `json:"results"`
+}
+
+// SearchHandler affiche la page de recherche et ses résultats
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	var currentUserID int
+	if currentUser != nil {
+		currentUserID = currentUser.ID
+	}
+
+	rawQuery := r.URL.Query().Get("q")
+	page := parsePage(r)
+	perPage := 10
+
+	data := map[string]interface{}{
+		"CurrentUser":   currentUser,
+		"PageTitle":     "Recherche",
+		"Query":         rawQuery,
+		"CurrentPage":   page,
+		"TotalPages":    0,
+		"Total":         0,
+		"SearchEnabled": h.Posts.SearchEnabled(),
+	}
+
+	query := models.ParseSearchQuery(rawQuery)
+	if h.Posts.SearchEnabled() && !query.IsEmpty() {
+		results, total, err := h.Posts.Search(r.Context(), query, page, perPage, currentUserID)
+		if err != nil {
+			http.Error(w, "Error searching posts", http.StatusInternalServerError)
+			log.Printf("Error searching posts: %v", err)
+			return
+		}
+		data["Results"] = results
+		data["Total"] = total
+		data["TotalPages"] = (total + perPage - 1) / perPage
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/search.html")
+	if err != nil {
+		http.Error(w, "Error loading templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// APISearchHandler expose la recherche au format JSON
+func (h *Handler) APISearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.Posts.SearchEnabled() {
+		http.Error(w, "Search is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var currentUserID int
+	if currentUser := middleware.GetUserFromContext(r); currentUser != nil {
+		currentUserID = currentUser.ID
+	}
+
+	rawQuery := r.URL.Query().Get("q")
+	query := models.ParseSearchQuery(rawQuery)
+	if query.IsEmpty() {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	page := parsePage(r)
+	perPage := 20
+	if perPageStr := r.URL.Query().Get("per_page"); perPageStr != "" {
+		if n, err := strconv.Atoi(perPageStr); err == nil && n > 0 && n <= 100 {
+			perPage = n
+		}
+	}
+
+	results, total, err := h.Posts.Search(r.Context(), query, page, perPage, currentUserID)
+	if err != nil {
+		http.Error(w, "Error searching posts", http.StatusInternalServerError)
+		log.Printf("Error searching posts: %v", err)
+		return
+	}
+
+	response := searchResponseJSON{
+		Query:      rawQuery,
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+		Results:    []searchResultJSON{},
+	}
+	for _, result := range results {
+		url := "/post/" + strconv.Itoa(result.PostID)
+		if result.CommentID > 0 {
+			url += "#comment-" + strconv.Itoa(result.CommentID)
+		}
+		response.Results = append(response.Results, searchResultJSON{
+			Type:      result.Type,
+			PostID:    result.PostID,
+			CommentID: result.CommentID,
+			Title:     result.Title,
+			TitleHTML: string(result.HighlightedTitle()),
+			Snippet:   result.PlainSnippet(),
+			Highlight: string(result.HighlightedSnippet()),
+			UserID:    result.UserID,
+			Username:  result.Username,
+			CreatedAt: result.CreatedAt,
+			Likes:     result.Likes,
+			Dislikes:  result.Dislikes,
+			URL:       url,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding search response: %v", err)
+	}
+}
+
+// parsePage lit le numéro de page dans l'URL (1 par défaut)
+func parsePage(r *http.Request) int {
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			return pageNum
+		}
+	}
+	return 1
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/models"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// searchAPI interroge l'API de recherche au nom de user et décode la réponse
+func searchAPI(t *testing.T, h *Handler, query string, user *models.User) searchResponseJSON {
+	t.Helper()
+	w := serve(h.APISearchHandler, get("/api/search?q="+url.QueryEscape(query)), user)
+	expectStatus(t, w, http.StatusOK)
+	var response searchResponseJSON
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding search response: %v", err)
+	}
+	return response
+}
+
+// La recherche ne renvoie que les posts des catégories que l'utilisateur peut lire
+func TestSearchHidesUnreadablePosts(t *testing.T) {
+	h := newTestHandler(t)
+	admin := createUser(t, h, "admin", "admin")
+	member := createUser(t, h, "member", "user")
+
+	membersID, err := h.Categories.CreateCategory(context.Background(),
+		models.Category{Name: "Membres", ReadPermission: models.CategoryReadMembers})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	createPost(t, h, admin, "Zèbre public", 1)
+	createPost(t, h, admin, "Zèbre des membres", membersID)
+
+	if response := searchAPI(t, h, "zèbre", nil); response.Total != 1 || response.Results[0].Title != "Zèbre public" {
+		t.Errorf("guest search: %+v", response)
+	}
+	if response := searchAPI(t, h, "zèbre", member); response.Total != 2 {
+		t.Errorf("member search: %d results, want 2", response.Total)
+	}
+}
+
+func TestSearchEmptyQuery(t *testing.T) {
+	h := newTestHandler(t)
+	w := serve(h.APISearchHandler, get("/api/search"), nil)
+	expectStatus(t, w, http.StatusBadRequest)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/database"
+	"forum/internal/store"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newSQLiteHandler crée des handlers branchés sur une base SQLite vide dans un dossier temporaire,
+// avec toutes les migrations appliquées. La base est fermée à la fin du test
+func newSQLiteHandler(t *testing.T) *Handler {
+	t.Helper()
+	if err := database.InitDB(database.DefaultConfig(filepath.Join(t.TempDir(), "forum.db"))); err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		database.CloseDB()
+	})
+	return New(store.NewSQLiteStores(), []byte("test-secret"), nil)
+}
+
+// Parcours complet sur la base SQLite: inscription, file de modération, abonnement, commentaire,
+// réaction et droits de lecture, en passant par les handlers comme le fait le serveur
+func TestSQLiteForumFlow(t *testing.T) {
+	h := newSQLiteHandler(t)
+	ctx := context.Background()
+
+	register := url.Values{
+		"email": {"alice@example.com"}, "username": {"alice"},
+		"password": {"password123"}, "confirm_password": {"password123"},
+	}
+	expectRedirect(t, serve(h.RegisterHandler, postForm("/register", register), nil), "/login")
+	expectStatus(t, serve(h.RegisterHandler, postForm("/register", register), nil), http.StatusBadRequest)
+	w := serve(h.LoginHandler, postForm("/login", url.Values{"email": {"alice@example.com"}, "password": {"password123"}}), nil)
+	expectRedirect(t, w, "/")
+	session, err := h.Sessions.GetSessionByUUID(ctx, w.Result().Cookies()[0].Value)
+	if err != nil {
+		t.Fatalf("session after login: %v", err)
+	}
+	alice, err := h.Users.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		t.Fatalf("fetching alice: %v", err)
+	}
+	bob := createUser(t, h, "bob", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	admin := createUser(t, h, "admin", "admin")
+
+	// Bob suit Alice avant qu'elle publie
+	follow := url.Values{"target_type": {"user"}, "target_id": {itoa(alice.ID)}}
+	expectRedirect(t, serve(h.SubscriptionHandler, postForm("/subscription/follow", follow), bob), "/following")
+
+	// Le premier post d'un nouveau membre passe par la file de modération
+	r := multipartForm(t, "/post/create", map[string][]string{
+		"title": {"Premier post"}, "content": {"Bonjour à tous"}, "categories": {"1"}, "tags": {"présentation"},
+	})
+	expectRedirect(t, serve(h.CreatePostHandler, r, alice), "/post/submitted")
+	pending, err := h.Moderation.GetPendingPosts(ctx)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending posts: %+v, error %v", pending, err)
+	}
+	w = serve(h.ApprovePostHandler, postForm("/mod/approve/"+itoa(pending[0].ID), nil), moderator)
+	expectStatus(t, w, http.StatusSeeOther)
+	postID, err := strconv.Atoi(strings.TrimPrefix(w.Header().Get("Location"), "/post/"))
+	if err != nil {
+		t.Fatalf("redirect after approval: %v", err)
+	}
+
+	w = serve(h.FollowingHandler, get("/following"), bob)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "Premier post") {
+		t.Errorf("following feed misses the post: %.300s", w.Body.String())
+	}
+
+	comment := url.Values{"post_id": {itoa(postID)}, "content": {"Bienvenue @alice"}}
+	expectRedirect(t, serve(h.CreateCommentHandler, postForm("/comment/create", comment), bob), "/post/"+itoa(postID))
+	react := url.Values{"post_id": {itoa(postID)}, "reaction_type": {"like"}}
+	expectRedirect(t, serve(h.ReactToPostHandler, postForm("/post/react", react), bob), "/post/"+itoa(postID))
+
+	w = serve(h.ViewPostHandler, get("/post/"+itoa(postID)), alice)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Bonjour à tous") || !strings.Contains(body, "Bienvenue") {
+		t.Errorf("post page: %.300s", body)
+	}
+	post, err := h.Posts.GetPostByID(ctx, postID, alice.ID)
+	if err != nil || post.Likes != 1 || len(post.Tags) != 1 {
+		t.Errorf("post after the reaction: %+v, error %v", post, err)
+	}
+	notifications, err := h.Notifications.GetNotifications(ctx, alice.ID, 10)
+	if err != nil || len(notifications) == 0 {
+		t.Errorf("notifications of alice: %+v, error %v", notifications, err)
+	}
+
+	// Les droits de lecture des catégories s'appliquent aux pages servies depuis SQLite
+	hiddenID := createStaffPost(t, h, moderator, "Post du staff")
+	expectStatus(t, serve(h.ViewPostHandler, get("/post/"+itoa(hiddenID)), bob), http.StatusNotFound)
+	expectStatus(t, serve(h.ViewPostHandler, get("/post/"+itoa(hiddenID)), moderator), http.StatusOK)
+	if w := serve(h.HomeHandler, get("/"), bob); strings.Contains(w.Body.String(), "Post du staff") {
+		t.Error("a member sees the staff post on the home page")
+	}
+
+	// Un administrateur nomme Bob modérateur: il lit alors le post du staff
+	expectRedirect(t, serve(h.UpdateUserRoleHandler, postForm("/admin/user/role/"+itoa(bob.ID), url.Values{"role": {"moderator"}}), admin), "/admin/users")
+	bob, err = h.Users.GetUserByID(ctx, bob.ID)
+	if err != nil || bob.Role != "moderator" {
+		t.Fatalf("bob after the promotion: %+v, error %v", bob, err)
+	}
+	expectStatus(t, serve(h.ViewPostHandler, get("/post/"+itoa(hiddenID)), bob), http.StatusOK)
+}
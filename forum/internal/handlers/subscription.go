@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FollowingPageSize est le nombre de posts par page du fil /following
+const FollowingPageSize = 10
+
+// followState décrit les boutons de suivi d'une cible, affichés par le template "follow-buttons"
+type followState struct {
+	TargetType string
+	TargetID   int
+	Following  bool   // L'utilisateur a un abonnement à la cible, actif ou muet
+	Muted      bool   // Cet abonnement est muet
+	Redirect   string // Page affichée après le clic
+}
+
+// followState renvoie l'état des boutons de suivi d'une cible pour l'utilisateur connecté
+// Il n'y a pas de bouton pour un visiteur ni pour suivre son propre compte
+func (h *Handler) followState(ctx context.Context, currentUser *models.User, targetType string, targetID int, redirect string) (*followState, error) {
+	if currentUser == nil || (targetType == models.SubscriptionUser && targetID == currentUser.ID) {
+		return nil, nil
+	}
+
+	state := &followState{TargetType: targetType, TargetID: targetID, Redirect: redirect}
+	subscription, err := h.Subscriptions.GetSubscription(ctx, currentUser.ID, targetType, targetID)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			return state, nil
+		}
+		return nil, err
+	}
+	state.Following = true
+	state.Muted = subscription.Muted
+	return state, nil
+}
+
+// localRedirect renvoie target si c'est une adresse du forum, fallback sinon
+func localRedirect(target, fallback string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.Contains(target, `\`) {
+		return fallback
+	}
+	return target
+}
+
+// subscriptionTarget lit la cible d'un formulaire d'abonnement
+func subscriptionTarget(r *http.Request) (string, int, bool) {
+	targetType := r.FormValue("target_type")
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil || targetID <= 0 || !models.ValidSubscriptionTarget(targetType) {
+		return "", 0, false
+	}
+	return targetType, targetID, true
+}
+
+// SubscriptionHandler traite les formulaires de suivi: /subscription/follow, /subscription/unfollow,
+// /subscription/mute et /subscription/unmute
+func (h *Handler) SubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	targetType, targetID, ok := subscriptionTarget(r)
+	if !ok {
+		http.Error(w, "Cible d'abonnement invalide", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, "/subscription/") {
+	case "follow":
+		err = h.Subscriptions.Follow(r.Context(), currentUser.ID, targetType, targetID)
+	case "unfollow":
+		err = h.Subscriptions.Unfollow(r.Context(), currentUser.ID, targetType, targetID)
+	case "mute":
+		err = h.Subscriptions.SetSubscriptionMuted(r.Context(), currentUser.ID, targetType, targetID, true)
+	case "unmute":
+		err = h.Subscriptions.SetSubscriptionMuted(r.Context(), currentUser.ID, targetType, targetID, false)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		switch err.Error() {
+		case "subscription target not found", "subscription not found":
+			http.NotFound(w, r)
+		case "cannot follow yourself":
+			http.Error(w, "Vous ne pouvez pas vous suivre vous-même", http.StatusBadRequest)
+		default:
+			http.Error(w, "Erreur lors de la mise à jour de l'abonnement", http.StatusInternalServerError)
+			log.Printf("Error updating subscription: %v", err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, localRedirect(r.FormValue("redirect"), "/following"), http.StatusSeeOther)
+}
+
+// FollowingHandler affiche le fil des posts suivis et la liste des abonnements de l'utilisateur
+func (h *Handler) FollowingHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	posts, total, err := h.Subscriptions.GetFollowingFeed(r.Context(), currentUser.ID, page, FollowingPageSize)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération du fil", http.StatusInternalServerError)
+		log.Printf("Error fetching following feed: %v", err)
+		return
+	}
+
+	subscriptions, err := h.Subscriptions.GetSubscriptions(r.Context(), currentUser.ID)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des abonnements", http.StatusInternalServerError)
+		log.Printf("Error fetching subscriptions: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Posts":         posts,
+		"Subscriptions": subscriptions,
+		"CurrentUser":   currentUser,
+		"CurrentPage":   page,
+		"TotalPages":    (total + FollowingPageSize - 1) / FollowingPageSize,
+		"Redirect":      r.URL.RequestURI(),
+		"PageTitle":     "Abonnements",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/following.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
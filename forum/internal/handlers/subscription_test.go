@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLocalRedirect(t *testing.T) {
+	for _, tt := range []struct {
+		target string
+		want   string
+	}{
+		{"/post/1", "/post/1"},
+		{"/following?page=2", "/following?page=2"},
+		{"", "/following"},
+		{"https://example.com/", "/following"},
+		{"//example.com/", "/following"},
+		{`/\example.com`, "/following"},
+	} {
+		if got := localRedirect(tt.target, "/following"); got != tt.want {
+			t.Errorf("localRedirect(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionHandler(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	reader := createUser(t, h, "reader", "user")
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, author, "Suivi", 1)
+	hiddenID := createStaffPost(t, h, moderator, "Post du staff")
+
+	target := func(targetType string, targetID int, redirect string) url.Values {
+		values := url.Values{"target_type": {targetType}, "target_id": {itoa(targetID)}}
+		if redirect != "" {
+			values.Set("redirect", redirect)
+		}
+		return values
+	}
+	expectRedirect(t, serve(h.SubscriptionHandler, postForm("/subscription/follow", target("post", postID, "")), nil), "/login")
+	expectStatus(t, serve(h.SubscriptionHandler, get("/subscription/follow"), reader), http.StatusMethodNotAllowed)
+
+	for _, c := range []struct {
+		action string
+		form   url.Values
+		status int
+	}{
+		{"follow", target("tag", 1, ""), http.StatusBadRequest},
+		{"follow", url.Values{"target_type": {"post"}, "target_id": {"abc"}}, http.StatusBadRequest},
+		{"follow", target("post", 0, ""), http.StatusBadRequest},
+		{"follow", target("user", reader.ID, ""), http.StatusBadRequest},
+		{"follow", target("post", 999, ""), http.StatusNotFound},
+		// Un post que le membre ne peut pas lire n'existe pas pour lui
+		{"follow", target("post", hiddenID, ""), http.StatusNotFound},
+		{"unfollow", target("post", postID, ""), http.StatusNotFound},
+		{"subscribe", target("post", postID, ""), http.StatusNotFound},
+	} {
+		w := serve(h.SubscriptionHandler, postForm("/subscription/"+c.action, c.form), reader)
+		if w.Code != c.status {
+			t.Errorf("%s %v: got status %d, want %d", c.action, c.form, w.Code, c.status)
+		}
+	}
+
+	// Chaque action renvoie vers la page d'origine, ou vers le fil si elle n'est pas sur le forum
+	for _, c := range []struct {
+		action   string
+		redirect string
+		location string
+		muted    bool
+	}{
+		{"follow", "/post/" + itoa(postID), "/post/" + itoa(postID), false},
+		{"mute", "https://example.com/", "/following", true},
+		{"unmute", "", "/following", false},
+	} {
+		w := serve(h.SubscriptionHandler, postForm("/subscription/"+c.action, target("post", postID, c.redirect)), reader)
+		expectRedirect(t, w, c.location)
+		subscription, err := h.Subscriptions.GetSubscription(ctx, reader.ID, "post", postID)
+		if err != nil || subscription.Muted != c.muted {
+			t.Errorf("subscription after %s: %+v, error %v", c.action, subscription, err)
+		}
+	}
+	expectRedirect(t, serve(h.SubscriptionHandler, postForm("/subscription/unfollow", target("post", postID, "")), reader), "/following")
+	if _, err := h.Subscriptions.GetSubscription(ctx, reader.ID, "post", postID); err == nil {
+		t.Error("subscription still exists after unfollow")
+	}
+}
+
+func TestFollowingHandler(t *testing.T) {
+	h := newTestHandler(t)
+	reader := createUser(t, h, "reader", "user")
+	author := createUser(t, h, "author", "user")
+	other := createUser(t, h, "other", "user")
+
+	expectRedirect(t, serve(h.FollowingHandler, get("/following"), nil), "/login")
+
+	follow := url.Values{"target_type": {"user"}, "target_id": {itoa(author.ID)}}
+	expectRedirect(t, serve(h.SubscriptionHandler, postForm("/subscription/follow", follow), reader), "/following")
+	createPost(t, h, author, "Post suivi", 1)
+	createPost(t, h, other, "Post ignoré", 1)
+
+	w := serve(h.FollowingHandler, get("/following"), reader)
+	expectStatus(t, w, http.StatusOK)
+	body := w.Body.String()
+	if !strings.Contains(body, "Post suivi") || strings.Contains(body, "Post ignoré") {
+		t.Errorf("following feed: %.300s", body)
+	}
+	if !strings.Contains(body, "author") {
+		t.Errorf("subscription to author missing from the page: %.300s", body)
+	}
+
+	// Une page au-delà de la dernière est vide, une page invalide affiche la première
+	if w := serve(h.FollowingHandler, get("/following?page=5"), reader); strings.Contains(w.Body.String(), "Post suivi") {
+		t.Error("page 5 of the feed shows the post")
+	}
+	if w := serve(h.FollowingHandler, get("/following?page=abc"), reader); !strings.Contains(w.Body.String(), "Post suivi") {
+		t.Error("invalid page does not show the first page")
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internal/middleware"
+	"forum/internal/models"
+	"forum/internal/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// tagErrors traduit les erreurs des tags et de leurs synonymes qui viennent de la saisie de l'utilisateur
+var tagErrors = errorResponses{
+	known: []errorResponse{
+		{models.ErrTagTooLong, fmt.Sprintf("Un tag ne peut pas dépasser %d caractères", models.MaxTagLength), http.StatusBadRequest},
+		{models.ErrTooManyTags, fmt.Sprintf("Un post ne peut pas avoir plus de %d tags", models.MaxTagsPerPost), http.StatusBadRequest},
+		{models.ErrTagNotFound, "Tag introuvable", http.StatusNotFound},
+		{models.ErrSynonymRequired, "Le synonyme et le tag sont obligatoires", http.StatusBadRequest},
+		{models.ErrSynonymExists, "Ce synonyme existe déjà", http.StatusBadRequest},
+		{models.ErrSynonymOfItself, "Un tag ne peut pas être son propre synonyme", http.StatusBadRequest},
+		{models.ErrSynonymNotFound, "Synonyme introuvable", http.StatusNotFound},
+	},
+	failure: "Erreur lors de l'enregistrement des tags",
+}
+
+// tagJSON est la représentation d'un tag dans l'API d'autocomplétion
+type tagJSON struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+// TagHandler affiche les posts d'un tag: /tag/{name}
+// Un synonyme ou un nom mal écrit redirige vers l'adresse du tag
+func (h *Handler) TagHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/tag/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	tag, err := h.Tags.GetTag(r.Context(), name)
+	if errors.Is(err, models.ErrTagNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error fetching tag", http.StatusInternalServerError)
+		log.Printf("Error fetching tag: %v", err)
+		return
+	}
+
+	if tag.Name != name {
+		target := "/tag/" + url.PathEscape(tag.Name)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	h.listPosts(w, r, tag.Name)
+}
+
+// APITagsHandler propose des tags pour l'autocomplétion: /api/tags?q=prefixe
+func (h *Handler) APITagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 10
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 && n <= 50 {
+			limit = n
+		}
+	}
+
+	tags, err := h.Tags.SearchTags(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, "Error searching tags", http.StatusInternalServerError)
+		log.Printf("Error searching tags: %v", err)
+		return
+	}
+
+	response := []tagJSON{}
+	for _, tag := range tags {
+		response = append(response, tagJSON{Name: tag.Name, PostCount: tag.PostCount})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding tags: %v", err)
+	}
+}
+
+// ListTagsHandler affiche la page de gestion des tags et de leurs synonymes
+func (h *Handler) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	tags, err := h.Tags.GetTags(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des tags", http.StatusInternalServerError)
+		log.Printf("Error fetching tags: %v", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Tags":        tags,
+		"CurrentUser": currentUser,
+		"PageTitle":   "Gestion des tags",
+	}
+
+	tmpl, err := utils.ParseTemplate("templates/base.html", "templates/mod_tags.html")
+	if err != nil {
+		http.Error(w, "Erreur lors du chargement des templates", http.StatusInternalServerError)
+		log.Printf("Error parsing template: %v", err)
+		return
+	}
+
+	data["ActiveAnnouncements"] = h.activeAnnouncements(r)
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		http.Error(w, "Erreur lors du rendu de la page", http.StatusInternalServerError)
+		log.Printf("Error executing template: %v", err)
+	}
+}
+
+// AddTagSynonymHandler ajoute un synonyme à un tag
+// Si le synonyme est déjà un tag, il est fusionné dans le tag choisi
+func (h *Handler) AddTagSynonymHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	err := h.Tags.AddTagSynonym(r.Context(), r.FormValue("synonym"), r.FormValue("tag"), currentUser.ID)
+	if err != nil {
+		respondError(w, err, tagErrors, "adding tag synonym")
+		return
+	}
+
+	http.Redirect(w, r, "/mod/tags", http.StatusSeeOther)
+}
+
+// RemoveTagSynonymHandler supprime un synonyme
+func (h *Handler) RemoveTagSynonymHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentUser := middleware.GetUserFromContext(r)
+	if currentUser == nil || (currentUser.Role != "moderator" && currentUser.Role != "admin") {
+		http.Error(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	err := h.Tags.RemoveTagSynonym(r.Context(), r.FormValue("synonym"))
+	if err != nil {
+		respondError(w, err, tagErrors, "removing tag synonym")
+		return
+	}
+
+	http.Redirect(w, r, "/mod/tags", http.StatusSeeOther)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTagPage(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, author, "Un post sur Go", 1)
+	if err := h.Tags.SetPostTags(context.Background(), postID, []string{"go"}); err != nil {
+		t.Fatalf("setting tags: %v", err)
+	}
+	if err := h.Tags.AddTagSynonym(context.Background(), "golang", "go", moderator.ID); err != nil {
+		t.Fatalf("adding synonym: %v", err)
+	}
+
+	w := serve(h.TagHandler, get("/tag/go"), nil)
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "Un post sur Go") {
+		t.Error("tag page does not list the post")
+	}
+
+	// Un synonyme ou une autre écriture redirige vers l'adresse du tag, avec les paramètres
+	for _, target := range []string{"/tag/golang?sort=top", "/tag/Go?sort=top"} {
+		w = serve(h.TagHandler, get(target), nil)
+		expectStatus(t, w, http.StatusMovedPermanently)
+		if location := w.Header().Get("Location"); location != "/tag/go?sort=top" {
+			t.Errorf("%s redirected to %q, want /tag/go?sort=top", target, location)
+		}
+	}
+
+	w = serve(h.TagHandler, get("/tag/rust"), nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestTagSynonymHandlers(t *testing.T) {
+	h := newTestHandler(t)
+	author := createUser(t, h, "author", "user")
+	moderator := createUser(t, h, "moderator", "moderator")
+	postID := createPost(t, h, author, "Un post sur Go", 1)
+	if err := h.Tags.SetPostTags(context.Background(), postID, []string{"go"}); err != nil {
+		t.Fatalf("setting tags: %v", err)
+	}
+
+	form := url.Values{"synonym": {"golang"}, "tag": {"go"}}
+	w := serve(h.AddTagSynonymHandler, postForm("/mod/tags/synonym", form), author)
+	expectStatus(t, w, http.StatusForbidden)
+
+	w = serve(h.AddTagSynonymHandler, postForm("/mod/tags/synonym", form), moderator)
+	expectRedirect(t, w, "/mod/tags")
+
+	for _, tt := range []struct {
+		synonym, tag string
+		status       int
+		text         string
+	}{
+		{"", "go", http.StatusBadRequest, "obligatoires"},
+		{"golang", "go", http.StatusBadRequest, "existe déjà"},
+		{"go", "go", http.StatusBadRequest, "son propre synonyme"},
+		{"rustlang", "rust", http.StatusNotFound, "Tag introuvable"},
+		{strings.Repeat("x", 31), "go", http.StatusBadRequest, "30 caractères"},
+	} {
+		form := url.Values{"synonym": {tt.synonym}, "tag": {tt.tag}}
+		w := serve(h.AddTagSynonymHandler, postForm("/mod/tags/synonym", form), moderator)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.text) {
+			t.Errorf("synonym %q of %q: got %d %q, want %d with %q", tt.synonym, tt.tag, w.Code, w.Body.String(), tt.status, tt.text)
+		}
+	}
+
+	remove := url.Values{"synonym": {"golang"}}
+	w = serve(h.RemoveTagSynonymHandler, postForm("/mod/tags/synonym/remove", remove), moderator)
+	expectRedirect(t, w, "/mod/tags")
+	w = serve(h.RemoveTagSynonymHandler, postForm("/mod/tags/synonym/remove", remove), moderator)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestCreatePostTagErrors(t *testing.T) {
+	h := newTestHandler(t)
+	member := createUser(t, h, "member", "user")
+
+	for _, tt := range []struct {
+		tags, text string
+	}{
+		{"a, b, c, d, e, f", "plus de 5 tags"},
+		{strings.Repeat("x", 31), "30 caractères"},
+	} {
+		r := multipartForm(t, "/post/create", map[string][]string{
+			"title": {"Titre"}, "content": {"Contenu"}, "categories": {"1"}, "tags": {tt.tags},
+		})
+		w := serve(h.CreatePostHandler, r, member)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.text) {
+			t.Errorf("tags %q: got %d %q, want 400 with %q", tt.tags, w.Code, w.Body.String(), tt.text)
+		}
+	}
+}
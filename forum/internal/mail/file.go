@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer enregistre chaque message dans un fichier .eml au lieu de l'envoyer
+// Il sert en développement et dans les essais: les fichiers s'ouvrent avec n'importe quel client mail
+type FileMailer struct {
+	Dir  string // Dossier des messages, créé au premier envoi
+	From string // Adresse de l'expéditeur
+}
+
+// NewFileMailer crée un FileMailer qui dépose les messages dans dir
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from}
+}
+
+// Send écrit le message dans un nouveau fichier du dossier
+// Le fichier est d'abord écrit sous un nom temporaire, pour qu'un lecteur ne voie jamais un message incomplet
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	data, err := msg.Bytes(m.From, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	// L'horodatage en tête du nom permet de lire les messages dans l'ordre d'envoi
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + uuid.NewString() + ".eml"
+	tmp := filepath.Join(m.Dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(m.Dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package mail envoie les emails du forum à travers un Mailer interchangeable:
+// SMTP en production, dépôt de fichiers .eml pour le développement et les essais
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message est un email avec une version texte et une version HTML du même contenu
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // En-têtes supplémentaires, par exemple List-Unsubscribe
+}
+
+// Mailer envoie des messages
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// validHeader refuse les retours à la ligne, qui permettraient d'injecter d'autres en-têtes
+func validHeader(value string) bool {
+	return !strings.ContainsAny(value, "\r\n")
+}
+
+// Bytes renvoie le message au format MIME, prêt à être envoyé ou enregistré
+// Le texte et le HTML sont les deux parties d'un multipart/alternative
+func (msg *Message) Bytes(from string, date time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	if !validHeader(msg.Subject) {
+		return nil, errors.New("invalid subject")
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := map[string]string{
+		"From":         from,
+		"To":           msg.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   "<" + uuid.NewString() + "@" + sender.Address[strings.LastIndex(sender.Address, "@")+1:] + ">",
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	for name, value := range msg.Headers {
+		if !validHeader(name) || !validHeader(value) {
+			return nil, fmt.Errorf("invalid header %q", name)
+		}
+		headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+	// Les en-têtes sont triés pour que le même message donne toujours le même fichier
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var head bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&head, "%s: %s\r\n", name, headers[name])
+	}
+	head.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if part.body == "" {
+			continue
+		}
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer envoie les messages à un serveur SMTP
+type SMTPMailer struct {
+	Addr string    // Adresse du serveur, par exemple "smtp.example.com:587"
+	From string    // Adresse de l'expéditeur
+	Auth smtp.Auth // nil pour un serveur sans authentification
+}
+
+// NewSMTPMailer crée un SMTPMailer
+// L'authentification PLAIN n'est utilisée que si username n'est pas vide; net/smtp la refuse sans TLS,
+// sauf vers localhost
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	m := &SMTPMailer{Addr: addr, From: from}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send envoie le message. net/smtp ne sait pas s'interrompre: le contexte n'est vérifié qu'avant l'envoi
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+	sender, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, m.Auth, sender.Address, []string{recipient.Address}, data)
+}
@@ -0,0 +1,107 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"html/template"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CacheSize est le nombre de contenus rendus gardés en mémoire
+const CacheSize = 2048
+
+// cache garde le HTML des contenus déjà rendus. La clé est l'empreinte du texte et des
+// mentions qu'il contient: chaque révision d'un post ou d'un commentaire a sa propre entrée,
+// et une modification du texte ne peut pas servir un rendu périmé. Les entrées les moins
+// récemment lues sont retirées
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	html template.HTML
+}
+
+var defaultCache = newCache(CacheSize)
+
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		entries: map[[sha256.Size]byte]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *cache) get(key [sha256.Size]byte) (template.HTML, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).html, true
+}
+
+func (c *cache) put(key [sha256.Size]byte, html template.HTML) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, html: html})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Render renvoie le HTML d'un contenu Markdown, prêt à être inséré dans un template
+// Le second argument, facultatif, associe les noms mentionnés à l'adresse de leur profil
+// Une révision déjà rendue est servie depuis le cache
+func Render(source string, mentions ...map[string]string) template.HTML {
+	var links map[string]string
+	if len(mentions) > 0 {
+		links = mentions[0]
+	}
+
+	key := cacheKey(source, links)
+	if html, ok := defaultCache.get(key); ok {
+		return html
+	}
+	html := template.HTML(ToHTML(source, links))
+	defaultCache.put(key, html)
+	return html
+}
+
+// cacheKey calcule l'empreinte d'un texte et des mentions qui y apparaissent. Une page passe
+// les mentions de tous ses contenus: seules celles du texte comptent pour que la clé d'un
+// commentaire soit la même sur toutes les pages
+func cacheKey(source string, links map[string]string) [sha256.Size]byte {
+	var names []string
+	for name := range links {
+		if strings.Contains(source, "@"+name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	hash.Write([]byte(source))
+	for _, name := range names {
+		hash.Write([]byte("\x00" + name + "\x00" + links[name]))
+	}
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	return key
+}
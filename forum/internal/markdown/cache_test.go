@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	previous := defaultCache
+	defaultCache = newCache(CacheSize)
+	t.Cleanup(func() { defaultCache = previous })
+	links := map[string]string{"alice": "/user/1"}
+
+	first := Render("Bonjour @alice", links)
+	if !strings.Contains(string(first), `href="/user/1"`) {
+		t.Fatalf("first render: %q", first)
+	}
+	if got := Render("Bonjour @alice", links); got != first || defaultCache.order.Len() != 1 {
+		t.Errorf("second render of the same revision: %q, %d entries", got, defaultCache.order.Len())
+	}
+
+	// Une nouvelle révision du texte a sa propre entrée
+	if got := Render("Bonjour @alice, modifié", links); !strings.Contains(string(got), "modifié") {
+		t.Errorf("render of an edited text: %q", got)
+	}
+
+	// Un profil déplacé ou supprimé change le rendu
+	if got := Render("Bonjour @alice", map[string]string{"alice": "/user/2"}); !strings.Contains(string(got), `href="/user/2"`) {
+		t.Errorf("render after the profile moved: %q", got)
+	}
+	if got := Render("Bonjour @alice"); strings.Contains(string(got), "href") {
+		t.Errorf("render without the mention link: %q", got)
+	}
+}
+
+// Les mentions des autres contenus de la page ne changent pas la clé d'un texte
+func TestCacheKeyIgnoresOtherMentions(t *testing.T) {
+	source := "Merci @alice"
+	key := cacheKey(source, map[string]string{"alice": "/user/1"})
+	if other := cacheKey(source, map[string]string{"alice": "/user/1", "bob": "/user/2"}); other != key {
+		t.Error("a mention absent from the text changes the key")
+	}
+	if other := cacheKey(source, map[string]string{"alice": "/user/3"}); other == key {
+		t.Error("a new profile address keeps the key")
+	}
+	if other := cacheKey(source+" !", map[string]string{"alice": "/user/1"}); other == key {
+		t.Error("an edited text keeps the key")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(2)
+	keys := [][sha256.Size]byte{cacheKey("a", nil), cacheKey("b", nil), cacheKey("c", nil)}
+	c.put(keys[0], "a")
+	c.put(keys[1], "b")
+	// Lire "a" en fait l'entrée la plus récente: "b" est retirée à l'arrivée de "c"
+	if _, ok := c.get(keys[0]); !ok {
+		t.Fatal("entry a is missing")
+	}
+	c.put(keys[2], "c")
+
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.get(keys[i]); ok != want {
+			t.Errorf("entry %d cached: %v, want %v", i, ok, want)
+		}
+	}
+	if len(c.entries) != 2 || c.order.Len() != 2 {
+		t.Errorf("cache holds %d entries and %d in order, want 2", len(c.entries), c.order.Len())
+	}
+}
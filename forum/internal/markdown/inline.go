@@ -0,0 +1,627 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Motifs des balises HTML acceptées dans le texte, filtrées ensuite par Sanitize
+// Les balises plus longues que maxTagLength ne sont pas reconnues, pour garder une analyse linéaire
+const (
+	maxTagLength  = 1024
+	maxLinkLength = 4096 // Longueur maximale de la destination et du titre d'un lien
+
+	openTagPattern  = `<[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>`
+	closeTagPattern = `</[a-zA-Z][a-zA-Z0-9-]*\s*>`
+)
+
+var (
+	inlineHTMLRe = regexp.MustCompile(`^(?:` + openTagPattern + `|` + closeTagPattern + `|<!--(?:[^-]|-[^-])*-->)`)
+	autolinkRe   = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailLinkRe  = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	entityRe     = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	tagRe        = regexp.MustCompile(`<[^>]*>`)
+)
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	htmlNode
+	delimNode
+	bracketNode
+	mentionNode
+)
+
+// node est un morceau de texte en cours d'analyse, dans une liste chaînée pour pouvoir
+// entourer une partie de la liste de <em>, <strong> ou d'un lien
+type node struct {
+	kind       nodeKind
+	text       string // Texte brut, HTML pour htmlNode, nom de l'utilisateur pour mentionNode
+	url        string // Profil de l'utilisateur mentionné
+	prev, next *node
+
+	// Suite de * ou de _
+	char              byte
+	count, length     int // Nombre de caractères restants et d'origine
+	canOpen, canClose bool
+}
+
+// bracket est un "[" ou un "![" qui peut commencer un lien ou une image
+type bracket struct {
+	node   *node
+	image  bool
+	active bool
+	delims int // Nombre de délimiteurs à l'ouverture
+	pos    int // Position du texte du lien dans la source
+}
+
+type inlineParser struct {
+	p          *parser
+	src        string
+	pos        int
+	head, tail *node
+	delims     []*node
+	brackets   []*bracket
+	noCloser   map[int]bool // Longueurs de suites de ` qui n'ont plus de fermeture dans la suite du texte
+}
+
+// inline convertit le texte d'un paragraphe ou d'un titre en HTML
+func (p *parser) inline(src string) string {
+	ip := &inlineParser{p: p, src: src, noCloser: map[int]bool{}}
+	ip.parse()
+	ip.processEmphasis(0)
+	return renderNodes(ip.head, nil)
+}
+
+func (ip *inlineParser) append(n *node) *node {
+	n.prev = ip.tail
+	if ip.tail != nil {
+		ip.tail.next = n
+	} else {
+		ip.head = n
+	}
+	ip.tail = n
+	return n
+}
+
+func (ip *inlineParser) appendText(text string) {
+	ip.append(&node{kind: textNode, text: text})
+}
+
+func (ip *inlineParser) appendHTML(text string) {
+	ip.append(&node{kind: htmlNode, text: text})
+}
+
+func (ip *inlineParser) remove(n *node) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		ip.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		ip.tail = n.prev
+	}
+}
+
+// renderNodes rend les nœuds de from jusqu'à to exclu
+func renderNodes(from, to *node) string {
+	var buf strings.Builder
+	for n := from; n != nil && n != to; n = n.next {
+		switch n.kind {
+		case htmlNode:
+			buf.WriteString(n.text)
+		case mentionNode:
+			buf.WriteString(`<a href="` + html.EscapeString(n.url) + `" class="mention">@` + html.EscapeString(n.text) + `</a>`)
+		default:
+			buf.WriteString(html.EscapeString(n.text))
+		}
+	}
+	return buf.String()
+}
+
+func (ip *inlineParser) parse() {
+	src := ip.src
+	for ip.pos < len(src) {
+		c := src[ip.pos]
+		switch c {
+		case '\\':
+			ip.parseBackslash()
+		case '`':
+			ip.parseCodeSpan()
+		case '*', '_':
+			ip.parseDelimiter()
+		case '[':
+			ip.pos++
+			ip.pushBracket("[", false)
+		case '!':
+			if ip.pos+1 < len(src) && src[ip.pos+1] == '[' {
+				ip.pos += 2
+				ip.pushBracket("![", true)
+			} else {
+				ip.pos++
+				ip.appendText("!")
+			}
+		case ']':
+			ip.closeBracket()
+		case '<':
+			ip.parseAngle()
+		case '&':
+			ip.parseEntity()
+		case '\n':
+			ip.parseNewline()
+		case '@':
+			ip.parseMention()
+		default:
+			end := ip.pos + 1
+			for end < len(src) && !strings.ContainsRune("\\`*_[!]<&\n@", rune(src[end])) {
+				end++
+			}
+			ip.appendText(src[ip.pos:end])
+			ip.pos = end
+		}
+	}
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func (ip *inlineParser) parseBackslash() {
+	src := ip.src
+	if ip.pos+1 < len(src) && src[ip.pos+1] == '\n' {
+		ip.appendHTML("<br />\n")
+		ip.pos += 2
+		ip.skipSpaces()
+		return
+	}
+	if ip.pos+1 < len(src) && isASCIIPunct(src[ip.pos+1]) {
+		ip.appendText(src[ip.pos+1 : ip.pos+2])
+		ip.pos += 2
+		return
+	}
+	ip.appendText("\\")
+	ip.pos++
+}
+
+func (ip *inlineParser) parseCodeSpan() {
+	src := ip.src
+	start := ip.pos
+	for ip.pos < len(src) && src[ip.pos] == '`' {
+		ip.pos++
+	}
+	ticks := ip.pos - start
+	if ip.noCloser[ticks] {
+		ip.appendText(src[start:ip.pos])
+		return
+	}
+
+	// Cherche une suite de même longueur pour fermer le code
+	for i := ip.pos; i < len(src); {
+		if src[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for i < len(src) && src[i] == '`' {
+			i++
+		}
+		if i-run == ticks {
+			code := strings.ReplaceAll(src[ip.pos:run], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			ip.appendHTML("<code>" + html.EscapeString(code) + "</code>")
+			ip.pos = i
+			return
+		}
+	}
+	ip.noCloser[ticks] = true
+	ip.appendText(src[start:ip.pos])
+}
+
+// parseDelimiter lit une suite de * ou de _, qui pourra devenir <em> ou <strong>
+func (ip *inlineParser) parseDelimiter() {
+	src := ip.src
+	c := src[ip.pos]
+	start := ip.pos
+	for ip.pos < len(src) && src[ip.pos] == c {
+		ip.pos++
+	}
+
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(src[:start])
+	}
+	if ip.pos < len(src) {
+		after, _ = utf8.DecodeRuneInString(src[ip.pos:])
+	}
+	beforeSpace, afterSpace := unicode.IsSpace(before), unicode.IsSpace(after)
+	beforePunct, afterPunct := isPunct(before), isPunct(after)
+	leftFlanking := !afterSpace && (!afterPunct || beforeSpace || beforePunct)
+	rightFlanking := !beforeSpace && (!beforePunct || afterSpace || afterPunct)
+
+	n := &node{kind: delimNode, text: src[start:ip.pos], char: c, count: ip.pos - start, length: ip.pos - start}
+	if c == '*' {
+		n.canOpen, n.canClose = leftFlanking, rightFlanking
+	} else {
+		n.canOpen = leftFlanking && (!rightFlanking || beforePunct)
+		n.canClose = rightFlanking && (!leftFlanking || afterPunct)
+	}
+	ip.append(n)
+	if n.canOpen || n.canClose {
+		ip.delims = append(ip.delims, n)
+	}
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// processEmphasis associe les délimiteurs ouvrants et fermants au-dessus de bottom
+func (ip *inlineParser) processEmphasis(bottom int) {
+	type openerKey struct {
+		char    byte
+		mod     int
+		canOpen bool
+	}
+	// Pour chaque sorte de délimiteur fermant, le délimiteur en dessous duquel il est inutile
+	// de chercher un ouvrant, pour éviter un temps quadratique
+	openersBottom := map[openerKey]*node{}
+
+	i := bottom
+	for i < len(ip.delims) {
+		closer := ip.delims[i]
+		if !closer.canClose {
+			i++
+			continue
+		}
+
+		key := openerKey{closer.char, closer.length % 3, closer.canOpen}
+		opener := -1
+		for j := i - 1; j >= bottom; j-- {
+			o := ip.delims[j]
+			if o == openersBottom[key] {
+				break
+			}
+			if o.char != closer.char || !o.canOpen {
+				continue
+			}
+			// Règle du multiple de 3 de CommonMark
+			if (o.canClose || closer.canOpen) && (o.length+closer.length)%3 == 0 && !(o.length%3 == 0 && closer.length%3 == 0) {
+				continue
+			}
+			opener = j
+			break
+		}
+
+		if opener < 0 {
+			if i > bottom {
+				openersBottom[key] = ip.delims[i-1]
+			}
+			if !closer.canOpen {
+				ip.delims = append(ip.delims[:i], ip.delims[i+1:]...)
+			} else {
+				i++
+			}
+			continue
+		}
+
+		o := ip.delims[opener]
+		used, tag := 1, "em"
+		if o.count >= 2 && closer.count >= 2 {
+			used, tag = 2, "strong"
+		}
+		o.count -= used
+		closer.count -= used
+		o.text = o.text[:o.count]
+		closer.text = closer.text[:closer.count]
+
+		wrapped := &node{kind: htmlNode, text: "<" + tag + ">" + renderNodes(o.next, closer) + "</" + tag + ">"}
+		o.next, wrapped.prev = wrapped, o
+		wrapped.next, closer.prev = closer, wrapped
+
+		// Les délimiteurs entre les deux ne peuvent plus rien fermer
+		ip.delims = append(ip.delims[:opener+1], ip.delims[i:]...)
+		i = opener + 1
+		if o.count == 0 {
+			ip.remove(o)
+			ip.delims = append(ip.delims[:opener], ip.delims[opener+1:]...)
+			i--
+		}
+		if closer.count == 0 {
+			ip.remove(closer)
+			ip.delims = append(ip.delims[:i], ip.delims[i+1:]...)
+		}
+	}
+	ip.delims = ip.delims[:bottom]
+}
+
+func (ip *inlineParser) pushBracket(text string, image bool) {
+	n := ip.append(&node{kind: bracketNode, text: text})
+	ip.brackets = append(ip.brackets, &bracket{node: n, image: image, active: true, delims: len(ip.delims), pos: ip.pos})
+}
+
+// closeBracket traite un "]": si un crochet ouvrant lui correspond et qu'il est suivi d'une
+// destination, le texte entre les deux devient un lien ou une image
+func (ip *inlineParser) closeBracket() {
+	labelEnd := ip.pos
+	ip.pos++
+	if len(ip.brackets) == 0 {
+		ip.appendText("]")
+		return
+	}
+	last := len(ip.brackets) - 1
+	b := ip.brackets[last]
+	if !b.active {
+		ip.brackets = ip.brackets[:last]
+		ip.appendText("]")
+		return
+	}
+
+	dest, title, end, ok := ip.linkTarget(ip.src[b.pos:labelEnd])
+	if !ok {
+		ip.brackets = ip.brackets[:last]
+		ip.appendText("]")
+		return
+	}
+	ip.pos = end
+	ip.brackets = ip.brackets[:last]
+
+	ip.processEmphasis(b.delims)
+	// Pas de lien dans un lien: les mentions du texte redeviennent du texte
+	for n := b.node.next; n != nil; n = n.next {
+		if n.kind == mentionNode {
+			n.kind = textNode
+			n.text = "@" + n.text
+		}
+	}
+	content := renderNodes(b.node.next, nil)
+
+	// Le crochet et le texte du lien sont remplacés par le lien
+	ip.tail = b.node.prev
+	if ip.tail != nil {
+		ip.tail.next = nil
+	} else {
+		ip.head = nil
+	}
+
+	attrs := ""
+	if title != "" {
+		attrs = ` title="` + html.EscapeString(title) + `"`
+	}
+	if b.image {
+		alt := tagRe.ReplaceAllString(content, "")
+		ip.appendHTML(`<img src="` + html.EscapeString(dest) + `" alt="` + alt + `"` + attrs + ` />`)
+		return
+	}
+	ip.appendHTML(`<a href="` + html.EscapeString(dest) + `"` + attrs + `>` + content + `</a>`)
+
+	// Pas de lien dans un lien
+	for _, other := range ip.brackets {
+		if !other.image {
+			other.active = false
+		}
+	}
+}
+
+// linkTarget lit ce qui suit le texte d'un lien: une destination entre parenthèses,
+// ou une référence [label], [] ou rien du tout pour une définition portant le nom du texte
+func (ip *inlineParser) linkTarget(text string) (dest, title string, end int, ok bool) {
+	src := ip.src
+	if ip.pos < len(src) && src[ip.pos] == '(' {
+		if dest, title, end, ok := ip.inlineLink(ip.pos); ok {
+			return dest, title, end, true
+		}
+	}
+
+	label, end := text, ip.pos
+	if ip.pos < len(src) && src[ip.pos] == '[' {
+		if close := strings.IndexByte(src[ip.pos+1:], ']'); close >= 0 && close <= 999 {
+			inner := src[ip.pos+1 : ip.pos+1+close]
+			if !strings.Contains(inner, "[") {
+				if strings.TrimSpace(inner) != "" {
+					label = inner
+				}
+				end = ip.pos + close + 2
+			}
+		}
+	}
+	if ref, ok := ip.p.refs[normalizeLabel(label)]; ok {
+		return ref.dest, ref.title, end, true
+	}
+	if end != ip.pos {
+		// [texte][label] inconnu: le texte seul peut encore être une référence
+		if ref, ok := ip.p.refs[normalizeLabel(text)]; ok {
+			return ref.dest, ref.title, ip.pos, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// inlineLink lit (destination "titre") à partir de la parenthèse ouvrante
+func (ip *inlineParser) inlineLink(pos int) (dest, title string, end int, ok bool) {
+	src := ip.src[:min(len(ip.src), pos+maxLinkLength)]
+	i := skipLinkSpace(src, pos+1)
+
+	if i < len(src) && src[i] == '<' {
+		j := i + 1
+		for j < len(src) && src[j] != '>' && src[j] != '<' && src[j] != '\n' {
+			if src[j] == '\\' && j+1 < len(src) {
+				j++
+			}
+			j++
+		}
+		if j >= len(src) || src[j] != '>' {
+			return "", "", 0, false
+		}
+		dest = src[i+1 : j]
+		i = j + 1
+	} else {
+		j, depth := i, 0
+		for j < len(src) && src[j] > ' ' {
+			if src[j] == '\\' && j+1 < len(src) && isASCIIPunct(src[j+1]) {
+				j += 2
+				continue
+			}
+			if src[j] == '(' {
+				depth++
+			} else if src[j] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+			j++
+		}
+		if depth != 0 {
+			return "", "", 0, false
+		}
+		dest = src[i:j]
+		i = j
+	}
+
+	j := skipLinkSpace(src, i)
+	if j > i && j < len(src) && strings.IndexByte(`"'(`, src[j]) >= 0 {
+		closing := src[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		k := j + 1
+		for k < len(src) && src[k] != closing {
+			if src[k] == '\\' && k+1 < len(src) {
+				k++
+			}
+			k++
+		}
+		if k >= len(src) {
+			return "", "", 0, false
+		}
+		title = src[j+1 : k]
+		j = skipLinkSpace(src, k+1)
+	}
+	if j >= len(src) || src[j] != ')' {
+		return "", "", 0, false
+	}
+	return unescapeText(dest), unescapeText(title), j + 1, true
+}
+
+// skipLinkSpace saute les espaces et au plus un retour à la ligne
+func skipLinkSpace(src string, i int) int {
+	newline := false
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t' || (src[i] == '\n' && !newline)) {
+		if src[i] == '\n' {
+			newline = true
+		}
+		i++
+	}
+	return i
+}
+
+// unescapeText applique les échappements par \ et les entités HTML d'une destination ou d'un titre
+func unescapeText(text string) string {
+	if !strings.ContainsAny(text, `\&`) {
+		return text
+	}
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]) {
+			i++
+		}
+		buf.WriteByte(text[i])
+	}
+	return html.UnescapeString(buf.String())
+}
+
+// parseAngle lit un lien automatique <https://...>, une adresse email ou une balise HTML
+func (ip *inlineParser) parseAngle() {
+	rest := ip.src[ip.pos:min(len(ip.src), ip.pos+maxTagLength)]
+	if m := autolinkRe.FindStringSubmatch(rest); m != nil {
+		ip.appendHTML(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + `</a>`)
+		ip.pos += len(m[0])
+		return
+	}
+	if m := emailLinkRe.FindStringSubmatch(rest); m != nil {
+		ip.appendHTML(`<a href="mailto:` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + `</a>`)
+		ip.pos += len(m[0])
+		return
+	}
+	if m := inlineHTMLRe.FindString(rest); m != "" {
+		ip.appendHTML(m)
+		ip.pos += len(m)
+		return
+	}
+	ip.appendText("<")
+	ip.pos++
+}
+
+// parseMention lit une mention @nom. Le @ doit suivre un caractère qui n'est pas une lettre,
+// pour ne pas prendre une adresse email pour une mention
+func (ip *inlineParser) parseMention() {
+	name := ""
+	if ip.pos == 0 || !isNameByte(ip.src[ip.pos-1]) {
+		end := ip.pos + 1
+		for end < len(ip.src) && end-ip.pos <= MaxMentionLength && (isNameByte(ip.src[end]) || ip.src[end] == '.' || ip.src[end] == '-') {
+			end++
+		}
+		// Un point ou un tiret final termine la phrase, il ne fait pas partie du nom
+		name = strings.TrimRight(ip.src[ip.pos+1:end], ".-")
+	}
+	if name == "" || len(name) > MaxMentionLength {
+		ip.appendText("@")
+		ip.pos++
+		return
+	}
+
+	ip.p.mentioned = append(ip.p.mentioned, name)
+	if url, ok := ip.p.mentions[name]; ok {
+		ip.append(&node{kind: mentionNode, text: name, url: url})
+	} else {
+		ip.appendText("@" + name)
+	}
+	ip.pos += 1 + len(name)
+}
+
+func isNameByte(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '_'
+}
+
+func (ip *inlineParser) parseEntity() {
+	m := entityRe.FindString(ip.src[ip.pos:])
+	if m == "" {
+		ip.appendText("&")
+		ip.pos++
+		return
+	}
+	// Une entité inconnue reste affichée telle quelle
+	ip.appendText(html.UnescapeString(m))
+	ip.pos += len(m)
+}
+
+// parseNewline traite un retour à la ligne: deux espaces avant lui forcent un saut de ligne
+func (ip *inlineParser) parseNewline() {
+	hard := false
+	if ip.tail != nil && ip.tail.kind == textNode {
+		trimmed := strings.TrimRight(ip.tail.text, " ")
+		hard = len(ip.tail.text)-len(trimmed) >= 2
+		ip.tail.text = trimmed
+	}
+	if hard {
+		ip.appendHTML("<br />\n")
+	} else {
+		ip.appendText("\n")
+	}
+	ip.pos++
+	ip.skipSpaces()
+}
+
+func (ip *inlineParser) skipSpaces() {
+	for ip.pos < len(ip.src) && ip.src[ip.pos] == ' ' {
+		ip.pos++
+	}
+}
@@ -0,0 +1,46 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// linkAttrRe trouve les adresses des liens et des images dans le HTML produit par ToHTML
+var linkAttrRe = regexp.MustCompile(` (?:href|src)="([^"]*)"`)
+
+// HasExternalLinks indique si un texte Markdown contient un lien ou une image vers un autre site
+// Le texte est rendu comme il sera affiché: les adresses écrites dans du code ou refusées par
+// Sanitize ne comptent pas, pas plus que les liens relatifs vers les pages du forum
+func HasExternalLinks(source string) bool {
+	for _, m := range linkAttrRe.FindAllStringSubmatch(ToHTML(source, nil), -1) {
+		if isExternalURL(html.UnescapeString(m[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExternalURL indique si une adresse acceptée par safeURL mène hors du forum: elle a un schéma,
+// ou commence par "//" (les navigateurs lisent aussi "\" comme "/")
+func isExternalURL(value string) bool {
+	url := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		if r == '\\' {
+			return '/'
+		}
+		return r
+	}, value)
+
+	if strings.HasPrefix(url, "//") {
+		return true
+	}
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 {
+		return false
+	}
+	slash := strings.IndexAny(url, "/?#")
+	return slash < 0 || slash > colon
+}
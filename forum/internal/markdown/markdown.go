@@ -0,0 +1,568 @@
+// Package markdown convertit le Markdown des posts et des commentaires en HTML.
+// Il implémente la syntaxe CommonMark courante (titres, paragraphes, listes, citations,
+// blocs de code indentés ou délimités, liens, images, emphase, code, HTML brut),
+// sans dépendance externe. Le HTML produit passe toujours par Sanitize
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	quoteBlock
+	listBlock
+	itemBlock
+	ruleBlock
+	htmlBlock
+)
+
+// block est un bloc du document: les paragraphes et les titres gardent leur texte brut,
+// analysé au moment du rendu, une fois toutes les définitions de liens connues
+type block struct {
+	kind     blockKind
+	text     string
+	level    int    // Niveau d'un titre
+	info     string // Langage d'un bloc de code
+	ordered  bool
+	start    int
+	tight    bool // Liste sans ligne vide entre ses éléments: les paragraphes ne sont pas entourés de <p>
+	children []*block
+}
+
+// reference est une définition de lien: [label]: destination "titre"
+type reference struct {
+	dest, title string
+}
+
+// maxNesting limite la profondeur des citations et des listes imbriquées: au-delà,
+// les marqueurs sont lus comme du texte
+const maxNesting = 32
+
+type parser struct {
+	refs      map[string]reference
+	depth     int
+	mentions  map[string]string // Nom mentionné -> adresse du profil
+	mentioned []string          // Noms rencontrés dans le texte, hors code
+}
+
+// ToHTML convertit un texte Markdown en HTML nettoyé
+// Les mentions @nom présentes dans mentions deviennent des liens vers l'adresse associée,
+// les autres restent du texte
+func ToHTML(source string, mentions map[string]string) string {
+	p := &parser{refs: map[string]reference{}, mentions: mentions}
+	blocks, _ := p.parseBlocks(splitLines(source))
+
+	var buf strings.Builder
+	p.renderBlocks(&buf, blocks, false)
+	return Sanitize(buf.String())
+}
+
+// splitLines découpe le texte en lignes et remplace les tabulations de l'indentation par des espaces
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.ReplaceAll(source, "\x00", "�")
+	source = strings.TrimRight(source, "\n")
+	if source == "" {
+		return nil
+	}
+
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandIndent(line)
+	}
+	return lines
+}
+
+func expandIndent(line string) string {
+	col, i := 0, 0
+	for ; i < len(line); i++ {
+		if line[i] == ' ' {
+			col++
+		} else if line[i] == '\t' {
+			col += 4 - col%4
+		} else {
+			break
+		}
+	}
+	if !strings.Contains(line[:i], "\t") {
+		return line
+	}
+	return strings.Repeat(" ", col) + line[i:]
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// stripIndent retire au plus n espaces en début de ligne
+func stripIndent(line string, n int) string {
+	return line[min(n, indentOf(line)):]
+}
+
+var (
+	atxHeadingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*))?$`)
+	setextRe     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	ruleRe       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRe      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	quoteRe      = regexp.MustCompile(`^ {0,3}> ?`)
+	referenceRe  = regexp.MustCompile(`^ {0,3}\[((?:[^\\\[\]]|\\.){1,999})\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*$`)
+
+	htmlCommentStartRe = regexp.MustCompile(`^ {0,3}<!--`)
+	htmlRawStartRe     = regexp.MustCompile(`^ {0,3}<(?i:(pre|script|style|textarea))(?:\s|>|$)`)
+	htmlTagStartRe     = regexp.MustCompile(`^ {0,3}</?([a-zA-Z][a-zA-Z0-9-]*)(?:\s|/?>|$)`)
+	htmlLoneTagRe      = regexp.MustCompile(`^ {0,3}(?:` + openTagPattern + `|` + closeTagPattern + `)[ \t]*$`)
+)
+
+// htmlBlockTags sont les balises qui commencent un bloc HTML, même au milieu d'un paragraphe
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"dialog": true, "dd": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "iframe": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "section": true,
+	"summary": true, "table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "tr": true, "ul": true,
+}
+
+// htmlBlockStart indique si la ligne commence un bloc HTML et renvoie ce qui le termine:
+// une chaîne à trouver, ou "" pour un bloc qui s'arrête à la première ligne vide
+func htmlBlockStart(line string, interrupting bool) (string, bool) {
+	if htmlCommentStartRe.MatchString(line) {
+		return "-->", true
+	}
+	if m := htmlRawStartRe.FindStringSubmatch(line); m != nil {
+		return "</" + strings.ToLower(m[1]) + ">", true
+	}
+	if m := htmlTagStartRe.FindStringSubmatch(line); m != nil && htmlBlockTags[strings.ToLower(m[1])] {
+		return "", true
+	}
+	// Une balise quelconque seule sur sa ligne commence un bloc, sauf au milieu d'un paragraphe
+	if !interrupting && htmlLoneTagRe.MatchString(line) {
+		return "", true
+	}
+	return "", false
+}
+
+// listMarker est le marqueur d'un élément de liste: "-", "+", "*", "1." ou "1)"
+type listMarker struct {
+	ordered bool
+	char    byte // Puce, ou délimiteur d'une liste numérotée
+	start   int
+	width   int // Indentation du contenu de l'élément
+	content string
+	empty   bool
+}
+
+func parseListMarker(line string) (listMarker, bool) {
+	n := indentOf(line)
+	if n > 3 || n >= len(line) {
+		return listMarker{}, false
+	}
+
+	m := listMarker{}
+	pos := n
+	switch line[pos] {
+	case '-', '+', '*':
+		m.char = line[pos]
+		pos++
+	default:
+		digits := pos
+		for digits < len(line) && digits-pos < 10 && line[digits] >= '0' && line[digits] <= '9' {
+			digits++
+		}
+		if digits == pos || digits-pos > 9 || digits >= len(line) || (line[digits] != '.' && line[digits] != ')') {
+			return listMarker{}, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(line[pos:digits])
+		m.char = line[digits]
+		pos = digits + 1
+	}
+
+	rest := line[pos:]
+	if isBlank(rest) {
+		m.empty = true
+		m.width = pos + 1
+		return m, true
+	}
+	if rest[0] != ' ' {
+		return listMarker{}, false
+	}
+	spaces := indentOf(rest)
+	if spaces >= 5 {
+		// Le contenu est un bloc de code indenté: un seul espace fait partie du marqueur
+		m.width = pos + 1
+	} else {
+		m.width = pos + spaces
+	}
+	m.content = line[m.width:]
+	return m, true
+}
+
+// interrupts indique si la ligne commence un bloc qui interrompt un paragraphe
+func (p *parser) interrupts(line string) bool {
+	if indentOf(line) >= 4 {
+		return false
+	}
+	if atxHeadingRe.MatchString(line) || ruleRe.MatchString(line) || quoteRe.MatchString(line) {
+		return true
+	}
+	if _, ok := fenceStart(line); ok {
+		return true
+	}
+	if _, ok := htmlBlockStart(line, true); ok {
+		return true
+	}
+	if m, ok := parseListMarker(line); ok && !m.empty && (!m.ordered || m.start == 1) {
+		return true
+	}
+	return false
+}
+
+// fence est l'ouverture d'un bloc de code délimité
+type fence struct {
+	indent int
+	marker string
+	info   string
+}
+
+func fenceStart(line string) (fence, bool) {
+	m := fenceRe.FindStringSubmatch(line)
+	if m == nil {
+		return fence{}, false
+	}
+	info := strings.TrimSpace(m[3])
+	if m[2][0] == '`' && strings.Contains(info, "`") {
+		return fence{}, false
+	}
+	return fence{indent: len(m[1]), marker: m[2], info: info}, true
+}
+
+// parseBlocks analyse une suite de lignes. Le booléen indique si une ligne vide sépare
+// deux des blocs, ce qui rend lâche la liste qui les contient
+func (p *parser) parseBlocks(lines []string) ([]*block, bool) {
+	var blocks []*block
+	loose, blank := false, false
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			blank = true
+			i++
+			continue
+		}
+		if blank && len(blocks) > 0 {
+			loose = true
+		}
+		blank = false
+
+		var b *block
+		n := 1
+		if indentOf(line) >= 4 {
+			b, n = parseIndentedCode(lines[i:])
+		} else if f, ok := fenceStart(line); ok {
+			b, n = parseFencedCode(lines[i:], f)
+		} else if m := atxHeadingRe.FindStringSubmatch(line); m != nil {
+			b = &block{kind: headingBlock, level: len(m[1]), text: trimHeading(m[2])}
+		} else if ruleRe.MatchString(line) {
+			b = &block{kind: ruleBlock}
+		} else if quoteRe.MatchString(line) && p.depth < maxNesting {
+			b, n = p.parseQuote(lines[i:])
+		} else if marker, ok := parseListMarker(line); ok && p.depth < maxNesting {
+			b, n = p.parseList(lines[i:], marker)
+		} else if end, ok := htmlBlockStart(line, false); ok {
+			b, n = parseHTMLBlock(lines[i:], end)
+		} else {
+			b, n = p.parseParagraph(lines[i:])
+		}
+
+		if b != nil {
+			blocks = append(blocks, b)
+		}
+		i += n
+	}
+	return blocks, loose
+}
+
+// trimHeading retire les espaces et la suite de # qui peut fermer un titre
+func trimHeading(text string) string {
+	text = strings.TrimRight(text, " \t")
+	trimmed := strings.TrimRight(text, "#")
+	if trimmed == "" || strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t") {
+		text = trimmed
+	}
+	return strings.TrimSpace(text)
+}
+
+func parseIndentedCode(lines []string) (*block, int) {
+	var code []string
+	n := 0
+	for ; n < len(lines); n++ {
+		if !isBlank(lines[n]) && indentOf(lines[n]) < 4 {
+			break
+		}
+		code = append(code, stripIndent(lines[n], 4))
+	}
+	// Les lignes vides finales ne font pas partie du bloc
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+		n--
+	}
+	return &block{kind: codeBlock, text: strings.Join(code, "\n") + "\n"}, n
+}
+
+func parseFencedCode(lines []string, f fence) (*block, int) {
+	closing := regexp.MustCompile(`^ {0,3}` + regexp.QuoteMeta(f.marker[:1]) + `{` + strconv.Itoa(len(f.marker)) + `,}[ \t]*$`)
+
+	var code []string
+	n := 1
+	for ; n < len(lines); n++ {
+		if closing.MatchString(lines[n]) {
+			n++
+			break
+		}
+		code = append(code, stripIndent(lines[n], f.indent))
+	}
+
+	b := &block{kind: codeBlock}
+	if len(code) > 0 {
+		b.text = strings.Join(code, "\n") + "\n"
+	}
+	if fields := strings.Fields(f.info); len(fields) > 0 {
+		b.info = unescapeText(fields[0])
+	}
+	return b, n
+}
+
+func (p *parser) parseQuote(lines []string) (*block, int) {
+	var inner []string
+	n := 0
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		if loc := quoteRe.FindStringIndex(line); loc != nil {
+			inner = append(inner, expandIndent(line[loc[1]:]))
+			continue
+		}
+		// Continuation paresseuse: une ligne sans ">" prolonge le paragraphe cité
+		if !isBlank(line) && !isBlank(inner[len(inner)-1]) && !p.interrupts(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+	p.depth++
+	children, _ := p.parseBlocks(inner)
+	p.depth--
+	return &block{kind: quoteBlock, children: children}, n
+}
+
+func (p *parser) parseList(lines []string, first listMarker) (*block, int) {
+	list := &block{kind: listBlock, ordered: first.ordered, start: first.start, tight: true}
+
+	i := 0
+	for i < len(lines) {
+		marker, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.char != first.char || ruleRe.MatchString(lines[i]) {
+			break
+		}
+
+		item := []string{marker.content}
+		j := i + 1
+		for ; j < len(lines); j++ {
+			line := lines[j]
+			if isBlank(line) {
+				// Un élément peut commencer par une seule ligne vide
+				if marker.empty && j == i+1 && j+1 < len(lines) && isBlank(lines[j+1]) {
+					break
+				}
+				item = append(item, "")
+				continue
+			}
+			if indentOf(line) >= marker.width {
+				item = append(item, line[marker.width:])
+				continue
+			}
+			// Continuation paresseuse d'un paragraphe de l'élément, sauf pour un nouvel élément
+			if _, isItem := parseListMarker(line); isItem {
+				break
+			}
+			if !isBlank(lines[j-1]) && !(marker.empty && j == i+1) && !p.interrupts(line) && !setextRe.MatchString(line) {
+				item = append(item, line)
+				continue
+			}
+			break
+		}
+
+		// Les lignes vides finales séparent les éléments, elles n'appartiennent à aucun
+		end := j
+		for end > i+1 && isBlank(lines[end-1]) {
+			end--
+		}
+		p.depth++
+		children, loose := p.parseBlocks(item[:end-i])
+		p.depth--
+		if loose {
+			list.tight = false
+		}
+		list.children = append(list.children, &block{kind: itemBlock, children: children})
+
+		next, ok := listMarker{}, false
+		if j < len(lines) {
+			next, ok = parseListMarker(lines[j])
+		}
+		if !ok || next.ordered != first.ordered || next.char != first.char || ruleRe.MatchString(lines[j]) {
+			return list, end
+		}
+		if end < j {
+			list.tight = false
+		}
+		i = j
+	}
+	return list, i
+}
+
+func parseHTMLBlock(lines []string, end string) (*block, int) {
+	n := 0
+	for ; n < len(lines); n++ {
+		if end == "" {
+			if isBlank(lines[n]) {
+				break
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(lines[n]), end) {
+			n++
+			break
+		}
+	}
+	return &block{kind: htmlBlock, text: strings.Join(lines[:n], "\n") + "\n"}, n
+}
+
+func (p *parser) parseParagraph(lines []string) (*block, int) {
+	text := []string{strings.TrimLeft(lines[0], " ")}
+	n := 1
+	level := 0
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		if isBlank(line) {
+			break
+		}
+		if m := setextRe.FindStringSubmatch(line); m != nil {
+			level = 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			break
+		}
+		if p.interrupts(line) {
+			break
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+
+	// Les définitions de liens en tête du paragraphe ne sont pas affichées
+	text = p.extractReferences(text)
+	if len(text) == 0 {
+		return nil, n
+	}
+
+	content := strings.TrimRight(strings.Join(text, "\n"), " \t")
+	if level > 0 {
+		return &block{kind: headingBlock, level: level, text: content}, n + 1
+	}
+	return &block{kind: paragraphBlock, text: content}, n
+}
+
+func (p *parser) extractReferences(lines []string) []string {
+	for len(lines) > 0 {
+		m := referenceRe.FindStringSubmatch(lines[0])
+		if m == nil {
+			break
+		}
+		label := normalizeLabel(m[1])
+		if label == "" {
+			break
+		}
+		dest := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+		title := ""
+		if len(m[3]) >= 2 {
+			title = m[3][1 : len(m[3])-1]
+		}
+		// La première définition d'un label l'emporte
+		if _, exists := p.refs[label]; !exists {
+			p.refs[label] = reference{dest: unescapeText(dest), title: unescapeText(title)}
+		}
+		lines = lines[1:]
+	}
+	return lines
+}
+
+// normalizeLabel permet de comparer les labels de liens sans tenir compte de la casse ni des espaces
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func (p *parser) renderBlocks(buf *strings.Builder, blocks []*block, tight bool) {
+	for i, b := range blocks {
+		switch b.kind {
+		case paragraphBlock:
+			if tight {
+				buf.WriteString(p.inline(b.text))
+				if i < len(blocks)-1 {
+					buf.WriteString("\n")
+				}
+			} else {
+				buf.WriteString("<p>" + p.inline(b.text) + "</p>\n")
+			}
+		case headingBlock:
+			tag := "h" + strconv.Itoa(b.level)
+			buf.WriteString("<" + tag + ">" + p.inline(b.text) + "</" + tag + ">\n")
+		case codeBlock:
+			buf.WriteString("<pre><code")
+			if b.info != "" {
+				buf.WriteString(` class="language-` + html.EscapeString(b.info) + `"`)
+			}
+			buf.WriteString(">" + html.EscapeString(b.text) + "</code></pre>\n")
+		case quoteBlock:
+			buf.WriteString("<blockquote>\n")
+			p.renderBlocks(buf, b.children, false)
+			buf.WriteString("</blockquote>\n")
+		case listBlock:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			buf.WriteString("<" + tag)
+			if b.ordered && b.start != 1 {
+				buf.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+			}
+			buf.WriteString(">\n")
+			for _, item := range b.children {
+				buf.WriteString("<li>")
+				if len(item.children) > 0 && (!b.tight || item.children[0].kind != paragraphBlock) {
+					buf.WriteString("\n")
+				}
+				p.renderBlocks(buf, item.children, b.tight)
+				buf.WriteString("</li>\n")
+			}
+			buf.WriteString("</" + tag + ">\n")
+		case ruleBlock:
+			buf.WriteString("<hr />\n")
+		case htmlBlock:
+			buf.WriteString(b.text)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	for _, tt := range []struct {
+		name, source, want string
+	}{
+		{"paragraphs", "Un\ndeux\n\ntrois", "<p>Un\ndeux</p>\n<p>trois</p>\n"},
+		{"headings", "# Titre #\nSous-titre\n---", "<h1>Titre</h1>\n<h2>Sous-titre</h2>\n"},
+		{"emphasis", "*a* **b** _c_ un_mot_", "<p><em>a</em> <strong>b</strong> <em>c</em> un_mot_</p>\n"},
+		{"tight list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"ordered list", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"quote", "> cité\nparesseux", "<blockquote>\n<p>cité\nparesseux</p>\n</blockquote>\n"},
+		{"code span", "`<b>` et `` ` ``", "<p><code>&lt;b&gt;</code> et <code>`</code></p>\n"},
+		{"link", `[lien](/post/1 "Titre")`, `<p><a href="/post/1" title="Titre" rel="nofollow noopener">lien</a></p>` + "\n"},
+		{"reference link", "[lien][ref]\n\n[REF]: https://example.com", `<p><a href="https://example.com" rel="nofollow noopener">lien</a></p>` + "\n"},
+		{"autolink", "<https://example.com>", `<p><a href="https://example.com" rel="nofollow noopener">https://example.com</a></p>` + "\n"},
+		{"image", "![alt *texte*](/a.png)", `<p><img src="/a.png" alt="alt texte"></p>` + "\n"},
+		{"javascript link", "[lien](javascript:alert(1))", `<p><a rel="nofollow noopener">lien</a></p>` + "\n"},
+		{"javascript autolink", "<javascript:alert(1)>", `<p><a rel="nofollow noopener">javascript:alert(1)</a></p>` + "\n"},
+		{"entity-encoded javascript link", "[lien](&#106;avascript:alert(1))", `<p><a rel="nofollow noopener">lien</a></p>` + "\n"},
+		{"data image", "![x](data:image/png;base64,AAAA)", `<p><img alt="x"></p>` + "\n"},
+		{"inline html", `a <span onclick="x()">b</span>`, "<p>a <span>b</span></p>\n"},
+		{"html block", "<div>\n<script>alert(1)</script>\n</div>", "<div>\n\n</div>\n"},
+		{"raw script block", "<script>\nalert(1)\n</script>\napres", "\n<p>apres</p>\n"},
+		{"unclosed html", "<details><summary>Plus", "<details><summary>Plus\n</summary></details>"},
+		{"escaped html", `\<script>`, "<p>&lt;script&gt;</p>\n"},
+	} {
+		if got := ToHTML(tt.source, nil); got != tt.want {
+			t.Errorf("%s: ToHTML(%q) =\n%q\nwant\n%q", tt.name, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestFencedCode(t *testing.T) {
+	for _, tt := range []struct {
+		name, source, want string
+	}{
+		{"no info string", "```\ncode\n```", "<pre><code>code\n</code></pre>\n"},
+		{"language", "```go\nfmt.Println(\"<ok>\")\n```", "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;ok&gt;&#34;)\n</code></pre>\n"},
+		{"only the first word", "``` python extra words\nx\n```", "<pre><code class=\"language-python\">x\n</code></pre>\n"},
+		{"tildes", "~~~c++\nx\n~~~", "<pre><code class=\"language-c++\">x\n</code></pre>\n"},
+		{"escaped info string", "```c\\#\nx\n```", "<pre><code class=\"language-c#\">x\n</code></pre>\n"},
+		{"quote in info string", "```go\"onclick=\"alert(1)\nx\n```", "<pre><code>x\n</code></pre>\n"},
+		{"html in info string", "```<script>\nx\n```", "<pre><code>x\n</code></pre>\n"},
+		{"backtick in info string", "```a`b\nx\n```", "<p>```a`b\nx</p>\n<pre><code></code></pre>\n"},
+		{"unclosed fence", "```go\nx\n\ny", "<pre><code class=\"language-go\">x\n\ny\n</code></pre>\n"},
+		{"longer closing fence", "```\nx\n`````\napres", "<pre><code>x\n</code></pre>\n<p>apres</p>\n"},
+		{"markdown inside", "```\n*a* <b>\n```", "<pre><code>*a* &lt;b&gt;\n</code></pre>\n"},
+	} {
+		if got := ToHTML(tt.source, nil); got != tt.want {
+			t.Errorf("%s: ToHTML(%q) =\n%q\nwant\n%q", tt.name, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMentionLinks(t *testing.T) {
+	links := map[string]string{"alice": "/user/1", "bob_2": "/user/2"}
+	for _, tt := range []struct {
+		name, source, want string
+	}{
+		{"known user", "Merci @alice !", `<p>Merci <a href="/user/1" class="mention" rel="nofollow noopener">@alice</a> !</p>` + "\n"},
+		{"unknown user", "Merci @carol", "<p>Merci @carol</p>\n"},
+		{"name with digits", "@bob_2, @alice.", `<p><a href="/user/2" class="mention" rel="nofollow noopener">@bob_2</a>, <a href="/user/1" class="mention" rel="nofollow noopener">@alice</a>.</p>` + "\n"},
+		{"email address", "alice@alice.fr", "<p>alice@alice.fr</p>\n"},
+		{"code span", "`@alice`", "<p><code>@alice</code></p>\n"},
+		{"code block", "    @alice", "<pre><code>@alice\n</code></pre>\n"},
+		{"inside a link", "[voir @alice](/post/1)", `<p><a href="/post/1" rel="nofollow noopener">voir @alice</a></p>` + "\n"},
+		{"emphasis", "**@alice**", `<p><strong><a href="/user/1" class="mention" rel="nofollow noopener">@alice</a></strong></p>` + "\n"},
+	} {
+		if got := ToHTML(tt.source, links); got != tt.want {
+			t.Errorf("%s: ToHTML(%q) =\n%q\nwant\n%q", tt.name, tt.source, got, tt.want)
+		}
+	}
+
+	// Une adresse de profil dangereuse est retirée par Sanitize
+	if got := ToHTML("@eve", map[string]string{"eve": "javascript:alert(1)"}); strings.Contains(got, "javascript") {
+		t.Errorf("mention link to a javascript address: %q", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		want   []string
+	}{
+		{"@alice et @bob, puis @alice", []string{"alice", "bob"}},
+		{"`@alice` et\n\n    @bob\n\n@carol", []string{"carol"}},
+		{"alice@example.com <https://example.com/@bob>", nil},
+		{"> @alice\n- @bob", []string{"alice", "bob"}},
+		{"@- et @.", nil},
+	} {
+		if got := Mentions(tt.source); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Mentions(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestHasExternalLinks(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		want   bool
+	}{
+		{"[lien](/post/1)", false},
+		{"[lien](https://example.com)", true},
+		{"<https://example.com>", true},
+		{"![image](//example.com/a.png)", true},
+		{`[lien](\\\\example.com)`, true},
+		{"`https://example.com`", false},
+		{"[lien](javascript:alert(1))", false},
+		{"texte sans lien", false},
+	} {
+		if got := HasExternalLinks(tt.source); got != tt.want {
+			t.Errorf("HasExternalLinks(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
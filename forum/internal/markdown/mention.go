@@ -0,0 +1,27 @@
+package markdown
+
+import "strings"
+
+// MaxMentionLength est la longueur maximale d'un nom mentionné avec @
+const MaxMentionLength = 64
+
+// Mentions renvoie les noms mentionnés avec @ dans un texte Markdown, sans doublon et dans
+// l'ordre du texte. Les mentions écrites dans du code ou dans un lien automatique sont ignorées
+func Mentions(source string) []string {
+	p := &parser{refs: map[string]reference{}}
+	blocks, _ := p.parseBlocks(splitLines(source))
+
+	// Le rendu analyse le texte des paragraphes et des titres, où se trouvent les mentions
+	var buf strings.Builder
+	p.renderBlocks(&buf, blocks, false)
+
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range p.mentioned {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
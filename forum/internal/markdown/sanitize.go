@@ -0,0 +1,303 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// allowedTags liste les balises conservées par Sanitize et, pour chacune, ses attributs autorisés
+var allowedTags = map[string][]string{
+	"a": {"href", "title", "class"}, "img": {"src", "alt", "title", "width", "height"},
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil, "pre": nil, "code": {"class"},
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"em": nil, "strong": nil, "b": nil, "i": nil, "u": nil, "s": nil, "del": nil, "ins": nil,
+	"sub": nil, "sup": nil, "kbd": nil, "mark": nil, "small": nil, "abbr": {"title"},
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"align"}, "td": {"align"},
+	"details": nil, "summary": nil, "div": nil, "span": nil,
+}
+
+// voidTags n'ont pas de balise fermante
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// droppedTags sont supprimées avec tout leur contenu
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "textarea": true,
+	"title": true, "noscript": true, "template": true, "xmp": true, "noembed": true,
+	"noframes": true, "select": true, "svg": true, "math": true, "head": true,
+}
+
+var (
+	languageClassRe = regexp.MustCompile(`^language-[a-zA-Z0-9_+#.-]+$`)
+	numberRe        = regexp.MustCompile(`^[0-9]{1,9}$`)
+	alignRe         = regexp.MustCompile(`^(?:left|center|right)$`)
+)
+
+type attribute struct {
+	name, value string
+}
+
+type tag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       []attribute
+}
+
+// Sanitize ne garde du HTML que les balises et les attributs autorisés. Les liens et les
+// images ne peuvent pointer que vers des adresses http(s), mailto ou relatives, et les liens
+// reçoivent rel="nofollow noopener". Les balises non fermées sont fermées à la fin
+func Sanitize(input string) string {
+	var out strings.Builder
+	var open []string
+
+	for i := 0; i < len(input); {
+		lt := strings.IndexByte(input[i:], '<')
+		if lt < 0 {
+			writeText(&out, input[i:])
+			break
+		}
+		writeText(&out, input[i:i+lt])
+		i += lt
+		rest := input[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return closeAll(&out, open)
+			}
+			i += 4 + end + 3
+			continue
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return closeAll(&out, open)
+			}
+			i += end + 1
+			continue
+		}
+
+		t, n, ok := parseTag(rest)
+		if !ok {
+			out.WriteString("&lt;")
+			i++
+			continue
+		}
+		i += n
+
+		if droppedTags[t.name] {
+			if !t.closing && !t.selfClosing {
+				i += skipElement(input[i:], t.name)
+			}
+			continue
+		}
+		attrs, allowed := allowedTags[t.name]
+		if !allowed {
+			continue
+		}
+
+		if t.closing {
+			// Ferme les balises ouvertes après celle-ci, puis la balise elle-même
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == t.name {
+					for k := len(open) - 1; k >= j; k-- {
+						out.WriteString("</" + open[k] + ">")
+					}
+					open = open[:j]
+					break
+				}
+			}
+			continue
+		}
+
+		out.WriteString("<" + t.name)
+		for _, attr := range t.attrs {
+			if value, ok := allowedAttribute(t.name, attr, attrs); ok {
+				out.WriteString(" " + attr.name + `="` + html.EscapeString(value) + `"`)
+			}
+		}
+		if t.name == "a" {
+			out.WriteString(` rel="nofollow noopener"`)
+		}
+		out.WriteString(">")
+		if !voidTags[t.name] {
+			open = append(open, t.name)
+		}
+	}
+	return closeAll(&out, open)
+}
+
+func closeAll(out *strings.Builder, open []string) string {
+	for j := len(open) - 1; j >= 0; j-- {
+		out.WriteString("</" + open[j] + ">")
+	}
+	return out.String()
+}
+
+// writeText écrit du texte en échappant les caractères spéciaux qui ne le sont pas encore
+func writeText(out *strings.Builder, text string) {
+	out.WriteString(html.EscapeString(html.UnescapeString(text)))
+}
+
+// allowedAttribute vérifie un attribut et renvoie sa valeur à écrire
+func allowedAttribute(tagName string, attr attribute, allowed []string) (string, bool) {
+	found := false
+	for _, name := range allowed {
+		if name == attr.name {
+			found = true
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	switch attr.name {
+	case "href":
+		return attr.value, safeURL(attr.value, false)
+	case "src":
+		return attr.value, safeURL(attr.value, true)
+	case "class":
+		if tagName == "a" {
+			return attr.value, attr.value == "mention"
+		}
+		return attr.value, languageClassRe.MatchString(attr.value)
+	case "start", "width", "height":
+		return attr.value, numberRe.MatchString(attr.value)
+	case "align":
+		return attr.value, alignRe.MatchString(attr.value)
+	}
+	return attr.value, true
+}
+
+// safeURL accepte les adresses relatives et les schémas http, https et mailto (pas mailto pour une image)
+func safeURL(value string, image bool) bool {
+	// Les navigateurs ignorent les espaces et les caractères de contrôle dans un schéma
+	url := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	if url == "" {
+		return false
+	}
+
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 {
+		return true
+	}
+	if slash := strings.IndexAny(url, "/?#"); slash >= 0 && slash < colon {
+		return true
+	}
+	switch strings.ToLower(url[:colon]) {
+	case "http", "https":
+		return true
+	case "mailto":
+		return !image
+	}
+	return false
+}
+
+// parseTag lit une balise ouvrante ou fermante au début de s et renvoie sa longueur
+func parseTag(s string) (tag, int, bool) {
+	s = s[:min(len(s), maxTagLength)]
+	t := tag{}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isAlpha(s[i]) || (i > start && (isDigit(s[i]) || s[i] == '-'))) {
+		i++
+	}
+	if i == start {
+		return t, 0, false
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return t, 0, false
+		}
+		if s[i] == '>' {
+			return t, i + 1, true
+		}
+		if s[i] == '/' {
+			if i+1 < len(s) && s[i+1] == '>' {
+				t.selfClosing = true
+				return t, i + 2, true
+			}
+			i++
+			continue
+		}
+
+		nameStart := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := attribute{name: strings.ToLower(s[nameStart:i])}
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return t, 0, false
+				}
+				attr.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				valueStart := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = s[valueStart:i]
+			}
+			attr.value = html.UnescapeString(attr.value)
+		}
+		if attr.name != "" && !t.closing {
+			t.attrs = append(t.attrs, attr)
+		}
+	}
+}
+
+// skipElement renvoie la longueur du contenu d'une balise supprimée, balise fermante comprise
+func skipElement(s, name string) int {
+	for i := 0; ; {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return len(s)
+		}
+		i += j
+		if len(s)-i >= 2+len(name) && strings.EqualFold(s[i+2:i+2+len(name)], name) {
+			if end := strings.IndexByte(s[i:], '>'); end >= 0 {
+				return i + end + 1
+			}
+			return len(s)
+		}
+		i += 2
+	}
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for _, tt := range []struct {
+		name, input, want string
+	}{
+		{"allowed tags", `<p>Un <strong>texte</strong> <em>sûr</em></p>`, `<p>Un <strong>texte</strong> <em>sûr</em></p>`},
+		{"relative link", `<a href="/post/1">lien</a>`, `<a href="/post/1" rel="nofollow noopener">lien</a>`},
+		{"https link", `<a href="https://example.com/?a=1&amp;b=2">lien</a>`, `<a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener">lien</a>`},
+		{"mailto link", `<a href="mailto:a@example.com">écrire</a>`, `<a href="mailto:a@example.com" rel="nofollow noopener">écrire</a>`},
+		{"javascript link", `<a href="javascript:alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"javascript in capitals", `<a href="JaVaScRiPt:alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"javascript with spaces", `<a href=" java	script:alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"entity-encoded scheme", `<a href="&#106;avascript&#58;alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"hex entity-encoded scheme", `<a href="&#x6A;avascript&colon;alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"encoded tab in scheme", `<a href="java&#9;script:alert(1)">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"data link", `<a href="data:text/html;base64,PHNjcmlwdD4=">lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"vbscript link", `<a href=vbscript:msgbox>lien</a>`, `<a rel="nofollow noopener">lien</a>`},
+		{"data image", `<img src="data:image/svg+xml;base64,PHN2Zz4=" alt="x">`, `<img alt="x">`},
+		{"mailto image", `<img src="mailto:a@example.com">`, `<img>`},
+		{"colon after a slash", `<a href="/search?q=a:b">lien</a>`, `<a href="/search?q=a:b" rel="nofollow noopener">lien</a>`},
+		{"event handler", `<p onclick="alert(1)">texte</p>`, `<p>texte</p>`},
+		{"event handler in capitals", `<img src="/a.png" ONERROR="alert(1)">`, `<img src="/a.png">`},
+		{"event handler without quotes", `<a href="/" onmouseover=alert(1)>lien</a>`, `<a href="/" rel="nofollow noopener">lien</a>`},
+		{"style attribute", `<span style="position:fixed">texte</span>`, `<span>texte</span>`},
+		{"mention class", `<a href="/user/1" class="mention">@bob</a>`, `<a href="/user/1" class="mention" rel="nofollow noopener">@bob</a>`},
+		{"other link class", `<a href="/" class="button">lien</a>`, `<a href="/" rel="nofollow noopener">lien</a>`},
+		{"language class", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"invalid class", `<code class="language-go x">x</code>`, `<code>x</code>`},
+		{"numeric attributes", `<ol start="3"><li>a</li></ol><img width="10%">`, `<ol start="3"><li>a</li></ol><img>`},
+		{"script", `avant<script>alert("<b>")</script>après`, `avantaprès`},
+		{"script in capitals", `avant<SCRIPT>alert(1)</ScRiPt>après`, `avantaprès`},
+		{"unclosed script", `avant<script>alert(1)`, `avant`},
+		{"svg", `<svg onload="alert(1)"><circle r="1"/></svg>fin`, `fin`},
+		{"svg with script", `<svg><script>alert(1)</script></svg>fin`, `fin`},
+		{"style", `<style>body{display:none}</style>texte`, `texte`},
+		{"iframe", `<iframe src="https://example.com"></iframe>texte`, `texte`},
+		{"unknown tag", `<form action="/x"><input name="a">texte</form>`, `texte`},
+		{"unclosed tags", `<p><strong>gras`, `<p><strong>gras</strong></p>`},
+		{"closing an outer tag", `<p><em>a</p>b`, `<p><em>a</em></p>b`},
+		{"stray closing tag", `a</div>b`, `ab`},
+		{"unfinished tag", `a <a href="/x`, `a &lt;a href=&#34;/x`},
+		{"lone angle bracket", `1 < 2 && 3 > 2`, `1 &lt; 2 &amp;&amp; 3 &gt; 2`},
+		{"comment", `a<!-- <script>alert(1)</script> -->b`, `ab`},
+		{"unclosed comment", `<p>a<!-- b`, `<p>a</p>`},
+		{"doctype", `<!DOCTYPE html>a`, `a`},
+		{"escaped entities stay escaped", `&lt;script&gt;`, `&lt;script&gt;`},
+	} {
+		if got := Sanitize(tt.input); got != tt.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+// Aucune adresse dangereuse ne survit, quelle que soit la façon de l'écrire
+func TestSanitizeURLSchemes(t *testing.T) {
+	for _, value := range []string{
+		"javascript:alert(1)",
+		"JAVASCRIPT:alert(1)",
+		"\x01javascript:alert(1)",
+		"java\nscript:alert(1)",
+		"&#x6a;&#x61;&#x76;&#x61;&#x73;&#x63;&#x72;&#x69;&#x70;&#x74;&#x3a;alert(1)",
+		"&#0000106avascript:alert(1)",
+		"data:text/html,<script>alert(1)</script>",
+		"DaTa:text/html,x",
+		"vbscript:x",
+		"file:///etc/passwd",
+	} {
+		for _, input := range []string{
+			`<a href="` + value + `">x</a>`,
+			`<img src="` + value + `">`,
+		} {
+			got := Sanitize(input)
+			if strings.Contains(got, "href=") || strings.Contains(got, "src=") {
+				t.Errorf("Sanitize(%q) = %q, the address is kept", input, got)
+			}
+		}
+	}
+}
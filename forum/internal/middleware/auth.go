@@ -1,7 +1,9 @@
 package middleware
 import (
 	"context"
+	"errors"
 	"forum/internal/models"
+	"forum/internal/store"
 	"net/http"
 )
 
@@ -12,7 +14,8 @@ const UserContextKey contextKey = "user"
 
 // AuthMiddleware vérifie si un utilisateur est authentifié
 // C'est comme un portier qui vérifie discrètement votre identité sans vous bloquer le passage
-func AuthMiddleware(next http.Handler) http.Handler {
+// Les sessions et les utilisateurs sont lus à travers les stockages fournis
+func AuthMiddleware(next http.Handler, sessions store.SessionStore, users store.UserStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// On essaie de récupérer le cookie de session du navigateur
 		// C'est comme vérifier si le visiteur porte un bracelet d'identification
@@ -26,7 +29,12 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		
 		// On vérifie si la session est valide dans notre base de données
 		// C'est comme scanner le bracelet pour voir s'il est authentique et toujours valable
-		session, err := models.GetSessionByUUID(cookie.Value)
+		session, err := sessions.GetSessionByUUID(r.Context(), cookie.Value)
+		if interrupted(err) {
+			// La lecture a été interrompue: la session n'est pas forcément invalide, on garde le cookie
+			http.Error(w, "Service temporairement indisponible", http.StatusServiceUnavailable)
+			return
+		}
 		if err != nil {
 			// Si la session est invalide ou expirée, on supprime le cookie du navigateur
 			// C'est comme retirer un bracelet périmé ou contrefait
@@ -45,11 +53,15 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		
 		// On récupère les informations de l'utilisateur associé à cette session
 		// C'est comme vérifier dans notre registre à qui appartient ce bracelet valide
-		user, err := models.GetUserByID(session.UserID)
+		user, err := users.GetUserByID(r.Context(), session.UserID)
+		if interrupted(err) {
+			http.Error(w, "Service temporairement indisponible", http.StatusServiceUnavailable)
+			return
+		}
 		if err != nil {
 			// Si l'utilisateur n'est pas trouvé, on supprime la session et le cookie
 			// C'est comme si le bracelet était valide mais que la personne n'existe plus dans notre système
-			models.DeleteSession(cookie.Value)
+			sessions.DeleteSession(r.Context(), cookie.Value)
 			http.SetCookie(w, &http.Cookie{
 				Name:     "session",
 				Value:    "",
@@ -71,6 +83,12 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// interrupted indique si une lecture a échoué parce que le client s'est déconnecté ou que le délai
+// de la requête est dépassé, et non parce que la session ou l'utilisateur n'existe pas
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // RequireAuthMiddleware redirige vers la page de connexion si l'utilisateur n'est pas authentifié
 // C'est comme un gardien qui vérifie si vous avez le droit d'entrer dans une zone réservée
 func RequireAuthMiddleware(next http.Handler) http.Handler {
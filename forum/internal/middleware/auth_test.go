@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"forum/internal/models"
+	"forum/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// failingSessions est un stockage de sessions dont la lecture échoue toujours avec err
+type failingSessions struct {
+	store.SessionStore
+	err     error
+	deleted bool
+}
+
+func (s *failingSessions) GetSessionByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	return nil, s.err
+}
+
+func (s *failingSessions) DeleteSession(ctx context.Context, uuid string) error {
+	s.deleted = true
+	return nil
+}
+
+// Une lecture interrompue ne déconnecte pas l'utilisateur; une session inconnue, si
+func TestAuthMiddlewareKeepsCookieWhenInterrupted(t *testing.T) {
+	tests := []struct {
+		err          error
+		status       int
+		clearsCookie bool
+	}{
+		{context.Canceled, http.StatusServiceUnavailable, false},
+		{context.DeadlineExceeded, http.StatusServiceUnavailable, false},
+		{errors.New("session not found"), http.StatusOK, true},
+	}
+	for _, test := range tests {
+		sessions := &failingSessions{err: test.err}
+		handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetUserFromContext(r) != nil {
+				t.Errorf("%v: request has a user", test.err)
+			}
+		}), sessions, nil)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "uuid"})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%v: got status %d, want %d", test.err, w.Code, test.status)
+		}
+		cleared := w.Header().Get("Set-Cookie") != ""
+		if cleared != test.clearsCookie {
+			t.Errorf("%v: cookie cleared %v, want %v", test.err, cleared, test.clearsCookie)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type RateLimiter struct {
 	mu          sync.Mutex
 	maxRequests int           // Nombre maximum de requêtes autorisées
 	interval    time.Duration // Fenêtre de temps pour le comptage des requêtes
+	cleanup     sync.Once     // Démarre une seule fois le nettoyage des visiteurs expirés
 }
 
 // NewRateLimiter crée un nouveau RateLimiter
@@ -33,7 +34,7 @@ func NewRateLimiter(maxRequests int, interval time.Duration) *RateLimiter {
 // Middleware retourne un middleware HTTP qui limite le débit
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	// Lancer une goroutine pour nettoyer les visiteurs expirés
-	go rl.cleanupVisitors()
+	rl.cleanup.Do(func() { go rl.cleanupVisitors() })
 
 	// Retourner le middleware
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -56,6 +57,14 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// Allow compte une action identifiée par une clé quelconque, par exemple l'ID d'un utilisateur,
+// et indique si elle reste dans la limite. Cela permet de limiter une action précise
+// (l'envoi d'un message privé) avec les mêmes règles que les requêtes d'une adresse IP
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.cleanup.Do(func() { go rl.cleanupVisitors() })
+	return !rl.isRateLimited(key)
+}
+
 // isRateLimited vérifie si un client a dépassé la limite de requêtes
 func (rl *RateLimiter) isRateLimited(ip string) bool {
 	rl.mu.Lock()
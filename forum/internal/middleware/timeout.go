@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware donne à chaque requête un délai maximal de traitement
+// Le contexte de la requête expire après ce délai: les requêtes SQL en cours sont interrompues
+// et les suivantes échouent immédiatement. Un délai nul désactive la limite.
+// Les adresses de exempt, comme le flux /events qui reste ouvert, ne sont pas limitées.
+func TimeoutMiddleware(next http.Handler, timeout time.Duration, exempt ...string) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range exempt {
+			if r.URL.Path == path {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		// Signaler les requêtes qui ont dépassé leur délai
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("Request exceeded its %v deadline: %s %s", timeout, r.Method, r.URL.Path)
+		}
+	})
+}
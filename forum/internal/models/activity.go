@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"forum/internal/database"
 	"log"
@@ -15,13 +16,58 @@ type UserActivity struct {
 	PostID    int       // ID du post associé
 	PostTitle string    // Titre du post associé
 	CreatedAt time.Time // Date de création de l'activité
-	Reaction  string    // Type de réaction (like/dislike) si applicable
+	Reaction  string    // Identifiant de la réaction si applicable
+	ReactionEmoji string // Emoji de la réaction (non stocké dans les tables de réactions)
+	ReactionLabel string // Libellé de la réaction (non stocké dans les tables de réactions)
+}
+
+// ActivityStats résume l'activité d'un utilisateur, sur laquelle portent les règles des badges
+type ActivityStats struct {
+	Posts           int       // Posts publiés
+	Comments        int       // Commentaires écrits
+	ReactionsGiven  int       // Réactions posées sur les contenus des autres
+	Upvotes         int       // Réactions positives reçues d'autres utilisateurs
+	AcceptedAnswers int       // Commentaires acceptés comme réponse au post d'un autre utilisateur
+	BestPostLikes   int       // Plus grand nombre de réactions positives sur un de ses posts (posts.like_count)
+	MemberSince     time.Time // Date d'inscription
+}
+
+// GetActivityStats compte l'activité d'un utilisateur en une seule requête
+func GetActivityStats(ctx context.Context, userID int) (*ActivityStats, error) {
+	stats := &ActivityStats{}
+	err := database.ReadDB.QueryRowContext(ctx, `
+		SELECT u.created_at,
+			(SELECT COUNT(*) FROM posts WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM comments WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM post_reactions x JOIN posts p ON p.id = x.post_id
+				WHERE x.user_id = u.id AND p.user_id != u.id)
+			+ (SELECT COUNT(*) FROM comment_reactions x JOIN comments c ON c.id = x.comment_id
+				WHERE x.user_id = u.id AND c.user_id != u.id),
+			(SELECT COUNT(*) FROM post_reactions x JOIN posts p ON p.id = x.post_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE p.user_id = u.id AND x.user_id != u.id AND r.score > 0)
+			+ (SELECT COUNT(*) FROM comment_reactions x JOIN comments c ON c.id = x.comment_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE c.user_id = u.id AND x.user_id != u.id AND r.score > 0),
+			(SELECT COUNT(*) FROM posts p JOIN comments c ON c.id = p.accepted_comment_id
+				WHERE c.user_id = u.id AND p.user_id != u.id),
+			(SELECT COALESCE(MAX(like_count), 0) FROM posts WHERE user_id = u.id)
+		FROM users u
+		WHERE u.id = ?`, userID).Scan(
+		&stats.MemberSince, &stats.Posts, &stats.Comments, &stats.ReactionsGiven,
+		&stats.Upvotes, &stats.AcceptedAnswers, &stats.BestPostLikes,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
 }
 
 // GetUserPosts récupère tous les posts créés par un utilisateur spécifique
-func GetUserPosts(userID int) ([]*Post, error) {
-	rows, err := database.DB.Query(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at
+func GetUserPosts(ctx context.Context, userID int) ([]*Post, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+postColumns+`
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.user_id = ?
@@ -31,71 +77,17 @@ func GetUserPosts(userID int) ([]*Post, error) {
 		log.Printf("Error querying user posts: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var posts []*Post
-	for rows.Next() {
-		post := &Post{}
-		err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt)
-		if err != nil {
-			log.Printf("Error scanning post row: %v", err)
-			return nil, err
-		}
-
-		// Récupérer les catégories pour ce post
-		categoryRows, err := database.DB.Query(`
-			SELECT c.id, c.name, c.description
-			FROM categories c
-			JOIN post_categories pc ON c.id = pc.category_id
-			WHERE pc.post_id = ?
-		`, post.ID)
-		if err != nil {
-			log.Printf("Error querying post categories: %v", err)
-			return nil, err
-		}
-
-		post.Categories = []Category{}
-		for categoryRows.Next() {
-			var category Category
-			err = categoryRows.Scan(&category.ID, &category.Name, &category.Description)
-			if err != nil {
-				categoryRows.Close()
-				log.Printf("Error scanning category row: %v", err)
-				return nil, err
-			}
-			post.Categories = append(post.Categories, category)
-		}
-		categoryRows.Close()
-
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'dislike')
-		`, post.ID, post.ID).Scan(&post.Likes, &post.Dislikes)
-		if err != nil {
-			log.Printf("Error counting post reactions: %v", err)
-			return nil, err
-		}
-
-		// Vérifier si l'utilisateur a réagi au post
-		var reactionType string
-		err = database.DB.QueryRow(`
-			SELECT reaction_type FROM post_reactions
-			WHERE user_id = ? AND post_id = ?
-		`, userID, post.ID).Scan(&reactionType)
-		if err == nil {
-			post.UserReaction = reactionType
-		} else if err != sql.ErrNoRows {
-			log.Printf("Error checking user reaction: %v", err)
-			return nil, err
-		}
 
-		posts = append(posts, post)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		log.Printf("Error scanning post rows: %v", err)
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating through post rows: %v", err)
+	// Récupérer les catégories et les réactions de tous les posts en une seule fois
+	err = loadPostDetails(ctx, posts, userID)
+	if err != nil {
+		log.Printf("Error loading post details: %v", err)
 		return nil, err
 	}
 
@@ -103,9 +95,9 @@ func GetUserPosts(userID int) ([]*Post, error) {
 }
 
 // GetUserComments récupère tous les commentaires créés par un utilisateur spécifique
-func GetUserComments(userID int) ([]*Comment, error) {
-	rows, err := database.DB.Query(`
-		SELECT c.id, c.content, c.user_id, u.username, c.post_id, p.title, c.created_at, c.updated_at
+func GetUserComments(ctx context.Context, userID int) ([]*Comment, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+commentColumns+`
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		JOIN posts p ON c.post_id = p.id
@@ -116,47 +108,17 @@ func GetUserComments(userID int) ([]*Comment, error) {
 		log.Printf("Error querying user comments: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var comments []*Comment
-	for rows.Next() {
-		comment := &Comment{}
-		var postTitle string
-		err = rows.Scan(&comment.ID, &comment.Content, &comment.UserID, &comment.Username, &comment.PostID, &postTitle, &comment.CreatedAt, &comment.UpdatedAt)
-		if err != nil {
-			log.Printf("Error scanning comment row: %v", err)
-			return nil, err
-		}
-
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM comment_reactions WHERE comment_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM comment_reactions WHERE comment_id = ? AND reaction_type = 'dislike')
-		`, comment.ID, comment.ID).Scan(&comment.Likes, &comment.Dislikes)
-		if err != nil {
-			log.Printf("Error counting comment reactions: %v", err)
-			return nil, err
-		}
 
-		// Vérifier si l'utilisateur a réagi au commentaire
-		var reactionType string
-		err = database.DB.QueryRow(`
-			SELECT reaction_type FROM comment_reactions
-			WHERE user_id = ? AND comment_id = ?
-		`, userID, comment.ID).Scan(&reactionType)
-		if err == nil {
-			comment.UserReaction = reactionType
-		} else if err != sql.ErrNoRows {
-			log.Printf("Error checking user reaction to comment: %v", err)
-			return nil, err
-		}
-
-		comments = append(comments, comment)
+	comments, err := scanComments(rows)
+	if err != nil {
+		log.Printf("Error scanning comment rows: %v", err)
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating through comment rows: %v", err)
+	// Récupérer les réactions de tous les commentaires en une seule fois
+	err = loadCommentDetails(ctx, comments, userID)
+	if err != nil {
+		log.Printf("Error loading comment details: %v", err)
 		return nil, err
 	}
 
@@ -164,147 +126,40 @@ func GetUserComments(userID int) ([]*Comment, error) {
 }
 
 // GetUserLikedPosts récupère tous les posts aimés par un utilisateur spécifique
-func GetUserLikedPosts(userID int) ([]*Post, error) {
-	rows, err := database.DB.Query(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at, pr.reaction_type
-		FROM posts p
-		JOIN users u ON p.user_id = u.id
-		JOIN post_reactions pr ON p.id = pr.post_id
-		WHERE pr.user_id = ? AND pr.reaction_type = 'like'
-		ORDER BY pr.created_at DESC
-	`, userID)
-	if err != nil {
-		log.Printf("Error querying user liked posts: %v", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var posts []*Post
-	for rows.Next() {
-		post := &Post{}
-		var reactionType string
-		err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt, &reactionType)
-		if err != nil {
-			log.Printf("Error scanning liked post row: %v", err)
-			return nil, err
-		}
-		post.UserReaction = reactionType
-
-		// Récupérer les catégories pour ce post
-		categoryRows, err := database.DB.Query(`
-			SELECT c.id, c.name, c.description
-			FROM categories c
-			JOIN post_categories pc ON c.id = pc.category_id
-			WHERE pc.post_id = ?
-		`, post.ID)
-		if err != nil {
-			log.Printf("Error querying liked post categories: %v", err)
-			return nil, err
-		}
-
-		post.Categories = []Category{}
-		for categoryRows.Next() {
-			var category Category
-			err = categoryRows.Scan(&category.ID, &category.Name, &category.Description)
-			if err != nil {
-				categoryRows.Close()
-				log.Printf("Error scanning category row for liked post: %v", err)
-				return nil, err
-			}
-			post.Categories = append(post.Categories, category)
-		}
-		categoryRows.Close()
-
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'dislike')
-		`, post.ID, post.ID).Scan(&post.Likes, &post.Dislikes)
-		if err != nil {
-			log.Printf("Error counting reactions for liked post: %v", err)
-			return nil, err
-		}
-
-		posts = append(posts, post)
-	}
-
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating through liked post rows: %v", err)
-		return nil, err
-	}
-
-	return posts, nil
+func GetUserLikedPosts(ctx context.Context, userID int) ([]*Post, error) {
+	return getUserReactedPosts(ctx, userID, "like")
 }
 
 // GetUserDislikedPosts récupère tous les posts non aimés par un utilisateur spécifique
-func GetUserDislikedPosts(userID int) ([]*Post, error) {
-	rows, err := database.DB.Query(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at, pr.reaction_type
+func GetUserDislikedPosts(ctx context.Context, userID int) ([]*Post, error) {
+	return getUserReactedPosts(ctx, userID, "dislike")
+}
+
+// getUserReactedPosts récupère les posts auxquels un utilisateur a réagi avec le type donné
+func getUserReactedPosts(ctx context.Context, userID int, reactionType string) ([]*Post, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+postColumns+`
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN post_reactions pr ON p.id = pr.post_id
-		WHERE pr.user_id = ? AND pr.reaction_type = 'dislike'
+		WHERE pr.user_id = ? AND pr.reaction_type = ?
 		ORDER BY pr.created_at DESC
-	`, userID)
+	`, userID, reactionType)
 	if err != nil {
-		log.Printf("Error querying user disliked posts: %v", err)
+		log.Printf("Error querying user %s posts: %v", reactionType, err)
 		return nil, err
 	}
-	defer rows.Close()
 
-	var posts []*Post
-	for rows.Next() {
-		post := &Post{}
-		var reactionType string
-		err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt, &reactionType)
-		if err != nil {
-			log.Printf("Error scanning disliked post row: %v", err)
-			return nil, err
-		}
-		post.UserReaction = reactionType
-
-		// Récupérer les catégories pour ce post
-		categoryRows, err := database.DB.Query(`
-			SELECT c.id, c.name, c.description
-			FROM categories c
-			JOIN post_categories pc ON c.id = pc.category_id
-			WHERE pc.post_id = ?
-		`, post.ID)
-		if err != nil {
-			log.Printf("Error querying disliked post categories: %v", err)
-			return nil, err
-		}
-
-		post.Categories = []Category{}
-		for categoryRows.Next() {
-			var category Category
-			err = categoryRows.Scan(&category.ID, &category.Name, &category.Description)
-			if err != nil {
-				categoryRows.Close()
-				log.Printf("Error scanning category row for disliked post: %v", err)
-				return nil, err
-			}
-			post.Categories = append(post.Categories, category)
-		}
-		categoryRows.Close()
-
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'dislike')
-		`, post.ID, post.ID).Scan(&post.Likes, &post.Dislikes)
-		if err != nil {
-			log.Printf("Error counting reactions for disliked post: %v", err)
-			return nil, err
-		}
-
-		posts = append(posts, post)
+	posts, err := scanPosts(rows)
+	if err != nil {
+		log.Printf("Error scanning %s post rows: %v", reactionType, err)
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating through disliked post rows: %v", err)
+	// Récupérer les catégories et les réactions de tous les posts en une seule fois
+	err = loadPostDetails(ctx, posts, userID)
+	if err != nil {
+		log.Printf("Error loading %s post details: %v", reactionType, err)
 		return nil, err
 	}
 
@@ -312,33 +167,35 @@ func GetUserDislikedPosts(userID int) ([]*Post, error) {
 }
 
 // GetUserActivity récupère un flux d'activités récentes d'un utilisateur
-func GetUserActivity(userID int, limit int) ([]*UserActivity, error) {
+func GetUserActivity(ctx context.Context, userID int, limit int) ([]*UserActivity, error) {
 	// Requête pour obtenir les activités d'un utilisateur avec des alias explicites
 	// et en utilisant des jointures appropriées
 	query := `
-		SELECT 'post' as activity_type, p.id as item_id, p.title as content, p.id as post_id, p.title as post_title, p.created_at as activity_date, NULL as reaction_type
+		SELECT 'post' as activity_type, p.id as item_id, p.title as content, p.id as post_id, p.title as post_title, p.created_at as activity_date, NULL as reaction_type, NULL as reaction_emoji, NULL as reaction_label
 		FROM posts p
 		WHERE p.user_id = ?
 		
 		UNION ALL
 		
-		SELECT 'comment' as activity_type, c.id as item_id, c.content as content, c.post_id as post_id, p.title as post_title, c.created_at as activity_date, NULL as reaction_type
+		SELECT 'comment' as activity_type, c.id as item_id, c.content as content, c.post_id as post_id, p.title as post_title, c.created_at as activity_date, NULL as reaction_type, NULL as reaction_emoji, NULL as reaction_label
 		FROM comments c
 		JOIN posts p ON c.post_id = p.id
 		WHERE c.user_id = ?
 		
 		UNION ALL
 		
-		SELECT 'post_reaction' as activity_type, pr.post_id as item_id, NULL as content, pr.post_id as post_id, p.title as post_title, pr.created_at as activity_date, pr.reaction_type as reaction_type
+		SELECT 'post_reaction' as activity_type, pr.post_id as item_id, NULL as content, pr.post_id as post_id, p.title as post_title, pr.created_at as activity_date, pr.reaction_type as reaction_type, r.emoji as reaction_emoji, r.label as reaction_label
 		FROM post_reactions pr
 		JOIN posts p ON pr.post_id = p.id
+		JOIN reactions r ON r.name = pr.reaction_type
 		WHERE pr.user_id = ?
 		
 		UNION ALL
 		
-		SELECT 'comment_reaction' as activity_type, cr.comment_id as item_id, c.content as content, c.post_id as post_id, p.title as post_title, cr.created_at as activity_date, cr.reaction_type as reaction_type
+		SELECT 'comment_reaction' as activity_type, cr.comment_id as item_id, c.content as content, c.post_id as post_id, p.title as post_title, cr.created_at as activity_date, cr.reaction_type as reaction_type, r.emoji as reaction_emoji, r.label as reaction_label
 		FROM comment_reactions cr
 		JOIN comments c ON cr.comment_id = c.id
+		JOIN reactions r ON r.name = cr.reaction_type
 		JOIN posts p ON c.post_id = p.id
 		WHERE cr.user_id = ?
 		
@@ -347,7 +204,7 @@ func GetUserActivity(userID int, limit int) ([]*UserActivity, error) {
 	`
 
 	// Exécution de la requête avec tous les paramètres
-	rows, err := database.DB.Query(query, userID, userID, userID, userID, limit)
+	rows, err := database.ReadDB.QueryContext(ctx, query, userID, userID, userID, userID, limit)
 	if err != nil {
 		log.Printf("Error executing activity query: %v", err)
 		return nil, err
@@ -360,7 +217,7 @@ func GetUserActivity(userID int, limit int) ([]*UserActivity, error) {
 		
 		// Utilisation de NullString pour les colonnes qui peuvent être NULL
 		var content sql.NullString
-		var reaction sql.NullString
+		var reaction, emoji, label sql.NullString
 		
 		// Scan des valeurs en gérant correctement les NULL
 		err = rows.Scan(
@@ -371,6 +228,8 @@ func GetUserActivity(userID int, limit int) ([]*UserActivity, error) {
 			&activity.PostTitle,
 			&activity.CreatedAt,
 			&reaction,
+			&emoji,
+			&label,
 		)
 		
 		if err != nil {
@@ -385,6 +244,8 @@ func GetUserActivity(userID int, limit int) ([]*UserActivity, error) {
 		
 		if reaction.Valid {
 			activity.Reaction = reaction.String
+			activity.ReactionEmoji = emoji.String
+			activity.ReactionLabel = label.String
 		}
 		
 		activities = append(activities, activity)
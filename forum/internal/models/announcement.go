@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strings"
+	"time"
+)
+
+// Niveaux des annonces, qui choisissent la couleur de leur bannière
+const (
+	AnnouncementInfo    = "info"
+	AnnouncementWarning = "warning"
+)
+
+// Erreurs des annonces, qui viennent de la saisie du modérateur
+var (
+	ErrAnnouncementRequired     = errors.New("announcement message is required")
+	ErrAnnouncementTooLong      = errors.New("announcement message is too long")
+	ErrInvalidAnnouncementLevel = errors.New("invalid announcement level")
+	ErrAnnouncementExpiryInPast = errors.New("announcement expiry must be in the future")
+	ErrAnnouncementNotFound     = errors.New("announcement not found")
+)
+
+// maxAnnouncementLength est la longueur maximale du message d'une annonce
+const maxAnnouncementLength = 500
+
+// Announcement est un message des modérateurs affiché en haut de toutes les pages
+type Announcement struct {
+	ID        int
+	Message   string // Markdown
+	Level     string // AnnouncementInfo ou AnnouncementWarning
+	UserID    int    // Modérateur qui a publié l'annonce, 0 si son compte a été supprimé
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime // Fin de l'annonce, absente pour une annonce sans fin
+	Active    bool         // L'annonce n'a pas expiré
+}
+
+// NewAnnouncement valide une annonce saisie par un modérateur. expiresAt est la fin de l'annonce,
+// zéro pour une annonce sans fin
+func NewAnnouncement(message, level string, userID int, expiresAt, now time.Time) (*Announcement, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, ErrAnnouncementRequired
+	}
+	if len([]rune(message)) > maxAnnouncementLength {
+		return nil, ErrAnnouncementTooLong
+	}
+	if level != AnnouncementInfo && level != AnnouncementWarning {
+		return nil, ErrInvalidAnnouncementLevel
+	}
+
+	announcement := &Announcement{Message: message, Level: level, UserID: userID, CreatedAt: now, Active: true}
+	if !expiresAt.IsZero() {
+		if !expiresAt.After(now) {
+			return nil, ErrAnnouncementExpiryInPast
+		}
+		announcement.ExpiresAt = sql.NullTime{Time: expiresAt.UTC(), Valid: true}
+	}
+	return announcement, nil
+}
+
+// CreateAnnouncement publie une annonce validée par NewAnnouncement
+func CreateAnnouncement(ctx context.Context, announcement *Announcement) (int, error) {
+	var expiresAt interface{}
+	if announcement.ExpiresAt.Valid {
+		expiresAt = sqliteTime(announcement.ExpiresAt.Time)
+	}
+	result, err := database.DB.ExecContext(ctx,
+		"INSERT INTO announcements (message, level, user_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		announcement.Message, announcement.Level, announcement.UserID, sqliteTime(announcement.CreatedAt), expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// DeleteAnnouncement retire une annonce
+func DeleteAnnouncement(ctx context.Context, announcementID int) error {
+	result, err := database.DB.ExecContext(ctx, "DELETE FROM announcements WHERE id = ?", announcementID)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrAnnouncementNotFound
+	}
+	return nil
+}
+
+// GetAnnouncements renvoie toutes les annonces, expirées comprises, des plus récentes aux plus anciennes
+func GetAnnouncements(ctx context.Context, now time.Time) ([]*Announcement, error) {
+	return queryAnnouncements(ctx, now, "")
+}
+
+// GetActiveAnnouncements renvoie les annonces qui n'ont pas expiré, des plus récentes aux plus anciennes
+func GetActiveAnnouncements(ctx context.Context, now time.Time) ([]*Announcement, error) {
+	return queryAnnouncements(ctx, now, "WHERE a.expires_at IS NULL OR datetime(a.expires_at) > datetime(?)", sqliteTime(now))
+}
+
+// queryAnnouncements lit les annonces qui vérifient la condition where
+func queryAnnouncements(ctx context.Context, now time.Time, where string, args ...interface{}) ([]*Announcement, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT a.id, a.message, a.level, COALESCE(a.user_id, 0), COALESCE(u.username, ''), a.created_at, a.expires_at
+		FROM announcements a
+		LEFT JOIN users u ON u.id = a.user_id
+		`+where+`
+		ORDER BY a.created_at DESC, a.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []*Announcement{}
+	for rows.Next() {
+		announcement := &Announcement{}
+		err := rows.Scan(&announcement.ID, &announcement.Message, &announcement.Level, &announcement.UserID,
+			&announcement.Username, &announcement.CreatedAt, &announcement.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		announcement.Active = !announcement.ExpiresAt.Valid || now.Before(announcement.ExpiresAt.Time)
+		announcements = append(announcements, announcement)
+	}
+	return announcements, rows.Err()
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"forum/internal/database"
+	"sort"
+	"time"
+)
+
+// Badge est une distinction gagnée par l'activité d'un utilisateur
+type Badge struct {
+	Name        string // Identifiant stable, stocké dans user_badges
+	Emoji       string
+	Label       string
+	Description string
+}
+
+// UserBadge est un badge gagné par un utilisateur
+type UserBadge struct {
+	Badge
+	AwardedAt time.Time
+}
+
+// badgeRule attribue un badge quand l'activité d'un utilisateur remplit sa condition
+type badgeRule struct {
+	Badge
+	earned func(stats *ActivityStats, now time.Time) bool
+}
+
+// badgeRules sont les badges du forum, dans leur ordre d'affichage. Un badge gagné reste acquis
+// même si l'activité qui l'a fait gagner disparaît
+var badgeRules = []badgeRule{
+	{Badge{"first-post", "📝", "Premier post", "A publié son premier post"},
+		func(s *ActivityStats, now time.Time) bool { return s.Posts >= 1 }},
+	{Badge{"first-comment", "💬", "Premier commentaire", "A écrit son premier commentaire"},
+		func(s *ActivityStats, now time.Time) bool { return s.Comments >= 1 }},
+	{Badge{"supporter", "🤝", "Supporter", "A réagi 25 fois aux contenus des autres"},
+		func(s *ActivityStats, now time.Time) bool { return s.ReactionsGiven >= 25 }},
+	{Badge{"appreciated", "👏", "Apprécié", "A reçu 10 réactions positives"},
+		func(s *ActivityStats, now time.Time) bool { return s.Upvotes >= 10 }},
+	{Badge{"popular", "🌟", "Post populaire", "Un de ses posts a reçu 10 réactions positives"},
+		func(s *ActivityStats, now time.Time) bool { return s.BestPostLikes >= 10 }},
+	{Badge{"solver", "✅", "Solution", "Une de ses réponses a été acceptée"},
+		func(s *ActivityStats, now time.Time) bool { return s.AcceptedAnswers >= 1 }},
+	{Badge{"expert", "🎓", "Expert", "10 de ses réponses ont été acceptées"},
+		func(s *ActivityStats, now time.Time) bool { return s.AcceptedAnswers >= 10 }},
+	{Badge{"prolific", "✍️", "Prolifique", "A écrit 100 posts et commentaires"},
+		func(s *ActivityStats, now time.Time) bool { return s.Posts+s.Comments >= 100 }},
+	{Badge{"veteran", "🎂", "Ancien", "Membre depuis plus d'un an"},
+		func(s *ActivityStats, now time.Time) bool { return now.Sub(s.MemberSince) >= 365*24*time.Hour }},
+}
+
+// badgeIndex renvoie la position d'un badge dans badgeRules, -1 s'il n'existe plus
+func badgeIndex(name string) int {
+	for i, rule := range badgeRules {
+		if rule.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindBadge renvoie le badge qui porte ce nom, s'il fait encore partie des règles
+func FindBadge(name string) (Badge, bool) {
+	if i := badgeIndex(name); i >= 0 {
+		return badgeRules[i].Badge, true
+	}
+	return Badge{}, false
+}
+
+// SortBadges range des badges dans leur ordre d'affichage
+func SortBadges(badges []Badge) {
+	sort.Slice(badges, func(i, j int) bool { return badgeIndex(badges[i].Name) < badgeIndex(badges[j].Name) })
+}
+
+// EarnedBadges renvoie les badges dont l'activité remplit la condition
+func EarnedBadges(stats *ActivityStats, now time.Time) []Badge {
+	var badges []Badge
+	for _, rule := range badgeRules {
+		if rule.earned(stats, now) {
+			badges = append(badges, rule.Badge)
+		}
+	}
+	return badges
+}
+
+// AwardBadges attribue à un utilisateur les badges qu'il a gagnés et renvoie ceux qu'il n'avait pas encore
+func AwardBadges(ctx context.Context, userID int) ([]Badge, error) {
+	stats, err := GetActivityStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var awarded []Badge
+	for _, badge := range EarnedBadges(stats, time.Now()) {
+		result, err := database.DB.ExecContext(ctx,
+			"INSERT OR IGNORE INTO user_badges (user_id, badge) VALUES (?, ?)", userID, badge.Name)
+		if err != nil {
+			return nil, err
+		}
+		added, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if added > 0 {
+			awarded = append(awarded, badge)
+		}
+	}
+	return awarded, nil
+}
+
+// AwardAllBadges attribue les badges gagnés par tous les utilisateurs et renvoie le nombre de badges attribués
+func AwardAllBadges(ctx context.Context) (int, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, "SELECT id FROM users ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		awarded, err := AwardBadges(ctx, userID)
+		if err != nil {
+			return total, err
+		}
+		total += len(awarded)
+	}
+	return total, nil
+}
+
+// GetUserBadges renvoie les badges d'un utilisateur dans l'ordre où il les a gagnés
+func GetUserBadges(ctx context.Context, userID int) ([]UserBadge, error) {
+	rows, err := database.ReadDB.QueryContext(ctx,
+		"SELECT badge, awarded_at FROM user_badges WHERE user_id = ? ORDER BY awarded_at, badge", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	badges := []UserBadge{}
+	for rows.Next() {
+		var name string
+		var awardedAt time.Time
+		if err := rows.Scan(&name, &awardedAt); err != nil {
+			return nil, err
+		}
+		// Un badge retiré des règles n'est plus affiché
+		if badge, ok := FindBadge(name); ok {
+			badges = append(badges, UserBadge{Badge: badge, AwardedAt: awardedAt})
+		}
+	}
+	return badges, rows.Err()
+}
+
+// loadUserBadges renvoie les badges de chaque utilisateur de la liste, dans l'ordre d'affichage des badges
+func loadUserBadges(ctx context.Context, userIDs []int) (map[int][]Badge, error) {
+	in, args := inClause(userIDs)
+	rows, err := database.ReadDB.QueryContext(ctx,
+		"SELECT user_id, badge FROM user_badges WHERE user_id IN "+in, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byUser := map[int][]Badge{}
+	for rows.Next() {
+		var userID int
+		var name string
+		if err := rows.Scan(&userID, &name); err != nil {
+			return nil, err
+		}
+		if badge, ok := FindBadge(name); ok {
+			byUser[userID] = append(byUser[userID], badge)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, badges := range byUser {
+		SortBadges(badges)
+	}
+	return byUser, nil
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"forum/internal/database"
+	"strings"
+)
+
+// Colonnes lues pour chaque post dans les listes (à utiliser avec scanPost)
+const postColumns = `p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at,
+	p.like_count, p.dislike_count, p.comment_count, COALESCE(p.accepted_comment_id, 0),
+	`+activePin+`, COALESCE(p.pinned_category_id, 0), p.pinned_until, p.locked_at IS NOT NULL`
+
+// Colonnes lues pour chaque commentaire dans les listes (à utiliser avec scanComment)
+const commentColumns = `c.id, c.content, c.user_id, u.username, c.post_id, c.created_at, c.updated_at,
+	c.like_count, c.dislike_count`
+
+// rowScanner est implémenté par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost lit les colonnes postColumns, suivies d'éventuelles colonnes supplémentaires
+func scanPost(row rowScanner, extra ...interface{}) (*Post, error) {
+	post := &Post{}
+	dest := []interface{}{
+		&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt,
+		&post.Likes, &post.Dislikes, &post.CommentCount, &post.AcceptedCommentID,
+		&post.Pinned, &post.PinnedCategoryID, &post.PinnedUntil, &post.Locked,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// scanComment lit les colonnes commentColumns, suivies d'éventuelles colonnes supplémentaires
+func scanComment(row rowScanner, extra ...interface{}) (*Comment, error) {
+	comment := &Comment{}
+	dest := []interface{}{
+		&comment.ID, &comment.Content, &comment.UserID, &comment.Username, &comment.PostID, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.Likes, &comment.Dislikes,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// scanPosts lit toutes les lignes d'une requête qui sélectionne postColumns
+func scanPosts(rows *sql.Rows) ([]*Post, error) {
+	defer rows.Close()
+
+	var posts []*Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// scanComments lit toutes les lignes d'une requête qui sélectionne commentColumns
+func scanComments(rows *sql.Rows) ([]*Comment, error) {
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// inClause renvoie "(?, ?, ...)" et les arguments correspondants pour une liste d'IDs
+func inClause(ids []int) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")", args
+}
+
+// loadPostDetails complète une page de posts avec leurs catégories, leurs tags, le nombre de chaque réaction,
+// les badges des auteurs, les réactions et les signets de l'utilisateur courant, en six requêtes quel que
+// soit le nombre de posts
+func loadPostDetails(ctx context.Context, posts []*Post, currentUserID int) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Post, len(posts))
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		post.Categories = []Category{}
+		post.Tags = []Tag{}
+		byID[post.ID] = post
+		ids = append(ids, post.ID)
+	}
+	in, args := inClause(ids)
+
+	// Toutes les catégories de la page en une seule requête
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+categoryColumns+`, pc.post_id
+		FROM post_categories pc
+		JOIN categories c ON c.id = pc.category_id
+		WHERE pc.post_id IN `+in+`
+		ORDER BY c.position, c.id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var postID int
+		category, err := scanCategory(rows, &postID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Categories = append(post.Categories, category)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Tous les tags de la page en une seule requête
+	rows, err = database.ReadDB.QueryContext(ctx, `
+		SELECT t.id, t.name, pt.post_id
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id IN `+in+`
+		ORDER BY t.name
+	`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tag Tag
+		var postID int
+		if err := rows.Scan(&tag.ID, &tag.Name, &postID); err != nil {
+			rows.Close()
+			return err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Tags = append(post.Tags, tag)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Les compteurs de chaque réaction, et celles de l'utilisateur courant
+	reactions, err := loadReactionCounts(ctx, ReactionOnPost, ids, currentUserID)
+	if err != nil {
+		return err
+	}
+	for postID, counts := range reactions {
+		byID[postID].Reactions = counts
+	}
+
+	// Les badges des auteurs de la page
+	authorIDs := make([]int, 0, len(posts))
+	for _, post := range posts {
+		authorIDs = append(authorIDs, post.UserID)
+	}
+	badges, err := loadUserBadges(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.AuthorBadges = badges[post.UserID]
+	}
+
+	// Les signets de l'utilisateur courant sur la page
+	if currentUserID <= 0 {
+		return nil
+	}
+	bookmarked, err := loadBookmarked(ctx, currentUserID, BookmarkPost, in, args)
+	if err != nil {
+		return err
+	}
+	for _, postID := range bookmarked {
+		byID[postID].IsBookmarked = true
+	}
+	return nil
+}
+
+// loadCommentDetails complète une liste de commentaires avec le nombre de chaque réaction, les badges des auteurs,
+// les réactions et les signets de l'utilisateur courant, en quatre requêtes quel que soit le nombre de commentaires
+func loadCommentDetails(ctx context.Context, comments []*Comment, currentUserID int) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Comment, len(comments))
+	ids := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+		ids = append(ids, comment.ID)
+	}
+	in, args := inClause(ids)
+
+	// Les compteurs de chaque réaction, et celles de l'utilisateur courant
+	reactions, err := loadReactionCounts(ctx, ReactionOnComment, ids, currentUserID)
+	if err != nil {
+		return err
+	}
+	for commentID, counts := range reactions {
+		byID[commentID].Reactions = counts
+	}
+
+	// Les badges des auteurs des commentaires
+	authorIDs := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		authorIDs = append(authorIDs, comment.UserID)
+	}
+	badges, err := loadUserBadges(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		comment.AuthorBadges = badges[comment.UserID]
+	}
+
+	// Les signets de l'utilisateur courant
+	if currentUserID <= 0 {
+		return nil
+	}
+	bookmarked, err := loadBookmarked(ctx, currentUserID, BookmarkComment, in, args)
+	if err != nil {
+		return err
+	}
+	for _, commentID := range bookmarked {
+		byID[commentID].IsBookmarked = true
+	}
+	return nil
+}
+
+// loadBookmarked renvoie les IDs, parmi ceux de la clause in, que l'utilisateur a dans ses signets
+func loadBookmarked(ctx context.Context, userID int, targetType, in string, args []interface{}) ([]int, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT target_id
+		FROM bookmarks
+		WHERE user_id = ? AND target_type = ? AND target_id IN `+in,
+		append([]interface{}{userID, targetType}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"forum/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contenus qu'un signet peut désigner
+const (
+	BookmarkPost    = "post"
+	BookmarkComment = "comment"
+)
+
+// Limites des signets
+const (
+	MaxBookmarkNoteLength = 1000 // Nombre maximal de caractères d'une note
+	MaxBookmarkFolderName = 50   // Nombre maximal de caractères du nom d'un dossier
+)
+
+// Erreurs des signets qui viennent de la saisie de l'utilisateur
+var (
+	ErrInvalidBookmarkTarget  = errors.New("invalid bookmark target")
+	ErrBookmarkTargetNotFound = errors.New("bookmark target not found")
+	ErrBookmarkNotFound       = errors.New("bookmark not found")
+	ErrBookmarkNoteTooLong    = errors.New("note is too long")
+	ErrInvalidFolderName      = errors.New("invalid folder name")
+	ErrFolderExists           = errors.New("folder already exists")
+	ErrFolderNotFound         = errors.New("folder not found")
+)
+
+// BookmarkFolder est un dossier de signets d'un utilisateur
+type BookmarkFolder struct {
+	ID        int
+	UserID    int
+	Name      string
+	Count     int // Nombre de signets du dossier (non stocké dans la table bookmark_folders)
+	CreatedAt time.Time
+}
+
+// Bookmark est un post ou un commentaire enregistré par un utilisateur pour plus tard
+// Les signets sont privés: seul leur propriétaire les voit
+type Bookmark struct {
+	ID         int
+	UserID     int
+	TargetType string // BookmarkPost ou BookmarkComment
+	TargetID   int
+	FolderID   int    // 0 si le signet n'est rangé dans aucun dossier
+	FolderName string // Nom du dossier (non stocké dans la table bookmarks)
+	Note       string
+	CreatedAt  time.Time
+	// Le contenu désigné, lu avec le signet
+	PostID     int
+	PostTitle  string
+	AuthorName string
+	Excerpt    string
+}
+
+// Path renvoie l'adresse du contenu désigné sur le forum
+func (b *Bookmark) Path() string {
+	path := "/post/" + strconv.Itoa(b.PostID)
+	if b.TargetType == BookmarkComment {
+		path += "#comment-" + strconv.Itoa(b.TargetID)
+	}
+	return path
+}
+
+// ValidBookmarkTarget indique si targetType est un contenu qu'on peut enregistrer
+func ValidBookmarkTarget(targetType string) bool {
+	return targetType == BookmarkPost || targetType == BookmarkComment
+}
+
+// NormalizeBookmarkFolderName nettoie le nom d'un dossier et vérifie sa longueur
+func NormalizeBookmarkFolderName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len([]rune(name)) > MaxBookmarkFolderName {
+		return "", ErrInvalidFolderName
+	}
+	return name, nil
+}
+
+// checkBookmarkFolder vérifie qu'un dossier appartient à l'utilisateur; 0 désigne l'absence de dossier
+func checkBookmarkFolder(ctx context.Context, q querier, userID, folderID int) error {
+	if folderID == 0 {
+		return nil
+	}
+	var count int
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM bookmark_folders WHERE id = ? AND user_id = ?", folderID, userID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// SaveBookmark enregistre un post ou un commentaire dans les signets d'un utilisateur
+// Enregistrer de nouveau un contenu déjà enregistré change son dossier et sa note
+func SaveBookmark(ctx context.Context, userID int, targetType string, targetID, folderID int, note string) error {
+	if !ValidBookmarkTarget(targetType) {
+		return ErrInvalidBookmarkTarget
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > MaxBookmarkNoteLength {
+		return ErrBookmarkNoteTooLong
+	}
+
+	// Un post rangé dans une catégorie que l'utilisateur ne peut pas lire n'existe pas pour lui
+	readable, readableArgs, err := viewerReadCondition(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "SELECT COUNT(*) FROM posts p WHERE p.id = ? AND " + readable
+	if targetType == BookmarkComment {
+		query = "SELECT COUNT(*) FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ? AND " + readable
+	}
+	var count int
+	err = tx.QueryRowContext(ctx, query, append([]interface{}{targetID}, readableArgs...)...).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrBookmarkTargetNotFound
+	}
+	if err := checkBookmarkFolder(ctx, tx, userID, folderID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO bookmarks (user_id, target_type, target_id, folder_id, note) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE SET folder_id = excluded.folder_id, note = excluded.note`,
+		userID, targetType, targetID, nullableID(folderID), note,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveBookmark retire un contenu des signets d'un utilisateur
+func RemoveBookmark(ctx context.Context, userID int, targetType string, targetID int) error {
+	result, err := database.DB.ExecContext(ctx,
+		"DELETE FROM bookmarks WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		userID, targetType, targetID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetBookmarks renvoie les signets d'un utilisateur, les plus récents d'abord
+// folderID limite la liste à un dossier; 0 renvoie tous les signets. Les signets des posts que
+// l'utilisateur ne peut plus lire sont gardés mais pas renvoyés
+func GetBookmarks(ctx context.Context, userID, folderID int) ([]*Bookmark, error) {
+	readable, readableArgs, err := viewerReadCondition(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT b.id, b.user_id, b.target_type, b.target_id, COALESCE(b.folder_id, 0), COALESCE(f.name, ''),
+			b.note, b.created_at, p.id, p.title, COALESCE(u.username, ''), COALESCE(c.content, p.content)
+		FROM bookmarks b
+		LEFT JOIN bookmark_folders f ON f.id = b.folder_id
+		LEFT JOIN comments c ON b.target_type = 'comment' AND c.id = b.target_id
+		JOIN posts p ON p.id = CASE b.target_type WHEN 'comment' THEN c.post_id ELSE b.target_id END
+		LEFT JOIN users u ON u.id = CASE b.target_type WHEN 'comment' THEN c.user_id ELSE p.user_id END
+		WHERE b.user_id = ? AND ` + readable
+	args := append([]interface{}{userID}, readableArgs...)
+	if folderID != 0 {
+		query += " AND b.folder_id = ?"
+		args = append(args, folderID)
+	}
+	query += " ORDER BY b.created_at DESC, b.id DESC"
+
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []*Bookmark{}
+	for rows.Next() {
+		b := &Bookmark{}
+		var content string
+		err := rows.Scan(&b.ID, &b.UserID, &b.TargetType, &b.TargetID, &b.FolderID, &b.FolderName,
+			&b.Note, &b.CreatedAt, &b.PostID, &b.PostTitle, &b.AuthorName, &content)
+		if err != nil {
+			return nil, err
+		}
+		b.Excerpt = DigestExcerpt(content)
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// GetBookmarkFolders renvoie les dossiers de signets d'un utilisateur par nom, avec leur nombre de signets
+func GetBookmarkFolders(ctx context.Context, userID int) ([]*BookmarkFolder, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT f.id, f.user_id, f.name, (SELECT COUNT(*) FROM bookmarks WHERE folder_id = f.id), f.created_at
+		FROM bookmark_folders f
+		WHERE f.user_id = ?
+		ORDER BY f.name COLLATE NOCASE`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []*BookmarkFolder{}
+	for rows.Next() {
+		f := &BookmarkFolder{}
+		if err := rows.Scan(&f.ID, &f.UserID, &f.Name, &f.Count, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// CreateBookmarkFolder crée un dossier de signets et renvoie son ID
+func CreateBookmarkFolder(ctx context.Context, userID int, name string) (int, error) {
+	name, err := NormalizeBookmarkFolderName(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = database.ReadDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM bookmark_folders WHERE user_id = ? AND name = ?", userID, name).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrFolderExists
+	}
+
+	result, err := database.DB.ExecContext(ctx,
+		"INSERT INTO bookmark_folders (user_id, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return 0, err
+	}
+	folderID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(folderID), nil
+}
+
+// DeleteBookmarkFolder supprime un dossier. Ses signets sont gardés, hors de tout dossier
+func DeleteBookmarkFolder(ctx context.Context, userID, folderID int) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// La clé étrangère ferait de même, mais elle peut être désactivée (-db-foreign-keys)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE bookmarks SET folder_id = NULL WHERE folder_id = ? AND user_id = ?", folderID, userID)
+	if err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM bookmark_folders WHERE id = ? AND user_id = ?", folderID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrFolderNotFound
+	}
+	return tx.Commit()
+}
+
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// createStaffTestPost crée un post dans une sous-catégorie d'une catégorie réservée à l'équipe
+func createStaffTestPost(t *testing.T, authorID int) int {
+	t.Helper()
+	ctx := context.Background()
+	staffID, err := CreateCategory(ctx, Category{Name: "Équipe", ReadPermission: CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	subID, err := CreateCategory(ctx, Category{Name: "Sous-forum", ParentID: staffID})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	postID, err := CreatePostWithDetails(ctx, NewPost{Title: "Post du staff", Content: "Contenu", UserID: authorID, CategoryIDs: []int{subID}})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	return postID
+}
+
+// Les signets et les votes suivent le droit de lecture des catégories du post
+func TestReadPermissionOfBookmarksAndVotes(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	moderatorID := createTestUser(t, "moderator")
+	memberID := createTestUser(t, "member")
+	if err := UpdateUserRole(ctx, moderatorID, "moderator"); err != nil {
+		t.Fatalf("setting role: %v", err)
+	}
+	postID := createStaffTestPost(t, moderatorID)
+	commentID, err := CreateComment(ctx, "Réponse du staff", moderatorID, postID)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+
+	for _, target := range []struct {
+		targetType string
+		targetID   int
+	}{{BookmarkPost, postID}, {BookmarkComment, commentID}} {
+		err := SaveBookmark(ctx, memberID, target.targetType, target.targetID, 0, "")
+		if !errors.Is(err, ErrBookmarkTargetNotFound) {
+			t.Errorf("member bookmarking a hidden %s: got error %v, want ErrBookmarkTargetNotFound", target.targetType, err)
+		}
+		if err := SaveBookmark(ctx, moderatorID, target.targetType, target.targetID, 0, ""); err != nil {
+			t.Errorf("moderator bookmarking a %s: %v", target.targetType, err)
+		}
+	}
+	if bookmarks, err := GetBookmarks(ctx, moderatorID, 0); err != nil || len(bookmarks) != 2 {
+		t.Fatalf("bookmarks of the moderator: %d, error %v", len(bookmarks), err)
+	}
+
+	poll, err := NewPoll("Oui ou non ?", []string{"Oui", "Non"}, false, false, false, time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("building poll: %v", err)
+	}
+	if err := UpdatePoll(ctx, postID, poll); err != nil {
+		t.Fatalf("creating poll: %v", err)
+	}
+	poll, err = GetPoll(ctx, postID, moderatorID)
+	if err != nil {
+		t.Fatalf("fetching poll: %v", err)
+	}
+	optionIDs := []int{poll.Options[0].ID}
+	if err := Vote(ctx, poll.ID, memberID, optionIDs); !errors.Is(err, ErrPollNotFound) {
+		t.Errorf("member voting on a hidden poll: got error %v, want ErrPollNotFound", err)
+	}
+	if err := Vote(ctx, poll.ID, moderatorID, optionIDs); err != nil {
+		t.Errorf("moderator voting: %v", err)
+	}
+
+	// Le modérateur qui perd son rôle garde ses signets, mais ne les voit plus
+	if err := UpdateUserRole(ctx, moderatorID, "user"); err != nil {
+		t.Fatalf("setting role: %v", err)
+	}
+	if bookmarks, err := GetBookmarks(ctx, moderatorID, 0); err != nil || len(bookmarks) != 0 {
+		t.Errorf("bookmarks after losing the role: %d, error %v", len(bookmarks), err)
+	}
+}
@@ -0,0 +1,631 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/database"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Droits de publication d'une catégorie
+const (
+	CategoryOpen           = "open"       // Tout le monde peut publier
+	CategoryModeratorsOnly = "moderators" // Seuls les modérateurs et les administrateurs peuvent créer des posts
+	CategoryReadOnly       = "readonly"   // Plus personne ne peut créer de posts ni commenter
+)
+
+// Droits de lecture d'une catégorie
+const (
+	CategoryReadEveryone = "everyone" // Visible par tous, visiteurs compris
+	CategoryReadMembers  = "members"  // Visible par les utilisateurs connectés
+	CategoryReadStaff    = "staff"    // Visible par les modérateurs et les administrateurs
+)
+
+// Longueur maximale du nom d'une catégorie
+const maxCategoryNameLength = 50
+
+// Une couleur est vide ou au format #rrggbb
+var categoryColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Erreurs de l'administration des catégories, qui viennent de la saisie de l'administrateur
+var (
+	ErrCategoryNotFound        = errors.New("category not found")
+	ErrCategoryNameRequired    = errors.New("category name is required")
+	ErrCategoryNameTooLong     = fmt.Errorf("category name must be at most %d characters", maxCategoryNameLength)
+	ErrInvalidCategoryColor    = errors.New("category color must be in the #rrggbb format")
+	ErrInvalidPostPermission   = errors.New("invalid category post permission")
+	ErrInvalidReadPermission   = errors.New("invalid category read permission")
+	ErrCategoryNameTaken       = errors.New("a category with this name already exists")
+	ErrMergeCategoryIntoItself = errors.New("cannot merge a category into itself")
+	ErrParentCategoryNotFound  = errors.New("parent category not found")
+	ErrCategoryCycle           = errors.New("a category cannot be placed under itself or one of its subcategories")
+)
+
+// ErrCategoryForbidden est renvoyée quand l'utilisateur ne peut pas publier dans l'une des catégories
+// choisies. Les erreurs suivantes précisent la raison et sont toutes des ErrCategoryForbidden
+var ErrCategoryForbidden = errors.New("posting in this category is not allowed")
+
+var (
+	ErrCategoryUnavailable    = fmt.Errorf("%w: category not found", ErrCategoryForbidden)
+	ErrCategoryArchived       = fmt.Errorf("%w: category is archived", ErrCategoryForbidden)
+	ErrCategoryReadOnly       = fmt.Errorf("%w: category is read-only", ErrCategoryForbidden)
+	ErrCategoryModeratorsOnly = fmt.Errorf("%w: only moderators can post in this category", ErrCategoryForbidden)
+)
+
+// Category représente une catégorie de posts
+type Category struct {
+	ID             int
+	Name           string
+	Description    sql.NullString // Modifié pour gérer les valeurs NULL
+	Position       int            // Ordre d'affichage, du plus petit au plus grand
+	Color          string         // Couleur d'affichage au format #rrggbb, ou vide
+	Archived       bool           // Une catégorie archivée reste visible sur ses posts mais n'en reçoit plus
+	PostPermission string         // CategoryOpen, CategoryModeratorsOnly ou CategoryReadOnly
+	ReadPermission string         // CategoryReadEveryone, CategoryReadMembers ou CategoryReadStaff
+	ParentID       int            // Catégorie parente, 0 pour une catégorie racine
+	Depth          int            // Profondeur dans l'arbre, remplie par SortCategoryTree
+}
+
+// AllowsPostsFrom indique si un utilisateur de ce rôle peut créer un post dans la catégorie
+func (c Category) AllowsPostsFrom(role string) bool {
+	if c.Archived {
+		return false
+	}
+	switch c.PostPermission {
+	case CategoryReadOnly:
+		return false
+	case CategoryModeratorsOnly:
+		return role == "moderator" || role == "admin"
+	default:
+		return true
+	}
+}
+
+// AllowsReadFrom indique si un utilisateur de ce rôle peut voir la catégorie et ses posts
+// Le rôle d'un visiteur non connecté est vide
+func (c Category) AllowsReadFrom(role string) bool {
+	switch c.ReadPermission {
+	case CategoryReadStaff:
+		return role == "moderator" || role == "admin"
+	case CategoryReadMembers:
+		return role != ""
+	default:
+		return true
+	}
+}
+
+// AllowsComments indique si les posts de la catégorie peuvent encore être commentés
+func (c Category) AllowsComments() bool {
+	return c.PostPermission != CategoryReadOnly
+}
+
+// Colonnes lues pour chaque catégorie (à utiliser avec scanCategory)
+const categoryColumns = `c.id, c.name, c.description, c.position, c.color, c.archived, c.post_permission,
+	c.read_permission, COALESCE(c.parent_id, 0)`
+
+// scanCategory lit les colonnes categoryColumns, suivies d'éventuelles colonnes supplémentaires
+func scanCategory(row rowScanner, extra ...interface{}) (Category, error) {
+	var category Category
+	dest := []interface{}{
+		&category.ID, &category.Name, &category.Description, &category.Position,
+		&category.Color, &category.Archived, &category.PostPermission, &category.ReadPermission,
+		&category.ParentID,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return category, err
+}
+
+// queryCategories exécute une requête qui sélectionne categoryColumns
+func queryCategories(ctx context.Context, query string, args ...interface{}) ([]Category, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// GetAllCategories récupère les catégories proposées à un utilisateur de ce rôle, dans l'ordre de l'arbre
+// Les réglages hérités des catégories parentes sont appliqués: une catégorie dont un ancêtre est archivé
+// ou que le rôle ne peut pas lire n'est pas renvoyée
+func GetAllCategories(ctx context.Context, role string) ([]Category, error) {
+	categories, err := GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return VisibleCategories(categories, role), nil
+}
+
+// VisibleCategories applique l'héritage des réglages et retire les catégories archivées
+// et celles qu'un utilisateur de ce rôle ne peut pas lire
+func VisibleCategories(categories []Category, role string) []Category {
+	var visible []Category
+	for _, category := range InheritCategorySettings(categories) {
+		if !category.Archived && category.AllowsReadFrom(role) {
+			visible = append(visible, category)
+		}
+	}
+	return visible
+}
+
+// GetCategories récupère toutes les catégories, y compris celles qui sont archivées, dans l'ordre de l'arbre
+// Les réglages sont ceux de chaque catégorie, sans héritage, pour l'administration
+func GetCategories(ctx context.Context) ([]Category, error) {
+	categories, err := queryCategories(ctx, "SELECT "+categoryColumns+" FROM categories c ORDER BY c.position, c.id")
+	if err != nil {
+		return nil, err
+	}
+	return SortCategoryTree(categories), nil
+}
+
+// GetCategoryByID récupère une catégorie par son ID
+func GetCategoryByID(ctx context.Context, categoryID int) (*Category, error) {
+	category, err := scanCategory(database.ReadDB.QueryRowContext(ctx,
+		"SELECT "+categoryColumns+" FROM categories c WHERE c.id = ?", categoryID))
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ValidateCategory normalise et vérifie les champs modifiables d'une catégorie
+func ValidateCategory(category *Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Description.String = strings.TrimSpace(category.Description.String)
+	category.Description.Valid = category.Description.String != ""
+	category.Color = strings.TrimSpace(category.Color)
+	if category.PostPermission == "" {
+		category.PostPermission = CategoryOpen
+	}
+	if category.ReadPermission == "" {
+		category.ReadPermission = CategoryReadEveryone
+	}
+
+	if category.Name == "" {
+		return ErrCategoryNameRequired
+	}
+	if len([]rune(category.Name)) > maxCategoryNameLength {
+		return ErrCategoryNameTooLong
+	}
+	if category.Color != "" && !categoryColorPattern.MatchString(category.Color) {
+		return ErrInvalidCategoryColor
+	}
+	switch category.PostPermission {
+	case CategoryOpen, CategoryModeratorsOnly, CategoryReadOnly:
+	default:
+		return ErrInvalidPostPermission
+	}
+	switch category.ReadPermission {
+	case CategoryReadEveryone, CategoryReadMembers, CategoryReadStaff:
+	default:
+		return ErrInvalidReadPermission
+	}
+	return nil
+}
+
+// categoryNameError traduit l'erreur de la contrainte UNIQUE sur le nom
+func categoryNameError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: categories.name") {
+		return ErrCategoryNameTaken
+	}
+	return err
+}
+
+// checkCategoryParent vérifie le parent choisi pour une catégorie (categoryID vaut 0 pour une nouvelle catégorie)
+func checkCategoryParent(ctx context.Context, categoryID, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	categories, err := queryCategories(ctx, "SELECT "+categoryColumns+" FROM categories c")
+	if err != nil {
+		return err
+	}
+	return CategoryParentError(categories, categoryID, parentID)
+}
+
+// nullableParentID convertit l'ID du parent pour la base: NULL pour une catégorie racine
+func nullableParentID(parentID int) interface{} {
+	if parentID == 0 {
+		return nil
+	}
+	return parentID
+}
+
+// CreateCategory crée une catégorie, placée après toutes les autres
+func CreateCategory(ctx context.Context, category Category) (int, error) {
+	if err := ValidateCategory(&category); err != nil {
+		return 0, err
+	}
+	if err := checkCategoryParent(ctx, 0, category.ParentID); err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		INSERT INTO categories (name, description, position, color, archived, post_permission, read_permission, parent_id)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM categories), ?, ?, ?, ?, ?)`,
+		category.Name, category.Description, category.Color, category.Archived, category.PostPermission,
+		category.ReadPermission, nullableParentID(category.ParentID),
+	)
+	if err != nil {
+		return 0, categoryNameError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateCategory modifie le nom, la description, la couleur, l'archivage, les droits de publication
+// et de lecture et le parent d'une catégorie
+// La position se modifie avec ReorderCategories
+func UpdateCategory(ctx context.Context, category Category) error {
+	if err := ValidateCategory(&category); err != nil {
+		return err
+	}
+	if err := checkCategoryParent(ctx, category.ID, category.ParentID); err != nil {
+		return err
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		UPDATE categories SET name = ?, description = ?, color = ?, archived = ?, post_permission = ?,
+			read_permission = ?, parent_id = ?
+		WHERE id = ?`,
+		category.Name, category.Description, category.Color, category.Archived, category.PostPermission,
+		category.ReadPermission, nullableParentID(category.ParentID), category.ID,
+	)
+	if err != nil {
+		return categoryNameError(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// ReorderCategories place les catégories données en tête, dans cet ordre
+// Les catégories absentes de la liste gardent leur ordre relatif et sont placées après
+func ReorderCategories(ctx context.Context, categoryIDs []int) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM categories ORDER BY position, id")
+	if err != nil {
+		return err
+	}
+	var current []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	exists := map[int]bool{}
+	for _, id := range current {
+		exists[id] = true
+	}
+
+	placed := map[int]bool{}
+	var order []int
+	for _, id := range categoryIDs {
+		if !exists[id] {
+			return fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+		}
+		if !placed[id] {
+			placed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !placed[id] {
+			order = append(order, id)
+		}
+	}
+
+	for i, id := range order {
+		_, err = tx.ExecContext(ctx, "UPDATE categories SET position = ? WHERE id = ?", i+1, id)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MergeCategories déplace tous les posts de la catégorie sourceID vers targetID, puis supprime sourceID
+// Un post qui était dans les deux catégories n'est compté qu'une fois dans la catégorie cible
+// Les sous-catégories de sourceID deviennent des sous-catégories de targetID, et les brouillons
+// rangés dans sourceID le sont dans targetID
+func MergeCategories(ctx context.Context, sourceID, targetID int) error {
+	if sourceID == targetID {
+		return ErrMergeCategoryIntoItself
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM categories WHERE id IN (?, ?)", sourceID, targetID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return ErrCategoryNotFound
+	}
+
+	// Les posts publiés et les posts en attente de modération sont rattachés à la catégorie cible
+	for _, table := range []string{"post_categories", "pending_post_categories"} {
+		_, err = tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO `+table+` (post_id, category_id)
+			SELECT post_id, ? FROM `+table+` WHERE category_id = ?`, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE category_id = ?", sourceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Les abonnés de la source suivent la cible; un abonnement existant à la cible est gardé
+	_, err = tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO subscriptions (user_id, target_type, target_id, muted)
+		SELECT user_id, 'category', ?, muted FROM subscriptions
+		WHERE target_type = 'category' AND target_id = ?`, targetID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	// Les posts épinglés dans la source restent épinglés dans la cible
+	_, err = tx.ExecContext(ctx, "UPDATE posts SET pinned_category_id = ? WHERE pinned_category_id = ?", targetID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	if err := mergeDraftCategoriesTx(ctx, tx, sourceID, targetID); err != nil {
+		return err
+	}
+
+	// Si la cible est une sous-catégorie de la source, elle remonte d'abord à la place de la source
+	// pour que le rattachement des sous-catégories ne crée pas de cycle
+	_, err = tx.ExecContext(ctx, `
+		WITH RECURSIVE descendants(id) AS (
+			SELECT id FROM categories WHERE parent_id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN descendants d ON c.parent_id = d.id
+		)
+		UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = ?)
+		WHERE id = ? AND id IN descendants`, sourceID, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE categories SET parent_id = ? WHERE parent_id = ?", targetID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", sourceID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// mergeDraftCategoriesTx remplace sourceID par targetID dans les catégories des brouillons, séparées
+// par des virgules. Un brouillon qui était dans les deux catégories ne garde la cible qu'une fois
+func mergeDraftCategoriesTx(ctx context.Context, tx *sql.Tx, sourceID, targetID int) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, categories FROM drafts WHERE ',' || categories || ',' LIKE ?", "%,"+strconv.Itoa(sourceID)+",%")
+	if err != nil {
+		return err
+	}
+	merged := map[int]string{}
+	for rows.Next() {
+		var draftID int
+		var categories string
+		if err := rows.Scan(&draftID, &categories); err != nil {
+			rows.Close()
+			return err
+		}
+		var categoryIDs []int
+		seen := map[int]bool{}
+		for _, id := range splitIDs(categories) {
+			if id == sourceID {
+				id = targetID
+			}
+			if !seen[id] {
+				seen[id] = true
+				categoryIDs = append(categoryIDs, id)
+			}
+		}
+		merged[draftID] = joinIDs(categoryIDs)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for draftID, categories := range merged {
+		if _, err := tx.ExecContext(ctx, "UPDATE drafts SET categories = ? WHERE id = ?", categories, draftID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readablePostsCondition renvoie la condition SQL qui garde les posts (alias p) qu'un utilisateur de ce rôle
+// peut lire, avec ses arguments. categories doit contenir toutes les catégories, pour que les droits
+// des parents soient hérités
+func readablePostsCondition(categories []Category, role string) (string, []interface{}) {
+	hidden := HiddenCategoryIDs(categories, role)
+	if len(hidden) == 0 {
+		return "1 = 1", nil
+	}
+	args := make([]interface{}, len(hidden))
+	for i, id := range hidden {
+		args[i] = id
+	}
+	return `p.id NOT IN (SELECT pc.post_id FROM post_categories pc
+		WHERE pc.category_id IN (?` + strings.Repeat(", ?", len(hidden)-1) + `))`, args
+}
+
+// viewerCategories renvoie le rôle de l'utilisateur userID (vide pour un visiteur) et toutes les catégories
+func viewerCategories(ctx context.Context, userID int) (string, []Category, error) {
+	role := ""
+	if userID > 0 {
+		err := database.ReadDB.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+		if err != nil && err != sql.ErrNoRows {
+			return "", nil, err
+		}
+	}
+	categories, err := queryCategories(ctx, "SELECT "+categoryColumns+" FROM categories c")
+	if err != nil {
+		return "", nil, err
+	}
+	return role, categories, nil
+}
+
+// viewerReadCondition renvoie la condition de readablePostsCondition pour l'utilisateur userID (0 pour un visiteur)
+func viewerReadCondition(ctx context.Context, userID int) (string, []interface{}, error) {
+	role, categories, err := viewerCategories(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	condition, args := readablePostsCondition(categories, role)
+	return condition, args, nil
+}
+
+// userRoles sont les rôles des utilisateurs inscrits
+var userRoles = []string{"user", "moderator", "admin"}
+
+// postReadersConditionTx renvoie la condition SQL qui garde les utilisateurs (colonne userColumn)
+// qui peuvent lire le post d'après ses catégories dans la transaction, avec ses arguments
+func postReadersConditionTx(ctx context.Context, tx *sql.Tx, userColumn string, postID int) (string, []interface{}, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories c")
+	if err != nil {
+		return "", nil, err
+	}
+	var categories []Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			rows.Close()
+			return "", nil, err
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT category_id FROM post_categories WHERE post_id = ?", postID)
+	if err != nil {
+		return "", nil, err
+	}
+	var categoryIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", nil, err
+		}
+		categoryIDs = append(categoryIDs, id)
+	}
+	if err := rows.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	var roles []interface{}
+	for _, role := range userRoles {
+		if CanReadPost(categories, categoryIDs, role) {
+			roles = append(roles, role)
+		}
+	}
+	switch len(roles) {
+	case len(userRoles):
+		return "1 = 1", nil, nil
+	case 0:
+		return "0 = 1", nil, nil
+	}
+	return userColumn + ` IN (SELECT id FROM users WHERE role IN (?` + strings.Repeat(", ?", len(roles)-1) + `))`, roles, nil
+}
+
+// CheckCategoryPermission vérifie qu'un utilisateur de ce rôle peut publier dans toutes les catégories données
+// Les réglages des catégories parentes s'appliquent à leurs sous-catégories
+func CheckCategoryPermission(ctx context.Context, categoryIDs []int, role string) error {
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	categories, err := queryCategories(ctx, "SELECT "+categoryColumns+" FROM categories c")
+	if err != nil {
+		return err
+	}
+	return CategoryPermissionError(categories, categoryIDs, role)
+}
+
+// CategoryPermissionError renvoie l'erreur qui empêche de publier dans les catégories données, ou nil
+// categories doit contenir toutes les catégories, pour que les réglages des parents soient hérités
+func CategoryPermissionError(categories []Category, categoryIDs []int, role string) error {
+	byID := map[int]Category{}
+	for _, category := range InheritCategorySettings(categories) {
+		byID[category.ID] = category
+	}
+
+	for _, id := range categoryIDs {
+		category, ok := byID[id]
+		switch {
+		case !ok, !category.AllowsReadFrom(role):
+			return fmt.Errorf("%w: %d", ErrCategoryUnavailable, id)
+		case category.Archived:
+			return fmt.Errorf("%w: %s", ErrCategoryArchived, category.Name)
+		case category.PostPermission == CategoryReadOnly:
+			return fmt.Errorf("%w: %s", ErrCategoryReadOnly, category.Name)
+		case !category.AllowsPostsFrom(role):
+			return fmt.Errorf("%w: %s", ErrCategoryModeratorsOnly, category.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"forum/internal/database"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Les catégories forment un arbre: chaque catégorie a au plus un parent (ParentID, 0 pour une racine)
+
+// CategoryNode est une catégorie de l'arbre affiché sur la page d'accueil
+// PostCount et LastActivity portent sur la catégorie et toutes ses sous-catégories
+type CategoryNode struct {
+	Category
+	PostCount    int
+	LastActivity time.Time // Date du dernier post ou commentaire, zéro s'il n'y en a aucun
+	Children     []*CategoryNode
+}
+
+// CategoryActivity contient les statistiques d'une catégorie et de ses sous-catégories
+type CategoryActivity struct {
+	PostCount    int
+	LastActivity time.Time
+}
+
+// permissionRank classe les droits de publication du plus ouvert au plus restrictif
+var permissionRank = map[string]int{
+	CategoryOpen:           0,
+	CategoryModeratorsOnly: 1,
+	CategoryReadOnly:       2,
+}
+
+// readRank classe les droits de lecture du plus ouvert au plus restrictif
+var readRank = map[string]int{
+	CategoryReadEveryone: 0,
+	CategoryReadMembers:  1,
+	CategoryReadStaff:    2,
+}
+
+// TreePrefix renvoie le décalage affiché devant le nom d'une catégorie dans les listes déroulantes
+func (c Category) TreePrefix() string {
+	return strings.Repeat("— ", c.Depth)
+}
+
+// SortCategoryTree range les catégories en profondeur d'abord: chaque catégorie est suivie de
+// ses sous-catégories, les sœurs étant classées par position. Depth est rempli
+// Une catégorie dont le parent est absent de la liste est traitée comme une racine
+func SortCategoryTree(categories []Category) []Category {
+	byID := map[int]bool{}
+	for _, category := range categories {
+		byID[category.ID] = true
+	}
+
+	children := map[int][]Category{}
+	for _, category := range categories {
+		parentID := category.ParentID
+		if !byID[parentID] {
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], category)
+	}
+	for _, siblings := range children {
+		sort.SliceStable(siblings, func(i, j int) bool {
+			if siblings[i].Position != siblings[j].Position {
+				return siblings[i].Position < siblings[j].Position
+			}
+			return siblings[i].ID < siblings[j].ID
+		})
+	}
+
+	sorted := make([]Category, 0, len(categories))
+	visited := map[int]bool{}
+	var walk func(parentID, depth int)
+	walk = func(parentID, depth int) {
+		for _, category := range children[parentID] {
+			// Protection contre un cycle éventuel dans les données
+			if visited[category.ID] {
+				continue
+			}
+			visited[category.ID] = true
+			category.Depth = depth
+			sorted = append(sorted, category)
+			walk(category.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+
+	// Les catégories prises dans un cycle ne sont atteintes depuis aucune racine: elles sont ajoutées à la fin
+	for _, category := range categories {
+		if !visited[category.ID] {
+			visited[category.ID] = true
+			category.Depth = 0
+			sorted = append(sorted, category)
+			walk(category.ID, 1)
+		}
+	}
+	return sorted
+}
+
+// InheritCategorySettings applique à chaque catégorie les réglages de ses ancêtres:
+// une catégorie est archivée si l'un de ses ancêtres l'est, et ses droits de publication et de lecture
+// sont les plus restrictifs de sa branche. Cela permet par exemple une sous-catégorie réservée
+// à l'équipe dont toutes les sous-catégories le sont aussi
+func InheritCategorySettings(categories []Category) []Category {
+	byID := map[int]Category{}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	result := make([]Category, len(categories))
+	for i, category := range categories {
+		seen := map[int]bool{category.ID: true}
+		for parent, ok := byID[category.ParentID]; ok && !seen[parent.ID]; parent, ok = byID[parent.ParentID] {
+			seen[parent.ID] = true
+			if parent.Archived {
+				category.Archived = true
+			}
+			if permissionRank[parent.PostPermission] > permissionRank[category.PostPermission] {
+				category.PostPermission = parent.PostPermission
+			}
+			if readRank[parent.ReadPermission] > readRank[category.ReadPermission] {
+				category.ReadPermission = parent.ReadPermission
+			}
+		}
+		result[i] = category
+	}
+	return result
+}
+
+// HiddenCategoryIDs renvoie les IDs des catégories qu'un utilisateur de ce rôle ne peut pas lire,
+// droits hérités des catégories parentes compris. Un post rangé dans l'une d'elles lui est caché
+func HiddenCategoryIDs(categories []Category, role string) []int {
+	var hidden []int
+	for _, category := range InheritCategorySettings(categories) {
+		if !category.AllowsReadFrom(role) {
+			hidden = append(hidden, category.ID)
+		}
+	}
+	return hidden
+}
+
+// CanReadPost indique si un utilisateur de ce rôle peut lire un post rangé dans ces catégories:
+// il doit pouvoir lire chacune d'elles
+func CanReadPost(categories []Category, postCategoryIDs []int, role string) bool {
+	hidden := map[int]bool{}
+	for _, id := range HiddenCategoryIDs(categories, role) {
+		hidden[id] = true
+	}
+	for _, id := range postCategoryIDs {
+		if hidden[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// CategoryDescendants renvoie l'ID de la catégorie suivi des IDs de toutes ses sous-catégories
+func CategoryDescendants(categories []Category, categoryID int) []int {
+	ids := []int{categoryID}
+	seen := map[int]bool{categoryID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, category := range categories {
+			if category.ParentID == ids[i] && !seen[category.ID] {
+				seen[category.ID] = true
+				ids = append(ids, category.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// CategoryPath renvoie la branche d'une catégorie, de la racine jusqu'à la catégorie elle-même
+func CategoryPath(categories []Category, categoryID int) []Category {
+	byID := map[int]Category{}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	var path []Category
+	seen := map[int]bool{}
+	for category, ok := byID[categoryID]; ok && !seen[category.ID]; category, ok = byID[category.ParentID] {
+		seen[category.ID] = true
+		path = append([]Category{category}, path...)
+	}
+	return path
+}
+
+// CategoryParentError vérifie que parentID peut devenir le parent de la catégorie categoryID
+// (0 pour une catégorie qui n'existe pas encore): le parent doit exister et ne peut être
+// ni la catégorie elle-même ni l'une de ses sous-catégories
+func CategoryParentError(categories []Category, categoryID, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	found := false
+	for _, category := range categories {
+		if category.ID == parentID {
+			found = true
+		}
+	}
+	if !found {
+		return ErrParentCategoryNotFound
+	}
+	if categoryID == 0 {
+		return nil
+	}
+	for _, id := range CategoryDescendants(categories, categoryID) {
+		if id == parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// BuildCategoryTree construit l'arbre des catégories avec leurs statistiques
+// Les catégories doivent être rangées par SortCategoryTree
+func BuildCategoryTree(categories []Category, activity map[int]CategoryActivity) []*CategoryNode {
+	nodes := map[int]*CategoryNode{}
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := &CategoryNode{
+			Category:     category,
+			PostCount:    activity[category.ID].PostCount,
+			LastActivity: activity[category.ID].LastActivity,
+		}
+		nodes[category.ID] = node
+		if parent, ok := nodes[category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// FindCategoryNode cherche une catégorie dans l'arbre
+func FindCategoryNode(nodes []*CategoryNode, categoryID int) *CategoryNode {
+	for _, node := range nodes {
+		if node.ID == categoryID {
+			return node
+		}
+		if found := FindCategoryNode(node.Children, categoryID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// GetCategoryTree renvoie l'arbre des catégories visibles par un utilisateur de ce rôle, avec pour chacune
+// le nombre de posts et la date de la dernière activité, sous-catégories comprises. Les posts que le rôle
+// ne peut pas lire ne sont pas comptés
+func GetCategoryTree(ctx context.Context, role string) ([]*CategoryNode, error) {
+	all, err := GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories := VisibleCategories(all, role)
+	readable, readableArgs := readablePostsCondition(all, role)
+
+	// La table tree associe chaque catégorie à elle-même et à toutes ses sous-catégories,
+	// pour compter une seule fois un post présent dans plusieurs catégories d'une même branche
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		WITH RECURSIVE tree(ancestor_id, category_id) AS (
+			SELECT id, id FROM categories
+			UNION
+			SELECT tree.ancestor_id, c.id FROM categories c JOIN tree ON c.parent_id = tree.category_id
+		)
+		SELECT tree.ancestor_id, COUNT(DISTINCT p.id),
+			MAX(COALESCE((SELECT MAX(cm.created_at) FROM comments cm WHERE cm.post_id = p.id), p.created_at), p.created_at)
+		FROM tree
+		JOIN post_categories pc ON pc.category_id = tree.category_id
+		JOIN posts p ON p.id = pc.post_id
+		WHERE `+readable+`
+		GROUP BY tree.ancestor_id`, readableArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := map[int]CategoryActivity{}
+	for rows.Next() {
+		var categoryID int
+		var stats CategoryActivity
+		var lastActivity sql.NullString
+		if err := rows.Scan(&categoryID, &stats.PostCount, &lastActivity); err != nil {
+			return nil, err
+		}
+		stats.LastActivity = parseTimestamp(lastActivity.String)
+		activity[categoryID] = stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCategoryTree(categories, activity), nil
+}
+
+// parseTimestamp lit une date renvoyée par une fonction d'agrégat de SQLite, qui perd le type de la colonne
+func parseTimestamp(value string) time.Time {
+	value = strings.TrimSuffix(value, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// GetCategoryPath renvoie la branche d'une catégorie, de la racine jusqu'à la catégorie elle-même
+func GetCategoryPath(ctx context.Context, categoryID int) ([]Category, error) {
+	categories, err := GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := CategoryPath(categories, categoryID)
+	if len(path) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+	return path, nil
+}
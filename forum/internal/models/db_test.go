@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"forum/internal/database"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// countingDriverName est le pilote SQLite des tests: il compte les requêtes envoyées à la base
+const countingDriverName = "sqlite3_counting"
+
+// queryCount est le nombre de requêtes exécutées par les deux pools depuis le début des tests
+var queryCount atomic.Int64
+
+func init() {
+	sql.Register(countingDriverName, countingDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// countingDriver enveloppe le pilote SQLite pour compter les requêtes
+type countingDriver struct {
+	*sqlite3.SQLiteDriver
+}
+
+func (d countingDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// countingConn compte chaque requête avant de la passer à la connexion SQLite
+type countingConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+// countQueries renvoie le nombre de requêtes exécutées par fn
+func countQueries(fn func()) int64 {
+	before := queryCount.Load()
+	fn()
+	return queryCount.Load() - before
+}
+
+// openTestDB crée une base vide dans un dossier temporaire, avec toutes les migrations appliquées
+// La base est fermée à la fin du test
+func openTestDB(tb testing.TB) {
+	tb.Helper()
+	config := database.DefaultConfig(filepath.Join(tb.TempDir(), "forum.db"))
+	config.Driver = countingDriverName
+	if err := database.InitDB(config); err != nil {
+		tb.Fatalf("opening test database: %v", err)
+	}
+	tb.Cleanup(func() {
+		database.CloseDB()
+	})
+}
+
+// createTestUser inscrit un utilisateur et renvoie son ID
+func createTestUser(tb testing.TB, username string) int {
+	tb.Helper()
+	ctx := context.Background()
+	if err := RegisterUser(ctx, username+"@example.com", username, "password123"); err != nil {
+		tb.Fatalf("registering %s: %v", username, err)
+	}
+	user, err := GetUserByEmail(ctx, username+"@example.com")
+	if err != nil {
+		tb.Fatalf("fetching %s: %v", username, err)
+	}
+	return user.ID
+}
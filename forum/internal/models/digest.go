@@ -0,0 +1,273 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fréquences des résumés d'activité envoyés par email
+const (
+	DigestNone   = "none"   // Pas de résumé
+	DigestDaily  = "daily"  // Un résumé par jour
+	DigestWeekly = "weekly" // Un résumé par semaine
+)
+
+// DigestMaxItems est le nombre maximal d'éléments de chaque rubrique d'un résumé
+const DigestMaxItems = 20
+
+// digestExcerptLength est la longueur maximale, en caractères, de l'extrait d'un élément
+const digestExcerptLength = 200
+
+// ValidDigestFrequency indique si frequency est une fréquence de résumé connue
+func ValidDigestFrequency(frequency string) bool {
+	return frequency == DigestNone || frequency == DigestDaily || frequency == DigestWeekly
+}
+
+// DigestPeriod renvoie la durée entre deux résumés d'une fréquence, 0 quand il n'y a pas de résumé
+func DigestPeriod(frequency string) time.Duration {
+	switch frequency {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// DigestRecipient est un utilisateur dont le résumé doit être envoyé
+type DigestRecipient struct {
+	UserID    int
+	Username  string
+	Email     string
+	Frequency string
+	Since     time.Time // Début de la période couverte: fin du résumé précédent
+}
+
+// DigestItem est un post ou un commentaire cité dans un résumé
+type DigestItem struct {
+	PostID     int
+	PostTitle  string
+	CommentID  int    // 0 quand l'élément est le post lui-même
+	AuthorName string // Vide si l'auteur a été supprimé
+	Excerpt    string // Début du texte, sans retours à la ligne
+	CreatedAt  time.Time
+}
+
+// Path renvoie l'adresse de l'élément sur le forum, sans le nom de domaine
+func (i *DigestItem) Path() string {
+	path := "/post/" + strconv.Itoa(i.PostID)
+	if i.CommentID > 0 {
+		path += "#comment-" + strconv.Itoa(i.CommentID)
+	}
+	return path
+}
+
+// Digest regroupe l'activité d'une période qui concerne un utilisateur
+type Digest struct {
+	Posts    []*DigestItem // Nouveaux posts des catégories suivies
+	Replies  []*DigestItem // Nouveaux commentaires sur les posts de l'utilisateur
+	Mentions []*DigestItem // Mentions de l'utilisateur avec @nom
+}
+
+// IsEmpty indique si le résumé ne contient rien: il n'est alors pas envoyé
+func (d *Digest) IsEmpty() bool {
+	return len(d.Posts) == 0 && len(d.Replies) == 0 && len(d.Mentions) == 0
+}
+
+// DigestExcerpt renvoie le début d'un texte sur une seule ligne, pour un résumé
+func DigestExcerpt(content string) string {
+	excerpt := strings.Join(strings.Fields(content), " ")
+	runes := []rune(excerpt)
+	if len(runes) <= digestExcerptLength {
+		return excerpt
+	}
+	return strings.TrimSpace(string(runes[:digestExcerptLength])) + "…"
+}
+
+// sqliteTime formate une date comme CURRENT_TIMESTAMP, pour la comparer aux dates de la base
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// SetDigestFrequency change la fréquence des résumés d'un utilisateur
+// Le premier résumé après une inscription ne couvre que l'activité qui suit ce choix
+func SetDigestFrequency(ctx context.Context, userID int, frequency string) error {
+	if !ValidDigestFrequency(frequency) {
+		return errors.New("invalid digest frequency")
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		UPDATE users SET
+			last_digest_at = CASE WHEN digest_frequency = 'none' OR last_digest_at IS NULL
+				THEN CURRENT_TIMESTAMP ELSE last_digest_at END,
+			digest_frequency = ?
+		WHERE id = ?`,
+		frequency, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetDueDigests renvoie les utilisateurs dont le résumé est dû à l'instant now
+func GetDueDigests(ctx context.Context, now time.Time) ([]*DigestRecipient, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT id, username, email, digest_frequency, last_digest_at
+		FROM users
+		WHERE digest_frequency != 'none'
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []*DigestRecipient{}
+	for rows.Next() {
+		recipient := &DigestRecipient{}
+		var lastDigest sql.NullTime
+		err = rows.Scan(&recipient.UserID, &recipient.Username, &recipient.Email, &recipient.Frequency, &lastDigest)
+		if err != nil {
+			return nil, err
+		}
+
+		period := DigestPeriod(recipient.Frequency)
+		if period == 0 || (lastDigest.Valid && now.Sub(lastDigest.Time) < period) {
+			continue
+		}
+		// Sans date de dernier envoi, le résumé couvre une période complète
+		recipient.Since = now.Add(-period)
+		if lastDigest.Valid {
+			recipient.Since = lastDigest.Time
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// queryDigestItems lit les éléments d'une rubrique de résumé
+// Chaque ligne contient: post_id, titre, comment_id, auteur, contenu et date
+func queryDigestItems(ctx context.Context, query string, args ...interface{}) ([]*DigestItem, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*DigestItem{}
+	for rows.Next() {
+		item := &DigestItem{}
+		var content string
+		err = rows.Scan(&item.PostID, &item.PostTitle, &item.CommentID, &item.AuthorName, &content, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Excerpt = DigestExcerpt(content)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// digestArgs complète les arguments d'une rubrique du résumé avec ceux de la condition de lecture et la limite
+func digestArgs(args, readableArgs []interface{}) []interface{} {
+	return append(append(args, readableArgs...), DigestMaxItems)
+}
+
+// GetDigest renvoie l'activité qui concerne un utilisateur entre since (exclu) et until (inclus):
+// les nouveaux posts des catégories qu'il suit, les commentaires sur ses posts et ses mentions
+// Les posts qu'il a rendus muets sont ignorés, et une réponse qui le mentionne n'apparaît que dans les mentions
+func GetDigest(ctx context.Context, userID int, since, until time.Time) (*Digest, error) {
+	from, to := sqliteTime(since), sqliteTime(until)
+	digest := &Digest{}
+
+	// Un post que l'utilisateur ne peut pas lire n'apparaît dans aucune rubrique
+	readable, readableArgs, err := viewerReadCondition(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Posts, err = queryDigestItems(ctx, `
+		SELECT p.id, p.title, 0, u.username, p.content, p.created_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id != ?
+			AND datetime(p.created_at) > datetime(?) AND datetime(p.created_at) <= datetime(?)
+			AND p.id IN (
+				SELECT pc.post_id FROM post_categories pc WHERE pc.category_id IN (
+					WITH RECURSIVE tree(id) AS (
+						SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'category' AND muted = 0
+						UNION
+						SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+					)
+					SELECT id FROM tree))
+			AND p.id NOT IN (SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'post' AND muted = 1)
+			AND `+readable+`
+		ORDER BY p.created_at, p.id
+		LIMIT ?`,
+		digestArgs([]interface{}{userID, from, to, userID, userID}, readableArgs)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Replies, err = queryDigestItems(ctx, `
+		SELECT p.id, p.title, c.id, u.username, c.content, c.created_at
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id
+		JOIN users u ON c.user_id = u.id
+		WHERE p.user_id = ? AND c.user_id != ?
+			AND datetime(c.created_at) > datetime(?) AND datetime(c.created_at) <= datetime(?)
+			AND p.id NOT IN (SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'post' AND muted = 1)
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.user_id = ? AND n.type = ? AND n.comment_id = c.id
+			)
+			AND `+readable+`
+		ORDER BY c.created_at, c.id
+		LIMIT ?`,
+		digestArgs([]interface{}{userID, userID, from, to, userID, userID, NotificationMention}, readableArgs)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Mentions, err = queryDigestItems(ctx, `
+		SELECT n.post_id, p.title, COALESCE(n.comment_id, 0), COALESCE(u.username, ''),
+			COALESCE(c.content, p.content), n.created_at
+		FROM notifications n
+		JOIN posts p ON n.post_id = p.id
+		LEFT JOIN comments c ON n.comment_id = c.id
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.user_id = ? AND n.type = ?
+			AND datetime(n.created_at) > datetime(?) AND datetime(n.created_at) <= datetime(?)
+			AND `+readable+`
+		ORDER BY n.created_at, n.id
+		LIMIT ?`,
+		digestArgs([]interface{}{userID, NotificationMention, from, to}, readableArgs)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// MarkDigestSent enregistre la fin de la période couverte par le dernier résumé d'un utilisateur
+func MarkDigestSent(ctx context.Context, userID int, until time.Time) error {
+	_, err := database.DB.ExecContext(ctx,
+		"UPDATE users SET last_digest_at = ? WHERE id = ?",
+		sqliteTime(until), userID,
+	)
+	return err
+}
@@ -0,0 +1,306 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDraftsPerUser limite le nombre de brouillons d'un utilisateur, brouillons programmés compris
+const MaxDraftsPerUser = 50
+
+// Erreurs des brouillons, qui viennent de la saisie de l'utilisateur
+var (
+	ErrDraftEmpty            = errors.New("draft is empty")
+	ErrTooManyDrafts         = errors.New("too many drafts")
+	ErrDraftNotFound         = errors.New("draft not found")
+	ErrPublishTimeInPast     = errors.New("publish time must be in the future")
+	ErrOnlyNewPostsScheduled = errors.New("only new posts can be scheduled")
+)
+
+// Draft est un brouillon de post, enregistré automatiquement pendant la saisie
+type Draft struct {
+	ID           int
+	UserID       int
+	PostID       int // Post publié dont le brouillon prépare la modification, 0 pour un nouveau post
+	Title        string
+	Content      string
+	CategoryIDs  []int
+	Tags         []string     // Tags normalisés (voir ParseTags)
+	PublishAt    sql.NullTime // Publication programmée, absente pour un simple brouillon
+	PublishError string       // Raison du refus de la dernière publication programmée
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// draftColumns sont les colonnes lues par scanDraft
+const draftColumns = `id, user_id, COALESCE(post_id, 0), title, content, categories, tags,
+	publish_at, publish_error, created_at, updated_at`
+
+// scanDraft lit les colonnes draftColumns
+func scanDraft(row rowScanner) (*Draft, error) {
+	draft := &Draft{}
+	var categories, tags string
+	err := row.Scan(&draft.ID, &draft.UserID, &draft.PostID, &draft.Title, &draft.Content, &categories, &tags,
+		&draft.PublishAt, &draft.PublishError, &draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	draft.CategoryIDs = splitIDs(categories)
+	draft.Tags = splitPendingTags(tags)
+	return draft, nil
+}
+
+// splitIDs relit une liste d'IDs séparés par des virgules
+func splitIDs(ids string) []int {
+	var result []int
+	for _, id := range strings.Split(ids, ",") {
+		if value, err := strconv.Atoi(id); err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// joinIDs écrit une liste d'IDs séparés par des virgules
+func joinIDs(ids []int) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// HasCategory indique si le brouillon est rangé dans la catégorie
+func (d *Draft) HasCategory(categoryID int) bool {
+	for _, id := range d.CategoryIDs {
+		if id == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveDraft enregistre un brouillon et renvoie son ID. Un brouillon d'ID 0 est créé, sauf le brouillon
+// de modification d'un post qui remplace celui que l'utilisateur a déjà pour ce post. Un brouillon
+// existant n'est mis à jour que s'il appartient à l'utilisateur. La programmation n'est pas modifiée
+// (voir ScheduleDraft)
+func SaveDraft(ctx context.Context, draft *Draft) (int, error) {
+	if strings.TrimSpace(draft.Title) == "" && strings.TrimSpace(draft.Content) == "" {
+		return 0, ErrDraftEmpty
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	draftID := draft.ID
+	if draftID == 0 && draft.PostID > 0 {
+		var count int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE id = ?", draft.PostID).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, ErrPostNotFound
+		}
+		err = tx.QueryRowContext(ctx,
+			"SELECT id FROM drafts WHERE user_id = ? AND post_id = ?", draft.UserID, draft.PostID).Scan(&draftID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	if draftID == 0 {
+		var count int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM drafts WHERE user_id = ?", draft.UserID).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		if count >= MaxDraftsPerUser {
+			return 0, ErrTooManyDrafts
+		}
+
+		var postID interface{}
+		if draft.PostID > 0 {
+			postID = draft.PostID
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO drafts (user_id, post_id, title, content, categories, tags)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			draft.UserID, postID, draft.Title, draft.Content, joinIDs(draft.CategoryIDs), strings.Join(draft.Tags, ","))
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		draftID = int(id)
+	} else {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE drafts SET title = ?, content = ?, categories = ?, tags = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND user_id = ?`,
+			draft.Title, draft.Content, joinIDs(draft.CategoryIDs), strings.Join(draft.Tags, ","), draftID, draft.UserID)
+		if err != nil {
+			return 0, err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if updated == 0 {
+			return 0, ErrDraftNotFound
+		}
+	}
+
+	return draftID, tx.Commit()
+}
+
+// GetDraft renvoie un brouillon de l'utilisateur
+func GetDraft(ctx context.Context, draftID, userID int) (*Draft, error) {
+	draft, err := scanDraft(database.ReadDB.QueryRowContext(ctx,
+		"SELECT "+draftColumns+" FROM drafts WHERE id = ? AND user_id = ?", draftID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDraftNotFound
+	}
+	return draft, err
+}
+
+// GetPostDraft renvoie le brouillon de modification d'un post préparé par l'utilisateur
+func GetPostDraft(ctx context.Context, postID, userID int) (*Draft, error) {
+	draft, err := scanDraft(database.ReadDB.QueryRowContext(ctx,
+		"SELECT "+draftColumns+" FROM drafts WHERE post_id = ? AND user_id = ?", postID, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDraftNotFound
+	}
+	return draft, err
+}
+
+// GetUserDrafts renvoie les brouillons d'un utilisateur, des plus récemment modifiés aux plus anciens
+func GetUserDrafts(ctx context.Context, userID int) ([]*Draft, error) {
+	return queryDrafts(ctx,
+		"SELECT "+draftColumns+" FROM drafts WHERE user_id = ? ORDER BY updated_at DESC, id DESC", userID)
+}
+
+// GetDueDrafts renvoie les brouillons programmés dont l'heure de publication est passée à l'instant now
+func GetDueDrafts(ctx context.Context, now time.Time) ([]*Draft, error) {
+	return queryDrafts(ctx, `
+		SELECT `+draftColumns+` FROM drafts
+		WHERE publish_at IS NOT NULL AND datetime(publish_at) <= datetime(?)
+		ORDER BY publish_at, id`, sqliteTime(now))
+}
+
+// queryDrafts lit les brouillons renvoyés par une requête qui sélectionne draftColumns
+func queryDrafts(ctx context.Context, query string, args ...interface{}) ([]*Draft, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drafts := []*Draft{}
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, rows.Err()
+}
+
+// DeleteDraft supprime un brouillon de l'utilisateur
+func DeleteDraft(ctx context.Context, draftID, userID int) error {
+	result, err := database.DB.ExecContext(ctx, "DELETE FROM drafts WHERE id = ? AND user_id = ?", draftID, userID)
+	if err != nil {
+		return err
+	}
+	return expectDraft(result)
+}
+
+// deleteDraftTx supprime un brouillon dans la transaction qui publie son post
+func deleteDraftTx(ctx context.Context, tx *sql.Tx, draftID, userID int) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM drafts WHERE id = ? AND user_id = ?", draftID, userID)
+	if err != nil {
+		return err
+	}
+	return expectDraft(result)
+}
+
+// expectDraft renvoie ErrDraftNotFound si la requête n'a touché aucun brouillon
+func expectDraft(result sql.Result) error {
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+// DeletePostDraft supprime le brouillon de modification d'un post, s'il y en a un
+func DeletePostDraft(ctx context.Context, postID, userID int) error {
+	_, err := database.DB.ExecContext(ctx, "DELETE FROM drafts WHERE post_id = ? AND user_id = ?", postID, userID)
+	return err
+}
+
+// ScheduleDraft programme la publication d'un brouillon de nouveau post à publishAt, ou retire sa
+// programmation si publishAt est zéro. L'erreur d'une publication précédente est effacée
+func ScheduleDraft(ctx context.Context, draftID, userID int, publishAt, now time.Time) error {
+	var value interface{}
+	if !publishAt.IsZero() {
+		if !publishAt.After(now) {
+			return ErrPublishTimeInPast
+		}
+		value = sqliteTime(publishAt)
+	}
+
+	var postID int
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(post_id, 0) FROM drafts WHERE id = ? AND user_id = ?", draftID, userID).Scan(&postID)
+	if err == sql.ErrNoRows {
+		return ErrDraftNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if postID != 0 && value != nil {
+		return ErrOnlyNewPostsScheduled
+	}
+
+	_, err = database.DB.ExecContext(ctx,
+		"UPDATE drafts SET publish_at = ?, publish_error = '' WHERE id = ?", value, draftID)
+	return err
+}
+
+// ClaimScheduledDraft retire la programmation d'un brouillon dû à l'instant now avant sa publication
+// Renvoie false si le brouillon n'est plus programmé à cette heure: il a été supprimé, reprogrammé ou
+// déjà pris en charge. Un brouillon pris en charge dont la publication échoue reste un simple brouillon
+func ClaimScheduledDraft(ctx context.Context, draftID int, now time.Time) (bool, error) {
+	result, err := database.DB.ExecContext(ctx, `
+		UPDATE drafts SET publish_at = NULL
+		WHERE id = ? AND publish_at IS NOT NULL AND datetime(publish_at) <= datetime(?)`,
+		draftID, sqliteTime(now))
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return claimed > 0, nil
+}
+
+// SetDraftPublishError enregistre la raison du refus d'une publication programmée
+func SetDraftPublishError(ctx context.Context, draftID int, message string) error {
+	_, err := database.DB.ExecContext(ctx, "UPDATE drafts SET publish_error = ? WHERE id = ?", message, draftID)
+	return err
+}
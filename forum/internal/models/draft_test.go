@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"forum/internal/database"
+	"reflect"
+	"testing"
+)
+
+// saveTestDraft enregistre un brouillon de nouveau post et renvoie son ID
+func saveTestDraft(t *testing.T, userID int, categoryIDs ...int) int {
+	t.Helper()
+	draftID, err := SaveDraft(context.Background(), &Draft{UserID: userID, Title: "Brouillon", Content: "Contenu", CategoryIDs: categoryIDs})
+	if err != nil {
+		t.Fatalf("saving draft: %v", err)
+	}
+	return draftID
+}
+
+// Le brouillon publié disparaît avec la création du post, et un brouillon introuvable ne publie rien
+func TestPublishDraftIsAtomic(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	otherID := createTestUser(t, "other")
+
+	draftID := saveTestDraft(t, authorID, 1)
+	post := NewPost{Title: "Publié", Content: "Contenu", UserID: authorID, CategoryIDs: []int{1}, Tags: []string{"golang"}, DraftID: draftID}
+	if _, err := CreatePostWithDetails(ctx, post); err != nil {
+		t.Fatalf("publishing draft: %v", err)
+	}
+	if _, err := GetDraft(ctx, draftID, authorID); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("published draft: got error %v, want ErrDraftNotFound", err)
+	}
+
+	// Publier de nouveau le même brouillon, ou le brouillon d'un autre, échoue sans rien publier
+	otherDraft := saveTestDraft(t, otherID, 1)
+	for _, id := range []int{draftID, otherDraft} {
+		post.DraftID = id
+		if _, err := CreatePostWithDetails(ctx, post); !errors.Is(err, ErrDraftNotFound) {
+			t.Errorf("publishing draft %d: got error %v, want ErrDraftNotFound", id, err)
+		}
+		if _, err := SubmitPendingPost(ctx, post); !errors.Is(err, ErrDraftNotFound) {
+			t.Errorf("submitting draft %d: got error %v, want ErrDraftNotFound", id, err)
+		}
+	}
+
+	pendingDraft := saveTestDraft(t, authorID, 1)
+	post.DraftID = pendingDraft
+	if _, err := SubmitPendingPost(ctx, post); err != nil {
+		t.Fatalf("submitting draft: %v", err)
+	}
+
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM posts":         1,
+		"SELECT COUNT(*) FROM pending_posts": 1,
+		"SELECT COUNT(*) FROM drafts":        1,
+	} {
+		var n int
+		if err := database.DB.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if n != want {
+			t.Errorf("%s: got %d, want %d", query, n, want)
+		}
+	}
+}
+
+// Les brouillons rangés dans la catégorie fusionnée le sont dans la cible, sans doublon
+func TestMergeCategoriesMovesDrafts(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	userID := createTestUser(t, "author")
+	sourceID, err := CreateCategory(ctx, Category{Name: "Source"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	targetID, err := CreateCategory(ctx, Category{Name: "Cible"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	moved := saveTestDraft(t, userID, 1, sourceID)
+	both := saveTestDraft(t, userID, sourceID, targetID)
+	untouched := saveTestDraft(t, userID, 1)
+	if err := MergeCategories(ctx, sourceID, targetID); err != nil {
+		t.Fatalf("merging categories: %v", err)
+	}
+
+	for draftID, want := range map[int][]int{moved: {1, targetID}, both: {targetID}, untouched: {1}} {
+		draft, err := GetDraft(ctx, draftID, userID)
+		if err != nil {
+			t.Fatalf("fetching draft %d: %v", draftID, err)
+		}
+		if !reflect.DeepEqual(draft.CategoryIDs, want) {
+			t.Errorf("draft %d categories: got %v, want %v", draftID, draft.CategoryIDs, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"forum/internal/database"
@@ -16,33 +17,11 @@ type Image struct {
 	CreatedAt time.Time
 }
 
-// InitImageTables initialise les tables pour la gestion des images
-func InitImageTables() error {
-	// Création de la table pour les images
-	imagesTable := `
-	CREATE TABLE IF NOT EXISTS images (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		filename TEXT NOT NULL,
-		user_id INTEGER NOT NULL,
-		post_id INTEGER,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id),
-		FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL
-	);`
-	
-	_, err := database.DB.Exec(imagesTable)
-	if err != nil {
-		return err
-	}
-	
-	return nil
-}
-
 // GetPostImage récupère l'image associée à un post
-func GetPostImage(postID int) (*Image, error) {
+func GetPostImage(ctx context.Context, postID int) (*Image, error) {
 	image := &Image{}
 	
-	err := database.DB.QueryRow(`
+	err := database.ReadDB.QueryRowContext(ctx, `
 		SELECT id, filename, user_id, post_id, created_at
 		FROM images
 		WHERE post_id = ?
@@ -60,15 +39,15 @@ func GetPostImage(postID int) (*Image, error) {
 }
 
 // DeletePostImage supprime l'association entre une image et un post
-func DeletePostImage(postID int) error {
+func DeletePostImage(ctx context.Context, postID int) error {
 	// Récupérer l'image
-	image, err := GetPostImage(postID)
+	image, err := GetPostImage(ctx, postID)
 	if err != nil {
 		return err
 	}
 	
 	// Mettre à jour l'enregistrement pour supprimer l'association
-	_, err = database.DB.Exec("UPDATE images SET post_id = NULL WHERE id = ?", image.ID)
+	_, err = database.DB.ExecContext(ctx, "UPDATE images SET post_id = NULL WHERE id = ?", image.ID)
 	if err != nil {
 		return err
 	}
@@ -77,14 +56,14 @@ func DeletePostImage(postID int) error {
 }
 
 // SaveImage enregistre une nouvelle image dans la base de données
-func SaveImage(filename string, userID int) (int, error) {
+func SaveImage(ctx context.Context, filename string, userID int) (int, error) {
 	// Vérifier les entrées
 	if filename == "" {
 		return 0, errors.New("filename is required")
 	}
 	
 	// Insérer l'image dans la base de données
-	result, err := database.DB.Exec(
+	result, err := database.DB.ExecContext(ctx, 
 		"INSERT INTO images (filename, user_id) VALUES (?, ?)",
 		filename, userID,
 	)
@@ -102,10 +81,10 @@ func SaveImage(filename string, userID int) (int, error) {
 }
 
 // GetImageByID récupère une image par son ID
-func GetImageByID(imageID string) (*Image, error) {
+func GetImageByID(ctx context.Context, imageID string) (*Image, error) {
 	image := &Image{}
 	
-	err := database.DB.QueryRow(`
+	err := database.ReadDB.QueryRowContext(ctx, `
 		SELECT id, filename, user_id, post_id, created_at
 		FROM images
 		WHERE id = ?
@@ -122,8 +101,8 @@ func GetImageByID(imageID string) (*Image, error) {
 }
 
 // GetImagesByPostID récupère toutes les images associées à un post
-func GetImagesByPostID(postID int) ([]*Image, error) {
-	rows, err := database.DB.Query(`
+func GetImagesByPostID(ctx context.Context, postID int) ([]*Image, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
 		SELECT id, filename, user_id, post_id, created_at
 		FROM images
 		WHERE post_id = ?
@@ -149,8 +128,8 @@ func GetImagesByPostID(postID int) ([]*Image, error) {
 }
 
 // GetImagesByUserID récupère toutes les images téléchargées par un utilisateur
-func GetImagesByUserID(userID int) ([]*Image, error) {
-	rows, err := database.DB.Query(`
+func GetImagesByUserID(ctx context.Context, userID int) ([]*Image, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
 		SELECT id, filename, user_id, post_id, created_at
 		FROM images
 		WHERE user_id = ?
@@ -176,10 +155,10 @@ func GetImagesByUserID(userID int) ([]*Image, error) {
 }
 
 // AssociateImageWithPost associe une image à un post
-func AssociateImageWithPost(imageID, postID int) error {
+func AssociateImageWithPost(ctx context.Context, imageID, postID int) error {
 	// Vérifier si l'image existe
 	var count int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM images WHERE id = ?", imageID).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM images WHERE id = ?", imageID).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -188,16 +167,16 @@ func AssociateImageWithPost(imageID, postID int) error {
 	}
 	
 	// Vérifier si le post existe
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ?", postID).Scan(&count)
+	err = database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE id = ?", postID).Scan(&count)
 	if err != nil {
 		return err
 	}
 	if count == 0 {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 	
 	// Associer l'image au post
-	_, err = database.DB.Exec("UPDATE images SET post_id = ? WHERE id = ?", postID, imageID)
+	_, err = database.DB.ExecContext(ctx, "UPDATE images SET post_id = ? WHERE id = ?", postID, imageID)
 	if err != nil {
 		return err
 	}
@@ -206,12 +185,12 @@ func AssociateImageWithPost(imageID, postID int) error {
 }
 
 // DeleteImage supprime une image
-func DeleteImage(imageID, userID int, isAdmin bool) error {
+func DeleteImage(ctx context.Context, imageID, userID int, isAdmin bool) error {
 	// Vérifier si l'image existe et si l'utilisateur a le droit de la supprimer
 	var ownerID int
 	var filename string
 	
-	err := database.DB.QueryRow("SELECT user_id, filename FROM images WHERE id = ?", imageID).Scan(&ownerID, &filename)
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT user_id, filename FROM images WHERE id = ?", imageID).Scan(&ownerID, &filename)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New("image not found")
@@ -225,7 +204,7 @@ func DeleteImage(imageID, userID int, isAdmin bool) error {
 	}
 	
 	// Supprimer l'image de la base de données
-	_, err = database.DB.Exec("DELETE FROM images WHERE id = ?", imageID)
+	_, err = database.DB.ExecContext(ctx, "DELETE FROM images WHERE id = ?", imageID)
 	if err != nil {
 		return err
 	}
@@ -234,8 +213,8 @@ func DeleteImage(imageID, userID int, isAdmin bool) error {
 }
 
 // GetUnassociatedImagesByUserID récupère les images d'un utilisateur qui ne sont pas associées à un post
-func GetUnassociatedImagesByUserID(userID int) ([]*Image, error) {
-	rows, err := database.DB.Query(`
+func GetUnassociatedImagesByUserID(ctx context.Context, userID int) ([]*Image, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
 		SELECT id, filename, user_id, post_id, created_at
 		FROM images
 		WHERE user_id = ? AND post_id IS NULL
@@ -0,0 +1,676 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limites des messages privés
+const (
+	MaxConversationMembers  = 10   // Nombre maximal de membres d'une conversation, créateur compris
+	MaxMessageLength        = 5000 // Nombre maximal de caractères d'un message
+	MaxMessageRetentionDays = 3650 // Durée de conservation maximale qu'un administrateur peut choisir
+)
+
+// Erreurs des messages privés qui viennent de la saisie ou des droits de l'utilisateur
+var (
+	ErrMessageEmpty          = errors.New("message is empty")
+	ErrMessageTooLong        = errors.New("message is too long")
+	ErrUserBlocked           = errors.New("user blocked")
+	ErrConversationTooSmall  = errors.New("conversation needs another member")
+	ErrConversationTooLarge  = errors.New("too many conversation members")
+	ErrConversationAbandoned = errors.New("conversation has no other member")
+	ErrConversationNotFound  = errors.New("conversation not found")
+	ErrMessageNotFound       = errors.New("message not found")
+	ErrCannotBlockYourself   = errors.New("cannot block yourself")
+	ErrBlockNotFound         = errors.New("block not found")
+)
+
+// messageRetentionSetting est la clé de la durée de conservation des messages dans la table settings
+const messageRetentionSetting = "message_retention_days"
+
+// ConversationMember est un membre d'une conversation qui ne l'a pas quittée
+type ConversationMember struct {
+	UserID   int
+	Username string
+}
+
+// Conversation est une conversation privée vue par l'un de ses membres
+type Conversation struct {
+	ID            int
+	Members       []ConversationMember // Membres présents, y compris l'utilisateur qui la consulte
+	Title         string               // Noms des autres membres, séparés par des virgules
+	LastMessage   string               // Début du dernier message, pour la boîte de réception
+	LastMessageAt time.Time
+	UnreadCount   int // Messages des autres membres que l'utilisateur n'a pas encore lus
+	CreatedAt     time.Time
+}
+
+// IsGroup indique si la conversation a plus de deux membres
+func (c *Conversation) IsGroup() bool {
+	return len(c.Members) > 2
+}
+
+// HasMember indique si un utilisateur fait partie des membres présents
+func (c *Conversation) HasMember(userID int) bool {
+	for _, member := range c.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Message est un message d'une conversation privée
+type Message struct {
+	ID             int
+	ConversationID int
+	UserID         int    // 0 si l'auteur a supprimé son compte
+	Username       string // Vide si l'auteur a supprimé son compte
+	Content        string
+	CreatedAt      time.Time
+}
+
+// BlockedUser est un utilisateur bloqué par un autre
+type BlockedUser struct {
+	UserID    int
+	Username  string
+	CreatedAt time.Time
+}
+
+// ValidMessageContent vérifie qu'un message n'est ni vide ni trop long
+func ValidMessageContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return ErrMessageEmpty
+	}
+	if len([]rune(content)) > MaxMessageLength {
+		return ErrMessageTooLong
+	}
+	return nil
+}
+
+// conversationTitle renvoie les noms des membres autres que userID
+func conversationTitle(members []ConversationMember, userID int) string {
+	var names []string
+	for _, member := range members {
+		if member.UserID != userID {
+			names = append(names, member.Username)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkBlocks refuse un échange entre un utilisateur et d'autres si l'un d'eux bloque l'autre
+func checkBlocks(ctx context.Context, q querier, userID int, otherIDs []int) error {
+	for _, otherID := range otherIDs {
+		var count int
+		err := q.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM user_blocks
+			WHERE (user_id = ? AND blocked_id = ?) OR (user_id = ? AND blocked_id = ?)`,
+			userID, otherID, otherID, userID,
+		).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserBlocked
+		}
+	}
+	return nil
+}
+
+// activeMembers renvoie les IDs des membres présents d'une conversation, sauf exceptID
+func activeMembers(ctx context.Context, tx *sql.Tx, conversationID, exceptID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT user_id FROM conversation_members WHERE conversation_id = ? AND left_at IS NULL AND user_id != ?",
+		conversationID, exceptID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// isActiveMember indique si un utilisateur fait partie d'une conversation et ne l'a pas quittée
+func isActiveMember(ctx context.Context, q querier, conversationID, userID int) (bool, error) {
+	var count int
+	err := q.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM conversation_members WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL",
+		conversationID, userID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// insertMessageTx ajoute un message à une conversation; son auteur l'a lu
+func insertMessageTx(ctx context.Context, tx *sql.Tx, conversationID, senderID int, content string) (int, error) {
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO messages (conversation_id, user_id, content) VALUES (?, ?, ?)",
+		conversationID, senderID, content,
+	)
+	if err != nil {
+		return 0, err
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE conversations SET last_message_at = CURRENT_TIMESTAMP WHERE id = ?", conversationID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE conversation_members SET last_read_message_id = ? WHERE conversation_id = ? AND user_id = ?",
+		messageID, conversationID, senderID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(messageID), nil
+}
+
+// CreateConversation démarre une conversation entre creatorID et les membres donnés, avec un premier message
+// Écrire à une seule personne avec qui une conversation à deux est déjà ouverte ajoute le message à celle-ci
+// Renvoie l'ID de la conversation
+func CreateConversation(ctx context.Context, creatorID int, memberIDs []int, content string) (int, error) {
+	if err := ValidMessageContent(content); err != nil {
+		return 0, err
+	}
+
+	// Sans doublon ni créateur
+	seen := map[int]bool{creatorID: true}
+	var others []int
+	for _, memberID := range memberIDs {
+		if !seen[memberID] {
+			seen[memberID] = true
+			others = append(others, memberID)
+		}
+	}
+	if len(others) == 0 {
+		return 0, ErrConversationTooSmall
+	}
+	if len(others)+1 > MaxConversationMembers {
+		return 0, ErrConversationTooLarge
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, memberID := range others {
+		var count int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = ?", memberID).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, ErrUserNotFound
+		}
+	}
+	if err := checkBlocks(ctx, tx, creatorID, others); err != nil {
+		return 0, err
+	}
+
+	// Une conversation à deux déjà ouverte entre les mêmes personnes est reprise
+	var conversationID int
+	if len(others) == 1 {
+		err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE((
+				SELECT conversation_id FROM conversation_members
+				GROUP BY conversation_id
+				HAVING COUNT(*) = 2
+					AND SUM(left_at IS NULL) = 2
+					AND SUM(user_id = ?) = 1
+					AND SUM(user_id = ?) = 1
+				ORDER BY conversation_id DESC LIMIT 1
+			), 0)`, creatorID, others[0]).Scan(&conversationID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if conversationID == 0 {
+		result, err := tx.ExecContext(ctx, "INSERT INTO conversations (creator_id) VALUES (?)", creatorID)
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		conversationID = int(id)
+
+		for _, memberID := range append([]int{creatorID}, others...) {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO conversation_members (conversation_id, user_id) VALUES (?, ?)",
+				conversationID, memberID,
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err := insertMessageTx(ctx, tx, conversationID, creatorID, content); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return conversationID, nil
+}
+
+// SendMessage ajoute un message à une conversation dont l'expéditeur est membre
+// L'envoi est refusé si l'expéditeur et l'un des autres membres se bloquent
+func SendMessage(ctx context.Context, conversationID, senderID int, content string) (int, error) {
+	if err := ValidMessageContent(content); err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	member, err := isActiveMember(ctx, tx, conversationID, senderID)
+	if err != nil {
+		return 0, err
+	}
+	if !member {
+		return 0, ErrConversationNotFound
+	}
+
+	others, err := activeMembers(ctx, tx, conversationID, senderID)
+	if err != nil {
+		return 0, err
+	}
+	if len(others) == 0 {
+		return 0, ErrConversationAbandoned
+	}
+	if err := checkBlocks(ctx, tx, senderID, others); err != nil {
+		return 0, err
+	}
+
+	messageID, err := insertMessageTx(ctx, tx, conversationID, senderID, content)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return messageID, nil
+}
+
+// loadConversationMembers remplit les membres et le titre des conversations vues par userID
+func loadConversationMembers(ctx context.Context, conversations []*Conversation, userID int) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	byID := map[int]*Conversation{}
+	placeholders := make([]string, len(conversations))
+	args := make([]interface{}, len(conversations))
+	for i, conversation := range conversations {
+		byID[conversation.ID] = conversation
+		placeholders[i] = "?"
+		args[i] = conversation.ID
+	}
+
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT cm.conversation_id, cm.user_id, u.username
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.left_at IS NULL AND cm.conversation_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY u.username COLLATE NOCASE`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var conversationID int
+		var member ConversationMember
+		if err := rows.Scan(&conversationID, &member.UserID, &member.Username); err != nil {
+			return err
+		}
+		byID[conversationID].Members = append(byID[conversationID].Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, conversation := range conversations {
+		conversation.Title = conversationTitle(conversation.Members, userID)
+	}
+	return nil
+}
+
+// conversationColumns sont les colonnes lues par scanConversation, pour le membre cm
+const conversationColumns = `c.id, c.last_message_at, c.created_at,
+	COALESCE((SELECT content FROM messages WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1), ''),
+	(SELECT COUNT(*) FROM messages m
+		WHERE m.conversation_id = c.id AND m.id > cm.last_read_message_id
+			AND (m.user_id IS NULL OR m.user_id != cm.user_id))`
+
+func scanConversation(row rowScanner) (*Conversation, error) {
+	c := &Conversation{}
+	err := row.Scan(&c.ID, &c.LastMessageAt, &c.CreatedAt, &c.LastMessage, &c.UnreadCount)
+	if err != nil {
+		return nil, err
+	}
+	c.LastMessage = DigestExcerpt(c.LastMessage)
+	return c, nil
+}
+
+// GetConversations renvoie la boîte de réception d'un utilisateur, les conversations les plus récentes d'abord
+func GetConversations(ctx context.Context, userID int) ([]*Conversation, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+conversationColumns+`
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = ?
+		WHERE cm.left_at IS NULL
+		ORDER BY c.last_message_at DESC, c.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []*Conversation
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return conversations, loadConversationMembers(ctx, conversations, userID)
+}
+
+// GetConversation renvoie une conversation si l'utilisateur en est membre
+func GetConversation(ctx context.Context, conversationID, userID int) (*Conversation, error) {
+	conversation, err := scanConversation(database.ReadDB.QueryRowContext(ctx, `
+		SELECT `+conversationColumns+`
+		FROM conversations c
+		JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = ?
+		WHERE c.id = ? AND cm.left_at IS NULL`, userID, conversationID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if err := loadConversationMembers(ctx, []*Conversation{conversation}, userID); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// messageColumns sont les colonnes lues par scanMessage
+const messageColumns = `m.id, m.conversation_id, COALESCE(m.user_id, 0), COALESCE(u.username, ''), m.content, m.created_at`
+
+func scanMessage(row rowScanner) (*Message, error) {
+	m := &Message{}
+	err := row.Scan(&m.ID, &m.ConversationID, &m.UserID, &m.Username, &m.Content, &m.CreatedAt)
+	return m, err
+}
+
+// GetMessages renvoie les limit derniers messages d'une conversation, du plus ancien au plus récent
+func GetMessages(ctx context.Context, conversationID, userID, limit int) ([]*Message, error) {
+	member, err := isActiveMember(ctx, database.ReadDB, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrConversationNotFound
+	}
+
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT * FROM (
+			SELECT `+messageColumns+`
+			FROM messages m
+			LEFT JOIN users u ON u.id = m.user_id
+			WHERE m.conversation_id = ?
+			ORDER BY m.id DESC LIMIT ?
+		) ORDER BY id`, conversationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// GetMessage renvoie un message d'une conversation dont l'utilisateur est membre
+func GetMessage(ctx context.Context, messageID, userID int) (*Message, error) {
+	message, err := scanMessage(database.ReadDB.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages m
+		LEFT JOIN users u ON u.id = m.user_id
+		JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = ?
+		WHERE m.id = ? AND cm.left_at IS NULL`, userID, messageID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+// MarkConversationRead marque comme lus tous les messages d'une conversation pour un utilisateur
+func MarkConversationRead(ctx context.Context, conversationID, userID int) error {
+	result, err := database.DB.ExecContext(ctx, `
+		UPDATE conversation_members
+		SET last_read_message_id = COALESCE((SELECT MAX(id) FROM messages WHERE conversation_id = ?), 0)
+		WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL`,
+		conversationID, conversationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// LeaveConversation retire un utilisateur d'une conversation. Ses messages restent visibles des autres membres
+// La conversation est supprimée quand son dernier membre la quitte
+func LeaveConversation(ctx context.Context, conversationID, userID int) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE conversation_members SET left_at = CURRENT_TIMESTAMP WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL",
+		conversationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConversationNotFound
+	}
+
+	others, err := activeMembers(ctx, tx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if len(others) == 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM conversations WHERE id = ?", conversationID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// BlockUser empêche tout échange de messages entre deux utilisateurs
+func BlockUser(ctx context.Context, userID, blockedID int) error {
+	if userID == blockedID {
+		return ErrCannotBlockYourself
+	}
+
+	var count int
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = ?", blockedID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = database.DB.ExecContext(ctx,
+		"INSERT OR IGNORE INTO user_blocks (user_id, blocked_id) VALUES (?, ?)", userID, blockedID)
+	return err
+}
+
+// UnblockUser lève un blocage
+func UnblockUser(ctx context.Context, userID, blockedID int) error {
+	result, err := database.DB.ExecContext(ctx,
+		"DELETE FROM user_blocks WHERE user_id = ? AND blocked_id = ?", userID, blockedID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrBlockNotFound
+	}
+	return nil
+}
+
+// GetBlockedUsers renvoie les utilisateurs bloqués par un utilisateur, par nom
+func GetBlockedUsers(ctx context.Context, userID int) ([]*BlockedUser, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT b.blocked_id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.user_id = ?
+		ORDER BY u.username COLLATE NOCASE`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocked []*BlockedUser
+	for rows.Next() {
+		b := &BlockedUser{}
+		if err := rows.Scan(&b.UserID, &b.Username, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, b)
+	}
+	return blocked, rows.Err()
+}
+
+// GetMessageRetention renvoie la durée de conservation des messages en jours, 0 s'ils sont gardés indéfiniment
+func GetMessageRetention(ctx context.Context) (int, error) {
+	var value string
+	err := database.ReadDB.QueryRowContext(ctx,
+		"SELECT value FROM settings WHERE key = ?", messageRetentionSetting).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// SetMessageRetention change la durée de conservation des messages, en jours (0 pour les garder indéfiniment)
+func SetMessageRetention(ctx context.Context, days int) error {
+	if days < 0 || days > MaxMessageRetentionDays {
+		return errors.New("invalid retention period")
+	}
+	_, err := database.DB.ExecContext(ctx, `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+		messageRetentionSetting, strconv.Itoa(days),
+	)
+	return err
+}
+
+// PurgeMessages supprime les messages plus anciens que la durée de conservation, puis les conversations vides
+// Un message visé par un signalement en attente est gardé jusqu'à son traitement
+// Renvoie le nombre de messages supprimés
+func PurgeMessages(ctx context.Context, now time.Time) (int, error) {
+	days, err := GetMessageRetention(ctx)
+	if err != nil || days == 0 {
+		return 0, err
+	}
+	cutoff := sqliteTime(now.AddDate(0, 0, -days))
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM messages
+		WHERE datetime(created_at) < datetime(?)
+			AND id NOT IN (SELECT content_id FROM reports WHERE type = 'message' AND status = 'pending')`,
+		cutoff,
+	)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM conversations
+		WHERE datetime(created_at) < datetime(?)
+			AND NOT EXISTS (SELECT 1 FROM messages WHERE conversation_id = conversations.id)`,
+		cutoff,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), tx.Commit()
+}
@@ -1,17 +1,22 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"forum/internal/database"
+	"strings"
 	"time"
 )
 
+// ErrAlreadyReported est renvoyée quand l'utilisateur a déjà signalé ce contenu
+var ErrAlreadyReported = errors.New("content already reported by this user")
+
 // Report représente un signalement de contenu inapproprié
 type Report struct {
 	ID            int
-	Type          string    // "post" ou "comment"
-	ContentID     int       // ID du post ou du commentaire
+	Type          string    // "post", "comment" ou "message"
+	ContentID     int       // ID du post, du commentaire ou du message
 	ReporterID    int       // Utilisateur qui a fait le signalement
 	ReporterName  string    // Nom d'utilisateur du reporter
 	Reason        string    // Raison du signalement
@@ -20,8 +25,14 @@ type Report struct {
 	AdminResponse sql.NullString  // Réponse de l'administrateur
 	CreatedAt     time.Time // Date de création du signalement
 	UpdatedAt     time.Time // Date de la dernière mise à jour
+	MessageAuthor  string // Auteur d'un message privé signalé: l'administrateur n'a pas accès à la conversation
+	MessageContent string // Texte d'un message privé signalé, vide s'il a été supprimé depuis
+	ContentUserID  int    // Auteur du contenu signalé, 0 s'il est inconnu: il compte dans sa réputation si le signalement est approuvé
 }
 
+// contentTables associe chaque type de contenu signalable à sa table
+var contentTables = map[string]string{"post": "posts", "comment": "comments", "message": "messages"}
+
 // PendingPost représente un post en attente de modération
 type PendingPost struct {
 	ID           int
@@ -34,88 +45,29 @@ type PendingPost struct {
 	ModeratorID  sql.NullInt64
 	Reason       sql.NullString
 	CreatedAt    time.Time
+	Poll         *Poll    // Sondage soumis avec le post, nil s'il n'y en a pas
+	Tags         []string // Tags normalisés soumis avec le post, ajoutés à l'approbation
 }
 
-// ModerationQueueInit initialise les tables nécessaires pour la modération
-func ModerationQueueInit() error {
-	// Création de la table pour les posts en attente de modération
-	pendingPostsTable := `
-	CREATE TABLE IF NOT EXISTS pending_posts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		content TEXT NOT NULL,
-		user_id INTEGER NOT NULL,
-		status TEXT DEFAULT 'pending',
-		moderator_id INTEGER,
-		reason TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id),
-		FOREIGN KEY (moderator_id) REFERENCES users(id)
-	);`
-	_, err := database.DB.Exec(pendingPostsTable)
-	if err != nil {
-		return err
-	}
-
-	// Création de la table pour les catégories des posts en attente
-	pendingPostCategoriesTable := `
-	CREATE TABLE IF NOT EXISTS pending_post_categories (
-		post_id INTEGER NOT NULL,
-		category_id INTEGER NOT NULL,
-		PRIMARY KEY (post_id, category_id),
-		FOREIGN KEY (post_id) REFERENCES pending_posts(id) ON DELETE CASCADE,
-		FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
-	);`
-	_, err = database.DB.Exec(pendingPostCategoriesTable)
-	if err != nil {
-		return err
-	}
-
-	// Création de la table pour les signalements
-	reportsTable := `
-	CREATE TABLE IF NOT EXISTS reports (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		type TEXT NOT NULL,
-		content_id INTEGER NOT NULL,
-		reporter_id INTEGER NOT NULL,
-		reason TEXT NOT NULL,
-		status TEXT DEFAULT 'pending',
-		admin_id INTEGER,
-		admin_response TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (reporter_id) REFERENCES users(id),
-		FOREIGN KEY (admin_id) REFERENCES users(id)
-	);`
-	_, err = database.DB.Exec(reportsTable)
-	if err != nil {
-		return err
-	}
-
-	// Modification de la table users pour ajouter la colonne role si elle n'existe pas déjà
-	// C'est déjà fait dans la structure initiale.
-
-	return nil
-}
-
-// SubmitPendingPost soumet un post à la modération
-func SubmitPendingPost(title, content string, userID int, categoryIDs []int) (int, error) {
+// SubmitPendingPost soumet un post et son sondage (nil s'il n'en a pas) à la modération
+// Les tags doivent être normalisés (voir ParseTags)
+func SubmitPendingPost(ctx context.Context, post NewPost) (int, error) {
 	// Vérifier si le titre et le contenu ne sont pas vides
-	if title == "" || content == "" {
+	if post.Title == "" || post.Content == "" {
 		return 0, errors.New("title and content are required")
 	}
 
 	// Démarrer une transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
 
 	// Insérer le post dans la table des posts en attente
-	result, err := tx.Exec(
-		"INSERT INTO pending_posts (title, content, user_id, status) VALUES (?, ?, ?, ?)",
-		title, content, userID, "pending",
+	result, err := tx.ExecContext(ctx, 
+		"INSERT INTO pending_posts (title, content, user_id, status, tags) VALUES (?, ?, ?, ?, ?)",
+		post.Title, post.Content, post.UserID, "pending", strings.Join(post.Tags, ","),
 	)
 	if err != nil {
 		return 0, err
@@ -128,8 +80,8 @@ func SubmitPendingPost(title, content string, userID int, categoryIDs []int) (in
 	}
 
 	// Associer les catégories au post en attente
-	for _, categoryID := range categoryIDs {
-		_, err = tx.Exec(
+	for _, categoryID := range post.CategoryIDs {
+		_, err = tx.ExecContext(ctx, 
 			"INSERT INTO pending_post_categories (post_id, category_id) VALUES (?, ?)",
 			postID, categoryID,
 		)
@@ -138,6 +90,20 @@ func SubmitPendingPost(title, content string, userID int, categoryIDs []int) (in
 		}
 	}
 
+	// Le sondage attend avec le post et le suit s'il est approuvé
+	if post.Poll != nil {
+		if _, err = insertPollTx(ctx, tx, "pending_post_id", int(postID), post.Poll); err != nil {
+			return 0, err
+		}
+	}
+
+	// Le brouillon publié disparaît avec la soumission
+	if post.DraftID != 0 {
+		if err = deleteDraftTx(ctx, tx, post.DraftID, post.UserID); err != nil {
+			return 0, err
+		}
+	}
+
 	// Valider la transaction
 	err = tx.Commit()
 	if err != nil {
@@ -147,10 +113,18 @@ func SubmitPendingPost(title, content string, userID int, categoryIDs []int) (in
 	return int(postID), nil
 }
 
+// splitPendingTags relit les tags d'un post en attente, enregistrés séparés par des virgules
+func splitPendingTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
 // GetPendingPosts récupère les posts en attente de modération
-func GetPendingPosts() ([]*PendingPost, error) {
-	rows, err := database.DB.Query(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.status, p.moderator_id, p.reason, p.created_at
+func GetPendingPosts(ctx context.Context) ([]*PendingPost, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT p.id, p.title, p.content, p.user_id, u.username, p.status, p.moderator_id, p.reason, p.created_at, p.tags
 		FROM pending_posts p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.status = 'pending'
@@ -162,54 +136,109 @@ func GetPendingPosts() ([]*PendingPost, error) {
 	defer rows.Close()
 
 	var pendingPosts []*PendingPost
+	byID := map[int]*PendingPost{}
+	var ids []int
 	for rows.Next() {
-		post := &PendingPost{}
+		post := &PendingPost{Categories: []Category{}}
+		var tags string
 		err = rows.Scan(
 			&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username,
-			&post.Status, &post.ModeratorID, &post.Reason, &post.CreatedAt,
+			&post.Status, &post.ModeratorID, &post.Reason, &post.CreatedAt, &tags,
 		)
 		if err != nil {
 			return nil, err
 		}
+		post.Tags = splitPendingTags(tags)
 
-		// Récupérer les catégories pour ce post
-		categoryRows, err := database.DB.Query(`
-			SELECT c.id, c.name, c.description
-			FROM categories c
-			JOIN pending_post_categories pc ON c.id = pc.category_id
-			WHERE pc.post_id = ?
-		`, post.ID)
+		pendingPosts = append(pendingPosts, post)
+		byID[post.ID] = post
+		ids = append(ids, post.ID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return pendingPosts, nil
+	}
+
+	// Récupérer les catégories de tous les posts en une seule requête
+	in, args := inClause(ids)
+	categoryRows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+categoryColumns+`, pc.post_id
+		FROM categories c
+		JOIN pending_post_categories pc ON c.id = pc.category_id
+		WHERE pc.post_id IN `+in+`
+		ORDER BY c.position, c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer categoryRows.Close()
+
+	for categoryRows.Next() {
+		var postID int
+		category, err := scanCategory(categoryRows, &postID)
 		if err != nil {
 			return nil, err
 		}
-
-		post.Categories = []Category{}
-		for categoryRows.Next() {
-			var category Category
-			err = categoryRows.Scan(&category.ID, &category.Name, &category.Description)
-			if err != nil {
-				categoryRows.Close()
-				return nil, err
-			}
+		if post, ok := byID[postID]; ok {
 			post.Categories = append(post.Categories, category)
 		}
-		categoryRows.Close()
+	}
+	if err = categoryRows.Err(); err != nil {
+		return nil, err
+	}
+	categoryRows.Close()
 
-		pendingPosts = append(pendingPosts, post)
+	// Les sondages soumis avec les posts, pour que le modérateur les voie avant d'approuver
+	pollRows, err := database.ReadDB.QueryContext(ctx,
+		"SELECT pending_post_id FROM polls WHERE pending_post_id IN "+in, args...)
+	if err != nil {
+		return nil, err
+	}
+	var pollOwners []int
+	for pollRows.Next() {
+		var pendingID int
+		if err := pollRows.Scan(&pendingID); err != nil {
+			pollRows.Close()
+			return nil, err
+		}
+		pollOwners = append(pollOwners, pendingID)
+	}
+	pollRows.Close()
+	if err = pollRows.Err(); err != nil {
+		return nil, err
+	}
+	for _, pendingID := range pollOwners {
+		poll, err := getPoll(ctx, "pending_post_id", pendingID, 0)
+		if err != nil {
+			return nil, err
+		}
+		poll.Prepare(time.Now(), 0)
+		byID[pendingID].Poll = poll
 	}
 
 	return pendingPosts, nil
 }
 
 // ApprovePendingPost approuve un post en attente et le publie
-func ApprovePendingPost(pendingID, moderatorID int) (int, error) {
+func ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error) {
+	// Démarrer une transaction: les lectures se font dedans pour qu'un post ne soit pas approuvé deux fois
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	// Récupérer les informations du post en attente
 	pendingPost := &PendingPost{}
-	err := database.DB.QueryRow(`
-		SELECT id, title, content, user_id, status FROM pending_posts
+	var tags string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, title, content, user_id, status, tags FROM pending_posts
 		WHERE id = ? AND status = 'pending'
 	`, pendingID).Scan(
-		&pendingPost.ID, &pendingPost.Title, &pendingPost.Content, &pendingPost.UserID, &pendingPost.Status,
+		&pendingPost.ID, &pendingPost.Title, &pendingPost.Content, &pendingPost.UserID, &pendingPost.Status, &tags,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -219,34 +248,31 @@ func ApprovePendingPost(pendingID, moderatorID int) (int, error) {
 	}
 
 	// Récupérer les catégories associées au post en attente
-	rows, err := database.DB.Query(`
+	rows, err := tx.QueryContext(ctx, `
 		SELECT category_id FROM pending_post_categories
 		WHERE post_id = ?
 	`, pendingID)
 	if err != nil {
 		return 0, err
 	}
-	defer rows.Close()
 
 	var categoryIDs []int
 	for rows.Next() {
 		var categoryID int
 		err = rows.Scan(&categoryID)
 		if err != nil {
+			rows.Close()
 			return 0, err
 		}
 		categoryIDs = append(categoryIDs, categoryID)
 	}
-
-	// Démarrer une transaction
-	tx, err := database.DB.Begin()
-	if err != nil {
+	rows.Close()
+	if err = rows.Err(); err != nil {
 		return 0, err
 	}
-	defer tx.Rollback()
 
 	// Créer le post approuvé
-	result, err := tx.Exec(
+	result, err := tx.ExecContext(ctx, 
 		"INSERT INTO posts (title, content, user_id) VALUES (?, ?, ?)",
 		pendingPost.Title, pendingPost.Content, pendingPost.UserID,
 	)
@@ -261,7 +287,7 @@ func ApprovePendingPost(pendingID, moderatorID int) (int, error) {
 
 	// Associer les catégories au nouveau post
 	for _, categoryID := range categoryIDs {
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx, 
 			"INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)",
 			postID, categoryID,
 		)
@@ -270,8 +296,34 @@ func ApprovePendingPost(pendingID, moderatorID int) (int, error) {
 		}
 	}
 
+	// Les tags et le sondage soumis avec le post le suivent
+	err = SetPostTagsTx(ctx, tx, int(postID), splitPendingTags(tags))
+	if err != nil {
+		return 0, err
+	}
+	err = movePendingPollTx(ctx, tx, pendingID, int(postID))
+	if err != nil {
+		return 0, err
+	}
+
+	// Notifier les utilisateurs mentionnés, maintenant que le post est publié
+	err = notifyMentionsTx(ctx, tx, pendingPost.UserID, int(postID), 0, pendingPost.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	// L'auteur suit son post, et les abonnés de l'auteur et des catégories sont prévenus
+	err = subscribeToPostTx(ctx, tx, pendingPost.UserID, int(postID))
+	if err != nil {
+		return 0, err
+	}
+	err = notifyNewPostTx(ctx, tx, pendingPost.UserID, int(postID))
+	if err != nil {
+		return 0, err
+	}
+
 	// Mettre à jour le statut du post en attente
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx, 
 		"UPDATE pending_posts SET status = 'approved', moderator_id = ? WHERE id = ?",
 		moderatorID, pendingID,
 	)
@@ -289,10 +341,10 @@ func ApprovePendingPost(pendingID, moderatorID int) (int, error) {
 }
 
 // RejectPendingPost rejette un post en attente
-func RejectPendingPost(pendingID, moderatorID int, reason string) error {
+func RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error {
 	// Vérifier si le post en attente existe et est en attente
 	var status string
-	err := database.DB.QueryRow(
+	err := database.ReadDB.QueryRowContext(ctx, 
 		"SELECT status FROM pending_posts WHERE id = ?",
 		pendingID,
 	).Scan(&status)
@@ -307,7 +359,7 @@ func RejectPendingPost(pendingID, moderatorID int, reason string) error {
 	}
 
 	// Mettre à jour le statut du post en attente
-	_, err = database.DB.Exec(
+	_, err = database.DB.ExecContext(ctx, 
 		"UPDATE pending_posts SET status = 'rejected', moderator_id = ?, reason = ? WHERE id = ?",
 		moderatorID, reason, pendingID,
 	)
@@ -319,9 +371,9 @@ func RejectPendingPost(pendingID, moderatorID int, reason string) error {
 }
 
 // ReportContent signale un contenu inapproprié
-func ReportContent(contentType string, contentID, reporterID int, reason string) (int, error) {
+func ReportContent(ctx context.Context, contentType string, contentID, reporterID int, reason string) (int, error) {
 	// Vérifier que le type de contenu est valide
-	if contentType != "post" && contentType != "comment" {
+	if contentType != "post" && contentType != "comment" && contentType != "message" {
 		return 0, errors.New("invalid content type")
 	}
 
@@ -330,10 +382,12 @@ func ReportContent(contentType string, contentID, reporterID int, reason string)
 	var query string
 	if contentType == "post" {
 		query = "SELECT COUNT(*) FROM posts WHERE id = ?"
+	} else if contentType == "message" {
+		query = "SELECT COUNT(*) FROM messages WHERE id = ?"
 	} else {
 		query = "SELECT COUNT(*) FROM comments WHERE id = ?"
 	}
-	err := database.DB.QueryRow(query, contentID).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, query, contentID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -342,7 +396,7 @@ func ReportContent(contentType string, contentID, reporterID int, reason string)
 	}
 
 	// Vérifier si l'utilisateur a déjà signalé ce contenu
-	err = database.DB.QueryRow(
+	err = database.ReadDB.QueryRowContext(ctx, 
 		"SELECT COUNT(*) FROM reports WHERE type = ? AND content_id = ? AND reporter_id = ?",
 		contentType, contentID, reporterID,
 	).Scan(&count)
@@ -350,13 +404,14 @@ func ReportContent(contentType string, contentID, reporterID int, reason string)
 		return 0, err
 	}
 	if count > 0 {
-		return 0, errors.New("content already reported by this user")
+		return 0, ErrAlreadyReported
 	}
 
-	// Créer le signalement
-	result, err := database.DB.Exec(
-		"INSERT INTO reports (type, content_id, reporter_id, reason, status) VALUES (?, ?, ?, ?, ?)",
-		contentType, contentID, reporterID, reason, "pending",
+	// Créer le signalement, avec l'auteur du contenu qui disparaît si le signalement est approuvé
+	result, err := database.DB.ExecContext(ctx, 
+		"INSERT INTO reports (type, content_id, reporter_id, reason, status, content_user_id) VALUES (?, ?, ?, ?, ?, "+
+			"(SELECT user_id FROM "+contentTables[contentType]+" WHERE id = ?))",
+		contentType, contentID, reporterID, reason, "pending", contentID,
 	)
 	if err != nil {
 		return 0, err
@@ -371,12 +426,15 @@ func ReportContent(contentType string, contentID, reporterID int, reason string)
 }
 
 // GetReports récupère les signalements en attente
-func GetReports() ([]*Report, error) {
-	rows, err := database.DB.Query(`
+func GetReports(ctx context.Context) ([]*Report, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
 		SELECT r.id, r.type, r.content_id, r.reporter_id, u.username, r.reason, 
-		       r.status, r.admin_id, r.admin_response, r.created_at, r.updated_at
+		       r.status, r.admin_id, r.admin_response, r.created_at, r.updated_at,
+		       COALESCE(mu.username, ''), COALESCE(m.content, ''), COALESCE(r.content_user_id, 0)
 		FROM reports r
 		JOIN users u ON r.reporter_id = u.id
+		LEFT JOIN messages m ON r.type = 'message' AND m.id = r.content_id
+		LEFT JOIN users mu ON mu.id = m.user_id
 		WHERE r.status = 'pending'
 		ORDER BY r.created_at DESC
 	`)
@@ -391,7 +449,7 @@ func GetReports() ([]*Report, error) {
 		err = rows.Scan(
 			&report.ID, &report.Type, &report.ContentID, &report.ReporterID, &report.ReporterName,
 			&report.Reason, &report.Status, &report.AdminID, &report.AdminResponse,
-			&report.CreatedAt, &report.UpdatedAt,
+			&report.CreatedAt, &report.UpdatedAt, &report.MessageAuthor, &report.MessageContent, &report.ContentUserID,
 		)
 		if err != nil {
 			return nil, err
@@ -403,7 +461,7 @@ func GetReports() ([]*Report, error) {
 }
 
 // HandleReport traite un signalement (approuver ou rejeter)
-func HandleReport(reportID, adminID int, status string, response string) error {
+func HandleReport(ctx context.Context, reportID, adminID int, status string, response string) error {
 	// Vérifier que le statut est valide
 	if status != "approved" && status != "rejected" {
 		return errors.New("invalid status")
@@ -411,7 +469,7 @@ func HandleReport(reportID, adminID int, status string, response string) error {
 
 	// Récupérer les informations du signalement
 	var report Report
-	err := database.DB.QueryRow(`
+	err := database.ReadDB.QueryRowContext(ctx, `
 		SELECT id, type, content_id, status FROM reports
 		WHERE id = ?
 	`, reportID).Scan(&report.ID, &report.Type, &report.ContentID, &report.Status)
@@ -428,14 +486,14 @@ func HandleReport(reportID, adminID int, status string, response string) error {
 	}
 
 	// Démarrer une transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	// Mettre à jour le statut du signalement
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx, 
 		"UPDATE reports SET status = ?, admin_id = ?, admin_response = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
 		status, adminID, response, reportID,
 	)
@@ -445,13 +503,13 @@ func HandleReport(reportID, adminID int, status string, response string) error {
 
 	// Si le signalement est approuvé, supprimer le contenu signalé
 	if status == "approved" {
-		var deleteQuery string
 		if report.Type == "post" {
-			deleteQuery = "DELETE FROM posts WHERE id = ?"
+			_, err = tx.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", report.ContentID)
+		} else if report.Type == "message" {
+			_, err = tx.ExecContext(ctx, "DELETE FROM messages WHERE id = ?", report.ContentID)
 		} else {
-			deleteQuery = "DELETE FROM comments WHERE id = ?"
+			err = deleteCommentTx(ctx, tx, report.ContentID)
 		}
-		_, err = tx.Exec(deleteQuery, report.ContentID)
 		if err != nil {
 			return err
 		}
@@ -462,7 +520,7 @@ func HandleReport(reportID, adminID int, status string, response string) error {
 }
 
 // UpdateUserRole change le rôle d'un utilisateur (admin, modérateur, utilisateur)
-func UpdateUserRole(userID int, newRole string) error {
+func UpdateUserRole(ctx context.Context, userID int, newRole string) error {
 	// Vérifier que le rôle est valide
 	if newRole != "user" && newRole != "moderator" && newRole != "admin" {
 		return errors.New("invalid role")
@@ -470,16 +528,16 @@ func UpdateUserRole(userID int, newRole string) error {
 
 	// Vérifier que l'utilisateur existe
 	var count int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", userID).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = ?", userID).Scan(&count)
 	if err != nil {
 		return err
 	}
 	if count == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	// Mettre à jour le rôle de l'utilisateur
-	_, err = database.DB.Exec("UPDATE users SET role = ? WHERE id = ?", newRole, userID)
+	_, err = database.DB.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", newRole, userID)
 	if err != nil {
 		return err
 	}
@@ -488,13 +546,13 @@ func UpdateUserRole(userID int, newRole string) error {
 }
 
 // RequestModeratorRole permet à un utilisateur de demander le rôle de modérateur
-func RequestModeratorRole(userID int, reason string) (int, error) {
+func RequestModeratorRole(ctx context.Context, userID int, reason string) (int, error) {
 	// Vérifier que l'utilisateur existe et n'est pas déjà modérateur ou admin
 	var role string
-	err := database.DB.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, errors.New("user not found")
+			return 0, ErrUserNotFound
 		}
 		return 0, err
 	}
@@ -504,7 +562,7 @@ func RequestModeratorRole(userID int, reason string) (int, error) {
 	}
 
 	// Créer un rapport spécial pour la demande de modérateur
-	result, err := database.DB.Exec(
+	result, err := database.DB.ExecContext(ctx, 
 		"INSERT INTO reports (type, content_id, reporter_id, reason, status) VALUES (?, ?, ?, ?, ?)",
 		"moderator_request", userID, userID, reason, "pending",
 	)
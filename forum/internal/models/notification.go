@@ -0,0 +1,230 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"forum/internal/markdown"
+	"strings"
+	"time"
+)
+
+// Types de notifications
+const (
+	NotificationMention = "mention" // L'utilisateur est mentionné avec @nom
+	NotificationComment = "comment" // Nouveau commentaire sur un post suivi
+	NotificationPost    = "post"    // Nouveau post d'une catégorie ou d'un utilisateur suivis
+)
+
+// Notification prévient un utilisateur d'un événement qui le concerne
+type Notification struct {
+	ID        int
+	UserID    int
+	ActorID   int    // Utilisateur à l'origine de la notification, 0 s'il a été supprimé
+	ActorName string // Nom de cet utilisateur (non stocké dans la table notifications)
+	Type      string
+	PostID    int
+	PostTitle string // Titre du post (non stocké dans la table notifications)
+	CommentID int    // 0 quand la notification concerne le texte du post
+	IsRead    bool
+	CreatedAt time.Time
+}
+
+// nullableID convertit un ID facultatif pour la base: 0 devient NULL
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+// resolveMention renvoie l'ID de l'utilisateur désigné par une mention, 0 s'il n'existe pas
+// Les noms sont comparés sans tenir compte de la casse, un nom identique passe en premier
+func resolveMention(ctx context.Context, q querier, name string) (int, error) {
+	var userID int
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT id FROM users WHERE username = ? COLLATE NOCASE
+			ORDER BY username = ? DESC, id LIMIT 1
+		), 0)`, name, name).Scan(&userID)
+	return userID, err
+}
+
+// ResolveMentions renvoie l'ID des utilisateurs mentionnés, par nom tel qu'il est écrit
+// Les noms qui ne correspondent à personne sont absents du résultat
+func ResolveMentions(ctx context.Context, names []string) (map[string]int, error) {
+	userIDs := map[string]int{}
+	for _, name := range names {
+		userID, err := resolveMention(ctx, database.ReadDB, name)
+		if err != nil {
+			return nil, err
+		}
+		if userID > 0 {
+			userIDs[name] = userID
+		}
+	}
+	return userIDs, nil
+}
+
+// notifyMentionsTx notifie les utilisateurs mentionnés dans un post ou un commentaire
+// Un utilisateur déjà notifié pour ce contenu ne l'est pas une seconde fois: modifier un
+// texte ne prévient que les nouvelles mentions. L'auteur ne se notifie pas lui-même, et un utilisateur
+// qui ne peut pas lire le post n'est pas notifié. Les catégories du post doivent déjà lui être associées
+func notifyMentionsTx(ctx context.Context, tx *sql.Tx, actorID, postID, commentID int, content string) error {
+	mentions := markdown.Mentions(content)
+	if len(mentions) == 0 {
+		return nil
+	}
+	readers, readersArgs, err := postReadersConditionTx(ctx, tx, "u.id", postID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range mentions {
+		userID, err := resolveMention(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if userID == 0 || userID == actorID {
+			continue
+		}
+
+		args := []interface{}{
+			actorID, NotificationMention, postID, nullableID(commentID), userID,
+			NotificationMention, postID, nullableID(commentID),
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id)
+			SELECT u.id, ?, ?, ?, ?
+			FROM users u
+			WHERE u.id = ? AND NOT EXISTS (
+				SELECT 1 FROM notifications
+				WHERE user_id = u.id AND type = ? AND post_id = ? AND comment_id IS ?
+			)
+			AND `+readers,
+			append(args, readersArgs...)...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotifications renvoie les dernières notifications d'un utilisateur, les plus récentes d'abord
+func GetNotifications(ctx context.Context, userID, limit int) ([]*Notification, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT n.id, n.user_id, COALESCE(n.actor_id, 0), COALESCE(u.username, ''), n.type,
+			n.post_id, p.title, COALESCE(n.comment_id, 0), n.is_read, n.created_at
+		FROM notifications n
+		JOIN posts p ON n.post_id = p.id
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.user_id = ?
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		n := &Notification{}
+		err = rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.ActorName, &n.Type,
+			&n.PostID, &n.PostTitle, &n.CommentID, &n.IsRead, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// ErrNotificationNotFound est renvoyée quand la notification n'existe pas ou appartient à un autre utilisateur
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// MarkNotificationRead marque une notification de l'utilisateur comme lue
+func MarkNotificationRead(ctx context.Context, notificationID, userID int) error {
+	result, err := database.DB.ExecContext(ctx,
+		"UPDATE notifications SET is_read = 1 WHERE id = ? AND user_id = ?",
+		notificationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marque toutes les notifications de l'utilisateur comme lues
+func MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	_, err := database.DB.ExecContext(ctx,
+		"UPDATE notifications SET is_read = 1 WHERE user_id = ? AND is_read = 0",
+		userID,
+	)
+	return err
+}
+
+// GetNotifiedUsers renvoie les utilisateurs qui ont une notification pour un post (commentID 0)
+// ou pour un commentaire
+func GetNotifiedUsers(ctx context.Context, postID, commentID int) ([]int, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT DISTINCT user_id FROM notifications
+		WHERE post_id = ? AND comment_id IS ?
+		ORDER BY user_id`,
+		postID, nullableID(commentID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// SuggestUsers propose les noms d'utilisateurs qui commencent par prefix, pour l'autocomplétion
+// des mentions. Seuls les noms qui peuvent être écrits après un @ sont proposés
+func SuggestUsers(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.TrimPrefix(strings.TrimSpace(prefix), "@")
+	if prefix == "" {
+		return []string{}, nil
+	}
+	// Les caractères spéciaux de LIKE sont échappés
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT username FROM users
+		WHERE username LIKE ? ESCAPE '\'
+			AND username NOT GLOB '*[^A-Za-z0-9_.-]*'
+			AND username NOT GLOB '*[.-]'
+			AND length(username) <= ?
+		ORDER BY length(username), username
+		LIMIT ?
+	`, pattern, markdown.MaxMentionLength, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Les abonnés, les personnes mentionnées et les résumés ne reçoivent que les posts qu'ils peuvent lire
+func TestNotificationsRespectReadPermission(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	memberID := createTestUser(t, "member")
+	moderatorID := createTestUser(t, "moderator")
+	if err := UpdateUserRole(ctx, moderatorID, "moderator"); err != nil {
+		t.Fatalf("setting role: %v", err)
+	}
+
+	parentID, err := CreateCategory(ctx, Category{Name: "Annonces internes"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	staffID, err := CreateCategory(ctx, Category{Name: "Équipe", ParentID: parentID, ReadPermission: CategoryReadStaff})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	for _, userID := range []int{memberID, moderatorID} {
+		if err := Follow(ctx, userID, SubscriptionCategory, parentID); err != nil {
+			t.Fatalf("following category: %v", err)
+		}
+		if err := Follow(ctx, userID, SubscriptionUser, authorID); err != nil {
+			t.Fatalf("following author: %v", err)
+		}
+	}
+
+	since := time.Now().Add(-time.Hour)
+	postID, err := CreatePostWithDetails(ctx, NewPost{
+		Title: "Sujet interne", Content: "Pour @member et @moderator", UserID: authorID, CategoryIDs: []int{staffID},
+	})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	if _, err := CreateComment(ctx, "Encore @member", authorID, postID); err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+
+	if err := Follow(ctx, memberID, SubscriptionPost, postID); err == nil {
+		t.Error("member followed a hidden post")
+	}
+	if err := Follow(ctx, memberID, SubscriptionCategory, staffID); err == nil {
+		t.Error("member followed a hidden category")
+	}
+
+	for _, tt := range []struct {
+		name          string
+		userID        int
+		notifications int
+		digestPosts   int
+		mentions      int
+	}{
+		{"member", memberID, 0, 0, 0},
+		// Le modérateur mentionné dans le post n'en reçoit pas une seconde notification
+		{"moderator", moderatorID, 1, 1, 1},
+	} {
+		notifications, err := GetNotifications(ctx, tt.userID, 10)
+		if err != nil {
+			t.Fatalf("notifications of %s: %v", tt.name, err)
+		}
+		if len(notifications) != tt.notifications {
+			t.Errorf("notifications of %s: got %d, want %d", tt.name, len(notifications), tt.notifications)
+		}
+
+		digest, err := GetDigest(ctx, tt.userID, since, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("digest of %s: %v", tt.name, err)
+		}
+		if len(digest.Posts) != tt.digestPosts || len(digest.Mentions) != tt.mentions {
+			t.Errorf("digest of %s: %d posts and %d mentions, want %d and %d",
+				tt.name, len(digest.Posts), len(digest.Mentions), tt.digestPosts, tt.mentions)
+		}
+	}
+}
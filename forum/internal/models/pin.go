@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strconv"
+	"time"
+)
+
+// activePin est vrai pour un post épinglé dont l'épinglage n'a pas expiré (posts aliasé en p)
+const activePin = `(p.pinned_at IS NOT NULL AND (p.pinned_until IS NULL OR datetime(p.pinned_until) > datetime('now')))`
+
+// pinnedFirst trie en tête les posts épinglés dans la liste de la catégorie, 0 pour la liste de tous les posts
+func pinnedFirst(categoryID int) string {
+	return `(` + activePin + ` AND COALESCE(p.pinned_category_id, 0) = ` + strconv.Itoa(categoryID) + `) DESC, `
+}
+
+// PinnedIn indique si le post est épinglé en tête de la liste de la catégorie, 0 pour la liste de tous les posts
+func (p *Post) PinnedIn(categoryID int) bool {
+	return p.Pinned && p.PinnedCategoryID == categoryID
+}
+
+// Erreurs de l'épinglage, qui viennent de la saisie du modérateur
+var (
+	ErrPinExpiryInPast   = errors.New("pin expiry must be in the future")
+	ErrPostNotInCategory = errors.New("post is not in this category")
+)
+
+// PinPost épingle un post en tête de la liste de tous les posts (categoryID 0) ou de la liste d'une
+// catégorie dont il fait partie, directement ou par une sous-catégorie. until est la fin de
+// l'épinglage, zéro pour un épinglage sans fin. Épingler de nouveau un post remplace son épinglage
+func PinPost(ctx context.Context, postID, categoryID int, until, now time.Time) error {
+	if !until.IsZero() && !until.After(now) {
+		return ErrPinExpiryInPast
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE id = ?", postID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPostNotFound
+	}
+
+	var category interface{}
+	if categoryID > 0 {
+		err = tx.QueryRowContext(ctx, `
+			WITH RECURSIVE tree(id) AS (
+				SELECT ?
+				UNION
+				SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT COUNT(*) FROM post_categories WHERE post_id = ? AND category_id IN tree`,
+			categoryID, postID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrPostNotInCategory
+		}
+		category = categoryID
+	}
+
+	var expiry interface{}
+	if !until.IsZero() {
+		expiry = sqliteTime(until)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE posts SET pinned_at = ?, pinned_category_id = ?, pinned_until = ?
+		WHERE id = ?`, sqliteTime(now), category, expiry, postID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnpinPost retire l'épinglage d'un post
+func UnpinPost(ctx context.Context, postID int) error {
+	result, err := database.DB.ExecContext(ctx, `
+		UPDATE posts SET pinned_at = NULL, pinned_category_id = NULL, pinned_until = NULL
+		WHERE id = ?`, postID)
+	if err != nil {
+		return err
+	}
+	return expectPost(result)
+}
+
+// ErrPostLocked est renvoyée quand on commente ou réagit dans une discussion verrouillée
+var ErrPostLocked = errors.New("post is locked")
+
+// SetPostLocked verrouille ou déverrouille un post. Un post verrouillé n'accepte plus de nouveaux
+// commentaires ni de réactions, sur lui comme sur ses commentaires
+func SetPostLocked(ctx context.Context, postID int, locked bool) error {
+	query := "UPDATE posts SET locked_at = NULL WHERE id = ?"
+	if locked {
+		// Verrouiller de nouveau un post garde la date du premier verrouillage
+		query = "UPDATE posts SET locked_at = COALESCE(locked_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	}
+	result, err := database.DB.ExecContext(ctx, query, postID)
+	if err != nil {
+		return err
+	}
+	return expectPost(result)
+}
+
+// expectPost renvoie "post not found" quand une modification n'a touché aucun post
+func expectPost(result sql.Result) error {
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// reactionTargetLockedTx indique si le contenu d'une réaction appartient à un post verrouillé:
+// le post lui-même, ou le post d'un commentaire
+func reactionTargetLockedTx(ctx context.Context, tx *sql.Tx, targetType string, itemID int) (bool, error) {
+	query := "SELECT locked_at IS NOT NULL FROM posts WHERE id = ?"
+	if targetType == ReactionOnComment {
+		query = "SELECT p.locked_at IS NOT NULL FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ?"
+	}
+	var locked bool
+	err := tx.QueryRowContext(ctx, query, itemID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, ErrReactionTargetNotFound
+	}
+	return locked, err
+}
@@ -0,0 +1,408 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"strings"
+	"time"
+)
+
+// Limites des sondages
+const (
+	MinPollOptions        = 2
+	MaxPollOptions        = 10
+	MaxPollQuestionLength = 300 // Nombre maximal de caractères de la question
+	MaxPollOptionLength   = 200 // Nombre maximal de caractères d'une option
+)
+
+// Erreurs des sondages qui viennent de la saisie ou des choix de l'utilisateur
+var (
+	ErrPollQuestionRequired = errors.New("poll question is required")
+	ErrPollQuestionTooLong  = errors.New("poll question is too long")
+	ErrPollOptionTooLong    = errors.New("poll option is too long")
+	ErrDuplicatePollOption  = errors.New("duplicate poll option")
+	ErrPollTooFewOptions    = errors.New("poll needs at least two options")
+	ErrPollTooManyOptions   = errors.New("too many poll options")
+	ErrPollClosesInPast     = errors.New("poll close date must be in the future")
+	ErrPollHasVotes         = errors.New("poll has votes")
+	ErrPollNotFound         = errors.New("poll not found")
+	ErrPollClosed           = errors.New("poll is closed")
+	ErrVoteCannotChange     = errors.New("vote cannot be changed")
+	ErrNoOptionSelected     = errors.New("no option selected")
+	ErrSingleChoicePoll     = errors.New("single choice poll")
+	ErrInvalidPollOption    = errors.New("invalid poll option")
+)
+
+// Poll est un sondage attaché à un post
+// Les résultats ne sont visibles qu'après avoir voté ou une fois le sondage clos
+type Poll struct {
+	ID              int
+	PostID          int // 0 tant que le post est en attente de modération
+	Question        string
+	MultipleChoice  bool         // Chaque votant peut choisir plusieurs options
+	Anonymous       bool         // Les noms des votants ne sont pas affichés
+	AllowVoteChange bool         // Un votant peut changer son vote tant que le sondage est ouvert
+	ClosesAt        sql.NullTime // Date de clôture, absente pour un sondage sans fin
+	CreatedAt       time.Time
+	Options         []*PollOption
+	Voters          int // Nombre de votants (0 tant que les résultats sont masqués)
+	// Les champs suivants sont calculés par Prepare pour l'utilisateur courant
+	Locked         bool // Le sondage a reçu des votes: il ne peut plus être modifié
+	Closed         bool
+	HasVoted       bool
+	ResultsVisible bool
+	CanVote        bool
+}
+
+// PollOption est un choix d'un sondage
+type PollOption struct {
+	ID      int
+	Label   string
+	Votes   int      // Nombre de votes (0 tant que les résultats sont masqués)
+	Percent int      // Part des votants qui ont choisi l'option
+	Voters  []string // Noms des votants, pour un sondage public dont les résultats sont visibles
+	Chosen  bool     // L'utilisateur courant a choisi cette option
+}
+
+// NewPoll vérifie la saisie d'un sondage et le renvoie, prêt à être enregistré
+// Les options vides sont ignorées; closesAt nul signifie un sondage sans date de clôture
+func NewPoll(question string, options []string, multipleChoice, anonymous, allowVoteChange bool, closesAt, now time.Time) (*Poll, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, ErrPollQuestionRequired
+	}
+	if len([]rune(question)) > MaxPollQuestionLength {
+		return nil, ErrPollQuestionTooLong
+	}
+
+	poll := &Poll{
+		Question:        question,
+		MultipleChoice:  multipleChoice,
+		Anonymous:       anonymous,
+		AllowVoteChange: allowVoteChange,
+	}
+	seen := map[string]bool{}
+	for _, label := range options {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		if len([]rune(label)) > MaxPollOptionLength {
+			return nil, ErrPollOptionTooLong
+		}
+		if seen[strings.ToLower(label)] {
+			return nil, ErrDuplicatePollOption
+		}
+		seen[strings.ToLower(label)] = true
+		poll.Options = append(poll.Options, &PollOption{Label: label})
+	}
+	if len(poll.Options) < MinPollOptions {
+		return nil, ErrPollTooFewOptions
+	}
+	if len(poll.Options) > MaxPollOptions {
+		return nil, ErrPollTooManyOptions
+	}
+
+	if !closesAt.IsZero() {
+		if !closesAt.After(now) {
+			return nil, ErrPollClosesInPast
+		}
+		poll.ClosesAt = sql.NullTime{Time: closesAt.UTC(), Valid: true}
+	}
+	return poll, nil
+}
+
+// Prepare calcule l'état du sondage pour l'utilisateur courant (0 pour un visiteur) à partir
+// des votes lus, puis masque les compteurs et les votants tant que les résultats ne sont pas visibles
+func (p *Poll) Prepare(now time.Time, currentUserID int) {
+	p.Closed = p.ClosesAt.Valid && !now.Before(p.ClosesAt.Time)
+	p.HasVoted = false
+	for _, option := range p.Options {
+		if option.Chosen {
+			p.HasVoted = true
+		}
+		if option.Votes > 0 {
+			p.Locked = true
+		}
+	}
+	p.ResultsVisible = p.HasVoted || p.Closed
+	p.CanVote = currentUserID > 0 && p.PostID != 0 && !p.Closed && (!p.HasVoted || p.AllowVoteChange)
+
+	for _, option := range p.Options {
+		option.Percent = 0
+		if !p.ResultsVisible {
+			option.Votes = 0
+			option.Voters = nil
+			continue
+		}
+		if p.Voters > 0 {
+			option.Percent = option.Votes * 100 / p.Voters
+		}
+	}
+	if !p.ResultsVisible {
+		p.Voters = 0
+	}
+}
+
+// insertPollTx enregistre un sondage et ses options pour un post (column "post_id")
+// ou un post en attente (column "pending_post_id")
+func insertPollTx(ctx context.Context, q querier, column string, ownerID int, poll *Poll) (int, error) {
+	var closesAt interface{}
+	if poll.ClosesAt.Valid {
+		closesAt = sqliteTime(poll.ClosesAt.Time)
+	}
+	result, err := q.ExecContext(ctx,
+		"INSERT INTO polls ("+column+", question, multiple_choice, anonymous, allow_vote_change, closes_at) VALUES (?, ?, ?, ?, ?, ?)",
+		ownerID, poll.Question, poll.MultipleChoice, poll.Anonymous, poll.AllowVoteChange, closesAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	pollID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, option := range poll.Options {
+		_, err = q.ExecContext(ctx,
+			"INSERT INTO poll_options (poll_id, position, label) VALUES (?, ?, ?)",
+			pollID, i+1, option.Label,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int(pollID), nil
+}
+
+// GetPoll renvoie le sondage d'un post, préparé pour l'utilisateur courant
+func GetPoll(ctx context.Context, postID, currentUserID int) (*Poll, error) {
+	poll, err := getPoll(ctx, "post_id", postID, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	poll.Prepare(time.Now(), currentUserID)
+	return poll, nil
+}
+
+// getPoll lit un sondage avec ses options, ses votes et les choix de l'utilisateur courant
+// column est "post_id" ou "pending_post_id"
+func getPoll(ctx context.Context, column string, ownerID, currentUserID int) (*Poll, error) {
+	poll := &Poll{}
+	var postID sql.NullInt64
+	err := database.ReadDB.QueryRowContext(ctx, `
+		SELECT id, post_id, question, multiple_choice, anonymous, allow_vote_change, closes_at, created_at,
+			(SELECT COUNT(DISTINCT user_id) FROM poll_votes WHERE poll_id = polls.id)
+		FROM polls
+		WHERE `+column+` = ?`, ownerID,
+	).Scan(&poll.ID, &postID, &poll.Question, &poll.MultipleChoice, &poll.Anonymous, &poll.AllowVoteChange,
+		&poll.ClosesAt, &poll.CreatedAt, &poll.Voters)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPollNotFound
+		}
+		return nil, err
+	}
+	poll.PostID = int(postID.Int64)
+
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT o.id, o.label, COUNT(v.user_id), COALESCE(MAX(v.user_id = ?), 0)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id = ?
+		GROUP BY o.id
+		ORDER BY o.position`, currentUserID, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int]*PollOption{}
+	for rows.Next() {
+		option := &PollOption{}
+		if err := rows.Scan(&option.ID, &option.Label, &option.Votes, &option.Chosen); err != nil {
+			return nil, err
+		}
+		poll.Options = append(poll.Options, option)
+		byID[option.ID] = option
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Les votants d'un sondage public, dans l'ordre des votes
+	if poll.Anonymous || poll.Voters == 0 {
+		return poll, nil
+	}
+	voterRows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT v.option_id, u.username
+		FROM poll_votes v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.poll_id = ?
+		ORDER BY v.created_at, u.username`, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer voterRows.Close()
+	for voterRows.Next() {
+		var optionID int
+		var username string
+		if err := voterRows.Scan(&optionID, &username); err != nil {
+			return nil, err
+		}
+		if option, ok := byID[optionID]; ok {
+			option.Voters = append(option.Voters, username)
+		}
+	}
+	return poll, voterRows.Err()
+}
+
+// UpdatePoll remplace le sondage d'un post, ou le retire si poll est nil
+// Un sondage qui a reçu des votes ne peut plus être modifié
+func UpdatePoll(ctx context.Context, postID int, poll *Poll) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pollID, votes int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, (SELECT COUNT(*) FROM poll_votes WHERE poll_id = polls.id)
+		FROM polls WHERE post_id = ?`, postID,
+	).Scan(&pollID, &votes)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if votes > 0 {
+		return ErrPollHasVotes
+	}
+	if pollID != 0 {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM polls WHERE id = ?", pollID); err != nil {
+			return err
+		}
+	}
+	if poll != nil {
+		if _, err = insertPollTx(ctx, tx, "post_id", postID, poll); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Vote enregistre le vote d'un utilisateur, qui remplace son vote précédent si le sondage le permet
+func Vote(ctx context.Context, pollID, userID int, optionIDs []int) error {
+	optionIDs = uniqueIDs(optionIDs)
+	if len(optionIDs) == 0 {
+		return ErrNoOptionSelected
+	}
+	readable, readableArgs, err := viewerReadCondition(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var postID sql.NullInt64
+	var multipleChoice, allowVoteChange bool
+	var closesAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT post_id, multiple_choice, allow_vote_change, closes_at FROM polls WHERE id = ?", pollID,
+	).Scan(&postID, &multipleChoice, &allowVoteChange, &closesAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPollNotFound
+		}
+		return err
+	}
+	// Un sondage en attente de modération n'est pas encore publié
+	if !postID.Valid {
+		return ErrPollNotFound
+	}
+	// Le sondage d'un post que l'utilisateur ne peut pas lire n'existe pas pour lui
+	var visible int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts p WHERE p.id = ? AND "+readable,
+		append([]interface{}{postID.Int64}, readableArgs...)...).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if visible == 0 {
+		return ErrPollNotFound
+	}
+	if closesAt.Valid && !time.Now().Before(closesAt.Time) {
+		return ErrPollClosed
+	}
+	if !multipleChoice && len(optionIDs) > 1 {
+		return ErrSingleChoicePoll
+	}
+
+	in, args := inClause(optionIDs)
+	var valid int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM poll_options WHERE poll_id = ? AND id IN "+in,
+		append([]interface{}{pollID}, args...)...,
+	).Scan(&valid)
+	if err != nil {
+		return err
+	}
+	if valid != len(optionIDs) {
+		return ErrInvalidPollOption
+	}
+
+	var previous int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM poll_votes WHERE poll_id = ? AND user_id = ?", pollID, userID,
+	).Scan(&previous)
+	if err != nil {
+		return err
+	}
+	if previous > 0 {
+		if !allowVoteChange {
+			return ErrVoteCannotChange
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?", pollID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, optionID := range optionIDs {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO poll_votes (poll_id, option_id, user_id) VALUES (?, ?, ?)",
+			pollID, optionID, userID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// uniqueIDs renvoie les IDs sans doublons, dans leur ordre d'origine
+func uniqueIDs(ids []int) []int {
+	seen := map[int]bool{}
+	var unique []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// movePendingPollTx rattache le sondage d'un post en attente au post publié lors de son approbation
+func movePendingPollTx(ctx context.Context, tx querier, pendingID, postID int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE polls SET post_id = ?, pending_post_id = NULL WHERE pending_post_id = ?",
+		postID, pendingID,
+	)
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"forum/internal/database"
@@ -17,17 +18,25 @@ type Post struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Categories []Category // Catégories associées au post
-	Likes     int         // Nombre de likes (calculé à la demande)
-	Dislikes  int         // Nombre de dislikes (calculé à la demande)
-	UserReaction string   // Reaction de l'utilisateur actuel: 'like', 'dislike' ou '' (vide)
+	Tags      []Tag       // Tags libres du post, par ordre alphabétique
+	Likes     int         // Nombre de réactions qui ajoutent un point au score (compteur stocké dans posts.like_count)
+	Dislikes  int         // Nombre de réactions qui retirent un point au score (compteur stocké dans posts.dislike_count)
+	CommentCount int      // Nombre de commentaires (compteur stocké dans posts.comment_count)
+	Reactions []ReactionCount // Nombre de réactions de chaque type, dans l'ordre d'affichage des réactions
+	AcceptedCommentID int // Commentaire accepté comme réponse, 0 si le post n'est pas résolu
+	IsBookmarked bool     // Le post est dans les signets de l'utilisateur actuel
+	AuthorBadges []Badge  // Badges de l'auteur, affichés à côté de son nom
+	Pinned    bool         // Le post est épinglé et l'épinglage n'a pas expiré
+	PinnedCategoryID int   // Catégorie où le post est épinglé, 0 pour la liste de tous les posts
+	PinnedUntil sql.NullTime // Fin de l'épinglage, absente pour un épinglage sans fin
+	Locked    bool         // Le post n'accepte plus de nouveaux commentaires ni de réactions
 }
 
-// Category représente une catégorie de posts
-type Category struct {
-	ID          int
-	Name        string
-	Description sql.NullString // Modifié pour gérer les valeurs NULL
-}
+// Filtres de GetPosts sur la résolution des posts
+const (
+	PostsSolved   = "solved"   // Posts qui ont une réponse acceptée
+	PostsUnsolved = "unsolved" // Posts sans réponse acceptée
+)
 
 // Comment représente un commentaire sur un post
 type Comment struct {
@@ -38,43 +47,92 @@ type Comment struct {
 	PostID    int
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Likes     int    // Nombre de likes (calculé à la demande)
-	Dislikes  int    // Nombre de dislikes (calculé à la demande)
-	UserReaction string // Reaction de l'utilisateur actuel: 'like', 'dislike' ou '' (vide)
+	Likes     int    // Nombre de réactions qui ajoutent un point au score (compteur stocké dans comments.like_count)
+	Dislikes  int    // Nombre de réactions qui retirent un point au score (compteur stocké dans comments.dislike_count)
+	Reactions []ReactionCount // Nombre de réactions de chaque type, dans l'ordre d'affichage des réactions
+	IsBookmarked bool   // Le commentaire est dans les signets de l'utilisateur actuel
+	AuthorBadges []Badge // Badges de l'auteur, affichés à côté de son nom
+}
+
+// NewPost est un post à publier avec tout ce qui l'accompagne
+type NewPost struct {
+	Title       string
+	Content     string
+	UserID      int
+	CategoryIDs []int
+	Tags        []string
+	Poll        *Poll // Sondage du post, nil s'il n'en a pas
+	ImageID     int   // Image déjà envoyée par l'auteur, 0 si le post n'en a pas
+	DraftID     int   // Brouillon publié, supprimé avec la création du post, 0 s'il n'y en a pas
 }
 
+// ErrPostNotFound est renvoyée quand le post n'existe pas
+var ErrPostNotFound = errors.New("post not found")
+
+// ErrCommentNotFound est renvoyée quand le commentaire n'existe pas, ou n'appartient pas au post demandé
+var ErrCommentNotFound = errors.New("comment not found")
+
 // CreatePost crée un nouveau post dans la base de données
-func CreatePost(title, content string, userID int, categoryIDs []int) (int, error) {
+func CreatePost(ctx context.Context, title, content string, userID int, categoryIDs []int) (int, error) {
+	return CreatePostWithDetails(ctx, NewPost{Title: title, Content: content, UserID: userID, CategoryIDs: categoryIDs})
+}
+
+// CreatePostWithDetails crée un post avec ses catégories, ses tags, son sondage et son image, et supprime
+// le brouillon publié. Tout est enregistré dans la même transaction: si une étape échoue, aucun post
+// n'est publié et le brouillon est gardé
+func CreatePostWithDetails(ctx context.Context, post NewPost) (int, error) {
 	// Vérifier si le titre et le contenu ne sont pas vides
-	if title == "" || content == "" {
+	if post.Title == "" || post.Content == "" {
 		return 0, errors.New("title and content are required")
 	}
 
 	// Démarrer une transaction pour s'assurer que toutes les opérations réussissent ou échouent ensemble
-	tx, err := database.DB.Begin()
+	tx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback() // En cas d'erreur, annuler toutes les opérations
 
+	postID, err := insertPostTx(ctx, tx, post)
+	if err != nil {
+		return 0, err
+	}
+	if post.DraftID != 0 {
+		if err := deleteDraftTx(ctx, tx, post.DraftID, post.UserID); err != nil {
+			return 0, err
+		}
+	}
+
+	// Valider la transaction
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return postID, nil
+}
+
+// insertPostTx enregistre un post et tout ce qui l'accompagne, et prévient les utilisateurs concernés
+func insertPostTx(ctx context.Context, tx *sql.Tx, post NewPost) (int, error) {
 	// Insérer le post dans la base de données
-	result, err := tx.Exec(
+	result, err := tx.ExecContext(ctx,
 		"INSERT INTO posts (title, content, user_id) VALUES (?, ?, ?)",
-		title, content, userID,
+		post.Title, post.Content, post.UserID,
 	)
 	if err != nil {
 		return 0, err
 	}
 
 	// Récupérer l'ID du post nouvellement créé
-	postID, err := result.LastInsertId()
+	id, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
+	postID := int(id)
 
 	// Associer les catégories au post
-	for _, categoryID := range categoryIDs {
-		_, err = tx.Exec(
+	for _, categoryID := range post.CategoryIDs {
+		_, err = tx.ExecContext(ctx,
 			"INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)",
 			postID, categoryID,
 		)
@@ -83,114 +141,142 @@ func CreatePost(title, content string, userID int, categoryIDs []int) (int, erro
 		}
 	}
 
-	// Valider la transaction
-	err = tx.Commit()
+	if err = SetPostTagsTx(ctx, tx, postID, post.Tags); err != nil {
+		return 0, err
+	}
+	if post.Poll != nil {
+		if _, err = insertPollTx(ctx, tx, "post_id", postID, post.Poll); err != nil {
+			return 0, err
+		}
+	}
+
+	// L'image doit appartenir à l'auteur et n'être attachée à aucun autre post
+	if post.ImageID > 0 {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE images SET post_id = ? WHERE id = ? AND user_id = ? AND post_id IS NULL",
+			postID, post.ImageID, post.UserID,
+		)
+		if err != nil {
+			return 0, err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if updated == 0 {
+			return 0, errors.New("image not found")
+		}
+	}
+
+	// Notifier les utilisateurs mentionnés dans le texte
+	err = notifyMentionsTx(ctx, tx, post.UserID, postID, 0, post.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	// L'auteur suit son post, et les abonnés de l'auteur et des catégories sont prévenus
+	err = subscribeToPostTx(ctx, tx, post.UserID, postID)
+	if err != nil {
+		return 0, err
+	}
+	err = notifyNewPostTx(ctx, tx, post.UserID, postID)
 	if err != nil {
 		return 0, err
 	}
 
-	return int(postID), nil
+	return postID, nil
 }
 
 // GetPostByID récupère un post par son ID, avec ses catégories et ses statistiques
-func GetPostByID(postID int, currentUserID int) (*Post, error) {
+func GetPostByID(ctx context.Context, postID int, currentUserID int) (*Post, error) {
 	// Récupérer les informations de base du post
-	post := &Post{}
-	err := database.DB.QueryRow(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at
+	post, err := scanPost(database.ReadDB.QueryRowContext(ctx, `
+		SELECT `+postColumns+`
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.id = ?
-	`, postID).Scan(
-		&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt,
-	)
+	`, postID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("post not found")
+			return nil, ErrPostNotFound
 		}
 		return nil, err
 	}
 
-	// Récupérer les catégories associées au post
-	rows, err := database.DB.Query(`
-		SELECT c.id, c.name, c.description
-		FROM categories c
-		JOIN post_categories pc ON c.id = pc.category_id
-		WHERE pc.post_id = ?
-	`, postID)
+	// Récupérer les catégories, les compteurs de réactions et la réaction de l'utilisateur actuel
+	err = loadPostDetails(ctx, []*Post{post}, currentUserID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	// Stocker les catégories dans le post
-	post.Categories = []Category{}
-	for rows.Next() {
-		var category Category
-		err = rows.Scan(&category.ID, &category.Name, &category.Description)
-		if err != nil {
-			return nil, err
-		}
-		post.Categories = append(post.Categories, category)
-	}
-
-	// Compter les likes et dislikes
-	err = database.DB.QueryRow(`
-		SELECT 
-			(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'like'),
-			(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'dislike')
-	`, postID, postID).Scan(&post.Likes, &post.Dislikes)
-	if err != nil {
-		return nil, err
-	}
-
-	// Vérifier si l'utilisateur actuel a réagi au post
-	if currentUserID > 0 {
-		var reactionType sql.NullString
-		err = database.DB.QueryRow(`
-			SELECT reaction_type FROM post_reactions
-			WHERE user_id = ? AND post_id = ?
-		`, currentUserID, postID).Scan(&reactionType)
-		if err != nil && err != sql.ErrNoRows {
-			return nil, err
-		}
-		if reactionType.Valid {
-			post.UserReaction = reactionType.String
-		}
-	}
 
 	return post, nil
 }
 
 // GetPosts récupère une liste de posts, avec pagination et filtrage optionnels
-func GetPosts(page, perPage int, categoryID int, userID int, sortBy string, currentUserID int) ([]*Post, int, error) {
+// solved vaut PostsSolved ou PostsUnsolved pour filtrer sur la réponse acceptée, vide pour tous les posts
+func GetPosts(ctx context.Context, page, perPage int, categoryID int, tags []string, userID int, solved string, sortBy string, currentUserID int) ([]*Post, int, error) {
 	// Calculer l'offset pour la pagination
 	offset := (page - 1) * perPage
 
 	// Préparer les parties de la requête SQL
 	query := `
-		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at, p.updated_at
+		SELECT ` + postColumns + `
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 	`
 	countQuery := `SELECT COUNT(*) FROM posts p`
 	
 	// Ajouter des conditions si nécessaire
-	var conditions []string
-	var args []interface{}
+	// Les posts des catégories que l'utilisateur ne peut pas lire ne sont jamais listés
+	readable, args, err := viewerReadCondition(ctx, currentUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	conditions := []string{readable}
 	
 	if categoryID > 0 {
-		query += ` JOIN post_categories pc ON p.id = pc.post_id`
-		countQuery += ` JOIN post_categories pc ON p.id = pc.post_id`
-		conditions = append(conditions, `pc.category_id = ?`)
+		// Une catégorie inclut les posts de toutes ses sous-catégories
+		conditions = append(conditions, `p.id IN (
+			WITH RECURSIVE tree(id) AS (
+				SELECT ?
+				UNION
+				SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT pc.post_id FROM post_categories pc WHERE pc.category_id IN tree)`)
 		args = append(args, categoryID)
 	}
+
+	// Chaque tag demandé doit être présent sur le post; un synonyme désigne son tag
+	for _, tag := range tags {
+		conditions = append(conditions, `p.id IN (
+			SELECT pt.post_id FROM post_tags pt
+			WHERE pt.tag_id = COALESCE(
+				(SELECT tag_id FROM tag_synonyms WHERE synonym = ?),
+				(SELECT id FROM tags WHERE name = ?)))`)
+		tag = NormalizeTag(tag)
+		args = append(args, tag, tag)
+	}
 	
 	if userID > 0 {
 		conditions = append(conditions, `p.user_id = ?`)
 		args = append(args, userID)
 	}
 	
+	switch solved {
+	case PostsSolved:
+		conditions = append(conditions, `p.accepted_comment_id IS NOT NULL`)
+	case PostsUnsolved:
+		conditions = append(conditions, `p.accepted_comment_id IS NULL`)
+	}
+	
+	// Les classements "top" ne portent que sur une période récente
+	switch sortBy {
+	case "top_week":
+		conditions = append(conditions, `p.created_at >= datetime('now', '-7 days')`)
+	case "top_month":
+		conditions = append(conditions, `p.created_at >= datetime('now', '-1 month')`)
+	}
+	
 	if len(conditions) > 0 {
 		query += ` WHERE ` + conditions[0]
 		countQuery += ` WHERE ` + conditions[0]
@@ -200,16 +286,34 @@ func GetPosts(page, perPage int, categoryID int, userID int, sortBy string, curr
 		}
 	}
 	
-	// Ajouter le tri
+	// Ajouter le tri (les compteurs stockés évitent de recompter les réactions pour chaque ligne)
+	// Les posts épinglés dans la liste affichée passent en tête, sauf dans la liste des posts d'un auteur
+	query += ` ORDER BY `
+	if userID == 0 {
+		query += pinnedFirst(categoryID)
+	}
 	switch sortBy {
 	case "likes":
-		query += ` ORDER BY (SELECT COUNT(*) FROM post_reactions WHERE post_id = p.id AND reaction_type = 'like') DESC`
+		query += `p.like_count DESC, p.created_at DESC`
+	case "hot":
+		// Score décroissant avec l'âge du post: (likes - dislikes + commentaires) / (heures + 2)²
+		query += `(p.like_count - p.dislike_count + p.comment_count)
+			/ ((julianday('now') - julianday(p.created_at)) * 24 + 2)
+			/ ((julianday('now') - julianday(p.created_at)) * 24 + 2) DESC, p.created_at DESC`
+	case "controversial":
+		// Beaucoup de réactions, réparties équitablement entre likes et dislikes
+		query += `(p.like_count + p.dislike_count)
+			* MIN(p.like_count, p.dislike_count) * 1.0 / MAX(p.like_count, p.dislike_count, 1) DESC, p.created_at DESC`
+	case "comments":
+		query += `p.comment_count DESC, p.created_at DESC`
+	case "top_week", "top_month":
+		query += `(p.like_count - p.dislike_count) DESC, p.created_at DESC`
 	case "date_desc":
-		query += ` ORDER BY p.created_at DESC`
+		query += `p.created_at DESC`
 	case "date_asc":
-		query += ` ORDER BY p.created_at ASC`
+		query += `p.created_at ASC`
 	default:
-		query += ` ORDER BY p.created_at DESC`
+		query += `p.created_at DESC`
 	}
 	
 	// Ajouter la pagination
@@ -218,88 +322,35 @@ func GetPosts(page, perPage int, categoryID int, userID int, sortBy string, curr
 	
 	// Exécuter la requête pour obtenir le nombre total de posts
 	var total int
-	err := database.DB.QueryRow(countQuery, args[:len(args)-2]...).Scan(&total)
+	err = database.ReadDB.QueryRowContext(ctx, countQuery, args[:len(args)-2]...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 	
 	// Exécuter la requête principale
-	rows, err := database.DB.Query(query, args...)
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	posts, err := scanPosts(rows)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer rows.Close()
 	
-	// Récupérer les résultats
-	var posts []*Post
-	for rows.Next() {
-		post := &Post{}
-		err = rows.Scan(
-			&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.CreatedAt, &post.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
-		}
-		
-		// Récupérer les catégories pour ce post
-		categoryRows, err := database.DB.Query(`
-			SELECT c.id, c.name, c.description
-			FROM categories c
-			JOIN post_categories pc ON c.id = pc.category_id
-			WHERE pc.post_id = ?
-		`, post.ID)
-		if err != nil {
-			return nil, 0, err
-		}
-		
-		post.Categories = []Category{}
-		for categoryRows.Next() {
-			var category Category
-			err = categoryRows.Scan(&category.ID, &category.Name, &category.Description)
-			if err != nil {
-				categoryRows.Close()
-				return nil, 0, err
-			}
-			post.Categories = append(post.Categories, category)
-		}
-		categoryRows.Close()
-		
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM post_reactions WHERE post_id = ? AND reaction_type = 'dislike')
-		`, post.ID, post.ID).Scan(&post.Likes, &post.Dislikes)
-		if err != nil {
-			return nil, 0, err
-		}
-		
-		// Vérifier si l'utilisateur actuel a réagi au post
-		if currentUserID > 0 {
-			var reactionType sql.NullString
-			err = database.DB.QueryRow(`
-				SELECT reaction_type FROM post_reactions
-				WHERE user_id = ? AND post_id = ?
-			`, currentUserID, post.ID).Scan(&reactionType)
-			if err != nil && err != sql.ErrNoRows {
-				return nil, 0, err
-			}
-			if reactionType.Valid {
-				post.UserReaction = reactionType.String
-			}
-		}
-		
-		posts = append(posts, post)
+	// Compléter la page avec les catégories et les réactions, en un nombre fixe de requêtes
+	err = loadPostDetails(ctx, posts, currentUserID)
+	if err != nil {
+		return nil, 0, err
 	}
 	
 	return posts, total, nil
 }
 
 // UpdatePost met à jour un post existant
-func UpdatePost(postID, userID int, title, content string, categoryIDs []int) error {
+func UpdatePost(ctx context.Context, postID, userID int, title, content string, categoryIDs []int) error {
 	// Vérifier si le post existe et appartient à l'utilisateur
 	var count int
-	err := database.DB.QueryRow(
+	err := database.ReadDB.QueryRowContext(ctx, 
 		"SELECT COUNT(*) FROM posts WHERE id = ? AND user_id = ?",
 		postID, userID,
 	).Scan(&count)
@@ -311,14 +362,14 @@ func UpdatePost(postID, userID int, title, content string, categoryIDs []int) er
 	}
 	
 	// Démarrer une transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 	
 	// Mettre à jour le post
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx, 
 		"UPDATE posts SET title = ?, content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
 		title, content, postID,
 	)
@@ -327,14 +378,14 @@ func UpdatePost(postID, userID int, title, content string, categoryIDs []int) er
 	}
 	
 	// Supprimer les anciennes catégories
-	_, err = tx.Exec("DELETE FROM post_categories WHERE post_id = ?", postID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM post_categories WHERE post_id = ?", postID)
 	if err != nil {
 		return err
 	}
 	
 	// Ajouter les nouvelles catégories
 	for _, categoryID := range categoryIDs {
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx, 
 			"INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)",
 			postID, categoryID,
 		)
@@ -343,12 +394,18 @@ func UpdatePost(postID, userID int, title, content string, categoryIDs []int) er
 		}
 	}
 	
+	// Notifier les utilisateurs mentionnés, sauf ceux qui l'ont déjà été pour ce post
+	err = notifyMentionsTx(ctx, tx, userID, postID, 0, content)
+	if err != nil {
+		return err
+	}
+	
 	// Valider la transaction
 	return tx.Commit()
 }
 
 // DeletePost supprime un post
-func DeletePost(postID, userID int, isAdmin bool) error {
+func DeletePost(ctx context.Context, postID, userID int, isAdmin bool) error {
 	// Vérifier si le post existe et si l'utilisateur a le droit de le supprimer
 	var count int
 	var query string
@@ -364,7 +421,7 @@ func DeletePost(postID, userID int, isAdmin bool) error {
 		args = []interface{}{postID, userID}
 	}
 	
-	err := database.DB.QueryRow(query, args...).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -373,91 +430,45 @@ func DeletePost(postID, userID int, isAdmin bool) error {
 	}
 	
 	// Supprimer le post (les contraintes de clé étrangère CASCADE supprimeront aussi les catégories, commentaires et réactions)
-	_, err = database.DB.Exec("DELETE FROM posts WHERE id = ?", postID)
-	return err
-}
-
-// ReactToPost ajoute, modifie ou supprime une réaction (like/dislike) à un post
-func ReactToPost(postID, userID int, reactionType string) error {
-	// Vérifier que le type de réaction est valide
-	if reactionType != "like" && reactionType != "dislike" && reactionType != "" {
-		return errors.New("invalid reaction type")
-	}
-	
-	// Vérifier si l'utilisateur a déjà réagi à ce post
-	var existingReaction string
-	err := database.DB.QueryRow(
-		"SELECT reaction_type FROM post_reactions WHERE user_id = ? AND post_id = ?",
-		userID, postID,
-	).Scan(&existingReaction)
-	
-	// Si une réaction existe déjà
-	if err == nil {
-		// Si la nouvelle réaction est vide, supprimer la réaction existante
-		if reactionType == "" {
-			_, err = database.DB.Exec(
-				"DELETE FROM post_reactions WHERE user_id = ? AND post_id = ?",
-				userID, postID,
-			)
-			return err
-		}
-		
-		// Si la réaction est différente, la mettre à jour
-		if existingReaction != reactionType {
-			_, err = database.DB.Exec(
-				"UPDATE post_reactions SET reaction_type = ? WHERE user_id = ? AND post_id = ?",
-				reactionType, userID, postID,
-			)
-			return err
-		}
-		
-		// Si la réaction est la même, ne rien faire
-		return nil
-	}
-	
-	// Si aucune réaction n'existe et que la nouvelle est vide, ne rien faire
-	if err == sql.ErrNoRows && reactionType == "" {
-		return nil
-	}
-	
-	// Sinon, ajouter la nouvelle réaction
-	_, err = database.DB.Exec(
-		"INSERT INTO post_reactions (user_id, post_id, reaction_type) VALUES (?, ?, ?)",
-		userID, postID, reactionType,
-	)
+	_, err = database.DB.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", postID)
 	return err
 }
 
-// GetAllCategories récupère toutes les catégories disponibles
-func GetAllCategories() ([]Category, error) {
-	rows, err := database.DB.Query("SELECT id, name, description FROM categories")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	var categories []Category
-	for rows.Next() {
-		var category Category
-		err = rows.Scan(&category.ID, &category.Name, &category.Description)
-		if err != nil {
-			return nil, err
-		}
-		categories = append(categories, category)
-	}
-	
-	return categories, nil
+// ReactToPost ajoute la réaction reactionType de l'utilisateur à un post, ou la retire si elle y est déjà
+// Un type vide retire toutes les réactions de l'utilisateur sur le post
+func ReactToPost(ctx context.Context, postID, userID int, reactionType string) error {
+	return react(ctx, ReactionOnPost, postID, userID, reactionType)
 }
 
 // CreateComment crée un nouveau commentaire sur un post
-func CreateComment(content string, userID, postID int) (int, error) {
+func CreateComment(ctx context.Context, content string, userID, postID int) (int, error) {
 	// Vérifier si le contenu n'est pas vide
 	if content == "" {
 		return 0, errors.New("comment content is required")
 	}
 	
+	// Le commentaire et le compteur du post sont modifiés dans la même transaction
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	
+	// Un post verrouillé n'accepte plus de nouveaux commentaires
+	var locked bool
+	err = tx.QueryRowContext(ctx, "SELECT locked_at IS NOT NULL FROM posts WHERE id = ?", postID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return 0, ErrPostNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if locked {
+		return 0, ErrPostLocked
+	}
+	
 	// Insérer le commentaire dans la base de données
-	result, err := database.DB.Exec(
+	result, err := tx.ExecContext(ctx, 
 		"INSERT INTO comments (content, user_id, post_id) VALUES (?, ?, ?)",
 		content, userID, postID,
 	)
@@ -471,13 +482,40 @@ func CreateComment(content string, userID, postID int) (int, error) {
 		return 0, err
 	}
 	
+	// Mettre à jour le nombre de commentaires du post
+	_, err = tx.ExecContext(ctx, "UPDATE posts SET comment_count = comment_count + 1 WHERE id = ?", postID)
+	if err != nil {
+		return 0, err
+	}
+	
+	// Notifier les utilisateurs mentionnés dans le commentaire
+	err = notifyMentionsTx(ctx, tx, userID, postID, int(commentID), content)
+	if err != nil {
+		return 0, err
+	}
+	
+	// Les abonnés du post sont prévenus, puis le commentateur suit le post
+	err = notifyNewCommentTx(ctx, tx, userID, postID, int(commentID))
+	if err != nil {
+		return 0, err
+	}
+	err = subscribeToPostTx(ctx, tx, userID, postID)
+	if err != nil {
+		return 0, err
+	}
+	
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	
 	return int(commentID), nil
 }
 
 // GetCommentsByPostID récupère tous les commentaires d'un post
-func GetCommentsByPostID(postID, currentUserID int) ([]*Comment, error) {
-	rows, err := database.DB.Query(`
-		SELECT c.id, c.content, c.user_id, u.username, c.post_id, c.created_at, c.updated_at
+func GetCommentsByPostID(ctx context.Context, postID, currentUserID int) ([]*Comment, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+commentColumns+`
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		WHERE c.post_id = ?
@@ -486,54 +524,51 @@ func GetCommentsByPostID(postID, currentUserID int) ([]*Comment, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
 	
-	var comments []*Comment
-	for rows.Next() {
-		comment := &Comment{}
-		err = rows.Scan(
-			&comment.ID, &comment.Content, &comment.UserID, &comment.Username, &comment.PostID, &comment.CreatedAt, &comment.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Compter les likes et dislikes
-		err = database.DB.QueryRow(`
-			SELECT 
-				(SELECT COUNT(*) FROM comment_reactions WHERE comment_id = ? AND reaction_type = 'like'),
-				(SELECT COUNT(*) FROM comment_reactions WHERE comment_id = ? AND reaction_type = 'dislike')
-		`, comment.ID, comment.ID).Scan(&comment.Likes, &comment.Dislikes)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Vérifier si l'utilisateur actuel a réagi au commentaire
-		if currentUserID > 0 {
-			var reactionType sql.NullString
-			err = database.DB.QueryRow(`
-				SELECT reaction_type FROM comment_reactions
-				WHERE user_id = ? AND comment_id = ?
-			`, currentUserID, comment.ID).Scan(&reactionType)
-			if err != nil && err != sql.ErrNoRows {
-				return nil, err
-			}
-			if reactionType.Valid {
-				comment.UserReaction = reactionType.String
-			}
-		}
-		
-		comments = append(comments, comment)
+	// Compter les réactions de tous les commentaires en une seule fois
+	err = loadCommentDetails(ctx, comments, currentUserID)
+	if err != nil {
+		return nil, err
 	}
 	
 	return comments, nil
 }
 
+// GetCommentByID récupère un commentaire, avec ses compteurs de réactions
+func GetCommentByID(ctx context.Context, commentID, currentUserID int) (*Comment, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = ?
+	`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, ErrCommentNotFound
+	}
+
+	err = loadCommentDetails(ctx, comments, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	return comments[0], nil
+}
+
 // UpdateComment met à jour un commentaire existant
-func UpdateComment(commentID, userID int, content string) error {
+func UpdateComment(ctx context.Context, commentID, userID int, content string) error {
 	// Vérifier si le commentaire existe et appartient à l'utilisateur
 	var count int
-	err := database.DB.QueryRow(
+	err := database.ReadDB.QueryRowContext(ctx, 
 		"SELECT COUNT(*) FROM comments WHERE id = ? AND user_id = ?",
 		commentID, userID,
 	).Scan(&count)
@@ -544,16 +579,34 @@ func UpdateComment(commentID, userID int, content string) error {
 		return errors.New("comment not found or you don't have permission to edit it")
 	}
 	
+	// Le commentaire et les notifications des mentions sont modifiés dans la même transaction
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
 	// Mettre à jour le commentaire
-	_, err = database.DB.Exec(
-		"UPDATE comments SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+	var postID int
+	err = tx.QueryRowContext(ctx, 
+		"UPDATE comments SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING post_id",
 		content, commentID,
-	)
-	return err
+	).Scan(&postID)
+	if err != nil {
+		return err
+	}
+	
+	// Notifier les utilisateurs mentionnés, sauf ceux qui l'ont déjà été pour ce commentaire
+	err = notifyMentionsTx(ctx, tx, userID, postID, commentID, content)
+	if err != nil {
+		return err
+	}
+	
+	return tx.Commit()
 }
 
 // DeleteComment supprime un commentaire
-func DeleteComment(commentID, userID int, isAdmin bool) error {
+func DeleteComment(ctx context.Context, commentID, userID int, isAdmin bool) error {
 	// Vérifier si le commentaire existe et si l'utilisateur a le droit de le supprimer
 	var count int
 	var query string
@@ -569,7 +622,7 @@ func DeleteComment(commentID, userID int, isAdmin bool) error {
 		args = []interface{}{commentID, userID}
 	}
 	
-	err := database.DB.QueryRow(query, args...).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -577,58 +630,84 @@ func DeleteComment(commentID, userID int, isAdmin bool) error {
 		return errors.New("comment not found or you don't have permission to delete it")
 	}
 	
-	// Supprimer le commentaire (les contraintes CASCADE supprimeront aussi les réactions)
-	_, err = database.DB.Exec("DELETE FROM comments WHERE id = ?", commentID)
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	// Supprimer le commentaire et décrémenter le compteur de son post
+	err = deleteCommentTx(ctx, tx, commentID)
+	if err != nil {
+		return err
+	}
+	
+	return tx.Commit()
+}
+
+// deleteCommentTx supprime un commentaire et met à jour le compteur de commentaires de son post
+// (les contraintes CASCADE supprimeront aussi les réactions)
+func deleteCommentTx(ctx context.Context, tx *sql.Tx, commentID int) error {
+	_, err := tx.ExecContext(ctx, 
+		"UPDATE posts SET comment_count = comment_count - 1 WHERE id = (SELECT post_id FROM comments WHERE id = ?)",
+		commentID,
+	)
+	if err != nil {
+		return err
+	}
+	
+	_, err = tx.ExecContext(ctx, "DELETE FROM comments WHERE id = ?", commentID)
 	return err
 }
 
-// ReactToComment ajoute, modifie ou supprime une réaction (like/dislike) à un commentaire
-func ReactToComment(commentID, userID int, reactionType string) error {
-	// Vérifier que le type de réaction est valide
-	if reactionType != "like" && reactionType != "dislike" && reactionType != "" {
-		return errors.New("invalid reaction type")
-	}
-	
-	// Vérifier si l'utilisateur a déjà réagi à ce commentaire
-	var existingReaction string
-	err := database.DB.QueryRow(
-		"SELECT reaction_type FROM comment_reactions WHERE user_id = ? AND comment_id = ?",
-		userID, commentID,
-	).Scan(&existingReaction)
-	
-	// Si une réaction existe déjà
-	if err == nil {
-		// Si la nouvelle réaction est vide, supprimer la réaction existante
-		if reactionType == "" {
-			_, err = database.DB.Exec(
-				"DELETE FROM comment_reactions WHERE user_id = ? AND comment_id = ?",
-				userID, commentID,
-			)
-			return err
-		}
-		
-		// Si la réaction est différente, la mettre à jour
-		if existingReaction != reactionType {
-			_, err = database.DB.Exec(
-				"UPDATE comment_reactions SET reaction_type = ? WHERE user_id = ? AND comment_id = ?",
-				reactionType, userID, commentID,
-			)
+// ReactToComment ajoute la réaction reactionType de l'utilisateur à un commentaire, ou la retire si elle y est déjà
+// Un type vide retire toutes les réactions de l'utilisateur sur le commentaire
+func ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error {
+	return react(ctx, ReactionOnComment, commentID, userID, reactionType)
+}
+
+// AcceptAnswer choisit le commentaire commentID comme réponse acceptée du post, qui devient résolu
+// commentID vaut 0 pour retirer la réponse acceptée. Les droits sont vérifiés par l'appelant
+func AcceptAnswer(ctx context.Context, postID, commentID int) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE id = ?", postID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPostNotFound
+	}
+
+	if commentID == 0 {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE posts SET accepted_comment_id = NULL, accepted_at = NULL WHERE id = ?", postID)
+		if err != nil {
 			return err
 		}
-		
-		// Si la réaction est la même, ne rien faire
-		return nil
+		return tx.Commit()
 	}
-	
-	// Si aucune réaction n'existe et que la nouvelle est vide, ne rien faire
-	if err == sql.ErrNoRows && reactionType == "" {
-		return nil
+
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM comments WHERE id = ? AND post_id = ?", commentID, postID).Scan(&count)
+	if err != nil {
+		return err
 	}
-	
-	// Sinon, ajouter la nouvelle réaction
-	_, err = database.DB.Exec(
-		"INSERT INTO comment_reactions (user_id, comment_id, reaction_type) VALUES (?, ?, ?)",
-		userID, commentID, reactionType,
-	)
-	return err
-}
\ No newline at end of file
+	if count == 0 {
+		return ErrCommentNotFound
+	}
+
+	// Accepter de nouveau la même réponse garde la date de la première acceptation
+	_, err = tx.ExecContext(ctx, `
+		UPDATE posts SET accepted_comment_id = ?, accepted_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND accepted_comment_id IS NOT ?`, commentID, postID, commentID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"forum/internal/database"
+	"testing"
+)
+
+// Les listes de posts et de commentaires doivent se charger en un nombre fixe de requêtes,
+// quelle que soit la taille de la page: les benchmarks mesurent ce nombre pour plusieurs tailles
+// et échouent s'il grandit
+
+// listingSizes sont les tailles de page mesurées
+var listingSizes = []int{10, 50, 100}
+
+// seedPosts crée count posts de l'auteur, rangés dans deux catégories, chacun avec un commentaire
+// et une réaction de reactorID
+func seedPosts(tb testing.TB, authorID, reactorID, count int) []int {
+	tb.Helper()
+	ctx := context.Background()
+	var postIDs []int
+	for i := 0; i < count; i++ {
+		postID, err := CreatePost(ctx, fmt.Sprintf("Post %d", i), "Contenu du post", authorID, []int{1, 2})
+		if err != nil {
+			tb.Fatalf("creating post: %v", err)
+		}
+		if _, err := CreateComment(ctx, "Un commentaire", reactorID, postID); err != nil {
+			tb.Fatalf("creating comment: %v", err)
+		}
+		if err := ReactToPost(ctx, postID, reactorID, "like"); err != nil {
+			tb.Fatalf("reacting to post: %v", err)
+		}
+		postIDs = append(postIDs, postID)
+	}
+	return postIDs
+}
+
+// benchmarkQueryCount mesure le nombre de requêtes de load pour chaque taille de listingSizes
+// et échoue si ce nombre change d'une taille à l'autre
+func benchmarkQueryCount(b *testing.B, load func(b *testing.B, size int)) {
+	counts := map[int]int64{}
+	for _, size := range listingSizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			queries := countQueries(func() {
+				for i := 0; i < b.N; i++ {
+					load(b, size)
+				}
+			})
+			perOp := queries / int64(b.N)
+			b.ReportMetric(float64(perOp), "queries/op")
+			counts[size] = perOp
+		})
+	}
+	for _, size := range listingSizes[1:] {
+		if counts[size] != counts[listingSizes[0]] {
+			b.Fatalf("query count grows with the page size: %v", counts)
+		}
+	}
+}
+
+func BenchmarkGetPosts(b *testing.B) {
+	openTestDB(b)
+	authorID := createTestUser(b, "author")
+	readerID := createTestUser(b, "reader")
+	seedPosts(b, authorID, readerID, listingSizes[len(listingSizes)-1])
+
+	benchmarkQueryCount(b, func(b *testing.B, size int) {
+		posts, _, err := GetPosts(context.Background(), 1, size, 0, nil, 0, "", "date_desc", readerID)
+		if err != nil || len(posts) != size {
+			b.Fatalf("GetPosts returned %d posts, error %v", len(posts), err)
+		}
+	})
+}
+
+func BenchmarkGetUserPosts(b *testing.B) {
+	openTestDB(b)
+	readerID := createTestUser(b, "reader")
+	authors := map[int]int{}
+	for _, size := range listingSizes {
+		authors[size] = createTestUser(b, fmt.Sprintf("author%d", size))
+		seedPosts(b, authors[size], readerID, size)
+	}
+
+	benchmarkQueryCount(b, func(b *testing.B, size int) {
+		posts, err := GetUserPosts(context.Background(), authors[size])
+		if err != nil || len(posts) != size {
+			b.Fatalf("GetUserPosts returned %d posts, error %v", len(posts), err)
+		}
+	})
+}
+
+func BenchmarkGetUserLikedPosts(b *testing.B) {
+	openTestDB(b)
+	authorID := createTestUser(b, "author")
+	readers := map[int]int{}
+	for _, size := range listingSizes {
+		readers[size] = createTestUser(b, fmt.Sprintf("reader%d", size))
+		seedPosts(b, authorID, readers[size], size)
+	}
+
+	benchmarkQueryCount(b, func(b *testing.B, size int) {
+		posts, err := GetUserLikedPosts(context.Background(), readers[size])
+		if err != nil || len(posts) != size {
+			b.Fatalf("GetUserLikedPosts returned %d posts, error %v", len(posts), err)
+		}
+	})
+}
+
+func BenchmarkGetCommentsByPostID(b *testing.B) {
+	openTestDB(b)
+	ctx := context.Background()
+	authorID := createTestUser(b, "author")
+	readerID := createTestUser(b, "reader")
+	posts := map[int]int{}
+	for _, size := range listingSizes {
+		posts[size] = seedPosts(b, authorID, readerID, 1)[0]
+		for i := 1; i < size; i++ {
+			commentID, err := CreateComment(ctx, "Un autre commentaire", readerID, posts[size])
+			if err != nil {
+				b.Fatalf("creating comment: %v", err)
+			}
+			if err := ReactToComment(ctx, commentID, authorID, "like"); err != nil {
+				b.Fatalf("reacting to comment: %v", err)
+			}
+		}
+	}
+
+	benchmarkQueryCount(b, func(b *testing.B, size int) {
+		comments, err := GetCommentsByPostID(ctx, posts[size], readerID)
+		if err != nil || len(comments) != size {
+			b.Fatalf("GetCommentsByPostID returned %d comments, error %v", len(comments), err)
+		}
+	})
+}
+
+// Les clés étrangères sont activées par la chaîne de connexion: supprimer un post supprime aussi ses
+// commentaires, ses réactions, ses catégories et ses tags, et détache ses images
+func TestDeletePostCascades(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	readerID := createTestUser(t, "reader")
+
+	postID := seedPosts(t, authorID, readerID, 1)[0]
+	if err := SetPostTags(ctx, postID, []string{"golang", "sqlite"}); err != nil {
+		t.Fatalf("tagging post: %v", err)
+	}
+	comments, err := GetCommentsByPostID(ctx, postID, 0)
+	if err != nil || len(comments) != 1 {
+		t.Fatalf("fetching comments: %d comments, error %v", len(comments), err)
+	}
+	if err := ReactToComment(ctx, comments[0].ID, authorID, "like"); err != nil {
+		t.Fatalf("reacting to comment: %v", err)
+	}
+	imageID, err := SaveImage(ctx, "image.png", authorID)
+	if err != nil {
+		t.Fatalf("saving image: %v", err)
+	}
+	if err := AssociateImageWithPost(ctx, imageID, postID); err != nil {
+		t.Fatalf("associating image: %v", err)
+	}
+
+	// Un second post vérifie que seules les lignes du post supprimé disparaissent
+	otherID := seedPosts(t, authorID, readerID, 1)[0]
+
+	if err := DeletePost(ctx, postID, authorID, false); err != nil {
+		t.Fatalf("deleting post: %v", err)
+	}
+
+	checks := []struct {
+		name  string
+		query string
+		args  []interface{}
+		want  int
+	}{
+		{"comments", "SELECT COUNT(*) FROM comments WHERE post_id = ?", []interface{}{postID}, 0},
+		{"comment reactions", "SELECT COUNT(*) FROM comment_reactions WHERE comment_id = ?", []interface{}{comments[0].ID}, 0},
+		{"post reactions", "SELECT COUNT(*) FROM post_reactions WHERE post_id = ?", []interface{}{postID}, 0},
+		{"categories", "SELECT COUNT(*) FROM post_categories WHERE post_id = ?", []interface{}{postID}, 0},
+		{"tags", "SELECT COUNT(*) FROM post_tags WHERE post_id = ?", []interface{}{postID}, 0},
+		{"images", "SELECT COUNT(*) FROM images WHERE post_id = ?", []interface{}{postID}, 0},
+		{"detached image", "SELECT COUNT(*) FROM images WHERE id = ? AND post_id IS NULL", []interface{}{imageID}, 1},
+		{"other post comments", "SELECT COUNT(*) FROM comments WHERE post_id = ?", []interface{}{otherID}, 1},
+		{"other post reactions", "SELECT COUNT(*) FROM post_reactions WHERE post_id = ?", []interface{}{otherID}, 1},
+		{"other post categories", "SELECT COUNT(*) FROM post_categories WHERE post_id = ?", []interface{}{otherID}, 2},
+	}
+	for _, check := range checks {
+		var count int
+		if err := database.ReadDB.QueryRowContext(ctx, check.query, check.args...).Scan(&count); err != nil {
+			t.Fatalf("counting %s: %v", check.name, err)
+		}
+		if count != check.want {
+			t.Errorf("%s: got %d rows, want %d", check.name, count, check.want)
+		}
+	}
+}
+
+// Un post est publié avec ses tags, son sondage et son image, ou pas du tout
+func TestCreatePostWithDetailsIsAtomic(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	otherID := createTestUser(t, "other")
+
+	poll := &Poll{Question: "Oui ou non ?", Options: []*PollOption{{Label: "Oui"}, {Label: "Non"}}}
+	ownImage, err := SaveImage(ctx, "own.png", authorID)
+	if err != nil {
+		t.Fatalf("saving image: %v", err)
+	}
+	postID, err := CreatePostWithDetails(ctx, NewPost{
+		Title: "Complet", Content: "Contenu", UserID: authorID, CategoryIDs: []int{1},
+		Tags: []string{"golang"}, Poll: poll, ImageID: ownImage,
+	})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	if _, err := GetPoll(ctx, postID, 0); err != nil {
+		t.Errorf("poll of the created post: %v", err)
+	}
+	if image, err := GetPostImage(ctx, postID); err != nil || image.ID != ownImage {
+		t.Errorf("image of the created post: %v, error %v", image, err)
+	}
+
+	// L'image d'un autre utilisateur fait échouer la dernière étape: rien ne doit rester
+	otherImage, err := SaveImage(ctx, "other.png", otherID)
+	if err != nil {
+		t.Fatalf("saving image: %v", err)
+	}
+	_, err = CreatePostWithDetails(ctx, NewPost{
+		Title: "Raté", Content: "Contenu", UserID: authorID, CategoryIDs: []int{1},
+		Tags: []string{"sqlite"}, Poll: poll, ImageID: otherImage,
+	})
+	if err == nil {
+		t.Fatal("creating a post with another user's image succeeded")
+	}
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM posts":                            1,
+		"SELECT COUNT(*) FROM polls":                            1,
+		"SELECT COUNT(*) FROM tags WHERE name = 'sqlite'":       0,
+		"SELECT COUNT(*) FROM images WHERE post_id IS NOT NULL": 1,
+	} {
+		var count int
+		if err := database.ReadDB.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if count != want {
+			t.Errorf("%s: got %d, want %d", query, count, want)
+		}
+	}
+}
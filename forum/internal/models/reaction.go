@@ -0,0 +1,368 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"regexp"
+	"strings"
+)
+
+// Contenus auxquels on peut réagir
+const (
+	ReactionOnPost    = "post"
+	ReactionOnComment = "comment"
+)
+
+// MaxReactionLabelLength est le nombre maximal de caractères du libellé d'une réaction
+const MaxReactionLabelLength = 30
+
+// reactionNamePattern est la forme de l'identifiant d'une réaction, stocké avec chaque réaction posée
+var reactionNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,20}$`)
+
+// Erreurs des réactions qui viennent de la saisie de l'utilisateur ou d'un administrateur
+var (
+	ErrInvalidReactionType    = errors.New("invalid reaction type")
+	ErrReactionArchived       = errors.New("reaction is archived")
+	ErrInvalidReactionTarget  = errors.New("invalid reaction target")
+	ErrReactionTargetNotFound = errors.New("reaction target not found")
+	ErrInvalidReactionName    = errors.New("invalid reaction name")
+	ErrInvalidReactionEmoji   = errors.New("invalid reaction emoji")
+	ErrReactionLabelRequired  = errors.New("reaction label is required")
+	ErrReactionLabelTooLong   = errors.New("reaction label is too long")
+	ErrInvalidReactionScore   = errors.New("invalid reaction score")
+	ErrReactionExists         = errors.New("reaction already exists")
+	ErrReactionNotFound       = errors.New("reaction not found")
+)
+
+// Reaction est une réaction proposée sur les posts et les commentaires, configurée par les administrateurs
+type Reaction struct {
+	ID       int
+	Name     string // Identifiant stable, stocké dans reaction_type
+	Emoji    string
+	Label    string
+	Score    int // +1 ou -1 si la réaction compte dans le score du contenu (like_count/dislike_count), 0 sinon
+	Position int
+	Archived bool // Une réaction archivée ne peut plus être ajoutée, celles déjà posées restent affichées
+}
+
+// ReactionCount est le nombre de réactions d'un type sur un post ou un commentaire
+type ReactionCount struct {
+	Reaction
+	Count int
+	Mine  bool     // L'utilisateur actuel a posé cette réaction
+	Users []string // Noms des utilisateurs qui ont réagi, remplis seulement par GetReactors
+}
+
+// ValidReactionTarget indique si targetType est un contenu auquel on peut réagir
+func ValidReactionTarget(targetType string) bool {
+	return targetType == ReactionOnPost || targetType == ReactionOnComment
+}
+
+// reactionTables renvoie la table des réactions d'un type de contenu, sa colonne et la table du contenu
+func reactionTables(targetType string) (table, column, items string) {
+	if targetType == ReactionOnComment {
+		return "comment_reactions", "comment_id", "comments"
+	}
+	return "post_reactions", "post_id", "posts"
+}
+
+// ValidateReaction nettoie et vérifie les champs d'une réaction
+func ValidateReaction(reaction *Reaction) error {
+	reaction.Name = strings.TrimSpace(reaction.Name)
+	reaction.Emoji = strings.TrimSpace(reaction.Emoji)
+	reaction.Label = strings.Join(strings.Fields(reaction.Label), " ")
+
+	if !reactionNamePattern.MatchString(reaction.Name) {
+		return ErrInvalidReactionName
+	}
+	if reaction.Emoji == "" || len([]rune(reaction.Emoji)) > 8 {
+		return ErrInvalidReactionEmoji
+	}
+	if reaction.Label == "" {
+		return ErrReactionLabelRequired
+	}
+	if len([]rune(reaction.Label)) > MaxReactionLabelLength {
+		return ErrReactionLabelTooLong
+	}
+	if reaction.Score < -1 || reaction.Score > 1 {
+		return ErrInvalidReactionScore
+	}
+	return nil
+}
+
+// GetReactions renvoie toutes les réactions, archivées comprises, dans l'ordre d'affichage
+func GetReactions(ctx context.Context) ([]Reaction, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT id, name, emoji, label, score, position, archived
+		FROM reactions
+		ORDER BY position, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []Reaction{}
+	for rows.Next() {
+		var r Reaction
+		if err := rows.Scan(&r.ID, &r.Name, &r.Emoji, &r.Label, &r.Score, &r.Position, &r.Archived); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, r)
+	}
+	return reactions, rows.Err()
+}
+
+// CreateReaction ajoute une réaction, placée après toutes les autres
+func CreateReaction(ctx context.Context, reaction Reaction) (int, error) {
+	if err := ValidateReaction(&reaction); err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		INSERT INTO reactions (name, emoji, label, score, position, archived)
+		VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM reactions), ?)`,
+		reaction.Name, reaction.Emoji, reaction.Label, reaction.Score, reaction.Archived,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: reactions.name") {
+			return 0, ErrReactionExists
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateReaction modifie l'emoji, le libellé, le score et l'archivage d'une réaction
+// L'identifiant ne change pas: il est stocké avec chaque réaction posée. Changer le score
+// recalcule les compteurs de tous les posts et commentaires
+func UpdateReaction(ctx context.Context, reaction Reaction) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldScore int
+	err = tx.QueryRowContext(ctx, "SELECT name, score FROM reactions WHERE id = ?", reaction.ID).Scan(&reaction.Name, &oldScore)
+	if err == sql.ErrNoRows {
+		return ErrReactionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := ValidateReaction(&reaction); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reactions SET emoji = ?, label = ?, score = ?, archived = ? WHERE id = ?",
+		reaction.Emoji, reaction.Label, reaction.Score, reaction.Archived, reaction.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if reaction.Score != oldScore {
+		for _, targetType := range []string{ReactionOnPost, ReactionOnComment} {
+			if _, err := tx.ExecContext(ctx, reactionCounterUpdate(targetType)); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// reactionCounterUpdate renvoie la requête qui recalcule like_count et dislike_count d'après les réactions
+// posées et le score de chaque réaction. Sans condition, elle met à jour tous les contenus
+func reactionCounterUpdate(targetType string) string {
+	table, column, items := reactionTables(targetType)
+	count := func(sign string) string {
+		return `(SELECT COUNT(*) FROM ` + table + ` x JOIN reactions r ON r.name = x.reaction_type
+			WHERE x.` + column + ` = ` + items + `.id AND r.score ` + sign + ` 0)`
+	}
+	return "UPDATE " + items + " SET like_count = " + count(">") + ", dislike_count = " + count("<")
+}
+
+// react ajoute la réaction reactionType de l'utilisateur à un contenu, ou la retire si elle y est déjà
+// Un type vide retire toutes les réactions de l'utilisateur sur ce contenu. Une réaction qui compte dans
+// le score remplace les réactions de score opposé de l'utilisateur: on ne peut pas aimer et ne pas aimer
+// le même contenu
+func react(ctx context.Context, targetType string, itemID, userID int, reactionType string) error {
+	table, column, _ := reactionTables(targetType)
+
+	// La réaction et les compteurs du contenu sont modifiés dans la même transaction
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Les réactions d'un post verrouillé et de ses commentaires sont figées
+	locked, err := reactionTargetLockedTx(ctx, tx, targetType, itemID)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrPostLocked
+	}
+
+	if reactionType == "" {
+		_, err = tx.ExecContext(ctx,
+			"DELETE FROM "+table+" WHERE user_id = ? AND "+column+" = ?", userID, itemID)
+	} else {
+		var archived bool
+		var score int
+		err = tx.QueryRowContext(ctx, "SELECT archived, score FROM reactions WHERE name = ?", reactionType).Scan(&archived, &score)
+		if err == sql.ErrNoRows {
+			return ErrInvalidReactionType
+		}
+		if err != nil {
+			return err
+		}
+
+		var result sql.Result
+		result, err = tx.ExecContext(ctx,
+			"DELETE FROM "+table+" WHERE user_id = ? AND "+column+" = ? AND reaction_type = ?",
+			userID, itemID, reactionType)
+		if err != nil {
+			return err
+		}
+		var removed int64
+		if removed, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		if removed == 0 {
+			if archived {
+				return ErrReactionArchived
+			}
+			if score != 0 {
+				_, err = tx.ExecContext(ctx, `
+					DELETE FROM `+table+` WHERE user_id = ? AND `+column+` = ?
+					AND reaction_type IN (SELECT name FROM reactions WHERE score = ?)`,
+					userID, itemID, -score)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO "+table+" (user_id, "+column+", reaction_type) VALUES (?, ?, ?)",
+				userID, itemID, reactionType)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Mettre à jour les compteurs dénormalisés du contenu
+	if _, err = tx.ExecContext(ctx, reactionCounterUpdate(targetType)+" WHERE id = ?", itemID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadReactionCounts compte les réactions de chaque contenu de la liste, en une requête en plus de celle
+// du jeu de réactions. Les réactions archivées que personne n'a posées sur un contenu sont omises
+func loadReactionCounts(ctx context.Context, targetType string, ids []int, currentUserID int) (map[int][]ReactionCount, error) {
+	reactions, err := GetReactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in, args := inClause(ids)
+
+	table, column, _ := reactionTables(targetType)
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+column+`, reaction_type, COUNT(*), MAX(user_id = ?)
+		FROM `+table+`
+		WHERE `+column+` IN `+in+`
+		GROUP BY `+column+`, reaction_type`,
+		append([]interface{}{currentUserID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type countKey struct {
+		itemID int
+		name   string
+	}
+	counts := map[countKey]ReactionCount{}
+	for rows.Next() {
+		var key countKey
+		var count ReactionCount
+		if err := rows.Scan(&key.itemID, &key.name, &count.Count, &count.Mine); err != nil {
+			return nil, err
+		}
+		counts[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byItem := map[int][]ReactionCount{}
+	for _, itemID := range ids {
+		list := make([]ReactionCount, 0, len(reactions))
+		for _, reaction := range reactions {
+			count := counts[countKey{itemID, reaction.Name}]
+			if reaction.Archived && count.Count == 0 {
+				continue
+			}
+			count.Reaction = reaction
+			list = append(list, count)
+		}
+		byItem[itemID] = list
+	}
+	return byItem, nil
+}
+
+// GetReactors renvoie, pour chaque réaction posée sur un contenu, les utilisateurs qui l'ont posée
+// dans l'ordre où ils ont réagi. Les réactions que personne n'a posées sont omises
+func GetReactors(ctx context.Context, targetType string, itemID int) ([]ReactionCount, error) {
+	if !ValidReactionTarget(targetType) {
+		return nil, ErrInvalidReactionTarget
+	}
+	table, column, items := reactionTables(targetType)
+
+	var count int
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+items+" WHERE id = ?", itemID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrReactionTargetNotFound
+	}
+
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT r.id, r.name, r.emoji, r.label, r.score, r.position, r.archived, u.username
+		FROM `+table+` x
+		JOIN reactions r ON r.name = x.reaction_type
+		JOIN users u ON u.id = x.user_id
+		WHERE x.`+column+` = ?
+		ORDER BY r.position, r.id, x.created_at, u.username`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactors := []ReactionCount{}
+	for rows.Next() {
+		var r Reaction
+		var username string
+		if err := rows.Scan(&r.ID, &r.Name, &r.Emoji, &r.Label, &r.Score, &r.Position, &r.Archived, &username); err != nil {
+			return nil, err
+		}
+		if len(reactors) == 0 || reactors[len(reactors)-1].ID != r.ID {
+			reactors = append(reactors, ReactionCount{Reaction: r})
+		}
+		last := &reactors[len(reactors)-1]
+		last.Count++
+		last.Users = append(last.Users, username)
+	}
+	return reactors, rows.Err()
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+// Un like remplace le dislike du même utilisateur, et inversement; les réactions sans score s'ajoutent
+func TestScoreReactionsAreExclusive(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	readerID := createTestUser(t, "reader")
+	postID := seedPosts(t, authorID, readerID, 1)[0]
+
+	if _, err := CreateReaction(ctx, Reaction{Name: "heart", Emoji: "❤️", Label: "Bravo"}); err != nil {
+		t.Fatalf("creating reaction: %v", err)
+	}
+	for _, reactionType := range []string{"heart", "dislike"} {
+		if err := ReactToPost(ctx, postID, readerID, reactionType); err != nil {
+			t.Fatalf("reacting %s: %v", reactionType, err)
+		}
+	}
+
+	post, err := GetPostByID(ctx, postID, readerID)
+	if err != nil {
+		t.Fatalf("fetching post: %v", err)
+	}
+	if post.Likes != 0 || post.Dislikes != 1 {
+		t.Errorf("counters: %d likes, %d dislikes, want 0 and 1", post.Likes, post.Dislikes)
+	}
+	mine := map[string]bool{}
+	for _, count := range post.Reactions {
+		mine[count.Name] = count.Mine
+	}
+	if mine["like"] || !mine["dislike"] || !mine["heart"] {
+		t.Errorf("reader's reactions: %v, want dislike and heart", mine)
+	}
+}
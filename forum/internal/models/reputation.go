@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"forum/internal/database"
+)
+
+// Points de réputation gagnés ou perdus pour chaque événement
+const (
+	ReputationPerUpvote         = 2   // Réaction positive reçue d'un autre utilisateur
+	ReputationPerDownvote       = -1  // Réaction négative reçue d'un autre utilisateur
+	ReputationPerAcceptedAnswer = 15  // Commentaire accepté comme réponse au post d'un autre utilisateur
+	ReputationPerApprovedPost   = 5   // Post approuvé par les modérateurs
+	ReputationPerRejectedPost   = -5  // Post refusé par les modérateurs
+	ReputationPerUpheldReport   = -20 // Contenu supprimé après un signalement approuvé
+)
+
+// TrustLevel est le niveau de confiance d'un utilisateur, déduit de sa réputation
+type TrustLevel int
+
+// Niveaux de confiance, du nouveau venu à l'habitué
+const (
+	TrustNew TrustLevel = iota
+	TrustBasic
+	TrustMember
+	TrustRegular
+)
+
+// trustThresholds est la réputation minimale de chaque niveau de confiance
+var trustThresholds = []int{0, 10, 50, 200}
+
+// Niveau de confiance minimal de chaque capacité. Les modérateurs et les administrateurs ont toutes les capacités
+const (
+	MinTrustSkipModeration = TrustBasic  // Publier un post sans passer par la file de modération
+	MinTrustLinks          = TrustBasic  // Mettre des liens ou des images externes dans un post ou un commentaire
+	MinTrustImages         = TrustMember // Envoyer des images
+)
+
+// Label renvoie le nom du niveau de confiance affiché aux utilisateurs
+func (t TrustLevel) Label() string {
+	switch t {
+	case TrustBasic:
+		return "Membre"
+	case TrustMember:
+		return "Membre confirmé"
+	case TrustRegular:
+		return "Habitué"
+	}
+	return "Nouveau"
+}
+
+// Reputation détaille le score de réputation d'un utilisateur
+type Reputation struct {
+	UserID          int
+	Upvotes         int // Réactions positives reçues d'autres utilisateurs sur ses posts et ses commentaires
+	Downvotes       int // Réactions négatives reçues d'autres utilisateurs
+	AcceptedAnswers int // Commentaires acceptés comme réponse au post d'un autre utilisateur
+	ApprovedPosts   int // Posts approuvés par les modérateurs
+	RejectedPosts   int // Posts refusés par les modérateurs
+	UpheldReports   int // Contenus supprimés après un signalement approuvé
+	Score           int // Total des points, qui peut être négatif
+	TrustLevel      TrustLevel
+	MinTrustLevel   TrustLevel // Niveau acquis quel que soit le score, comme celui des comptes inscrits avant la réputation
+	NextLevelAt     int        // Réputation du niveau suivant, 0 au niveau maximal
+	Staff           bool       // Modérateur ou administrateur: toutes les capacités sans condition de niveau
+}
+
+// Compute calcule le score et le niveau de confiance à partir du détail de la réputation
+func (r *Reputation) Compute() {
+	r.Score = r.Upvotes*ReputationPerUpvote +
+		r.Downvotes*ReputationPerDownvote +
+		r.AcceptedAnswers*ReputationPerAcceptedAnswer +
+		r.ApprovedPosts*ReputationPerApprovedPost +
+		r.RejectedPosts*ReputationPerRejectedPost +
+		r.UpheldReports*ReputationPerUpheldReport
+
+	r.TrustLevel = TrustNew
+	r.NextLevelAt = 0
+	for level, threshold := range trustThresholds {
+		if r.Score < threshold {
+			r.NextLevelAt = threshold
+			break
+		}
+		r.TrustLevel = TrustLevel(level)
+	}
+
+	// Le niveau minimal du compte l'emporte sur le score, et le niveau suivant est celui qui le dépasse
+	if r.TrustLevel < r.MinTrustLevel {
+		r.TrustLevel = r.MinTrustLevel
+		r.NextLevelAt = 0
+		if next := int(r.MinTrustLevel) + 1; next < len(trustThresholds) {
+			r.NextLevelAt = trustThresholds[next]
+		}
+	}
+}
+
+// Allows indique si l'utilisateur a le niveau de confiance demandé par une capacité
+func (r *Reputation) Allows(minLevel TrustLevel) bool {
+	return r.Staff || r.TrustLevel >= minLevel
+}
+
+// GetReputation calcule la réputation d'un utilisateur à partir des réactions reçues, des réponses
+// acceptées et des décisions de modération. Les réactions d'un utilisateur à ses propres contenus
+// et les réponses qu'il accepte sur ses propres posts ne comptent pas
+func GetReputation(ctx context.Context, userID int) (*Reputation, error) {
+	reputation := &Reputation{UserID: userID}
+	var role string
+	err := database.ReadDB.QueryRowContext(ctx, `
+		SELECT u.role, u.min_trust_level,
+			(SELECT COUNT(*) FROM post_reactions x JOIN posts p ON p.id = x.post_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE p.user_id = u.id AND x.user_id != u.id AND r.score > 0)
+			+ (SELECT COUNT(*) FROM comment_reactions x JOIN comments c ON c.id = x.comment_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE c.user_id = u.id AND x.user_id != u.id AND r.score > 0),
+			(SELECT COUNT(*) FROM post_reactions x JOIN posts p ON p.id = x.post_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE p.user_id = u.id AND x.user_id != u.id AND r.score < 0)
+			+ (SELECT COUNT(*) FROM comment_reactions x JOIN comments c ON c.id = x.comment_id JOIN reactions r ON r.name = x.reaction_type
+				WHERE c.user_id = u.id AND x.user_id != u.id AND r.score < 0),
+			(SELECT COUNT(*) FROM posts p JOIN comments c ON c.id = p.accepted_comment_id
+				WHERE c.user_id = u.id AND p.user_id != u.id),
+			(SELECT COUNT(*) FROM pending_posts WHERE user_id = u.id AND status = 'approved'),
+			(SELECT COUNT(*) FROM pending_posts WHERE user_id = u.id AND status = 'rejected'),
+			(SELECT COUNT(*) FROM reports WHERE content_user_id = u.id AND status = 'approved'
+				AND type IN ('post', 'comment', 'message'))
+		FROM users u
+		WHERE u.id = ?`, userID).Scan(
+		&role, &reputation.MinTrustLevel, &reputation.Upvotes, &reputation.Downvotes, &reputation.AcceptedAnswers,
+		&reputation.ApprovedPosts, &reputation.RejectedPosts, &reputation.UpheldReports,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reputation.Staff = role == "moderator" || role == "admin"
+	reputation.Compute()
+	return reputation, nil
+}
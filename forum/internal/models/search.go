@@ -0,0 +1,348 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"forum/internal/database"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Marqueurs utilisés par snippet() pour entourer les termes trouvés
+// On les remplace par des balises <mark> après avoir échappé le texte
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+// SearchQuery représente une recherche analysée à partir de la saisie de l'utilisateur
+type SearchQuery struct {
+	Raw      string   // Texte saisi par l'utilisateur
+	Terms    []string // Mots simples (un "*" final active la recherche par préfixe)
+	Phrases  []string // Expressions entre guillemets
+	Author   string   // Filtre "author:nom"
+	Category string   // Filtre "category:nom"
+	After    string   // Filtre "after:AAAA-MM-JJ" (inclus)
+	Before   string   // Filtre "before:AAAA-MM-JJ" (inclus)
+}
+
+// SearchResult représente un post ou un commentaire trouvé par la recherche
+type SearchResult struct {
+	Type      string    // "post" ou "comment"
+	PostID    int       // ID du post (ou du post parent pour un commentaire)
+	CommentID int       // ID du commentaire (0 pour un post)
+	Title     string    // Titre du post
+	TitleMark string    // Titre du post avec marqueurs autour des termes trouvés
+	Snippet   string    // Extrait contenant les termes trouvés, avec marqueurs
+	UserID    int       // Auteur du post ou du commentaire
+	Username  string    // Nom de l'auteur
+	CreatedAt time.Time // Date de création
+	Likes     int       // Nombre de likes
+	Dislikes  int       // Nombre de dislikes
+	Score     float64   // Score de pertinence (plus petit = plus pertinent)
+}
+
+// HighlightedSnippet renvoie l'extrait échappé avec les termes trouvés entourés de <mark>
+func (r *SearchResult) HighlightedSnippet() template.HTML {
+	return highlightMarks(r.Snippet)
+}
+
+// HighlightedTitle renvoie le titre échappé avec les termes trouvés entourés de <mark>
+func (r *SearchResult) HighlightedTitle() template.HTML {
+	if r.TitleMark == "" {
+		return template.HTML(html.EscapeString(r.Title))
+	}
+	return highlightMarks(r.TitleMark)
+}
+
+// highlightMarks échappe un texte et remplace les marqueurs de snippet() et highlight() par des balises <mark>
+func highlightMarks(text string) template.HTML {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, highlightEnd, "</mark>")
+	return template.HTML(escaped)
+}
+
+// PlainSnippet renvoie l'extrait sans marqueurs (pour l'API JSON)
+func (r *SearchResult) PlainSnippet() string {
+	return strings.NewReplacer(highlightStart, "", highlightEnd, "").Replace(r.Snippet)
+}
+
+// ParseSearchQuery analyse la syntaxe de recherche:
+// mots, "expressions exactes", author:nom, category:nom, after:AAAA-MM-JJ, before:AAAA-MM-JJ
+func ParseSearchQuery(raw string) SearchQuery {
+	query := SearchQuery{Raw: raw}
+
+	for _, token := range tokenizeSearch(raw) {
+		// Une expression entre guillemets
+		if strings.HasPrefix(token, `"`) {
+			phrase := strings.TrimSpace(strings.Trim(token, `"`))
+			if phrase != "" {
+				query.Phrases = append(query.Phrases, phrase)
+			}
+			continue
+		}
+
+		// Un filtre de la forme clé:valeur
+		if key, value, found := strings.Cut(token, ":"); found && value != "" {
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(key) {
+			case "author", "user":
+				query.Author = value
+				continue
+			case "category", "cat":
+				query.Category = value
+				continue
+			case "after", "since":
+				if isSearchDate(value) {
+					query.After = value
+					continue
+				}
+			case "before", "until":
+				if isSearchDate(value) {
+					query.Before = value
+					continue
+				}
+			}
+		}
+
+		// Sinon, un mot simple
+		if term := cleanSearchTerm(token); term != "" {
+			query.Terms = append(query.Terms, term)
+		}
+	}
+
+	return query
+}
+
+// tokenizeSearch découpe la saisie en gardant les guillemets ensemble (y compris après "clé:")
+func tokenizeSearch(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// cleanSearchTerm ne garde que les lettres et chiffres d'un mot, et un éventuel "*" final
+func cleanSearchTerm(token string) string {
+	prefix := strings.HasSuffix(token, "*")
+	term := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' {
+			return r
+		}
+		return ' '
+	}, token)
+	term = strings.Join(strings.Fields(term), " ")
+	if term == "" {
+		return ""
+	}
+	if prefix {
+		return term + "*"
+	}
+	return term
+}
+
+// isSearchDate vérifie qu'une date est au format AAAA-MM-JJ
+func isSearchDate(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// IsEmpty indique si la recherche ne contient ni texte ni filtre
+func (q SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && q.Author == "" && q.Category == "" && q.After == "" && q.Before == ""
+}
+
+// MatchExpression construit l'expression MATCH de FTS5
+// Chaque mot est mis entre guillemets pour que la saisie ne puisse pas casser la syntaxe FTS5
+func (q SearchQuery) MatchExpression() string {
+	var parts []string
+	for _, term := range q.Terms {
+		if strings.HasSuffix(term, "*") {
+			parts = append(parts, quoteFTS(strings.TrimSuffix(term, "*"))+"*")
+		} else {
+			parts = append(parts, quoteFTS(term))
+		}
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, quoteFTS(phrase))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// quoteFTS entoure une chaîne de guillemets en doublant ceux qu'elle contient
+func quoteFTS(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// searchFilters construit les conditions SQL communes aux posts et aux commentaires
+// dateColumn et authorAlias désignent l'élément recherché, postAlias le post concerné
+func (q SearchQuery) searchFilters(dateColumn, authorAlias, postAlias string) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if q.Author != "" {
+		conditions = append(conditions, authorAlias+".username = ? COLLATE NOCASE")
+		args = append(args, q.Author)
+	}
+	if q.Category != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM post_categories spc
+			JOIN categories sc ON sc.id = spc.category_id
+			WHERE spc.post_id = `+postAlias+`.id AND sc.name = ? COLLATE NOCASE
+		)`)
+		args = append(args, q.Category)
+	}
+	if q.After != "" {
+		conditions = append(conditions, "date("+dateColumn+") >= ?")
+		args = append(args, q.After)
+	}
+	if q.Before != "" {
+		conditions = append(conditions, "date("+dateColumn+") <= ?")
+		args = append(args, q.Before)
+	}
+
+	return conditions, args
+}
+
+// Search recherche dans les posts et les commentaires que l'utilisateur currentUserID (0 pour un visiteur)
+// peut lire, avec les mêmes droits de lecture des catégories que GetPosts
+// Le classement combine BM25 (le titre pèse plus que le contenu) et le solde de réactions
+func Search(ctx context.Context, query SearchQuery, page, perPage int, currentUserID int) ([]*SearchResult, int, error) {
+	if !database.SearchEnabled {
+		return nil, 0, errors.New("search is not available")
+	}
+	if query.IsEmpty() {
+		return nil, 0, errors.New("empty search query")
+	}
+
+	offset := (page - 1) * perPage
+	match := query.MatchExpression()
+
+	readable, readableArgs, err := viewerReadCondition(ctx, currentUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var unionQuery string
+	var args []interface{}
+
+	if match != "" {
+		postConditions, postArgs := query.searchFilters("p.created_at", "u", "p")
+		commentConditions, commentArgs := query.searchFilters("c.created_at", "u", "p")
+		postConditions, postArgs = append(postConditions, readable), append(postArgs, readableArgs...)
+		commentConditions, commentArgs = append(commentConditions, readable), append(commentArgs, readableArgs...)
+
+		// Le score BM25 est négatif: plus il est petit, plus le résultat est pertinent
+		// On le multiplie par un bonus qui grandit avec le solde de likes
+		unionQuery = `
+			SELECT 'post' AS type, p.id AS post_id, 0 AS comment_id, p.title AS title,
+			       highlight(posts_fts, 0, char(2), char(3)) AS title_mark,
+			       snippet(posts_fts, 1, char(2), char(3), '…', 24) AS snippet,
+			       p.user_id AS user_id, u.username AS username, p.created_at AS created_at,
+			       p.like_count AS likes, p.dislike_count AS dislikes,
+			       bm25(posts_fts, 10.0, 1.0) * (1.0 + 0.1 * MAX(p.like_count - p.dislike_count, 0)) AS score
+			FROM posts_fts
+			JOIN posts p ON p.id = posts_fts.rowid
+			JOIN users u ON u.id = p.user_id
+			WHERE posts_fts MATCH ?` + joinConditions(postConditions) + `
+
+			UNION ALL
+
+			SELECT 'comment' AS type, p.id AS post_id, c.id AS comment_id, p.title AS title,
+			       p.title AS title_mark,
+			       snippet(comments_fts, 0, char(2), char(3), '…', 24) AS snippet,
+			       c.user_id AS user_id, u.username AS username, c.created_at AS created_at,
+			       c.like_count AS likes, c.dislike_count AS dislikes,
+			       bm25(comments_fts) * (1.0 + 0.1 * MAX(c.like_count - c.dislike_count, 0)) AS score
+			FROM comments_fts
+			JOIN comments c ON c.id = comments_fts.rowid
+			JOIN posts p ON p.id = c.post_id
+			JOIN users u ON u.id = c.user_id
+			WHERE comments_fts MATCH ?` + joinConditions(commentConditions)
+
+		args = append(args, match)
+		args = append(args, postArgs...)
+		args = append(args, match)
+		args = append(args, commentArgs...)
+	} else {
+		// Sans texte à chercher, seuls les filtres s'appliquent: on liste les posts du plus récent au plus ancien
+		postConditions, postArgs := query.searchFilters("p.created_at", "u", "p")
+		postConditions, postArgs = append(postConditions, readable), append(postArgs, readableArgs...)
+
+		unionQuery = `
+			SELECT 'post' AS type, p.id AS post_id, 0 AS comment_id, p.title AS title,
+			       p.title AS title_mark,
+			       substr(p.content, 1, 200) AS snippet,
+			       p.user_id AS user_id, u.username AS username, p.created_at AS created_at,
+			       p.like_count AS likes, p.dislike_count AS dislikes,
+			       -julianday(p.created_at) AS score
+			FROM posts p
+			JOIN users u ON u.id = p.user_id
+			WHERE 1 = 1` + joinConditions(postConditions)
+
+		args = append(args, postArgs...)
+	}
+
+	// Compter le nombre total de résultats pour la pagination
+	var total int
+	err = database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+unionQuery+")", args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.ReadDB.QueryContext(ctx, unionQuery+` ORDER BY score ASC, created_at DESC LIMIT ? OFFSET ?`, append(args, perPage, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*SearchResult
+	for rows.Next() {
+		result := &SearchResult{}
+		err = rows.Scan(
+			&result.Type, &result.PostID, &result.CommentID, &result.Title, &result.TitleMark, &result.Snippet,
+			&result.UserID, &result.Username, &result.CreatedAt, &result.Likes, &result.Dislikes, &result.Score,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// joinConditions ajoute des conditions à une clause WHERE existante
+func joinConditions(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internal/database"
+	"time"
+)
+
+// Cibles d'un abonnement
+const (
+	SubscriptionPost     = "post"     // Nouveaux commentaires d'un post
+	SubscriptionCategory = "category" // Nouveaux posts d'une catégorie et de ses sous-catégories
+	SubscriptionUser     = "user"     // Nouveaux posts d'un utilisateur
+)
+
+// Subscription est l'abonnement d'un utilisateur à un post, une catégorie ou un autre utilisateur
+type Subscription struct {
+	ID         int
+	UserID     int
+	TargetType string // SubscriptionPost, SubscriptionCategory ou SubscriptionUser
+	TargetID   int
+	TargetName string // Titre du post, nom de la catégorie ou de l'utilisateur (non stocké dans la table subscriptions)
+	Muted      bool   // Un abonnement muet ne notifie rien et ses posts ne sont pas dans le fil
+	CreatedAt  time.Time
+}
+
+// ValidSubscriptionTarget indique si targetType est une cible d'abonnement connue
+func ValidSubscriptionTarget(targetType string) bool {
+	return targetType == SubscriptionPost || targetType == SubscriptionCategory || targetType == SubscriptionUser
+}
+
+// subscriptionTargetTables donne la table de chaque cible, pour vérifier qu'elle existe
+var subscriptionTargetTables = map[string]string{
+	SubscriptionPost:     "posts",
+	SubscriptionCategory: "categories",
+	SubscriptionUser:     "users",
+}
+
+// checkSubscriptionTarget vérifie qu'un utilisateur peut s'abonner à une cible
+func checkSubscriptionTarget(ctx context.Context, q querier, userID int, targetType string, targetID int) error {
+	table, ok := subscriptionTargetTables[targetType]
+	if !ok {
+		return errors.New("invalid subscription target")
+	}
+	if targetType == SubscriptionUser && targetID == userID {
+		return errors.New("cannot follow yourself")
+	}
+
+	// Un post ou une catégorie que l'utilisateur ne peut pas lire n'existe pas pour lui
+	role, categories, err := viewerCategories(ctx, userID)
+	if err != nil {
+		return err
+	}
+	query, args := "SELECT COUNT(*) FROM "+table+" WHERE id = ?", []interface{}{targetID}
+	switch targetType {
+	case SubscriptionPost:
+		readable, readableArgs := readablePostsCondition(categories, role)
+		query, args = "SELECT COUNT(*) FROM posts p WHERE p.id = ? AND "+readable, append(args, readableArgs...)
+	case SubscriptionCategory:
+		for _, id := range HiddenCategoryIDs(categories, role) {
+			if id == targetID {
+				return errors.New("subscription target not found")
+			}
+		}
+	}
+
+	var count int
+	err = q.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("subscription target not found")
+	}
+	return nil
+}
+
+// setSubscription crée l'abonnement ou modifie son réglage muet
+func setSubscription(ctx context.Context, userID int, targetType string, targetID int, muted bool) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkSubscriptionTarget(ctx, tx, userID, targetType, targetID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO subscriptions (user_id, target_type, target_id, muted) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE SET muted = excluded.muted`,
+		userID, targetType, targetID, muted,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Follow abonne un utilisateur à une cible. Suivre de nouveau une cible muette la rend active
+func Follow(ctx context.Context, userID int, targetType string, targetID int) error {
+	return setSubscription(ctx, userID, targetType, targetID, false)
+}
+
+// SetSubscriptionMuted rend un abonnement muet ou actif
+// Rendre muet un post qu'on ne suit pas encore empêche l'abonnement automatique quand on le commente
+func SetSubscriptionMuted(ctx context.Context, userID int, targetType string, targetID int, muted bool) error {
+	return setSubscription(ctx, userID, targetType, targetID, muted)
+}
+
+// Unfollow supprime un abonnement
+func Unfollow(ctx context.Context, userID int, targetType string, targetID int) error {
+	result, err := database.DB.ExecContext(ctx,
+		"DELETE FROM subscriptions WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		userID, targetType, targetID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("subscription not found")
+	}
+	return nil
+}
+
+// subscriptionColumns sont les colonnes lues par scanSubscription
+const subscriptionColumns = `s.id, s.user_id, s.target_type, s.target_id,
+	COALESCE(CASE s.target_type
+		WHEN 'post' THEN (SELECT title FROM posts WHERE id = s.target_id)
+		WHEN 'category' THEN (SELECT name FROM categories WHERE id = s.target_id)
+		WHEN 'user' THEN (SELECT username FROM users WHERE id = s.target_id)
+	END, '') AS target_name, s.muted, s.created_at`
+
+func scanSubscription(row rowScanner) (*Subscription, error) {
+	s := &Subscription{}
+	err := row.Scan(&s.ID, &s.UserID, &s.TargetType, &s.TargetID, &s.TargetName, &s.Muted, &s.CreatedAt)
+	return s, err
+}
+
+// GetSubscription renvoie l'abonnement d'un utilisateur à une cible
+func GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (*Subscription, error) {
+	s, err := scanSubscription(database.ReadDB.QueryRowContext(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions s
+		WHERE s.user_id = ? AND s.target_type = ? AND s.target_id = ?`,
+		userID, targetType, targetID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("subscription not found")
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSubscriptions renvoie tous les abonnements d'un utilisateur, par type puis par nom
+func GetSubscriptions(ctx context.Context, userID int) ([]*Subscription, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions s
+		WHERE s.user_id = ?
+		ORDER BY s.target_type, target_name COLLATE NOCASE, s.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// followedPostsCondition sélectionne les posts du fil d'un utilisateur: les posts suivis, ceux des
+// catégories suivies (sous-catégories comprises) et ceux des utilisateurs suivis, sauf les posts muets
+const followedPostsCondition = `p.id IN (
+		SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'post' AND muted = 0
+		UNION
+		SELECT fp.id FROM posts fp JOIN subscriptions s ON s.target_type = 'user' AND s.target_id = fp.user_id
+		WHERE s.user_id = ? AND s.muted = 0
+		UNION
+		SELECT pc.post_id FROM post_categories pc WHERE pc.category_id IN (
+			WITH RECURSIVE tree(id) AS (
+				SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'category' AND muted = 0
+				UNION
+				SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT id FROM tree))
+	AND p.id NOT IN (SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'post' AND muted = 1)`
+
+// GetFollowingFeed renvoie une page des posts suivis par un utilisateur, les plus récemment actifs d'abord
+// L'activité d'un post est la date de son dernier commentaire, ou sa date de création
+func GetFollowingFeed(ctx context.Context, userID, page, perPage int) ([]*Post, int, error) {
+	readable, readableArgs, err := viewerReadCondition(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	condition := followedPostsCondition + " AND " + readable
+	args := append([]interface{}{userID, userID, userID, userID}, readableArgs...)
+
+	var total int
+	err = database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts p WHERE "+condition, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.ReadDB.QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE `+condition+`
+		ORDER BY COALESCE((SELECT MAX(c.created_at) FROM comments c WHERE c.post_id = p.id), p.created_at) DESC, p.id DESC
+		LIMIT ? OFFSET ?`,
+		append(args, perPage, (page-1)*perPage)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = loadPostDetails(ctx, posts, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// subscribeToPostTx abonne l'auteur d'un post ou d'un commentaire au post
+// Un abonnement existant, même muet, n'est pas modifié
+func subscribeToPostTx(ctx context.Context, tx *sql.Tx, userID, postID int) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO subscriptions (user_id, target_type, target_id) VALUES (?, 'post', ?)",
+		userID, postID,
+	)
+	return err
+}
+
+// notifyNewPostTx notifie les abonnés de l'auteur et des catégories d'un nouveau post
+// Les catégories doivent déjà être associées au post. Un abonné déjà notifié pour ce post,
+// par exemple parce qu'il y est mentionné, ne l'est pas une seconde fois, et un abonné qui ne peut
+// pas lire le post, par exemple rangé dans une sous-catégorie réservée à l'équipe, ne l'est pas du tout
+func notifyNewPostTx(ctx context.Context, tx *sql.Tx, authorID, postID int) error {
+	readers, readersArgs, err := postReadersConditionTx(ctx, tx, "s.user_id", postID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, actor_id, type, post_id)
+		SELECT DISTINCT s.user_id, ?, ?, ?
+		FROM subscriptions s
+		WHERE s.muted = 0 AND s.user_id != ?
+			AND ((s.target_type = 'user' AND s.target_id = ?)
+				OR (s.target_type = 'category' AND s.target_id IN (
+					WITH RECURSIVE ancestors(id) AS (
+						SELECT category_id FROM post_categories WHERE post_id = ?
+						UNION
+						SELECT c.parent_id FROM categories c JOIN ancestors a ON c.id = a.id
+						WHERE c.parent_id IS NOT NULL
+					)
+					SELECT id FROM ancestors)))
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.user_id = s.user_id AND n.post_id = ? AND n.comment_id IS NULL
+			)
+			AND `+readers,
+		append([]interface{}{authorID, NotificationPost, postID, authorID, authorID, postID, postID}, readersArgs...)...,
+	)
+	return err
+}
+
+// notifyNewCommentTx notifie les abonnés d'un post qu'un commentaire y a été publié
+// Un abonné déjà notifié pour ce commentaire, par exemple parce qu'il y est mentionné, ne l'est pas une seconde fois
+// Un abonné qui ne peut plus lire le post, déplacé depuis dans une catégorie réservée, n'est pas notifié
+func notifyNewCommentTx(ctx context.Context, tx *sql.Tx, authorID, postID, commentID int) error {
+	readers, readersArgs, err := postReadersConditionTx(ctx, tx, "s.user_id", postID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id)
+		SELECT s.user_id, ?, ?, ?, ?
+		FROM subscriptions s
+		WHERE s.target_type = 'post' AND s.target_id = ? AND s.muted = 0 AND s.user_id != ?
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.user_id = s.user_id AND n.post_id = ? AND n.comment_id = ?
+			)
+			AND `+readers,
+		append([]interface{}{authorID, NotificationComment, postID, commentID, postID, authorID, postID, commentID}, readersArgs...)...,
+	)
+	return err
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"context"
+	"forum/internal/database"
+	"reflect"
+	"testing"
+)
+
+// feedTitles renvoie les titres d'une page du fil de l'utilisateur et le nombre total de posts
+func feedTitles(t *testing.T, userID, page, perPage int) ([]string, int) {
+	t.Helper()
+	posts, total, err := GetFollowingFeed(context.Background(), userID, page, perPage)
+	if err != nil {
+		t.Fatalf("fetching feed: %v", err)
+	}
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	return titles, total
+}
+
+// Le fil réunit les posts suivis, ceux des catégories suivies et de leurs sous-catégories et ceux des
+// utilisateurs suivis, les plus récemment actifs d'abord; les abonnements muets en sont retirés
+func TestFollowingFeed(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	readerID := createTestUser(t, "reader")
+	aliceID := createTestUser(t, "alice")
+	bobID := createTestUser(t, "bob")
+	games, err := CreateCategory(ctx, Category{Name: "Jeux"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	chess, err := CreateCategory(ctx, Category{Name: "Échecs", ParentID: games})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+
+	posts := map[string]int{}
+	for _, p := range []struct {
+		title    string
+		authorID int
+		category int
+		age      string
+	}{
+		{"De alice", aliceID, 1, "-4 hours"},
+		{"Aux échecs", bobID, chess, "-3 hours"},
+		{"Suivi", bobID, 1, "-2 hours"},
+		{"Muet", aliceID, 1, "-1 hours"},
+		{"Hors du fil", bobID, 1, "-1 hours"},
+	} {
+		postID, err := CreatePost(ctx, p.title, "Contenu", p.authorID, []int{p.category})
+		if err != nil {
+			t.Fatalf("creating %s: %v", p.title, err)
+		}
+		if _, err := database.DB.Exec("UPDATE posts SET created_at = datetime('now', ?) WHERE id = ?", p.age, postID); err != nil {
+			t.Fatalf("aging %s: %v", p.title, err)
+		}
+		posts[p.title] = postID
+	}
+
+	for _, s := range []struct {
+		targetType string
+		targetID   int
+	}{
+		{SubscriptionUser, aliceID},
+		{SubscriptionCategory, games},
+		{SubscriptionPost, posts["Suivi"]},
+	} {
+		if err := Follow(ctx, readerID, s.targetType, s.targetID); err != nil {
+			t.Fatalf("following %s %d: %v", s.targetType, s.targetID, err)
+		}
+	}
+	if err := SetSubscriptionMuted(ctx, readerID, SubscriptionPost, posts["Muet"], true); err != nil {
+		t.Fatalf("muting post: %v", err)
+	}
+
+	// Un commentaire récent fait remonter le post en tête du fil
+	commentID, err := CreateComment(ctx, "Relance", bobID, posts["De alice"])
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	if _, err := database.DB.Exec("UPDATE comments SET created_at = datetime('now', '-30 minutes') WHERE id = ?", commentID); err != nil {
+		t.Fatalf("aging comment: %v", err)
+	}
+
+	want := []string{"De alice", "Suivi", "Aux échecs"}
+	if titles, total := feedTitles(t, readerID, 1, 10); !reflect.DeepEqual(titles, want) || total != 3 {
+		t.Errorf("feed: %v (total %d), want %v", titles, total, want)
+	}
+	if titles, total := feedTitles(t, readerID, 2, 2); !reflect.DeepEqual(titles, want[2:]) || total != 3 {
+		t.Errorf("second page: %v (total %d), want %v", titles, total, want[2:])
+	}
+
+	// Rendre muet un utilisateur retire ses posts du fil, le suivre de nouveau les y remet
+	if err := SetSubscriptionMuted(ctx, readerID, SubscriptionUser, aliceID, true); err != nil {
+		t.Fatalf("muting user: %v", err)
+	}
+	if titles, _ := feedTitles(t, readerID, 1, 10); !reflect.DeepEqual(titles, want[1:]) {
+		t.Errorf("feed with alice muted: %v, want %v", titles, want[1:])
+	}
+	if err := Follow(ctx, readerID, SubscriptionUser, aliceID); err != nil {
+		t.Fatalf("following again: %v", err)
+	}
+	if err := Unfollow(ctx, readerID, SubscriptionCategory, games); err != nil {
+		t.Fatalf("unfollowing category: %v", err)
+	}
+	if titles, _ := feedTitles(t, readerID, 1, 10); !reflect.DeepEqual(titles, want[:2]) {
+		t.Errorf("feed without the category: %v, want %v", titles, want[:2])
+	}
+
+	subscriptions, err := GetSubscriptions(ctx, readerID)
+	if err != nil {
+		t.Fatalf("fetching subscriptions: %v", err)
+	}
+	var got []string
+	for _, s := range subscriptions {
+		state := ""
+		if s.Muted {
+			state = " (muet)"
+		}
+		got = append(got, s.TargetType+" "+s.TargetName+state)
+	}
+	if want := []string{"post Muet (muet)", "post Suivi", "user alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriptions: %v, want %v", got, want)
+	}
+
+	for _, tt := range []struct {
+		action     string
+		err        error
+		targetType string
+		targetID   int
+		want       string
+	}{
+		{"follow", Follow(ctx, readerID, SubscriptionUser, readerID), SubscriptionUser, readerID, "cannot follow yourself"},
+		{"follow", Follow(ctx, readerID, SubscriptionPost, 999), SubscriptionPost, 999, "subscription target not found"},
+		{"follow", Follow(ctx, readerID, "tag", 1), "tag", 1, "invalid subscription target"},
+		{"unfollow", Unfollow(ctx, readerID, SubscriptionCategory, games), SubscriptionCategory, games, "subscription not found"},
+	} {
+		if tt.err == nil || tt.err.Error() != tt.want {
+			t.Errorf("%s %s %d: got error %v, want %q", tt.action, tt.targetType, tt.targetID, tt.err, tt.want)
+		}
+	}
+}
+
+// Un nouveau post notifie une seule fois les abonnés de son auteur et de ses catégories, sauf
+// les abonnements muets et l'auteur lui-même; un commentaire notifie les abonnés du post
+func TestSubscriptionNotifications(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	fanID := createTestUser(t, "fan")
+	mutedID := createTestUser(t, "muted")
+	commenterID := createTestUser(t, "commenter")
+	parentID, err := CreateCategory(ctx, Category{Name: "Jeux"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	subID, err := CreateCategory(ctx, Category{Name: "Échecs", ParentID: parentID})
+	if err != nil {
+		t.Fatalf("creating subcategory: %v", err)
+	}
+	for _, s := range []struct {
+		userID     int
+		targetType string
+		targetID   int
+		muted      bool
+	}{
+		{fanID, SubscriptionUser, authorID, false},
+		{fanID, SubscriptionCategory, parentID, false},
+		{mutedID, SubscriptionUser, authorID, true},
+		{authorID, SubscriptionCategory, parentID, false},
+	} {
+		if err := SetSubscriptionMuted(ctx, s.userID, s.targetType, s.targetID, s.muted); err != nil {
+			t.Fatalf("subscribing: %v", err)
+		}
+	}
+
+	postID, err := CreatePost(ctx, "Ouverture", "Contenu", authorID, []int{subID})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	notificationTypes := func(userID int) []string {
+		t.Helper()
+		notifications, err := GetNotifications(ctx, userID, 10)
+		if err != nil {
+			t.Fatalf("fetching notifications: %v", err)
+		}
+		var types []string
+		for _, n := range notifications {
+			types = append(types, n.Type)
+		}
+		return types
+	}
+	for _, tt := range []struct {
+		name   string
+		userID int
+		want   []string
+	}{
+		{"fan", fanID, []string{NotificationPost}},
+		{"muted", mutedID, nil},
+		{"author", authorID, nil},
+	} {
+		if got := notificationTypes(tt.userID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("notifications of %s after the post: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// L'auteur est abonné à son post; un post rendu muet avant de le commenter le reste
+	if err := SetSubscriptionMuted(ctx, commenterID, SubscriptionPost, postID, true); err != nil {
+		t.Fatalf("muting post: %v", err)
+	}
+	if _, err := CreateComment(ctx, "Premier commentaire", commenterID, postID); err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	if got := notificationTypes(authorID); !reflect.DeepEqual(got, []string{NotificationComment}) {
+		t.Errorf("notifications of the author after the comment: %v", got)
+	}
+	if s, err := GetSubscription(ctx, commenterID, SubscriptionPost, postID); err != nil || !s.Muted {
+		t.Errorf("subscription of the commenter: %+v, error %v", s, err)
+	}
+
+	// Le fan qui commente est abonné au post et notifié des commentaires suivants
+	if _, err := CreateComment(ctx, "Deuxième commentaire", fanID, postID); err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	if _, err := CreateComment(ctx, "Troisième commentaire", authorID, postID); err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	if got := notificationTypes(fanID); len(got) != 2 || got[0] != NotificationComment {
+		t.Errorf("notifications of the fan: %v, want a comment and the post", got)
+	}
+	if got := notificationTypes(commenterID); got != nil {
+		t.Errorf("notifications of the commenter who muted the post: %v", got)
+	}
+}
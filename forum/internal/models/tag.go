@@ -0,0 +1,367 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/database"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Limites des tags d'un post
+const (
+	MaxTagsPerPost = 5
+	MaxTagLength   = 30
+)
+
+// Erreurs des tags et de leurs synonymes, qui viennent de la saisie de l'utilisateur
+var (
+	ErrTagTooLong      = errors.New("tag is too long")
+	ErrTooManyTags     = errors.New("too many tags")
+	ErrTagNotFound     = errors.New("tag not found")
+	ErrSynonymRequired = errors.New("synonym and tag are required")
+	ErrSynonymExists   = errors.New("this synonym already exists")
+	ErrSynonymOfItself = errors.New("a tag cannot be a synonym of itself")
+	ErrSynonymNotFound = errors.New("synonym not found")
+)
+
+// Nombre de niveaux de taille dans le nuage de tags
+const tagCloudWeights = 5
+
+// Tag représente un tag libre ajouté à des posts
+type Tag struct {
+	ID        int
+	Name      string
+	PostCount int      // Nombre de posts qui portent le tag
+	Weight    int      // Taille dans le nuage de tags, de 1 à 5 (rempli par GetTagCloud)
+	Synonyms  []string // Synonymes remplacés par ce tag (rempli par GetTags)
+}
+
+// NormalizeTag met un tag sous sa forme enregistrée: minuscules, sans "#" initial, avec des tirets
+// à la place des espaces et des soulignés. Seuls les lettres, les chiffres et les caractères
+// "+", "#" et "." (pour "c++", "c#" ou "node.js") sont gardés
+func NormalizeTag(name string) string {
+	name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "#")
+
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.':
+			b.WriteRune(r)
+			dash = false
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			if !dash && b.Len() > 0 {
+				b.WriteRune('-')
+				dash = true
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
+
+// ParseTags lit la liste de tags séparés par des virgules saisie dans le formulaire d'un post
+// Les tags sont normalisés et dédoublonnés
+func ParseTags(input string) ([]string, error) {
+	var tags []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(input, ",") {
+		tag := NormalizeTag(part)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > MaxTagLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrTagTooLong, tag, MaxTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTagsPerPost {
+		return nil, fmt.Errorf("%w: a post can have at most %d", ErrTooManyTags, MaxTagsPerPost)
+	}
+	return tags, nil
+}
+
+// TagNames renvoie les noms des tags, par exemple pour préremplir le formulaire de modification d'un post
+func TagNames(tags []Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// SetTagWeights calcule la taille de chaque tag du nuage, proportionnelle à son nombre de posts
+func SetTagWeights(tags []Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	least, most := tags[0].PostCount, tags[0].PostCount
+	for _, tag := range tags {
+		least = min(least, tag.PostCount)
+		most = max(most, tag.PostCount)
+	}
+	for i := range tags {
+		tags[i].Weight = 1
+		if most > least {
+			tags[i].Weight = 1 + (tags[i].PostCount-least)*(tagCloudWeights-1)/(most-least)
+		}
+	}
+}
+
+// resolveTagID renvoie l'ID du tag désigné par un nom ou par l'un de ses synonymes, 0 s'il n'existe pas
+func resolveTagID(ctx context.Context, q querier, name string) (int, error) {
+	var tagID int
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(
+			(SELECT tag_id FROM tag_synonyms WHERE synonym = ?),
+			(SELECT id FROM tags WHERE name = ?),
+			0)`, name, name).Scan(&tagID)
+	return tagID, err
+}
+
+// querier est implémenté par *sql.DB et *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// SetPostTags remplace les tags d'un post. Les noms doivent être normalisés (voir ParseTags);
+// les synonymes sont remplacés par leur tag et les tags inconnus sont créés
+func SetPostTags(ctx context.Context, postID int, names []string) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := SetPostTagsTx(ctx, tx, postID, names); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetPostTagsTx fait le travail de SetPostTags dans une transaction existante, pour l'import
+func SetPostTagsTx(ctx context.Context, tx *sql.Tx, postID int, names []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = ?", postID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		tagID, err := resolveTagID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if tagID == 0 {
+			result, err := tx.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?)", name)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			tagID = int(id)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO post_tags (post_id, tag_id) VALUES (?, ?)", postID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Les tags qui ne sont plus utilisés disparaissent, sauf s'ils ont des synonymes
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM tags
+		WHERE id NOT IN (SELECT tag_id FROM post_tags)
+			AND id NOT IN (SELECT tag_id FROM tag_synonyms)`)
+	return err
+}
+
+// Colonnes lues pour chaque tag, avec son nombre de posts
+const tagColumns = `t.id, t.name, (SELECT COUNT(*) FROM post_tags pt WHERE pt.tag_id = t.id)`
+
+// queryTags exécute une requête qui sélectionne tagColumns
+func queryTags(ctx context.Context, query string, args ...interface{}) ([]Tag, error) {
+	rows, err := database.ReadDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetTag récupère un tag par son nom ou par l'un de ses synonymes
+func GetTag(ctx context.Context, name string) (*Tag, error) {
+	tagID, err := resolveTagID(ctx, database.ReadDB, NormalizeTag(name))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := queryTags(ctx, "SELECT "+tagColumns+" FROM tags t WHERE t.id = ?", tagID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, ErrTagNotFound
+	}
+	return &tags[0], nil
+}
+
+// SearchTags propose les tags qui commencent par prefix, les plus utilisés d'abord
+// Un synonyme qui correspond propose le tag qu'il remplace
+func SearchTags(ctx context.Context, prefix string, limit int) ([]Tag, error) {
+	prefix = NormalizeTag(prefix)
+	if prefix == "" {
+		return []Tag{}, nil
+	}
+	// Les caractères spéciaux de LIKE sont échappés
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	return queryTags(ctx, `
+		SELECT `+tagColumns+`
+		FROM tags t
+		WHERE t.name LIKE ? ESCAPE '\'
+			OR t.id IN (SELECT tag_id FROM tag_synonyms WHERE synonym LIKE ? ESCAPE '\')
+		ORDER BY 3 DESC, t.name
+		LIMIT ?`, pattern, pattern, limit)
+}
+
+// GetTagCloud renvoie les tags les plus utilisés, triés par nom, avec leur taille dans le nuage
+func GetTagCloud(ctx context.Context, limit int) ([]Tag, error) {
+	tags, err := queryTags(ctx, `
+		SELECT `+tagColumns+`
+		FROM tags t
+		WHERE t.id IN (SELECT tag_id FROM post_tags)
+		ORDER BY 3 DESC, t.name
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	SetTagWeights(tags)
+	return tags, nil
+}
+
+// GetTags récupère tous les tags avec leurs synonymes, pour la page de gestion des modérateurs
+func GetTags(ctx context.Context) ([]Tag, error) {
+	tags, err := queryTags(ctx, "SELECT "+tagColumns+" FROM tags t ORDER BY t.name")
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int]*Tag{}
+	for i := range tags {
+		byID[tags[i].ID] = &tags[i]
+	}
+	rows, err := database.ReadDB.QueryContext(ctx, "SELECT synonym, tag_id FROM tag_synonyms ORDER BY synonym")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var synonym string
+		var tagID int
+		if err := rows.Scan(&synonym, &tagID); err != nil {
+			return nil, err
+		}
+		if tag, ok := byID[tagID]; ok {
+			tag.Synonyms = append(tag.Synonyms, synonym)
+		}
+	}
+	return tags, rows.Err()
+}
+
+// AddTagSynonym fait de synonym un synonyme du tag tagName
+// Si synonym est déjà un tag, ses posts sont rattachés à tagName et il est supprimé
+func AddTagSynonym(ctx context.Context, synonym, tagName string, moderatorID int) error {
+	synonym, tagName = NormalizeTag(synonym), NormalizeTag(tagName)
+	if synonym == "" || tagName == "" {
+		return ErrSynonymRequired
+	}
+	if len([]rune(synonym)) > MaxTagLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrTagTooLong, synonym, MaxTagLength)
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tagID, err := resolveTagID(ctx, tx, tagName)
+	if err != nil {
+		return err
+	}
+	if tagID == 0 {
+		return ErrTagNotFound
+	}
+
+	var existing int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM tag_synonyms WHERE synonym = ?", synonym).Scan(&existing)
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrSynonymExists
+	}
+
+	var oldTagID int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = ?", synonym).Scan(&oldTagID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if oldTagID == tagID {
+		return ErrSynonymOfItself
+	}
+	if oldTagID != 0 {
+		// Fusion: les posts et les synonymes de l'ancien tag passent au nouveau
+		_, err = tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO post_tags (post_id, tag_id)
+			SELECT post_id, ? FROM post_tags WHERE tag_id = ?`, tagID, oldTagID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE tag_synonyms SET tag_id = ? WHERE tag_id = ?", tagID, oldTagID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM tags WHERE id = ?", oldTagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO tag_synonyms (synonym, tag_id, created_by) VALUES (?, ?, ?)", synonym, tagID, moderatorID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveTagSynonym supprime un synonyme; les posts déjà rattachés au tag le restent
+func RemoveTagSynonym(ctx context.Context, synonym string) error {
+	result, err := database.DB.ExecContext(ctx, "DELETE FROM tag_synonyms WHERE synonym = ?", NormalizeTag(synonym))
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSynonymNotFound
+	}
+	return nil
+}
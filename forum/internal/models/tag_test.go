@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for _, tt := range []struct {
+		name, want string
+	}{
+		{"Go", "go"},
+		{"  #Golang ", "golang"},
+		{"Machine Learning", "machine-learning"},
+		{"snake_case", "snake-case"},
+		{"a  -  b", "a-b"},
+		{"C++", "c++"},
+		{"C#", "c#"},
+		{"Node.js", "node.js"},
+		{".net.", "net"},
+		{"-déjà vu-", "déjà-vu"},
+		{"hello, world!", "hello-world"},
+		{"##", "#"},
+		{"!?", ""},
+	} {
+		if got := NormalizeTag(tt.name); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  []string
+		err   error
+	}{
+		{"", nil, nil},
+		{"Go, SQLite", []string{"go", "sqlite"}, nil},
+		{"go, #Go, GO,, ,", []string{"go"}, nil},
+		{"web dev, web_dev", []string{"web-dev"}, nil},
+		{"a,b,c,d,e", []string{"a", "b", "c", "d", "e"}, nil},
+		{"a,b,c,d,e,f", nil, ErrTooManyTags},
+		{"a,b,c,d,e,a", []string{"a", "b", "c", "d", "e"}, nil},
+		{strings.Repeat("x", MaxTagLength), []string{strings.Repeat("x", MaxTagLength)}, nil},
+		{"go," + strings.Repeat("x", MaxTagLength+1), nil, ErrTagTooLong},
+		{strings.Repeat("é", MaxTagLength), []string{strings.Repeat("é", MaxTagLength)}, nil},
+	} {
+		got, err := ParseTags(tt.input)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseTags(%q): got error %v, want %v", tt.input, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSetTagWeights(t *testing.T) {
+	tags := []Tag{{PostCount: 1}, {PostCount: 3}, {PostCount: 5}}
+	SetTagWeights(tags)
+	for i, want := range []int{1, 3, 5} {
+		if tags[i].Weight != want {
+			t.Errorf("weight of a tag with %d posts: got %d, want %d", tags[i].PostCount, tags[i].Weight, want)
+		}
+	}
+
+	same := []Tag{{PostCount: 2}, {PostCount: 2}}
+	SetTagWeights(same)
+	if same[0].Weight != 1 || same[1].Weight != 1 {
+		t.Errorf("weights of tags with the same count: got %d and %d, want 1", same[0].Weight, same[1].Weight)
+	}
+}
+
+// postTagNames renvoie les noms des tags d'un post
+func postTagNames(t *testing.T, postID int) []string {
+	t.Helper()
+	post, err := GetPostByID(context.Background(), postID, 0)
+	if err != nil {
+		t.Fatalf("fetching post %d: %v", postID, err)
+	}
+	return TagNames(post.Tags)
+}
+
+// Un synonyme redirige vers son tag, et un tag existant devenu synonyme est fusionné dans l'autre
+func TestTagSynonyms(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	authorID := createTestUser(t, "author")
+	moderatorID := createTestUser(t, "moderator")
+
+	goPost, err := CreatePostWithDetails(ctx, NewPost{Title: "Go", Content: "Contenu", UserID: authorID, CategoryIDs: []int{1}, Tags: []string{"go"}})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	golangPost, err := CreatePostWithDetails(ctx, NewPost{Title: "Golang", Content: "Contenu", UserID: authorID, CategoryIDs: []int{1}, Tags: []string{"golang", "sqlite"}})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+
+	// "golang" devient un synonyme de "go": son post passe à "go" et le tag disparaît
+	if err := AddTagSynonym(ctx, "#Golang", "Go", moderatorID); err != nil {
+		t.Fatalf("adding synonym: %v", err)
+	}
+	if got := postTagNames(t, golangPost); !reflect.DeepEqual(got, []string{"go", "sqlite"}) {
+		t.Errorf("tags of the merged post: got %q, want [go sqlite]", got)
+	}
+	tag, err := GetTag(ctx, "golang")
+	if err != nil || tag.Name != "go" || tag.PostCount != 2 {
+		t.Errorf("GetTag(golang) = %+v, error %v, want go with 2 posts", tag, err)
+	}
+	tags, err := GetTags(ctx)
+	if err != nil {
+		t.Fatalf("listing tags: %v", err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+		if tag.Name == "go" && !reflect.DeepEqual(tag.Synonyms, []string{"golang"}) {
+			t.Errorf("synonyms of go: got %q, want [golang]", tag.Synonyms)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"go", "sqlite"}) {
+		t.Errorf("tags after the merge: got %q, want [go sqlite]", names)
+	}
+
+	// Un nouveau post écrit avec le synonyme reçoit le tag
+	if err := SetPostTags(ctx, goPost, []string{"golang", "testing"}); err != nil {
+		t.Fatalf("setting tags: %v", err)
+	}
+	if got := postTagNames(t, goPost); !reflect.DeepEqual(got, []string{"go", "testing"}) {
+		t.Errorf("tags written with a synonym: got %q, want [go testing]", got)
+	}
+
+	// L'autocomplétion propose le tag quand le préfixe correspond à un synonyme
+	found, err := SearchTags(ctx, "gol", 10)
+	if err != nil || len(found) != 1 || found[0].Name != "go" {
+		t.Errorf("SearchTags(gol) = %+v, error %v, want go", found, err)
+	}
+	if found, err := SearchTags(ctx, "%", 10); err != nil || len(found) != 0 {
+		t.Errorf("SearchTags(%%) = %+v, error %v, want nothing", found, err)
+	}
+
+	for _, tt := range []struct {
+		synonym, tag string
+		err          error
+	}{
+		{"", "go", ErrSynonymRequired},
+		{"golang", "go", ErrSynonymExists},
+		{"go", "go", ErrSynonymOfItself},
+		{"golang-lang", "rust", ErrTagNotFound},
+		{strings.Repeat("x", MaxTagLength+1), "go", ErrTagTooLong},
+	} {
+		if err := AddTagSynonym(ctx, tt.synonym, tt.tag, moderatorID); !errors.Is(err, tt.err) {
+			t.Errorf("AddTagSynonym(%q, %q): got error %v, want %v", tt.synonym, tt.tag, err, tt.err)
+		}
+	}
+
+	// Retirer le synonyme laisse les posts sur le tag, mais le synonyme redevient un tag à part
+	if err := RemoveTagSynonym(ctx, "golang"); err != nil {
+		t.Fatalf("removing synonym: %v", err)
+	}
+	if err := RemoveTagSynonym(ctx, "golang"); !errors.Is(err, ErrSynonymNotFound) {
+		t.Errorf("removing a missing synonym: got error %v, want ErrSynonymNotFound", err)
+	}
+	if got := postTagNames(t, golangPost); !reflect.DeepEqual(got, []string{"go", "sqlite"}) {
+		t.Errorf("tags after removing the synonym: got %q, want [go sqlite]", got)
+	}
+	if _, err := GetTag(ctx, "golang"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("GetTag(golang) after removing the synonym: got error %v, want ErrTagNotFound", err)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"forum/internal/database"
@@ -11,6 +12,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrUserNotFound est renvoyée quand l'utilisateur demandé n'existe pas
+var ErrUserNotFound = errors.New("user not found")
+
 // User est comme une fiche d'identité pour chaque personne qui utilise le forum
 // Elle contient toutes les informations dont nous avons besoin pour identifier et gérer un utilisateur
 type User struct {
@@ -20,6 +24,9 @@ type User struct {
 	Password  string     // Le mot de passe (stocké de façon sécurisée)
 	Role      string     // Le rôle de l'utilisateur (administrateur, modérateur, utilisateur simple...)
 	CreatedAt time.Time  // La date et l'heure de création du compte
+	UnreadNotifications int // Nombre de notifications non lues (rempli par GetUserByID)
+	DigestFrequency string  // Fréquence des résumés par email: DigestNone, DigestDaily ou DigestWeekly
+	UnreadConversations int // Nombre de conversations privées avec des messages non lus (rempli par GetUserByID)
 }
 
 // Session est comme un bracelet d'entrée temporaire pour un utilisateur connecté
@@ -34,9 +41,9 @@ type Session struct {
 
 // RegisterUser inscrit un nouvel utilisateur dans la base de données
 // C'est comme remplir un formulaire d'inscription et l'ajouter au registre des membres
-func RegisterUser(email, username, password string) error {
+func RegisterUser(ctx context.Context, email, username, password string) error {
 	var count int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count)
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -44,7 +51,7 @@ func RegisterUser(email, username, password string) error {
 		return errors.New("email already exists")
 	}
 
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count)
+	err = database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -57,7 +64,7 @@ func RegisterUser(email, username, password string) error {
 		return err
 	}
 
-	_, err = database.DB.Exec(
+	_, err = database.DB.ExecContext(ctx, 
 		"INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, ?)",
 		email, username, string(hashedPassword), "user",
 	)
@@ -69,15 +76,15 @@ func RegisterUser(email, username, password string) error {
 }
 
 // GetUserByEmail recherche un utilisateur par son adresse email
-func GetUserByEmail(email string) (*User, error) {
+func GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	user := &User{}
-	err := database.DB.QueryRow(
+	err := database.ReadDB.QueryRowContext(ctx, 
 		"SELECT id, email, username, password, role, created_at FROM users WHERE email = ?",
 		email,
 	).Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -85,15 +92,25 @@ func GetUserByEmail(email string) (*User, error) {
 }
 
 // GetUserByID recherche un utilisateur par son numéro d'identification
-func GetUserByID(id int) (*User, error) {
+func GetUserByID(ctx context.Context, id int) (*User, error) {
 	user := &User{}
-	err := database.DB.QueryRow(
-		"SELECT id, email, username, password, role, created_at FROM users WHERE id = ?",
+	// Les nombres de notifications et de conversations non lues servent au menu de chaque page
+	err := database.ReadDB.QueryRowContext(ctx, `
+		SELECT id, email, username, password, role, created_at,
+			(SELECT COUNT(*) FROM notifications WHERE user_id = users.id AND is_read = 0),
+			digest_frequency,
+			(SELECT COUNT(*) FROM conversation_members cm
+				WHERE cm.user_id = users.id AND cm.left_at IS NULL AND EXISTS (
+					SELECT 1 FROM messages m
+					WHERE m.conversation_id = cm.conversation_id AND m.id > cm.last_read_message_id
+						AND (m.user_id IS NULL OR m.user_id != users.id)))
+		FROM users WHERE id = ?`,
 		id,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UnreadNotifications,
+		&user.DigestFrequency, &user.UnreadConversations)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -101,8 +118,8 @@ func GetUserByID(id int) (*User, error) {
 }
 
 // Authenticate vérifie si les identifiants fournis correspondent à un utilisateur
-func Authenticate(email, password string) (*User, error) {
-	user, err := GetUserByEmail(email)
+func Authenticate(ctx context.Context, email, password string) (*User, error) {
+	user, err := GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -116,16 +133,16 @@ func Authenticate(email, password string) (*User, error) {
 }
 
 // CreateSession crée une nouvelle session pour un utilisateur connecté
-func CreateSession(userID int) (*Session, error) {
+func CreateSession(ctx context.Context, userID int) (*Session, error) {
 	sessionUUID := uuid.New().String()
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	_, err := database.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	_, err := database.DB.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = database.DB.Exec(
+	_, err = database.DB.ExecContext(ctx, 
 		"INSERT INTO sessions (uuid, user_id, expires_at) VALUES (?, ?, ?)",
 		sessionUUID, userID, expiresAt,
 	)
@@ -143,9 +160,9 @@ func CreateSession(userID int) (*Session, error) {
 }
 
 // GetSessionByUUID recherche une session par son code unique
-func GetSessionByUUID(uuid string) (*Session, error) {
+func GetSessionByUUID(ctx context.Context, uuid string) (*Session, error) {
 	session := &Session{}
-	err := database.DB.QueryRow(
+	err := database.ReadDB.QueryRowContext(ctx, 
 		"SELECT id, uuid, user_id, created_at, expires_at FROM sessions WHERE uuid = ?",
 		uuid,
 	).Scan(&session.ID, &session.UUID, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
@@ -157,7 +174,7 @@ func GetSessionByUUID(uuid string) (*Session, error) {
 	}
 
 	if session.ExpiresAt.Before(time.Now()) {
-		_, err = database.DB.Exec("DELETE FROM sessions WHERE uuid = ?", uuid)
+		_, err = database.DB.ExecContext(ctx, "DELETE FROM sessions WHERE uuid = ?", uuid)
 		if err != nil {
 			log.Printf("Error deleting expired session: %v", err)
 		}
@@ -168,17 +185,17 @@ func GetSessionByUUID(uuid string) (*Session, error) {
 }
 
 // DeleteSession supprime une session par son code unique
-func DeleteSession(uuid string) error {
-	_, err := database.DB.Exec("DELETE FROM sessions WHERE uuid = ?", uuid)
+func DeleteSession(ctx context.Context, uuid string) error {
+	_, err := database.DB.ExecContext(ctx, "DELETE FROM sessions WHERE uuid = ?", uuid)
 	return err
 }
 
 // GetAllUsers récupère tous les utilisateurs
 // C'est comme récupérer toutes les fiches d'inscription du registre des membres
-func GetAllUsers() ([]*User, error) {
+func GetAllUsers(ctx context.Context) ([]*User, error) {
 	users := []*User{}
 
-	rows, err := database.DB.Query(`
+	rows, err := database.ReadDB.QueryContext(ctx, `
 		SELECT id, email, username, password, role, created_at 
 		FROM users 
 		ORDER BY id ASC
@@ -201,3 +218,49 @@ func GetAllUsers() ([]*User, error) {
 
 	return users, nil
 }
+
+// GetUserByOAuthID recherche un utilisateur par l'identifiant fourni par un service OAuth (Google, GitHub)
+func GetUserByOAuthID(ctx context.Context, oauthID string) (*User, error) {
+	var userID int
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT id FROM users WHERE oauth_id = ?", oauthID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return GetUserByID(ctx, userID)
+}
+
+// LinkOAuthID associe un identifiant OAuth à un compte existant
+func LinkOAuthID(ctx context.Context, userID int, oauthID string) error {
+	_, err := database.DB.ExecContext(ctx, "UPDATE users SET oauth_id = ? WHERE id = ?", oauthID, userID)
+	return err
+}
+
+// CreateOAuthUser crée un compte sans mot de passe pour un utilisateur venant d'un service OAuth
+func CreateOAuthUser(ctx context.Context, email, username, oauthID string) (int, error) {
+	result, err := database.DB.ExecContext(ctx, 
+		"INSERT INTO users (email, username, password, oauth_id, role) VALUES (?, ?, ?, ?, ?)",
+		email, username, "", oauthID, "user",
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UsernameExists vérifie si un nom d'utilisateur est déjà pris
+func UsernameExists(ctx context.Context, username string) (bool, error) {
+	var count int
+	err := database.ReadDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,150 @@
+// Package publish publie les brouillons programmés à leur heure de publication, avec les règles
+// qui s'appliquent à ce moment-là: permissions des catégories, niveau de confiance et file de modération
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"forum/internal/markdown"
+	"forum/internal/models"
+	"forum/internal/store"
+	"log"
+	"strings"
+	"time"
+)
+
+// Publisher publie les brouillons programmés dus
+type Publisher struct {
+	Stores store.Stores
+}
+
+// NewPublisher crée un Publisher
+func NewPublisher(stores store.Stores) *Publisher {
+	return &Publisher{Stores: stores}
+}
+
+// Run publie les brouillons programmés dus à l'instant now et renvoie le nombre de posts publiés
+// ou envoyés à la file de modération. Un brouillon refusé reste un simple brouillon avec la raison
+// du refus, que son auteur voit sur son profil. Une erreur n'empêche pas de publier les autres
+func (p *Publisher) Run(ctx context.Context, now time.Time) (int, error) {
+	drafts, err := p.Stores.Drafts.GetDueDrafts(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var failures []error
+	for _, draft := range drafts {
+		if err := ctx.Err(); err != nil {
+			return published, err
+		}
+		// Le brouillon est retiré de la programmation avant d'être publié, pour ne jamais le publier deux fois
+		claimed, err := p.Stores.Drafts.ClaimScheduledDraft(ctx, draft.ID, now)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("draft %d: %w", draft.ID, err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		refusal, err := p.publish(ctx, draft)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("draft %d: %w", draft.ID, err))
+			refusal = "La publication a échoué à cause d'une erreur du serveur"
+		}
+		if refusal != "" {
+			if err := p.Stores.Drafts.SetDraftPublishError(ctx, draft.ID, refusal); err != nil {
+				failures = append(failures, fmt.Errorf("draft %d: %w", draft.ID, err))
+			}
+			continue
+		}
+		published++
+	}
+	return published, errors.Join(failures...)
+}
+
+// publish publie un brouillon et le supprime dans la même transaction, pour ne jamais publier
+// deux fois le même brouillon ni le perdre si la publication échoue. Si les règles du forum
+// refusent la publication, le brouillon est gardé et publish renvoie la raison du refus
+func (p *Publisher) publish(ctx context.Context, draft *models.Draft) (string, error) {
+	if strings.TrimSpace(draft.Title) == "" || strings.TrimSpace(draft.Content) == "" {
+		return "Le titre et le contenu sont obligatoires", nil
+	}
+
+	user, err := p.Stores.Users.GetUserByID(ctx, draft.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	// Les catégories sont vérifiées avec le rôle de l'auteur au moment de la publication:
+	// une catégorie archivée ou fermée depuis la programmation refuse le post
+	err = p.Stores.Categories.CheckCategoryPermission(ctx, draft.CategoryIDs, user.Role)
+	if errors.Is(err, models.ErrCategoryForbidden) {
+		return categoryRefusal(err), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	reputation, err := p.Stores.Reputation.GetReputation(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if (markdown.HasExternalLinks(draft.Title) || markdown.HasExternalLinks(draft.Content)) &&
+		!reputation.Allows(models.MinTrustLinks) {
+		return "Votre niveau de confiance ne permet pas encore de publier des liens vers d'autres sites", nil
+	}
+
+	post := models.NewPost{
+		Title:       draft.Title,
+		Content:     draft.Content,
+		UserID:      user.ID,
+		CategoryIDs: draft.CategoryIDs,
+		Tags:        draft.Tags,
+		DraftID:     draft.ID,
+	}
+
+	// Les posts des nouveaux membres passent par la file de modération, comme un post publié à la main
+	if !reputation.Allows(models.MinTrustSkipModeration) {
+		_, err = p.Stores.Moderation.SubmitPendingPost(ctx, post)
+	} else {
+		_, err = p.Stores.Posts.CreatePostWithDetails(ctx, post)
+	}
+	return "", err
+}
+
+// categoryRefusal renvoie la raison affichée à l'auteur d'un brouillon refusé dans ses catégories
+func categoryRefusal(err error) string {
+	switch {
+	case errors.Is(err, models.ErrCategoryArchived):
+		return "Publication refusée: une des catégories choisies est archivée"
+	case errors.Is(err, models.ErrCategoryReadOnly):
+		return "Publication refusée: une des catégories choisies est en lecture seule"
+	case errors.Is(err, models.ErrCategoryModeratorsOnly):
+		return "Publication refusée: seuls les modérateurs peuvent publier dans une des catégories choisies"
+	}
+	return "Publication refusée: une des catégories choisies n'existe plus"
+}
+
+// Schedule publie les brouillons programmés dus toutes les "interval" jusqu'à l'annulation du contexte
+func (p *Publisher) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			published, err := p.Run(ctx, now)
+			if err != nil {
+				log.Printf("Error publishing scheduled drafts: %v", err)
+			}
+			if published > 0 {
+				log.Printf("Published %d scheduled drafts", published)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// LoadSecret lit la clé secrète du serveur, qui signe les liens envoyés par email
+// Si le fichier n'existe pas, une clé aléatoire est créée: elle doit être conservée,
+// sinon les liens déjà envoyés ne sont plus valides
+func LoadSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret := bytes.TrimSpace(data)
+		if len(secret) == 0 {
+			return nil, errors.New("secret file is empty")
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	secret := []byte(hex.EncodeToString(key))
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	// Seul le propriétaire du fichier peut lire la clé
+	if err := os.WriteFile(path, append(secret, '\n'), 0600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ScheduleBadgeAwards attribue les badges gagnés par tous les utilisateurs toutes les "interval"
+// jusqu'à l'annulation du contexte. Un profil consulté reçoit aussi ses badges sans attendre ce passage
+func ScheduleBadgeAwards(ctx context.Context, reputation ReputationStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			awarded, err := reputation.AwardAllBadges(ctx)
+			if err != nil {
+				log.Printf("Error awarding badges: %v", err)
+			}
+			if awarded > 0 {
+				log.Printf("Awarded %d badges", awarded)
+			}
+		}
+	}
+}
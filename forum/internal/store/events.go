@@ -0,0 +1,356 @@
+package store
+
+import (
+	"context"
+	"forum/internal/events"
+	"forum/internal/models"
+	"log"
+)
+
+// Contenu JSON des événements temps réel
+
+type commentEvent struct {
+	PostID       int `json:"post_id"`
+	CommentID    int `json:"comment_id"`
+	CommentCount int `json:"comment_count"`
+}
+
+type reactionEvent struct {
+	PostID    int            `json:"post_id"`
+	CommentID int            `json:"comment_id,omitempty"` // Absent pour les réactions au post
+	Likes     int            `json:"likes"`
+	Dislikes  int            `json:"dislikes"`
+	Reactions map[string]int `json:"reactions"` // Nombre de chaque réaction, par identifiant
+}
+
+type notificationEvent struct {
+	Unread int `json:"unread"`
+}
+
+type pendingEvent struct {
+	Pending int `json:"pending"`
+}
+
+type messageEvent struct {
+	ConversationID int `json:"conversation_id"`
+	MessageID      int `json:"message_id,omitempty"` // Absent quand la conversation vient d'être lue
+	Unread         int `json:"unread"`               // Nombre de conversations non lues du destinataire
+}
+
+// WithEvents renvoie des stockages qui publient sur hub un événement temps réel après chaque
+// modification réussie qui intéresse les pages ouvertes: commentaires, réactions, notifications,
+// messages privés et file de modération. Les données des événements sont relues à travers les stockages d'origine,
+// ce qui fonctionne avec SQLite comme en mémoire. Une publication ratée est journalisée
+// mais ne fait pas échouer la modification, qui est déjà enregistrée
+func WithEvents(stores Stores, hub *events.Hub) Stores {
+	p := &publisher{stores: stores, hub: hub}
+	stores.Posts = &eventPostStore{PostStore: stores.Posts, publisher: p}
+	stores.Comments = &eventCommentStore{CommentStore: stores.Comments, publisher: p}
+	stores.Moderation = &eventModerationStore{ModerationStore: stores.Moderation, publisher: p}
+	stores.Notifications = &eventNotificationStore{NotificationStore: stores.Notifications, publisher: p}
+	stores.Messages = &eventMessageStore{MessageStore: stores.Messages, publisher: p}
+	return stores
+}
+
+// publisher prépare et publie les événements à partir des stockages d'origine
+type publisher struct {
+	stores Stores
+	hub    *events.Hub
+}
+
+func (p *publisher) publish(topic, eventType string, data interface{}) {
+	if err := p.hub.Publish(topic, eventType, data); err != nil {
+		log.Printf("Error publishing %s event: %v", eventType, err)
+	}
+}
+
+// failed journalise une erreur de lecture des données d'un événement
+func (p *publisher) failed(eventType string, err error) {
+	log.Printf("Error preparing %s event: %v", eventType, err)
+}
+
+// comment annonce un nouveau commentaire aux lecteurs de son post
+func (p *publisher) comment(ctx context.Context, postID, commentID int) {
+	post, err := p.stores.Posts.GetPostByID(ctx, postID, 0)
+	if err != nil {
+		p.failed(events.TypeComment, err)
+		return
+	}
+	p.publish(events.PostTopic(postID), events.TypeComment, commentEvent{
+		PostID:       postID,
+		CommentID:    commentID,
+		CommentCount: post.CommentCount,
+	})
+}
+
+// postReaction envoie les compteurs de réactions d'un post
+func (p *publisher) postReaction(ctx context.Context, postID int) {
+	post, err := p.stores.Posts.GetPostByID(ctx, postID, 0)
+	if err != nil {
+		p.failed(events.TypeReaction, err)
+		return
+	}
+	p.publish(events.PostTopic(postID), events.TypeReaction, reactionEvent{
+		PostID:    postID,
+		Likes:     post.Likes,
+		Dislikes:  post.Dislikes,
+		Reactions: reactionTotals(post.Reactions),
+	})
+}
+
+// commentReaction envoie les compteurs de réactions d'un commentaire
+func (p *publisher) commentReaction(ctx context.Context, commentID int) {
+	comment, err := p.stores.Comments.GetCommentByID(ctx, commentID, 0)
+	if err != nil {
+		p.failed(events.TypeReaction, err)
+		return
+	}
+	p.publish(events.PostTopic(comment.PostID), events.TypeReaction, reactionEvent{
+		PostID:    comment.PostID,
+		CommentID: commentID,
+		Likes:     comment.Likes,
+		Dislikes:  comment.Dislikes,
+		Reactions: reactionTotals(comment.Reactions),
+	})
+}
+
+// reactionTotals renvoie le nombre de chaque réaction d'un contenu, par identifiant de réaction
+func reactionTotals(counts []models.ReactionCount) map[string]int {
+	totals := make(map[string]int, len(counts))
+	for _, count := range counts {
+		totals[count.Name] = count.Count
+	}
+	return totals
+}
+
+// unread envoie à un utilisateur son nombre de notifications non lues
+func (p *publisher) unread(ctx context.Context, userID int) {
+	user, err := p.stores.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		p.failed(events.TypeNotification, err)
+		return
+	}
+	p.publish(events.UserTopic(userID), events.TypeNotification, notificationEvent{Unread: user.UnreadNotifications})
+}
+
+// notifications prévient les utilisateurs notifiés pour un post (commentID 0) ou un commentaire
+// Les utilisateurs déjà notifiés auparavant reçoivent leur compteur inchangé, ce qui est sans effet
+func (p *publisher) notifications(ctx context.Context, postID, commentID int) {
+	userIDs, err := p.stores.Notifications.GetNotifiedUsers(ctx, postID, commentID)
+	if err != nil {
+		p.failed(events.TypeNotification, err)
+		return
+	}
+	for _, userID := range userIDs {
+		p.unread(ctx, userID)
+	}
+}
+
+// pending envoie aux modérateurs le nombre de posts en attente
+func (p *publisher) pending(ctx context.Context) {
+	pendingPosts, err := p.stores.Moderation.GetPendingPosts(ctx)
+	if err != nil {
+		p.failed(events.TypePending, err)
+		return
+	}
+	p.publish(events.ModerationTopic, events.TypePending, pendingEvent{Pending: len(pendingPosts)})
+}
+
+// message envoie aux membres présents d'une conversation son nouveau message, ou à un membre
+// qui vient de la lire son nombre de conversations non lues (messageID 0)
+func (p *publisher) message(ctx context.Context, conversationID, messageID, userID int) {
+	userIDs := []int{userID}
+	if messageID != 0 {
+		conversation, err := p.stores.Messages.GetConversation(ctx, conversationID, userID)
+		if err != nil {
+			p.failed(events.TypeMessage, err)
+			return
+		}
+		userIDs = nil
+		for _, member := range conversation.Members {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+	for _, memberID := range userIDs {
+		user, err := p.stores.Users.GetUserByID(ctx, memberID)
+		if err != nil {
+			p.failed(events.TypeMessage, err)
+			continue
+		}
+		p.publish(events.UserTopic(memberID), events.TypeMessage, messageEvent{
+			ConversationID: conversationID,
+			MessageID:      messageID,
+			Unread:         user.UnreadConversations,
+		})
+	}
+}
+
+// eventPostStore publie les nouvelles notifications et les réactions des posts
+type eventPostStore struct {
+	PostStore
+	publisher *publisher
+}
+
+func (s *eventPostStore) CreatePost(ctx context.Context, title, content string, userID int, categoryIDs []int) (int, error) {
+	postID, err := s.PostStore.CreatePost(ctx, title, content, userID, categoryIDs)
+	if err == nil {
+		s.publisher.notifications(ctx, postID, 0)
+	}
+	return postID, err
+}
+
+func (s *eventPostStore) CreatePostWithDetails(ctx context.Context, post models.NewPost) (int, error) {
+	postID, err := s.PostStore.CreatePostWithDetails(ctx, post)
+	if err == nil {
+		s.publisher.notifications(ctx, postID, 0)
+	}
+	return postID, err
+}
+
+func (s *eventPostStore) UpdatePost(ctx context.Context, postID, userID int, title, content string, categoryIDs []int) error {
+	err := s.PostStore.UpdatePost(ctx, postID, userID, title, content, categoryIDs)
+	if err == nil {
+		s.publisher.notifications(ctx, postID, 0)
+	}
+	return err
+}
+
+func (s *eventPostStore) ReactToPost(ctx context.Context, postID, userID int, reactionType string) error {
+	err := s.PostStore.ReactToPost(ctx, postID, userID, reactionType)
+	if err == nil {
+		s.publisher.postReaction(ctx, postID)
+	}
+	return err
+}
+
+// eventCommentStore publie les nouveaux commentaires, leurs notifications et leurs réactions
+type eventCommentStore struct {
+	CommentStore
+	publisher *publisher
+}
+
+func (s *eventCommentStore) CreateComment(ctx context.Context, content string, userID, postID int) (int, error) {
+	commentID, err := s.CommentStore.CreateComment(ctx, content, userID, postID)
+	if err == nil {
+		s.publisher.comment(ctx, postID, commentID)
+		s.publisher.notifications(ctx, postID, commentID)
+	}
+	return commentID, err
+}
+
+func (s *eventCommentStore) UpdateComment(ctx context.Context, commentID, userID int, content string) error {
+	err := s.CommentStore.UpdateComment(ctx, commentID, userID, content)
+	if err != nil {
+		return err
+	}
+	// Le post du commentaire est relu pour retrouver ses notifications
+	comment, lookupErr := s.CommentStore.GetCommentByID(ctx, commentID, 0)
+	if lookupErr != nil {
+		s.publisher.failed(events.TypeNotification, lookupErr)
+		return nil
+	}
+	s.publisher.notifications(ctx, comment.PostID, commentID)
+	return nil
+}
+
+func (s *eventCommentStore) ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error {
+	err := s.CommentStore.ReactToComment(ctx, commentID, userID, reactionType)
+	if err == nil {
+		s.publisher.commentReaction(ctx, commentID)
+	}
+	return err
+}
+
+// eventModerationStore publie l'état de la file de modération et les notifications des posts approuvés
+type eventModerationStore struct {
+	ModerationStore
+	publisher *publisher
+}
+
+func (s *eventModerationStore) SubmitPendingPost(ctx context.Context, post models.NewPost) (int, error) {
+	pendingID, err := s.ModerationStore.SubmitPendingPost(ctx, post)
+	if err == nil {
+		s.publisher.pending(ctx)
+	}
+	return pendingID, err
+}
+
+func (s *eventModerationStore) ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error) {
+	postID, err := s.ModerationStore.ApprovePendingPost(ctx, pendingID, moderatorID)
+	if err == nil {
+		s.publisher.pending(ctx)
+		s.publisher.notifications(ctx, postID, 0)
+	}
+	return postID, err
+}
+
+func (s *eventModerationStore) RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error {
+	err := s.ModerationStore.RejectPendingPost(ctx, pendingID, moderatorID, reason)
+	if err == nil {
+		s.publisher.pending(ctx)
+	}
+	return err
+}
+
+// eventNotificationStore publie le compteur de notifications quand l'utilisateur les lit,
+// pour mettre à jour ses autres onglets
+type eventNotificationStore struct {
+	NotificationStore
+	publisher *publisher
+}
+
+func (s *eventNotificationStore) MarkNotificationRead(ctx context.Context, notificationID, userID int) error {
+	err := s.NotificationStore.MarkNotificationRead(ctx, notificationID, userID)
+	if err == nil {
+		s.publisher.unread(ctx, userID)
+	}
+	return err
+}
+
+func (s *eventNotificationStore) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	err := s.NotificationStore.MarkAllNotificationsRead(ctx, userID)
+	if err == nil {
+		s.publisher.unread(ctx, userID)
+	}
+	return err
+}
+
+// eventMessageStore publie les nouveaux messages privés et les conversations lues
+type eventMessageStore struct {
+	MessageStore
+	publisher *publisher
+}
+
+func (s *eventMessageStore) CreateConversation(ctx context.Context, creatorID int, memberIDs []int, content string) (int, error) {
+	conversationID, err := s.MessageStore.CreateConversation(ctx, creatorID, memberIDs, content)
+	if err != nil {
+		return conversationID, err
+	}
+	// Le message vient d'être ajouté: c'est le dernier de la conversation
+	messages, lookupErr := s.MessageStore.GetMessages(ctx, conversationID, creatorID, 1)
+	if lookupErr != nil {
+		s.publisher.failed(events.TypeMessage, lookupErr)
+		return conversationID, nil
+	}
+	if len(messages) == 0 {
+		return conversationID, nil
+	}
+	s.publisher.message(ctx, conversationID, messages[0].ID, creatorID)
+	return conversationID, nil
+}
+
+func (s *eventMessageStore) SendMessage(ctx context.Context, conversationID, senderID int, content string) (int, error) {
+	messageID, err := s.MessageStore.SendMessage(ctx, conversationID, senderID, content)
+	if err == nil {
+		s.publisher.message(ctx, conversationID, messageID, senderID)
+	}
+	return messageID, err
+}
+
+func (s *eventMessageStore) MarkConversationRead(ctx context.Context, conversationID, userID int) error {
+	err := s.MessageStore.MarkConversationRead(ctx, conversationID, userID)
+	if err == nil {
+		s.publisher.message(ctx, conversationID, 0, userID)
+	}
+	return err
+}
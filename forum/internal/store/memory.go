@@ -0,0 +1,4045 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/markdown"
+	"forum/internal/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryStore implémente toutes les interfaces de stockage en mémoire, sans base de données
+// Il sert à faire tourner les handlers dans les essais: les données disparaissent à l'arrêt du programme
+// Les règles (droits, compteurs, messages d'erreur) sont les mêmes que celles du paquet models
+// Le contexte est accepté pour respecter les interfaces mais n'est pas utilisé: rien ne bloque en mémoire
+type MemoryStore struct {
+	mu sync.Mutex
+
+	users            map[int]*models.User
+	oauthIDs         map[int]string
+	sessions         map[string]*models.Session
+	categories       []models.Category
+	posts            map[int]*memoryPost
+	comments         map[int]*models.Comment
+	reactions        []models.Reaction
+	postReactions    map[reactionKey]time.Time // Date de chaque réaction posée
+	commentReactions map[reactionKey]time.Time
+	pendingPosts     map[int]*memoryPendingPost
+	reports          map[int]*models.Report
+	images           map[int]*models.Image
+	tags             map[int]*models.Tag
+	tagSynonyms      map[string]int // Synonyme -> ID du tag
+	notifications    map[int]*models.Notification
+	subscriptions    map[subscriptionKey]*models.Subscription
+	digestSentAt     map[int]time.Time // Fin de la période du dernier résumé de chaque utilisateur
+	conversations    map[int]*memoryConversation
+	messages         map[int]*models.Message
+	blocks           map[blockKey]time.Time // Date de chaque blocage
+	settings         map[string]string
+	bookmarks        map[bookmarkKey]*models.Bookmark
+	bookmarkFolders  map[int]*models.BookmarkFolder
+	polls            map[int]*memoryPoll
+	userBadges       map[userBadgeKey]time.Time // Date d'attribution de chaque badge
+	announcements    map[int]*models.Announcement
+	drafts           map[int]*models.Draft
+
+	lastIDs map[string]int // Dernier ID attribué dans chaque table
+}
+
+// memoryPost garde un post et les IDs de ses catégories et de ses tags
+type memoryPost struct {
+	post        models.Post
+	categoryIDs []int
+	tagIDs      []int
+	acceptedAt  time.Time // Date d'acceptation de la réponse acceptée
+	pinnedAt    time.Time // Date d'épinglage, zéro si le post n'est pas épinglé
+}
+
+// memoryPendingPost garde un post en attente et les IDs de ses catégories
+type memoryPendingPost struct {
+	post        models.PendingPost
+	categoryIDs []int
+}
+
+// userBadgeKey identifie un badge gagné par un utilisateur
+type userBadgeKey struct {
+	userID int
+	badge  string
+}
+
+// subscriptionKey identifie l'abonnement d'un utilisateur à une cible
+type subscriptionKey struct {
+	userID     int
+	targetType string
+	targetID   int
+}
+
+// memoryConversation garde une conversation privée et l'état de chacun de ses membres
+type memoryConversation struct {
+	id            int
+	createdAt     time.Time
+	lastMessageAt time.Time
+	members       map[int]*memoryMember
+}
+
+// memoryMember est la participation d'un utilisateur à une conversation
+type memoryMember struct {
+	lastReadMessageID int
+	left              bool
+}
+
+// blockKey identifie le blocage d'un utilisateur par un autre
+type blockKey struct {
+	userID    int
+	blockedID int
+}
+
+// bookmarkKey identifie le signet d'un utilisateur sur un contenu
+type bookmarkKey struct {
+	userID     int
+	targetType string
+	targetID   int
+}
+
+// memoryPoll garde un sondage, sans ses compteurs, et ses votes
+// poll.PostID vaut 0 tant que le sondage appartient au post en attente pendingID
+type memoryPoll struct {
+	poll      models.Poll
+	pendingID int
+	votes     map[pollVoteKey]time.Time // Date de chaque vote
+}
+
+// pollVoteKey identifie le choix d'une option par un utilisateur
+type pollVoteKey struct {
+	optionID int
+	userID   int
+}
+
+// reactionKey identifie une réaction d'un utilisateur sur un post ou un commentaire
+type reactionKey struct {
+	userID       int
+	itemID       int
+	reactionType string
+}
+
+// Vérification à la compilation que MemoryStore implémente toutes les interfaces
+var (
+	_ PostStore         = (*MemoryStore)(nil)
+	_ CommentStore      = (*MemoryStore)(nil)
+	_ UserStore         = (*MemoryStore)(nil)
+	_ SessionStore      = (*MemoryStore)(nil)
+	_ ModerationStore   = (*MemoryStore)(nil)
+	_ ImageStore        = (*MemoryStore)(nil)
+	_ TagStore          = (*MemoryStore)(nil)
+	_ CategoryStore     = (*MemoryStore)(nil)
+	_ NotificationStore = (*MemoryStore)(nil)
+	_ SubscriptionStore = (*MemoryStore)(nil)
+	_ DigestStore       = (*MemoryStore)(nil)
+	_ MessageStore      = (*MemoryStore)(nil)
+	_ BookmarkStore     = (*MemoryStore)(nil)
+	_ PollStore         = (*MemoryStore)(nil)
+	_ ReactionStore     = (*MemoryStore)(nil)
+	_ ReputationStore   = (*MemoryStore)(nil)
+	_ DraftStore        = (*MemoryStore)(nil)
+)
+
+// NewMemoryStore crée un stockage vide contenant les catégories par défaut
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{
+		users:            map[int]*models.User{},
+		oauthIDs:         map[int]string{},
+		sessions:         map[string]*models.Session{},
+		posts:            map[int]*memoryPost{},
+		comments:         map[int]*models.Comment{},
+		postReactions:    map[reactionKey]time.Time{},
+		commentReactions: map[reactionKey]time.Time{},
+		pendingPosts:     map[int]*memoryPendingPost{},
+		reports:          map[int]*models.Report{},
+		images:           map[int]*models.Image{},
+		tags:             map[int]*models.Tag{},
+		tagSynonyms:      map[string]int{},
+		notifications:    map[int]*models.Notification{},
+		subscriptions:    map[subscriptionKey]*models.Subscription{},
+		digestSentAt:     map[int]time.Time{},
+		conversations:    map[int]*memoryConversation{},
+		messages:         map[int]*models.Message{},
+		blocks:           map[blockKey]time.Time{},
+		settings:         map[string]string{},
+		bookmarks:        map[bookmarkKey]*models.Bookmark{},
+		bookmarkFolders:  map[int]*models.BookmarkFolder{},
+		polls:            map[int]*memoryPoll{},
+		userBadges:       map[userBadgeKey]time.Time{},
+		announcements:    map[int]*models.Announcement{},
+		drafts:           map[int]*models.Draft{},
+		lastIDs:          map[string]int{},
+	}
+	for i, name := range []string{"Général", "Technologie", "Sport", "Musique", "Cinéma", "Jeux vidéo", "Science", "Art", "Politique", "Autre"} {
+		id := m.nextID("categories")
+		m.categories = append(m.categories, models.Category{ID: id, Name: name, Position: i + 1, PostPermission: models.CategoryOpen})
+	}
+	m.reactions = []models.Reaction{
+		{ID: m.nextID("reactions"), Name: "like", Emoji: "👍", Label: "J'aime", Score: 1, Position: 1},
+		{ID: m.nextID("reactions"), Name: "dislike", Emoji: "👎", Label: "Je n'aime pas", Score: -1, Position: 2},
+	}
+	return m
+}
+
+// NewMemoryStores renvoie les dépendances de stockage branchées sur un nouveau MemoryStore
+func NewMemoryStores() Stores {
+	m := NewMemoryStore()
+	return Stores{
+		Posts:         m,
+		Comments:      m,
+		Users:         m,
+		Sessions:      m,
+		Moderation:    m,
+		Images:        m,
+		Categories:    m,
+		Tags:          m,
+		Notifications: m,
+		Subscriptions: m,
+		Digests:       m,
+		Messages:      m,
+		Bookmarks:     m,
+		Polls:         m,
+		Reactions:     m,
+		Reputation:    m,
+		Drafts:        m,
+	}
+}
+
+// nextID renvoie un nouvel identifiant pour une table, comme AUTOINCREMENT
+func (m *MemoryStore) nextID(table string) int {
+	m.lastIDs[table]++
+	return m.lastIDs[table]
+}
+
+// username renvoie le nom d'un utilisateur, ou une chaîne vide s'il n'existe pas
+func (m *MemoryStore) username(userID int) string {
+	if user, ok := m.users[userID]; ok {
+		return user.Username
+	}
+	return ""
+}
+
+// userRole renvoie le rôle d'un utilisateur, ou une chaîne vide pour un visiteur
+func (m *MemoryStore) userRole(userID int) string {
+	if user, ok := m.users[userID]; ok {
+		return user.Role
+	}
+	return ""
+}
+
+// canRead indique si l'utilisateur userID (0 pour un visiteur) peut lire le post
+func (m *MemoryStore) canRead(stored *memoryPost, userID int) bool {
+	return models.CanReadPost(m.categories, stored.categoryIDs, m.userRole(userID))
+}
+
+// Posts
+
+func (m *MemoryStore) CreatePost(ctx context.Context, title, content string, userID int, categoryIDs []int) (int, error) {
+	return m.CreatePostWithDetails(ctx, models.NewPost{Title: title, Content: content, UserID: userID, CategoryIDs: categoryIDs})
+}
+
+func (m *MemoryStore) CreatePostWithDetails(ctx context.Context, post models.NewPost) (int, error) {
+	if post.Title == "" || post.Content == "" {
+		return 0, errors.New("title and content are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// L'image et le brouillon sont vérifiés avant de créer le post, pour ne rien publier s'ils manquent
+	if err := m.checkDraft(post); err != nil {
+		return 0, err
+	}
+	var image *models.Image
+	if post.ImageID > 0 {
+		var ok bool
+		image, ok = m.images[post.ImageID]
+		if !ok || image.UserID != post.UserID || image.PostID.Valid {
+			return 0, errors.New("image not found")
+		}
+	}
+
+	now := time.Now()
+	id := m.nextID("posts")
+	stored := &memoryPost{
+		post: models.Post{
+			ID:        id,
+			Title:     post.Title,
+			Content:   post.Content,
+			UserID:    post.UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		categoryIDs: append([]int(nil), post.CategoryIDs...),
+	}
+	m.posts[id] = stored
+	m.setPostTags(stored, post.Tags)
+	if post.Poll != nil {
+		m.insertPoll(id, 0, post.Poll)
+	}
+	if image != nil {
+		image.PostID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	delete(m.drafts, post.DraftID)
+	m.notifyMentions(post.UserID, id, 0, post.Content)
+	m.subscribeToPost(post.UserID, id)
+	m.notifyNewPost(post.UserID, id)
+	return id, nil
+}
+
+// postCopy renvoie une copie du post complétée comme le fait loadPostDetails
+func (m *MemoryStore) postCopy(stored *memoryPost, currentUserID int) *models.Post {
+	post := stored.post
+	post.Username = m.username(post.UserID)
+	post.Categories = []models.Category{}
+	for _, category := range m.sortedCategories() {
+		for _, id := range stored.categoryIDs {
+			if id == category.ID {
+				post.Categories = append(post.Categories, category)
+			}
+		}
+	}
+	post.Tags = []models.Tag{}
+	for _, id := range stored.tagIDs {
+		post.Tags = append(post.Tags, models.Tag{ID: id, Name: m.tags[id].Name})
+	}
+	sort.Slice(post.Tags, func(i, j int) bool { return post.Tags[i].Name < post.Tags[j].Name })
+	post.Reactions = m.reactionCounts(m.postReactions, post.ID, currentUserID)
+	post.AuthorBadges = m.authorBadges(post.UserID)
+	post.Pinned = !stored.pinnedAt.IsZero() && (!post.PinnedUntil.Valid || time.Now().Before(post.PinnedUntil.Time))
+	post.IsBookmarked = false
+	if currentUserID > 0 {
+		_, post.IsBookmarked = m.bookmarks[bookmarkKey{currentUserID, models.BookmarkPost, post.ID}]
+	}
+	return &post
+}
+
+func (m *MemoryStore) GetPostByID(ctx context.Context, postID, currentUserID int) (*models.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return nil, models.ErrPostNotFound
+	}
+	return m.postCopy(stored, currentUserID), nil
+}
+
+func (m *MemoryStore) GetPosts(ctx context.Context, page, perPage, categoryID int, tags []string, userID int, solved, sortBy string, currentUserID int) ([]*models.Post, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Une catégorie inclut les posts de toutes ses sous-catégories
+	var categoryIDs []int
+	if categoryID > 0 {
+		categoryIDs = models.CategoryDescendants(m.categories, categoryID)
+	}
+
+	now := time.Now()
+	var posts []*models.Post
+	for _, stored := range m.posts {
+		if !m.canRead(stored, currentUserID) {
+			continue
+		}
+		post := m.postCopy(stored, currentUserID)
+		if userID > 0 && post.UserID != userID {
+			continue
+		}
+		if categoryID > 0 && !hasAnyCategory(post, categoryIDs) {
+			continue
+		}
+		if !m.hasAllTags(stored, tags) {
+			continue
+		}
+		if (solved == models.PostsSolved && post.AcceptedCommentID == 0) || (solved == models.PostsUnsolved && post.AcceptedCommentID != 0) {
+			continue
+		}
+		switch sortBy {
+		case "top_week":
+			if post.CreatedAt.Before(now.AddDate(0, 0, -7)) {
+				continue
+			}
+		case "top_month":
+			if post.CreatedAt.Before(now.AddDate(0, -1, 0)) {
+				continue
+			}
+		}
+		posts = append(posts, post)
+	}
+
+	// Même ordre que GetPosts: le critère choisi, puis les plus récents d'abord
+	score := func(post *models.Post) float64 {
+		switch sortBy {
+		case "likes":
+			return float64(post.Likes)
+		case "hot":
+			hours := now.Sub(post.CreatedAt).Hours() + 2
+			return float64(post.Likes-post.Dislikes+post.CommentCount) / (hours * hours)
+		case "controversial":
+			high, low := post.Likes, post.Dislikes
+			if low > high {
+				high, low = low, high
+			}
+			if high < 1 {
+				high = 1
+			}
+			return float64(post.Likes+post.Dislikes) * float64(low) / float64(high)
+		case "comments":
+			return float64(post.CommentCount)
+		case "top_week", "top_month":
+			return float64(post.Likes - post.Dislikes)
+		}
+		return 0
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		// Les posts épinglés dans la liste affichée passent en tête, sauf dans la liste des posts d'un auteur
+		if pi, pj := userID == 0 && posts[i].PinnedIn(categoryID), userID == 0 && posts[j].PinnedIn(categoryID); pi != pj {
+			return pi
+		}
+		if si, sj := score(posts[i]), score(posts[j]); si != sj {
+			return si > sj
+		}
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			// Départage stable par ID, à la place de l'ordre d'insertion de SQLite
+			if sortBy == "date_asc" {
+				return posts[i].ID < posts[j].ID
+			}
+			return posts[i].ID > posts[j].ID
+		}
+		if sortBy == "date_asc" {
+			return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	total := len(posts)
+	return paginate(posts, page, perPage), total, nil
+}
+
+// hasAnyCategory vérifie si un post appartient à l'une des catégories
+func hasAnyCategory(post *models.Post, categoryIDs []int) bool {
+	for _, category := range post.Categories {
+		for _, id := range categoryIDs {
+			if category.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paginate renvoie la page demandée d'une liste déjà triée
+func paginate[T any](items []T, page, perPage int) []T {
+	offset := (page - 1) * perPage
+	if offset < 0 || offset >= len(items) {
+		return nil
+	}
+	end := offset + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+func (m *MemoryStore) UpdatePost(ctx context.Context, postID, userID int, title, content string, categoryIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok || stored.post.UserID != userID {
+		return errors.New("post not found or you don't have permission to edit it")
+	}
+	stored.post.Title = title
+	stored.post.Content = content
+	stored.post.UpdatedAt = time.Now()
+	stored.categoryIDs = append([]int(nil), categoryIDs...)
+	m.notifyMentions(userID, postID, 0, content)
+	return nil
+}
+
+func (m *MemoryStore) DeletePost(ctx context.Context, postID, userID int, isAdmin bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok || (!isAdmin && stored.post.UserID != userID) {
+		return errors.New("post not found or you don't have permission to delete it")
+	}
+	m.deletePost(postID)
+	return nil
+}
+
+// deletePost supprime un post avec ses commentaires et ses réactions, comme les ON DELETE CASCADE
+func (m *MemoryStore) deletePost(postID int) {
+	delete(m.posts, postID)
+	m.pruneTags()
+	for id, notification := range m.notifications {
+		if notification.PostID == postID {
+			delete(m.notifications, id)
+		}
+	}
+	for key := range m.subscriptions {
+		if key.targetType == models.SubscriptionPost && key.targetID == postID {
+			delete(m.subscriptions, key)
+		}
+	}
+	for key := range m.postReactions {
+		if key.itemID == postID {
+			delete(m.postReactions, key)
+		}
+	}
+	for key := range m.bookmarks {
+		if key.targetType == models.BookmarkPost && key.targetID == postID {
+			delete(m.bookmarks, key)
+		}
+	}
+	for id, stored := range m.polls {
+		if stored.poll.PostID == postID {
+			delete(m.polls, id)
+		}
+	}
+	for id, comment := range m.comments {
+		if comment.PostID == postID {
+			m.deleteComment(id)
+		}
+	}
+	for _, image := range m.images {
+		if image.PostID.Valid && int(image.PostID.Int64) == postID {
+			image.PostID = sql.NullInt64{}
+		}
+	}
+	for id, draft := range m.drafts {
+		if draft.PostID == postID {
+			delete(m.drafts, id)
+		}
+	}
+}
+
+func (m *MemoryStore) ReactToPost(ctx context.Context, postID, userID int, reactionType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	if stored.post.Locked {
+		return models.ErrPostLocked
+	}
+	if err := m.react(m.postReactions, postID, userID, reactionType); err != nil {
+		return err
+	}
+	stored.post.Likes, stored.post.Dislikes = m.scoreCounts(m.postReactions, postID)
+	return nil
+}
+
+func (m *MemoryStore) AcceptAnswer(ctx context.Context, postID, commentID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	if commentID != 0 {
+		comment, ok := m.comments[commentID]
+		if !ok || comment.PostID != postID {
+			return models.ErrCommentNotFound
+		}
+	}
+	// Accepter de nouveau la même réponse garde la date de la première acceptation
+	if stored.post.AcceptedCommentID != commentID {
+		stored.post.AcceptedCommentID = commentID
+		stored.acceptedAt = time.Time{}
+		if commentID != 0 {
+			stored.acceptedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetAllCategories(ctx context.Context, role string) ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return models.VisibleCategories(models.SortCategoryTree(m.categories), role), nil
+}
+
+func (m *MemoryStore) GetUserPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var posts []*models.Post
+	for _, stored := range m.posts {
+		if stored.post.UserID == userID {
+			posts = append(posts, m.postCopy(stored, userID))
+		}
+	}
+	sortPostsByDate(posts)
+	return posts, nil
+}
+
+func (m *MemoryStore) GetUserLikedPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	return m.getUserReactedPosts(userID, "like")
+}
+
+func (m *MemoryStore) GetUserDislikedPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	return m.getUserReactedPosts(userID, "dislike")
+}
+
+func (m *MemoryStore) getUserReactedPosts(userID int, reactionType string) ([]*models.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var posts []*models.Post
+	for key := range m.postReactions {
+		if key.userID != userID || key.reactionType != reactionType {
+			continue
+		}
+		if stored, ok := m.posts[key.itemID]; ok {
+			posts = append(posts, m.postCopy(stored, userID))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return m.postReactions[reactionKey{userID, posts[i].ID, reactionType}].After(m.postReactions[reactionKey{userID, posts[j].ID, reactionType}])
+	})
+	return posts, nil
+}
+
+// sortPostsByDate trie des posts du plus récent au plus ancien
+func sortPostsByDate(posts []*models.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+}
+
+// Search fait une recherche simple par sous-chaîne (sans classement par pertinence ni surlignage)
+func (m *MemoryStore) Search(ctx context.Context, query models.SearchQuery, page, perPage, currentUserID int) ([]*models.SearchResult, int, error) {
+	if query.IsEmpty() {
+		return nil, 0, errors.New("empty search query")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var results []*models.SearchResult
+	for _, stored := range m.posts {
+		if !m.canRead(stored, currentUserID) {
+			continue
+		}
+		post := m.postCopy(stored, 0)
+		if !m.matchesSearch(query, post.Title+" "+post.Content, post.UserID, post, post.CreatedAt) {
+			continue
+		}
+		results = append(results, &models.SearchResult{
+			Type:      "post",
+			PostID:    post.ID,
+			Title:     post.Title,
+			Snippet:   post.Content,
+			UserID:    post.UserID,
+			Username:  post.Username,
+			CreatedAt: post.CreatedAt,
+			Likes:     post.Likes,
+			Dislikes:  post.Dislikes,
+		})
+	}
+	for _, comment := range m.comments {
+		stored, ok := m.posts[comment.PostID]
+		if !ok || !m.canRead(stored, currentUserID) {
+			continue
+		}
+		post := m.postCopy(stored, 0)
+		if !m.matchesSearch(query, comment.Content, comment.UserID, post, comment.CreatedAt) {
+			continue
+		}
+		results = append(results, &models.SearchResult{
+			Type:      "comment",
+			PostID:    comment.PostID,
+			CommentID: comment.ID,
+			Title:     post.Title,
+			Snippet:   comment.Content,
+			UserID:    comment.UserID,
+			Username:  m.username(comment.UserID),
+			CreatedAt: comment.CreatedAt,
+			Likes:     comment.Likes,
+			Dislikes:  comment.Dislikes,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	total := len(results)
+	return paginate(results, page, perPage), total, nil
+}
+
+// SearchEnabled renvoie toujours vrai: la recherche en mémoire ne dépend d'aucune option de compilation
+func (m *MemoryStore) SearchEnabled() bool {
+	return true
+}
+
+// matchesSearch vérifie qu'un texte contient tous les termes et respecte les filtres de la recherche
+func (m *MemoryStore) matchesSearch(query models.SearchQuery, text string, authorID int, post *models.Post, createdAt time.Time) bool {
+	text = strings.ToLower(text)
+	for _, term := range query.Terms {
+		if !strings.Contains(text, strings.ToLower(strings.TrimSuffix(term, "*"))) {
+			return false
+		}
+	}
+	for _, phrase := range query.Phrases {
+		if !strings.Contains(text, strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	if query.Author != "" && !strings.EqualFold(m.username(authorID), query.Author) {
+		return false
+	}
+	if query.Category != "" {
+		found := false
+		for _, category := range post.Categories {
+			if strings.EqualFold(category.Name, query.Category) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	day := createdAt.Format("2006-01-02")
+	if query.After != "" && day < query.After {
+		return false
+	}
+	if query.Before != "" && day > query.Before {
+		return false
+	}
+	return true
+}
+
+// Commentaires
+
+func (m *MemoryStore) CreateComment(ctx context.Context, content string, userID, postID int) (int, error) {
+	if content == "" {
+		return 0, errors.New("comment content is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return 0, models.ErrPostNotFound
+	}
+	if stored.post.Locked {
+		return 0, models.ErrPostLocked
+	}
+
+	now := time.Now()
+	id := m.nextID("comments")
+	m.comments[id] = &models.Comment{
+		ID:        id,
+		Content:   content,
+		UserID:    userID,
+		PostID:    postID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	stored.post.CommentCount++
+	m.notifyMentions(userID, postID, id, content)
+	m.notifyNewComment(userID, postID, id)
+	m.subscribeToPost(userID, postID)
+	return id, nil
+}
+
+// commentCopy renvoie une copie du commentaire complétée comme le fait loadCommentDetails
+func (m *MemoryStore) commentCopy(stored *models.Comment, currentUserID int) *models.Comment {
+	comment := *stored
+	comment.Username = m.username(comment.UserID)
+	comment.Reactions = m.reactionCounts(m.commentReactions, comment.ID, currentUserID)
+	comment.AuthorBadges = m.authorBadges(comment.UserID)
+	comment.IsBookmarked = false
+	if currentUserID > 0 {
+		_, comment.IsBookmarked = m.bookmarks[bookmarkKey{currentUserID, models.BookmarkComment, comment.ID}]
+	}
+	return &comment
+}
+
+// sortCommentsByDate trie des commentaires par date de création
+func sortCommentsByDate(comments []*models.Comment, newestFirst bool) {
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return (comments[i].ID > comments[j].ID) == newestFirst
+		}
+		return comments[i].CreatedAt.After(comments[j].CreatedAt) == newestFirst
+	})
+}
+
+func (m *MemoryStore) GetCommentsByPostID(ctx context.Context, postID, currentUserID int) ([]*models.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var comments []*models.Comment
+	for _, comment := range m.comments {
+		if comment.PostID == postID {
+			comments = append(comments, m.commentCopy(comment, currentUserID))
+		}
+	}
+	sortCommentsByDate(comments, false)
+	return comments, nil
+}
+
+func (m *MemoryStore) GetCommentByID(ctx context.Context, commentID, currentUserID int) (*models.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[commentID]
+	if !ok {
+		return nil, models.ErrCommentNotFound
+	}
+	return m.commentCopy(comment, currentUserID), nil
+}
+
+func (m *MemoryStore) UpdateComment(ctx context.Context, commentID, userID int, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[commentID]
+	if !ok || comment.UserID != userID {
+		return errors.New("comment not found or you don't have permission to edit it")
+	}
+	comment.Content = content
+	comment.UpdatedAt = time.Now()
+	m.notifyMentions(userID, comment.PostID, commentID, content)
+	return nil
+}
+
+func (m *MemoryStore) DeleteComment(ctx context.Context, commentID, userID int, isAdmin bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[commentID]
+	if !ok || (!isAdmin && comment.UserID != userID) {
+		return errors.New("comment not found or you don't have permission to delete it")
+	}
+	m.deleteComment(commentID)
+	return nil
+}
+
+// deleteComment supprime un commentaire et ses réactions, et met à jour le compteur de son post
+func (m *MemoryStore) deleteComment(commentID int) {
+	comment, ok := m.comments[commentID]
+	if !ok {
+		return
+	}
+	if stored, ok := m.posts[comment.PostID]; ok {
+		stored.post.CommentCount--
+		// Comme le déclencheur posts_accepted_comment_delete: le post n'est plus résolu
+		if stored.post.AcceptedCommentID == commentID {
+			stored.post.AcceptedCommentID = 0
+			stored.acceptedAt = time.Time{}
+		}
+	}
+	delete(m.comments, commentID)
+	for id, notification := range m.notifications {
+		if notification.CommentID == commentID {
+			delete(m.notifications, id)
+		}
+	}
+	for key := range m.commentReactions {
+		if key.itemID == commentID {
+			delete(m.commentReactions, key)
+		}
+	}
+	for key := range m.bookmarks {
+		if key.targetType == models.BookmarkComment && key.targetID == commentID {
+			delete(m.bookmarks, key)
+		}
+	}
+}
+
+func (m *MemoryStore) ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[commentID]
+	if !ok {
+		return models.ErrCommentNotFound
+	}
+	if stored, ok := m.posts[comment.PostID]; ok && stored.post.Locked {
+		return models.ErrPostLocked
+	}
+	if err := m.react(m.commentReactions, commentID, userID, reactionType); err != nil {
+		return err
+	}
+	comment.Likes, comment.Dislikes = m.scoreCounts(m.commentReactions, commentID)
+	return nil
+}
+
+func (m *MemoryStore) GetUserComments(ctx context.Context, userID int) ([]*models.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var comments []*models.Comment
+	for _, comment := range m.comments {
+		if comment.UserID != userID {
+			continue
+		}
+		if _, ok := m.posts[comment.PostID]; ok {
+			comments = append(comments, m.commentCopy(comment, userID))
+		}
+	}
+	sortCommentsByDate(comments, true)
+	return comments, nil
+}
+
+// Utilisateurs
+
+func (m *MemoryStore) RegisterUser(ctx context.Context, email, username, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return errors.New("email already exists")
+		}
+	}
+	for _, user := range m.users {
+		if user.Username == username {
+			return errors.New("username already exists")
+		}
+	}
+
+	// Le coût minimal garde les essais rapides, la vérification reste la même
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+
+	id := m.nextID("users")
+	m.users[id] = &models.User{
+		ID:              id,
+		Email:           email,
+		Username:        username,
+		Password:        string(hashedPassword),
+		Role:            "user",
+		CreatedAt:       time.Now(),
+		DigestFrequency: models.DigestNone,
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	copied := *user
+	copied.UnreadNotifications = 0
+	for _, notification := range m.notifications {
+		if notification.UserID == id && !notification.IsRead {
+			copied.UnreadNotifications++
+		}
+	}
+	copied.UnreadConversations = 0
+	for _, conversation := range m.conversations {
+		if m.unreadMessages(conversation, id) > 0 {
+			copied.UnreadConversations++
+		}
+	}
+	return &copied, nil
+}
+
+func (m *MemoryStore) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := m.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return nil, errors.New("invalid password")
+	}
+	return user, nil
+}
+
+func (m *MemoryStore) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := []*models.User{}
+	for _, user := range m.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (m *MemoryStore) GetUserActivity(ctx context.Context, userID, limit int) ([]*models.UserActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var activities []*models.UserActivity
+	for _, stored := range m.posts {
+		if stored.post.UserID == userID {
+			activities = append(activities, &models.UserActivity{
+				Type: "post", ID: stored.post.ID, Content: stored.post.Title,
+				PostID: stored.post.ID, PostTitle: stored.post.Title, CreatedAt: stored.post.CreatedAt,
+			})
+		}
+	}
+	for _, comment := range m.comments {
+		stored, ok := m.posts[comment.PostID]
+		if comment.UserID == userID && ok {
+			activities = append(activities, &models.UserActivity{
+				Type: "comment", ID: comment.ID, Content: comment.Content,
+				PostID: comment.PostID, PostTitle: stored.post.Title, CreatedAt: comment.CreatedAt,
+			})
+		}
+	}
+	for key, createdAt := range m.postReactions {
+		stored, ok := m.posts[key.itemID]
+		reaction, known := m.reactionByName(key.reactionType)
+		if key.userID == userID && ok && known {
+			activities = append(activities, &models.UserActivity{
+				Type: "post_reaction", ID: key.itemID, PostID: key.itemID,
+				PostTitle: stored.post.Title, CreatedAt: createdAt, Reaction: key.reactionType,
+				ReactionEmoji: reaction.Emoji, ReactionLabel: reaction.Label,
+			})
+		}
+	}
+	for key, createdAt := range m.commentReactions {
+		comment, ok := m.comments[key.itemID]
+		reaction, known := m.reactionByName(key.reactionType)
+		if key.userID != userID || !ok || !known {
+			continue
+		}
+		if stored, ok := m.posts[comment.PostID]; ok {
+			activities = append(activities, &models.UserActivity{
+				Type: "comment_reaction", ID: key.itemID, Content: comment.Content, PostID: comment.PostID,
+				PostTitle: stored.post.Title, CreatedAt: createdAt, Reaction: key.reactionType,
+				ReactionEmoji: reaction.Emoji, ReactionLabel: reaction.Label,
+			})
+		}
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].CreatedAt.After(activities[j].CreatedAt)
+	})
+	if len(activities) > limit {
+		activities = activities[:limit]
+	}
+	return activities, nil
+}
+
+func (m *MemoryStore) GetUserByOAuthID(ctx context.Context, oauthID string) (*models.User, error) {
+	m.mu.Lock()
+	var userID int
+	for id, linked := range m.oauthIDs {
+		if linked == oauthID {
+			userID = id
+		}
+	}
+	m.mu.Unlock()
+
+	if userID == 0 {
+		return nil, models.ErrUserNotFound
+	}
+	return m.GetUserByID(ctx, userID)
+}
+
+func (m *MemoryStore) LinkOAuthID(ctx context.Context, userID int, oauthID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; ok {
+		m.oauthIDs[userID] = oauthID
+	}
+	return nil
+}
+
+func (m *MemoryStore) CreateOAuthUser(ctx context.Context, email, username, oauthID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email || user.Username == username {
+			return 0, errors.New("UNIQUE constraint failed: users")
+		}
+	}
+
+	id := m.nextID("users")
+	m.users[id] = &models.User{ID: id, Email: email, Username: username, Role: "user", CreatedAt: time.Now(), DigestFrequency: models.DigestNone}
+	m.oauthIDs[id] = oauthID
+	return id, nil
+}
+
+func (m *MemoryStore) UsernameExists(ctx context.Context, username string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddUser ajoute directement un utilisateur avec un rôle donné (pour préparer un essai)
+func (m *MemoryStore) AddUser(ctx context.Context, email, username, password, role string) (*models.User, error) {
+	if err := m.RegisterUser(ctx, email, username, password); err != nil {
+		return nil, err
+	}
+	user, err := m.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.UpdateUserRole(ctx, user.ID, role); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// Sessions
+
+func (m *MemoryStore) CreateSession(ctx context.Context, userID int) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, session := range m.sessions {
+		if session.UserID == userID {
+			delete(m.sessions, key)
+		}
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:        m.nextID("sessions"),
+		UUID:      uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(24 * time.Hour),
+	}
+	m.sessions[session.UUID] = session
+
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MemoryStore) GetSessionByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[uuid]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	if session.ExpiresAt.Before(time.Now()) {
+		delete(m.sessions, uuid)
+		return nil, errors.New("session expired")
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MemoryStore) DeleteSession(ctx context.Context, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, uuid)
+	return nil
+}
+
+// Modération
+
+func (m *MemoryStore) SubmitPendingPost(ctx context.Context, post models.NewPost) (int, error) {
+	if post.Title == "" || post.Content == "" {
+		return 0, errors.New("title and content are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkDraft(post); err != nil {
+		return 0, err
+	}
+	id := m.nextID("pending_posts")
+	m.pendingPosts[id] = &memoryPendingPost{
+		post: models.PendingPost{
+			ID:        id,
+			Title:     post.Title,
+			Content:   post.Content,
+			UserID:    post.UserID,
+			Status:    "pending",
+			CreatedAt: time.Now(),
+			Tags:      append([]string(nil), post.Tags...),
+		},
+		categoryIDs: append([]int(nil), post.CategoryIDs...),
+	}
+	if post.Poll != nil {
+		m.insertPoll(0, id, post.Poll)
+	}
+	delete(m.drafts, post.DraftID)
+	return id, nil
+}
+
+func (m *MemoryStore) GetPendingPosts(ctx context.Context) ([]*models.PendingPost, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pendingPosts []*models.PendingPost
+	for _, stored := range m.pendingPosts {
+		if stored.post.Status != "pending" {
+			continue
+		}
+		post := stored.post
+		post.Username = m.username(post.UserID)
+		post.Categories = []models.Category{}
+		for _, category := range m.sortedCategories() {
+			for _, id := range stored.categoryIDs {
+				if id == category.ID {
+					post.Categories = append(post.Categories, category)
+				}
+			}
+		}
+		for _, poll := range m.polls {
+			if poll.pendingID == post.ID {
+				post.Poll = m.pollCopy(poll, 0)
+			}
+		}
+		pendingPosts = append(pendingPosts, &post)
+	}
+	sort.Slice(pendingPosts, func(i, j int) bool {
+		return pendingPosts[i].CreatedAt.After(pendingPosts[j].CreatedAt)
+	})
+	return pendingPosts, nil
+}
+
+func (m *MemoryStore) ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error) {
+	m.mu.Lock()
+	stored, ok := m.pendingPosts[pendingID]
+	if !ok || stored.post.Status != "pending" {
+		m.mu.Unlock()
+		return 0, errors.New("pending post not found or already processed")
+	}
+	stored.post.Status = "approved"
+	stored.post.ModeratorID = sql.NullInt64{Int64: int64(moderatorID), Valid: true}
+	pending := stored.post
+	categoryIDs := stored.categoryIDs
+	m.mu.Unlock()
+
+	// Les tags et le sondage soumis avec le post le suivent
+	postID, err := m.CreatePostWithDetails(ctx, models.NewPost{
+		Title:       pending.Title,
+		Content:     pending.Content,
+		UserID:      pending.UserID,
+		CategoryIDs: categoryIDs,
+		Tags:        pending.Tags,
+	})
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stored := range m.polls {
+		if stored.pendingID == pendingID {
+			stored.pendingID = 0
+			stored.poll.PostID = postID
+		}
+	}
+	return postID, nil
+}
+
+func (m *MemoryStore) RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.pendingPosts[pendingID]
+	if !ok {
+		return errors.New("pending post not found")
+	}
+	if stored.post.Status != "pending" {
+		return errors.New("post is not in pending status")
+	}
+	stored.post.Status = "rejected"
+	stored.post.ModeratorID = sql.NullInt64{Int64: int64(moderatorID), Valid: true}
+	stored.post.Reason = sql.NullString{String: reason, Valid: true}
+	return nil
+}
+
+func (m *MemoryStore) ReportContent(ctx context.Context, contentType string, contentID, reporterID int, reason string) (int, error) {
+	if contentType != "post" && contentType != "comment" && contentType != "message" {
+		return 0, errors.New("invalid content type")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// L'auteur du contenu est retenu: le contenu disparaît si le signalement est approuvé
+	exists := false
+	contentUserID := 0
+	if contentType == "post" {
+		var stored *memoryPost
+		if stored, exists = m.posts[contentID]; exists {
+			contentUserID = stored.post.UserID
+		}
+	} else if contentType == "message" {
+		var message *models.Message
+		if message, exists = m.messages[contentID]; exists {
+			contentUserID = message.UserID
+		}
+	} else {
+		var comment *models.Comment
+		if comment, exists = m.comments[contentID]; exists {
+			contentUserID = comment.UserID
+		}
+	}
+	if !exists {
+		return 0, errors.New("content not found")
+	}
+
+	for _, report := range m.reports {
+		if report.Type == contentType && report.ContentID == contentID && report.ReporterID == reporterID {
+			return 0, models.ErrAlreadyReported
+		}
+	}
+
+	return m.addReport(contentType, contentID, reporterID, contentUserID, reason), nil
+}
+
+// addReport enregistre un signalement en attente
+func (m *MemoryStore) addReport(contentType string, contentID, reporterID, contentUserID int, reason string) int {
+	now := time.Now()
+	id := m.nextID("reports")
+	m.reports[id] = &models.Report{
+		ID:            id,
+		Type:          contentType,
+		ContentID:     contentID,
+		ReporterID:    reporterID,
+		Reason:        reason,
+		Status:        "pending",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ContentUserID: contentUserID,
+	}
+	return id
+}
+
+func (m *MemoryStore) GetReports(ctx context.Context) ([]*models.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reports []*models.Report
+	for _, report := range m.reports {
+		if report.Status != "pending" {
+			continue
+		}
+		copied := *report
+		copied.ReporterName = m.username(report.ReporterID)
+		if message, ok := m.messages[report.ContentID]; ok && report.Type == "message" {
+			copied.MessageAuthor = m.username(message.UserID)
+			copied.MessageContent = message.Content
+		}
+		reports = append(reports, &copied)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+func (m *MemoryStore) HandleReport(ctx context.Context, reportID, adminID int, status, response string) error {
+	if status != "approved" && status != "rejected" {
+		return errors.New("invalid status")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report, ok := m.reports[reportID]
+	if !ok {
+		return errors.New("report not found")
+	}
+	if report.Status != "pending" {
+		return errors.New("report has already been processed")
+	}
+
+	report.Status = status
+	report.AdminID = sql.NullInt64{Int64: int64(adminID), Valid: true}
+	report.AdminResponse = sql.NullString{String: response, Valid: true}
+	report.UpdatedAt = time.Now()
+
+	if status == "approved" {
+		if report.Type == "post" {
+			m.deletePost(report.ContentID)
+		} else if report.Type == "message" {
+			delete(m.messages, report.ContentID)
+		} else {
+			m.deleteComment(report.ContentID)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) UpdateUserRole(ctx context.Context, userID int, newRole string) error {
+	if newRole != "user" && newRole != "moderator" && newRole != "admin" {
+		return errors.New("invalid role")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	user.Role = newRole
+	return nil
+}
+
+func (m *MemoryStore) RequestModeratorRole(ctx context.Context, userID int, reason string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return 0, models.ErrUserNotFound
+	}
+	if user.Role == "moderator" || user.Role == "admin" {
+		return 0, errors.New("user already has elevated privileges")
+	}
+
+	return m.addReport("moderator_request", userID, userID, 0, reason), nil
+}
+
+func (m *MemoryStore) PinPost(ctx context.Context, postID, categoryID int, until, now time.Time) error {
+	if !until.IsZero() && !until.After(now) {
+		return models.ErrPinExpiryInPast
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	if categoryID > 0 && !hasAnyCategory(m.postCopy(stored, 0), models.CategoryDescendants(m.categories, categoryID)) {
+		return models.ErrPostNotInCategory
+	}
+
+	stored.pinnedAt = now
+	stored.post.PinnedCategoryID = categoryID
+	stored.post.PinnedUntil = sql.NullTime{}
+	if !until.IsZero() {
+		stored.post.PinnedUntil = sql.NullTime{Time: until.UTC(), Valid: true}
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnpinPost(ctx context.Context, postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	stored.pinnedAt = time.Time{}
+	stored.post.PinnedCategoryID = 0
+	stored.post.PinnedUntil = sql.NullTime{}
+	return nil
+}
+
+func (m *MemoryStore) SetPostLocked(ctx context.Context, postID int, locked bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	stored.post.Locked = locked
+	return nil
+}
+
+func (m *MemoryStore) CreateAnnouncement(ctx context.Context, announcement *models.Announcement) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *announcement
+	stored.ID = m.nextID("announcements")
+	m.announcements[stored.ID] = &stored
+	return stored.ID, nil
+}
+
+func (m *MemoryStore) DeleteAnnouncement(ctx context.Context, announcementID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.announcements[announcementID]; !ok {
+		return models.ErrAnnouncementNotFound
+	}
+	delete(m.announcements, announcementID)
+	return nil
+}
+
+func (m *MemoryStore) GetAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.announcementList(now, false), nil
+}
+
+func (m *MemoryStore) GetActiveAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.announcementList(now, true), nil
+}
+
+// announcementList copie les annonces, seulement celles qui n'ont pas expiré si activeOnly est vrai,
+// dans le même ordre que GetAnnouncements
+func (m *MemoryStore) announcementList(now time.Time, activeOnly bool) []*models.Announcement {
+	announcements := []*models.Announcement{}
+	for _, stored := range m.announcements {
+		announcement := *stored
+		announcement.Active = !announcement.ExpiresAt.Valid || now.Before(announcement.ExpiresAt.Time)
+		if activeOnly && !announcement.Active {
+			continue
+		}
+		announcement.Username = m.username(announcement.UserID)
+		announcements = append(announcements, &announcement)
+	}
+	sort.Slice(announcements, func(i, j int) bool {
+		if !announcements[i].CreatedAt.Equal(announcements[j].CreatedAt) {
+			return announcements[i].CreatedAt.After(announcements[j].CreatedAt)
+		}
+		return announcements[i].ID > announcements[j].ID
+	})
+	return announcements
+}
+
+// Images
+
+func (m *MemoryStore) SaveImage(ctx context.Context, filename string, userID int) (int, error) {
+	if filename == "" {
+		return 0, errors.New("filename is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextID("images")
+	m.images[id] = &models.Image{ID: id, Filename: filename, UserID: userID, CreatedAt: time.Now()}
+	return id, nil
+}
+
+func (m *MemoryStore) GetImageByID(ctx context.Context, imageID string) (*models.Image, error) {
+	id, err := strconv.Atoi(imageID)
+	if err != nil {
+		return nil, errors.New("image not found")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	image, ok := m.images[id]
+	if !ok {
+		return nil, errors.New("image not found")
+	}
+	copied := *image
+	return &copied, nil
+}
+
+func (m *MemoryStore) GetPostImage(ctx context.Context, postID int) (*models.Image, error) {
+	images, _ := m.GetImagesByPostID(ctx, postID)
+	if len(images) == 0 {
+		return nil, errors.New("image not found")
+	}
+	return images[len(images)-1], nil
+}
+
+// filterImages renvoie les images qui vérifient une condition, des plus récentes aux plus anciennes
+func (m *MemoryStore) filterImages(keep func(*models.Image) bool) []*models.Image {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var images []*models.Image
+	for _, image := range m.images {
+		if keep(image) {
+			copied := *image
+			images = append(images, &copied)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].CreatedAt.Equal(images[j].CreatedAt) {
+			return images[i].ID > images[j].ID
+		}
+		return images[i].CreatedAt.After(images[j].CreatedAt)
+	})
+	return images
+}
+
+func (m *MemoryStore) GetImagesByPostID(ctx context.Context, postID int) ([]*models.Image, error) {
+	return m.filterImages(func(image *models.Image) bool {
+		return image.PostID.Valid && int(image.PostID.Int64) == postID
+	}), nil
+}
+
+func (m *MemoryStore) GetImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error) {
+	return m.filterImages(func(image *models.Image) bool {
+		return image.UserID == userID
+	}), nil
+}
+
+func (m *MemoryStore) GetUnassociatedImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error) {
+	return m.filterImages(func(image *models.Image) bool {
+		return image.UserID == userID && !image.PostID.Valid
+	}), nil
+}
+
+func (m *MemoryStore) AssociateImageWithPost(ctx context.Context, imageID, postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	image, ok := m.images[imageID]
+	if !ok {
+		return errors.New("image not found")
+	}
+	if _, ok := m.posts[postID]; !ok {
+		return models.ErrPostNotFound
+	}
+	image.PostID = sql.NullInt64{Int64: int64(postID), Valid: true}
+	return nil
+}
+
+func (m *MemoryStore) DeletePostImage(ctx context.Context, postID int) error {
+	image, err := m.GetPostImage(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.images[image.ID]; ok {
+		stored.PostID = sql.NullInt64{}
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteImage(ctx context.Context, imageID, userID int, isAdmin bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	image, ok := m.images[imageID]
+	if !ok {
+		return errors.New("image not found")
+	}
+	if !isAdmin && image.UserID != userID {
+		return errors.New("you don't have permission to delete this image")
+	}
+	delete(m.images, imageID)
+	return nil
+}
+
+// Catégories
+
+// sortedCategories renvoie une copie des catégories dans l'ordre d'affichage
+func (m *MemoryStore) sortedCategories() []models.Category {
+	categories := append([]models.Category(nil), m.categories...)
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories
+}
+
+// categoryIndex renvoie l'indice d'une catégorie dans m.categories, ou -1
+func (m *MemoryStore) categoryIndex(categoryID int) int {
+	for i, category := range m.categories {
+		if category.ID == categoryID {
+			return i
+		}
+	}
+	return -1
+}
+
+// categoryNameTaken vérifie si une autre catégorie porte déjà ce nom
+func (m *MemoryStore) categoryNameTaken(name string, exceptID int) bool {
+	for _, category := range m.categories {
+		if category.ID != exceptID && category.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) GetCategories(ctx context.Context) ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return models.SortCategoryTree(m.categories), nil
+}
+
+func (m *MemoryStore) GetCategoryByID(ctx context.Context, categoryID int) (*models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.categoryIndex(categoryID)
+	if i < 0 {
+		return nil, models.ErrCategoryNotFound
+	}
+	category := m.categories[i]
+	return &category, nil
+}
+
+func (m *MemoryStore) CreateCategory(ctx context.Context, category models.Category) (int, error) {
+	if err := models.ValidateCategory(&category); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.categoryNameTaken(category.Name, 0) {
+		return 0, models.ErrCategoryNameTaken
+	}
+	if err := models.CategoryParentError(m.categories, 0, category.ParentID); err != nil {
+		return 0, err
+	}
+	category.ID = m.nextID("categories")
+	category.Position = 1
+	for _, existing := range m.categories {
+		if existing.Position >= category.Position {
+			category.Position = existing.Position + 1
+		}
+	}
+	m.categories = append(m.categories, category)
+	return category.ID, nil
+}
+
+func (m *MemoryStore) UpdateCategory(ctx context.Context, category models.Category) error {
+	if err := models.ValidateCategory(&category); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.categoryIndex(category.ID)
+	if i < 0 {
+		return models.ErrCategoryNotFound
+	}
+	if m.categoryNameTaken(category.Name, category.ID) {
+		return models.ErrCategoryNameTaken
+	}
+	if err := models.CategoryParentError(m.categories, category.ID, category.ParentID); err != nil {
+		return err
+	}
+	category.Position = m.categories[i].Position
+	m.categories[i] = category
+	return nil
+}
+
+func (m *MemoryStore) ReorderCategories(ctx context.Context, categoryIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	placed := map[int]bool{}
+	var order []int
+	for _, id := range categoryIDs {
+		if m.categoryIndex(id) < 0 {
+			return fmt.Errorf("%w: %d", models.ErrCategoryNotFound, id)
+		}
+		if !placed[id] {
+			placed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, category := range m.sortedCategories() {
+		if !placed[category.ID] {
+			order = append(order, category.ID)
+		}
+	}
+
+	for position, id := range order {
+		m.categories[m.categoryIndex(id)].Position = position + 1
+	}
+	return nil
+}
+
+func (m *MemoryStore) MergeCategories(ctx context.Context, sourceID, targetID int) error {
+	if sourceID == targetID {
+		return models.ErrMergeCategoryIntoItself
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source := m.categoryIndex(sourceID)
+	if source < 0 || m.categoryIndex(targetID) < 0 {
+		return models.ErrCategoryNotFound
+	}
+
+	for _, stored := range m.posts {
+		stored.categoryIDs = replaceID(stored.categoryIDs, sourceID, targetID)
+	}
+	for _, stored := range m.pendingPosts {
+		stored.categoryIDs = replaceID(stored.categoryIDs, sourceID, targetID)
+	}
+	for _, draft := range m.drafts {
+		if draft.HasCategory(sourceID) {
+			draft.CategoryIDs = replaceID(draft.CategoryIDs, sourceID, targetID)
+		}
+	}
+	for _, stored := range m.posts {
+		if stored.post.PinnedCategoryID == sourceID {
+			stored.post.PinnedCategoryID = targetID
+		}
+	}
+
+	for key, subscription := range m.subscriptions {
+		if key.targetType == models.SubscriptionCategory && key.targetID == sourceID {
+			delete(m.subscriptions, key)
+			key.targetID = targetID
+			if _, ok := m.subscriptions[key]; !ok {
+				subscription.TargetID = targetID
+				m.subscriptions[key] = subscription
+			}
+		}
+	}
+
+	// Même rattachement des sous-catégories que MergeCategories: une cible placée sous la source
+	// remonte d'abord à sa place
+	sourceParentID := m.categories[source].ParentID
+	for _, id := range models.CategoryDescendants(m.categories, sourceID)[1:] {
+		if id == targetID {
+			m.categories[m.categoryIndex(targetID)].ParentID = sourceParentID
+		}
+	}
+	for i := range m.categories {
+		if m.categories[i].ParentID == sourceID {
+			m.categories[i].ParentID = targetID
+		}
+	}
+	m.categories = append(m.categories[:source], m.categories[source+1:]...)
+	return nil
+}
+
+// replaceID remplace sourceID par targetID dans une liste d'IDs, sans créer de doublon
+func replaceID(categoryIDs []int, sourceID, targetID int) []int {
+	var result []int
+	seen := map[int]bool{}
+	for _, id := range categoryIDs {
+		if id == sourceID {
+			id = targetID
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func (m *MemoryStore) CheckCategoryPermission(ctx context.Context, categoryIDs []int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return models.CategoryPermissionError(m.categories, categoryIDs, role)
+}
+
+func (m *MemoryStore) GetCategoryTree(ctx context.Context, role string) ([]*models.CategoryNode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Dernière activité de chaque post: sa création ou son dernier commentaire
+	lastActivity := map[int]time.Time{}
+	for id, stored := range m.posts {
+		lastActivity[id] = stored.post.CreatedAt
+	}
+	for _, comment := range m.comments {
+		if comment.CreatedAt.After(lastActivity[comment.PostID]) {
+			lastActivity[comment.PostID] = comment.CreatedAt
+		}
+	}
+
+	// Un post compte une seule fois pour chaque ancêtre, même s'il est dans plusieurs catégories de la branche
+	// Les posts que le rôle ne peut pas lire ne sont pas comptés
+	postsByCategory := map[int]map[int]bool{}
+	for id, stored := range m.posts {
+		if !models.CanReadPost(m.categories, stored.categoryIDs, role) {
+			continue
+		}
+		for _, categoryID := range stored.categoryIDs {
+			for _, ancestor := range models.CategoryPath(m.categories, categoryID) {
+				if postsByCategory[ancestor.ID] == nil {
+					postsByCategory[ancestor.ID] = map[int]bool{}
+				}
+				postsByCategory[ancestor.ID][id] = true
+			}
+		}
+	}
+
+	activity := map[int]models.CategoryActivity{}
+	for categoryID, postIDs := range postsByCategory {
+		stats := models.CategoryActivity{PostCount: len(postIDs)}
+		for postID := range postIDs {
+			if lastActivity[postID].After(stats.LastActivity) {
+				stats.LastActivity = lastActivity[postID]
+			}
+		}
+		activity[categoryID] = stats
+	}
+
+	categories := models.VisibleCategories(models.SortCategoryTree(m.categories), role)
+	return models.BuildCategoryTree(categories, activity), nil
+}
+
+func (m *MemoryStore) GetCategoryPath(ctx context.Context, categoryID int) ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := models.CategoryPath(m.categories, categoryID)
+	if len(path) == 0 {
+		return nil, models.ErrCategoryNotFound
+	}
+	return path, nil
+}
+
+// Tags
+
+// resolveTag renvoie l'ID du tag désigné par un nom normalisé ou par l'un de ses synonymes, 0 s'il n'existe pas
+func (m *MemoryStore) resolveTag(name string) int {
+	if tagID, ok := m.tagSynonyms[name]; ok {
+		return tagID
+	}
+	for id, tag := range m.tags {
+		if tag.Name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+// hasAllTags vérifie qu'un post porte tous les tags demandés
+func (m *MemoryStore) hasAllTags(stored *memoryPost, names []string) bool {
+	for _, name := range names {
+		tagID := m.resolveTag(models.NormalizeTag(name))
+		found := false
+		for _, id := range stored.tagIDs {
+			if id == tagID {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// tagCopy renvoie une copie d'un tag avec son nombre de posts
+func (m *MemoryStore) tagCopy(tagID int) models.Tag {
+	tag := models.Tag{ID: tagID, Name: m.tags[tagID].Name}
+	for _, stored := range m.posts {
+		for _, id := range stored.tagIDs {
+			if id == tagID {
+				tag.PostCount++
+			}
+		}
+	}
+	return tag
+}
+
+// pruneTags supprime les tags qui ne sont plus utilisés et n'ont pas de synonyme, comme SetPostTags
+func (m *MemoryStore) pruneTags() {
+	used := map[int]bool{}
+	for _, stored := range m.posts {
+		for _, id := range stored.tagIDs {
+			used[id] = true
+		}
+	}
+	for _, id := range m.tagSynonyms {
+		used[id] = true
+	}
+	for id := range m.tags {
+		if !used[id] {
+			delete(m.tags, id)
+		}
+	}
+}
+
+func (m *MemoryStore) SetPostTags(ctx context.Context, postID int, names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.posts[postID]
+	if !ok {
+		return models.ErrPostNotFound
+	}
+	m.setPostTags(stored, names)
+	return nil
+}
+
+// setPostTags remplace les tags du post, en créant ceux qui n'existent pas encore
+func (m *MemoryStore) setPostTags(stored *memoryPost, names []string) {
+	var tagIDs []int
+	seen := map[int]bool{}
+	for _, name := range names {
+		tagID := m.resolveTag(name)
+		if tagID == 0 {
+			tagID = m.nextID("tags")
+			m.tags[tagID] = &models.Tag{ID: tagID, Name: name}
+		}
+		if !seen[tagID] {
+			seen[tagID] = true
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+	stored.tagIDs = tagIDs
+	m.pruneTags()
+}
+
+func (m *MemoryStore) GetTag(ctx context.Context, name string) (*models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tagID := m.resolveTag(models.NormalizeTag(name))
+	if tagID == 0 {
+		return nil, models.ErrTagNotFound
+	}
+	tag := m.tagCopy(tagID)
+	return &tag, nil
+}
+
+// sortTagsByCount trie les tags comme les requêtes SQL: les plus utilisés d'abord, puis par nom
+func sortTagsByCount(tags []models.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+}
+
+func (m *MemoryStore) SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix = models.NormalizeTag(prefix)
+	tags := []models.Tag{}
+	if prefix == "" {
+		return tags, nil
+	}
+	matches := map[int]bool{}
+	for id, tag := range m.tags {
+		if strings.HasPrefix(tag.Name, prefix) {
+			matches[id] = true
+		}
+	}
+	for synonym, id := range m.tagSynonyms {
+		if strings.HasPrefix(synonym, prefix) {
+			matches[id] = true
+		}
+	}
+	for id := range matches {
+		tags = append(tags, m.tagCopy(id))
+	}
+	sortTagsByCount(tags)
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func (m *MemoryStore) GetTagCloud(ctx context.Context, limit int) ([]models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tags []models.Tag
+	for id := range m.tags {
+		if tag := m.tagCopy(id); tag.PostCount > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sortTagsByCount(tags)
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	models.SetTagWeights(tags)
+	return tags, nil
+}
+
+func (m *MemoryStore) GetTags(ctx context.Context) ([]models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tags []models.Tag
+	for id := range m.tags {
+		tag := m.tagCopy(id)
+		for synonym, tagID := range m.tagSynonyms {
+			if tagID == id {
+				tag.Synonyms = append(tag.Synonyms, synonym)
+			}
+		}
+		sort.Strings(tag.Synonyms)
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (m *MemoryStore) AddTagSynonym(ctx context.Context, synonym, tagName string, moderatorID int) error {
+	synonym, tagName = models.NormalizeTag(synonym), models.NormalizeTag(tagName)
+	if synonym == "" || tagName == "" {
+		return models.ErrSynonymRequired
+	}
+	if len([]rune(synonym)) > models.MaxTagLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", models.ErrTagTooLong, synonym, models.MaxTagLength)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tagID := m.resolveTag(tagName)
+	if tagID == 0 {
+		return models.ErrTagNotFound
+	}
+	if _, ok := m.tagSynonyms[synonym]; ok {
+		return models.ErrSynonymExists
+	}
+
+	// Si le synonyme est déjà un tag, ses posts et ses synonymes passent au nouveau tag
+	oldTagID := 0
+	for id, tag := range m.tags {
+		if tag.Name == synonym {
+			oldTagID = id
+		}
+	}
+	if oldTagID == tagID {
+		return models.ErrSynonymOfItself
+	}
+	if oldTagID != 0 {
+		for _, stored := range m.posts {
+			stored.tagIDs = replaceID(stored.tagIDs, oldTagID, tagID)
+		}
+		for s, id := range m.tagSynonyms {
+			if id == oldTagID {
+				m.tagSynonyms[s] = tagID
+			}
+		}
+		delete(m.tags, oldTagID)
+	}
+	m.tagSynonyms[synonym] = tagID
+	return nil
+}
+
+func (m *MemoryStore) RemoveTagSynonym(ctx context.Context, synonym string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	synonym = models.NormalizeTag(synonym)
+	if _, ok := m.tagSynonyms[synonym]; !ok {
+		return models.ErrSynonymNotFound
+	}
+	delete(m.tagSynonyms, synonym)
+	return nil
+}
+
+// Notifications
+
+// mentionableRe reconnaît les noms d'utilisateurs qui peuvent être écrits après un @
+var mentionableRe = regexp.MustCompile(`^[A-Za-z0-9_.-]*[A-Za-z0-9_]$`)
+
+// resolveMention renvoie l'ID de l'utilisateur désigné par une mention, 0 s'il n'existe pas
+// Un nom identique passe avant un nom qui ne diffère que par la casse
+func (m *MemoryStore) resolveMention(name string) int {
+	found := 0
+	for id, user := range m.users {
+		if user.Username == name {
+			return id
+		}
+		if strings.EqualFold(user.Username, name) && (found == 0 || id < found) {
+			found = id
+		}
+	}
+	return found
+}
+
+// notifyMentions notifie les utilisateurs mentionnés dans un contenu, une seule fois par contenu
+// Un utilisateur qui ne peut pas lire le post n'est pas notifié
+func (m *MemoryStore) notifyMentions(actorID, postID, commentID int, content string) {
+	for _, name := range markdown.Mentions(content) {
+		userID := m.resolveMention(name)
+		if userID == 0 || userID == actorID || !m.canRead(m.posts[postID], userID) {
+			continue
+		}
+		if !m.hasNotification(userID, models.NotificationMention, postID, commentID) {
+			m.addNotification(userID, actorID, models.NotificationMention, postID, commentID)
+		}
+	}
+}
+
+// hasNotification vérifie si un utilisateur a déjà une notification pour un contenu
+// Un type vide accepte tous les types, comme les requêtes de notifyNewPostTx et notifyNewCommentTx
+func (m *MemoryStore) hasNotification(userID int, notificationType string, postID, commentID int) bool {
+	for _, notification := range m.notifications {
+		if notification.UserID == userID && (notificationType == "" || notification.Type == notificationType) &&
+			notification.PostID == postID && notification.CommentID == commentID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) addNotification(userID, actorID int, notificationType string, postID, commentID int) {
+	id := m.nextID("notifications")
+	m.notifications[id] = &models.Notification{
+		ID:        id,
+		UserID:    userID,
+		ActorID:   actorID,
+		Type:      notificationType,
+		PostID:    postID,
+		CommentID: commentID,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (m *MemoryStore) GetNotifications(ctx context.Context, userID, limit int) ([]*models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notifications := []*models.Notification{}
+	for _, stored := range m.notifications {
+		if stored.UserID != userID {
+			continue
+		}
+		notification := *stored
+		notification.ActorName = m.username(notification.ActorID)
+		if post, ok := m.posts[notification.PostID]; ok {
+			notification.PostTitle = post.post.Title
+		}
+		notifications = append(notifications, &notification)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		if notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+			return notifications[i].ID > notifications[j].ID
+		}
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (m *MemoryStore) MarkNotificationRead(ctx context.Context, notificationID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notification, ok := m.notifications[notificationID]
+	if !ok || notification.UserID != userID {
+		return models.ErrNotificationNotFound
+	}
+	notification.IsRead = true
+	return nil
+}
+
+func (m *MemoryStore) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, notification := range m.notifications {
+		if notification.UserID == userID {
+			notification.IsRead = true
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ResolveMentions(ctx context.Context, names []string) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userIDs := map[string]int{}
+	for _, name := range names {
+		if userID := m.resolveMention(name); userID > 0 {
+			userIDs[name] = userID
+		}
+	}
+	return userIDs, nil
+}
+
+func (m *MemoryStore) SuggestUsers(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "@"))
+	if prefix == "" {
+		return []string{}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := []string{}
+	for _, user := range m.users {
+		if strings.HasPrefix(strings.ToLower(user.Username), prefix) &&
+			mentionableRe.MatchString(user.Username) && len(user.Username) <= markdown.MaxMentionLength {
+			names = append(names, user.Username)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return names, nil
+}
+
+func (m *MemoryStore) GetNotifiedUsers(ctx context.Context, postID, commentID int) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[int]bool{}
+	userIDs := []int{}
+	for _, notification := range m.notifications {
+		if notification.PostID == postID && notification.CommentID == commentID && !seen[notification.UserID] {
+			seen[notification.UserID] = true
+			userIDs = append(userIDs, notification.UserID)
+		}
+	}
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
+
+// Abonnements
+
+// subscribeToPost abonne l'auteur d'un post ou d'un commentaire au post, sans modifier un abonnement existant
+func (m *MemoryStore) subscribeToPost(userID, postID int) {
+	key := subscriptionKey{userID, models.SubscriptionPost, postID}
+	if _, ok := m.subscriptions[key]; !ok {
+		m.subscriptions[key] = &models.Subscription{
+			ID:         m.nextID("subscriptions"),
+			UserID:     userID,
+			TargetType: models.SubscriptionPost,
+			TargetID:   postID,
+			CreatedAt:  time.Now(),
+		}
+	}
+}
+
+// notifyNewPost notifie les abonnés de l'auteur et des catégories d'un nouveau post
+func (m *MemoryStore) notifyNewPost(authorID, postID int) {
+	// Une catégorie suivie inclut ses sous-catégories
+	categoryIDs := map[int]bool{}
+	for _, categoryID := range m.posts[postID].categoryIDs {
+		for _, category := range models.CategoryPath(m.categories, categoryID) {
+			categoryIDs[category.ID] = true
+		}
+	}
+
+	for key, subscription := range m.subscriptions {
+		if subscription.Muted || key.userID == authorID {
+			continue
+		}
+		matches := (key.targetType == models.SubscriptionUser && key.targetID == authorID) ||
+			(key.targetType == models.SubscriptionCategory && categoryIDs[key.targetID])
+		if matches && m.canRead(m.posts[postID], key.userID) && !m.hasNotification(key.userID, "", postID, 0) {
+			m.addNotification(key.userID, authorID, models.NotificationPost, postID, 0)
+		}
+	}
+}
+
+// notifyNewComment notifie les abonnés d'un post qu'un commentaire y a été publié
+func (m *MemoryStore) notifyNewComment(authorID, postID, commentID int) {
+	for key, subscription := range m.subscriptions {
+		if key.targetType != models.SubscriptionPost || key.targetID != postID || subscription.Muted || key.userID == authorID {
+			continue
+		}
+		if m.canRead(m.posts[postID], key.userID) && !m.hasNotification(key.userID, "", postID, commentID) {
+			m.addNotification(key.userID, authorID, models.NotificationComment, postID, commentID)
+		}
+	}
+}
+
+// subscriptionTargetName renvoie le nom d'une cible, comme la colonne target_name de GetSubscriptions
+func (m *MemoryStore) subscriptionTargetName(targetType string, targetID int) (string, bool) {
+	switch targetType {
+	case models.SubscriptionPost:
+		if stored, ok := m.posts[targetID]; ok {
+			return stored.post.Title, true
+		}
+	case models.SubscriptionCategory:
+		if index := m.categoryIndex(targetID); index >= 0 {
+			return m.categories[index].Name, true
+		}
+	case models.SubscriptionUser:
+		if user, ok := m.users[targetID]; ok {
+			return user.Username, true
+		}
+	}
+	return "", false
+}
+
+// canFollow indique si l'utilisateur peut lire le post ou la catégorie qu'il veut suivre
+func (m *MemoryStore) canFollow(userID int, targetType string, targetID int) bool {
+	switch targetType {
+	case models.SubscriptionPost:
+		return m.canRead(m.posts[targetID], userID)
+	case models.SubscriptionCategory:
+		for _, id := range models.HiddenCategoryIDs(m.categories, m.userRole(userID)) {
+			if id == targetID {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *MemoryStore) setSubscription(userID int, targetType string, targetID int, muted bool) error {
+	if !models.ValidSubscriptionTarget(targetType) {
+		return errors.New("invalid subscription target")
+	}
+	if targetType == models.SubscriptionUser && targetID == userID {
+		return errors.New("cannot follow yourself")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subscriptionTargetName(targetType, targetID); !ok || !m.canFollow(userID, targetType, targetID) {
+		return errors.New("subscription target not found")
+	}
+	key := subscriptionKey{userID, targetType, targetID}
+	if subscription, ok := m.subscriptions[key]; ok {
+		subscription.Muted = muted
+		return nil
+	}
+	m.subscriptions[key] = &models.Subscription{
+		ID:         m.nextID("subscriptions"),
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Muted:      muted,
+		CreatedAt:  time.Now(),
+	}
+	return nil
+}
+
+func (m *MemoryStore) Follow(ctx context.Context, userID int, targetType string, targetID int) error {
+	return m.setSubscription(userID, targetType, targetID, false)
+}
+
+func (m *MemoryStore) SetSubscriptionMuted(ctx context.Context, userID int, targetType string, targetID int, muted bool) error {
+	return m.setSubscription(userID, targetType, targetID, muted)
+}
+
+func (m *MemoryStore) Unfollow(ctx context.Context, userID int, targetType string, targetID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := subscriptionKey{userID, targetType, targetID}
+	if _, ok := m.subscriptions[key]; !ok {
+		return errors.New("subscription not found")
+	}
+	delete(m.subscriptions, key)
+	return nil
+}
+
+// subscriptionCopy renvoie une copie de l'abonnement avec le nom de sa cible
+func (m *MemoryStore) subscriptionCopy(stored *models.Subscription) *models.Subscription {
+	subscription := *stored
+	subscription.TargetName, _ = m.subscriptionTargetName(subscription.TargetType, subscription.TargetID)
+	return &subscription
+}
+
+func (m *MemoryStore) GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.subscriptions[subscriptionKey{userID, targetType, targetID}]
+	if !ok {
+		return nil, errors.New("subscription not found")
+	}
+	return m.subscriptionCopy(stored), nil
+}
+
+func (m *MemoryStore) GetSubscriptions(ctx context.Context, userID int) ([]*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions := []*models.Subscription{}
+	for key, stored := range m.subscriptions {
+		if key.userID == userID {
+			subscriptions = append(subscriptions, m.subscriptionCopy(stored))
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.TargetType != b.TargetType {
+			return a.TargetType < b.TargetType
+		}
+		if nameA, nameB := strings.ToLower(a.TargetName), strings.ToLower(b.TargetName); nameA != nameB {
+			return nameA < nameB
+		}
+		return a.ID < b.ID
+	})
+	return subscriptions, nil
+}
+
+// followedCategories renvoie les catégories suivies par un utilisateur, sous-catégories comprises
+func (m *MemoryStore) followedCategories(userID int) map[int]bool {
+	categoryIDs := map[int]bool{}
+	for key, subscription := range m.subscriptions {
+		if key.userID == userID && key.targetType == models.SubscriptionCategory && !subscription.Muted {
+			for _, id := range models.CategoryDescendants(m.categories, key.targetID) {
+				categoryIDs[id] = true
+			}
+		}
+	}
+	return categoryIDs
+}
+
+// mutedPost indique si un utilisateur a rendu un post muet
+func (m *MemoryStore) mutedPost(userID, postID int) bool {
+	subscription, ok := m.subscriptions[subscriptionKey{userID, models.SubscriptionPost, postID}]
+	return ok && subscription.Muted
+}
+
+func (m *MemoryStore) GetFollowingFeed(ctx context.Context, userID, page, perPage int) ([]*models.Post, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	followedCategories := m.followedCategories(userID)
+
+	var posts []*models.Post
+	activity := map[int]time.Time{}
+	for postID, stored := range m.posts {
+		subscription, subscribed := m.subscriptions[subscriptionKey{userID, models.SubscriptionPost, postID}]
+		if subscribed && subscription.Muted {
+			continue
+		}
+		followed := subscribed
+		if author, ok := m.subscriptions[subscriptionKey{userID, models.SubscriptionUser, stored.post.UserID}]; ok && !author.Muted {
+			followed = true
+		}
+		for _, categoryID := range stored.categoryIDs {
+			if followedCategories[categoryID] {
+				followed = true
+			}
+		}
+		if !followed || !m.canRead(stored, userID) {
+			continue
+		}
+
+		activity[postID] = stored.post.CreatedAt
+		for _, comment := range m.comments {
+			if comment.PostID == postID && comment.CreatedAt.After(activity[postID]) {
+				activity[postID] = comment.CreatedAt
+			}
+		}
+		posts = append(posts, m.postCopy(stored, userID))
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if a, b := activity[posts[i].ID], activity[posts[j].ID]; !a.Equal(b) {
+			return a.After(b)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	return paginate(posts, page, perPage), len(posts), nil
+}
+
+// Résumés par email
+
+func (m *MemoryStore) SetDigestFrequency(ctx context.Context, userID int, frequency string) error {
+	if !models.ValidDigestFrequency(frequency) {
+		return errors.New("invalid digest frequency")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	if _, sent := m.digestSentAt[userID]; !sent || user.DigestFrequency == models.DigestNone {
+		m.digestSentAt[userID] = time.Now()
+	}
+	user.DigestFrequency = frequency
+	return nil
+}
+
+func (m *MemoryStore) GetDueDigests(ctx context.Context, now time.Time) ([]*models.DigestRecipient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recipients := []*models.DigestRecipient{}
+	for _, user := range m.users {
+		period := models.DigestPeriod(user.DigestFrequency)
+		if period == 0 {
+			continue
+		}
+		lastDigest, sent := m.digestSentAt[user.ID]
+		if sent && now.Sub(lastDigest) < period {
+			continue
+		}
+		recipient := &models.DigestRecipient{
+			UserID:    user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Frequency: user.DigestFrequency,
+			Since:     now.Add(-period),
+		}
+		if sent {
+			recipient.Since = lastDigest
+		}
+		recipients = append(recipients, recipient)
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].UserID < recipients[j].UserID })
+	return recipients, nil
+}
+
+// sortDigestItems trie les éléments d'une rubrique du plus ancien au plus récent et garde les premiers
+func sortDigestItems(items []*models.DigestItem) []*models.DigestItem {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		if items[i].PostID != items[j].PostID {
+			return items[i].PostID < items[j].PostID
+		}
+		return items[i].CommentID < items[j].CommentID
+	})
+	if len(items) > models.DigestMaxItems {
+		items = items[:models.DigestMaxItems]
+	}
+	return items
+}
+
+func (m *MemoryStore) GetDigest(ctx context.Context, userID int, since, until time.Time) (*models.Digest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inPeriod := func(t time.Time) bool { return t.After(since) && !t.After(until) }
+	digest := &models.Digest{Posts: []*models.DigestItem{}, Replies: []*models.DigestItem{}, Mentions: []*models.DigestItem{}}
+
+	followedCategories := m.followedCategories(userID)
+	for postID, stored := range m.posts {
+		if stored.post.UserID == userID || !inPeriod(stored.post.CreatedAt) || m.mutedPost(userID, postID) || !m.canRead(stored, userID) {
+			continue
+		}
+		for _, categoryID := range stored.categoryIDs {
+			if followedCategories[categoryID] {
+				digest.Posts = append(digest.Posts, &models.DigestItem{
+					PostID:     postID,
+					PostTitle:  stored.post.Title,
+					AuthorName: m.username(stored.post.UserID),
+					Excerpt:    models.DigestExcerpt(stored.post.Content),
+					CreatedAt:  stored.post.CreatedAt,
+				})
+				break
+			}
+		}
+	}
+
+	for _, comment := range m.comments {
+		stored, ok := m.posts[comment.PostID]
+		if !ok || stored.post.UserID != userID || comment.UserID == userID || !inPeriod(comment.CreatedAt) ||
+			m.mutedPost(userID, comment.PostID) || m.hasNotification(userID, models.NotificationMention, comment.PostID, comment.ID) ||
+			!m.canRead(stored, userID) {
+			continue
+		}
+		digest.Replies = append(digest.Replies, &models.DigestItem{
+			PostID:     comment.PostID,
+			PostTitle:  stored.post.Title,
+			CommentID:  comment.ID,
+			AuthorName: m.username(comment.UserID),
+			Excerpt:    models.DigestExcerpt(comment.Content),
+			CreatedAt:  comment.CreatedAt,
+		})
+	}
+
+	for _, notification := range m.notifications {
+		stored, ok := m.posts[notification.PostID]
+		if notification.UserID != userID || notification.Type != models.NotificationMention || !ok || !inPeriod(notification.CreatedAt) ||
+			!m.canRead(stored, userID) {
+			continue
+		}
+		content := stored.post.Content
+		if comment, ok := m.comments[notification.CommentID]; ok {
+			content = comment.Content
+		}
+		digest.Mentions = append(digest.Mentions, &models.DigestItem{
+			PostID:     notification.PostID,
+			PostTitle:  stored.post.Title,
+			CommentID:  notification.CommentID,
+			AuthorName: m.username(notification.ActorID),
+			Excerpt:    models.DigestExcerpt(content),
+			CreatedAt:  notification.CreatedAt,
+		})
+	}
+
+	digest.Posts = sortDigestItems(digest.Posts)
+	digest.Replies = sortDigestItems(digest.Replies)
+	digest.Mentions = sortDigestItems(digest.Mentions)
+	return digest, nil
+}
+
+func (m *MemoryStore) MarkDigestSent(ctx context.Context, userID int, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.digestSentAt[userID] = until
+	return nil
+}
+
+// Messages privés
+
+// activeMember renvoie la participation d'un utilisateur à une conversation qu'il n'a pas quittée
+func (m *MemoryStore) activeMember(conversationID, userID int) (*memoryConversation, *memoryMember, bool) {
+	conversation, ok := m.conversations[conversationID]
+	if !ok {
+		return nil, nil, false
+	}
+	member, ok := conversation.members[userID]
+	if !ok || member.left {
+		return nil, nil, false
+	}
+	return conversation, member, true
+}
+
+// otherMembers renvoie les IDs des membres présents d'une conversation, sauf exceptID
+func (m *MemoryStore) otherMembers(conversation *memoryConversation, exceptID int) []int {
+	var userIDs []int
+	for userID, member := range conversation.members {
+		if userID != exceptID && !member.left {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Ints(userIDs)
+	return userIDs
+}
+
+// checkBlocks refuse un échange entre un utilisateur et d'autres si l'un d'eux bloque l'autre
+func (m *MemoryStore) checkBlocks(userID int, otherIDs []int) error {
+	for _, otherID := range otherIDs {
+		_, blocked := m.blocks[blockKey{userID, otherID}]
+		_, blockedBy := m.blocks[blockKey{otherID, userID}]
+		if blocked || blockedBy {
+			return models.ErrUserBlocked
+		}
+	}
+	return nil
+}
+
+// unreadMessages compte les messages des autres membres qu'un utilisateur n'a pas lus
+func (m *MemoryStore) unreadMessages(conversation *memoryConversation, userID int) int {
+	member, ok := conversation.members[userID]
+	if !ok || member.left {
+		return 0
+	}
+	unread := 0
+	for _, message := range m.messages {
+		if message.ConversationID == conversation.id && message.ID > member.lastReadMessageID && message.UserID != userID {
+			unread++
+		}
+	}
+	return unread
+}
+
+// addMessage ajoute un message à une conversation; son auteur l'a lu
+func (m *MemoryStore) addMessage(conversation *memoryConversation, senderID int, content string) int {
+	now := time.Now()
+	id := m.nextID("messages")
+	m.messages[id] = &models.Message{
+		ID:             id,
+		ConversationID: conversation.id,
+		UserID:         senderID,
+		Content:        content,
+		CreatedAt:      now,
+	}
+	conversation.lastMessageAt = now
+	conversation.members[senderID].lastReadMessageID = id
+	return id
+}
+
+// conversationCopy renvoie une conversation vue par un utilisateur
+func (m *MemoryStore) conversationCopy(conversation *memoryConversation, userID int) *models.Conversation {
+	c := &models.Conversation{
+		ID:            conversation.id,
+		LastMessageAt: conversation.lastMessageAt,
+		UnreadCount:   m.unreadMessages(conversation, userID),
+		CreatedAt:     conversation.createdAt,
+	}
+	lastID := 0
+	for _, message := range m.messages {
+		if message.ConversationID == conversation.id && message.ID > lastID {
+			lastID = message.ID
+			c.LastMessage = models.DigestExcerpt(message.Content)
+		}
+	}
+	var names []string
+	for memberID, member := range conversation.members {
+		if member.left {
+			continue
+		}
+		c.Members = append(c.Members, models.ConversationMember{UserID: memberID, Username: m.username(memberID)})
+	}
+	sort.Slice(c.Members, func(i, j int) bool {
+		return strings.ToLower(c.Members[i].Username) < strings.ToLower(c.Members[j].Username)
+	})
+	for _, member := range c.Members {
+		if member.UserID != userID {
+			names = append(names, member.Username)
+		}
+	}
+	c.Title = strings.Join(names, ", ")
+	return c
+}
+
+func (m *MemoryStore) CreateConversation(ctx context.Context, creatorID int, memberIDs []int, content string) (int, error) {
+	if err := models.ValidMessageContent(content); err != nil {
+		return 0, err
+	}
+
+	seen := map[int]bool{creatorID: true}
+	var others []int
+	for _, memberID := range memberIDs {
+		if !seen[memberID] {
+			seen[memberID] = true
+			others = append(others, memberID)
+		}
+	}
+	if len(others) == 0 {
+		return 0, models.ErrConversationTooSmall
+	}
+	if len(others)+1 > models.MaxConversationMembers {
+		return 0, models.ErrConversationTooLarge
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, memberID := range others {
+		if _, ok := m.users[memberID]; !ok {
+			return 0, models.ErrUserNotFound
+		}
+	}
+	if err := m.checkBlocks(creatorID, others); err != nil {
+		return 0, err
+	}
+
+	// Une conversation à deux déjà ouverte entre les mêmes personnes est reprise
+	var conversation *memoryConversation
+	if len(others) == 1 {
+		for _, existing := range m.conversations {
+			creator, hasCreator := existing.members[creatorID]
+			other, hasOther := existing.members[others[0]]
+			if len(existing.members) == 2 && hasCreator && hasOther && !creator.left && !other.left &&
+				(conversation == nil || existing.id > conversation.id) {
+				conversation = existing
+			}
+		}
+	}
+
+	if conversation == nil {
+		now := time.Now()
+		conversation = &memoryConversation{
+			id:            m.nextID("conversations"),
+			createdAt:     now,
+			lastMessageAt: now,
+			members:       map[int]*memoryMember{creatorID: {}},
+		}
+		for _, memberID := range others {
+			conversation.members[memberID] = &memoryMember{}
+		}
+		m.conversations[conversation.id] = conversation
+	}
+
+	m.addMessage(conversation, creatorID, content)
+	return conversation.id, nil
+}
+
+func (m *MemoryStore) SendMessage(ctx context.Context, conversationID, senderID int, content string) (int, error) {
+	if err := models.ValidMessageContent(content); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conversation, _, ok := m.activeMember(conversationID, senderID)
+	if !ok {
+		return 0, models.ErrConversationNotFound
+	}
+	others := m.otherMembers(conversation, senderID)
+	if len(others) == 0 {
+		return 0, models.ErrConversationAbandoned
+	}
+	if err := m.checkBlocks(senderID, others); err != nil {
+		return 0, err
+	}
+	return m.addMessage(conversation, senderID, content), nil
+}
+
+func (m *MemoryStore) GetConversations(ctx context.Context, userID int) ([]*models.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var conversations []*models.Conversation
+	for _, conversation := range m.conversations {
+		if _, _, ok := m.activeMember(conversation.id, userID); ok {
+			conversations = append(conversations, m.conversationCopy(conversation, userID))
+		}
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		if !conversations[i].LastMessageAt.Equal(conversations[j].LastMessageAt) {
+			return conversations[i].LastMessageAt.After(conversations[j].LastMessageAt)
+		}
+		return conversations[i].ID > conversations[j].ID
+	})
+	return conversations, nil
+}
+
+func (m *MemoryStore) GetConversation(ctx context.Context, conversationID, userID int) (*models.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conversation, _, ok := m.activeMember(conversationID, userID)
+	if !ok {
+		return nil, models.ErrConversationNotFound
+	}
+	return m.conversationCopy(conversation, userID), nil
+}
+
+// messageCopy renvoie une copie d'un message avec le nom de son auteur
+func (m *MemoryStore) messageCopy(message *models.Message) *models.Message {
+	copied := *message
+	copied.Username = m.username(message.UserID)
+	return &copied
+}
+
+func (m *MemoryStore) GetMessages(ctx context.Context, conversationID, userID, limit int) ([]*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, _, ok := m.activeMember(conversationID, userID); !ok {
+		return nil, models.ErrConversationNotFound
+	}
+	var messages []*models.Message
+	for _, message := range m.messages {
+		if message.ConversationID == conversationID {
+			messages = append(messages, m.messageCopy(message))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
+
+func (m *MemoryStore) GetMessage(ctx context.Context, messageID, userID int) (*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	message, ok := m.messages[messageID]
+	if !ok {
+		return nil, models.ErrMessageNotFound
+	}
+	if _, _, ok := m.activeMember(message.ConversationID, userID); !ok {
+		return nil, models.ErrMessageNotFound
+	}
+	return m.messageCopy(message), nil
+}
+
+func (m *MemoryStore) MarkConversationRead(ctx context.Context, conversationID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, member, ok := m.activeMember(conversationID, userID)
+	if !ok {
+		return models.ErrConversationNotFound
+	}
+	member.lastReadMessageID = 0
+	for _, message := range m.messages {
+		if message.ConversationID == conversationID && message.ID > member.lastReadMessageID {
+			member.lastReadMessageID = message.ID
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) LeaveConversation(ctx context.Context, conversationID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conversation, member, ok := m.activeMember(conversationID, userID)
+	if !ok {
+		return models.ErrConversationNotFound
+	}
+	member.left = true
+	if len(m.otherMembers(conversation, userID)) == 0 {
+		m.deleteConversation(conversationID)
+	}
+	return nil
+}
+
+// deleteConversation supprime une conversation et ses messages
+func (m *MemoryStore) deleteConversation(conversationID int) {
+	delete(m.conversations, conversationID)
+	for id, message := range m.messages {
+		if message.ConversationID == conversationID {
+			delete(m.messages, id)
+		}
+	}
+}
+
+func (m *MemoryStore) BlockUser(ctx context.Context, userID, blockedID int) error {
+	if userID == blockedID {
+		return models.ErrCannotBlockYourself
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[blockedID]; !ok {
+		return models.ErrUserNotFound
+	}
+	if _, ok := m.blocks[blockKey{userID, blockedID}]; !ok {
+		m.blocks[blockKey{userID, blockedID}] = time.Now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnblockUser(ctx context.Context, userID, blockedID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.blocks[blockKey{userID, blockedID}]; !ok {
+		return models.ErrBlockNotFound
+	}
+	delete(m.blocks, blockKey{userID, blockedID})
+	return nil
+}
+
+func (m *MemoryStore) GetBlockedUsers(ctx context.Context, userID int) ([]*models.BlockedUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var blocked []*models.BlockedUser
+	for key, createdAt := range m.blocks {
+		if key.userID == userID {
+			blocked = append(blocked, &models.BlockedUser{UserID: key.blockedID, Username: m.username(key.blockedID), CreatedAt: createdAt})
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return strings.ToLower(blocked[i].Username) < strings.ToLower(blocked[j].Username)
+	})
+	return blocked, nil
+}
+
+// messageRetentionSetting est la clé de la durée de conservation des messages, comme dans la table settings
+const messageRetentionSetting = "message_retention_days"
+
+func (m *MemoryStore) GetMessageRetention(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.settings[messageRetentionSetting]
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (m *MemoryStore) SetMessageRetention(ctx context.Context, days int) error {
+	if days < 0 || days > models.MaxMessageRetentionDays {
+		return errors.New("invalid retention period")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.settings[messageRetentionSetting] = strconv.Itoa(days)
+	return nil
+}
+
+func (m *MemoryStore) PurgeMessages(ctx context.Context, now time.Time) (int, error) {
+	days, err := m.GetMessageRetention(ctx)
+	if err != nil || days == 0 {
+		return 0, err
+	}
+	cutoff := now.AddDate(0, 0, -days)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reported := map[int]bool{}
+	for _, report := range m.reports {
+		if report.Type == "message" && report.Status == "pending" {
+			reported[report.ContentID] = true
+		}
+	}
+
+	purged := 0
+	for id, message := range m.messages {
+		if message.CreatedAt.Before(cutoff) && !reported[id] {
+			delete(m.messages, id)
+			purged++
+		}
+	}
+
+	for id, conversation := range m.conversations {
+		if !conversation.createdAt.Before(cutoff) {
+			continue
+		}
+		empty := true
+		for _, message := range m.messages {
+			if message.ConversationID == id {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			delete(m.conversations, id)
+		}
+	}
+	return purged, nil
+}
+
+// Signets
+
+func (m *MemoryStore) SaveBookmark(ctx context.Context, userID int, targetType string, targetID, folderID int, note string) error {
+	if !models.ValidBookmarkTarget(targetType) {
+		return models.ErrInvalidBookmarkTarget
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > models.MaxBookmarkNoteLength {
+		return models.ErrBookmarkNoteTooLong
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	postID := targetID
+	if targetType == models.BookmarkComment {
+		comment, ok := m.comments[targetID]
+		if !ok {
+			return models.ErrBookmarkTargetNotFound
+		}
+		postID = comment.PostID
+	}
+	if post, ok := m.posts[postID]; !ok || !m.canRead(post, userID) {
+		return models.ErrBookmarkTargetNotFound
+	}
+	if folderID != 0 {
+		folder, ok := m.bookmarkFolders[folderID]
+		if !ok || folder.UserID != userID {
+			return models.ErrFolderNotFound
+		}
+	}
+
+	key := bookmarkKey{userID, targetType, targetID}
+	bookmark, ok := m.bookmarks[key]
+	if !ok {
+		bookmark = &models.Bookmark{ID: m.nextID("bookmarks"), UserID: userID, TargetType: targetType, TargetID: targetID, CreatedAt: time.Now()}
+		m.bookmarks[key] = bookmark
+	}
+	bookmark.FolderID = folderID
+	bookmark.Note = note
+	return nil
+}
+
+func (m *MemoryStore) RemoveBookmark(ctx context.Context, userID int, targetType string, targetID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := bookmarkKey{userID, targetType, targetID}
+	if _, ok := m.bookmarks[key]; !ok {
+		return models.ErrBookmarkNotFound
+	}
+	delete(m.bookmarks, key)
+	return nil
+}
+
+func (m *MemoryStore) GetBookmarks(ctx context.Context, userID, folderID int) ([]*models.Bookmark, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bookmarks := []*models.Bookmark{}
+	for key, stored := range m.bookmarks {
+		if key.userID != userID || (folderID != 0 && stored.FolderID != folderID) {
+			continue
+		}
+		bookmark := *stored
+		if folder, ok := m.bookmarkFolders[bookmark.FolderID]; ok {
+			bookmark.FolderName = folder.Name
+		}
+		content, authorID := "", 0
+		if key.targetType == models.BookmarkComment {
+			comment, ok := m.comments[key.targetID]
+			if !ok {
+				continue
+			}
+			bookmark.PostID, content, authorID = comment.PostID, comment.Content, comment.UserID
+		} else {
+			bookmark.PostID = key.targetID
+		}
+		post, ok := m.posts[bookmark.PostID]
+		if !ok || !m.canRead(post, userID) {
+			continue
+		}
+		if key.targetType == models.BookmarkPost {
+			content, authorID = post.post.Content, post.post.UserID
+		}
+		bookmark.PostTitle = post.post.Title
+		bookmark.AuthorName = m.username(authorID)
+		bookmark.Excerpt = models.DigestExcerpt(content)
+		bookmarks = append(bookmarks, &bookmark)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		if bookmarks[i].CreatedAt.Equal(bookmarks[j].CreatedAt) {
+			return bookmarks[i].ID > bookmarks[j].ID
+		}
+		return bookmarks[i].CreatedAt.After(bookmarks[j].CreatedAt)
+	})
+	return bookmarks, nil
+}
+
+func (m *MemoryStore) GetBookmarkFolders(ctx context.Context, userID int) ([]*models.BookmarkFolder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folders := []*models.BookmarkFolder{}
+	for _, stored := range m.bookmarkFolders {
+		if stored.UserID != userID {
+			continue
+		}
+		folder := *stored
+		folder.Count = 0
+		for _, bookmark := range m.bookmarks {
+			if bookmark.FolderID == folder.ID {
+				folder.Count++
+			}
+		}
+		folders = append(folders, &folder)
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return strings.ToLower(folders[i].Name) < strings.ToLower(folders[j].Name)
+	})
+	return folders, nil
+}
+
+func (m *MemoryStore) CreateBookmarkFolder(ctx context.Context, userID int, name string) (int, error) {
+	name, err := models.NormalizeBookmarkFolderName(name)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, folder := range m.bookmarkFolders {
+		if folder.UserID == userID && folder.Name == name {
+			return 0, models.ErrFolderExists
+		}
+	}
+	id := m.nextID("bookmark_folders")
+	m.bookmarkFolders[id] = &models.BookmarkFolder{ID: id, UserID: userID, Name: name, CreatedAt: time.Now()}
+	return id, nil
+}
+
+func (m *MemoryStore) DeleteBookmarkFolder(ctx context.Context, userID, folderID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folder, ok := m.bookmarkFolders[folderID]
+	if !ok || folder.UserID != userID {
+		return models.ErrFolderNotFound
+	}
+	delete(m.bookmarkFolders, folderID)
+	for _, bookmark := range m.bookmarks {
+		if bookmark.FolderID == folderID {
+			bookmark.FolderID = 0
+		}
+	}
+	return nil
+}
+
+// Sondages
+
+// insertPoll enregistre un sondage pour un post publié (postID) ou en attente (pendingID)
+func (m *MemoryStore) insertPoll(postID, pendingID int, poll *models.Poll) int {
+	stored := &memoryPoll{poll: *poll, pendingID: pendingID, votes: map[pollVoteKey]time.Time{}}
+	stored.poll.ID = m.nextID("polls")
+	stored.poll.PostID = postID
+	stored.poll.CreatedAt = time.Now()
+	stored.poll.Options = nil
+	for _, option := range poll.Options {
+		stored.poll.Options = append(stored.poll.Options, &models.PollOption{ID: m.nextID("poll_options"), Label: option.Label})
+	}
+	m.polls[stored.poll.ID] = stored
+	return stored.poll.ID
+}
+
+// pollCopy renvoie une copie du sondage avec ses votes, préparée comme le fait models.GetPoll
+func (m *MemoryStore) pollCopy(stored *memoryPoll, currentUserID int) *models.Poll {
+	poll := stored.poll
+	poll.Options = nil
+	byID := map[int]*models.PollOption{}
+	for _, option := range stored.poll.Options {
+		copied := &models.PollOption{ID: option.ID, Label: option.Label}
+		poll.Options = append(poll.Options, copied)
+		byID[option.ID] = copied
+	}
+
+	// Les votes dans leur ordre, pour la liste des votants d'un sondage public
+	keys := make([]pollVoteKey, 0, len(stored.votes))
+	for key := range stored.votes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ti, tj := stored.votes[keys[i]], stored.votes[keys[j]]
+		if ti.Equal(tj) {
+			return m.username(keys[i].userID) < m.username(keys[j].userID)
+		}
+		return ti.Before(tj)
+	})
+	voters := map[int]bool{}
+	for _, key := range keys {
+		option := byID[key.optionID]
+		option.Votes++
+		option.Chosen = option.Chosen || (currentUserID > 0 && key.userID == currentUserID)
+		if !poll.Anonymous {
+			option.Voters = append(option.Voters, m.username(key.userID))
+		}
+		voters[key.userID] = true
+	}
+	poll.Voters = len(voters)
+	poll.Prepare(time.Now(), currentUserID)
+	return &poll
+}
+
+// postPoll renvoie le sondage stocké d'un post publié
+func (m *MemoryStore) postPoll(postID int) *memoryPoll {
+	for _, stored := range m.polls {
+		if stored.poll.PostID == postID && stored.pendingID == 0 {
+			return stored
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetPoll(ctx context.Context, postID, currentUserID int) (*models.Poll, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.postPoll(postID)
+	if stored == nil {
+		return nil, models.ErrPollNotFound
+	}
+	return m.pollCopy(stored, currentUserID), nil
+}
+
+func (m *MemoryStore) UpdatePoll(ctx context.Context, postID int, poll *models.Poll) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.postPoll(postID)
+	if stored != nil {
+		if len(stored.votes) > 0 {
+			return models.ErrPollHasVotes
+		}
+		delete(m.polls, stored.poll.ID)
+	}
+	if poll != nil {
+		m.insertPoll(postID, 0, poll)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Vote(ctx context.Context, pollID, userID int, optionIDs []int) error {
+	seen := map[int]bool{}
+	var unique []int
+	for _, id := range optionIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return models.ErrNoOptionSelected
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.polls[pollID]
+	if !ok || stored.pendingID != 0 {
+		return models.ErrPollNotFound
+	}
+	if post, ok := m.posts[stored.poll.PostID]; !ok || !m.canRead(post, userID) {
+		return models.ErrPollNotFound
+	}
+	poll := stored.poll
+	if poll.ClosesAt.Valid && !time.Now().Before(poll.ClosesAt.Time) {
+		return models.ErrPollClosed
+	}
+	if !poll.MultipleChoice && len(unique) > 1 {
+		return models.ErrSingleChoicePoll
+	}
+	for _, id := range unique {
+		found := false
+		for _, option := range poll.Options {
+			found = found || option.ID == id
+		}
+		if !found {
+			return models.ErrInvalidPollOption
+		}
+	}
+
+	var previous []pollVoteKey
+	for key := range stored.votes {
+		if key.userID == userID {
+			previous = append(previous, key)
+		}
+	}
+	if len(previous) > 0 && !poll.AllowVoteChange {
+		return models.ErrVoteCannotChange
+	}
+	for _, key := range previous {
+		delete(stored.votes, key)
+	}
+	now := time.Now()
+	for _, id := range unique {
+		stored.votes[pollVoteKey{id, userID}] = now
+	}
+	return nil
+}
+
+// Réactions
+
+// reactionByName renvoie la réaction configurée sous cet identifiant
+func (m *MemoryStore) reactionByName(name string) (models.Reaction, bool) {
+	for _, reaction := range m.reactions {
+		if reaction.Name == name {
+			return reaction, true
+		}
+	}
+	return models.Reaction{}, false
+}
+
+// react ajoute la réaction de l'utilisateur à un contenu, ou la retire si elle y est déjà
+// Un type vide retire toutes les réactions de l'utilisateur sur ce contenu
+func (m *MemoryStore) react(reactions map[reactionKey]time.Time, itemID, userID int, reactionType string) error {
+	if reactionType == "" {
+		for key := range reactions {
+			if key.userID == userID && key.itemID == itemID {
+				delete(reactions, key)
+			}
+		}
+		return nil
+	}
+
+	reaction, ok := m.reactionByName(reactionType)
+	if !ok {
+		return models.ErrInvalidReactionType
+	}
+	key := reactionKey{userID, itemID, reactionType}
+	if _, exists := reactions[key]; exists {
+		delete(reactions, key)
+		return nil
+	}
+	if reaction.Archived {
+		return models.ErrReactionArchived
+	}
+	// Une réaction qui compte dans le score remplace celles de score opposé
+	if reaction.Score != 0 {
+		for other := range reactions {
+			if other.userID != userID || other.itemID != itemID {
+				continue
+			}
+			if existing, _ := m.reactionByName(other.reactionType); existing.Score == -reaction.Score {
+				delete(reactions, other)
+			}
+		}
+	}
+	reactions[key] = time.Now()
+	return nil
+}
+
+// scoreCounts compte les réactions d'un contenu qui ajoutent et qui retirent un point au score
+func (m *MemoryStore) scoreCounts(reactions map[reactionKey]time.Time, itemID int) (likes, dislikes int) {
+	for key := range reactions {
+		if key.itemID != itemID {
+			continue
+		}
+		reaction, _ := m.reactionByName(key.reactionType)
+		switch {
+		case reaction.Score > 0:
+			likes++
+		case reaction.Score < 0:
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+// reactionCounts compte chaque réaction d'un contenu comme le fait loadReactionCounts
+func (m *MemoryStore) reactionCounts(reactions map[reactionKey]time.Time, itemID, currentUserID int) []models.ReactionCount {
+	counts := make([]models.ReactionCount, 0, len(m.reactions))
+	for _, reaction := range m.sortedReactions() {
+		count := models.ReactionCount{Reaction: reaction}
+		for key := range reactions {
+			if key.itemID == itemID && key.reactionType == reaction.Name {
+				count.Count++
+				count.Mine = count.Mine || (currentUserID > 0 && key.userID == currentUserID)
+			}
+		}
+		if reaction.Archived && count.Count == 0 {
+			continue
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// sortedReactions renvoie une copie des réactions dans l'ordre d'affichage
+func (m *MemoryStore) sortedReactions() []models.Reaction {
+	reactions := append([]models.Reaction(nil), m.reactions...)
+	sort.SliceStable(reactions, func(i, j int) bool {
+		if reactions[i].Position != reactions[j].Position {
+			return reactions[i].Position < reactions[j].Position
+		}
+		return reactions[i].ID < reactions[j].ID
+	})
+	return reactions
+}
+
+func (m *MemoryStore) GetReactions(ctx context.Context) ([]models.Reaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sortedReactions(), nil
+}
+
+func (m *MemoryStore) CreateReaction(ctx context.Context, reaction models.Reaction) (int, error) {
+	if err := models.ValidateReaction(&reaction); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.reactionByName(reaction.Name); exists {
+		return 0, models.ErrReactionExists
+	}
+	reaction.ID = m.nextID("reactions")
+	reaction.Position = 1
+	for _, existing := range m.reactions {
+		if existing.Position >= reaction.Position {
+			reaction.Position = existing.Position + 1
+		}
+	}
+	m.reactions = append(m.reactions, reaction)
+	return reaction.ID, nil
+}
+
+func (m *MemoryStore) UpdateReaction(ctx context.Context, reaction models.Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, existing := range m.reactions {
+		if existing.ID != reaction.ID {
+			continue
+		}
+		reaction.Name = existing.Name
+		reaction.Position = existing.Position
+		if err := models.ValidateReaction(&reaction); err != nil {
+			return err
+		}
+		m.reactions[i] = reaction
+
+		// Changer le score recalcule les compteurs de tous les contenus
+		if reaction.Score != existing.Score {
+			for id, stored := range m.posts {
+				stored.post.Likes, stored.post.Dislikes = m.scoreCounts(m.postReactions, id)
+			}
+			for id, comment := range m.comments {
+				comment.Likes, comment.Dislikes = m.scoreCounts(m.commentReactions, id)
+			}
+		}
+		return nil
+	}
+	return models.ErrReactionNotFound
+}
+
+func (m *MemoryStore) GetReactors(ctx context.Context, targetType string, targetID int) ([]models.ReactionCount, error) {
+	if !models.ValidReactionTarget(targetType) {
+		return nil, models.ErrInvalidReactionTarget
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reactions := m.postReactions
+	_, exists := m.posts[targetID]
+	if targetType == models.ReactionOnComment {
+		reactions = m.commentReactions
+		_, exists = m.comments[targetID]
+	}
+	if !exists {
+		return nil, models.ErrReactionTargetNotFound
+	}
+
+	reactors := []models.ReactionCount{}
+	for _, reaction := range m.sortedReactions() {
+		var keys []reactionKey
+		for key := range reactions {
+			if key.itemID == targetID && key.reactionType == reaction.Name {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if !reactions[keys[i]].Equal(reactions[keys[j]]) {
+				return reactions[keys[i]].Before(reactions[keys[j]])
+			}
+			return m.username(keys[i].userID) < m.username(keys[j].userID)
+		})
+		count := models.ReactionCount{Reaction: reaction, Count: len(keys)}
+		for _, key := range keys {
+			count.Users = append(count.Users, m.username(key.userID))
+		}
+		reactors = append(reactors, count)
+	}
+	return reactors, nil
+}
+
+// Réputation
+
+// receivedVotes compte les réactions positives et négatives posées par d'autres utilisateurs sur les contenus d'un utilisateur
+func (m *MemoryStore) receivedVotes(userID int) (upvotes, downvotes int) {
+	count := func(reactions map[reactionKey]time.Time, authorID func(itemID int) int) {
+		for key := range reactions {
+			if key.userID == userID || authorID(key.itemID) != userID {
+				continue
+			}
+			reaction, _ := m.reactionByName(key.reactionType)
+			switch {
+			case reaction.Score > 0:
+				upvotes++
+			case reaction.Score < 0:
+				downvotes++
+			}
+		}
+	}
+	count(m.postReactions, func(postID int) int {
+		if stored, ok := m.posts[postID]; ok {
+			return stored.post.UserID
+		}
+		return 0
+	})
+	count(m.commentReactions, func(commentID int) int {
+		if comment, ok := m.comments[commentID]; ok {
+			return comment.UserID
+		}
+		return 0
+	})
+	return upvotes, downvotes
+}
+
+// acceptedAnswers compte les commentaires d'un utilisateur acceptés comme réponse au post d'un autre utilisateur
+func (m *MemoryStore) acceptedAnswers(userID int) int {
+	accepted := 0
+	for _, stored := range m.posts {
+		comment, ok := m.comments[stored.post.AcceptedCommentID]
+		if ok && comment.UserID == userID && stored.post.UserID != userID {
+			accepted++
+		}
+	}
+	return accepted
+}
+
+func (m *MemoryStore) GetReputation(ctx context.Context, userID int) (*models.Reputation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	reputation := &models.Reputation{UserID: userID, Staff: user.Role == "moderator" || user.Role == "admin"}
+	reputation.Upvotes, reputation.Downvotes = m.receivedVotes(userID)
+	reputation.AcceptedAnswers = m.acceptedAnswers(userID)
+	for _, stored := range m.pendingPosts {
+		if stored.post.UserID != userID {
+			continue
+		}
+		switch stored.post.Status {
+		case "approved":
+			reputation.ApprovedPosts++
+		case "rejected":
+			reputation.RejectedPosts++
+		}
+	}
+	for _, report := range m.reports {
+		if report.ContentUserID == userID && report.Status == "approved" && report.Type != "moderator_request" {
+			reputation.UpheldReports++
+		}
+	}
+	reputation.Compute()
+	return reputation, nil
+}
+
+// activityStats compte l'activité d'un utilisateur comme le fait models.GetActivityStats
+func (m *MemoryStore) activityStats(user *models.User) *models.ActivityStats {
+	stats := &models.ActivityStats{MemberSince: user.CreatedAt}
+	for _, stored := range m.posts {
+		if stored.post.UserID == user.ID {
+			stats.Posts++
+			likes, _ := m.scoreCounts(m.postReactions, stored.post.ID)
+			if likes > stats.BestPostLikes {
+				stats.BestPostLikes = likes
+			}
+		}
+	}
+	for _, comment := range m.comments {
+		if comment.UserID == user.ID {
+			stats.Comments++
+		}
+	}
+	for key := range m.postReactions {
+		if stored, ok := m.posts[key.itemID]; ok && key.userID == user.ID && stored.post.UserID != user.ID {
+			stats.ReactionsGiven++
+		}
+	}
+	for key := range m.commentReactions {
+		if comment, ok := m.comments[key.itemID]; ok && key.userID == user.ID && comment.UserID != user.ID {
+			stats.ReactionsGiven++
+		}
+	}
+	stats.Upvotes, _ = m.receivedVotes(user.ID)
+	stats.AcceptedAnswers = m.acceptedAnswers(user.ID)
+	return stats
+}
+
+// awardBadges attribue à un utilisateur les badges qu'il a gagnés et renvoie ceux qu'il n'avait pas encore
+func (m *MemoryStore) awardBadges(user *models.User, now time.Time) []models.Badge {
+	var awarded []models.Badge
+	for _, badge := range models.EarnedBadges(m.activityStats(user), now) {
+		key := userBadgeKey{user.ID, badge.Name}
+		if _, ok := m.userBadges[key]; !ok {
+			m.userBadges[key] = now
+			awarded = append(awarded, badge)
+		}
+	}
+	return awarded
+}
+
+func (m *MemoryStore) AwardBadges(ctx context.Context, userID int) ([]models.Badge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	return m.awardBadges(user, time.Now()), nil
+}
+
+func (m *MemoryStore) AwardAllBadges(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	total := 0
+	for _, user := range m.users {
+		total += len(m.awardBadges(user, now))
+	}
+	return total, nil
+}
+
+func (m *MemoryStore) GetUserBadges(ctx context.Context, userID int) ([]models.UserBadge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	badges := []models.UserBadge{}
+	for key, awardedAt := range m.userBadges {
+		if badge, ok := models.FindBadge(key.badge); ok && key.userID == userID {
+			badges = append(badges, models.UserBadge{Badge: badge, AwardedAt: awardedAt})
+		}
+	}
+	sort.Slice(badges, func(i, j int) bool {
+		if !badges[i].AwardedAt.Equal(badges[j].AwardedAt) {
+			return badges[i].AwardedAt.Before(badges[j].AwardedAt)
+		}
+		return badges[i].Name < badges[j].Name
+	})
+	return badges, nil
+}
+
+// authorBadges renvoie les badges d'un utilisateur dans leur ordre d'affichage, comme loadUserBadges
+func (m *MemoryStore) authorBadges(userID int) []models.Badge {
+	var badges []models.Badge
+	for key := range m.userBadges {
+		if badge, ok := models.FindBadge(key.badge); ok && key.userID == userID {
+			badges = append(badges, badge)
+		}
+	}
+	models.SortBadges(badges)
+	return badges
+}
+
+// Brouillons
+
+// draftCopy renvoie une copie d'un brouillon, que l'appelant peut modifier
+func draftCopy(draft *models.Draft) *models.Draft {
+	copied := *draft
+	copied.CategoryIDs = append([]int(nil), draft.CategoryIDs...)
+	copied.Tags = append([]string(nil), draft.Tags...)
+	return &copied
+}
+
+func (m *MemoryStore) SaveDraft(ctx context.Context, draft *models.Draft) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if strings.TrimSpace(draft.Title) == "" && strings.TrimSpace(draft.Content) == "" {
+		return 0, models.ErrDraftEmpty
+	}
+
+	draftID := draft.ID
+	if draftID == 0 && draft.PostID > 0 {
+		if _, ok := m.posts[draft.PostID]; !ok {
+			return 0, models.ErrPostNotFound
+		}
+		for id, stored := range m.drafts {
+			if stored.UserID == draft.UserID && stored.PostID == draft.PostID {
+				draftID = id
+			}
+		}
+	}
+
+	now := time.Now()
+	if draftID == 0 {
+		count := 0
+		for _, stored := range m.drafts {
+			if stored.UserID == draft.UserID {
+				count++
+			}
+		}
+		if count >= models.MaxDraftsPerUser {
+			return 0, models.ErrTooManyDrafts
+		}
+		stored := draftCopy(draft)
+		stored.ID = m.nextID("drafts")
+		stored.PublishAt = sql.NullTime{}
+		stored.PublishError = ""
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+		m.drafts[stored.ID] = stored
+		return stored.ID, nil
+	}
+
+	stored, ok := m.drafts[draftID]
+	if !ok || stored.UserID != draft.UserID {
+		return 0, models.ErrDraftNotFound
+	}
+	stored.Title = draft.Title
+	stored.Content = draft.Content
+	stored.CategoryIDs = append([]int(nil), draft.CategoryIDs...)
+	stored.Tags = append([]string(nil), draft.Tags...)
+	stored.UpdatedAt = now
+	return draftID, nil
+}
+
+func (m *MemoryStore) GetDraft(ctx context.Context, draftID, userID int) (*models.Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.drafts[draftID]
+	if !ok || stored.UserID != userID {
+		return nil, models.ErrDraftNotFound
+	}
+	return draftCopy(stored), nil
+}
+
+func (m *MemoryStore) GetPostDraft(ctx context.Context, postID, userID int) (*models.Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.drafts {
+		if stored.UserID == userID && stored.PostID == postID {
+			return draftCopy(stored), nil
+		}
+	}
+	return nil, models.ErrDraftNotFound
+}
+
+func (m *MemoryStore) GetUserDrafts(ctx context.Context, userID int) ([]*models.Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	drafts := []*models.Draft{}
+	for _, stored := range m.drafts {
+		if stored.UserID == userID {
+			drafts = append(drafts, draftCopy(stored))
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		if !drafts[i].UpdatedAt.Equal(drafts[j].UpdatedAt) {
+			return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+		}
+		return drafts[i].ID > drafts[j].ID
+	})
+	return drafts, nil
+}
+
+// checkDraft vérifie que le brouillon publié avec le post existe et appartient à son auteur
+func (m *MemoryStore) checkDraft(post models.NewPost) error {
+	if post.DraftID == 0 {
+		return nil
+	}
+	if stored, ok := m.drafts[post.DraftID]; !ok || stored.UserID != post.UserID {
+		return models.ErrDraftNotFound
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteDraft(ctx context.Context, draftID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.drafts[draftID]
+	if !ok || stored.UserID != userID {
+		return models.ErrDraftNotFound
+	}
+	delete(m.drafts, draftID)
+	return nil
+}
+
+func (m *MemoryStore) DeletePostDraft(ctx context.Context, postID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, stored := range m.drafts {
+		if stored.UserID == userID && stored.PostID == postID {
+			delete(m.drafts, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ScheduleDraft(ctx context.Context, draftID, userID int, publishAt, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !publishAt.IsZero() && !publishAt.After(now) {
+		return models.ErrPublishTimeInPast
+	}
+	stored, ok := m.drafts[draftID]
+	if !ok || stored.UserID != userID {
+		return models.ErrDraftNotFound
+	}
+	if stored.PostID != 0 && !publishAt.IsZero() {
+		return models.ErrOnlyNewPostsScheduled
+	}
+	stored.PublishAt = sql.NullTime{}
+	if !publishAt.IsZero() {
+		stored.PublishAt = sql.NullTime{Time: publishAt.UTC(), Valid: true}
+	}
+	stored.PublishError = ""
+	return nil
+}
+
+func (m *MemoryStore) GetDueDrafts(ctx context.Context, now time.Time) ([]*models.Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	drafts := []*models.Draft{}
+	for _, stored := range m.drafts {
+		if stored.PublishAt.Valid && !stored.PublishAt.Time.After(now) {
+			drafts = append(drafts, draftCopy(stored))
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		if !drafts[i].PublishAt.Time.Equal(drafts[j].PublishAt.Time) {
+			return drafts[i].PublishAt.Time.Before(drafts[j].PublishAt.Time)
+		}
+		return drafts[i].ID < drafts[j].ID
+	})
+	return drafts, nil
+}
+
+func (m *MemoryStore) ClaimScheduledDraft(ctx context.Context, draftID int, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.drafts[draftID]
+	if !ok || !stored.PublishAt.Valid || stored.PublishAt.Time.After(now) {
+		return false, nil
+	}
+	stored.PublishAt = sql.NullTime{}
+	return true, nil
+}
+
+func (m *MemoryStore) SetDraftPublishError(ctx context.Context, draftID int, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.drafts[draftID]; ok {
+		stored.PublishError = message
+	}
+	return nil
+}
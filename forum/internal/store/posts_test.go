@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"forum/internal/database"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// backend ouvre un stockage vide et renvoie une fonction qui vieillit un post de la durée donnée
+type backend struct {
+	name string
+	open func(t *testing.T) (Stores, func(postID int, age time.Duration))
+}
+
+// backends sont les deux stockages qui doivent trier et compter les posts de la même façon
+var backends = []backend{
+	{"memory", func(t *testing.T) (Stores, func(int, time.Duration)) {
+		stores := NewMemoryStores()
+		m := stores.Posts.(*MemoryStore)
+		return stores, func(postID int, age time.Duration) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.posts[postID].post.CreatedAt = time.Now().Add(-age)
+		}
+	}},
+	{"sqlite", func(t *testing.T) (Stores, func(int, time.Duration)) {
+		return openSQLiteStores(t), func(postID int, age time.Duration) {
+			modifier := fmt.Sprintf("-%d minutes", int(age.Minutes()))
+			if _, err := database.DB.Exec("UPDATE posts SET created_at = datetime('now', ?) WHERE id = ?", modifier, postID); err != nil {
+				t.Fatalf("aging post %d: %v", postID, err)
+			}
+		}
+	}},
+}
+
+// openSQLiteStores ouvre une base vide dans un dossier temporaire, avec toutes les migrations appliquées
+// La base est fermée à la fin du test
+func openSQLiteStores(t *testing.T) Stores {
+	t.Helper()
+	if err := database.InitDB(database.DefaultConfig(filepath.Join(t.TempDir(), "forum.db"))); err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		database.CloseDB()
+	})
+	return NewSQLiteStores()
+}
+
+// registerUsers inscrit les utilisateurs et renvoie leurs IDs dans le même ordre
+func registerUsers(t *testing.T, stores Stores, names ...string) []int {
+	t.Helper()
+	ctx := context.Background()
+	var ids []int
+	for _, name := range names {
+		if err := stores.Users.RegisterUser(ctx, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("registering %s: %v", name, err)
+		}
+		user, err := stores.Users.GetUserByEmail(ctx, name+"@example.com")
+		if err != nil {
+			t.Fatalf("fetching %s: %v", name, err)
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func TestGetPostsSorts(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			stores, age := b.open(t)
+			ctx := context.Background()
+			users := registerUsers(t, stores, "author", "r1", "r2", "r3", "r4")
+			author, readers := users[0], users[1:]
+
+			// Chaque post a un âge, des réactions des lecteurs et un nombre de commentaires
+			for _, p := range []struct {
+				title     string
+				age       time.Duration
+				reactions []string
+				comments  int
+			}{
+				{"Récent", 10 * time.Minute, []string{"like"}, 0},
+				{"Débattu", time.Hour, []string{"like", "like", "dislike", "dislike"}, 0},
+				{"Ancien", 10 * 24 * time.Hour, []string{"like", "like", "like"}, 2},
+				{"Très ancien", 40 * 24 * time.Hour, []string{"like", "like", "like", "like"}, 0},
+			} {
+				postID, err := stores.Posts.CreatePost(ctx, p.title, "Contenu", author, []int{1})
+				if err != nil {
+					t.Fatalf("creating %s: %v", p.title, err)
+				}
+				for i, reactionType := range p.reactions {
+					if err := stores.Posts.ReactToPost(ctx, postID, readers[i], reactionType); err != nil {
+						t.Fatalf("reacting to %s: %v", p.title, err)
+					}
+				}
+				for i := 0; i < p.comments; i++ {
+					if _, err := stores.Comments.CreateComment(ctx, "Un commentaire", readers[i], postID); err != nil {
+						t.Fatalf("commenting %s: %v", p.title, err)
+					}
+				}
+				age(postID, p.age)
+			}
+
+			for _, tt := range []struct {
+				sortBy string
+				want   []string
+			}{
+				{"likes", []string{"Très ancien", "Ancien", "Débattu", "Récent"}},
+				// Le score s'effondre avec l'âge: un like récent passe devant cinq points vieux de dix jours,
+				// et un post sans score reste derrière les anciens posts appréciés
+				{"hot", []string{"Récent", "Ancien", "Très ancien", "Débattu"}},
+				// Seul le post partagé entre likes et dislikes est controversé, les autres suivent par date
+				{"controversial", []string{"Débattu", "Récent", "Ancien", "Très ancien"}},
+				{"comments", []string{"Ancien", "Récent", "Débattu", "Très ancien"}},
+				{"top_week", []string{"Récent", "Débattu"}},
+				{"top_month", []string{"Ancien", "Récent", "Débattu"}},
+				{"date_desc", []string{"Récent", "Débattu", "Ancien", "Très ancien"}},
+				{"date_asc", []string{"Très ancien", "Ancien", "Débattu", "Récent"}},
+			} {
+				got, total, err := stores.Posts.GetPosts(ctx, 1, 10, 0, nil, 0, "", tt.sortBy, 0)
+				if err != nil {
+					t.Fatalf("sorting by %s: %v", tt.sortBy, err)
+				}
+				var titles []string
+				for _, post := range got {
+					titles = append(titles, post.Title)
+				}
+				if !reflect.DeepEqual(titles, tt.want) || total != len(tt.want) {
+					t.Errorf("sorted by %s: %v (total %d), want %v", tt.sortBy, titles, total, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// Les compteurs stockés suivent les réactions posées, remplacées et retirées, et les commentaires
+func TestPostCounters(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			stores, _ := b.open(t)
+			ctx := context.Background()
+			users := registerUsers(t, stores, "author", "r1", "r2")
+			author, r1, r2 := users[0], users[1], users[2]
+			postID, err := stores.Posts.CreatePost(ctx, "Titre", "Contenu", author, []int{1})
+			if err != nil {
+				t.Fatalf("creating post: %v", err)
+			}
+			commentID, err := stores.Comments.CreateComment(ctx, "Premier", r1, postID)
+			if err != nil {
+				t.Fatalf("creating comment: %v", err)
+			}
+
+			expect := func(step string, likes, dislikes, comments int) {
+				t.Helper()
+				post, err := stores.Posts.GetPostByID(ctx, postID, author)
+				if err != nil {
+					t.Fatalf("%s: fetching post: %v", step, err)
+				}
+				if post.Likes != likes || post.Dislikes != dislikes || post.CommentCount != comments {
+					t.Errorf("%s: %d likes, %d dislikes, %d comments, want %d, %d and %d",
+						step, post.Likes, post.Dislikes, post.CommentCount, likes, dislikes, comments)
+				}
+			}
+			react := func(userID int, reactionType string) {
+				t.Helper()
+				if err := stores.Posts.ReactToPost(ctx, postID, userID, reactionType); err != nil {
+					t.Fatalf("reacting %q: %v", reactionType, err)
+				}
+			}
+
+			expect("after the first comment", 0, 0, 1)
+			react(r1, "like")
+			react(r2, "like")
+			expect("after two likes", 2, 0, 1)
+			react(r1, "dislike")
+			expect("after a like replaced by a dislike", 1, 1, 1)
+			react(r2, "like")
+			expect("after a like removed", 0, 1, 1)
+			react(r1, "")
+			expect("after all reactions removed", 0, 0, 1)
+
+			if _, err := stores.Comments.CreateComment(ctx, "Second", r2, postID); err != nil {
+				t.Fatalf("creating comment: %v", err)
+			}
+			expect("after the second comment", 0, 0, 2)
+			if err := stores.Comments.DeleteComment(ctx, commentID, r1, false); err != nil {
+				t.Fatalf("deleting comment: %v", err)
+			}
+			expect("after a comment deleted", 0, 0, 1)
+
+			// Les commentaires ont leurs propres compteurs
+			comments, err := stores.Comments.GetCommentsByPostID(ctx, postID, author)
+			if err != nil || len(comments) != 1 {
+				t.Fatalf("comments: %+v, error %v", comments, err)
+			}
+			secondID := comments[0].ID
+			if err := stores.Comments.ReactToComment(ctx, secondID, author, "like"); err != nil {
+				t.Fatalf("reacting to comment: %v", err)
+			}
+			if err := stores.Comments.ReactToComment(ctx, secondID, r1, "dislike"); err != nil {
+				t.Fatalf("reacting to comment: %v", err)
+			}
+			comment, err := stores.Comments.GetCommentByID(ctx, secondID, author)
+			if err != nil || comment.Likes != 1 || comment.Dislikes != 1 {
+				t.Errorf("comment counters: %+v, error %v", comment, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ScheduleMessagePurge supprime les messages privés expirés toutes les "interval" jusqu'à l'annulation du contexte
+// La durée de conservation est relue à chaque passage: un changement fait par un administrateur
+// s'applique sans redémarrer le serveur
+func ScheduleMessagePurge(ctx context.Context, messages MessageStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := messages.PurgeMessages(ctx, now)
+			if err != nil {
+				log.Printf("Error purging expired messages: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d expired messages", purged)
+			}
+		}
+	}
+}
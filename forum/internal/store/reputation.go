@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"forum/internal/models"
+	"sync"
+	"time"
+)
+
+// WithReputationCache renvoie des stockages qui gardent la réputation calculée de chaque utilisateur
+// pendant ttl, pour ne pas refaire ses comptes à chaque post publié. Le cache est vidé après chaque
+// modification qui peut changer une réputation: réactions, réponses acceptées, suppressions,
+// décisions de modération, rôles et scores des réactions. ttl borne le retard des modifications
+// faites en dehors de ces stockages, par exemple par un autre processus
+func WithReputationCache(stores Stores, ttl time.Duration) Stores {
+	c := &reputationCache{ttl: ttl, entries: make(map[int]cachedReputation), now: time.Now}
+	stores.Posts = &cachedPostStore{PostStore: stores.Posts, cache: c}
+	stores.Comments = &cachedCommentStore{CommentStore: stores.Comments, cache: c}
+	stores.Moderation = &cachedModerationStore{ModerationStore: stores.Moderation, cache: c}
+	stores.Reactions = &cachedReactionStore{ReactionStore: stores.Reactions, cache: c}
+	stores.Reputation = &cachedReputationStore{ReputationStore: stores.Reputation, cache: c}
+	return stores
+}
+
+type cachedReputation struct {
+	reputation models.Reputation
+	expires    time.Time
+}
+
+// reputationCache garde les réputations calculées par utilisateur
+type reputationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[int]cachedReputation
+	now     func() time.Time
+}
+
+// get renvoie une copie de la réputation gardée pour l'utilisateur si elle n'a pas expiré
+func (c *reputationCache) get(userID int) (*models.Reputation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	reputation := entry.reputation
+	return &reputation, true
+}
+
+func (c *reputationCache) put(reputation *models.Reputation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[reputation.UserID] = cachedReputation{reputation: *reputation, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate vide tout le cache: une modification change la réputation de l'auteur du contenu,
+// que l'appelant ne connaît pas toujours
+func (c *reputationCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[int]cachedReputation)
+}
+
+// invalidated vide le cache quand la modification a réussi et renvoie son erreur
+func (c *reputationCache) invalidated(err error) error {
+	if err == nil {
+		c.invalidate()
+	}
+	return err
+}
+
+// cachedReputationStore lit les réputations à travers le cache
+type cachedReputationStore struct {
+	ReputationStore
+	cache *reputationCache
+}
+
+func (s *cachedReputationStore) GetReputation(ctx context.Context, userID int) (*models.Reputation, error) {
+	if reputation, ok := s.cache.get(userID); ok {
+		return reputation, nil
+	}
+	reputation, err := s.ReputationStore.GetReputation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(reputation)
+	return reputation, nil
+}
+
+// cachedPostStore vide le cache après les réactions aux posts, les réponses acceptées et les suppressions
+type cachedPostStore struct {
+	PostStore
+	cache *reputationCache
+}
+
+func (s *cachedPostStore) DeletePost(ctx context.Context, postID, userID int, isAdmin bool) error {
+	return s.cache.invalidated(s.PostStore.DeletePost(ctx, postID, userID, isAdmin))
+}
+
+func (s *cachedPostStore) ReactToPost(ctx context.Context, postID, userID int, reactionType string) error {
+	return s.cache.invalidated(s.PostStore.ReactToPost(ctx, postID, userID, reactionType))
+}
+
+func (s *cachedPostStore) AcceptAnswer(ctx context.Context, postID, commentID int) error {
+	return s.cache.invalidated(s.PostStore.AcceptAnswer(ctx, postID, commentID))
+}
+
+// cachedCommentStore vide le cache après les réactions aux commentaires et les suppressions
+type cachedCommentStore struct {
+	CommentStore
+	cache *reputationCache
+}
+
+func (s *cachedCommentStore) DeleteComment(ctx context.Context, commentID, userID int, isAdmin bool) error {
+	return s.cache.invalidated(s.CommentStore.DeleteComment(ctx, commentID, userID, isAdmin))
+}
+
+func (s *cachedCommentStore) ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error {
+	return s.cache.invalidated(s.CommentStore.ReactToComment(ctx, commentID, userID, reactionType))
+}
+
+// cachedModerationStore vide le cache après les décisions de modération et les changements de rôle
+type cachedModerationStore struct {
+	ModerationStore
+	cache *reputationCache
+}
+
+func (s *cachedModerationStore) ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error) {
+	postID, err := s.ModerationStore.ApprovePendingPost(ctx, pendingID, moderatorID)
+	return postID, s.cache.invalidated(err)
+}
+
+func (s *cachedModerationStore) RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error {
+	return s.cache.invalidated(s.ModerationStore.RejectPendingPost(ctx, pendingID, moderatorID, reason))
+}
+
+func (s *cachedModerationStore) HandleReport(ctx context.Context, reportID, adminID int, status, response string) error {
+	return s.cache.invalidated(s.ModerationStore.HandleReport(ctx, reportID, adminID, status, response))
+}
+
+func (s *cachedModerationStore) UpdateUserRole(ctx context.Context, userID int, newRole string) error {
+	return s.cache.invalidated(s.ModerationStore.UpdateUserRole(ctx, userID, newRole))
+}
+
+// cachedReactionStore vide le cache quand le score d'une réaction change
+type cachedReactionStore struct {
+	ReactionStore
+	cache *reputationCache
+}
+
+func (s *cachedReactionStore) UpdateReaction(ctx context.Context, reaction models.Reaction) error {
+	return s.cache.invalidated(s.ReactionStore.UpdateReaction(ctx, reaction))
+}
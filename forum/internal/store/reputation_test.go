@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"forum/internal/models"
+	"testing"
+	"time"
+)
+
+// La réputation gardée en cache est recalculée après une réaction, ou quand elle expire
+func TestReputationCache(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryStores()
+	stores := WithReputationCache(memory, time.Minute)
+	cache := stores.Reputation.(*cachedReputationStore).cache
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for _, name := range []string{"author", "reader"} {
+		if err := stores.Users.RegisterUser(ctx, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("registering %s: %v", name, err)
+		}
+	}
+	author, _ := stores.Users.GetUserByEmail(ctx, "author@example.com")
+	reader, _ := stores.Users.GetUserByEmail(ctx, "reader@example.com")
+	postID, err := stores.Posts.CreatePost(ctx, "Titre", "Contenu", author.ID, []int{1})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+
+	score := func() int {
+		t.Helper()
+		reputation, err := stores.Reputation.GetReputation(ctx, author.ID)
+		if err != nil {
+			t.Fatalf("fetching reputation: %v", err)
+		}
+		return reputation.Score
+	}
+
+	if got := score(); got != 0 {
+		t.Fatalf("initial score: got %d, want 0", got)
+	}
+	if err := stores.Posts.ReactToPost(ctx, postID, reader.ID, "like"); err != nil {
+		t.Fatalf("reacting: %v", err)
+	}
+	if got := score(); got != models.ReputationPerUpvote {
+		t.Errorf("score after reaction: got %d, want %d", got, models.ReputationPerUpvote)
+	}
+
+	// Une modification faite sans passer par le cache n'est vue qu'à l'expiration
+	if err := memory.Posts.ReactToPost(ctx, postID, reader.ID, ""); err != nil {
+		t.Fatalf("removing reaction: %v", err)
+	}
+	if got := score(); got != models.ReputationPerUpvote {
+		t.Errorf("cached score: got %d, want %d", got, models.ReputationPerUpvote)
+	}
+	now = now.Add(time.Minute)
+	if got := score(); got != 0 {
+		t.Errorf("score after expiry: got %d, want 0", got)
+	}
+}
@@ -0,0 +1,166 @@
+//go:build sqlite_fts5
+
+package store
+
+import (
+	"context"
+	"forum/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openSearchStores ouvre une base vide dans un dossier temporaire, avec les index de recherche
+func openSearchStores(t *testing.T) Stores {
+	t.Helper()
+	stores := openSQLiteStores(t)
+	if !stores.Posts.SearchEnabled() {
+		t.Fatal("search is disabled although SQLite was built with FTS5")
+	}
+	return stores
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	for _, tt := range []struct {
+		raw   string
+		want  models.SearchQuery
+		match string
+	}{
+		{
+			raw:   "golang concurrence",
+			want:  models.SearchQuery{Terms: []string{"golang", "concurrence"}},
+			match: `"golang" AND "concurrence"`,
+		},
+		{
+			raw:   `"mot exact" prog*`,
+			want:  models.SearchQuery{Terms: []string{"prog*"}, Phrases: []string{"mot exact"}},
+			match: `"prog"* AND "mot exact"`,
+		},
+		{
+			raw:  `author:alice category:"Vie du forum" after:2024-01-01 before:2024-12-31`,
+			want: models.SearchQuery{Author: "alice", Category: "Vie du forum", After: "2024-01-01", Before: "2024-12-31"},
+		},
+		{
+			// Une date invalide reste un mot à chercher, la ponctuation FTS5 est retirée
+			raw:   `after:hier NEAR(a) "x""`,
+			want:  models.SearchQuery{Terms: []string{"after hier", "NEAR a"}, Phrases: []string{"x"}},
+			match: `"after hier" AND "NEAR a" AND "x"`,
+		},
+		{raw: `"" * :`, want: models.SearchQuery{}},
+	} {
+		got := models.ParseSearchQuery(tt.raw)
+		tt.want.Raw = tt.raw
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+		if match := got.MatchExpression(); match != tt.match {
+			t.Errorf("MatchExpression of %q = %s, want %s", tt.raw, match, tt.match)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	stores := openSearchStores(t)
+	ctx := context.Background()
+	for _, name := range []string{"alice", "bob"} {
+		if err := stores.Users.RegisterUser(ctx, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("registering %s: %v", name, err)
+		}
+	}
+	alice, _ := stores.Users.GetUserByEmail(ctx, "alice@example.com")
+	bob, _ := stores.Users.GetUserByEmail(ctx, "bob@example.com")
+
+	// Un terme du titre pèse plus que le même terme dans le contenu
+	inContent, err := stores.Posts.CreatePost(ctx, "Questions diverses", "Un mot sur les goroutines <b>et</b> le reste", alice.ID, []int{1})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	inTitle, err := stores.Posts.CreatePost(ctx, "Les goroutines", "Comment les arrêter proprement ?", bob.ID, []int{1})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	commentID, err := stores.Comments.CreateComment(ctx, "Les goroutines se terminent avec un contexte annulé", alice.ID, inTitle)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+
+	search := func(raw string) []*models.SearchResult {
+		t.Helper()
+		results, total, err := stores.Posts.Search(ctx, models.ParseSearchQuery(raw), 1, 10, 0)
+		if err != nil {
+			t.Fatalf("searching %q: %v", raw, err)
+		}
+		if total != len(results) {
+			t.Errorf("searching %q: total %d for %d results", raw, total, len(results))
+		}
+		return results
+	}
+
+	results := search("goroutines")
+	if len(results) != 3 {
+		t.Fatalf("results: got %d, want 3", len(results))
+	}
+	if results[0].Type != "post" || results[0].PostID != inTitle {
+		t.Errorf("first result: %+v, want the post with the term in its title", results[0])
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Score < results[i-1].Score {
+			t.Errorf("results are not sorted by bm25 score: %v before %v", results[i-1].Score, results[i].Score)
+		}
+	}
+	for _, result := range results {
+		if result.Score >= 0 {
+			t.Errorf("bm25 score of a match: got %v, want a negative score", result.Score)
+		}
+		switch {
+		case result.Type == "post" && result.PostID == inTitle:
+			if got := result.HighlightedTitle(); got != "Les <mark>goroutines</mark>" {
+				t.Errorf("highlighted title: %q", got)
+			}
+		case result.Type == "post" && result.PostID == inContent:
+			// Le texte est échappé avant que les marqueurs deviennent des balises <mark>
+			if got := string(result.HighlightedSnippet()); got != "Un mot sur les <mark>goroutines</mark> &lt;b&gt;et&lt;/b&gt; le reste" {
+				t.Errorf("highlighted snippet: %q", got)
+			}
+			if got := result.PlainSnippet(); got != "Un mot sur les goroutines <b>et</b> le reste" {
+				t.Errorf("plain snippet: %q", got)
+			}
+		case result.Type == "comment":
+			if result.CommentID != commentID || result.PostID != inTitle || result.Username != "alice" {
+				t.Errorf("comment result: %+v", result)
+			}
+		}
+	}
+
+	// Les accents sont ignorés, le préfixe et les filtres s'appliquent
+	if results := search("arreter"); len(results) != 1 || results[0].PostID != inTitle {
+		t.Errorf("search without diacritics: %+v", results)
+	}
+	if results := search("gorout*"); len(results) != 3 {
+		t.Errorf("prefix search: %d results, want 3", len(results))
+	}
+	if results := search("goroutines author:alice"); len(results) != 2 {
+		t.Errorf("search by author: %d results, want 2", len(results))
+	}
+	if results := search(`"contexte annulé"`); len(results) != 1 || results[0].Type != "comment" {
+		t.Errorf("phrase search: %+v", results)
+	}
+
+	// Les triggers gardent l'index à jour après une modification et une suppression
+	if err := stores.Posts.UpdatePost(ctx, inContent, alice.ID, "Questions diverses", "Plus rien à voir", []int{1}); err != nil {
+		t.Fatalf("updating post: %v", err)
+	}
+	if results := search("goroutines"); len(results) != 2 {
+		t.Errorf("results after the update: %d, want 2", len(results))
+	}
+	if err := stores.Posts.DeletePost(ctx, inTitle, bob.ID, false); err != nil {
+		t.Fatalf("deleting post: %v", err)
+	}
+	if results := search("goroutines"); len(results) != 0 {
+		t.Errorf("results after the deletion: %+v", results)
+	}
+
+	if _, _, err := stores.Posts.Search(ctx, models.ParseSearchQuery("  "), 1, 10, 0); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("empty query: got error %v", err)
+	}
+}
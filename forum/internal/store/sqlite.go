@@ -0,0 +1,719 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"forum/internal/database"
+	"forum/internal/models"
+	"log"
+	"time"
+)
+
+// SQLiteStore implémente toutes les interfaces de stockage avec la base SQLite
+// Chaque méthode délègue à la fonction correspondante du paquet models, qui utilise database.DB et database.ReadDB
+type SQLiteStore struct{}
+
+// Vérification à la compilation que SQLiteStore implémente toutes les interfaces
+var (
+	_ PostStore         = (*SQLiteStore)(nil)
+	_ CommentStore      = (*SQLiteStore)(nil)
+	_ UserStore         = (*SQLiteStore)(nil)
+	_ SessionStore      = (*SQLiteStore)(nil)
+	_ ModerationStore   = (*SQLiteStore)(nil)
+	_ ImageStore        = (*SQLiteStore)(nil)
+	_ TagStore          = (*SQLiteStore)(nil)
+	_ CategoryStore     = (*SQLiteStore)(nil)
+	_ NotificationStore = (*SQLiteStore)(nil)
+	_ SubscriptionStore = (*SQLiteStore)(nil)
+	_ DigestStore       = (*SQLiteStore)(nil)
+	_ MessageStore      = (*SQLiteStore)(nil)
+	_ BookmarkStore     = (*SQLiteStore)(nil)
+	_ PollStore         = (*SQLiteStore)(nil)
+	_ ReactionStore     = (*SQLiteStore)(nil)
+	_ ReputationStore   = (*SQLiteStore)(nil)
+	_ DraftStore        = (*SQLiteStore)(nil)
+)
+
+// NewSQLiteStores renvoie les dépendances de stockage branchées sur la base SQLite
+// La base doit avoir été ouverte avec database.InitDB
+func NewSQLiteStores() Stores {
+	s := &SQLiteStore{}
+	return Stores{
+		Posts:         s,
+		Comments:      s,
+		Users:         s,
+		Sessions:      s,
+		Moderation:    s,
+		Images:        s,
+		Tags:          s,
+		Categories:    s,
+		Notifications: s,
+		Subscriptions: s,
+		Digests:       s,
+		Messages:      s,
+		Bookmarks:     s,
+		Polls:         s,
+		Reactions:     s,
+		Reputation:    s,
+		Drafts:        s,
+	}
+}
+
+// logCancelled signale à part les requêtes interrompues parce que le client s'est déconnecté
+// ou que le délai de la requête HTTP est dépassé, et renvoie alors l'erreur du contexte
+func logCancelled(ctx context.Context, operation string, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("Query cancelled: %s exceeded the request deadline", operation)
+	} else {
+		log.Printf("Query cancelled: %s was interrupted because the client disconnected", operation)
+	}
+	return ctx.Err()
+}
+
+// Posts
+
+func (s *SQLiteStore) CreatePost(ctx context.Context, title, content string, userID int, categoryIDs []int) (int, error) {
+	result, err := models.CreatePost(ctx, title, content, userID, categoryIDs)
+	return result, logCancelled(ctx, "CreatePost", err)
+}
+
+func (s *SQLiteStore) CreatePostWithDetails(ctx context.Context, post models.NewPost) (int, error) {
+	result, err := models.CreatePostWithDetails(ctx, post)
+	return result, logCancelled(ctx, "CreatePostWithDetails", err)
+}
+
+func (s *SQLiteStore) GetPostByID(ctx context.Context, postID, currentUserID int) (*models.Post, error) {
+	result, err := models.GetPostByID(ctx, postID, currentUserID)
+	return result, logCancelled(ctx, "GetPostByID", err)
+}
+
+func (s *SQLiteStore) GetPosts(ctx context.Context, page, perPage, categoryID int, tags []string, userID int, solved, sortBy string, currentUserID int) ([]*models.Post, int, error) {
+	result, total, err := models.GetPosts(ctx, page, perPage, categoryID, tags, userID, solved, sortBy, currentUserID)
+	return result, total, logCancelled(ctx, "GetPosts", err)
+}
+
+func (s *SQLiteStore) UpdatePost(ctx context.Context, postID, userID int, title, content string, categoryIDs []int) error {
+	return logCancelled(ctx, "UpdatePost", models.UpdatePost(ctx, postID, userID, title, content, categoryIDs))
+}
+
+func (s *SQLiteStore) DeletePost(ctx context.Context, postID, userID int, isAdmin bool) error {
+	return logCancelled(ctx, "DeletePost", models.DeletePost(ctx, postID, userID, isAdmin))
+}
+
+func (s *SQLiteStore) ReactToPost(ctx context.Context, postID, userID int, reactionType string) error {
+	return logCancelled(ctx, "ReactToPost", models.ReactToPost(ctx, postID, userID, reactionType))
+}
+
+func (s *SQLiteStore) AcceptAnswer(ctx context.Context, postID, commentID int) error {
+	return logCancelled(ctx, "AcceptAnswer", models.AcceptAnswer(ctx, postID, commentID))
+}
+
+func (s *SQLiteStore) GetAllCategories(ctx context.Context, role string) ([]models.Category, error) {
+	result, err := models.GetAllCategories(ctx, role)
+	return result, logCancelled(ctx, "GetAllCategories", err)
+}
+
+func (s *SQLiteStore) GetUserPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	result, err := models.GetUserPosts(ctx, userID)
+	return result, logCancelled(ctx, "GetUserPosts", err)
+}
+
+func (s *SQLiteStore) GetUserLikedPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	result, err := models.GetUserLikedPosts(ctx, userID)
+	return result, logCancelled(ctx, "GetUserLikedPosts", err)
+}
+
+func (s *SQLiteStore) GetUserDislikedPosts(ctx context.Context, userID int) ([]*models.Post, error) {
+	result, err := models.GetUserDislikedPosts(ctx, userID)
+	return result, logCancelled(ctx, "GetUserDislikedPosts", err)
+}
+
+func (s *SQLiteStore) Search(ctx context.Context, query models.SearchQuery, page, perPage, currentUserID int) ([]*models.SearchResult, int, error) {
+	result, total, err := models.Search(ctx, query, page, perPage, currentUserID)
+	return result, total, logCancelled(ctx, "Search", err)
+}
+
+// SearchEnabled indique si SQLite a été compilé avec FTS5
+func (s *SQLiteStore) SearchEnabled() bool {
+	return database.SearchEnabled
+}
+
+// Commentaires
+
+func (s *SQLiteStore) CreateComment(ctx context.Context, content string, userID, postID int) (int, error) {
+	result, err := models.CreateComment(ctx, content, userID, postID)
+	return result, logCancelled(ctx, "CreateComment", err)
+}
+
+func (s *SQLiteStore) GetCommentsByPostID(ctx context.Context, postID, currentUserID int) ([]*models.Comment, error) {
+	result, err := models.GetCommentsByPostID(ctx, postID, currentUserID)
+	return result, logCancelled(ctx, "GetCommentsByPostID", err)
+}
+
+func (s *SQLiteStore) GetCommentByID(ctx context.Context, commentID, currentUserID int) (*models.Comment, error) {
+	result, err := models.GetCommentByID(ctx, commentID, currentUserID)
+	return result, logCancelled(ctx, "GetCommentByID", err)
+}
+
+func (s *SQLiteStore) UpdateComment(ctx context.Context, commentID, userID int, content string) error {
+	return logCancelled(ctx, "UpdateComment", models.UpdateComment(ctx, commentID, userID, content))
+}
+
+func (s *SQLiteStore) DeleteComment(ctx context.Context, commentID, userID int, isAdmin bool) error {
+	return logCancelled(ctx, "DeleteComment", models.DeleteComment(ctx, commentID, userID, isAdmin))
+}
+
+func (s *SQLiteStore) ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error {
+	return logCancelled(ctx, "ReactToComment", models.ReactToComment(ctx, commentID, userID, reactionType))
+}
+
+func (s *SQLiteStore) GetUserComments(ctx context.Context, userID int) ([]*models.Comment, error) {
+	result, err := models.GetUserComments(ctx, userID)
+	return result, logCancelled(ctx, "GetUserComments", err)
+}
+
+// Utilisateurs
+
+func (s *SQLiteStore) RegisterUser(ctx context.Context, email, username, password string) error {
+	return logCancelled(ctx, "RegisterUser", models.RegisterUser(ctx, email, username, password))
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	result, err := models.GetUserByEmail(ctx, email)
+	return result, logCancelled(ctx, "GetUserByEmail", err)
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	result, err := models.GetUserByID(ctx, id)
+	return result, logCancelled(ctx, "GetUserByID", err)
+}
+
+func (s *SQLiteStore) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	result, err := models.Authenticate(ctx, email, password)
+	return result, logCancelled(ctx, "Authenticate", err)
+}
+
+func (s *SQLiteStore) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	result, err := models.GetAllUsers(ctx)
+	return result, logCancelled(ctx, "GetAllUsers", err)
+}
+
+func (s *SQLiteStore) GetUserActivity(ctx context.Context, userID, limit int) ([]*models.UserActivity, error) {
+	result, err := models.GetUserActivity(ctx, userID, limit)
+	return result, logCancelled(ctx, "GetUserActivity", err)
+}
+
+func (s *SQLiteStore) GetUserByOAuthID(ctx context.Context, oauthID string) (*models.User, error) {
+	result, err := models.GetUserByOAuthID(ctx, oauthID)
+	return result, logCancelled(ctx, "GetUserByOAuthID", err)
+}
+
+func (s *SQLiteStore) LinkOAuthID(ctx context.Context, userID int, oauthID string) error {
+	return logCancelled(ctx, "LinkOAuthID", models.LinkOAuthID(ctx, userID, oauthID))
+}
+
+func (s *SQLiteStore) CreateOAuthUser(ctx context.Context, email, username, oauthID string) (int, error) {
+	result, err := models.CreateOAuthUser(ctx, email, username, oauthID)
+	return result, logCancelled(ctx, "CreateOAuthUser", err)
+}
+
+func (s *SQLiteStore) UsernameExists(ctx context.Context, username string) (bool, error) {
+	result, err := models.UsernameExists(ctx, username)
+	return result, logCancelled(ctx, "UsernameExists", err)
+}
+
+// Sessions
+
+func (s *SQLiteStore) CreateSession(ctx context.Context, userID int) (*models.Session, error) {
+	result, err := models.CreateSession(ctx, userID)
+	return result, logCancelled(ctx, "CreateSession", err)
+}
+
+func (s *SQLiteStore) GetSessionByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	result, err := models.GetSessionByUUID(ctx, uuid)
+	return result, logCancelled(ctx, "GetSessionByUUID", err)
+}
+
+func (s *SQLiteStore) DeleteSession(ctx context.Context, uuid string) error {
+	return logCancelled(ctx, "DeleteSession", models.DeleteSession(ctx, uuid))
+}
+
+// Modération
+
+func (s *SQLiteStore) SubmitPendingPost(ctx context.Context, post models.NewPost) (int, error) {
+	result, err := models.SubmitPendingPost(ctx, post)
+	return result, logCancelled(ctx, "SubmitPendingPost", err)
+}
+
+func (s *SQLiteStore) GetPendingPosts(ctx context.Context) ([]*models.PendingPost, error) {
+	result, err := models.GetPendingPosts(ctx)
+	return result, logCancelled(ctx, "GetPendingPosts", err)
+}
+
+func (s *SQLiteStore) ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error) {
+	result, err := models.ApprovePendingPost(ctx, pendingID, moderatorID)
+	return result, logCancelled(ctx, "ApprovePendingPost", err)
+}
+
+func (s *SQLiteStore) RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error {
+	return logCancelled(ctx, "RejectPendingPost", models.RejectPendingPost(ctx, pendingID, moderatorID, reason))
+}
+
+func (s *SQLiteStore) ReportContent(ctx context.Context, contentType string, contentID, reporterID int, reason string) (int, error) {
+	result, err := models.ReportContent(ctx, contentType, contentID, reporterID, reason)
+	return result, logCancelled(ctx, "ReportContent", err)
+}
+
+func (s *SQLiteStore) GetReports(ctx context.Context) ([]*models.Report, error) {
+	result, err := models.GetReports(ctx)
+	return result, logCancelled(ctx, "GetReports", err)
+}
+
+func (s *SQLiteStore) HandleReport(ctx context.Context, reportID, adminID int, status, response string) error {
+	return logCancelled(ctx, "HandleReport", models.HandleReport(ctx, reportID, adminID, status, response))
+}
+
+func (s *SQLiteStore) UpdateUserRole(ctx context.Context, userID int, newRole string) error {
+	return logCancelled(ctx, "UpdateUserRole", models.UpdateUserRole(ctx, userID, newRole))
+}
+
+func (s *SQLiteStore) RequestModeratorRole(ctx context.Context, userID int, reason string) (int, error) {
+	result, err := models.RequestModeratorRole(ctx, userID, reason)
+	return result, logCancelled(ctx, "RequestModeratorRole", err)
+}
+
+func (s *SQLiteStore) PinPost(ctx context.Context, postID, categoryID int, until, now time.Time) error {
+	return logCancelled(ctx, "PinPost", models.PinPost(ctx, postID, categoryID, until, now))
+}
+
+func (s *SQLiteStore) UnpinPost(ctx context.Context, postID int) error {
+	return logCancelled(ctx, "UnpinPost", models.UnpinPost(ctx, postID))
+}
+
+func (s *SQLiteStore) SetPostLocked(ctx context.Context, postID int, locked bool) error {
+	return logCancelled(ctx, "SetPostLocked", models.SetPostLocked(ctx, postID, locked))
+}
+
+func (s *SQLiteStore) CreateAnnouncement(ctx context.Context, announcement *models.Announcement) (int, error) {
+	result, err := models.CreateAnnouncement(ctx, announcement)
+	return result, logCancelled(ctx, "CreateAnnouncement", err)
+}
+
+func (s *SQLiteStore) DeleteAnnouncement(ctx context.Context, announcementID int) error {
+	return logCancelled(ctx, "DeleteAnnouncement", models.DeleteAnnouncement(ctx, announcementID))
+}
+
+func (s *SQLiteStore) GetAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	result, err := models.GetAnnouncements(ctx, now)
+	return result, logCancelled(ctx, "GetAnnouncements", err)
+}
+
+func (s *SQLiteStore) GetActiveAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	result, err := models.GetActiveAnnouncements(ctx, now)
+	return result, logCancelled(ctx, "GetActiveAnnouncements", err)
+}
+
+// Images
+
+func (s *SQLiteStore) SaveImage(ctx context.Context, filename string, userID int) (int, error) {
+	result, err := models.SaveImage(ctx, filename, userID)
+	return result, logCancelled(ctx, "SaveImage", err)
+}
+
+func (s *SQLiteStore) GetImageByID(ctx context.Context, imageID string) (*models.Image, error) {
+	result, err := models.GetImageByID(ctx, imageID)
+	return result, logCancelled(ctx, "GetImageByID", err)
+}
+
+func (s *SQLiteStore) GetPostImage(ctx context.Context, postID int) (*models.Image, error) {
+	result, err := models.GetPostImage(ctx, postID)
+	return result, logCancelled(ctx, "GetPostImage", err)
+}
+
+func (s *SQLiteStore) GetImagesByPostID(ctx context.Context, postID int) ([]*models.Image, error) {
+	result, err := models.GetImagesByPostID(ctx, postID)
+	return result, logCancelled(ctx, "GetImagesByPostID", err)
+}
+
+func (s *SQLiteStore) GetImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error) {
+	result, err := models.GetImagesByUserID(ctx, userID)
+	return result, logCancelled(ctx, "GetImagesByUserID", err)
+}
+
+func (s *SQLiteStore) GetUnassociatedImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error) {
+	result, err := models.GetUnassociatedImagesByUserID(ctx, userID)
+	return result, logCancelled(ctx, "GetUnassociatedImagesByUserID", err)
+}
+
+func (s *SQLiteStore) AssociateImageWithPost(ctx context.Context, imageID, postID int) error {
+	return logCancelled(ctx, "AssociateImageWithPost", models.AssociateImageWithPost(ctx, imageID, postID))
+}
+
+func (s *SQLiteStore) DeletePostImage(ctx context.Context, postID int) error {
+	return logCancelled(ctx, "DeletePostImage", models.DeletePostImage(ctx, postID))
+}
+
+func (s *SQLiteStore) DeleteImage(ctx context.Context, imageID, userID int, isAdmin bool) error {
+	return logCancelled(ctx, "DeleteImage", models.DeleteImage(ctx, imageID, userID, isAdmin))
+}
+
+// Catégories
+
+func (s *SQLiteStore) GetCategories(ctx context.Context) ([]models.Category, error) {
+	result, err := models.GetCategories(ctx)
+	return result, logCancelled(ctx, "GetCategories", err)
+}
+
+func (s *SQLiteStore) GetCategoryByID(ctx context.Context, categoryID int) (*models.Category, error) {
+	result, err := models.GetCategoryByID(ctx, categoryID)
+	return result, logCancelled(ctx, "GetCategoryByID", err)
+}
+
+func (s *SQLiteStore) CreateCategory(ctx context.Context, category models.Category) (int, error) {
+	result, err := models.CreateCategory(ctx, category)
+	return result, logCancelled(ctx, "CreateCategory", err)
+}
+
+func (s *SQLiteStore) UpdateCategory(ctx context.Context, category models.Category) error {
+	return logCancelled(ctx, "UpdateCategory", models.UpdateCategory(ctx, category))
+}
+
+func (s *SQLiteStore) ReorderCategories(ctx context.Context, categoryIDs []int) error {
+	return logCancelled(ctx, "ReorderCategories", models.ReorderCategories(ctx, categoryIDs))
+}
+
+func (s *SQLiteStore) MergeCategories(ctx context.Context, sourceID, targetID int) error {
+	return logCancelled(ctx, "MergeCategories", models.MergeCategories(ctx, sourceID, targetID))
+}
+
+func (s *SQLiteStore) CheckCategoryPermission(ctx context.Context, categoryIDs []int, role string) error {
+	return logCancelled(ctx, "CheckCategoryPermission", models.CheckCategoryPermission(ctx, categoryIDs, role))
+}
+
+func (s *SQLiteStore) GetCategoryTree(ctx context.Context, role string) ([]*models.CategoryNode, error) {
+	result, err := models.GetCategoryTree(ctx, role)
+	return result, logCancelled(ctx, "GetCategoryTree", err)
+}
+
+func (s *SQLiteStore) GetCategoryPath(ctx context.Context, categoryID int) ([]models.Category, error) {
+	result, err := models.GetCategoryPath(ctx, categoryID)
+	return result, logCancelled(ctx, "GetCategoryPath", err)
+}
+
+// Tags
+
+func (s *SQLiteStore) SetPostTags(ctx context.Context, postID int, names []string) error {
+	return logCancelled(ctx, "SetPostTags", models.SetPostTags(ctx, postID, names))
+}
+
+func (s *SQLiteStore) GetTag(ctx context.Context, name string) (*models.Tag, error) {
+	result, err := models.GetTag(ctx, name)
+	return result, logCancelled(ctx, "GetTag", err)
+}
+
+func (s *SQLiteStore) SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	result, err := models.SearchTags(ctx, prefix, limit)
+	return result, logCancelled(ctx, "SearchTags", err)
+}
+
+func (s *SQLiteStore) GetTagCloud(ctx context.Context, limit int) ([]models.Tag, error) {
+	result, err := models.GetTagCloud(ctx, limit)
+	return result, logCancelled(ctx, "GetTagCloud", err)
+}
+
+func (s *SQLiteStore) GetTags(ctx context.Context) ([]models.Tag, error) {
+	result, err := models.GetTags(ctx)
+	return result, logCancelled(ctx, "GetTags", err)
+}
+
+func (s *SQLiteStore) AddTagSynonym(ctx context.Context, synonym, tagName string, moderatorID int) error {
+	return logCancelled(ctx, "AddTagSynonym", models.AddTagSynonym(ctx, synonym, tagName, moderatorID))
+}
+
+func (s *SQLiteStore) RemoveTagSynonym(ctx context.Context, synonym string) error {
+	return logCancelled(ctx, "RemoveTagSynonym", models.RemoveTagSynonym(ctx, synonym))
+}
+
+// Notifications
+
+func (s *SQLiteStore) GetNotifications(ctx context.Context, userID, limit int) ([]*models.Notification, error) {
+	result, err := models.GetNotifications(ctx, userID, limit)
+	return result, logCancelled(ctx, "GetNotifications", err)
+}
+
+func (s *SQLiteStore) MarkNotificationRead(ctx context.Context, notificationID, userID int) error {
+	return logCancelled(ctx, "MarkNotificationRead", models.MarkNotificationRead(ctx, notificationID, userID))
+}
+
+func (s *SQLiteStore) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	return logCancelled(ctx, "MarkAllNotificationsRead", models.MarkAllNotificationsRead(ctx, userID))
+}
+
+func (s *SQLiteStore) ResolveMentions(ctx context.Context, names []string) (map[string]int, error) {
+	result, err := models.ResolveMentions(ctx, names)
+	return result, logCancelled(ctx, "ResolveMentions", err)
+}
+
+func (s *SQLiteStore) SuggestUsers(ctx context.Context, prefix string, limit int) ([]string, error) {
+	result, err := models.SuggestUsers(ctx, prefix, limit)
+	return result, logCancelled(ctx, "SuggestUsers", err)
+}
+
+func (s *SQLiteStore) GetNotifiedUsers(ctx context.Context, postID, commentID int) ([]int, error) {
+	result, err := models.GetNotifiedUsers(ctx, postID, commentID)
+	return result, logCancelled(ctx, "GetNotifiedUsers", err)
+}
+
+// Abonnements
+
+func (s *SQLiteStore) Follow(ctx context.Context, userID int, targetType string, targetID int) error {
+	return logCancelled(ctx, "Follow", models.Follow(ctx, userID, targetType, targetID))
+}
+
+func (s *SQLiteStore) Unfollow(ctx context.Context, userID int, targetType string, targetID int) error {
+	return logCancelled(ctx, "Unfollow", models.Unfollow(ctx, userID, targetType, targetID))
+}
+
+func (s *SQLiteStore) SetSubscriptionMuted(ctx context.Context, userID int, targetType string, targetID int, muted bool) error {
+	return logCancelled(ctx, "SetSubscriptionMuted", models.SetSubscriptionMuted(ctx, userID, targetType, targetID, muted))
+}
+
+func (s *SQLiteStore) GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (*models.Subscription, error) {
+	result, err := models.GetSubscription(ctx, userID, targetType, targetID)
+	return result, logCancelled(ctx, "GetSubscription", err)
+}
+
+func (s *SQLiteStore) GetSubscriptions(ctx context.Context, userID int) ([]*models.Subscription, error) {
+	result, err := models.GetSubscriptions(ctx, userID)
+	return result, logCancelled(ctx, "GetSubscriptions", err)
+}
+
+func (s *SQLiteStore) GetFollowingFeed(ctx context.Context, userID, page, perPage int) ([]*models.Post, int, error) {
+	result, total, err := models.GetFollowingFeed(ctx, userID, page, perPage)
+	return result, total, logCancelled(ctx, "GetFollowingFeed", err)
+}
+
+// Résumés par email
+
+func (s *SQLiteStore) SetDigestFrequency(ctx context.Context, userID int, frequency string) error {
+	return logCancelled(ctx, "SetDigestFrequency", models.SetDigestFrequency(ctx, userID, frequency))
+}
+
+func (s *SQLiteStore) GetDueDigests(ctx context.Context, now time.Time) ([]*models.DigestRecipient, error) {
+	result, err := models.GetDueDigests(ctx, now)
+	return result, logCancelled(ctx, "GetDueDigests", err)
+}
+
+func (s *SQLiteStore) GetDigest(ctx context.Context, userID int, since, until time.Time) (*models.Digest, error) {
+	result, err := models.GetDigest(ctx, userID, since, until)
+	return result, logCancelled(ctx, "GetDigest", err)
+}
+
+func (s *SQLiteStore) MarkDigestSent(ctx context.Context, userID int, until time.Time) error {
+	return logCancelled(ctx, "MarkDigestSent", models.MarkDigestSent(ctx, userID, until))
+}
+
+// Messages privés
+
+func (s *SQLiteStore) CreateConversation(ctx context.Context, creatorID int, memberIDs []int, content string) (int, error) {
+	result, err := models.CreateConversation(ctx, creatorID, memberIDs, content)
+	return result, logCancelled(ctx, "CreateConversation", err)
+}
+
+func (s *SQLiteStore) SendMessage(ctx context.Context, conversationID, senderID int, content string) (int, error) {
+	result, err := models.SendMessage(ctx, conversationID, senderID, content)
+	return result, logCancelled(ctx, "SendMessage", err)
+}
+
+func (s *SQLiteStore) GetConversations(ctx context.Context, userID int) ([]*models.Conversation, error) {
+	result, err := models.GetConversations(ctx, userID)
+	return result, logCancelled(ctx, "GetConversations", err)
+}
+
+func (s *SQLiteStore) GetConversation(ctx context.Context, conversationID, userID int) (*models.Conversation, error) {
+	result, err := models.GetConversation(ctx, conversationID, userID)
+	return result, logCancelled(ctx, "GetConversation", err)
+}
+
+func (s *SQLiteStore) GetMessages(ctx context.Context, conversationID, userID, limit int) ([]*models.Message, error) {
+	result, err := models.GetMessages(ctx, conversationID, userID, limit)
+	return result, logCancelled(ctx, "GetMessages", err)
+}
+
+func (s *SQLiteStore) GetMessage(ctx context.Context, messageID, userID int) (*models.Message, error) {
+	result, err := models.GetMessage(ctx, messageID, userID)
+	return result, logCancelled(ctx, "GetMessage", err)
+}
+
+func (s *SQLiteStore) MarkConversationRead(ctx context.Context, conversationID, userID int) error {
+	return logCancelled(ctx, "MarkConversationRead", models.MarkConversationRead(ctx, conversationID, userID))
+}
+
+func (s *SQLiteStore) LeaveConversation(ctx context.Context, conversationID, userID int) error {
+	return logCancelled(ctx, "LeaveConversation", models.LeaveConversation(ctx, conversationID, userID))
+}
+
+func (s *SQLiteStore) BlockUser(ctx context.Context, userID, blockedID int) error {
+	return logCancelled(ctx, "BlockUser", models.BlockUser(ctx, userID, blockedID))
+}
+
+func (s *SQLiteStore) UnblockUser(ctx context.Context, userID, blockedID int) error {
+	return logCancelled(ctx, "UnblockUser", models.UnblockUser(ctx, userID, blockedID))
+}
+
+func (s *SQLiteStore) GetBlockedUsers(ctx context.Context, userID int) ([]*models.BlockedUser, error) {
+	result, err := models.GetBlockedUsers(ctx, userID)
+	return result, logCancelled(ctx, "GetBlockedUsers", err)
+}
+
+func (s *SQLiteStore) GetMessageRetention(ctx context.Context) (int, error) {
+	result, err := models.GetMessageRetention(ctx)
+	return result, logCancelled(ctx, "GetMessageRetention", err)
+}
+
+func (s *SQLiteStore) SetMessageRetention(ctx context.Context, days int) error {
+	return logCancelled(ctx, "SetMessageRetention", models.SetMessageRetention(ctx, days))
+}
+
+func (s *SQLiteStore) PurgeMessages(ctx context.Context, now time.Time) (int, error) {
+	result, err := models.PurgeMessages(ctx, now)
+	return result, logCancelled(ctx, "PurgeMessages", err)
+}
+
+// Signets
+
+func (s *SQLiteStore) SaveBookmark(ctx context.Context, userID int, targetType string, targetID, folderID int, note string) error {
+	return logCancelled(ctx, "SaveBookmark", models.SaveBookmark(ctx, userID, targetType, targetID, folderID, note))
+}
+
+func (s *SQLiteStore) RemoveBookmark(ctx context.Context, userID int, targetType string, targetID int) error {
+	return logCancelled(ctx, "RemoveBookmark", models.RemoveBookmark(ctx, userID, targetType, targetID))
+}
+
+func (s *SQLiteStore) GetBookmarks(ctx context.Context, userID, folderID int) ([]*models.Bookmark, error) {
+	result, err := models.GetBookmarks(ctx, userID, folderID)
+	return result, logCancelled(ctx, "GetBookmarks", err)
+}
+
+func (s *SQLiteStore) GetBookmarkFolders(ctx context.Context, userID int) ([]*models.BookmarkFolder, error) {
+	result, err := models.GetBookmarkFolders(ctx, userID)
+	return result, logCancelled(ctx, "GetBookmarkFolders", err)
+}
+
+func (s *SQLiteStore) CreateBookmarkFolder(ctx context.Context, userID int, name string) (int, error) {
+	result, err := models.CreateBookmarkFolder(ctx, userID, name)
+	return result, logCancelled(ctx, "CreateBookmarkFolder", err)
+}
+
+func (s *SQLiteStore) DeleteBookmarkFolder(ctx context.Context, userID, folderID int) error {
+	return logCancelled(ctx, "DeleteBookmarkFolder", models.DeleteBookmarkFolder(ctx, userID, folderID))
+}
+
+// Sondages
+
+func (s *SQLiteStore) GetPoll(ctx context.Context, postID, currentUserID int) (*models.Poll, error) {
+	result, err := models.GetPoll(ctx, postID, currentUserID)
+	return result, logCancelled(ctx, "GetPoll", err)
+}
+
+func (s *SQLiteStore) UpdatePoll(ctx context.Context, postID int, poll *models.Poll) error {
+	return logCancelled(ctx, "UpdatePoll", models.UpdatePoll(ctx, postID, poll))
+}
+
+func (s *SQLiteStore) Vote(ctx context.Context, pollID, userID int, optionIDs []int) error {
+	return logCancelled(ctx, "Vote", models.Vote(ctx, pollID, userID, optionIDs))
+}
+
+// Réactions
+
+func (s *SQLiteStore) GetReactions(ctx context.Context) ([]models.Reaction, error) {
+	result, err := models.GetReactions(ctx)
+	return result, logCancelled(ctx, "GetReactions", err)
+}
+
+func (s *SQLiteStore) CreateReaction(ctx context.Context, reaction models.Reaction) (int, error) {
+	result, err := models.CreateReaction(ctx, reaction)
+	return result, logCancelled(ctx, "CreateReaction", err)
+}
+
+func (s *SQLiteStore) UpdateReaction(ctx context.Context, reaction models.Reaction) error {
+	return logCancelled(ctx, "UpdateReaction", models.UpdateReaction(ctx, reaction))
+}
+
+func (s *SQLiteStore) GetReactors(ctx context.Context, targetType string, targetID int) ([]models.ReactionCount, error) {
+	result, err := models.GetReactors(ctx, targetType, targetID)
+	return result, logCancelled(ctx, "GetReactors", err)
+}
+
+// Réputation
+
+func (s *SQLiteStore) GetReputation(ctx context.Context, userID int) (*models.Reputation, error) {
+	result, err := models.GetReputation(ctx, userID)
+	return result, logCancelled(ctx, "GetReputation", err)
+}
+
+func (s *SQLiteStore) AwardBadges(ctx context.Context, userID int) ([]models.Badge, error) {
+	result, err := models.AwardBadges(ctx, userID)
+	return result, logCancelled(ctx, "AwardBadges", err)
+}
+
+func (s *SQLiteStore) AwardAllBadges(ctx context.Context) (int, error) {
+	result, err := models.AwardAllBadges(ctx)
+	return result, logCancelled(ctx, "AwardAllBadges", err)
+}
+
+func (s *SQLiteStore) GetUserBadges(ctx context.Context, userID int) ([]models.UserBadge, error) {
+	result, err := models.GetUserBadges(ctx, userID)
+	return result, logCancelled(ctx, "GetUserBadges", err)
+}
+
+// Brouillons
+
+func (s *SQLiteStore) SaveDraft(ctx context.Context, draft *models.Draft) (int, error) {
+	result, err := models.SaveDraft(ctx, draft)
+	return result, logCancelled(ctx, "SaveDraft", err)
+}
+
+func (s *SQLiteStore) GetDraft(ctx context.Context, draftID, userID int) (*models.Draft, error) {
+	result, err := models.GetDraft(ctx, draftID, userID)
+	return result, logCancelled(ctx, "GetDraft", err)
+}
+
+func (s *SQLiteStore) GetPostDraft(ctx context.Context, postID, userID int) (*models.Draft, error) {
+	result, err := models.GetPostDraft(ctx, postID, userID)
+	return result, logCancelled(ctx, "GetPostDraft", err)
+}
+
+func (s *SQLiteStore) GetUserDrafts(ctx context.Context, userID int) ([]*models.Draft, error) {
+	result, err := models.GetUserDrafts(ctx, userID)
+	return result, logCancelled(ctx, "GetUserDrafts", err)
+}
+
+func (s *SQLiteStore) DeleteDraft(ctx context.Context, draftID, userID int) error {
+	return logCancelled(ctx, "DeleteDraft", models.DeleteDraft(ctx, draftID, userID))
+}
+
+func (s *SQLiteStore) DeletePostDraft(ctx context.Context, postID, userID int) error {
+	return logCancelled(ctx, "DeletePostDraft", models.DeletePostDraft(ctx, postID, userID))
+}
+
+func (s *SQLiteStore) ScheduleDraft(ctx context.Context, draftID, userID int, publishAt, now time.Time) error {
+	return logCancelled(ctx, "ScheduleDraft", models.ScheduleDraft(ctx, draftID, userID, publishAt, now))
+}
+
+func (s *SQLiteStore) GetDueDrafts(ctx context.Context, now time.Time) ([]*models.Draft, error) {
+	result, err := models.GetDueDrafts(ctx, now)
+	return result, logCancelled(ctx, "GetDueDrafts", err)
+}
+
+func (s *SQLiteStore) ClaimScheduledDraft(ctx context.Context, draftID int, now time.Time) (bool, error) {
+	result, err := models.ClaimScheduledDraft(ctx, draftID, now)
+	return result, logCancelled(ctx, "ClaimScheduledDraft", err)
+}
+
+func (s *SQLiteStore) SetDraftPublishError(ctx context.Context, draftID int, message string) error {
+	return logCancelled(ctx, "SetDraftPublishError", models.SetDraftPublishError(ctx, draftID, message))
+}
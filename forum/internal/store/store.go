@@ -0,0 +1,237 @@
+// Package store décrit avec des interfaces tout ce dont les handlers ont besoin pour lire et écrire les données
+// Les handlers ne dépendent que d'elles: on peut leur donner la base SQLite en production (SQLiteStore)
+// ou un stockage en mémoire pour les essais (MemoryStore), sans rien changer à leur code
+// Le stockage en mémoire réutilise les fonctions de models qui travaillent sur des données déjà chargées
+// (arbre et droits des catégories, tags, réactions), pour que les deux stockages se comportent de la même façon
+// Chaque méthode reçoit le contexte de la requête HTTP: si le client part ou si le délai est dépassé,
+// les requêtes SQL en cours sont interrompues
+package store
+
+import (
+	"context"
+	"forum/internal/models"
+	"time"
+)
+
+// PostStore gère les posts, leurs réactions et les catégories
+type PostStore interface {
+	CreatePost(ctx context.Context, title, content string, userID int, categoryIDs []int) (int, error)
+	CreatePostWithDetails(ctx context.Context, post models.NewPost) (int, error)
+	GetPostByID(ctx context.Context, postID, currentUserID int) (*models.Post, error)
+	GetPosts(ctx context.Context, page, perPage, categoryID int, tags []string, userID int, solved, sortBy string, currentUserID int) ([]*models.Post, int, error)
+	UpdatePost(ctx context.Context, postID, userID int, title, content string, categoryIDs []int) error
+	DeletePost(ctx context.Context, postID, userID int, isAdmin bool) error
+	ReactToPost(ctx context.Context, postID, userID int, reactionType string) error
+	AcceptAnswer(ctx context.Context, postID, commentID int) error
+	GetAllCategories(ctx context.Context, role string) ([]models.Category, error)
+	GetUserPosts(ctx context.Context, userID int) ([]*models.Post, error)
+	GetUserLikedPosts(ctx context.Context, userID int) ([]*models.Post, error)
+	GetUserDislikedPosts(ctx context.Context, userID int) ([]*models.Post, error)
+	Search(ctx context.Context, query models.SearchQuery, page, perPage, currentUserID int) ([]*models.SearchResult, int, error)
+	SearchEnabled() bool
+}
+
+// CommentStore gère les commentaires et leurs réactions
+type CommentStore interface {
+	CreateComment(ctx context.Context, content string, userID, postID int) (int, error)
+	GetCommentsByPostID(ctx context.Context, postID, currentUserID int) ([]*models.Comment, error)
+	GetCommentByID(ctx context.Context, commentID, currentUserID int) (*models.Comment, error)
+	UpdateComment(ctx context.Context, commentID, userID int, content string) error
+	DeleteComment(ctx context.Context, commentID, userID int, isAdmin bool) error
+	ReactToComment(ctx context.Context, commentID, userID int, reactionType string) error
+	GetUserComments(ctx context.Context, userID int) ([]*models.Comment, error)
+}
+
+// UserStore gère les comptes utilisateurs
+type UserStore interface {
+	RegisterUser(ctx context.Context, email, username, password string) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	Authenticate(ctx context.Context, email, password string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	GetUserActivity(ctx context.Context, userID, limit int) ([]*models.UserActivity, error)
+	GetUserByOAuthID(ctx context.Context, oauthID string) (*models.User, error)
+	LinkOAuthID(ctx context.Context, userID int, oauthID string) error
+	CreateOAuthUser(ctx context.Context, email, username, oauthID string) (int, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+}
+
+// SessionStore gère les sessions des utilisateurs connectés
+type SessionStore interface {
+	CreateSession(ctx context.Context, userID int) (*models.Session, error)
+	GetSessionByUUID(ctx context.Context, uuid string) (*models.Session, error)
+	DeleteSession(ctx context.Context, uuid string) error
+}
+
+// ModerationStore gère les posts en attente, les signalements et les rôles
+type ModerationStore interface {
+	SubmitPendingPost(ctx context.Context, post models.NewPost) (int, error)
+	GetPendingPosts(ctx context.Context) ([]*models.PendingPost, error)
+	ApprovePendingPost(ctx context.Context, pendingID, moderatorID int) (int, error)
+	RejectPendingPost(ctx context.Context, pendingID, moderatorID int, reason string) error
+	ReportContent(ctx context.Context, contentType string, contentID, reporterID int, reason string) (int, error)
+	GetReports(ctx context.Context) ([]*models.Report, error)
+	HandleReport(ctx context.Context, reportID, adminID int, status, response string) error
+	UpdateUserRole(ctx context.Context, userID int, newRole string) error
+	RequestModeratorRole(ctx context.Context, userID int, reason string) (int, error)
+	PinPost(ctx context.Context, postID, categoryID int, until, now time.Time) error
+	UnpinPost(ctx context.Context, postID int) error
+	SetPostLocked(ctx context.Context, postID int, locked bool) error
+	CreateAnnouncement(ctx context.Context, announcement *models.Announcement) (int, error)
+	DeleteAnnouncement(ctx context.Context, announcementID int) error
+	GetAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error)
+	GetActiveAnnouncements(ctx context.Context, now time.Time) ([]*models.Announcement, error)
+}
+
+// ImageStore gère les images envoyées par les utilisateurs
+type ImageStore interface {
+	SaveImage(ctx context.Context, filename string, userID int) (int, error)
+	GetImageByID(ctx context.Context, imageID string) (*models.Image, error)
+	GetPostImage(ctx context.Context, postID int) (*models.Image, error)
+	GetImagesByPostID(ctx context.Context, postID int) ([]*models.Image, error)
+	GetImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error)
+	GetUnassociatedImagesByUserID(ctx context.Context, userID int) ([]*models.Image, error)
+	AssociateImageWithPost(ctx context.Context, imageID, postID int) error
+	DeletePostImage(ctx context.Context, postID int) error
+	DeleteImage(ctx context.Context, imageID, userID int, isAdmin bool) error
+}
+
+// CategoryStore gère l'administration des catégories et leurs droits de publication et de lecture
+type CategoryStore interface {
+	GetCategories(ctx context.Context) ([]models.Category, error)
+	GetCategoryByID(ctx context.Context, categoryID int) (*models.Category, error)
+	CreateCategory(ctx context.Context, category models.Category) (int, error)
+	UpdateCategory(ctx context.Context, category models.Category) error
+	ReorderCategories(ctx context.Context, categoryIDs []int) error
+	MergeCategories(ctx context.Context, sourceID, targetID int) error
+	CheckCategoryPermission(ctx context.Context, categoryIDs []int, role string) error
+	GetCategoryTree(ctx context.Context, role string) ([]*models.CategoryNode, error)
+	GetCategoryPath(ctx context.Context, categoryID int) ([]models.Category, error)
+}
+
+// TagStore gère les tags libres des posts et leurs synonymes
+type TagStore interface {
+	SetPostTags(ctx context.Context, postID int, names []string) error
+	GetTag(ctx context.Context, name string) (*models.Tag, error)
+	SearchTags(ctx context.Context, prefix string, limit int) ([]models.Tag, error)
+	GetTagCloud(ctx context.Context, limit int) ([]models.Tag, error)
+	GetTags(ctx context.Context) ([]models.Tag, error)
+	AddTagSynonym(ctx context.Context, synonym, tagName string, moderatorID int) error
+	RemoveTagSynonym(ctx context.Context, synonym string) error
+}
+
+// NotificationStore gère les notifications des utilisateurs et la résolution des mentions @nom
+type NotificationStore interface {
+	GetNotifications(ctx context.Context, userID, limit int) ([]*models.Notification, error)
+	MarkNotificationRead(ctx context.Context, notificationID, userID int) error
+	MarkAllNotificationsRead(ctx context.Context, userID int) error
+	ResolveMentions(ctx context.Context, names []string) (map[string]int, error)
+	SuggestUsers(ctx context.Context, prefix string, limit int) ([]string, error)
+	GetNotifiedUsers(ctx context.Context, postID, commentID int) ([]int, error)
+}
+
+// SubscriptionStore gère les abonnements aux posts, aux catégories et aux utilisateurs
+type SubscriptionStore interface {
+	Follow(ctx context.Context, userID int, targetType string, targetID int) error
+	Unfollow(ctx context.Context, userID int, targetType string, targetID int) error
+	SetSubscriptionMuted(ctx context.Context, userID int, targetType string, targetID int, muted bool) error
+	GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (*models.Subscription, error)
+	GetSubscriptions(ctx context.Context, userID int) ([]*models.Subscription, error)
+	GetFollowingFeed(ctx context.Context, userID, page, perPage int) ([]*models.Post, int, error)
+}
+
+// DigestStore gère les résumés d'activité envoyés par email
+type DigestStore interface {
+	SetDigestFrequency(ctx context.Context, userID int, frequency string) error
+	GetDueDigests(ctx context.Context, now time.Time) ([]*models.DigestRecipient, error)
+	GetDigest(ctx context.Context, userID int, since, until time.Time) (*models.Digest, error)
+	MarkDigestSent(ctx context.Context, userID int, until time.Time) error
+}
+
+// MessageStore gère les conversations privées, les blocages entre utilisateurs
+// et la durée de conservation des messages
+type MessageStore interface {
+	CreateConversation(ctx context.Context, creatorID int, memberIDs []int, content string) (int, error)
+	SendMessage(ctx context.Context, conversationID, senderID int, content string) (int, error)
+	GetConversations(ctx context.Context, userID int) ([]*models.Conversation, error)
+	GetConversation(ctx context.Context, conversationID, userID int) (*models.Conversation, error)
+	GetMessages(ctx context.Context, conversationID, userID, limit int) ([]*models.Message, error)
+	GetMessage(ctx context.Context, messageID, userID int) (*models.Message, error)
+	MarkConversationRead(ctx context.Context, conversationID, userID int) error
+	LeaveConversation(ctx context.Context, conversationID, userID int) error
+	BlockUser(ctx context.Context, userID, blockedID int) error
+	UnblockUser(ctx context.Context, userID, blockedID int) error
+	GetBlockedUsers(ctx context.Context, userID int) ([]*models.BlockedUser, error)
+	GetMessageRetention(ctx context.Context) (int, error)
+	SetMessageRetention(ctx context.Context, days int) error
+	PurgeMessages(ctx context.Context, now time.Time) (int, error)
+}
+
+// BookmarkStore gère les signets privés des utilisateurs et leurs dossiers
+type BookmarkStore interface {
+	SaveBookmark(ctx context.Context, userID int, targetType string, targetID, folderID int, note string) error
+	RemoveBookmark(ctx context.Context, userID int, targetType string, targetID int) error
+	GetBookmarks(ctx context.Context, userID, folderID int) ([]*models.Bookmark, error)
+	GetBookmarkFolders(ctx context.Context, userID int) ([]*models.BookmarkFolder, error)
+	CreateBookmarkFolder(ctx context.Context, userID int, name string) (int, error)
+	DeleteBookmarkFolder(ctx context.Context, userID, folderID int) error
+}
+
+// PollStore gère les sondages des posts et leurs votes
+type PollStore interface {
+	GetPoll(ctx context.Context, postID, currentUserID int) (*models.Poll, error)
+	UpdatePoll(ctx context.Context, postID int, poll *models.Poll) error
+	Vote(ctx context.Context, pollID, userID int, optionIDs []int) error
+}
+
+// ReactionStore gère le jeu de réactions configuré par les administrateurs
+// et les listes des utilisateurs qui ont réagi à un contenu
+type ReactionStore interface {
+	GetReactions(ctx context.Context) ([]models.Reaction, error)
+	CreateReaction(ctx context.Context, reaction models.Reaction) (int, error)
+	UpdateReaction(ctx context.Context, reaction models.Reaction) error
+	GetReactors(ctx context.Context, targetType string, targetID int) ([]models.ReactionCount, error)
+}
+
+// ReputationStore calcule la réputation des utilisateurs et leur attribue des badges
+type ReputationStore interface {
+	GetReputation(ctx context.Context, userID int) (*models.Reputation, error)
+	AwardBadges(ctx context.Context, userID int) ([]models.Badge, error)
+	AwardAllBadges(ctx context.Context) (int, error)
+	GetUserBadges(ctx context.Context, userID int) ([]models.UserBadge, error)
+}
+
+// DraftStore gère les brouillons des posts et leur publication programmée
+type DraftStore interface {
+	SaveDraft(ctx context.Context, draft *models.Draft) (int, error)
+	GetDraft(ctx context.Context, draftID, userID int) (*models.Draft, error)
+	GetPostDraft(ctx context.Context, postID, userID int) (*models.Draft, error)
+	GetUserDrafts(ctx context.Context, userID int) ([]*models.Draft, error)
+	DeleteDraft(ctx context.Context, draftID, userID int) error
+	DeletePostDraft(ctx context.Context, postID, userID int) error
+	ScheduleDraft(ctx context.Context, draftID, userID int, publishAt, now time.Time) error
+	GetDueDrafts(ctx context.Context, now time.Time) ([]*models.Draft, error)
+	ClaimScheduledDraft(ctx context.Context, draftID int, now time.Time) (bool, error)
+	SetDraftPublishError(ctx context.Context, draftID int, message string) error
+}
+
+// Stores regroupe toutes les dépendances de stockage passées aux handlers et aux middlewares
+type Stores struct {
+	Posts         PostStore
+	Comments      CommentStore
+	Users         UserStore
+	Sessions      SessionStore
+	Moderation    ModerationStore
+	Images        ImageStore
+	Categories    CategoryStore
+	Tags          TagStore
+	Notifications NotificationStore
+	Subscriptions SubscriptionStore
+	Digests       DigestStore
+	Messages      MessageStore
+	Bookmarks     BookmarkStore
+	Polls         PollStore
+	Reactions     ReactionStore
+	Reputation    ReputationStore
+	Drafts        DraftStore
+}
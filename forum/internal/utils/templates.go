@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"forum/internal/markdown"
 	"html/template"
 	"path/filepath"
 )
@@ -20,6 +21,8 @@ func ParseTemplate(baseTemplate string, templates ...string) (*template.Template
 			}
 			return result
 		},
+		// Rendu du Markdown des posts et des commentaires, mis en cache par révision
+		"markdown": markdown.Render,
 	}
 	
 	allTemplates := append([]string{baseTemplate}, templates...)